@@ -0,0 +1,33 @@
+// Package quickgraphchi serves a Graphy instance from a chi router. It is a separate
+// module so that the quickgraph module doesn't depend on chi.
+//
+//	r := chi.NewRouter()
+//	quickgraphchi.Handle(r, "/tenants/{tenant}/graphql", graph.HttpHandler(), requireTenant)
+//
+// chi keeps the route of a request in the request's context, which is the context that
+// the graph functions are called with, so they can read the route parameters:
+//
+//	func orders(ctx context.Context) []Order {
+//		tenant := quickgraphchi.URLParam(ctx, "tenant")
+//		...
+//	}
+package quickgraphchi
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+)
+
+// Handle serves the requests to the pattern with the handler, which is usually the one
+// returned by Graphy.HttpHandler or NewGraphHttpHandler. The middlewares only apply to
+// this route.
+func Handle(r chi.Router, pattern string, h http.Handler, middlewares ...func(http.Handler) http.Handler) {
+	r.With(middlewares...).Handle(pattern, h)
+}
+
+// URLParam returns the value of a parameter of the route of the request that a graph
+// function is called for, or "" if there is no such parameter.
+func URLParam(ctx context.Context, key string) string {
+	return chi.URLParamFromCtx(ctx, key)
+}
@@ -0,0 +1,47 @@
+package quickgraphchi
+
+import (
+	"context"
+	"github.com/gburgyan/go-quickgraph"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type userKey struct{}
+
+func TestHandle(t *testing.T) {
+	ctx := context.Background()
+	g := quickgraph.Graphy{}
+	g.RegisterQuery(ctx, "tenant", func(ctx context.Context) string {
+		user, _ := ctx.Value(userKey{}).(string)
+		return URLParam(ctx, "tenant") + " " + user
+	})
+
+	r := chi.NewRouter()
+	setUser := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), userKey{}, "ada")))
+		})
+	}
+	Handle(r, "/tenants/{tenant}/graphql", g.HttpHandler(), setUser)
+	r.Get("/health", func(w http.ResponseWriter, req *http.Request) {
+		user, _ := req.Context().Value(userKey{}).(string)
+		_, _ = w.Write([]byte("ok " + user))
+	})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("POST", "/tenants/acme/graphql", strings.NewReader(`{"query": "{ tenant }"}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `{"data":{"tenant":"acme ada"}}`, rec.Body.String())
+
+	// The middleware only applies to the route of the graph.
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/health", nil))
+	assert.Equal(t, "ok ", rec.Body.String())
+
+	assert.Equal(t, "", URLParam(ctx, "tenant"))
+}
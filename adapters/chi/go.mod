@@ -0,0 +1,19 @@
+module github.com/gburgyan/go-quickgraph/adapters/chi
+
+go 1.20
+
+require (
+	github.com/gburgyan/go-quickgraph v0.0.0
+	github.com/go-chi/chi/v5 v5.0.12
+	github.com/stretchr/testify v1.8.4
+)
+
+require (
+	github.com/alecthomas/participle/v2 v2.1.1 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/gburgyan/go-timing v0.7.6 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/gburgyan/go-quickgraph => ../..
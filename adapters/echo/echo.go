@@ -0,0 +1,42 @@
+// Package quickgraphecho serves a Graphy instance from an echo server. It is a separate
+// module so that the quickgraph module doesn't depend on echo.
+//
+//	e := echo.New()
+//	e.Any("/tenants/:tenant/graphql", quickgraphecho.Handler(graph.HttpHandler()), requireTenant)
+//
+// The echo.Context of the request is added to the context that the graph functions are
+// called with, so they can read the route parameters and the values that echo middleware
+// sets:
+//
+//	func orders(ctx context.Context) []Order {
+//		c, _ := quickgraphecho.FromContext(ctx)
+//		tenant := c.Param("tenant")
+//		...
+//	}
+package quickgraphecho
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+type contextKey struct{}
+
+// Handler returns an echo.HandlerFunc that serves the requests with the handler, which is
+// usually the one returned by Graphy.HttpHandler or NewGraphHttpHandler.
+func Handler(h http.Handler) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		ctx := context.WithValue(req.Context(), contextKey{}, c)
+		h.ServeHTTP(c.Response(), req.WithContext(ctx))
+		return nil
+	}
+}
+
+// FromContext returns the echo.Context of the request that a graph function is called
+// for, if the request was served by a Handler.
+func FromContext(ctx context.Context) (echo.Context, bool) {
+	c, ok := ctx.Value(contextKey{}).(echo.Context)
+	return c, ok
+}
@@ -0,0 +1,41 @@
+package quickgraphecho
+
+import (
+	"context"
+	"github.com/gburgyan/go-quickgraph"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	g := quickgraph.Graphy{}
+	g.RegisterQuery(ctx, "tenant", func(ctx context.Context) string {
+		c, ok := FromContext(ctx)
+		if !ok {
+			return "none"
+		}
+		return c.Param("tenant") + " " + c.Get("user").(string)
+	})
+
+	e := echo.New()
+	setUser := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("user", "ada")
+			return next(c)
+		}
+	}
+	e.Any("/tenants/:tenant/graphql", Handler(g.HttpHandler()), setUser)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("POST", "/tenants/acme/graphql", strings.NewReader(`{"query": "{ tenant }"}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `{"data":{"tenant":"acme ada"}}`, rec.Body.String())
+
+	_, ok := FromContext(ctx)
+	assert.False(t, ok)
+}
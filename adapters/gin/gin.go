@@ -0,0 +1,40 @@
+// Package quickgraphgin serves a Graphy instance from a gin router. It is a separate
+// module so that the quickgraph module doesn't depend on gin.
+//
+//	r := gin.New()
+//	r.Any("/tenants/:tenant/graphql", requireTenant, quickgraphgin.Handler(graph.HttpHandler()))
+//
+// The gin.Context of the request is added to the context that the graph functions are
+// called with, so they can read the route parameters and the values that gin middleware
+// sets:
+//
+//	func orders(ctx context.Context) []Order {
+//		c, _ := quickgraphgin.FromContext(ctx)
+//		tenant := c.Param("tenant")
+//		...
+//	}
+package quickgraphgin
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type contextKey struct{}
+
+// Handler returns a gin.HandlerFunc that serves the requests with the handler, which is
+// usually the one returned by Graphy.HttpHandler or NewGraphHttpHandler.
+func Handler(h http.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.WithValue(c.Request.Context(), contextKey{}, c)
+		h.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+	}
+}
+
+// FromContext returns the gin.Context of the request that a graph function is called
+// for, if the request was served by a Handler.
+func FromContext(ctx context.Context) (*gin.Context, bool) {
+	c, ok := ctx.Value(contextKey{}).(*gin.Context)
+	return c, ok
+}
@@ -0,0 +1,39 @@
+package quickgraphgin
+
+import (
+	"context"
+	"github.com/gburgyan/go-quickgraph"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	g := quickgraph.Graphy{}
+	g.RegisterQuery(ctx, "tenant", func(ctx context.Context) string {
+		c, ok := FromContext(ctx)
+		if !ok {
+			return "none"
+		}
+		return c.Param("tenant") + " " + c.GetString("user")
+	})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	setUser := func(c *gin.Context) {
+		c.Set("user", "ada")
+	}
+	r.Any("/tenants/:tenant/graphql", setUser, Handler(g.HttpHandler()))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("POST", "/tenants/acme/graphql", strings.NewReader(`{"query": "{ tenant }"}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `{"data":{"tenant":"acme ada"}}`, rec.Body.String())
+
+	_, ok := FromContext(ctx)
+	assert.False(t, ok)
+}
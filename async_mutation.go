@@ -0,0 +1,256 @@
+package quickgraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// OperationState is the state of an asynchronous mutation.
+type OperationState string
+
+const (
+	OperationPending   OperationState = "PENDING"
+	OperationSucceeded OperationState = "SUCCEEDED"
+	OperationFailed    OperationState = "FAILED"
+)
+
+// EnumValues implements StringEnumValues.
+func (s OperationState) EnumValues() []EnumValue {
+	return []EnumValue{
+		{Name: string(OperationPending), Description: "The mutation is still running."},
+		{Name: string(OperationSucceeded), Description: "The mutation finished, and its result is available."},
+		{Name: string(OperationFailed), Description: "The mutation failed, and its error is available."},
+	}
+}
+
+// OperationStatus is what clients know about an asynchronous mutation. It is returned by
+// the mutation itself and by the "operation" query.
+type OperationStatus struct {
+	ID    string         `graphy:"id"`
+	State OperationState `graphy:"state"`
+
+	// Result is the result of the mutation as JSON, once it has succeeded.
+	Result *string `graphy:"result"`
+
+	// Error is the error of the mutation, if it failed.
+	Error *string `graphy:"error"`
+
+	CreatedAt   time.Time  `graphy:"createdAt"`
+	CompletedAt *time.Time `graphy:"completedAt"`
+}
+
+// OperationStore keeps the status of asynchronous mutations so that clients can ask for
+// it. The status is saved when a mutation starts and again when it completes, which may
+// be on a different goroutine. A store that is shared between instances of a service, such
+// as one backed by a database, allows any instance to answer.
+type OperationStore interface {
+	// SaveOperation saves the status of an operation, replacing what was saved for it
+	// before.
+	SaveOperation(ctx context.Context, status *OperationStatus) error
+
+	// LoadOperation returns the status of an operation, or nil if it isn't known.
+	LoadOperation(ctx context.Context, id string) (*OperationStatus, error)
+}
+
+// MemoryOperationStore is an OperationStore that keeps the operations in memory. It is
+// the default OperationStore. Use NewMemoryOperationStore to create one.
+type MemoryOperationStore struct {
+	// Retention is how long the operations are kept after they complete. If it is zero,
+	// they are kept forever.
+	Retention time.Duration
+
+	mu         sync.Mutex
+	operations map[string]OperationStatus
+}
+
+// NewMemoryOperationStore returns an empty MemoryOperationStore.
+func NewMemoryOperationStore() *MemoryOperationStore {
+	return &MemoryOperationStore{
+		operations: map[string]OperationStatus{},
+	}
+}
+
+// SaveOperation implements OperationStore. Operations that completed longer ago than
+// the Retention are removed along the way.
+func (m *MemoryOperationStore) SaveOperation(ctx context.Context, status *OperationStatus) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Retention > 0 {
+		cutoff := Now(ctx).Add(-m.Retention)
+		for id, op := range m.operations {
+			if op.CompletedAt != nil && op.CompletedAt.Before(cutoff) {
+				delete(m.operations, id)
+			}
+		}
+	}
+	m.operations[status.ID] = *status
+	return nil
+}
+
+// LoadOperation implements OperationStore.
+func (m *MemoryOperationStore) LoadOperation(ctx context.Context, id string) (*OperationStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	op, ok := m.operations[id]
+	if !ok {
+		return nil, nil
+	}
+	return &op, nil
+}
+
+// asyncOperationQuery is the name of the query for the status of asynchronous mutations.
+const asyncOperationQuery = "operation"
+
+// RegisterAsyncMutation registers a mutation that runs in the background. Calling it
+// returns an OperationStatus in the PENDING state right away, and its ID can be used with
+// the "operation(id: String!)" query, which is registered along with the first
+// asynchronous mutation, to find out when it has SUCCEEDED or FAILED. The result of the
+// function is available in the status as JSON.
+//
+// The function and the names are the same as for RegisterMutation. The function keeps the
+// values of the context of the request, but not its deadline or cancellation, since it
+// runs after the request is done. The statuses are kept in the OperationStore; if there
+// isn't one, a MemoryOperationStore is used.
+func (g *Graphy) RegisterAsyncMutation(ctx context.Context, name string, f any, names ...string) {
+	if g.OperationStore == nil {
+		g.OperationStore = NewMemoryOperationStore()
+	}
+
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name:           name,
+		Function:       g.asyncMutationWrapper(name, f),
+		ParameterNames: names,
+		Mode:           ModeMutation,
+	})
+
+	g.structureLock.RLock()
+	_, registered := g.processors[asyncOperationQuery]
+	g.structureLock.RUnlock()
+	if !registered {
+		g.RegisterFunction(ctx, FunctionDefinition{
+			Name: asyncOperationQuery,
+			Function: func(ctx context.Context, id string) (*OperationStatus, error) {
+				return g.OperationStore.LoadOperation(ctx, id)
+			},
+			ParameterNames: []string{"id"},
+			Mode:           ModeQuery,
+		})
+	}
+}
+
+// asyncMutationWrapper returns a function with the same parameters as f, with a context
+// first if f doesn't have one, that starts f in the background and returns its status.
+func (g *Graphy) asyncMutationWrapper(name string, f any) any {
+	fv := reflect.ValueOf(f)
+	ft := fv.Type()
+	if ft.Kind() != reflect.Func {
+		panic(fmt.Sprintf("async mutation %s must be a function", name))
+	}
+	if ft.NumOut() == 0 || ft.NumOut() > 2 || (ft.NumOut() == 2 && ft.Out(1) != errorType) {
+		panic(fmt.Sprintf("async mutation %s must return a result, an error, or both", name))
+	}
+
+	hasCtx := ft.NumIn() > 0 && ft.In(0) == contextType
+	in := []reflect.Type{contextType}
+	for i := 0; i < ft.NumIn(); i++ {
+		if i == 0 && hasCtx {
+			continue
+		}
+		in = append(in, ft.In(i))
+	}
+	out := []reflect.Type{reflect.TypeOf(&OperationStatus{}), errorType}
+	wrapperType := reflect.FuncOf(in, out, ft.IsVariadic())
+
+	return reflect.MakeFunc(wrapperType, func(args []reflect.Value) []reflect.Value {
+		ctx := args[0].Interface().(context.Context)
+		status := &OperationStatus{
+			ID:        NewID(ctx),
+			State:     OperationPending,
+			CreatedAt: Now(ctx),
+		}
+		err := g.OperationStore.SaveOperation(ctx, status)
+		if err != nil {
+			return []reflect.Value{reflect.Zero(out[0]), reflect.ValueOf(&err).Elem()}
+		}
+
+		bgCtx := detachedValues{ctx}
+		callArgs := args[1:]
+		if hasCtx {
+			callArgs = append([]reflect.Value{reflect.ValueOf(context.Context(bgCtx))}, callArgs...)
+		}
+		result := *status
+		go g.runAsyncMutation(bgCtx, fv, callArgs, result)
+
+		return []reflect.Value{reflect.ValueOf(status), reflect.Zero(errorType)}
+	}).Interface()
+}
+
+// runAsyncMutation calls the function of an asynchronous mutation and saves its outcome.
+func (g *Graphy) runAsyncMutation(ctx context.Context, fv reflect.Value, args []reflect.Value, status OperationStatus) {
+	result, err := callAsyncMutation(fv, args)
+	if err == nil {
+		var payload []byte
+		payload, err = json.Marshal(result)
+		if err == nil {
+			s := string(payload)
+			status.Result = &s
+		}
+	}
+	if err != nil {
+		message := err.Error()
+		status.Error = &message
+		status.State = OperationFailed
+	} else {
+		status.State = OperationSucceeded
+	}
+	completed := Now(ctx)
+	status.CompletedAt = &completed
+
+	err = g.OperationStore.SaveOperation(ctx, &status)
+	if err != nil {
+		log.Printf("Error saving the status of operation %s: %v", status.ID, err)
+	}
+}
+
+func callAsyncMutation(fv reflect.Value, args []reflect.Value) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	var results []reflect.Value
+	if fv.Type().IsVariadic() {
+		results = fv.CallSlice(args)
+	} else {
+		results = fv.Call(args)
+	}
+	last := results[len(results)-1]
+	if last.Type() == errorType {
+		if !last.IsNil() {
+			return nil, last.Interface().(error)
+		}
+		if len(results) == 1 {
+			return nil, nil
+		}
+	}
+	return results[0].Interface(), nil
+}
+
+// detachedValues is a context with the values of another context, but without its
+// deadline and cancellation.
+type detachedValues struct {
+	parent context.Context
+}
+
+func (d detachedValues) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (d detachedValues) Done() <-chan struct{}       { return nil }
+func (d detachedValues) Err() error                  { return nil }
+func (d detachedValues) Value(key any) any           { return d.parent.Value(key) }
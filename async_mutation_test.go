@@ -0,0 +1,144 @@
+package quickgraph
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+type asyncReport struct {
+	Rows int `json:"rows"`
+}
+
+func waitForOperation(t *testing.T, g *Graphy, id string) *OperationStatus {
+	var status *OperationStatus
+	assert.Eventually(t, func() bool {
+		status, _ = g.OperationStore.LoadOperation(context.Background(), id)
+		return status != nil && status.State != OperationPending
+	}, time.Second, time.Millisecond)
+	return status
+}
+
+func TestAsyncMutation(t *testing.T) {
+	release := make(chan struct{})
+	ids := 0
+	g := Graphy{
+		TestingHooks: &TestingHooks{
+			Now: func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) },
+			NewID: func() string {
+				ids++
+				return "op-" + strings.Repeat("x", ids)
+			},
+		},
+	}
+	g.RegisterAsyncMutation(context.Background(), "buildReport", func(ctx context.Context, rows int) (asyncReport, error) {
+		<-release
+		if ctx.Err() != nil {
+			return asyncReport{}, ctx.Err()
+		}
+		if rows < 0 {
+			return asyncReport{}, errors.New("rows must not be negative")
+		}
+		return asyncReport{Rows: rows}, nil
+	}, "rows")
+
+	// The request's context is cancelled once it is done, which the mutation doesn't see.
+	ctx, cancel := context.WithCancel(context.Background())
+	result, err := g.ProcessRequest(ctx, `mutation { buildReport(rows: 3) { id state result } }`, "")
+	cancel()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"buildReport":{"id":"op-x","result":null,"state":"PENDING"}}}`, result)
+
+	result, err = g.ProcessRequest(context.Background(), `{ operation(id: "op-x") { state createdAt completedAt } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"operation":{"completedAt":null,"createdAt":"2024-01-02T03:04:05Z","state":"PENDING"}}}`, result)
+
+	close(release)
+	waitForOperation(t, &g, "op-x")
+	result, err = g.ProcessRequest(context.Background(), `{ operation(id: "op-x") { state result error completedAt } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"operation":{"completedAt":"2024-01-02T03:04:05Z","error":null,"result":"{\"rows\":3}","state":"SUCCEEDED"}}}`, result)
+
+	// Unknown operations are null.
+	result, err = g.ProcessRequest(context.Background(), `{ operation(id: "nope") { state } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"operation":null}}`, result)
+}
+
+func TestAsyncMutation_Failures(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+	ctx := context.Background()
+	ids := 0
+	g := Graphy{
+		TestingHooks: &TestingHooks{
+			Now: func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) },
+			NewID: func() string {
+				ids++
+				return "op-" + strings.Repeat("x", ids)
+			},
+		},
+	}
+	g.RegisterAsyncMutation(ctx, "buildReport", func(ctx context.Context, rows int) (asyncReport, error) {
+		<-release
+		if ctx.Err() != nil {
+			return asyncReport{}, ctx.Err()
+		}
+		if rows < 0 {
+			return asyncReport{}, errors.New("rows must not be negative")
+		}
+		return asyncReport{Rows: rows}, nil
+	}, "rows")
+	g.RegisterAsyncMutation(ctx, "explode", func() error {
+		<-release
+		panic("boom")
+	})
+
+	_, err := g.ProcessRequest(ctx, `mutation m($rows: Int!) { buildReport(rows: $rows) { id } }`, `{"rows": -1}`)
+	assert.NoError(t, err)
+	status := waitForOperation(t, &g, "op-x")
+	assert.Equal(t, OperationFailed, status.State)
+	assert.Equal(t, "rows must not be negative", *status.Error)
+
+	_, err = g.ProcessRequest(ctx, `mutation { explode { id } }`, "")
+	assert.NoError(t, err)
+	status = waitForOperation(t, &g, "op-xx")
+	assert.Equal(t, OperationFailed, status.State)
+	assert.Equal(t, "panic: boom", *status.Error)
+}
+
+func TestAsyncMutation_Schema(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterAsyncMutation(ctx, "buildReport", func(ctx context.Context, rows int) (asyncReport, error) {
+		return asyncReport{Rows: rows}, nil
+	}, "rows")
+	schema := g.SchemaDefinition(ctx)
+	assert.Contains(t, schema, "buildReport(rows: Int!): OperationStatus")
+	assert.Contains(t, schema, "operation(id: String!): OperationStatus")
+	assert.Contains(t, schema, "enum OperationState {")
+
+	assert.Panics(t, func() {
+		g.RegisterAsyncMutation(ctx, "bad", func() {})
+	})
+}
+
+func TestMemoryOperationStore_Retention(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryOperationStore()
+	store.Retention = time.Hour
+
+	old := time.Now().Add(-2 * time.Hour)
+	assert.NoError(t, store.SaveOperation(ctx, &OperationStatus{ID: "old", State: OperationSucceeded, CompletedAt: &old}))
+	assert.NoError(t, store.SaveOperation(ctx, &OperationStatus{ID: "pending", State: OperationPending}))
+	assert.NoError(t, store.SaveOperation(ctx, &OperationStatus{ID: "new", State: OperationPending}))
+
+	status, err := store.LoadOperation(ctx, "old")
+	assert.NoError(t, err)
+	assert.Nil(t, status)
+	status, _ = store.LoadOperation(ctx, "pending")
+	assert.NotNil(t, status)
+}
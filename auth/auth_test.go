@@ -0,0 +1,407 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/gburgyan/go-quickgraph"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNow = time.Unix(1700000000, 0)
+
+func encodeSegment(v any) string {
+	data, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func signHS256(secret []byte, header map[string]any, claims map[string]any) string {
+	signed := encodeSegment(header) + "." + encodeSegment(claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	signed := encodeSegment(map[string]any{"alg": "RS256", "kid": kid}) + "." + encodeSegment(claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func signES256(key *ecdsa.PrivateKey, claims map[string]any) string {
+	signed := encodeSegment(map[string]any{"alg": "ES256"}) + "." + encodeSegment(claims)
+	digest := sha256.Sum256([]byte(signed))
+	r, s, _ := ecdsa.Sign(rand.Reader, key, digest[:])
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestValidator_HMAC(t *testing.T) {
+	ctx := context.Background()
+	secret := []byte("secret")
+	v := &Validator{
+		Keys:     StaticKey(secret),
+		Issuer:   "https://issuer.example",
+		Audience: "api",
+		Now:      func() time.Time { return testNow },
+	}
+	header := map[string]any{"alg": "HS256", "typ": "JWT"}
+	claims := map[string]any{
+		"sub": "user-1",
+		"iss": "https://issuer.example",
+		"aud": []string{"other", "api"},
+		"exp": testNow.Add(time.Hour).Unix(),
+	}
+
+	token := signHS256(secret, header, claims)
+	result, err := v.Validate(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", result.Subject())
+	assert.Equal(t, []string{"other", "api"}, result.Audience())
+	exp, ok := result.Time("exp")
+	assert.True(t, ok)
+	assert.Equal(t, testNow.Add(time.Hour), exp)
+
+	_, err = v.Validate(ctx, signHS256([]byte("wrong"), header, claims))
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	_, err = v.Validate(ctx, "not.a.token")
+	assert.ErrorIs(t, err, ErrMalformedToken)
+
+	_, err = v.Validate(ctx, signHS256(secret, map[string]any{"alg": "none"}, claims))
+	assert.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+
+	expired := map[string]any{"iss": "https://issuer.example", "aud": "api", "exp": testNow.Unix()}
+	_, err = v.Validate(ctx, signHS256(secret, header, expired))
+	assert.ErrorIs(t, err, ErrExpired)
+
+	// The leeway allows for clock skew.
+	v.Leeway = time.Minute
+	_, err = v.Validate(ctx, signHS256(secret, header, expired))
+	assert.NoError(t, err)
+	v.Leeway = 0
+
+	notYet := map[string]any{"iss": "https://issuer.example", "aud": "api", "nbf": testNow.Add(time.Hour).Unix()}
+	_, err = v.Validate(ctx, signHS256(secret, header, notYet))
+	assert.ErrorIs(t, err, ErrNotYetValid)
+
+	_, err = v.Validate(ctx, signHS256(secret, header, map[string]any{"iss": "https://other.example", "aud": "api"}))
+	assert.ErrorIs(t, err, ErrInvalidIssuer)
+
+	_, err = v.Validate(ctx, signHS256(secret, header, map[string]any{"iss": "https://issuer.example", "aud": "other"}))
+	assert.ErrorIs(t, err, ErrInvalidAudience)
+
+	// Times that can't be converted don't wrap around, and make the token invalid.
+	farFuture := map[string]any{"iss": "https://issuer.example", "aud": "api", "exp": 1e19}
+	_, ok = Claims{"exp": json.Number("1e19")}.Time("exp")
+	assert.False(t, ok)
+	_, err = v.Validate(ctx, signHS256(secret, header, farFuture))
+	assert.ErrorIs(t, err, ErrMalformedToken)
+
+	exp, ok = Claims{"exp": json.Number("1700000000.5")}.Time("exp")
+	assert.True(t, ok)
+	assert.Equal(t, testNow.Add(500*time.Millisecond), exp)
+}
+
+func TestValidator_KeyTypeMustMatchAlgorithm(t *testing.T) {
+	ctx := context.Background()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	v := &Validator{Keys: StaticKey(&rsaKey.PublicKey)}
+	_, err := v.Validate(ctx, signRS256(rsaKey, "", map[string]any{"sub": "a"}))
+	assert.NoError(t, err)
+
+	// A token that claims to be signed with HMAC can't use the public key as the secret.
+	_, err = v.Validate(ctx, signHS256([]byte("anything"), map[string]any{"alg": "HS256"}, map[string]any{"sub": "a"}))
+	assert.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+
+	v = &Validator{Keys: StaticKey(&ecKey.PublicKey)}
+	claims, err := v.Validate(ctx, signES256(ecKey, map[string]any{"sub": "b"}))
+	assert.NoError(t, err)
+	assert.Equal(t, "b", claims.Subject())
+
+	// The curve of the key must be the one of the algorithm.
+	token := signES256(ecKey, map[string]any{"sub": "b"})
+	parts := strings.Split(token, ".")
+	token = encodeSegment(map[string]any{"alg": "ES384"}) + "." + parts[1] + "." + parts[2]
+	_, err = v.Validate(ctx, token)
+	assert.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+}
+
+func TestJWKS(t *testing.T) {
+	ctx := context.Background()
+	key1, _ := rsa.GenerateKey(rand.Reader, 2048)
+	key2, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	published := []*rsa.PrivateKey{key1}
+	fetches := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			fmt.Fprintf(w, `{"issuer":%q,"jwks_uri":%q}`, server.URL, server.URL+"/keys")
+		case "/keys":
+			fetches++
+			var keys []map[string]string
+			for i, key := range published {
+				keys = append(keys, map[string]string{
+					"kty": "RSA",
+					"kid": fmt.Sprintf("key%d", i+1),
+					"use": "sig",
+					"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	jwks, err := DiscoverJWKS(ctx, server.Client(), server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/keys", jwks.URL)
+	v := &Validator{Keys: jwks.Key}
+
+	_, err = v.Validate(ctx, signRS256(key1, "key1", map[string]any{"sub": "a"}))
+	assert.NoError(t, err)
+	_, err = v.Validate(ctx, signRS256(key1, "key1", map[string]any{"sub": "a"}))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fetches)
+
+	// A rotated key is picked up once the refresh interval has passed.
+	published = append(published, key2)
+	_, err = v.Validate(ctx, signRS256(key2, "key2", map[string]any{"sub": "a"}))
+	assert.Error(t, err)
+	assert.Equal(t, 1, fetches)
+
+	jwks.MinRefreshInterval = time.Nanosecond
+	_, err = v.Validate(ctx, signRS256(key2, "key2", map[string]any{"sub": "a"}))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fetches)
+}
+
+func TestJWKS_SlowFetch(t *testing.T) {
+	ctx := context.Background()
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	release := make(chan struct{})
+	slow := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slow {
+			<-release
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "key1",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	defer server.Close()
+
+	jwks := &JWKS{URL: server.URL, Client: server.Client(), MinRefreshInterval: time.Nanosecond}
+	v := &Validator{Keys: jwks.Key}
+	_, err := v.Validate(ctx, signRS256(key, "key1", map[string]any{"sub": "a"}))
+	assert.NoError(t, err)
+
+	// A token with an unknown key makes a fetch that takes a while.
+	slow = true
+	unknown := make(chan error)
+	go func() {
+		_, err := v.Validate(ctx, signRS256(key, "key2", map[string]any{"sub": "a"}))
+		unknown <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// Tokens with known keys are validated in the meantime.
+	_, err = v.Validate(ctx, signRS256(key, "key1", map[string]any{"sub": "a"}))
+	assert.NoError(t, err)
+
+	close(release)
+	assert.ErrorContains(t, <-unknown, "unknown key key2")
+}
+
+func TestJWKS_FailedFetch(t *testing.T) {
+	ctx := context.Background()
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	failing := true
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		if failing {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "key1",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	defer server.Close()
+
+	jwks := &JWKS{URL: server.URL, Client: server.Client(), MinRefreshInterval: time.Hour}
+	v := &Validator{Keys: jwks.Key}
+	_, err := v.Validate(ctx, signRS256(key, "key1", map[string]any{"sub": "a"}))
+	assert.ErrorContains(t, err, "503 Service Unavailable")
+	assert.Equal(t, 1, fetches)
+
+	// The provider isn't asked again until the refresh interval has passed.
+	failing = false
+	_, err = v.Validate(ctx, signRS256(key, "key1", map[string]any{"sub": "a"}))
+	assert.ErrorContains(t, err, "503 Service Unavailable")
+	assert.Equal(t, 1, fetches)
+
+	jwks.MinRefreshInterval = time.Nanosecond
+	_, err = v.Validate(ctx, signRS256(key, "key1", map[string]any{"sub": "a"}))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fetches)
+
+	// A failed fetch keeps the keys that are known.
+	failing = true
+	_, err = v.Validate(ctx, signRS256(key, "key2", map[string]any{"sub": "a"}))
+	assert.ErrorContains(t, err, "503 Service Unavailable")
+	assert.Equal(t, 3, fetches)
+	_, err = v.Validate(ctx, signRS256(key, "key1", map[string]any{"sub": "a"}))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, fetches)
+}
+
+func TestJWKS_KeysWithoutID(t *testing.T) {
+	ctx := context.Background()
+	key1, _ := rsa.GenerateKey(rand.Reader, 2048)
+	key2, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var keys []map[string]string
+		for _, key := range []*rsa.PrivateKey{key1, key2} {
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		keys = append(keys, map[string]string{
+			"kty": "EC",
+			"crv": "P-256",
+			"x":   base64.RawURLEncoding.EncodeToString(ecKey.X.Bytes()),
+			"y":   base64.RawURLEncoding.EncodeToString(ecKey.Y.Bytes()),
+		})
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	}))
+	defer server.Close()
+
+	jwks := &JWKS{URL: server.URL, Client: server.Client()}
+	v := &Validator{Keys: jwks.Key}
+
+	// Every key is tried for tokens without a key ID, not just the last one.
+	_, err := v.Validate(ctx, signRS256(key1, "", map[string]any{"sub": "a"}))
+	assert.NoError(t, err)
+	_, err = v.Validate(ctx, signRS256(key2, "", map[string]any{"sub": "a"}))
+	assert.NoError(t, err)
+	_, err = v.Validate(ctx, signES256(ecKey, map[string]any{"sub": "a"}))
+	assert.NoError(t, err)
+
+	_, err = v.Validate(ctx, signRS256(other, "", map[string]any{"sub": "a"}))
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+type authTestUser struct {
+	ID string
+}
+
+type authTestUserKey struct{}
+
+func TestMiddleware(t *testing.T) {
+	ctx := context.Background()
+	secret := []byte("secret")
+
+	g := quickgraph.Graphy{}
+	g.RegisterQuery(ctx, "whoami", func(ctx context.Context) string {
+		claims, ok := ClaimsFromContext(ctx)
+		if !ok {
+			return "anonymous"
+		}
+		user := ctx.Value(authTestUserKey{}).(authTestUser)
+		return claims.Subject() + "/" + user.ID
+	})
+
+	m := &Middleware{
+		Validator: &Validator{Keys: StaticKey(secret)},
+		ContextMapper: func(ctx context.Context, claims Claims) (context.Context, error) {
+			return context.WithValue(ctx, authTestUserKey{}, authTestUser{ID: "u-" + claims.Subject()}), nil
+		},
+	}
+	handler := m.Handler(g.HttpHandler())
+
+	serve := func(authorization string) (int, string, string) {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(`{"query":"{ whoami }"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		res := rec.Result()
+		body, _ := io.ReadAll(res.Body)
+		return res.StatusCode, res.Header.Get("WWW-Authenticate"), string(body)
+	}
+
+	token := signHS256(secret, map[string]any{"alg": "HS256"}, map[string]any{"sub": "alice"})
+	status, _, body := serve("Bearer " + token)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"data":{"whoami":"alice/u-alice"}}`, body)
+
+	status, challenge, _ := serve("")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, "Bearer", challenge)
+
+	status, challenge, _ = serve("Bearer " + signHS256([]byte("wrong"), map[string]any{"alg": "HS256"}, map[string]any{"sub": "alice"}))
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, `Bearer error="invalid_token"`, challenge)
+
+	// Optional authentication lets anonymous requests through, but not invalid tokens.
+	m.Optional = true
+	status, _, body = serve("")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"data":{"whoami":"anonymous"}}`, body)
+
+	status, _, _ = serve("Bearer garbage")
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestRolesFromClaim(t *testing.T) {
+	roles := RolesFromClaim("roles")
+	assert.Nil(t, roles(context.Background()))
+
+	ctx := WithClaims(context.Background(), Claims{"roles": []any{"admin", 7, "editor"}})
+	assert.Equal(t, []string{"admin", "editor"}, roles(ctx))
+
+	ctx = WithClaims(context.Background(), Claims{"roles": "reader"})
+	assert.Equal(t, []string{"reader"}, roles(ctx))
+}
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// Claims are the claims of a validated token.
+type Claims map[string]any
+
+type claimsContextKey struct{}
+
+// WithClaims returns a context that carries the claims. Middleware does this for each
+// request with a valid token.
+func WithClaims(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims of the token that the current request was
+// authenticated with. The second return value is false if the request has no token.
+func ClaimsFromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(Claims)
+	return claims, ok
+}
+
+// String returns the value of a claim if it is a string, or "" otherwise.
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Subject returns the "sub" claim.
+func (c Claims) Subject() string {
+	return c.String("sub")
+}
+
+// Issuer returns the "iss" claim.
+func (c Claims) Issuer() string {
+	return c.String("iss")
+}
+
+// Audience returns the "aud" claim.
+func (c Claims) Audience() []string {
+	return c.Strings("aud")
+}
+
+// Strings returns the value of a claim that may be either a single string or a list of
+// strings; either way it is returned as a list. Values that aren't strings are skipped.
+func (c Claims) Strings(name string) []string {
+	switch v := c[name].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var result []string
+		for _, a := range v {
+			if s, ok := a.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// RolesFromClaim returns a function that reads the roles of a caller from a claim of
+// their token, such as "roles" or "groups". It can be used as the Roles of a
+// quickgraph.OperationAccess.
+func RolesFromClaim(name string) func(ctx context.Context) []string {
+	return func(ctx context.Context) []string {
+		claims, _ := ClaimsFromContext(ctx)
+		return claims.Strings(name)
+	}
+}
+
+// The range of the times that claims can hold, which is the years 1 through 9999. Numbers
+// outside of it can't be converted to times without overflowing.
+const (
+	minClaimTime = -62135596800
+	maxClaimTime = 253402300799
+)
+
+// Time returns the value of a claim that holds a time as the number of seconds since the
+// epoch, such as "exp" or "iat". It returns false if the claim isn't a number of seconds
+// between the years 1 and 9999.
+func (c Claims) Time(name string) (time.Time, bool) {
+	n, ok := c[name].(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	seconds, err := n.Float64()
+	if err != nil || math.IsNaN(seconds) || seconds < minClaimTime || seconds > maxClaimTime {
+		return time.Time{}, false
+	}
+	whole, frac := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(frac*float64(time.Second))), true
+}
@@ -0,0 +1,281 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JWKS provides the keys from a JSON Web Key Set, as published by OpenID Connect
+// providers. The keys are fetched when they are first needed, and fetched again when a
+// token refers to a key that isn't known yet, so that key rotation is picked up. If a
+// fetch fails, the keys that were fetched before are kept.
+type JWKS struct {
+	// URL is the location of the key set.
+	URL string
+
+	// Client is used to fetch the key set. If it is nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// MinRefreshInterval is the minimum time between fetches of the key set, so that
+	// tokens with unknown key IDs can't cause a fetch for every request, and a provider
+	// that is down isn't asked again for every request either. If it is zero, the key set
+	// is fetched at most once a minute.
+	MinRefreshInterval time.Duration
+
+	mu   sync.Mutex
+	keys map[string]any
+	// unnamed are the keys that have no key ID.
+	unnamed []any
+	// fetchedAt is when the key set was last fetched, whether that succeeded or not, and
+	// fetchErr is the error if it didn't.
+	fetchedAt time.Time
+	fetchErr  error
+
+	// fetching is closed when the fetch that is in progress, if any, is done. The fetch
+	// is made without holding mu so that the keys that are known can still be used.
+	fetching chan struct{}
+}
+
+// DiscoverJWKS finds the key set of an OpenID Connect provider from the discovery
+// document of the issuer.
+func DiscoverJWKS(ctx context.Context, client *http.Client, issuer string) (*JWKS, error) {
+	var config struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	err := getJSON(ctx, client, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", &config)
+	if err != nil {
+		return nil, err
+	}
+	if config.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document of %s has no jwks_uri", issuer)
+	}
+	return &JWKS{URL: config.JWKSURI, Client: client}, nil
+}
+
+// Key returns the key that matches the key ID of the header. Tokens without a key ID
+// may be signed with any of the keys, so for those it returns the keys that can be used
+// with the algorithm of the token. It can be used as the Keys of a Validator.
+func (j *JWKS) Key(ctx context.Context, header Header) (any, error) {
+	j.mu.Lock()
+	if key, ok := j.lookup(header); ok {
+		j.mu.Unlock()
+		return key, nil
+	}
+
+	interval := j.MinRefreshInterval
+	if interval == 0 {
+		interval = time.Minute
+	}
+	if !j.fetchedAt.IsZero() && time.Since(j.fetchedAt) < interval {
+		defer j.mu.Unlock()
+		return nil, j.missingKey(header)
+	}
+
+	if done := j.fetching; done != nil {
+		// Another request is fetching the key set already, so wait for it.
+		j.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	} else {
+		done = make(chan struct{})
+		j.fetching = done
+		j.mu.Unlock()
+
+		keys, unnamed, err := j.fetch(ctx)
+
+		j.mu.Lock()
+		if err == nil {
+			j.keys = keys
+			j.unnamed = unnamed
+		}
+		j.fetchedAt = time.Now()
+		j.fetchErr = err
+		j.fetching = nil
+		close(done)
+		j.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if key, ok := j.lookup(header); ok {
+		return key, nil
+	}
+	return nil, j.missingKey(header)
+}
+
+// lookup finds the key for the header in the keys that are known. If the header has no
+// key ID and more than one key can be used with its algorithm, the key is a []any of all
+// of them. It must be called with mu held.
+func (j *JWKS) lookup(header Header) (any, bool) {
+	if header.KeyID != "" {
+		key, ok := j.keys[header.KeyID]
+		return key, ok
+	}
+
+	var candidates []any
+	for _, key := range j.keys {
+		if usableWith(key, header.Algorithm) {
+			candidates = append(candidates, key)
+		}
+	}
+	for _, key := range j.unnamed {
+		if usableWith(key, header.Algorithm) {
+			candidates = append(candidates, key)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return nil, false
+	case 1:
+		return candidates[0], true
+	}
+	return candidates, true
+}
+
+// missingKey returns the error for a header that has no key. If the last fetch failed,
+// that is the reason. It must be called with mu held.
+func (j *JWKS) missingKey(header Header) error {
+	if j.fetchErr != nil {
+		return j.fetchErr
+	}
+	if header.KeyID == "" {
+		return fmt.Errorf("no key for algorithm %s", header.Algorithm)
+	}
+	return fmt.Errorf("unknown key %s", header.KeyID)
+}
+
+// usableWith reports whether the key can verify the signatures of the algorithm.
+func usableWith(key any, algorithm string) bool {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return strings.HasPrefix(algorithm, "RS")
+	case *ecdsa.PublicKey:
+		return ecdsaCurves[algorithm] == key.Curve
+	}
+	return false
+}
+
+// jsonWebKey holds the members of a JSON Web Key that are needed for RSA and EC keys.
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+func (j *JWKS) fetch(ctx context.Context) (map[string]any, []any, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	err := getJSON(ctx, j.Client, j.URL, &set)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keys := map[string]any{}
+	var unnamed []any
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, nil, fmt.Errorf("error parsing key %s: %w", jwk.KeyID, err)
+		}
+		if key == nil {
+			continue
+		}
+		if jwk.KeyID == "" {
+			unnamed = append(unnamed, key)
+		} else {
+			keys[jwk.KeyID] = key
+		}
+	}
+	return keys, unnamed, nil
+}
+
+// publicKey converts the JSON Web Key to a public key. Key types that aren't supported
+// return nil without an error so that they don't prevent the other keys from being used.
+func (jwk jsonWebKey) publicKey() (any, error) {
+	switch jwk.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, nil
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, target any) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error fetching %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
@@ -0,0 +1,269 @@
+// Package auth provides optional helpers for authenticating GraphQL requests with JSON
+// Web Tokens (JWTs), such as the ID and access tokens that are issued by OpenID Connect
+// providers.
+//
+// A Validator checks the signature and the standard claims of a token. Middleware wraps
+// the HTTP handler of a Graphy instance so that the claims of the bearer token of each
+// request are available to the functions through ClaimsFromContext.
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrMalformedToken is returned for tokens that are not well-formed JWTs.
+	ErrMalformedToken = errors.New("malformed token")
+	// ErrInvalidSignature is returned when the signature of a token doesn't match.
+	ErrInvalidSignature = errors.New("invalid token signature")
+	// ErrUnsupportedAlgorithm is returned for tokens that are signed with an algorithm that
+	// isn't supported, including the "none" algorithm.
+	ErrUnsupportedAlgorithm = errors.New("unsupported token algorithm")
+	// ErrExpired is returned for tokens that have expired.
+	ErrExpired = errors.New("token has expired")
+	// ErrNotYetValid is returned for tokens that are not valid yet.
+	ErrNotYetValid = errors.New("token is not valid yet")
+	// ErrInvalidIssuer is returned when the issuer of a token is not the expected one.
+	ErrInvalidIssuer = errors.New("invalid token issuer")
+	// ErrInvalidAudience is returned when a token is not meant for the expected audience.
+	ErrInvalidAudience = errors.New("invalid token audience")
+)
+
+// Header is the header of a JWT.
+type Header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid,omitempty"`
+	Type      string `json:"typ,omitempty"`
+}
+
+// KeyFunc returns the key that is used to verify the signature of a token with the given
+// header. The key is a []byte for the HMAC algorithms (HS256, HS384, HS512), an
+// *rsa.PublicKey for the RSA algorithms (RS256, RS384, RS512), and an *ecdsa.PublicKey for
+// the ECDSA algorithms (ES256, ES384, ES512). If it can't tell which of several keys the
+// token was signed with, it can return them as a []any, and the signature is accepted if
+// any of them verifies it.
+type KeyFunc func(ctx context.Context, header Header) (any, error)
+
+// StaticKey returns a KeyFunc that always returns the same key.
+func StaticKey(key any) KeyFunc {
+	return func(ctx context.Context, header Header) (any, error) {
+		return key, nil
+	}
+}
+
+// Validator validates JWTs.
+type Validator struct {
+	// Keys provides the key to verify the signature of each token. For OpenID Connect
+	// providers, use the Key method of a JWKS to get the keys from the provider.
+	Keys KeyFunc
+
+	// Issuer, if set, is the value that the "iss" claim of the tokens must have.
+	Issuer string
+
+	// Audience, if set, is a value that the "aud" claim of the tokens must contain.
+	Audience string
+
+	// Leeway is the amount of clock skew that is allowed when checking the "exp" and
+	// "nbf" claims.
+	Leeway time.Duration
+
+	// Now returns the current time. If it is nil, time.Now is used.
+	Now func() time.Time
+}
+
+// Validate parses the token, verifies its signature and checks its "exp", "nbf", "iss",
+// and "aud" claims. It returns the claims of the token if it is valid.
+func (v *Validator) Validate(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+
+	var header Header
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	if v.Keys == nil {
+		return nil, errors.New("no keys configured for the validator")
+	}
+	key, err := v.Keys(ctx, header)
+	if err != nil {
+		return nil, fmt.Errorf("error getting the key for the token: %w", err)
+	}
+	err = verifySignatureWithAny(header.Algorithm, parts[0]+"."+parts[1], signature, key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = v.checkClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (v *Validator) checkClaims(claims Claims) error {
+	now := time.Now()
+	if v.Now != nil {
+		now = v.Now()
+	}
+	// A time that is there but can't be read would otherwise be ignored, making the token
+	// valid forever.
+	exp, hasExp := claims.Time("exp")
+	nbf, hasNbf := claims.Time("nbf")
+	if _, ok := claims["exp"]; ok && !hasExp {
+		return ErrMalformedToken
+	}
+	if _, ok := claims["nbf"]; ok && !hasNbf {
+		return ErrMalformedToken
+	}
+	if hasExp && !now.Before(exp.Add(v.Leeway)) {
+		return ErrExpired
+	}
+	if hasNbf && now.Add(v.Leeway).Before(nbf) {
+		return ErrNotYetValid
+	}
+	if v.Issuer != "" && claims.Issuer() != v.Issuer {
+		return ErrInvalidIssuer
+	}
+	if v.Audience != "" {
+		found := false
+		for _, aud := range claims.Audience() {
+			if aud == v.Audience {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ErrInvalidAudience
+		}
+	}
+	return nil
+}
+
+func decodeSegment(segment string, target any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrMalformedToken
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	if err := decoder.Decode(target); err != nil {
+		return ErrMalformedToken
+	}
+	return nil
+}
+
+// ecdsaCurves are the curves of the ECDSA algorithms.
+var ecdsaCurves = map[string]elliptic.Curve{
+	"ES256": elliptic.P256(),
+	"ES384": elliptic.P384(),
+	"ES512": elliptic.P521(),
+}
+
+// verifySignatureWithAny checks the signature with the key, or, if the key is a []any,
+// with each of the keys until one of them verifies it.
+func verifySignatureWithAny(algorithm, signed string, signature []byte, key any) error {
+	keys, ok := key.([]any)
+	if !ok {
+		return verifySignature(algorithm, signed, signature, key)
+	}
+	err := ErrInvalidSignature
+	for _, key := range keys {
+		if err = verifySignature(algorithm, signed, signature, key); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// verifySignature checks the signature of the signed part of a token. The type of the key
+// must match the algorithm so that a public key can't be used as an HMAC secret.
+func verifySignature(algorithm, signed string, signature []byte, key any) error {
+	if len(algorithm) != 5 {
+		return ErrUnsupportedAlgorithm
+	}
+	var hash crypto.Hash
+	switch algorithm[2:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return ErrUnsupportedAlgorithm
+	}
+	hasher := hash.New()
+	hasher.Write([]byte(signed))
+	digest := hasher.Sum(nil)
+
+	switch algorithm[:2] {
+	case "HS":
+		secret, ok := key.([]byte)
+		if !ok {
+			return fmt.Errorf("%w: %s needs a []byte key", ErrUnsupportedAlgorithm, algorithm)
+		}
+		mac := hmac.New(hash.New, secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrInvalidSignature
+		}
+	case "RS":
+		publicKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: %s needs an *rsa.PublicKey", ErrUnsupportedAlgorithm, algorithm)
+		}
+		if rsa.VerifyPKCS1v15(publicKey, hash, digest, signature) != nil {
+			return ErrInvalidSignature
+		}
+	case "ES":
+		publicKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: %s needs an *ecdsa.PublicKey", ErrUnsupportedAlgorithm, algorithm)
+		}
+		// Each of the algorithms is for one curve, so that a key can only be used with one.
+		curve, ok := ecdsaCurves[algorithm]
+		if !ok {
+			return ErrUnsupportedAlgorithm
+		}
+		if publicKey.Curve != curve {
+			return fmt.Errorf("%w: %s needs a key on the %s curve", ErrUnsupportedAlgorithm, algorithm, curve.Params().Name)
+		}
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return ErrInvalidSignature
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(publicKey, digest, r, s) {
+			return ErrInvalidSignature
+		}
+	default:
+		return ErrUnsupportedAlgorithm
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Middleware authenticates HTTP requests with the bearer token in their Authorization
+// header before passing them on to the next handler, typically the one that is returned
+// from Graphy.HttpHandler.
+type Middleware struct {
+	// Validator validates the tokens.
+	Validator *Validator
+
+	// Optional lets requests without an Authorization header through without claims, so
+	// that the functions can decide what anonymous clients are allowed to do. Requests
+	// with an invalid token are always rejected.
+	Optional bool
+
+	// ContextMapper, if set, is called with the claims of each authenticated request. The
+	// context that it returns is used for the request, which allows the claims to be
+	// mapped to the application's own representation of the user.
+	ContextMapper func(ctx context.Context, claims Claims) (context.Context, error)
+}
+
+// Handler wraps the next handler. Requests that can't be authenticated get a 401
+// Unauthorized response with a WWW-Authenticate header.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		if authorization == "" && m.Optional {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := bearerToken(authorization)
+		if !ok {
+			unauthorized(w, "")
+			return
+		}
+		ctx := r.Context()
+		claims, err := m.Validator.Validate(ctx, token)
+		if err != nil {
+			unauthorized(w, "invalid_token")
+			return
+		}
+		ctx = WithClaims(ctx, claims)
+		if m.ContextMapper != nil {
+			ctx, err = m.ContextMapper(ctx, claims)
+			if err != nil {
+				unauthorized(w, "invalid_token")
+				return
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// bearerToken extracts the token from an Authorization header that uses the Bearer scheme.
+func bearerToken(authorization string) (string, bool) {
+	scheme, token, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func unauthorized(w http.ResponseWriter, errorCode string) {
+	challenge := "Bearer"
+	if errorCode != "" {
+		challenge += ` error="` + errorCode + `"`
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
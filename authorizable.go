@@ -0,0 +1,118 @@
+package quickgraph
+
+import (
+	"context"
+	"fmt"
+	"github.com/alecthomas/participle/v2/lexer"
+	"reflect"
+	"sync"
+)
+
+// Authorizable is implemented by output types that decide themselves who may see them,
+// which keeps row-level security in one place instead of in each function that returns
+// the type. CanView is called for each object before its fields are generated. An object
+// that the caller may not view fails with an error with the UNAUTHORIZED code, unless it
+// is an element of a list; those are left out of the list or replaced with null, according
+// to Graphy.UnauthorizedListItems.
+type Authorizable interface {
+	CanView(ctx context.Context) (bool, error)
+}
+
+// UnauthorizedListItemPolicy is what happens to the objects in lists that the caller may
+// not view.
+type UnauthorizedListItemPolicy int
+
+const (
+	// UnauthorizedItemsSkipped leaves the objects out of the list. This is the default.
+	UnauthorizedItemsSkipped UnauthorizedListItemPolicy = iota
+
+	// UnauthorizedItemsNull replaces the objects with null, so that the list keeps its
+	// length.
+	UnauthorizedItemsNull
+)
+
+var authorizableType = reflect.TypeOf((*Authorizable)(nil)).Elem()
+
+// authorizableKind is how the values of a type implement Authorizable.
+type authorizableKind int
+
+const (
+	notAuthorizable authorizableKind = iota
+	authorizableValue
+	authorizablePointer
+)
+
+// authorizableKinds caches the authorizableKind of each struct type.
+var authorizableKinds sync.Map
+
+func authorizableKindOf(typ reflect.Type) authorizableKind {
+	if kind, ok := authorizableKinds.Load(typ); ok {
+		return kind.(authorizableKind)
+	}
+	kind := notAuthorizable
+	if typ.Implements(authorizableType) {
+		kind = authorizableValue
+	} else if reflect.PointerTo(typ).Implements(authorizableType) {
+		kind = authorizablePointer
+	}
+	authorizableKinds.Store(typ, kind)
+	return kind
+}
+
+// unauthorizedObjectError is the error for an object that the caller may not view. The
+// functions that process lists look for it to leave the object out; elsewhere it is the
+// GraphError that it holds.
+type unauthorizedObjectError struct {
+	gErr GraphError
+}
+
+func (e unauthorizedObjectError) Error() string {
+	return e.gErr.Error()
+}
+
+func (e unauthorizedObjectError) As(target any) bool {
+	if gErr, ok := target.(*GraphError); ok {
+		*gErr = e.gErr
+		return true
+	}
+	return false
+}
+
+// checkCanView checks whether the caller may view a struct. The pointer is the value that
+// the struct was reached through, if it was reached through one, so that CanView can be
+// implemented on the pointer type without copying the struct.
+func checkCanView(ctx context.Context, value, pointer reflect.Value, pos lexer.Position) error {
+	var authorizable Authorizable
+	switch authorizableKindOf(value.Type()) {
+	case notAuthorizable:
+		return nil
+	case authorizableValue:
+		authorizable = value.Interface().(Authorizable)
+	case authorizablePointer:
+		if !pointer.IsValid() {
+			pointer = reflect.New(value.Type())
+			pointer.Elem().Set(value)
+		}
+		authorizable = pointer.Interface().(Authorizable)
+	}
+
+	allowed, err := authorizable.CanView(ctx)
+	if err != nil {
+		return AugmentGraphError(err, fmt.Sprintf("error checking access to %s", value.Type().Name()), pos)
+	}
+	if !allowed {
+		gErr := NewGraphError(fmt.Sprintf("not authorized to view %s", value.Type().Name()), pos)
+		gErr.AddExtension("code", ErrorCodeUnauthorized)
+		return unauthorizedObjectError{gErr: gErr}
+	}
+	return nil
+}
+
+// unauthorizedListItem returns what to put in a list in place of an object that the caller
+// may not view, and whether to put anything at all. It returns false for other errors.
+func (g *Graphy) unauthorizedListItem(err error) (include bool, ok bool) {
+	if _, ok := err.(unauthorizedObjectError); !ok {
+		return false, false
+	}
+	return g.UnauthorizedListItems == UnauthorizedItemsNull, true
+}
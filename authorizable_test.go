@@ -0,0 +1,101 @@
+package quickgraph
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type authzViewerKey struct{}
+
+type authzDocument struct {
+	Id    string
+	Owner string
+}
+
+func (d *authzDocument) CanView(ctx context.Context) (bool, error) {
+	viewer, _ := ctx.Value(authzViewerKey{}).(string)
+	if viewer == "" {
+		return false, errors.New("no viewer")
+	}
+	return viewer == "admin" || viewer == d.Owner, nil
+}
+
+type authzFolder struct {
+	Name      string
+	Documents []authzDocument
+}
+
+func TestAuthorizable(t *testing.T) {
+	ctx := context.WithValue(context.Background(), authzViewerKey{}, "ada")
+	g := Graphy{}
+	documents := []authzDocument{{Id: "1", Owner: "ada"}, {Id: "2", Owner: "bob"}, {Id: "3", Owner: "ada"}}
+	g.RegisterQuery(ctx, "documents", func() []authzDocument {
+		return documents
+	})
+	g.RegisterQuery(ctx, "document", func(id string) *authzDocument {
+		for _, d := range documents {
+			if d.Id == id {
+				return &d
+			}
+		}
+		return nil
+	}, "id")
+	g.RegisterQuery(ctx, "folder", func() authzFolder {
+		return authzFolder{Name: "all", Documents: documents}
+	})
+
+	result, err := g.ProcessRequest(ctx, `{ documents { Id } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"documents":[{"Id":"1"},{"Id":"3"}]}}`, result)
+
+	result, err = g.ProcessRequest(ctx, `{ folder { Name Documents { Id } } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"folder":{"Documents":[{"Id":"1"},{"Id":"3"}],"Name":"all"}}}`, result)
+
+	result, err = g.ProcessRequest(ctx, `{ document(id: "1") { Id } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"document":{"Id":"1"}}}`, result)
+
+	result, err = g.ProcessRequest(ctx, `{ document(id: "2") { Id } }`, "")
+	assert.Error(t, err)
+	assert.Equal(t, `{"data":{},"errors":[{"message":"not authorized to view authzDocument","locations":[{"line":1,"column":23}],"path":["document"],"extensions":{"code":"UNAUTHORIZED"}}]}`, result)
+
+	// Errors from CanView fail the field.
+	_, err = g.ProcessRequest(context.Background(), `{ document(id: "1") { Id } }`, "")
+	assert.ErrorContains(t, err, "no viewer")
+
+	// The schema doesn't include CanView.
+	schema := g.SchemaDefinition(ctx)
+	assert.NotContains(t, schema, "CanView")
+}
+
+func TestAuthorizable_NullItems(t *testing.T) {
+	ctx := context.WithValue(context.Background(), authzViewerKey{}, "bob")
+	g := Graphy{UnauthorizedListItems: UnauthorizedItemsNull}
+	documents := []authzDocument{{Id: "1", Owner: "ada"}, {Id: "2", Owner: "bob"}, {Id: "3", Owner: "ada"}}
+	g.RegisterQuery(ctx, "documents", func() []authzDocument {
+		return documents
+	})
+
+	result, err := g.ProcessRequest(ctx, `{ documents { Id } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"documents":[null,{"Id":"2"},null]}}`, result)
+}
+
+func TestAuthorizable_Concurrent(t *testing.T) {
+	ctx := context.WithValue(context.Background(), authzViewerKey{}, "ada")
+	g := Graphy{QueryLimits: &QueryLimits{MaxConcurrentResolvers: 4}}
+	documents := []authzDocument{{Id: "1", Owner: "ada"}, {Id: "2", Owner: "bob"}, {Id: "3", Owner: "ada"}}
+	g.RegisterQuery(ctx, "documents", func() []authzDocument {
+		return documents
+	})
+	g.RegisterTypeField(ctx, authzDocument{}, "Title", func(d authzDocument) string {
+		return "Document " + d.Id
+	})
+
+	result, err := g.ProcessRequest(ctx, `{ documents { Title } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"documents":[{"Title":"Document 1"},{"Title":"Document 3"}]}}`, result)
+}
@@ -0,0 +1,226 @@
+package quickgraph
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// RegisterBatchField adds a virtual field to an existing type, like RegisterTypeField,
+// that is resolved for all the elements of a list at once. This avoids fetching the
+// field separately for each element of a list, the N+1 problem, without a dataloader:
+//
+//	g.RegisterBatchField(ctx, Post{}, "author", func(ctx context.Context, posts []Post) ([]*User, error) {
+//		return usersByIds(ctx, authorIds(posts))
+//	})
+//
+// The function takes a slice of the values, or of pointers to them, optionally preceded
+// by a context.Context. It returns the value of the field for each of the values, in the
+// same order, and may return an error. When the field is selected on the elements of a
+// slice, the function is called once for all of them; elsewhere it is called with just
+// the one value. Batch fields don't take arguments. Invalid functions cause a panic.
+func (g *Graphy) RegisterBatchField(ctx context.Context, typ any, name string, f any) {
+	baseType := reflect.TypeOf(typ)
+	if baseType.Kind() == reflect.Ptr {
+		baseType = baseType.Elem()
+	}
+	bf := newBatchField(name, baseType, reflect.ValueOf(f))
+
+	g.structureLock.Lock()
+	if g.batchFields == nil {
+		g.batchFields = map[reflect.Type]map[string]*batchField{}
+	}
+	if g.batchFields[baseType] == nil {
+		g.batchFields[baseType] = map[string]*batchField{}
+	}
+	g.batchFields[baseType][name] = bf
+	g.structureLock.Unlock()
+
+	g.RegisterTypeField(ctx, typ, name, bf.single().Interface())
+}
+
+// batchField is a field that was registered with RegisterBatchField.
+type batchField struct {
+	name         string
+	fn           reflect.Value
+	takesContext bool
+
+	// itemType is the type of the elements of the slice that the function takes, which
+	// is either the type of the field or a pointer to it.
+	itemType   reflect.Type
+	resultType reflect.Type
+}
+
+func newBatchField(name string, baseType reflect.Type, fn reflect.Value) *batchField {
+	ft := fn.Type()
+	if ft.Kind() != reflect.Func {
+		panic(fmt.Sprintf("batch field %s: %v is not a func", name, ft))
+	}
+	bf := &batchField{name: name, fn: fn}
+
+	in := 0
+	if ft.NumIn() == 2 && ft.In(0).ConvertibleTo(contextType) {
+		bf.takesContext = true
+		in = 1
+	}
+	if ft.NumIn() != in+1 || ft.In(in).Kind() != reflect.Slice ||
+		(ft.In(in).Elem() != baseType && ft.In(in).Elem() != reflect.PtrTo(baseType)) {
+		panic(fmt.Sprintf("batch field %s: the function must take a []%v or []*%v, optionally after a context", name, baseType, baseType))
+	}
+	bf.itemType = ft.In(in).Elem()
+
+	if ft.NumOut() == 0 || ft.NumOut() > 2 || ft.Out(0).Kind() != reflect.Slice ||
+		(ft.NumOut() == 2 && ft.Out(1) != errorType) {
+		panic(fmt.Sprintf("batch field %s: the function must return a slice, optionally followed by an error", name))
+	}
+	bf.resultType = ft.Out(0).Elem()
+	return bf
+}
+
+// single returns a function for RegisterTypeField that resolves the field for a single
+// value by calling the batch function with just that value.
+func (bf *batchField) single() reflect.Value {
+	ft := reflect.FuncOf([]reflect.Type{contextType, bf.itemType}, []reflect.Type{bf.resultType, errorType}, false)
+	return reflect.MakeFunc(ft, func(args []reflect.Value) []reflect.Value {
+		values, err := bf.call(args[0].Interface().(context.Context), []reflect.Value{args[1]})
+		if err != nil {
+			return []reflect.Value{reflect.Zero(bf.resultType), reflect.ValueOf(&err).Elem()}
+		}
+		return []reflect.Value{values.Index(0), reflect.Zero(errorType)}
+	})
+}
+
+// call calls the batch function with the items and returns the values of the field for
+// them.
+func (bf *batchField) call(ctx context.Context, items []reflect.Value) (reflect.Value, error) {
+	slice := reflect.MakeSlice(reflect.SliceOf(bf.itemType), len(items), len(items))
+	for i, item := range items {
+		slice.Index(i).Set(item)
+	}
+	ctx, hintCall := startCacheHintCall(ctx)
+	args := []reflect.Value{slice}
+	if bf.takesContext {
+		args = []reflect.Value{reflect.ValueOf(ctx), slice}
+	}
+
+	out := bf.fn.Call(args)
+	hintCall.end()
+	if len(out) == 2 && !out[1].IsNil() {
+		return reflect.Value{}, out[1].Interface().(error)
+	}
+	if out[0].Len() != len(items) {
+		return reflect.Value{}, fmt.Errorf("batch field %s returned %d values for %d items", bf.name, out[0].Len(), len(items))
+	}
+	return out[0], nil
+}
+
+// item converts an element of a slice to the type that the batch function takes.
+func (bf *batchField) item(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.Ptr {
+		if bf.itemType.Kind() == reflect.Ptr {
+			return v
+		}
+		return v.Elem()
+	}
+	if bf.itemType.Kind() == reflect.Ptr {
+		// The elements of slices are addressable.
+		return v.Addr()
+	}
+	return v
+}
+
+// batchValues are the values of the batch fields of each element of a slice, keyed by
+// the name of the field.
+type batchValues []map[string]any
+
+// context returns the context to process the element of the slice at the index with, which
+// carries the values of its batch fields to processOutputStruct.
+func (bv batchValues) context(ctx context.Context, index int) context.Context {
+	if bv == nil || bv[index] == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, batchValuesContextKey, bv[index])
+}
+
+// prefetchBatchFields calls the functions of the batch fields that the filter selects on
+// the elements of the slice, once for all the elements. It returns nil if there aren't
+// any.
+func (f *graphFunction) prefetchBatchFields(ctx context.Context, req *request, filter *resultFilter, slice reflect.Value) (result batchValues, retErr error) {
+	if filter == nil || req == nil || len(f.g.batchFields) == 0 {
+		return nil, nil
+	}
+	elemType := slice.Type().Elem()
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	fields := f.g.batchFields[elemType]
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	plan := req.stub.plan.selection(f.g, req.stub.fragments, filter, elemType)
+	done := map[string]bool{}
+	for _, pf := range plan.fields {
+		if pf.typeName || pf.unknown || pf.lookup.fieldType != FieldTypeGraphFunction {
+			continue
+		}
+		bf, ok := fields[pf.lookup.name]
+		if !ok || done[bf.name] {
+			continue
+		}
+		done[bf.name] = true
+
+		var items []reflect.Value
+		var indexes []int
+		for i := 0; i < slice.Len(); i++ {
+			elem := slice.Index(i)
+			if elem.Kind() == reflect.Ptr && elem.IsNil() {
+				continue
+			}
+			items = append(items, bf.item(elem))
+			indexes = append(indexes, i)
+		}
+		if len(items) == 0 {
+			continue
+		}
+
+		measure := f.g.measureResolvers() || req.tracing()
+		var start time.Time
+		if measure {
+			start = f.g.now()
+		}
+		callCtx, endSpan := req.startResolverSpan(ctx, plan.typeName, pf.field.Name, pf.field.resultKey())
+		values, err := bf.callSafely(callCtx, items)
+		endSpan(err)
+		if measure {
+			duration := f.g.now().Sub(start)
+			f.g.recordResolver(plan.typeName, pf.field.Name, duration, err)
+			req.traceResolver(plan.typeName, pf.field.Name, pf.field.Params, start, duration, err)
+		}
+		if err != nil {
+			return nil, AugmentGraphError(err, fmt.Sprintf("error calling batch field %s", bf.name), pf.field.Pos, pf.field.Name)
+		}
+
+		if result == nil {
+			result = make(batchValues, slice.Len())
+		}
+		for j, i := range indexes {
+			if result[i] == nil {
+				result[i] = map[string]any{}
+			}
+			result[i][bf.name] = values.Index(j).Interface()
+		}
+	}
+	return result, nil
+}
+
+// callSafely calls the batch function like call, and returns panics as errors.
+func (bf *batchField) callSafely(ctx context.Context, items []reflect.Value) (values reflect.Value, retErr error) {
+	defer func() {
+		if r := recover(); r != nil {
+			retErr = fmt.Errorf("batch field %s panicked: %v", bf.name, r)
+		}
+	}()
+	return bf.call(ctx, items)
+}
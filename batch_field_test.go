@@ -0,0 +1,115 @@
+package quickgraph
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+type batchPost struct {
+	Title    string
+	AuthorId int
+	Related  *batchPost
+}
+
+type batchAuthor struct {
+	Name string
+}
+
+// batchAuthors is a batch function that records the sizes of the batches that it is
+// called with.
+type batchAuthors struct {
+	mu      sync.Mutex
+	batches []int
+}
+
+func (b *batchAuthors) fetch(ctx context.Context, posts []*batchPost) ([]*batchAuthor, error) {
+	b.mu.Lock()
+	b.batches = append(b.batches, len(posts))
+	b.mu.Unlock()
+	names := map[int]string{1: "Ann", 2: "Bob"}
+	var result []*batchAuthor
+	for _, p := range posts {
+		if name, ok := names[p.AuthorId]; ok {
+			result = append(result, &batchAuthor{Name: name})
+		} else {
+			result = append(result, nil)
+		}
+	}
+	return result, nil
+}
+
+func TestGraphy_RegisterBatchField(t *testing.T) {
+	ctx := context.Background()
+	authors := &batchAuthors{}
+	g := Graphy{}
+	g.RegisterQuery(ctx, "posts", func() []batchPost {
+		return []batchPost{
+			{Title: "a", AuthorId: 1, Related: &batchPost{Title: "c", AuthorId: 2}},
+			{Title: "b", AuthorId: 2},
+			{Title: "d", AuthorId: 3},
+		}
+	})
+	g.RegisterQuery(ctx, "post", func() batchPost {
+		return batchPost{Title: "a", AuthorId: 1}
+	})
+	g.RegisterBatchField(ctx, batchPost{}, "author", authors.fetch)
+
+	// The authors of the posts in the list are fetched together, while the author of the
+	// related post is fetched on its own.
+	result, err := g.ProcessRequest(ctx, `{ posts { Title author { Name } Related { author { Name } } } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"posts":[`+
+		`{"Related":{"author":{"Name":"Bob"}},"Title":"a","author":{"Name":"Ann"}},`+
+		`{"Related":null,"Title":"b","author":{"Name":"Bob"}},`+
+		`{"Related":null,"Title":"d","author":null}]}}`, result)
+	assert.ElementsMatch(t, []int{3, 1}, authors.batches)
+
+	authors.batches = nil
+	result, err = g.ProcessRequest(ctx, `{ post { author { Name } } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"post":{"author":{"Name":"Ann"}}}}`, result)
+	assert.Equal(t, []int{1}, authors.batches)
+
+	// Lists where the field isn't selected don't call the function.
+	authors.batches = nil
+	_, err = g.ProcessRequest(ctx, `{ posts { Title } }`, "")
+	assert.NoError(t, err)
+	assert.Empty(t, authors.batches)
+
+	assert.Contains(t, g.SchemaDefinition(ctx), `type batchPost {
+	author: batchAuthor
+	AuthorId: Int!
+	Related: batchPost
+	Title: String!
+}`)
+}
+
+func TestGraphy_RegisterBatchField_Errors(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "posts", func() []batchPost {
+		return []batchPost{{Title: "a"}, {Title: "b"}}
+	})
+	g.RegisterBatchField(ctx, batchPost{}, "failing", func(posts []batchPost) ([]string, error) {
+		return nil, errors.New("no authors")
+	})
+	g.RegisterBatchField(ctx, batchPost{}, "short", func(posts []batchPost) []string {
+		return []string{"x"}
+	})
+
+	_, err := g.ProcessRequest(ctx, `{ posts { failing } }`, "")
+	assert.EqualError(t, err, "error calling batch field failing (path: posts/failing) [1:11]: no authors")
+
+	_, err = g.ProcessRequest(ctx, `{ posts { short } }`, "")
+	assert.EqualError(t, err, "error calling batch field short (path: posts/short) [1:11]: batch field short returned 1 values for 2 items")
+
+	assert.PanicsWithValue(t, "batch field bad: the function must take a []quickgraph.batchPost or []*quickgraph.batchPost, optionally after a context", func() {
+		g.RegisterBatchField(ctx, batchPost{}, "bad", func(p batchPost) []string { return nil })
+	})
+	assert.PanicsWithValue(t, "batch field bad: the function must return a slice, optionally followed by an error", func() {
+		g.RegisterBatchField(ctx, batchPost{}, "bad", func(p []batchPost) string { return "" })
+	})
+}
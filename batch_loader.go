@@ -0,0 +1,221 @@
+package quickgraph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// defaultBatchLoaderWait is how long Load waits for more keys if BatchLoaderWait isn't set.
+const defaultBatchLoaderWait = time.Millisecond
+
+// RegisterBatchLoader registers a function that loads values by their keys in batches,
+// for Load. This is a dataloader: the resolvers that Load values of the same type during
+// a request are collected into a single call of the function, and the values are cached
+// for the rest of the request, so the N+1 pattern of a query such as
+// `users { posts { author } }` becomes one call for all the authors:
+//
+//	g.RegisterBatchLoader(ctx, func(ctx context.Context, ids []UserID) ([]*User, error) {
+//		return db.UsersByIds(ctx, ids)
+//	})
+//
+//	func (p *Post) Author(ctx context.Context) (*User, error) {
+//		return quickgraph.Load[*User](ctx, p.AuthorID)
+//	}
+//
+// The function takes a slice of keys, optionally preceded by a context.Context. It
+// returns the value for each of the keys, in the same order, and may return an error. The
+// keys must be comparable. There is one loader for each combination of key and value
+// type; registering another one replaces it. Invalid functions cause a panic.
+//
+// Keys are only batched together if they are loaded at about the same time, so batching
+// needs the resolvers to run concurrently, as they do with
+// QueryLimits.MaxConcurrentResolvers. Otherwise, RegisterBatchField may be a better fit.
+func (g *Graphy) RegisterBatchLoader(ctx context.Context, fn any) {
+	bl := newBatchLoader(reflect.ValueOf(fn))
+
+	g.structureLock.Lock()
+	defer g.structureLock.Unlock()
+	if g.batchLoaders == nil {
+		g.batchLoaders = map[batchLoaderKey]*batchLoader{}
+	}
+	g.batchLoaders[bl.key] = bl
+}
+
+// Load loads the value for the key with the batch loader that was registered for the types
+// of the key and the value, which is given explicitly: Load[*User](ctx, id). It waits
+// BatchLoaderWait for other keys to load in the same batch. It must be called with the
+// context of a request.
+func Load[V any, K comparable](ctx context.Context, key K) (V, error) {
+	var zero V
+	req, ok := ctx.Value(requestContextKey).(*request)
+	if !ok {
+		return zero, errors.New("Load must be called with the context of a request")
+	}
+	lk := batchLoaderKey{
+		keyType:   reflect.TypeOf((*K)(nil)).Elem(),
+		valueType: reflect.TypeOf((*V)(nil)).Elem(),
+	}
+	bl, ok := req.graphy.batchLoaders[lk]
+	if !ok {
+		return zero, fmt.Errorf("no batch loader is registered for %v keys and %v values", lk.keyType, lk.valueType)
+	}
+
+	result := req.batchLoads(bl).load(ctx, reflect.ValueOf(&key).Elem(), req.graphy.batchLoaderWait())
+	select {
+	case <-result.done:
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+	if result.err != nil {
+		return zero, result.err
+	}
+	return result.value.Interface().(V), nil
+}
+
+func (g *Graphy) batchLoaderWait() time.Duration {
+	if g.BatchLoaderWait > 0 {
+		return g.BatchLoaderWait
+	}
+	return defaultBatchLoaderWait
+}
+
+// batchLoaderKey identifies a batch loader by the types of its keys and values.
+type batchLoaderKey struct {
+	keyType   reflect.Type
+	valueType reflect.Type
+}
+
+// batchLoader is a function that was registered with RegisterBatchLoader.
+type batchLoader struct {
+	key          batchLoaderKey
+	fn           reflect.Value
+	takesContext bool
+}
+
+func newBatchLoader(fn reflect.Value) *batchLoader {
+	ft := fn.Type()
+	if ft.Kind() != reflect.Func {
+		panic(fmt.Sprintf("batch loader: %v is not a func", ft))
+	}
+	bl := &batchLoader{fn: fn}
+
+	in := 0
+	if ft.NumIn() == 2 && ft.In(0).ConvertibleTo(contextType) {
+		bl.takesContext = true
+		in = 1
+	}
+	if ft.NumIn() != in+1 || ft.In(in).Kind() != reflect.Slice || !ft.In(in).Elem().Comparable() {
+		panic(fmt.Sprintf("batch loader %v: the function must take a slice of comparable keys, optionally after a context", ft))
+	}
+	if ft.NumOut() == 0 || ft.NumOut() > 2 || ft.Out(0).Kind() != reflect.Slice ||
+		(ft.NumOut() == 2 && ft.Out(1) != errorType) {
+		panic(fmt.Sprintf("batch loader %v: the function must return a slice, optionally followed by an error", ft))
+	}
+	bl.key = batchLoaderKey{keyType: ft.In(in).Elem(), valueType: ft.Out(0).Elem()}
+	return bl
+}
+
+// call calls the batch function with the keys and returns the values for them. Panics are
+// returned as errors.
+func (bl *batchLoader) call(ctx context.Context, keys []reflect.Value) (values reflect.Value, retErr error) {
+	defer func() {
+		if r := recover(); r != nil {
+			retErr = fmt.Errorf("batch loader for %v panicked: %v", bl.key.valueType, r)
+		}
+	}()
+
+	slice := reflect.MakeSlice(reflect.SliceOf(bl.key.keyType), len(keys), len(keys))
+	for i, key := range keys {
+		slice.Index(i).Set(key)
+	}
+	args := []reflect.Value{slice}
+	if bl.takesContext {
+		args = []reflect.Value{reflect.ValueOf(ctx), slice}
+	}
+
+	out := bl.fn.Call(args)
+	if len(out) == 2 && !out[1].IsNil() {
+		return reflect.Value{}, out[1].Interface().(error)
+	}
+	if out[0].Len() != len(keys) {
+		return reflect.Value{}, fmt.Errorf("batch loader for %v returned %d values for %d keys", bl.key.valueType, out[0].Len(), len(keys))
+	}
+	return out[0], nil
+}
+
+// batchLoads are the loads of a batch loader during a request. The results are kept for
+// the rest of the request, including the ones of the keys that are waiting to be loaded
+// in the next batch.
+type batchLoads struct {
+	loader *batchLoader
+
+	mu      sync.Mutex
+	results map[any]*batchLoadResult
+	pending []reflect.Value
+	waiting []*batchLoadResult
+}
+
+// batchLoadResult is the result of loading a key. done is closed once it is loaded.
+type batchLoadResult struct {
+	done  chan struct{}
+	value reflect.Value
+	err   error
+}
+
+// batchLoads returns the loads of the batch loader for the request.
+func (r *request) batchLoads(bl *batchLoader) *batchLoads {
+	r.loadsMu.Lock()
+	defer r.loadsMu.Unlock()
+	if r.loads == nil {
+		r.loads = map[*batchLoader]*batchLoads{}
+	}
+	loads, ok := r.loads[bl]
+	if !ok {
+		loads = &batchLoads{loader: bl, results: map[any]*batchLoadResult{}}
+		r.loads[bl] = loads
+	}
+	return loads
+}
+
+// load returns the result for the key. If the key hasn't been loaded yet, it is added to
+// the next batch, which is loaded once the wait is over.
+func (l *batchLoads) load(ctx context.Context, key reflect.Value, wait time.Duration) *batchLoadResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if result, ok := l.results[key.Interface()]; ok {
+		return result
+	}
+	result := &batchLoadResult{done: make(chan struct{})}
+	l.results[key.Interface()] = result
+	l.pending = append(l.pending, key)
+	l.waiting = append(l.waiting, result)
+	if len(l.pending) == 1 {
+		time.AfterFunc(wait, func() {
+			l.dispatch(ctx)
+		})
+	}
+	return result
+}
+
+// dispatch loads the keys of the batch.
+func (l *batchLoads) dispatch(ctx context.Context) {
+	l.mu.Lock()
+	keys, results := l.pending, l.waiting
+	l.pending, l.waiting = nil, nil
+	l.mu.Unlock()
+
+	values, err := l.loader.call(ctx, keys)
+	for i, result := range results {
+		if err != nil {
+			result.err = err
+		} else {
+			result.value = values.Index(i)
+		}
+		close(result.done)
+	}
+}
@@ -0,0 +1,86 @@
+package quickgraph
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+type loaderUserID string
+
+type loaderUser struct {
+	Name string
+}
+
+type loaderPost struct {
+	Title    string
+	AuthorID loaderUserID `graphy:"-"`
+}
+
+func (p loaderPost) Author(ctx context.Context) (*loaderUser, error) {
+	return Load[*loaderUser](ctx, p.AuthorID)
+}
+
+func TestGraphy_RegisterBatchLoader(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{
+		QueryLimits:     &QueryLimits{MaxConcurrentResolvers: 10},
+		BatchLoaderWait: 20 * time.Millisecond,
+	}
+	var mu sync.Mutex
+	var batches [][]loaderUserID
+	g.RegisterBatchLoader(ctx, func(ctx context.Context, ids []loaderUserID) ([]*loaderUser, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, ids)
+		users := make([]*loaderUser, len(ids))
+		for i, id := range ids {
+			if id == "broken" {
+				return nil, errors.New("storage is down")
+			}
+			users[i] = &loaderUser{Name: "user " + string(id)}
+		}
+		return users, nil
+	})
+	g.RegisterQuery(ctx, "posts", func() []loaderPost {
+		return []loaderPost{{"a", "1"}, {"b", "2"}, {"c", "1"}, {"d", "2"}, {"e", "1"}}
+	})
+
+	result, err := g.ProcessRequest(ctx, `{ posts { Title Author { Name } } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"posts":[{"Author":{"Name":"user 1"},"Title":"a"},{"Author":{"Name":"user 2"},"Title":"b"},{"Author":{"Name":"user 1"},"Title":"c"},{"Author":{"Name":"user 2"},"Title":"d"},{"Author":{"Name":"user 1"},"Title":"e"}]}}`, result)
+	if assert.Len(t, batches, 1) {
+		assert.ElementsMatch(t, []loaderUserID{"1", "2"}, batches[0])
+	}
+
+	// The cache is only kept for a request.
+	batches = nil
+	_, err = g.ProcessRequest(ctx, `{ posts { Author { Name } } }`, "")
+	assert.NoError(t, err)
+	assert.Len(t, batches, 1)
+
+	// The errors of the loader are the errors of all the fields in the batch.
+	g.RegisterQuery(ctx, "broken", func() []loaderPost {
+		return []loaderPost{{"a", "1"}, {"b", "broken"}}
+	})
+	_, err = g.ProcessRequest(ctx, `{ broken { Author { Name } } }`, "")
+	assert.ErrorContains(t, err, "storage is down")
+}
+
+func TestLoad_Errors(t *testing.T) {
+	ctx := context.Background()
+	_, err := Load[*loaderUser](ctx, loaderUserID("1"))
+	assert.EqualError(t, err, "Load must be called with the context of a request")
+
+	g := Graphy{}
+	g.RegisterQuery(ctx, "post", func() loaderPost { return loaderPost{Title: "a", AuthorID: "1"} })
+	_, err = g.ProcessRequest(ctx, `{ post { Author { Name } } }`, "")
+	assert.ErrorContains(t, err, "no batch loader is registered for quickgraph.loaderUserID keys and *quickgraph.loaderUser values")
+
+	assert.Panics(t, func() {
+		g.RegisterBatchLoader(ctx, func(ids [][]int) []string { return nil })
+	})
+}
@@ -177,3 +177,32 @@ mutation CreateReviewForEpisode($ep: Episode!, $review: ReviewInput!) {
 
 	b.ReportAllocs()
 }
+
+func BenchmarkListResults(b *testing.B) {
+	type item struct {
+		ID   int
+		Name string
+		Tags []string
+	}
+
+	ctx := context.Background()
+	g := Graphy{
+		RequestCache: simpleCache{
+			values: map[string]*simpleCacheEntry{},
+		},
+	}
+	items := make([]item, 100)
+	for i := range items {
+		items[i] = item{ID: i, Name: "item", Tags: []string{"a", "b"}}
+	}
+	g.RegisterQuery(ctx, "items", func(ctx context.Context, count int) []item {
+		return items[:count]
+	}, "count")
+
+	input := `{ items(count: 100) { ID Name Tags } }`
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = g.ProcessRequest(ctx, input, "")
+	}
+}
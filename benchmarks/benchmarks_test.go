@@ -0,0 +1,29 @@
+package benchmarks
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestScenarios(t *testing.T) {
+	ctx := context.Background()
+	for _, s := range Scenarios() {
+		t.Run(s.Name, func(t *testing.T) {
+			result, err := s.Graphy(false).ProcessRequest(ctx, s.Query, s.Variables)
+			assert.NoError(t, err)
+			assert.NotContains(t, result, `"errors"`)
+		})
+	}
+}
+
+func BenchmarkScenarios(b *testing.B) {
+	for _, s := range Scenarios() {
+		b.Run(s.Name+"/Uncached", func(b *testing.B) {
+			Run(b, s.Graphy(false), s.Query, s.Variables)
+		})
+		b.Run(s.Name+"/Cached", func(b *testing.B) {
+			Run(b, s.Graphy(true), s.Query, s.Variables)
+		})
+	}
+}
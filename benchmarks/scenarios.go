@@ -0,0 +1,274 @@
+// Package benchmarks has reproducible load scenarios for Graphy, for catching performance
+// regressions in the parsing of requests, their execution, and the serialization of the
+// results. The scenarios are run by the benchmarks of this package:
+//
+//	go test -bench . -benchmem ./benchmarks
+//
+// The helpers can be used to benchmark the requests of other services as well:
+//
+//	func BenchmarkDashboard(b *testing.B) {
+//		g := newGraph()
+//		g.RequestCache = benchmarks.NewRequestCache()
+//		benchmarks.Run(b, g, dashboardQuery, "")
+//	}
+package benchmarks
+
+import (
+	"context"
+	"fmt"
+	"github.com/gburgyan/go-quickgraph"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Scenario is a request and the Graphy that processes it.
+type Scenario struct {
+	Name      string
+	Query     string
+	Variables string
+
+	// Setup registers the functions and types that the request uses.
+	Setup func(ctx context.Context, g *quickgraph.Graphy)
+}
+
+// Graphy returns a new Graphy for the scenario. If cached is set, the parsed request is
+// cached, so that only its execution and the serialization of its result are measured.
+func (s Scenario) Graphy(cached bool) *quickgraph.Graphy {
+	g := &quickgraph.Graphy{}
+	if cached {
+		g.RequestCache = NewRequestCache()
+	}
+	s.Setup(context.Background(), g)
+	return g
+}
+
+// Run benchmarks processing the request with the Graphy. The allocations are reported,
+// and the benchmark fails if the request does.
+func Run(b *testing.B, g *quickgraph.Graphy, query, variables string) {
+	b.Helper()
+	ctx := context.Background()
+
+	// The first request also checks the scenario, and fills the cache if there is one.
+	if _, err := g.ProcessRequest(ctx, query, variables); err != nil {
+		b.Fatalf("request failed: %v", err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = g.ProcessRequest(ctx, query, variables)
+	}
+}
+
+// Scenarios returns all the scenarios:
+//
+//   - DeepQuery selects a chain of objects 25 levels deep.
+//   - WideList returns 1000 objects with 10 fields each.
+//   - HeavyFragments selects a list of 200 values of an interface with many named and
+//     inline fragments.
+//   - MutationWithVariables parses nested input objects from the variables.
+func Scenarios() []Scenario {
+	return []Scenario{DeepQuery(), WideList(), HeavyFragments(), MutationWithVariables()}
+}
+
+// RequestCache is a GraphRequestCache that keeps all the requests in memory.
+type RequestCache struct {
+	mu    sync.RWMutex
+	stubs map[string]requestCacheEntry
+}
+
+type requestCacheEntry struct {
+	stub *quickgraph.RequestStub
+	err  error
+}
+
+// NewRequestCache returns an empty RequestCache.
+func NewRequestCache() *RequestCache {
+	return &RequestCache{stubs: map[string]requestCacheEntry{}}
+}
+
+func (c *RequestCache) GetRequestStub(ctx context.Context, request string) (*quickgraph.RequestStub, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry := c.stubs[request]
+	return entry.stub, entry.err
+}
+
+func (c *RequestCache) SetRequestStub(ctx context.Context, request string, stub *quickgraph.RequestStub, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stubs[request] = requestCacheEntry{stub: stub, err: err}
+}
+
+// deepQueryDepth is the number of levels that DeepQuery selects.
+const deepQueryDepth = 25
+
+type deepNode struct {
+	Level int
+	Name  string
+	Child *deepNode
+}
+
+// DeepQuery selects a chain of objects deepQueryDepth levels deep.
+func DeepQuery() Scenario {
+	var root *deepNode
+	for level := deepQueryDepth; level > 0; level-- {
+		root = &deepNode{Level: level, Name: fmt.Sprintf("node %d", level), Child: root}
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString("{ root ")
+	for i := 0; i < deepQueryDepth; i++ {
+		sb.WriteString("{ Level Name ")
+		if i < deepQueryDepth-1 {
+			sb.WriteString("Child ")
+		}
+	}
+	sb.WriteString(strings.Repeat("} ", deepQueryDepth))
+	sb.WriteString("}")
+
+	return Scenario{
+		Name:  "DeepQuery",
+		Query: sb.String(),
+		Setup: func(ctx context.Context, g *quickgraph.Graphy) {
+			g.RegisterQuery(ctx, "root", func() *deepNode { return root })
+		},
+	}
+}
+
+type wideItem struct {
+	ID       int
+	SKU      string
+	Name     string
+	Price    float64
+	Quantity int
+	Active   bool
+	Category string
+	Vendor   string
+	Tags     []string
+	Rating   float64
+}
+
+// WideList returns 1000 objects with 10 fields each.
+func WideList() Scenario {
+	items := make([]wideItem, 1000)
+	for i := range items {
+		items[i] = wideItem{
+			ID:       i,
+			SKU:      fmt.Sprintf("SKU-%05d", i),
+			Name:     fmt.Sprintf("Item %d", i),
+			Price:    float64(i) * 1.25,
+			Quantity: i % 17,
+			Active:   i%3 != 0,
+			Category: "hardware",
+			Vendor:   "Acme",
+			Tags:     []string{"new", "sale"},
+			Rating:   4.5,
+		}
+	}
+
+	return Scenario{
+		Name:  "WideList",
+		Query: `{ items { ID SKU Name Price Quantity Active Category Vendor Tags Rating } }`,
+		Setup: func(ctx context.Context, g *quickgraph.Graphy) {
+			g.RegisterQuery(ctx, "items", func() []wideItem { return items })
+		},
+	}
+}
+
+type libraryEntity struct {
+	ID      string
+	Created string
+	Owner   string
+}
+
+type libraryDocument struct {
+	libraryEntity
+	Title string
+	Pages int
+}
+
+type libraryImage struct {
+	libraryEntity
+	Width  int
+	Height int
+	Format string
+}
+
+type libraryVideo struct {
+	libraryEntity
+	Duration int
+	Codec    string
+}
+
+// HeavyFragments selects a list of 200 values of an interface with many named and inline
+// fragments.
+func HeavyFragments() Scenario {
+	entities := make([]any, 200)
+	for i := range entities {
+		base := libraryEntity{ID: fmt.Sprintf("e%d", i), Created: "2024-01-01", Owner: "owner"}
+		switch i % 3 {
+		case 0:
+			entities[i] = libraryDocument{libraryEntity: base, Title: "Report", Pages: i}
+		case 1:
+			entities[i] = libraryImage{libraryEntity: base, Width: 640, Height: 480, Format: "png"}
+		default:
+			entities[i] = libraryVideo{libraryEntity: base, Duration: i, Codec: "h264"}
+		}
+	}
+
+	return Scenario{
+		Name: "HeavyFragments",
+		Query: `query Library {
+	entities {
+		...EntityFields
+		...DocumentFields
+		...ImageFields
+		... on libraryVideo { Duration Codec ...EntityFields }
+		... on libraryDocument { Pages ...EntityFields }
+	}
+}
+
+fragment EntityFields on libraryEntity { ID Created Owner }
+fragment DocumentFields on libraryDocument { Title Pages ...EntityFields }
+fragment ImageFields on libraryImage { Width Height Format ...EntityFields }`,
+		Setup: func(ctx context.Context, g *quickgraph.Graphy) {
+			g.RegisterFunction(ctx, quickgraph.FunctionDefinition{
+				Name:              "entities",
+				Function:          func() []any { return entities },
+				ReturnAnyOverride: []any{libraryDocument{}, libraryImage{}, libraryVideo{}},
+			})
+		},
+	}
+}
+
+type reviewInput struct {
+	Stars      int
+	Commentary string
+	Tags       []string
+	Author     reviewAuthor
+}
+
+type reviewAuthor struct {
+	Name  string
+	Email string
+}
+
+// MutationWithVariables parses nested input objects from the variables.
+func MutationWithVariables() Scenario {
+	return Scenario{
+		Name: "MutationWithVariables",
+		Query: `mutation Review($first: reviewInput!, $second: reviewInput!) {
+	first: review(input: $first) { Stars Commentary Tags Author { Name Email } }
+	second: review(input: $second) { Stars Commentary Tags Author { Name Email } }
+}`,
+		Variables: `{
+	"first": {"Stars": 5, "Commentary": "Great", "Tags": ["a", "b", "c"], "Author": {"Name": "Ann", "Email": "ann@example.com"}},
+	"second": {"Stars": 2, "Commentary": "Meh", "Tags": [], "Author": {"Name": "Bob", "Email": "bob@example.com"}}
+}`,
+		Setup: func(ctx context.Context, g *quickgraph.Graphy) {
+			g.RegisterMutation(ctx, "review", func(input reviewInput) reviewInput { return input }, "input")
+		},
+	}
+}
@@ -0,0 +1,148 @@
+package quickgraph
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CacheScope says which caches may keep the result of a request.
+type CacheScope int
+
+const (
+	// CacheScopePublic results are the same for every user, so shared caches may keep them.
+	CacheScopePublic CacheScope = iota
+	// CacheScopePrivate results are specific to the user, so only the user's own cache
+	// may keep them.
+	CacheScopePrivate
+)
+
+// CacheHint describes how long the result of a request may be cached. It is used to
+// generate the Cache-Control header of the response.
+type CacheHint struct {
+	// MaxAge is how long the result is fresh.
+	MaxAge time.Duration
+
+	// StaleWhileRevalidate is how long after MaxAge a cache may keep serving the result
+	// while it fetches a fresh one in the background.
+	StaleWhileRevalidate time.Duration
+
+	// Scope says which caches may keep the result.
+	Scope CacheScope
+}
+
+// HeaderValue returns the hint formatted as the value of a Cache-Control header.
+func (ch CacheHint) HeaderValue() string {
+	value := fmt.Sprintf("max-age=%d", int64(ch.MaxAge/time.Second))
+	if ch.Scope == CacheScopePrivate {
+		value = "private, " + value
+	}
+	if ch.StaleWhileRevalidate > 0 {
+		value += fmt.Sprintf(", stale-while-revalidate=%d", int64(ch.StaleWhileRevalidate/time.Second))
+	}
+	return value
+}
+
+// cacheHints combines the hints that are set while a request is processed. Functions
+// may run concurrently, so access is guarded by the mutex.
+type cacheHints struct {
+	mu   sync.Mutex
+	hint CacheHint
+	set  bool
+
+	// unhinted is set once a function returns without setting a hint. Nothing is known
+	// about how long its result may be cached, so the request as a whole can't be.
+	unhinted bool
+}
+
+func (ch *cacheHints) get() (CacheHint, bool) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return ch.hint, ch.set && !ch.unhinted
+}
+
+// cacheHintCall tracks whether the function that is being called sets a hint.
+type cacheHintCall struct {
+	hints *cacheHints
+	set   bool
+}
+
+// startCacheHintCall returns the context to call a function with so that the hints it
+// sets are attributed to it. The call is nil if the request doesn't collect hints.
+func startCacheHintCall(ctx context.Context) (context.Context, *cacheHintCall) {
+	ch, ok := ctx.Value(cacheHintContextKey).(*cacheHints)
+	if !ok {
+		return ctx, nil
+	}
+	call := &cacheHintCall{hints: ch}
+	return context.WithValue(ctx, cacheHintCallContextKey, call), call
+}
+
+// end records that the function has returned, which makes the request uncacheable if it
+// didn't set a hint.
+func (call *cacheHintCall) end() {
+	if call == nil {
+		return
+	}
+	call.hints.mu.Lock()
+	defer call.hints.mu.Unlock()
+	if !call.set {
+		call.hints.unhinted = true
+	}
+}
+
+// SetCacheHint declares how long the result of the function that is being called may be
+// cached by any cache. A request can involve many functions, so the hint for the request
+// as a whole is the most restrictive of the hints that were set: the smallest MaxAge, the
+// smallest StaleWhileRevalidate, and private if any of them is private. If any function
+// returns without setting a hint, the request has no hint at all and isn't cached.
+//
+// The combined hint is available from the context returned by WithCacheHints, and the
+// HTTP handler uses it for the Cache-Control header of successful queries. Otherwise
+// this does nothing.
+func SetCacheHint(ctx context.Context, maxAge, staleWhileRevalidate time.Duration) {
+	setCacheHint(ctx, CacheHint{MaxAge: maxAge, StaleWhileRevalidate: staleWhileRevalidate})
+}
+
+// SetPrivateCacheHint is like SetCacheHint, but for results that are specific to the
+// user, which only the user's own cache may keep.
+func SetPrivateCacheHint(ctx context.Context, maxAge, staleWhileRevalidate time.Duration) {
+	setCacheHint(ctx, CacheHint{MaxAge: maxAge, StaleWhileRevalidate: staleWhileRevalidate, Scope: CacheScopePrivate})
+}
+
+func setCacheHint(ctx context.Context, hint CacheHint) {
+	ch, ok := ctx.Value(cacheHintContextKey).(*cacheHints)
+	if !ok {
+		return
+	}
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if call, ok := ctx.Value(cacheHintCallContextKey).(*cacheHintCall); ok && call.hints == ch {
+		call.set = true
+	}
+	if !ch.set {
+		ch.hint = hint
+		ch.set = true
+		return
+	}
+	if hint.MaxAge < ch.hint.MaxAge {
+		ch.hint.MaxAge = hint.MaxAge
+	}
+	if hint.StaleWhileRevalidate < ch.hint.StaleWhileRevalidate {
+		ch.hint.StaleWhileRevalidate = hint.StaleWhileRevalidate
+	}
+	if hint.Scope == CacheScopePrivate {
+		ch.hint.Scope = CacheScopePrivate
+	}
+}
+
+// WithCacheHints returns a context that collects the cache hints that functions set
+// while a request is processed with it. The returned function gives the combined hint
+// once the request is complete; it returns false if no function set a hint or if any
+// function returned without setting one. This is
+// intended for response caches that sit in front of ProcessRequest.
+func WithCacheHints(ctx context.Context) (context.Context, func() (CacheHint, bool)) {
+	ch := &cacheHints{}
+	return context.WithValue(ctx, cacheHintContextKey, ch), ch.get
+}
@@ -0,0 +1,103 @@
+package quickgraph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type cacheHintTestItem struct {
+	Name string
+}
+
+func (i cacheHintTestItem) Price(ctx context.Context) int {
+	SetCacheHint(ctx, 10*time.Second, time.Minute)
+	return 42
+}
+
+func TestCacheHints(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "item", func(ctx context.Context) cacheHintTestItem {
+		SetCacheHint(ctx, time.Hour, 30*time.Second)
+		return cacheHintTestItem{Name: "widget"}
+	})
+
+	hintCtx, hint := WithCacheHints(ctx)
+	_, ok := hint()
+	assert.False(t, ok)
+
+	_, err := g.ProcessRequest(hintCtx, `{ item { Name } }`, "")
+	assert.NoError(t, err)
+	h, ok := hint()
+	assert.True(t, ok)
+	assert.Equal(t, CacheHint{MaxAge: time.Hour, StaleWhileRevalidate: 30 * time.Second}, h)
+	assert.Equal(t, "max-age=3600, stale-while-revalidate=30", h.HeaderValue())
+
+	// The most restrictive of the hints wins.
+	hintCtx, hint = WithCacheHints(ctx)
+	_, err = g.ProcessRequest(hintCtx, `{ item { Name Price } }`, "")
+	assert.NoError(t, err)
+	h, ok = hint()
+	assert.True(t, ok)
+	assert.Equal(t, CacheHint{MaxAge: 10 * time.Second, StaleWhileRevalidate: 30 * time.Second}, h)
+
+	// Without a collector, setting a hint does nothing.
+	_, err = g.ProcessRequest(ctx, `{ item { Name Price } }`, "")
+	assert.NoError(t, err)
+}
+
+func TestCacheHints_Unhinted(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "hinted", func(ctx context.Context) string {
+		SetCacheHint(ctx, time.Hour, 0)
+		return "hinted"
+	})
+	g.RegisterQuery(ctx, "unhinted", func() string {
+		return "unhinted"
+	})
+
+	// One resolver not setting a hint makes the whole result uncacheable.
+	hintCtx, hint := WithCacheHints(ctx)
+	_, err := g.ProcessRequest(hintCtx, `{ hinted unhinted }`, "")
+	assert.NoError(t, err)
+	_, ok := hint()
+	assert.False(t, ok)
+
+	hintCtx, hint = WithCacheHints(ctx)
+	_, err = g.ProcessRequest(hintCtx, `{ hinted }`, "")
+	assert.NoError(t, err)
+	h, ok := hint()
+	assert.True(t, ok)
+	assert.Equal(t, CacheHint{MaxAge: time.Hour}, h)
+}
+
+func TestCacheHints_PrivateScope(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "catalog", func(ctx context.Context) string {
+		SetCacheHint(ctx, time.Hour, time.Minute)
+		return "catalog"
+	})
+	g.RegisterQuery(ctx, "cart", func(ctx context.Context) string {
+		SetPrivateCacheHint(ctx, 5*time.Minute, time.Minute)
+		return "cart"
+	})
+
+	// Private wins over public.
+	hintCtx, hint := WithCacheHints(ctx)
+	_, err := g.ProcessRequest(hintCtx, `{ catalog cart }`, "")
+	assert.NoError(t, err)
+	h, ok := hint()
+	assert.True(t, ok)
+	assert.Equal(t, CacheHint{MaxAge: 5 * time.Minute, StaleWhileRevalidate: time.Minute, Scope: CacheScopePrivate}, h)
+	assert.Equal(t, "private, max-age=300, stale-while-revalidate=60", h.HeaderValue())
+}
+
+func TestCacheHint_HeaderValue(t *testing.T) {
+	assert.Equal(t, "max-age=0", CacheHint{}.HeaderValue())
+	assert.Equal(t, "max-age=90", CacheHint{MaxAge: 90 * time.Second}.HeaderValue())
+	assert.Equal(t, "private, max-age=90", CacheHint{MaxAge: 90 * time.Second, Scope: CacheScopePrivate}.HeaderValue())
+}
@@ -0,0 +1,261 @@
+package quickgraph
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// versionInfo holds the versions of the API that a field or function was added in and is
+// scheduled to be removed in. These come from the `since` and `removedIn` parts of the
+// graphy tag, or from FunctionDefinition.Since and FunctionDefinition.RemovedIn.
+type versionInfo struct {
+	since     string
+	removedIn string
+}
+
+// description returns the versions as a description for the schema, or nil if there
+// aren't any.
+func (v versionInfo) description() *string {
+	var parts []string
+	if v.since != "" {
+		parts = append(parts, fmt.Sprintf("Since %s.", v.since))
+	}
+	if v.removedIn != "" {
+		parts = append(parts, fmt.Sprintf("Removed in %s.", v.removedIn))
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	result := strings.Join(parts, " ")
+	return &result
+}
+
+// SchemaChangeKind is the kind of change that a SchemaChange describes.
+type SchemaChangeKind string
+
+const (
+	SchemaChangeTypeAdded             SchemaChangeKind = "TYPE_ADDED"
+	SchemaChangeTypeRemoved           SchemaChangeKind = "TYPE_REMOVED"
+	SchemaChangeFieldAdded            SchemaChangeKind = "FIELD_ADDED"
+	SchemaChangeFieldRemoved          SchemaChangeKind = "FIELD_REMOVED"
+	SchemaChangeFieldChanged          SchemaChangeKind = "FIELD_CHANGED"
+	SchemaChangeFieldDeprecated       SchemaChangeKind = "FIELD_DEPRECATED"
+	SchemaChangeFieldRemovalScheduled SchemaChangeKind = "FIELD_REMOVAL_SCHEDULED"
+)
+
+// SchemaChange is a single difference between two schemas, as found by SchemaChangelog.
+// Queries and mutations are reported as fields of the Query and Mutation types. FieldName
+// is empty for changes to whole types.
+type SchemaChange struct {
+	Kind        SchemaChangeKind
+	TypeName    string
+	FieldName   string
+	Description string
+}
+
+// changelogField is what is compared for each field of a type. The signature includes
+// the arguments of the field as well as its type.
+type changelogField struct {
+	signature         string
+	deprecated        bool
+	deprecationReason string
+	versions          versionInfo
+}
+
+// SchemaChangelog compares the schemas of two Graphy instances, typically the previous
+// and the current release of a service, and returns the differences between them. This is
+// intended for generating release notes; see FormatChangelog.
+//
+// The changes are sorted by type and field name. Fields of types that were added or
+// removed are not reported individually.
+func SchemaChangelog(ctx context.Context, from, to *Graphy) []SchemaChange {
+	fromTypes := from.changelogTypes()
+	toTypes := to.changelogTypes()
+
+	var changes []SchemaChange
+	for _, typeName := range sortedKeys(toTypes) {
+		if _, ok := fromTypes[typeName]; !ok {
+			changes = append(changes, SchemaChange{
+				Kind:        SchemaChangeTypeAdded,
+				TypeName:    typeName,
+				Description: fmt.Sprintf("Type %s was added.", typeName),
+			})
+		}
+	}
+	for _, typeName := range sortedKeys(fromTypes) {
+		if _, ok := toTypes[typeName]; !ok {
+			changes = append(changes, SchemaChange{
+				Kind:        SchemaChangeTypeRemoved,
+				TypeName:    typeName,
+				Description: fmt.Sprintf("Type %s was removed.", typeName),
+			})
+			continue
+		}
+		changes = append(changes, changelogFieldChanges(typeName, fromTypes[typeName], toTypes[typeName])...)
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].TypeName != changes[j].TypeName {
+			return changes[i].TypeName < changes[j].TypeName
+		}
+		return changes[i].FieldName < changes[j].FieldName
+	})
+	return changes
+}
+
+func changelogFieldChanges(typeName string, fromFields, toFields map[string]changelogField) []SchemaChange {
+	var changes []SchemaChange
+	for _, fieldName := range sortedKeys(toFields) {
+		qualified := typeName + "." + fieldName
+		toField := toFields[fieldName]
+		fromField, ok := fromFields[fieldName]
+		if !ok {
+			description := fmt.Sprintf("Field %s was added.", qualified)
+			if toField.versions.since != "" {
+				description = fmt.Sprintf("Field %s was added in %s.", qualified, toField.versions.since)
+			}
+			changes = append(changes, SchemaChange{
+				Kind:        SchemaChangeFieldAdded,
+				TypeName:    typeName,
+				FieldName:   fieldName,
+				Description: description,
+			})
+			continue
+		}
+		if fromField.signature != toField.signature {
+			changes = append(changes, SchemaChange{
+				Kind:        SchemaChangeFieldChanged,
+				TypeName:    typeName,
+				FieldName:   fieldName,
+				Description: fmt.Sprintf("Field %s changed from %s to %s.", qualified, fromField.signature, toField.signature),
+			})
+		}
+		if toField.deprecated && !fromField.deprecated {
+			description := fmt.Sprintf("Field %s was deprecated.", qualified)
+			if toField.deprecationReason != "" {
+				description = fmt.Sprintf("Field %s was deprecated: %s", qualified, toField.deprecationReason)
+			}
+			changes = append(changes, SchemaChange{
+				Kind:        SchemaChangeFieldDeprecated,
+				TypeName:    typeName,
+				FieldName:   fieldName,
+				Description: description,
+			})
+		}
+		if toField.versions.removedIn != "" && toField.versions.removedIn != fromField.versions.removedIn {
+			changes = append(changes, SchemaChange{
+				Kind:        SchemaChangeFieldRemovalScheduled,
+				TypeName:    typeName,
+				FieldName:   fieldName,
+				Description: fmt.Sprintf("Field %s is scheduled for removal in %s.", qualified, toField.versions.removedIn),
+			})
+		}
+	}
+	for _, fieldName := range sortedKeys(fromFields) {
+		if _, ok := toFields[fieldName]; !ok {
+			changes = append(changes, SchemaChange{
+				Kind:        SchemaChangeFieldRemoved,
+				TypeName:    typeName,
+				FieldName:   fieldName,
+				Description: fmt.Sprintf("Field %s.%s was removed.", typeName, fieldName),
+			})
+		}
+	}
+	return changes
+}
+
+// FormatChangelog formats the changes as a Markdown list, one change per line.
+func FormatChangelog(changes []SchemaChange) string {
+	sb := strings.Builder{}
+	for _, change := range changes {
+		sb.WriteString("- ")
+		sb.WriteString(change.Description)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// changelogTypes collects the fields of each of the types of the schema, keyed by type
+// and field name. Enum values are treated as fields without a signature.
+func (g *Graphy) changelogTypes() map[string]map[string]changelogField {
+	g.structureLock.RLock()
+	defer g.structureLock.RUnlock()
+
+	is := g.getSchemaTypes().introspectionSchema
+
+	result := map[string]map[string]changelogField{}
+	for _, t := range is.Types {
+		name := t.Name
+		switch t {
+		case is.Queries:
+			name = "Query"
+		case is.Mutations:
+			name = "Mutation"
+		}
+
+		fields := map[string]changelogField{}
+		for _, f := range t.fieldsRaw {
+			fields[f.Name] = changelogField{
+				signature:         changelogSignature(f.Args, f.Type),
+				deprecated:        f.IsDeprecated,
+				deprecationReason: derefString(f.DeprecationReason),
+				versions:          f.versions,
+			}
+		}
+		for _, f := range t.InputFields {
+			fields[f.Name] = changelogField{
+				signature: changelogTypeName(f.Type),
+				versions:  f.versions,
+			}
+		}
+		for _, v := range t.enumValuesRaw {
+			fields[v.Name] = changelogField{
+				deprecated:        v.IsDeprecated,
+				deprecationReason: derefString(v.DeprecationReason),
+			}
+		}
+		result[name] = fields
+	}
+	return result
+}
+
+// changelogSignature renders the arguments and type of a field the way they would
+// appear in the schema, for instance `(id: String!): Character`.
+func changelogSignature(args []__InputValue, t *__Type) string {
+	sb := strings.Builder{}
+	if len(args) > 0 {
+		sb.WriteString("(")
+		for i, arg := range args {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(arg.Name)
+			sb.WriteString(": ")
+			sb.WriteString(changelogTypeName(arg.Type))
+		}
+		sb.WriteString(")")
+	}
+	sb.WriteString(": ")
+	sb.WriteString(changelogTypeName(t))
+	return sb.String()
+}
+
+func changelogTypeName(t *__Type) string {
+	switch t.Kind {
+	case IntrospectionKindNonNull:
+		return changelogTypeName(t.OfType) + "!"
+	case IntrospectionKindList:
+		return "[" + changelogTypeName(t.OfType) + "]"
+	default:
+		return t.Name
+	}
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,119 @@
+package quickgraph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type changelogShipV1 struct {
+	Name  string
+	Class string
+	Crew  int
+}
+
+type changelogShipV2 struct {
+	Name     string
+	Class    string `graphy:"deprecated=Use Model,removedIn=2.0"`
+	Model    string `graphy:"since=1.4"`
+	Capacity int    `graphy:"since=1.4"`
+}
+
+type changelogPilot struct {
+	Name string
+}
+
+func TestSchemaChangelog(t *testing.T) {
+	ctx := context.Background()
+
+	v1 := &Graphy{}
+	v1.RegisterQuery(ctx, "ship", func(name string) changelogShipV1 { return changelogShipV1{} }, "name")
+	v1.RegisterQuery(ctx, "fleet", func() []changelogShipV1 { return nil })
+
+	v2 := &Graphy{}
+	v2.RegisterFunction(ctx, FunctionDefinition{
+		Name:           "ship",
+		Function:       func(name string, registry string) changelogShipV2 { return changelogShipV2{} },
+		ParameterNames: []string{"name", "registry"},
+		Mode:           ModeQuery,
+	})
+	v2.RegisterFunction(ctx, FunctionDefinition{
+		Name:     "pilot",
+		Function: func() changelogPilot { return changelogPilot{} },
+		Mode:     ModeQuery,
+		Since:    "1.4",
+	})
+
+	changes := SchemaChangelog(ctx, v1, v2)
+	assert.Equal(t, `- Field Query.fleet was removed.
+- Field Query.pilot was added in 1.4.
+- Field Query.ship changed from (name: string!): changelogShipV1! to (name: string!, registry: string!): changelogShipV2!.
+- Type changelogPilot was added.
+- Type changelogShipV1 was removed.
+- Type changelogShipV2 was added.
+`, FormatChangelog(changes))
+	assert.Equal(t, SchemaChange{
+		Kind:        SchemaChangeFieldAdded,
+		TypeName:    "Query",
+		FieldName:   "pilot",
+		Description: "Field Query.pilot was added in 1.4.",
+	}, changes[1])
+
+	assert.Empty(t, SchemaChangelog(ctx, v2, v2))
+}
+
+func TestSchemaChangelog_Fields(t *testing.T) {
+	from := map[string]changelogField{
+		"Name":  {signature: ": String!"},
+		"Class": {signature: ": String!"},
+		"Crew":  {signature: ": Int!"},
+	}
+	to := map[string]changelogField{
+		"Name":     {signature: ": String!"},
+		"Class":    {signature: ": String!", deprecated: true, deprecationReason: "Use Model", versions: versionInfo{removedIn: "2.0"}},
+		"Model":    {signature: ": String!", versions: versionInfo{since: "1.4"}},
+		"Capacity": {signature: ": Int"},
+	}
+
+	assert.Equal(t, `- Field Ship.Capacity was added.
+- Field Ship.Class was deprecated: Use Model
+- Field Ship.Class is scheduled for removal in 2.0.
+- Field Ship.Model was added in 1.4.
+- Field Ship.Crew was removed.
+`, FormatChangelog(changelogFieldChanges("Ship", from, to)))
+}
+
+func TestVersionInfo_Schema(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name:      "ship",
+		Function:  func() changelogShipV2 { return changelogShipV2{} },
+		Mode:      ModeQuery,
+		Since:     "1.2",
+		RemovedIn: "3.0",
+	})
+	g.EnableIntrospection(ctx)
+
+	expected := `type Query {
+	"Since 1.2. Removed in 3.0."
+	ship: changelogShipV2!
+}
+
+type changelogShipV2 {
+	"Since 1.4."
+	Capacity: Int!
+	"Removed in 2.0."
+	Class: String! @deprecated(reason: "Use Model")
+	"Since 1.4."
+	Model: String!
+	Name: String!
+}
+
+`
+	assert.Equal(t, expected, g.SchemaDefinition(ctx))
+
+	result, err := g.ProcessRequest(ctx, `{ __type(name: "changelogShipV2") { fields(includeDeprecated: true) { name description } } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"__type":{"fields":[{"description":"Since 1.4.","name":"Capacity"},{"description":"Removed in 2.0.","name":"Class"},{"description":"Since 1.4.","name":"Model"},{"description":null,"name":"Name"}]}}}`, result)
+}
@@ -0,0 +1,139 @@
+// Command quickgraph-types generates a list of the exported struct types in a package so
+// that they can all be registered with Graphy.RegisterTypes without listing each of them
+// by hand. It is intended to be run with go generate from the package that contains the
+// types:
+//
+//	//go:generate go run github.com/gburgyan/go-quickgraph/cmd/quickgraph-types
+//
+// This writes quickgraph_types.go, which declares a GraphTypes variable with an instance
+// of each type. The types are then registered with:
+//
+//	g.RegisterTypes(ctx, models.GraphTypes...)
+//
+// Types with a doc comment that contains "quickgraph:ignore" are left out, as are generic
+// types since they can't be instantiated without type arguments.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const ignoreMarker = "quickgraph:ignore"
+
+type options struct {
+	dir      string
+	output   string
+	variable string
+	exclude  map[string]bool
+}
+
+func main() {
+	opts := options{exclude: map[string]bool{}}
+	var exclude string
+	flag.StringVar(&opts.dir, "dir", ".", "directory of the package to scan")
+	flag.StringVar(&opts.output, "o", "quickgraph_types.go", "name of the file to generate in the package directory")
+	flag.StringVar(&opts.variable, "var", "GraphTypes", "name of the variable to generate")
+	flag.StringVar(&exclude, "exclude", "", "comma-separated list of type names to leave out")
+	flag.Parse()
+
+	for _, name := range strings.Split(exclude, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			opts.exclude[name] = true
+		}
+	}
+
+	src, err := generate(opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(opts.dir, opts.output), src, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generate scans the package in opts.dir and returns the source of the file that lists
+// its exported struct types.
+func generate(opts options) ([]byte, error) {
+	entries, err := os.ReadDir(opts.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	packageName := ""
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") || name == opts.output {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(opts.dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		if packageName == "" {
+			packageName = file.Name.Name
+		} else if packageName != file.Name.Name {
+			return nil, fmt.Errorf("found packages %s and %s in %s", packageName, file.Name.Name, opts.dir)
+		}
+		names = append(names, structTypeNames(file, opts.exclude)...)
+	}
+	if packageName == "" {
+		return nil, fmt.Errorf("no Go files found in %s", opts.dir)
+	}
+	sort.Strings(names)
+
+	buf := bytes.Buffer{}
+	buf.WriteString("// Code generated by quickgraph-types. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", packageName)
+	fmt.Fprintf(&buf, "// %s lists the graph types of this package for Graphy.RegisterTypes.\n", opts.variable)
+	fmt.Fprintf(&buf, "var %s = []any{\n", opts.variable)
+	for _, name := range names {
+		fmt.Fprintf(&buf, "\t%s{},\n", name)
+	}
+	buf.WriteString("}\n")
+	return format.Source(buf.Bytes())
+}
+
+// structTypeNames returns the names of the exported, non-generic struct types that are
+// declared in the file.
+func structTypeNames(file *ast.File, exclude map[string]bool) []string {
+	var names []string
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			if _, ok := typeSpec.Type.(*ast.StructType); !ok {
+				continue
+			}
+			if !typeSpec.Name.IsExported() || typeSpec.TypeParams != nil || exclude[typeSpec.Name.Name] {
+				continue
+			}
+			// The doc comment is on the declaration unless the types are grouped.
+			doc := typeSpec.Doc
+			if doc == nil && len(genDecl.Specs) == 1 {
+				doc = genDecl.Doc
+			}
+			if doc != nil && strings.Contains(doc.Text(), ignoreMarker) {
+				continue
+			}
+			names = append(names, typeSpec.Name.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "models.go", `package models
+
+type Character struct {
+	Name string
+}
+
+type Human struct {
+	Character
+	Height float64
+}
+
+// Internal is not part of the graph.
+// quickgraph:ignore
+type Internal struct{}
+
+type (
+	Droid struct {
+		Character
+	}
+	Episode string
+	private struct{}
+)
+
+type Page[T any] struct {
+	Items []T
+}
+`)
+	writeFile(t, dir, "more.go", `package models
+
+type Starship struct {
+	Name string
+}
+
+type Secret struct{}
+`)
+	writeFile(t, dir, "models_test.go", `package models
+
+type TestOnly struct{}
+`)
+	writeFile(t, dir, "quickgraph_types.go", `package models
+
+var GraphTypes = []any{Stale{}}
+`)
+
+	src, err := generate(options{
+		dir:      dir,
+		output:   "quickgraph_types.go",
+		variable: "GraphTypes",
+		exclude:  map[string]bool{"Secret": true},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `// Code generated by quickgraph-types. DO NOT EDIT.
+
+package models
+
+// GraphTypes lists the graph types of this package for Graphy.RegisterTypes.
+var GraphTypes = []any{
+	Character{},
+	Droid{},
+	Human{},
+	Starship{},
+}
+`, string(src))
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	dir := t.TempDir()
+	_, err := generate(options{dir: dir, output: "quickgraph_types.go", variable: "GraphTypes"})
+	assert.ErrorContains(t, err, "no Go files found")
+
+	writeFile(t, dir, "a.go", "package a\n")
+	writeFile(t, dir, "b.go", "package b\n")
+	_, err = generate(options{dir: dir, output: "quickgraph_types.go", variable: "GraphTypes"})
+	assert.ErrorContains(t, err, "found packages a and b")
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,149 @@
+package quickgraph
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CompressionEncoder creates a writer that compresses what is written to it into w. The
+// writer is closed once the whole response has been written.
+type CompressionEncoder func(w io.Writer) (io.WriteCloser, error)
+
+// Compression configures the compression of the responses of the HTTP handler. Responses
+// are compressed with the first of the Encodings that the client accepts according to its
+// Accept-Encoding header. Set it on GraphHttpHandler.Compression to enable it.
+type Compression struct {
+	// MinSize is the size in bytes below which responses are not compressed, as small
+	// responses don't benefit from it. If it is zero, responses of less than 1024 bytes
+	// are not compressed.
+	MinSize int
+
+	// Encodings are the content codings that may be used, in order of preference. If it is
+	// empty, "gzip" and "deflate" are used.
+	Encodings []string
+
+	// Encoders provides the encoders for content codings other than "gzip" and "deflate",
+	// such as "br". The codings must also be listed in Encodings to be used.
+	Encoders map[string]CompressionEncoder
+}
+
+const defaultCompressionMinSize = 1024
+
+var defaultCompressionEncodings = []string{"gzip", "deflate"}
+
+// encoder returns the encoder for a content coding, or nil if there is none.
+func (c *Compression) encoder(encoding string) CompressionEncoder {
+	if encoder, ok := c.Encoders[encoding]; ok {
+		return encoder
+	}
+	switch encoding {
+	case "gzip":
+		return func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		}
+	case "deflate":
+		// The "deflate" content coding is the zlib format, not a raw deflate stream.
+		return func(w io.Writer) (io.WriteCloser, error) {
+			return zlib.NewWriterLevel(w, zlib.DefaultCompression)
+		}
+	}
+	return nil
+}
+
+// negotiate returns the content coding to use for a response of the given size, or "" if
+// the response should not be compressed.
+func (c *Compression) negotiate(acceptEncoding string, size int) string {
+	minSize := c.MinSize
+	if minSize == 0 {
+		minSize = defaultCompressionMinSize
+	}
+	if size < minSize || acceptEncoding == "" {
+		return ""
+	}
+
+	accepted := parseAcceptEncoding(acceptEncoding)
+	encodings := c.Encodings
+	if len(encodings) == 0 {
+		encodings = defaultCompressionEncodings
+	}
+	for _, encoding := range encodings {
+		q, ok := accepted[encoding]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+		if ok && q > 0 && c.encoder(encoding) != nil {
+			return encoding
+		}
+	}
+	return ""
+}
+
+// parseAcceptEncoding returns the quality values of the codings in an Accept-Encoding
+// header.
+func parseAcceptEncoding(header string) map[string]float64 {
+	result := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+		if coding == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			name, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if found && strings.EqualFold(name, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		result[coding] = q
+	}
+	return result
+}
+
+// responseEncoding returns the content coding to use for a response of the given size,
+// or "" if it shouldn't be compressed.
+func (g GraphHttpHandler) responseEncoding(writer http.ResponseWriter, request *http.Request, size int) string {
+	if g.Compression == nil {
+		return ""
+	}
+	// Caches need to know that the response depends on the header, whether or not this
+	// particular response ends up being compressed.
+	writer.Header().Add("Vary", "Accept-Encoding")
+	return g.Compression.negotiate(request.Header.Get("Accept-Encoding"), size)
+}
+
+// writeResponse writes the status and the body of a response, compressing the body with
+// the encoding if it is set.
+func (g GraphHttpHandler) writeResponse(writer http.ResponseWriter, status int, body []byte, encoding string) {
+	if encoding == "" {
+		writer.WriteHeader(status)
+		_, err := writer.Write(body)
+		if err != nil {
+			log.Printf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	writer.Header().Set("Content-Encoding", encoding)
+	writer.Header().Del("Content-Length")
+	writer.WriteHeader(status)
+	encoder, err := g.Compression.encoder(encoding)(writer)
+	if err != nil {
+		log.Printf("Error creating %s encoder: %v", encoding, err)
+		return
+	}
+	_, err = encoder.Write(body)
+	if err == nil {
+		err = encoder.Close()
+	}
+	if err != nil {
+		log.Printf("Error writing response: %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package quickgraph
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func serveCompressed(h *GraphHttpHandler, size string, acceptEncoding string) (*http.Response, []byte) {
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"query":"{ text(size: `+size+`) }"}`))
+	req.Header.Set("Content-Type", "application/json")
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	res := rec.Result()
+	body, _ := io.ReadAll(res.Body)
+	return res, body
+}
+
+func TestCompression(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "text", func(size int) string {
+		return strings.Repeat("a", size)
+	}, "size")
+	h := NewGraphHttpHandler(&g)
+	h.Compression = &Compression{}
+	expected := `{"data":{"text":"` + strings.Repeat("a", 2000) + `"}}`
+
+	res, body := serveCompressed(h, "2000", "gzip, deflate")
+	assert.Equal(t, "gzip", res.Header.Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", res.Header.Get("Vary"))
+	assert.Less(t, len(body), 2000)
+	reader, err := gzip.NewReader(strings.NewReader(string(body)))
+	assert.NoError(t, err)
+	decompressed, _ := io.ReadAll(reader)
+	assert.Equal(t, expected, string(decompressed))
+
+	// The client's preferences are honored, including refusals.
+	res, body = serveCompressed(h, "2000", "gzip;q=0, deflate;q=0.5")
+	assert.Equal(t, "deflate", res.Header.Get("Content-Encoding"))
+	zr, err := zlib.NewReader(strings.NewReader(string(body)))
+	assert.NoError(t, err)
+	decompressed, _ = io.ReadAll(zr)
+	assert.Equal(t, expected, string(decompressed))
+
+	// Small responses and clients that don't ask for it don't get compression.
+	res, body = serveCompressed(h, "10", "gzip")
+	assert.Equal(t, "", res.Header.Get("Content-Encoding"))
+	assert.Equal(t, `{"data":{"text":"aaaaaaaaaa"}}`, string(body))
+
+	res, body = serveCompressed(h, "2000", "")
+	assert.Equal(t, "", res.Header.Get("Content-Encoding"))
+	assert.Equal(t, expected, string(body))
+	assert.Equal(t, "Accept-Encoding", res.Header.Get("Vary"))
+}
+
+func TestCompression_CustomEncoder(t *testing.T) {
+	encoded := 0
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "text", func(size int) string {
+		return strings.Repeat("a", size)
+	}, "size")
+	h := NewGraphHttpHandler(&g)
+	h.Compression = &Compression{
+		MinSize:   1,
+		Encodings: []string{"x-test", "gzip"},
+		Encoders: map[string]CompressionEncoder{
+			"x-test": func(w io.Writer) (io.WriteCloser, error) {
+				encoded++
+				return nopWriteCloser{w}, nil
+			},
+		},
+	}
+
+	res, body := serveCompressed(h, "3", "gzip, x-test")
+	assert.Equal(t, "x-test", res.Header.Get("Content-Encoding"))
+	assert.Equal(t, `{"data":{"text":"aaa"}}`, string(body))
+	assert.Equal(t, 1, encoded)
+
+	res, _ = serveCompressed(h, "3", "*")
+	assert.Equal(t, "x-test", res.Header.Get("Content-Encoding"))
+}
+
+func TestCompression_ETag(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "text", func(size int) string {
+		return strings.Repeat("a", size)
+	}, "size")
+	h := NewGraphHttpHandler(&g)
+	h.Compression = &Compression{}
+	h.EnableETags = true
+
+	plain, _ := serveCompressed(h, "2000", "")
+	compressed, _ := serveCompressed(h, "2000", "gzip")
+	assert.NotEqual(t, plain.Header.Get("ETag"), compressed.Header.Get("ETag"))
+	assert.True(t, strings.HasSuffix(compressed.Header.Get("ETag"), `-gzip"`))
+}
+
+func TestParseAcceptEncoding(t *testing.T) {
+	assert.Equal(t, map[string]float64{"gzip": 1, "br": 0.8, "*": 0}, parseAcceptEncoding("gzip, br;q=0.8, *;q=0"))
+}
@@ -0,0 +1,162 @@
+package quickgraph
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ValidateConfiguration checks the settings of the Graphy for values that can't be
+// intended, such as negative limits or settings that have no effect without another one,
+// and returns an error for each problem that it finds. Nothing is changed. Calling it in
+// a test or at startup catches mistakes in the configuration of a deployment before
+// they cause trouble in production:
+//
+//	if errs := g.ValidateConfiguration(); len(errs) > 0 {
+//		log.Fatal(errors.Join(errs...))
+//	}
+func (g *Graphy) ValidateConfiguration() []error {
+	g.structureLock.RLock()
+	defer g.structureLock.RUnlock()
+
+	var errs []error
+	notNegative := func(name string, value int64) {
+		if value < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative", name))
+		}
+	}
+
+	if limits := g.QueryLimits; limits != nil {
+		notNegative("QueryLimits.MaxDepth", int64(limits.MaxDepth))
+		notNegative("QueryLimits.IntrospectionMaxDepth", int64(limits.IntrospectionMaxDepth))
+		notNegative("QueryLimits.MaxConcurrentResolvers", int64(limits.MaxConcurrentResolvers))
+		notNegative("QueryLimits.MaxConcurrentCommands", int64(limits.MaxConcurrentCommands))
+		notNegative("QueryLimits.MaxEstimatedResponse", int64(limits.MaxEstimatedResponse))
+		notNegative("QueryLimits.DefaultListSize", int64(limits.DefaultListSize))
+	}
+	notNegative("ResultChunkSize", int64(g.ResultChunkSize))
+	notNegative("MaxExecDepth", int64(g.MaxExecDepth))
+	notNegative("StuckResolverTimeout", int64(g.StuckResolverTimeout))
+	notNegative("BatchLoaderWait", int64(g.BatchLoaderWait))
+	if g.StuckResolverHandler != nil && g.StuckResolverTimeout == 0 {
+		errs = append(errs, errors.New("StuckResolverHandler is set without a StuckResolverTimeout, so it is never called"))
+	}
+
+	if g.CommandErrorMode != CollectAllErrors && g.CommandErrorMode != FailFast {
+		errs = append(errs, fmt.Errorf("CommandErrorMode %d is unknown", g.CommandErrorMode))
+	}
+	if g.UnauthorizedListItems != UnauthorizedItemsSkipped && g.UnauthorizedListItems != UnauthorizedItemsNull {
+		errs = append(errs, fmt.Errorf("UnauthorizedListItems %d is unknown", g.UnauthorizedListItems))
+	}
+	if g.UnusedVariables < VariablesIgnore || g.UnusedVariables > VariablesReject {
+		errs = append(errs, fmt.Errorf("UnusedVariables %d is unknown", g.UnusedVariables))
+	}
+	if g.UnknownQueryFields != UnknownFieldsRejected && g.UnknownQueryFields != UnknownFieldsNull {
+		errs = append(errs, fmt.Errorf("UnknownQueryFields %d is unknown", g.UnknownQueryFields))
+	}
+	if g.UnknownMutationFields != UnknownFieldsRejected && g.UnknownMutationFields != UnknownFieldsNull {
+		errs = append(errs, fmt.Errorf("UnknownMutationFields %d is unknown", g.UnknownMutationFields))
+	}
+
+	if g.TrustedDocumentsOnly && g.TrustedDocuments == nil {
+		errs = append(errs, errors.New("TrustedDocumentsOnly is set without TrustedDocuments, so all requests are rejected"))
+	}
+	if dc := g.DetachedContext; dc != nil && (dc.Capture == nil) != (dc.Restore == nil) {
+		errs = append(errs, errors.New("DetachedContext needs both Capture and Restore"))
+	}
+	if p := g.ErrorPolicy; p != nil {
+		var categories []string
+		for category, redactor := range p.Redactors {
+			if redactor == nil {
+				categories = append(categories, fmt.Sprintf("%q", category))
+			}
+		}
+		if len(categories) > 0 {
+			sort.Strings(categories)
+			errs = append(errs, fmt.Errorf("ErrorPolicy has nil redactors for the categories %s", strings.Join(categories, ", ")))
+		}
+	}
+	errs = append(errs, g.validateOperationAccess()...)
+
+	names := keys(g.processors)
+	sort.Strings(names)
+	for _, name := range names {
+		f := g.processors[name]
+		notNegative(fmt.Sprintf("ListSize of function %s", name), int64(f.listSize))
+		notNegative(fmt.Sprintf("MaxResults of function %s", name), int64(f.maxResults))
+		if f.resultLimitPolicy != ResultLimitError && f.maxResults == 0 {
+			errs = append(errs, fmt.Errorf("function %s has a ResultLimitPolicy without MaxResults", name))
+		}
+	}
+	return errs
+}
+
+// validateOperationAccess checks that the operations that OperationAccess refers to are
+// registered, since a misspelled name would make the operation unreachable.
+func (g *Graphy) validateOperationAccess() []error {
+	oa := g.OperationAccess
+	if oa == nil {
+		return nil
+	}
+
+	var errs []error
+	if len(oa.Allowed) > 0 && oa.Roles == nil {
+		errs = append(errs, errors.New("OperationAccess.Allowed is set without Roles, so it never applies"))
+	}
+
+	unknown := map[string]bool{}
+	check := func(operations []string) {
+		for _, op := range operations {
+			if _, ok := g.processors[op]; !ok && op != "*" && !strings.HasPrefix(op, "__") {
+				unknown[op] = true
+			}
+		}
+	}
+	check(oa.Public)
+	for _, operations := range oa.Allowed {
+		check(operations)
+	}
+	for _, op := range sortedKeys(unknown) {
+		errs = append(errs, fmt.Errorf("OperationAccess refers to the unknown operation %s", op))
+	}
+	return errs
+}
+
+// ValidateConfiguration checks the settings of the handler, as well as those of its
+// Graphy, in the same way as Graphy.ValidateConfiguration.
+func (g *GraphHttpHandler) ValidateConfiguration() []error {
+	errs := g.graphy.ValidateConfiguration()
+
+	if t := g.Throttle; t != nil {
+		for _, rl := range []struct {
+			name  string
+			limit *RateLimit
+		}{{"PerIP", t.PerIP}, {"PerAPIKey", t.PerAPIKey}} {
+			if rl.limit == nil {
+				continue
+			}
+			if rl.limit.Budget <= 0 {
+				errs = append(errs, fmt.Errorf("Throttle.%s.Budget must be positive", rl.name))
+			}
+			if rl.limit.Window <= 0 {
+				errs = append(errs, fmt.Errorf("Throttle.%s.Window must be positive", rl.name))
+			}
+		}
+		if t.PerIP == nil && t.PerAPIKey == nil && t.Banned == nil {
+			errs = append(errs, errors.New("Throttle has no PerIP, PerAPIKey, or Banned, so it doesn't limit anything"))
+		}
+	}
+
+	if c := g.Compression; c != nil {
+		if c.MinSize < 0 {
+			errs = append(errs, errors.New("Compression.MinSize must not be negative"))
+		}
+		for _, encoding := range c.Encodings {
+			if c.encoder(encoding) == nil {
+				errs = append(errs, fmt.Errorf("Compression has no encoder for %s", encoding))
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,75 @@
+package quickgraph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func errorStrings(errs []error) []string {
+	var result []string
+	for _, err := range errs {
+		result = append(result, err.Error())
+	}
+	return result
+}
+
+func TestGraphy_ValidateConfiguration(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "orders", func() []string { return nil })
+	assert.Empty(t, g.ValidateConfiguration())
+
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name:              "items",
+		Function:          func() []string { return nil },
+		ResultLimitPolicy: ResultLimitTruncate,
+	})
+	g.QueryLimits = &QueryLimits{MaxDepth: -1}
+	g.ResultChunkSize = -5
+	g.StuckResolverHandler = func(stuck StuckResolver) {}
+	g.CommandErrorMode = 7
+	g.TrustedDocumentsOnly = true
+	g.DetachedContext = &DetachedContext{Capture: func(ctx context.Context) map[string]string { return nil }}
+	g.ErrorPolicy = &ErrorPolicy{Redactors: map[ErrorCategory]ErrorRedactor{ErrorCodeUnauthorized: nil}}
+	g.OperationAccess = &OperationAccess{
+		Public:  []string{"orders", "__schema"},
+		Allowed: map[string][]string{"admin": {"*", "odrers"}},
+	}
+
+	assert.Equal(t, []string{
+		"QueryLimits.MaxDepth must not be negative",
+		"ResultChunkSize must not be negative",
+		"StuckResolverHandler is set without a StuckResolverTimeout, so it is never called",
+		"CommandErrorMode 7 is unknown",
+		"TrustedDocumentsOnly is set without TrustedDocuments, so all requests are rejected",
+		"DetachedContext needs both Capture and Restore",
+		`ErrorPolicy has nil redactors for the categories "UNAUTHORIZED"`,
+		"OperationAccess.Allowed is set without Roles, so it never applies",
+		"OperationAccess refers to the unknown operation odrers",
+		"function items has a ResultLimitPolicy without MaxResults",
+	}, errorStrings(g.ValidateConfiguration()))
+}
+
+func TestGraphHttpHandler_ValidateConfiguration(t *testing.T) {
+	g := Graphy{}
+	h := NewGraphHttpHandler(&g)
+	assert.Empty(t, h.ValidateConfiguration())
+
+	g.ResultChunkSize = -1
+	h.Throttle = &Throttle{PerIP: &RateLimit{Budget: 0, Window: time.Minute}}
+	h.Compression = &Compression{Encodings: []string{"br", "gzip"}}
+	assert.Equal(t, []string{
+		"ResultChunkSize must not be negative",
+		"Throttle.PerIP.Budget must be positive",
+		"Compression has no encoder for br",
+	}, errorStrings(h.ValidateConfiguration()))
+
+	h.Throttle = &Throttle{}
+	h.Compression = nil
+	g.ResultChunkSize = 0
+	assert.Equal(t, []string{
+		"Throttle has no PerIP, PerAPIKey, or Banned, so it doesn't limit anything",
+	}, errorStrings(h.ValidateConfiguration()))
+}
@@ -0,0 +1,63 @@
+package quickgraph
+
+import "context"
+
+// contextKey is the type used for the keys of the values that this library stores in a
+// context.Context. Using a private type ensures that there are no collisions with keys
+// from other packages.
+type contextKey int
+
+const (
+	dryRunContextKey contextKey = iota
+	requestContextKey
+	httpResponseContextKey
+	cacheHintContextKey
+	cacheHintCallContextKey
+	formatContextKey
+	detachedContextKey
+	execContextKey
+	batchValuesContextKey
+	executionTraceContextKey
+	typenamesContextKey
+	incrementalContextKey
+	resultPathContextKey
+)
+
+// WithDryRun returns a context that causes a request processed with it to run in dry-run
+// mode. In dry-run mode the request is parsed and validated and the parameters of the
+// root functions are parsed and coerced, but the functions themselves are not called.
+// This is primarily useful for mutations, for instance to validate a form without
+// actually submitting it. Any errors that the request would have caused before the
+// functions are called are returned as usual.
+//
+// Functions that are registered with FunctionDefinition.SupportsDryRun set are still
+// called and are responsible for checking IsDryRun themselves.
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunContextKey, true)
+}
+
+// IsDryRun reports whether the context belongs to a request running in dry-run mode.
+func IsDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunContextKey).(bool)
+	return dryRun
+}
+
+// Variables returns the variables of the request that is currently being processed. The
+// values have already been parsed into the Go types of the parameters they are used for,
+// including any defaults from the operation definition. This allows functions, as well
+// as anything they call, to inspect the variables without having to re-parse the raw
+// variable JSON.
+//
+// A new map is returned for each call. If the context does not belong to a request, nil
+// is returned.
+func Variables(ctx context.Context) map[string]any {
+	req, ok := ctx.Value(requestContextKey).(*request)
+	if !ok {
+		return nil
+	}
+	result := make(map[string]any, len(req.variables))
+	for name, value := range req.variables {
+		result[name] = value.Interface()
+	}
+	return result
+}
@@ -0,0 +1,86 @@
+package quickgraph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDryRun_MutationNotCalled(t *testing.T) {
+	type input struct {
+		Name  string
+		Count int
+	}
+	called := 0
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterMutation(ctx, "create", func(ctx context.Context, in input) string {
+		called++
+		return in.Name
+	})
+
+	result, err := g.ProcessRequest(WithDryRun(ctx), `mutation { create(Name: "foo", Count: 3) }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"create":null}}`, result)
+	assert.Equal(t, 0, called)
+
+	// Input errors are still reported.
+	result, err = g.ProcessRequest(WithDryRun(ctx), `mutation { create(Name: "foo") }`, "")
+	assert.Error(t, err)
+	assert.Equal(t, `{"data":{},"errors":[{"message":"error getting call parameters for function create: missing required parameters: Count","locations":[{"line":1,"column":12}],"path":["create"]}]}`, result)
+	assert.Equal(t, 0, called)
+
+	// Without dry-run the function is called normally.
+	result, err = g.ProcessRequest(ctx, `mutation { create(Name: "foo", Count: 3) }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"create":"foo"}}`, result)
+	assert.Equal(t, 1, called)
+}
+
+func TestDryRun_SupportsDryRun(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name: "create",
+		Function: func(ctx context.Context, name string) string {
+			if IsDryRun(ctx) {
+				return "would create " + name
+			}
+			return "created " + name
+		},
+		ParameterNames: []string{"name"},
+		Mode:           ModeMutation,
+		SupportsDryRun: true,
+	})
+
+	result, err := g.ProcessRequest(WithDryRun(ctx), `mutation { create(name: "foo") }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"create":"would create foo"}}`, result)
+
+	result, err = g.ProcessRequest(ctx, `mutation { create(name: "foo") }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"create":"created foo"}}`, result)
+}
+
+func TestVariables(t *testing.T) {
+	type input struct {
+		Name  string
+		Count *int
+	}
+	var seen map[string]any
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "echo", func(ctx context.Context, in input) string {
+		seen = Variables(ctx)
+		return in.Name
+	})
+
+	query := `query Echo($name: String!, $count: Int = 7) { echo(Name: $name, Count: $count) }`
+	result, err := g.ProcessRequest(ctx, query, `{"name": "foo"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"echo":"foo"}}`, result)
+
+	count := 7
+	assert.Equal(t, map[string]any{"name": "foo", "count": &count}, seen)
+	assert.Nil(t, Variables(ctx))
+}
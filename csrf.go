@@ -0,0 +1,70 @@
+package quickgraph
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ErrorCodeCSRFBlocked is the code of the error for requests that CSRFPrevention blocks.
+const ErrorCodeCSRFBlocked = "CSRF_BLOCKED"
+
+// CSRFPrevention blocks cross-site request forgery against endpoints that authenticate
+// with cookies, following the recommendation of the GraphQL over HTTP specification. A
+// browser sends a request from another site without asking the server first only if it
+// is a "simple" request: one without custom headers whose Content-Type, if it has one,
+// is application/x-www-form-urlencoded, multipart/form-data, or text/plain. Requests like
+// that are rejected with a 400 Bad Request, while requests with a Content-Type of
+// application/json or one of the Headers can only come from pages the server allows
+// with CORS.
+type CSRFPrevention struct {
+	// Headers are the headers that show that a request was preflighted when it doesn't
+	// have another Content-Type. If it is empty, X-Apollo-Operation-Name,
+	// Apollo-Require-Preflight, and GraphQL-Require-Preflight are used, which is what
+	// the common clients send.
+	Headers []string
+
+	// AllOperations applies the check to queries as well as mutations. Queries don't
+	// change anything, but the check prevents other sites from learning how long they
+	// take, for instance.
+	AllOperations bool
+}
+
+var defaultCSRFPreventionHeaders = []string{"X-Apollo-Operation-Name", "Apollo-Require-Preflight", "GraphQL-Require-Preflight"}
+
+// errCSRFBlocked is reported for requests that CSRFPrevention blocks.
+var errCSRFBlocked = GraphError{
+	Message:    "this request requires a Content-Type of application/json or a header that makes browsers preflight it",
+	Extensions: map[string]string{"code": ErrorCodeCSRFBlocked},
+}
+
+// preflighted reports whether a browser would have preflighted the request if it came
+// from another site.
+func (c *CSRFPrevention) preflighted(request *http.Request) bool {
+	if contentType := request.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil && err != mime.ErrInvalidMediaParameter {
+			// Browsers only look at the media type, so they send content types that can't
+			// be parsed, like "text/plain; a=b; a=c", without a preflight.
+			return false
+		}
+		// With invalid parameters, like "text/plain; foo", the media type is still
+		// returned, and browsers send those without a preflight as well.
+		switch strings.ToLower(mediaType) {
+		case "application/x-www-form-urlencoded", "multipart/form-data", "text/plain":
+		default:
+			return true
+		}
+	}
+
+	headers := c.Headers
+	if len(headers) == 0 {
+		headers = defaultCSRFPreventionHeaders
+	}
+	for _, header := range headers {
+		if request.Header.Get(header) != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package quickgraph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCSRFPrevention(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	calls := 0
+	g.RegisterQuery(ctx, "balance", func() int { return 10 })
+	g.RegisterMutation(ctx, "transfer", func() int {
+		calls++
+		return 5
+	})
+	h := NewGraphHttpHandler(&g)
+	h.CSRFPrevention = &CSRFPrevention{}
+
+	serve := func(query string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(`{"query": "`+query+`"}`))
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	blocked := []map[string]string{
+		nil,
+		{"Content-Type": "text/plain"},
+		{"Content-Type": "application/x-www-form-urlencoded"},
+		{"Content-Type": "multipart/form-data; boundary=x"},
+		{"Content-Type": "text/plain; foo"},
+		{"Content-Type": "text/plain;charset"},
+		{"Content-Type": "text/plain; a=b; a=c"},
+	}
+	for _, headers := range blocked {
+		rec := serve("mutation { transfer }", headers)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, "%v", headers)
+		assert.Contains(t, rec.Body.String(), `"code":"CSRF_BLOCKED"`)
+	}
+	assert.Equal(t, 0, calls)
+
+	allowed := []map[string]string{
+		{"Content-Type": "application/json"},
+		{"Content-Type": "application/graphql-response+json; charset=utf-8"},
+		{"Content-Type": "text/plain", "X-Apollo-Operation-Name": "transfer"},
+		{"GraphQL-Require-Preflight": "1"},
+	}
+	for _, headers := range allowed {
+		rec := serve("mutation { transfer }", headers)
+		assert.Equal(t, http.StatusOK, rec.Code, "%v", headers)
+		assert.Equal(t, `{"data":{"transfer":5}}`, rec.Body.String())
+	}
+	assert.Equal(t, len(allowed), calls)
+
+	// Queries are only checked if asked to, and then before they are even parsed.
+	rec := serve("{ balance }", map[string]string{"Content-Type": "text/plain"})
+	assert.Equal(t, `{"data":{"balance":10}}`, rec.Body.String())
+	h.CSRFPrevention = &CSRFPrevention{Headers: []string{"X-Requested-With"}, AllOperations: true}
+	rec = serve("{ balance }", map[string]string{"Content-Type": "text/plain"})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"code":"CSRF_BLOCKED"`)
+	rec = serve("{ balance }", map[string]string{"Content-Type": "text/plain", "X-Requested-With": "fetch"})
+	assert.Equal(t, `{"data":{"balance":10}}`, rec.Body.String())
+}
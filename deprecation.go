@@ -0,0 +1,87 @@
+package quickgraph
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// defaultDeprecationReason is the reason of deprecated functions that don't give one,
+// which is the default of the @deprecated directive in the GraphQL spec.
+const defaultDeprecationReason = "No longer supported"
+
+// deprecatedOperation is the entry in the extensions of the response for a deprecated
+// query or mutation that the request used.
+type deprecatedOperation struct {
+	Operation string     `json:"operation"`
+	Reason    string     `json:"reason"`
+	SunsetAt  *time.Time `json:"sunsetAt,omitempty"`
+}
+
+// isDeprecated reports whether the function is deprecated, either with a reason or by
+// having a sunset.
+func (f *graphFunction) isDeprecated() bool {
+	return f.deprecatedReason != nil || !f.sunsetAt.IsZero()
+}
+
+func (f *graphFunction) deprecationReason() string {
+	if f.deprecatedReason == nil {
+		return defaultDeprecationReason
+	}
+	return *f.deprecatedReason
+}
+
+// schemaDescription returns the description of the function in the schema, which has its
+// versions and its sunset.
+func (f *graphFunction) schemaDescription() *string {
+	description := f.versions.description()
+	if f.sunsetAt.IsZero() {
+		return description
+	}
+	sunset := fmt.Sprintf("Sunset at %s.", f.sunsetAt.UTC().Format(time.RFC3339))
+	if description != nil {
+		sunset = *description + " " + sunset
+	}
+	return &sunset
+}
+
+// deprecatedOperations returns the deprecated queries and mutations that the request
+// uses, in the order that they are used in.
+func (rs *RequestStub) deprecatedOperations() []deprecatedOperation {
+	var result []deprecatedOperation
+	seen := map[string]bool{}
+	for _, command := range rs.commands {
+		f, ok := rs.graphy.processors[command.Name]
+		if !ok || !f.isDeprecated() || seen[command.Name] {
+			continue
+		}
+		seen[command.Name] = true
+		d := deprecatedOperation{Operation: f.name, Reason: f.deprecationReason()}
+		if !f.sunsetAt.IsZero() {
+			sunsetAt := f.sunsetAt
+			d.SunsetAt = &sunsetAt
+		}
+		result = append(result, d)
+	}
+	return result
+}
+
+// setDeprecationHeaders sets the Deprecation and Sunset headers for the deprecated
+// operations that a request used, unless they are already set.
+func setDeprecationHeaders(header http.Header, deprecations []deprecatedOperation) {
+	if len(deprecations) == 0 {
+		return
+	}
+	if header.Get("Deprecation") == "" {
+		header.Set("Deprecation", "true")
+	}
+	var sunset time.Time
+	for _, d := range deprecations {
+		if d.SunsetAt != nil && (sunset.IsZero() || d.SunsetAt.Before(sunset)) {
+			sunset = *d.SunsetAt
+		}
+	}
+	if !sunset.IsZero() && header.Get("Sunset") == "" {
+		header.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+	}
+}
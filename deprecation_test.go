@@ -0,0 +1,128 @@
+package quickgraph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDeprecation_Schema(t *testing.T) {
+	ctx := context.Background()
+	reason := "Use orders instead."
+	g := Graphy{}
+	g.RegisterQuery(ctx, "orders", func() []string { return []string{"a"} })
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name:             "legacyOrders",
+		Function:         func() []string { return []string{"a"} },
+		DeprecatedReason: &reason,
+		SunsetAt:         time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC),
+	})
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name:     "oldestOrders",
+		Function: func() []string { return []string{"a"} },
+		SunsetAt: time.Date(2027, 1, 31, 0, 0, 0, 0, time.UTC),
+	})
+
+	expected := `type Query {
+	"Sunset at 2027-06-30T00:00:00Z."
+	legacyOrders: [String!]! @deprecated(reason: "Use orders instead.")
+	"Sunset at 2027-01-31T00:00:00Z."
+	oldestOrders: [String!]! @deprecated(reason: "No longer supported")
+	orders: [String!]!
+}
+
+`
+	assert.Equal(t, expected, g.SchemaDefinition(ctx))
+}
+
+func TestDeprecation_Introspection(t *testing.T) {
+	ctx := context.Background()
+	reason := "Use orders instead."
+	g := Graphy{}
+	g.RegisterQuery(ctx, "orders", func() []string { return []string{"a"} })
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name:             "legacyOrders",
+		Function:         func() []string { return []string{"a"} },
+		DeprecatedReason: &reason,
+		SunsetAt:         time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC),
+	})
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name:     "oldestOrders",
+		Function: func() []string { return []string{"a"} },
+		SunsetAt: time.Date(2027, 1, 31, 0, 0, 0, 0, time.UTC),
+	})
+	g.EnableIntrospection(ctx)
+
+	result, err := g.ProcessRequest(ctx, `{ __schema { queryType { fields(includeDeprecated: true) { name isDeprecated deprecationReason sunsetAt } } } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"__schema":{"queryType":{"fields":[`+
+		`{"deprecationReason":"Use orders instead.","isDeprecated":true,"name":"legacyOrders","sunsetAt":"2027-06-30T00:00:00Z"},`+
+		`{"deprecationReason":"No longer supported","isDeprecated":true,"name":"oldestOrders","sunsetAt":"2027-01-31T00:00:00Z"},`+
+		`{"deprecationReason":null,"isDeprecated":false,"name":"orders","sunsetAt":null}]}}}}`, result)
+}
+
+func TestDeprecation_ResponseExtensions(t *testing.T) {
+	ctx := context.Background()
+	reason := "Use orders instead."
+	g := Graphy{}
+	g.RegisterQuery(ctx, "orders", func() []string { return []string{"a"} })
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name:             "legacyOrders",
+		Function:         func() []string { return []string{"a"} },
+		DeprecatedReason: &reason,
+		SunsetAt:         time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC),
+	})
+
+	result, err := g.ProcessRequest(ctx, `{ orders }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"orders":["a"]}}`, result)
+
+	result, err = g.ProcessRequest(ctx, `{ orders legacyOrders again: legacyOrders }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"again":["a"],"legacyOrders":["a"],"orders":["a"]},"extensions":{"deprecations":[`+
+		`{"operation":"legacyOrders","reason":"Use orders instead.","sunsetAt":"2027-06-30T00:00:00Z"}]}}`, result)
+}
+
+func TestDeprecation_HTTPHeaders(t *testing.T) {
+	ctx := context.Background()
+	reason := "Use orders instead."
+	g := Graphy{}
+	g.RegisterQuery(ctx, "orders", func() []string { return []string{"a"} })
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name:             "legacyOrders",
+		Function:         func() []string { return []string{"a"} },
+		DeprecatedReason: &reason,
+		SunsetAt:         time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC),
+	})
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name:     "oldestOrders",
+		Function: func() []string { return []string{"a"} },
+		SunsetAt: time.Date(2027, 1, 31, 0, 0, 0, 0, time.UTC),
+	})
+	h := NewGraphHttpHandler(&g)
+
+	serve := func(query string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(graphqlRequest{Query: query})
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("POST", "/", bytes.NewReader(body)))
+		return rec
+	}
+
+	// The headers are off by default.
+	rec := serve(`{ legacyOrders }`)
+	assert.Empty(t, rec.Header().Get("Deprecation"))
+
+	h.DeprecationHeaders = true
+	rec = serve(`{ orders }`)
+	assert.Empty(t, rec.Header().Get("Deprecation"))
+	assert.Empty(t, rec.Header().Get("Sunset"))
+
+	// The earliest sunset is used.
+	rec = serve(`{ legacyOrders oldestOrders }`)
+	assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+	assert.Equal(t, "Sun, 31 Jan 2027 00:00:00 GMT", rec.Header().Get("Sunset"))
+}
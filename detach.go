@@ -0,0 +1,98 @@
+package quickgraph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// DetachedOperation is a snapshot of a request that is being processed, with everything
+// that is needed to process it again later, such as in a background worker for a mutation
+// that takes too long to finish while the client waits. It can be serialized to JSON to
+// be put on a queue. Use Detach to create one and Graphy.ResumeDetached to process it.
+type DetachedOperation struct {
+	// ID identifies the operation, for instance so that the client can poll for its
+	// result. It is generated with NewID.
+	ID string `json:"id"`
+
+	// Request is the text of the request.
+	Request string `json:"request"`
+
+	// Variables are the variables of the request as they were received.
+	Variables json.RawMessage `json:"variables,omitempty"`
+
+	// Operation is the name of the request as given by RequestStub.Name.
+	Operation string `json:"operation,omitempty"`
+
+	// Mutation is true if the request is a mutation.
+	Mutation bool `json:"mutation,omitempty"`
+
+	// Values are the values from the context that DetachedContext.Capture saved.
+	Values map[string]string `json:"values,omitempty"`
+
+	// DetachedAt is when Detach was called, according to Now.
+	DetachedAt time.Time `json:"detachedAt"`
+}
+
+// DetachedContext saves the values of a context that a DetachedOperation needs, such as
+// the identity of the user for authorization, and restores them when the operation is
+// resumed. Values in a context can be anything, so only the application knows how to
+// save them.
+type DetachedContext struct {
+	// Capture returns the values of the context to save with the operation.
+	Capture func(ctx context.Context) map[string]string
+
+	// Restore returns a context with the values that Capture saved.
+	Restore func(ctx context.Context, values map[string]string) context.Context
+}
+
+// ErrNotInRequest is returned by Detach when the context doesn't belong to a request.
+var ErrNotInRequest = errors.New("context does not belong to a request")
+
+// Detach returns a snapshot of the request that is being processed, so that it can be
+// processed again later with Graphy.ResumeDetached. A function typically detaches its
+// request, puts the snapshot on a queue, and returns the ID of the snapshot for the client
+// to check on later; the worker that takes it off the queue then resumes it, and the
+// function, seeing that it was resumed with Resumed, does the actual work.
+func Detach(ctx context.Context) (*DetachedOperation, error) {
+	req, ok := ctx.Value(requestContextKey).(*request)
+	if !ok {
+		return nil, ErrNotInRequest
+	}
+	g := req.graphy
+	op := &DetachedOperation{
+		ID:         g.newID(),
+		Request:    req.document,
+		Operation:  req.stub.Name(),
+		Mutation:   req.stub.mode == RequestMutation,
+		DetachedAt: g.now(),
+	}
+	if req.variableJson != "" {
+		op.Variables = json.RawMessage(req.variableJson)
+	}
+	if g.DetachedContext != nil && g.DetachedContext.Capture != nil {
+		op.Values = g.DetachedContext.Capture(ctx)
+	}
+	return op, nil
+}
+
+// ResumeDetached processes a request that was detached with Detach. The values of the
+// context are restored with DetachedContext.Restore, and Resumed reports the operation to
+// the functions that are called. The request is checked the same way as when it was
+// first processed, such as for OperationAccess, except that TrustedDocumentsOnly doesn't
+// apply since the request was already accepted.
+func (g *Graphy) ResumeDetached(ctx context.Context, op *DetachedOperation) (string, error) {
+	if g.DetachedContext != nil && g.DetachedContext.Restore != nil {
+		ctx = g.DetachedContext.Restore(ctx, op.Values)
+	}
+	ctx = context.WithValue(ctx, detachedContextKey, op)
+	return g.processRequestWithTypes(ctx, op.Request, string(op.Variables))
+}
+
+// Resumed returns the operation that is being resumed with Graphy.ResumeDetached, if the
+// context belongs to one.
+func Resumed(ctx context.Context) (*DetachedOperation, bool) {
+	op, ok := ctx.Value(detachedContextKey).(*DetachedOperation)
+	return op, ok
+}
@@ -0,0 +1,70 @@
+package quickgraph
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type detachUserKey struct{}
+
+func TestDetach(t *testing.T) {
+	ctx := context.Background()
+	var queue []string
+	g := Graphy{
+		TestingHooks: &TestingHooks{
+			Now:   func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) },
+			NewID: func() string { return "op-1" },
+		},
+		DetachedContext: &DetachedContext{
+			Capture: func(ctx context.Context) map[string]string {
+				return map[string]string{"user": ctx.Value(detachUserKey{}).(string)}
+			},
+			Restore: func(ctx context.Context, values map[string]string) context.Context {
+				return context.WithValue(ctx, detachUserKey{}, values["user"])
+			},
+		},
+	}
+	g.RegisterMutation(ctx, "export", func(ctx context.Context, format string) (string, error) {
+		user := ctx.Value(detachUserKey{}).(string)
+		if op, ok := Resumed(ctx); ok {
+			return "exported " + format + " for " + user + " as " + op.ID, nil
+		}
+		op, err := Detach(ctx)
+		if err != nil {
+			return "", err
+		}
+		payload, err := json.Marshal(op)
+		if err != nil {
+			return "", err
+		}
+		queue = append(queue, string(payload))
+		return "queued " + op.ID, nil
+	}, "format")
+
+	request := `mutation Export($format: String!) { export(format: $format) }`
+	userCtx := context.WithValue(ctx, detachUserKey{}, "ada")
+	result, err := g.ProcessRequest(userCtx, request, `{"format": "csv"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"export":"queued op-1"}}`, result)
+
+	if assert.Len(t, queue, 1) {
+		assert.Equal(t, `{"id":"op-1","request":"mutation Export($format: String!) { export(format: $format) }","variables":{"format":"csv"},"operation":"Export","mutation":true,"values":{"user":"ada"},"detachedAt":"2024-01-02T03:04:05Z"}`, queue[0])
+
+		var op DetachedOperation
+		assert.NoError(t, json.Unmarshal([]byte(queue[0]), &op))
+		result, err = g.ResumeDetached(ctx, &op)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"data":{"export":"exported csv for ada as op-1"}}`, result)
+	}
+}
+
+func TestDetach_NotInRequest(t *testing.T) {
+	_, err := Detach(context.Background())
+	assert.ErrorIs(t, err, ErrNotInRequest)
+
+	_, ok := Resumed(context.Background())
+	assert.False(t, ok)
+}
@@ -2,15 +2,407 @@ package quickgraph
 
 import (
 	"context"
+	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 )
 
+// DirectiveLocation is a set of the places in requests that a directive registered with
+// RegisterDirective can be used in. Locations are combined with |, for instance
+// DirectiveLocationField | DirectiveLocationInlineFragment.
+type DirectiveLocation int
+
+const (
+	// DirectiveLocationField is a field of a result. Queries and mutations themselves
+	// can't have directives.
+	DirectiveLocationField DirectiveLocation = 1 << iota
+
+	// DirectiveLocationFragmentSpread is a spread of a named fragment, such as
+	// `...userFields @auth`.
+	DirectiveLocationFragmentSpread
+
+	// DirectiveLocationInlineFragment is an inline fragment, such as
+	// `... on User @auth { email }`.
+	DirectiveLocationInlineFragment
+)
+
+// directiveLocationNames are the names of the locations in the schema.
+var directiveLocationNames = []struct {
+	location DirectiveLocation
+	name     string
+}{
+	{DirectiveLocationField, "FIELD"},
+	{DirectiveLocationFragmentSpread, "FRAGMENT_SPREAD"},
+	{DirectiveLocationInlineFragment, "INLINE_FRAGMENT"},
+}
+
+// names returns the names of the locations in the schema.
+func (l DirectiveLocation) names() []string {
+	var result []string
+	for _, ln := range directiveLocationNames {
+		if l&ln.location != 0 {
+			result = append(result, ln.name)
+		}
+	}
+	return result
+}
+
+// builtInDirectives are the directives that can't be registered because the library
+// implements them itself.
+var builtInDirectives = map[string]bool{
+	"defer":      true,
+	"deprecated": true,
+	"include":    true,
+	"skip":       true,
+	"stream":     true,
+}
+
+// conditionArgs are the arguments of the @skip and @include directives.
+type conditionArgs struct {
+	If bool `graphy:"if"`
+}
+
+// conditionDirectives are the @skip and @include directives of the GraphQL spec. They are
+// always available, and are reported in introspection but not in the schema, like the
+// other built-in directives.
+var conditionDirectives = map[string]*executableDirective{
+	"skip": newConditionDirective("skip", "Directs the executor to skip this field or fragment when the \"if\" argument is true.",
+		func(args conditionArgs) bool { return !args.If }),
+	"include": newConditionDirective("include", "Directs the executor to include this field or fragment only when the \"if\" argument is true.",
+		func(args conditionArgs) bool { return args.If }),
+}
+
+func newConditionDirective(name, description string, handler func(conditionArgs) bool) *executableDirective {
+	ed, _ := newExecutableDirective(name, DirectiveLocationField|DirectiveLocationFragmentSpread|DirectiveLocationInlineFragment, handler)
+	ed.description = &description
+	return ed
+}
+
 type directiveInput struct {
 	Name string
 	Type reflect.Type
 }
 
+// directiveHandler is a directive that decides whether the fields that it is on are
+// resolved at all.
 type directiveHandler interface {
 	InputTypes() []directiveInput
 	HandlePreFetch(ctx context.Context, request *request, directive *directive) (bool, error)
 }
+
+// executableDirective is a directiveHandler that calls the handler function of a
+// directive registered with RegisterDirective.
+type executableDirective struct {
+	name       string
+	locations  DirectiveLocation
+	handler    reflect.Value
+	hasContext bool
+	argsType   reflect.Type
+	hasError   bool
+
+	// description is only set for the built-in directives.
+	description *string
+}
+
+// RegisterDirective registers a directive that clients can use in requests at the
+// locations, for instance:
+//
+//	g.RegisterDirective(ctx, "uppercase", quickgraph.DirectiveLocationField, strings.ToUpper)
+//	g.RegisterDirective(ctx, "feature", quickgraph.DirectiveLocationField|quickgraph.DirectiveLocationInlineFragment,
+//		func(ctx context.Context, args struct{ Name string `json:"name"` }) bool {
+//			return features.Enabled(ctx, args.Name)
+//		})
+//
+// The handler is a function of one of these forms:
+//
+//	func([ctx context.Context,] [args A]) (bool[, error])
+//	func([ctx context.Context,] value V[, args A]) (R[, error])
+//
+// The first form is called before the fields that the directive is on are resolved, and
+// the fields are left out of the result if it returns false, like with @include. A, if
+// present, is a struct whose fields are the arguments of the directive, as with
+// OutputDirective. The second form transforms the values of scalar fields, exactly like
+// an OutputDirective, so it can only be used on fields.
+//
+// The directives are part of the schema and its introspection. Registering a directive
+// with a handler of another form, or a transforming directive for other locations than
+// fields, panics, as does registering @defer, @stream, or @deprecated.
+func (g *Graphy) RegisterDirective(ctx context.Context, name string, location DirectiveLocation, handler any) {
+	name = strings.TrimPrefix(name, "@")
+	if name == "" {
+		panic("directive: name is required")
+	}
+	if builtInDirectives[name] {
+		panic(fmt.Sprintf("directive @%s: the directive is built in", name))
+	}
+	if location == 0 || location&^(DirectiveLocationField|DirectiveLocationFragmentSpread|DirectiveLocationInlineFragment) != 0 {
+		panic(fmt.Sprintf("directive @%s: unsupported location %d", name, location))
+	}
+
+	ed, ok := newExecutableDirective(name, location, handler)
+	if !ok && location != DirectiveLocationField {
+		panic(fmt.Sprintf("directive @%s: directives that transform values can only be used on fields", name))
+	}
+
+	g.structureLock.Lock()
+	defer g.structureLock.Unlock()
+	if ok {
+		if g.directives == nil {
+			g.directives = map[string]*executableDirective{}
+		}
+		g.directives[name] = ed
+		delete(g.outputDirectives, name)
+	} else {
+		if g.outputDirectives == nil {
+			g.outputDirectives = map[string]*outputDirective{}
+		}
+		g.outputDirectives[name] = newOutputDirective(OutputDirective{Name: name, Handler: handler})
+		delete(g.directives, name)
+	}
+	g.invalidateSchema()
+}
+
+// newExecutableDirective creates the directive if the handler has the form of one that
+// decides whether fields are resolved. It returns false for other handlers.
+func newExecutableDirective(name string, location DirectiveLocation, handler any) (*executableDirective, bool) {
+	hv := reflect.ValueOf(handler)
+	if hv.Kind() != reflect.Func {
+		panic(fmt.Sprintf("directive @%s: handler must be a function", name))
+	}
+	ht := hv.Type()
+	boolResult := (ht.NumOut() == 1 || (ht.NumOut() == 2 && ht.Out(1) == errorType)) &&
+		ht.Out(0).Kind() == reflect.Bool
+	if !boolResult {
+		return nil, false
+	}
+
+	ed := &executableDirective{
+		name:      name,
+		locations: location,
+		handler:   hv,
+		hasError:  ht.NumOut() == 2,
+	}
+	in := 0
+	if in < ht.NumIn() && ht.In(in) == contextType {
+		ed.hasContext = true
+		in++
+	}
+	if in < ht.NumIn() {
+		if ht.In(in).Kind() != reflect.Struct || ht.In(in) == timeType {
+			// This is a value that is transformed into a bool.
+			return nil, false
+		}
+		ed.argsType = ht.In(in)
+		for _, field := range directiveArgs(ed.argsType) {
+			ft := field.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && ft != timeType {
+				panic(fmt.Sprintf("directive @%s: argument %s must be a scalar", name, field.Name))
+			}
+		}
+		in++
+	}
+	if in < ht.NumIn() {
+		panic(fmt.Sprintf("directive @%s: handler has too many parameters", name))
+	}
+	return ed, true
+}
+
+// InputTypes returns the arguments of the directive.
+func (ed *executableDirective) InputTypes() []directiveInput {
+	var result []directiveInput
+	for _, field := range directiveArgs(ed.argsType) {
+		name, _ := graphFieldName(field)
+		result = append(result, directiveInput{Name: name, Type: field.Type})
+	}
+	return result
+}
+
+// HandlePreFetch calls the handler with the arguments of the directive and returns
+// whether the fields that it is on are resolved.
+func (ed *executableDirective) HandlePreFetch(ctx context.Context, req *request, d *directive) (include bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			include = false
+			err = fmt.Errorf("directive @%s panicked: %v", ed.name, r)
+		}
+	}()
+
+	in := make([]reflect.Value, 0, 2)
+	if ed.hasContext {
+		in = append(in, reflect.ValueOf(ctx))
+	}
+	if ed.argsType != nil {
+		args, err := parseDirectiveArgs(ctx, req, d, ed.argsType)
+		if err != nil {
+			return false, AugmentGraphError(err, fmt.Sprintf("error parsing arguments of %s", d.Name), d.Pos)
+		}
+		in = append(in, args)
+	} else if d.Parameters != nil && len(d.Parameters.Values) > 0 {
+		return false, NewGraphError(fmt.Sprintf("directive @%s has no arguments", ed.name), d.Pos)
+	}
+	out := ed.handler.Call(in)
+	if ed.hasError && !out[1].IsNil() {
+		return false, AugmentGraphError(out[1].Interface().(error), fmt.Sprintf("error applying %s", d.Name), d.Pos)
+	}
+	return out[0].Bool(), nil
+}
+
+// parseDirectiveArgs parses the arguments of a directive into a new value of the type.
+func parseDirectiveArgs(ctx context.Context, req *request, d *directive, argsType reflect.Type) (reflect.Value, error) {
+	var params []namedValue
+	if d.Parameters != nil {
+		params = d.Parameters.Values
+	}
+	args := reflect.New(argsType).Elem()
+	err := parseMapIntoValue(ctx, req, genericValue{Map: params, Pos: d.Pos}, args)
+	return args, err
+}
+
+// directiveArgs returns the fields of the argument struct of a directive that are its
+// arguments, in order.
+func directiveArgs(argsType reflect.Type) []reflect.StructField {
+	if argsType == nil {
+		return nil
+	}
+	var result []reflect.StructField
+	for i := 0; i < argsType.NumField(); i++ {
+		field := argsType.Field(i)
+		if _, include := graphFieldName(field); include {
+			result = append(result, field)
+		}
+	}
+	return result
+}
+
+// includeField reports whether the field is resolved, according to the directives that
+// are registered with RegisterDirective on it and on the fragment that selects it, if
+// there is one.
+func (g *Graphy) includeField(ctx context.Context, req *request, pf plannedField) (bool, error) {
+	if len(pf.field.Directives) == 0 && pf.fragment == nil {
+		return true, nil
+	}
+	include, err := g.handleDirectives(ctx, req, pf.field.Directives, DirectiveLocationField)
+	if err != nil || !include || pf.fragment == nil {
+		return include, err
+	}
+	return g.handleDirectives(ctx, req, pf.fragment.directives(), fragmentLocation(pf.fragment))
+}
+
+func (g *Graphy) handleDirectives(ctx context.Context, req *request, directives []directive, location DirectiveLocation) (bool, error) {
+	for i := range directives {
+		d := &directives[i]
+		ed, ok := g.executableDirective(d.Name)
+		if !ok || ed.locations&location == 0 {
+			continue
+		}
+		include, err := ed.HandlePreFetch(ctx, req, d)
+		if err != nil || !include {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// executableDirective returns the directive that decides whether fields are resolved with
+// the name, which may start with an @.
+func (g *Graphy) executableDirective(name string) (*executableDirective, bool) {
+	name = strings.TrimPrefix(name, "@")
+	if ed, ok := conditionDirectives[name]; ok {
+		return ed, true
+	}
+	ed, ok := g.directives[name]
+	return ed, ok
+}
+
+func fragmentLocation(fc *fragmentCall) DirectiveLocation {
+	if fc.Inline != nil {
+		return DirectiveLocationInlineFragment
+	}
+	return DirectiveLocationFragmentSpread
+}
+
+// validateDirectives checks that the directives registered with RegisterDirective are
+// used where they are allowed, and adds the variables that their arguments use.
+func (g *Graphy) validateDirectives(directives []directive, location DirectiveLocation, variableTypeMap map[string]*requestVariable) error {
+	for _, d := range directives {
+		ed, ok := g.executableDirective(d.Name)
+		if !ok {
+			continue
+		}
+		if ed.locations&location == 0 {
+			return NewGraphError(fmt.Sprintf("directive %s can't be used on %s", d.Name, location.names()[0]), d.Pos)
+		}
+		if ed.argsType != nil && d.Parameters != nil {
+			err := g.addNestedInputVariables(genericValue{Map: d.Parameters.Values}, ed.argsType, variableTypeMap)
+			if err != nil {
+				return AugmentGraphError(err, fmt.Sprintf("error adding variables for %s", d.Name), d.Pos)
+			}
+		}
+	}
+	return nil
+}
+
+// sortedDirectives returns the directives registered with RegisterDirective sorted by
+// name.
+func (g *Graphy) sortedDirectives() []*executableDirective {
+	var result []*executableDirective
+	for _, ed := range g.directives {
+		result = append(result, ed)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].name < result[j].name
+	})
+	return result
+}
+
+// schemaForDirectives writes the definitions of the directives registered with
+// RegisterDirective.
+func (g *Graphy) schemaForDirectives() string {
+	sb := strings.Builder{}
+	for _, ed := range g.sortedDirectives() {
+		sb.WriteString("directive @")
+		sb.WriteString(ed.name)
+		if args := ed.InputTypes(); len(args) > 0 {
+			sb.WriteString("(")
+			for i, arg := range args {
+				if i > 0 {
+					sb.WriteString(", ")
+				}
+				sb.WriteString(arg.Name)
+				sb.WriteString(": ")
+				sb.WriteString(g.schemaRefForType(g.typeLookup(arg.Type), nil))
+			}
+			sb.WriteString(")")
+		}
+		sb.WriteString(" on ")
+		sb.WriteString(strings.Join(ed.locations.names(), " | "))
+		sb.WriteString("\n\n")
+	}
+	return sb.String()
+}
+
+// introspectionDirectives describes @skip, @include, and the directives registered with
+// RegisterDirective for introspection.
+func (g *Graphy) introspectionDirectives(is *__Schema) []*__Directive {
+	var result []*__Directive
+	directives := append([]*executableDirective{conditionDirectives["include"], conditionDirectives["skip"]}, g.sortedDirectives()...)
+	for _, ed := range directives {
+		d := &__Directive{
+			Name:        ed.name,
+			Description: ed.description,
+			Locations:   ed.locations.names(),
+		}
+		for _, arg := range ed.InputTypes() {
+			d.Args = append(d.Args, __InputValue{
+				Name: arg.Name,
+				Type: g.getIntrospectionModifiedType(is, g.typeLookup(arg.Type), TypeInput),
+			})
+		}
+		result = append(result, d)
+	}
+	return result
+}
@@ -0,0 +1,148 @@
+package quickgraph
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+type featureArgs struct {
+	Name string `graphy:"name"`
+}
+
+func TestGraphy_RegisterDirective(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "article", func() directiveTestArticle {
+		return directiveTestArticle{
+			Title:       "Matrices for Fun",
+			Tags:        []string{"math", "go"},
+			PublishedAt: time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC),
+			Author:      directiveTestAuthor{Name: "Ada"},
+		}
+	})
+	g.RegisterOutputDirective(ctx, OutputDirective{
+		Name:        "uppercase",
+		Description: "Converts a string to upper case.",
+		Handler:     strings.ToUpper,
+	})
+	g.RegisterOutputDirective(ctx, OutputDirective{
+		Name: "formatDate",
+		Handler: func(t time.Time, args formatDateArgs) string {
+			return t.Format(args.Format)
+		},
+	})
+	g.RegisterOutputDirective(ctx, OutputDirective{
+		Name: "truncate",
+		Handler: func(ctx context.Context, s string, args truncateArgs) (string, error) {
+			if args.Length == 0 {
+				return "", errors.New("length must be positive")
+			}
+			if len(s) <= args.Length {
+				return s, nil
+			}
+			suffix := "..."
+			if args.Suffix != nil {
+				suffix = *args.Suffix
+			}
+			return s[:args.Length] + suffix, nil
+		},
+	})
+	enabled := map[string]bool{"tags": true}
+	g.RegisterDirective(ctx, "feature", DirectiveLocationField|DirectiveLocationFragmentSpread|DirectiveLocationInlineFragment,
+		func(ctx context.Context, args featureArgs) (bool, error) {
+			if args.Name == "broken" {
+				return false, errors.New("feature store unavailable")
+			}
+			return enabled[args.Name], nil
+		})
+	g.RegisterDirective(ctx, "@signedIn", DirectiveLocationField, func() bool { return false })
+	g.RegisterDirective(ctx, "lowercase", DirectiveLocationField, strings.ToLower)
+
+	result, err := g.ProcessRequest(ctx, `{ article { Title @feature(name: "titles") Tags @feature(name: "tags") Author @signedIn { Name } } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"article":{"Tags":["math","go"]}}}`, result)
+
+	result, err = g.ProcessRequest(ctx, `query titles($name: String!) { article { Title @lowercase @feature(name: $name) } }`, `{"name": "tags"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"article":{"Title":"matrices for fun"}}}`, result)
+
+	// The directives of fragments apply to all the fields that they select.
+	result, err = g.ProcessRequest(ctx, `{ article { Tags ... on directiveTestArticle @feature(name: "titles") { Title } ...author @feature(name: "tags") } }
+fragment author on directiveTestArticle { Author { Name } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"article":{"Author":{"Name":"Ada"},"Tags":["math","go"]}}}`, result)
+
+	_, err = g.ProcessRequest(ctx, `{ article { Title @feature(name: "broken") } }`, "")
+	assert.ErrorContains(t, err, "feature store unavailable")
+	_, err = g.ProcessRequest(ctx, `{ article { ... on directiveTestArticle @signedIn { Title } } }`, "")
+	assert.ErrorContains(t, err, "directive @signedIn can't be used on INLINE_FRAGMENT")
+
+	schema := g.SchemaDefinition(ctx)
+	assert.Contains(t, schema, `directive @lowercase on FIELD
+
+directive @truncate(length: Int!, suffix: String) on FIELD
+
+"Converts a string to upper case."
+directive @uppercase on FIELD
+
+directive @feature(name: String!) on FIELD | FRAGMENT_SPREAD | INLINE_FRAGMENT
+
+directive @signedIn on FIELD
+`)
+	var names []string
+	for _, d := range g.Schema().Directives() {
+		names = append(names, d.Name)
+	}
+	assert.Equal(t, []string{"feature", "formatDate", "include", "lowercase", "signedIn", "skip", "truncate", "uppercase"}, names)
+	assert.Equal(t, []string{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"}, g.Schema().Directives()[0].Locations)
+
+	assert.PanicsWithValue(t, "directive @stream: the directive is built in", func() {
+		g.RegisterDirective(ctx, "stream", DirectiveLocationField, func() bool { return true })
+	})
+	assert.PanicsWithValue(t, "directive @upper: directives that transform values can only be used on fields", func() {
+		g.RegisterDirective(ctx, "upper", DirectiveLocationInlineFragment, strings.ToUpper)
+	})
+}
+
+func TestGraphy_SkipAndInclude(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "article", func() directiveTestArticle {
+		return directiveTestArticle{
+			Title:       "Matrices for Fun",
+			Tags:        []string{"math", "go"},
+			PublishedAt: time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC),
+			Author:      directiveTestAuthor{Name: "Ada"},
+		}
+	})
+
+	query := `query article($withTags: Boolean!, $withoutAuthor: Boolean!) {
+  article {
+    Title @skip(if: false)
+    Tags @include(if: $withTags)
+    ...author @skip(if: $withoutAuthor)
+    ... on directiveTestArticle @include(if: false) { PublishedAt }
+  }
+}
+fragment author on directiveTestArticle { Author { Name } }`
+	result, err := g.ProcessRequest(ctx, query, `{"withTags": true, "withoutAuthor": true}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"article":{"Tags":["math","go"],"Title":"Matrices for Fun"}}}`, result)
+
+	result, err = g.ProcessRequest(ctx, query, `{"withTags": false, "withoutAuthor": false}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"article":{"Author":{"Name":"Ada"},"Title":"Matrices for Fun"}}}`, result)
+
+	_, err = g.ProcessRequest(ctx, `{ article { Title @skip } }`, "")
+	assert.Error(t, err)
+
+	// They are built in, so they are only reported by introspection.
+	assert.NotContains(t, g.SchemaDefinition(ctx), "@skip")
+	assert.PanicsWithValue(t, "directive @include: the directive is built in", func() {
+		g.RegisterDirective(ctx, "include", DirectiveLocationField, func() bool { return true })
+	})
+}
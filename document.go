@@ -0,0 +1,519 @@
+package quickgraph
+
+import (
+	"fmt"
+	"github.com/alecthomas/participle/v2/lexer"
+	"strings"
+)
+
+// DocumentRewriter changes requests before they are validated, for instance to rename
+// deprecated fields to their replacements, to remove fields that aren't allowed, or to add
+// arguments. The rewriters run when a request is parsed, so if a RequestCache is in use,
+// the rewritten request is cached; a rewrite must depend only on the request itself.
+type DocumentRewriter interface {
+	// RewriteDocument changes the request in place. If it returns an error, the request
+	// fails with it.
+	RewriteDocument(doc *Document, schema *SchemaModel) error
+}
+
+// DocumentRewriterFunc adapts a function to a DocumentRewriter.
+type DocumentRewriterFunc func(doc *Document, schema *SchemaModel) error
+
+func (f DocumentRewriterFunc) RewriteDocument(doc *Document, schema *SchemaModel) error {
+	return f(doc, schema)
+}
+
+// Document is a parsed request that DocumentRewriters can change. Unlike RequestDocument,
+// fragments are kept as they are written rather than merged into the fields that use them.
+type Document struct {
+	// Operation is "query", "mutation", or "" if the request doesn't say.
+	Operation string
+
+	// Name is the name of the operation, or "" if it has none. An operation that declares
+	// variables needs a name.
+	Name string
+
+	Variables []*DocumentVariable
+
+	// Fields are the queries or mutations that are called.
+	Fields []*DocumentField
+
+	Fragments []*DocumentFragment
+
+	pos lexer.Position
+}
+
+// DocumentVariable is a variable that the operation declares.
+type DocumentVariable struct {
+	// Name is the name of the variable, without the leading "$".
+	Name string
+
+	// Type is the type of the variable as it is written, such as "[String!]!".
+	Type string
+
+	// Default is the default value, or nil if there is none.
+	Default *DocumentValue
+
+	pos lexer.Position
+}
+
+// DocumentField is a field that is selected in a request, either a query or mutation, or
+// a field of its result.
+type DocumentField struct {
+	// Alias is the alias of the field, or "" if it has none. The field is in the result
+	// under its alias.
+	Alias string
+
+	Name      string
+	Arguments []*DocumentArgument
+
+	// Directives are the directives of the field. Queries and mutations can't have
+	// directives.
+	Directives []*DocumentDirective
+
+	// Fields and Fragments are what is selected from the result of the field.
+	Fields    []*DocumentField
+	Fragments []*DocumentFragmentSpread
+
+	pos lexer.Position
+}
+
+// DocumentArgument is an argument of a field or directive, or a field of an object value.
+type DocumentArgument struct {
+	Name  string
+	Value *DocumentValue
+
+	pos lexer.Position
+}
+
+// DocumentDirective is a directive on a field or a fragment spread, such as
+// @include(if: $flag).
+type DocumentDirective struct {
+	// Name is the name of the directive, without the leading "@".
+	Name      string
+	Arguments []*DocumentArgument
+
+	pos lexer.Position
+}
+
+// DocumentFragment is a named fragment that is defined in a request.
+type DocumentFragment struct {
+	Name      string
+	OnType    string
+	Fields    []*DocumentField
+	Fragments []*DocumentFragmentSpread
+
+	pos lexer.Position
+}
+
+// DocumentFragmentSpread is the use of a fragment in a selection. If Name is set, it refers
+// to the named fragment; otherwise it is an inline fragment with the other fields.
+type DocumentFragmentSpread struct {
+	Name string
+
+	OnType    string
+	Fields    []*DocumentField
+	Fragments []*DocumentFragmentSpread
+
+	// Directives are the directives of the spread, such as @defer.
+	Directives []*DocumentDirective
+}
+
+// DocumentValue is a value in a request. Exactly one of its fields is set.
+type DocumentValue struct {
+	// Variable is the name of a variable, without the leading "$".
+	Variable string
+
+	// Identifier is an enum value, or true, false, or null.
+	Identifier string
+
+	// String is a string as it is written, without the quotes.
+	String *string
+
+	Int   *int64
+	Float *float64
+
+	Object []*DocumentArgument
+	List   []*DocumentValue
+
+	pos lexer.Position
+}
+
+// rewriteDocument runs the DocumentRewriters on a parsed request and returns the result.
+func (g *Graphy) rewriteDocument(parsedCall *wrapper) (*wrapper, error) {
+	if len(g.DocumentRewriters) == 0 {
+		return parsedCall, nil
+	}
+
+	doc := newDocument(parsedCall)
+	schema := g.getSchemaTypes().model
+	for _, rewriter := range g.DocumentRewriters {
+		err := rewriter.RewriteDocument(doc, schema)
+		if err != nil {
+			return nil, AugmentGraphError(err, "error rewriting request", lexer.Position{})
+		}
+	}
+	rewritten, err := doc.wrapper()
+	if err != nil {
+		return nil, AugmentGraphError(err, "error rewriting request", lexer.Position{})
+	}
+	return rewritten, nil
+}
+
+func newDocument(parsedCall *wrapper) *Document {
+	doc := &Document{
+		Operation: parsedCall.Mode,
+		pos:       parsedCall.Pos,
+	}
+	if parsedCall.OperationDef != nil {
+		doc.Name = parsedCall.OperationDef.Name
+		for _, v := range parsedCall.OperationDef.Variables {
+			dv := &DocumentVariable{
+				Name: v.Name[1:],
+				Type: variableTypeString(v.Type),
+				pos:  v.Pos,
+			}
+			if v.Value != nil {
+				dv.Default = newDocumentValue(*v.Value)
+			}
+			doc.Variables = append(doc.Variables, dv)
+		}
+	}
+	for _, c := range parsedCall.Commands {
+		field := &DocumentField{
+			Name:      c.Name,
+			Arguments: newDocumentArguments(c.Parameters),
+			pos:       c.Pos,
+		}
+		if c.Alias != nil {
+			field.Alias = *c.Alias
+		}
+		field.Fields, field.Fragments = newDocumentSelection(c.ResultFilter)
+		doc.Fields = append(doc.Fields, field)
+	}
+	for _, f := range parsedCall.Fragments {
+		df := &DocumentFragment{
+			Name:   f.Name,
+			OnType: f.Definition.TypeName,
+			pos:    f.Pos,
+		}
+		df.Fields, df.Fragments = newDocumentSelection(f.Definition.Filter)
+		doc.Fragments = append(doc.Fragments, df)
+	}
+	return doc
+}
+
+func newDocumentSelection(filter *resultFilter) ([]*DocumentField, []*DocumentFragmentSpread) {
+	if filter == nil {
+		return nil, nil
+	}
+	var fields []*DocumentField
+	for _, f := range filter.Fields {
+		field := &DocumentField{
+			Name:      f.Name,
+			Arguments: newDocumentArguments(f.Params),
+			pos:       f.Pos,
+		}
+		if f.Alias != nil {
+			field.Alias = *f.Alias
+		}
+		field.Directives = newDocumentDirectives(f.Directives)
+		field.Fields, field.Fragments = newDocumentSelection(f.SubParts)
+		fields = append(fields, field)
+	}
+	var spreads []*DocumentFragmentSpread
+	for _, fc := range filter.Fragments {
+		spread := &DocumentFragmentSpread{
+			Directives: newDocumentDirectives(fc.directives()),
+		}
+		if fc.FragmentRef != nil {
+			spread.Name = *fc.FragmentRef
+		} else if fc.Inline != nil {
+			spread.OnType = fc.Inline.TypeName
+			spread.Fields, spread.Fragments = newDocumentSelection(fc.Inline.Filter)
+		}
+		spreads = append(spreads, spread)
+	}
+	return fields, spreads
+}
+
+func newDocumentDirectives(directives []directive) []*DocumentDirective {
+	var result []*DocumentDirective
+	for _, d := range directives {
+		result = append(result, &DocumentDirective{
+			Name:      d.Name[1:],
+			Arguments: newDocumentArguments(d.Parameters),
+			pos:       d.Pos,
+		})
+	}
+	return result
+}
+
+func newDocumentArguments(params *parameterList) []*DocumentArgument {
+	if params == nil {
+		return nil
+	}
+	return newDocumentObject(params.Values)
+}
+
+func newDocumentObject(values []namedValue) []*DocumentArgument {
+	var args []*DocumentArgument
+	for _, v := range values {
+		args = append(args, &DocumentArgument{
+			Name:  v.Name,
+			Value: newDocumentValue(v.Value),
+			pos:   v.Pos,
+		})
+	}
+	return args
+}
+
+func newDocumentValue(v genericValue) *DocumentValue {
+	dv := &DocumentValue{
+		Int:    v.Int,
+		Float:  v.Float,
+		Object: newDocumentObject(v.Map),
+		pos:    v.Pos,
+	}
+	if v.Variable != nil {
+		dv.Variable = (*v.Variable)[1:]
+	}
+	if v.Identifier != nil {
+		dv.Identifier = *v.Identifier
+	}
+	if v.String != nil {
+		// The string value has quotes around it, remove them.
+		s := (*v.String)[1 : len(*v.String)-1]
+		dv.String = &s
+	}
+	for _, item := range v.List {
+		dv.List = append(dv.List, newDocumentValue(item))
+	}
+	return dv
+}
+
+func variableTypeString(t variableType) string {
+	var s string
+	if t.Array != nil && t.Array.InnerType != nil {
+		s = "[" + variableTypeString(*t.Array.InnerType) + "]"
+	} else if t.ConcreteType != nil {
+		s = t.ConcreteType.Name
+	}
+	return s + t.IsRequired
+}
+
+// wrapper converts the document back to the form that the rest of the request processing
+// uses.
+func (doc *Document) wrapper() (*wrapper, error) {
+	w := &wrapper{
+		Mode: doc.Operation,
+		Pos:  doc.pos,
+	}
+	if doc.Name != "" {
+		w.OperationDef = &operationDef{Name: doc.Name, Pos: doc.pos}
+		for _, v := range doc.Variables {
+			typ, err := parseVariableTypeString(v.Type)
+			if err != nil {
+				return nil, fmt.Errorf("variable %s: %w", v.Name, err)
+			}
+			vd := variableDef{Name: "$" + v.Name, Type: typ, Pos: v.pos}
+			if v.Default != nil {
+				value := v.Default.genericValue()
+				vd.Value = &value
+			}
+			w.OperationDef.Variables = append(w.OperationDef.Variables, vd)
+		}
+	} else if len(doc.Variables) > 0 {
+		return nil, fmt.Errorf("an operation with variables needs a name")
+	}
+
+	if len(doc.Fields) == 0 {
+		return nil, fmt.Errorf("the request has no fields")
+	}
+	for _, f := range doc.Fields {
+		if len(f.Directives) > 0 {
+			return nil, fmt.Errorf("directives aren't supported on %s", f.Name)
+		}
+		c := command{
+			Name:       f.Name,
+			Parameters: parameterListOf(f.Arguments),
+			Pos:        f.pos,
+		}
+		if f.Alias != "" {
+			alias := f.Alias
+			c.Alias = &alias
+		}
+		filter, err := resultFilterOf(f.Fields, f.Fragments)
+		if err != nil {
+			return nil, err
+		}
+		c.ResultFilter = filter
+		w.Commands = append(w.Commands, c)
+	}
+
+	for _, f := range doc.Fragments {
+		filter, err := resultFilterOf(f.Fields, f.Fragments)
+		if err != nil {
+			return nil, err
+		}
+		if filter == nil {
+			return nil, fmt.Errorf("fragment %s has no fields", f.Name)
+		}
+		w.Fragments = append(w.Fragments, fragment{
+			Name:       f.Name,
+			Definition: &fragmentDef{TypeName: f.OnType, Filter: filter},
+			Pos:        f.pos,
+		})
+	}
+	return w, nil
+}
+
+// resultFilterOf converts a selection, which is nil if it is empty.
+func resultFilterOf(fields []*DocumentField, spreads []*DocumentFragmentSpread) (*resultFilter, error) {
+	if len(fields) == 0 && len(spreads) == 0 {
+		return nil, nil
+	}
+	filter := &resultFilter{}
+	for _, f := range fields {
+		rf := resultField{
+			Name:       f.Name,
+			Params:     parameterListOf(f.Arguments),
+			Directives: directivesOf(f.Directives),
+			Pos:        f.pos,
+		}
+		if f.Alias != "" {
+			alias := f.Alias
+			rf.Alias = &alias
+		}
+		subParts, err := resultFilterOf(f.Fields, f.Fragments)
+		if err != nil {
+			return nil, err
+		}
+		rf.SubParts = subParts
+		filter.Fields = append(filter.Fields, rf)
+	}
+	for _, s := range spreads {
+		if s.Name != "" {
+			name := s.Name
+			filter.Fragments = append(filter.Fragments, fragmentCall{FragmentRef: &name, Directives: directivesOf(s.Directives)})
+			continue
+		}
+		inline, err := resultFilterOf(s.Fields, s.Fragments)
+		if err != nil {
+			return nil, err
+		}
+		if inline == nil {
+			return nil, fmt.Errorf("inline fragment on %s has no fields", s.OnType)
+		}
+		filter.Fragments = append(filter.Fragments, fragmentCall{
+			Inline: &fragmentDef{TypeName: s.OnType, Directives: directivesOf(s.Directives), Filter: inline},
+		})
+	}
+	return filter, nil
+}
+
+func directivesOf(directives []*DocumentDirective) []directive {
+	var result []directive
+	for _, d := range directives {
+		result = append(result, directive{
+			Name:       "@" + d.Name,
+			Parameters: parameterListOf(d.Arguments),
+			Pos:        d.pos,
+		})
+	}
+	return result
+}
+
+func parameterListOf(args []*DocumentArgument) *parameterList {
+	if len(args) == 0 {
+		return nil
+	}
+	return &parameterList{Values: namedValuesOf(args)}
+}
+
+func namedValuesOf(args []*DocumentArgument) []namedValue {
+	var values []namedValue
+	for _, a := range args {
+		nv := namedValue{Name: a.Name, Pos: a.pos}
+		if a.Value != nil {
+			nv.Value = a.Value.genericValue()
+		} else {
+			nv.Value = genericValue{Identifier: &nullIdentifier}
+		}
+		values = append(values, nv)
+	}
+	return values
+}
+
+// nullIdentifier is used for arguments without a value.
+var nullIdentifier = "null"
+
+func (v *DocumentValue) genericValue() genericValue {
+	gv := genericValue{
+		Int:   v.Int,
+		Float: v.Float,
+		Map:   namedValuesOf(v.Object),
+		Pos:   v.pos,
+	}
+	if v.Variable != "" {
+		variable := "$" + v.Variable
+		gv.Variable = &variable
+	}
+	if v.Identifier != "" {
+		identifier := v.Identifier
+		gv.Identifier = &identifier
+	}
+	if v.String != nil {
+		s := `"` + *v.String + `"`
+		gv.String = &s
+	}
+	for _, item := range v.List {
+		gv.List = append(gv.List, item.genericValue())
+	}
+	return gv
+}
+
+// parseVariableTypeString parses the type of a variable, such as "[String!]!".
+func parseVariableTypeString(s string) (variableType, error) {
+	typ, rest, err := parseVariableTypePart(strings.TrimSpace(s))
+	if err != nil {
+		return variableType{}, err
+	}
+	if rest != "" {
+		return variableType{}, fmt.Errorf("invalid type %s", s)
+	}
+	return typ, nil
+}
+
+func parseVariableTypePart(s string) (variableType, string, error) {
+	var typ variableType
+	if strings.HasPrefix(s, "[") {
+		inner, rest, err := parseVariableTypePart(strings.TrimSpace(s[1:]))
+		if err != nil {
+			return typ, "", err
+		}
+		rest = strings.TrimSpace(rest)
+		if !strings.HasPrefix(rest, "]") {
+			return typ, "", fmt.Errorf("invalid type %s", s)
+		}
+		typ.Array = &variableArrayType{InnerType: &inner}
+		s = strings.TrimSpace(rest[1:])
+	} else {
+		end := strings.IndexFunc(s, func(r rune) bool {
+			return !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+		})
+		if end < 0 {
+			end = len(s)
+		}
+		if end == 0 {
+			return typ, "", fmt.Errorf("invalid type %s", s)
+		}
+		typ.ConcreteType = &variableConcreteType{Name: s[:end]}
+		s = strings.TrimSpace(s[end:])
+	}
+	if strings.HasPrefix(s, "!") {
+		typ.IsRequired = "!"
+		s = s[1:]
+	}
+	return typ, s, nil
+}
@@ -0,0 +1,166 @@
+package quickgraph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type documentTestFilter struct {
+	Tenant string
+	Tags   []string
+}
+
+type documentTestOrder struct {
+	Id     string
+	Tenant string
+	Secret string
+	Tags   []string
+}
+
+// walkDocumentFields calls f for each field of the document, including the fields of
+// fragments.
+func walkDocumentFields(fields []*DocumentField, spreads []*DocumentFragmentSpread, f func(field *DocumentField)) {
+	for _, field := range fields {
+		f(field)
+		walkDocumentFields(field.Fields, field.Fragments, f)
+	}
+	for _, spread := range spreads {
+		walkDocumentFields(spread.Fields, spread.Fragments, f)
+	}
+}
+
+func TestDocumentRewriters_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	unchanged := DocumentRewriterFunc(func(doc *Document, schema *SchemaModel) error {
+		return nil
+	})
+	request := `query Orders($show: Boolean!, $tag: String = "new") {
+		list: orders(filter: { Tenant: "acme", Tags: [$tag, "old"] }) { Id @include(if: $show) ...Parts ... on documentTestOrder { Tags } }
+	}
+	fragment Parts on documentTestOrder { Tenant }`
+	g := Graphy{}
+	g.RegisterQuery(ctx, "orders", func(filter *documentTestFilter) []documentTestOrder {
+		order := documentTestOrder{Id: "1", Tenant: "any", Secret: "s3cret"}
+		if filter != nil {
+			order.Tenant = filter.Tenant
+			order.Tags = filter.Tags
+		}
+		return []documentTestOrder{order}
+	}, "filter")
+
+	expected, err := g.ProcessRequest(ctx, request, `{"show": true}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"list":[{"Id":"1","Tags":["new","old"],"Tenant":"acme"}]}}`, expected)
+
+	g.DocumentRewriters = []DocumentRewriter{unchanged}
+	result, err := g.ProcessRequest(ctx, request, `{"show": true}`)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestDocumentRewriters(t *testing.T) {
+	ctx := context.Background()
+
+	// Renames a deprecated field, removes a field that isn't allowed, and adds the tenant
+	// to the filter.
+	rewriter := DocumentRewriterFunc(func(doc *Document, schema *SchemaModel) error {
+		for _, op := range doc.Fields {
+			if op.Name == "allOrders" {
+				// The alias keeps the name of the field in the result.
+				if op.Alias == "" {
+					op.Alias = op.Name
+				}
+				op.Name = "orders"
+			}
+			tenant := "acme"
+			op.Arguments = []*DocumentArgument{{
+				Name: "filter",
+				Value: &DocumentValue{Object: []*DocumentArgument{
+					{Name: "Tenant", Value: &DocumentValue{String: &tenant}},
+					{Name: "Tags", Value: &DocumentValue{List: []*DocumentValue{}}},
+				}},
+			}}
+		}
+		walkDocumentFields(doc.Fields, nil, func(field *DocumentField) {
+			var allowed []*DocumentField
+			for _, sub := range field.Fields {
+				if sub.Name != "Secret" {
+					allowed = append(allowed, sub)
+				}
+			}
+			field.Fields = allowed
+		})
+		return nil
+	})
+	g := Graphy{DocumentRewriters: []DocumentRewriter{rewriter}}
+	g.RegisterQuery(ctx, "orders", func(filter *documentTestFilter) []documentTestOrder {
+		order := documentTestOrder{Id: "1", Tenant: "any", Secret: "s3cret"}
+		if filter != nil {
+			order.Tenant = filter.Tenant
+			order.Tags = filter.Tags
+		}
+		return []documentTestOrder{order}
+	}, "filter")
+
+	result, err := g.ProcessRequest(ctx, `{ allOrders { Id Tenant Secret } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"allOrders":[{"Id":"1","Tenant":"acme"}]}}`, result)
+}
+
+func TestDocumentRewriters_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	g := Graphy{DocumentRewriters: []DocumentRewriter{DocumentRewriterFunc(func(doc *Document, schema *SchemaModel) error {
+		return GraphError{Message: "not today"}
+	})}}
+	g.RegisterQuery(ctx, "orders", func(filter *documentTestFilter) []documentTestOrder {
+		order := documentTestOrder{Id: "1", Tenant: "any", Secret: "s3cret"}
+		if filter != nil {
+			order.Tenant = filter.Tenant
+			order.Tags = filter.Tags
+		}
+		return []documentTestOrder{order}
+	}, "filter")
+	_, err := g.ProcessRequest(ctx, `{ orders { Id } }`, "")
+	assert.EqualError(t, err, "not today")
+
+	g.DocumentRewriters = []DocumentRewriter{DocumentRewriterFunc(func(doc *Document, schema *SchemaModel) error {
+		doc.Variables[0].Type = "[String"
+		return nil
+	})}
+	_, err = g.ProcessRequest(ctx, `query Q($tag: String) { orders { Id } }`, "")
+	assert.ErrorContains(t, err, "error rewriting request")
+	assert.ErrorContains(t, err, "variable tag: invalid type [String")
+}
+
+func TestDocumentRewriters_FieldAlias(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{DocumentRewriters: []DocumentRewriter{DocumentRewriterFunc(func(doc *Document, schema *SchemaModel) error {
+		doc.Fields[0].Fields[0].Alias = "identifier"
+		return nil
+	})}}
+	g.RegisterQuery(ctx, "orders", func(filter *documentTestFilter) []documentTestOrder {
+		order := documentTestOrder{Id: "1", Tenant: "any", Secret: "s3cret"}
+		if filter != nil {
+			order.Tenant = filter.Tenant
+			order.Tags = filter.Tags
+		}
+		return []documentTestOrder{order}
+	}, "filter")
+	result, err := g.ProcessRequest(ctx, `{ orders(filter: { Tenant: "acme", Tags: [] }) { Id } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"orders":[{"identifier":"1"}]}}`, result)
+}
+
+func TestParseVariableTypeString(t *testing.T) {
+	for _, s := range []string{"Int", "Int!", "[String]", "[String!]!", "[[Float]!]"} {
+		typ, err := parseVariableTypeString(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, s, variableTypeString(typ))
+	}
+	for _, s := range []string{"", "[Int", "Int]", "!"} {
+		_, err := parseVariableTypeString(s)
+		assert.Error(t, err, s)
+	}
+}
@@ -0,0 +1,177 @@
+package quickgraph
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// DynamicEnumLoader loads the current values of a dynamic enum, typically from a
+// datastore.
+type DynamicEnumLoader func(ctx context.Context) ([]EnumValue, error)
+
+// RegisterDynamicEnum makes the string type that has the given name in the graph an enum
+// whose values are loaded at runtime, rather than fixed by a StringEnumValues
+// implementation:
+//
+//	type Country string
+//
+//	err := g.RegisterDynamicEnum(ctx, "Country", loadCountries, time.Hour)
+//
+// The values are loaded right away, and an error loading them is returned. If the refresh
+// interval isn't zero, they are loaded again at that interval until the context is done;
+// if that fails, the previous values are kept. RefreshDynamicEnum loads them on demand.
+// The schema and introspection always show the current values, and input values of the
+// type are only accepted if they are one of them.
+func (g *Graphy) RegisterDynamicEnum(ctx context.Context, name string, loader DynamicEnumLoader, refreshInterval time.Duration) error {
+	de := &dynamicEnum{name: name, loader: loader}
+	_, err := de.load(ctx)
+	if err != nil {
+		return fmt.Errorf("error loading the values of enum %s: %w", name, err)
+	}
+
+	g.structureLock.Lock()
+	if g.dynamicEnums == nil {
+		g.dynamicEnums = map[string]*dynamicEnum{}
+	}
+	g.dynamicEnums[name] = de
+	g.invalidateSchema()
+	g.structureLock.Unlock()
+
+	if refreshInterval > 0 {
+		go g.refreshDynamicEnum(ctx, de, refreshInterval)
+	}
+	return nil
+}
+
+// RefreshDynamicEnum loads the values of a dynamic enum that was registered with
+// RegisterDynamicEnum again. If that fails, the previous values are kept.
+func (g *Graphy) RefreshDynamicEnum(ctx context.Context, name string) error {
+	g.structureLock.RLock()
+	de, ok := g.dynamicEnums[name]
+	g.structureLock.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown dynamic enum %s", name)
+	}
+
+	changed, err := de.load(ctx)
+	if err != nil {
+		return fmt.Errorf("error loading the values of enum %s: %w", name, err)
+	}
+	if changed {
+		g.invalidateSchema()
+	}
+	return nil
+}
+
+// refreshDynamicEnum reloads the values of the enum at the interval until the context is
+// done.
+func (g *Graphy) refreshDynamicEnum(ctx context.Context, de *dynamicEnum, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = g.RefreshDynamicEnum(ctx, de.name)
+		}
+	}
+}
+
+// dynamicEnum is an enum that was registered with RegisterDynamicEnum.
+type dynamicEnum struct {
+	name   string
+	loader DynamicEnumLoader
+	values atomic.Pointer[[]EnumValue]
+}
+
+// load calls the loader and stores the values that it returns. It reports whether they
+// are different from the previous ones.
+func (de *dynamicEnum) load(ctx context.Context) (changed bool, retErr error) {
+	defer func() {
+		if r := recover(); r != nil {
+			retErr = fmt.Errorf("loader panicked: %v", r)
+		}
+	}()
+	values, err := de.loader(ctx)
+	if err != nil {
+		return false, err
+	}
+	previous := de.values.Swap(&values)
+	return previous == nil || !reflect.DeepEqual(*previous, values), nil
+}
+
+// isValid reports whether the value is one of the current values of the enum.
+func (de *dynamicEnum) isValid(value string) bool {
+	for _, v := range *de.values.Load() {
+		if v.Name == value {
+			return true
+		}
+	}
+	return false
+}
+
+// dynamicEnumFor returns the dynamic enum for a type, or nil if it isn't one. Only string
+// types can be dynamic enums.
+func (g *Graphy) dynamicEnumFor(tl *typeLookup) *dynamicEnum {
+	if len(g.dynamicEnums) == 0 || tl.rootType == nil || tl.rootType.Kind() != reflect.String {
+		return nil
+	}
+	return g.dynamicEnums[tl.name]
+}
+
+// isEnum reports whether the type is an enum, either because it implements
+// StringEnumValues or because it is a dynamic enum.
+func (g *Graphy) isEnum(tl *typeLookup) bool {
+	if tl.rootType == nil {
+		return false
+	}
+	return g.dynamicEnumFor(tl) != nil || tl.rootType.ConvertibleTo(stringEnumValuesType)
+}
+
+// enumValues returns the current values of an enum type.
+func (g *Graphy) enumValues(tl *typeLookup) []EnumValue {
+	if de := g.dynamicEnumFor(tl); de != nil {
+		return *de.values.Load()
+	}
+	enumValue := reflect.New(tl.rootType)
+	se := enumValue.Convert(stringEnumValuesType).Interface().(StringEnumValues)
+	return se.EnumValues()
+}
+
+// checkDynamicEnums checks that the values of dynamic enums in an input value, including
+// those in lists and input objects, are current values of their enums.
+func (g *Graphy) checkDynamicEnums(v reflect.Value) error {
+	if len(g.dynamicEnums) == 0 {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			return g.checkDynamicEnums(v.Elem())
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := g.checkDynamicEnums(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+			if err := g.checkDynamicEnums(v.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.String:
+		if de := g.dynamicEnumFor(g.typeLookup(v.Type())); de != nil && !de.isValid(v.String()) {
+			return fmt.Errorf("invalid enum value %s", v.String())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package quickgraph
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+type dynamicCountry string
+
+type dynamicAddress struct {
+	Street  string
+	Country dynamicCountry
+}
+
+// dynamicCountries is a loader whose values can be changed by the tests.
+type dynamicCountries struct {
+	mu     sync.Mutex
+	values []string
+	err    error
+}
+
+func (c *dynamicCountries) set(values ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values = values
+}
+
+func (c *dynamicCountries) load(ctx context.Context) ([]EnumValue, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+	var result []EnumValue
+	for _, v := range c.values {
+		result = append(result, EnumValue{Name: v})
+	}
+	return result, nil
+}
+
+func TestGraphy_RegisterDynamicEnum(t *testing.T) {
+	ctx := context.Background()
+	countries := &dynamicCountries{values: []string{"CA", "US"}}
+	g := Graphy{}
+	g.RegisterQuery(ctx, "shipTo", func(country dynamicCountry) dynamicCountry {
+		return country
+	}, "country")
+	err := g.RegisterDynamicEnum(ctx, "dynamicCountry", countries.load, 0)
+	assert.NoError(t, err)
+	g.EnableIntrospection(ctx)
+
+	assert.Contains(t, g.SchemaDefinition(ctx), "enum dynamicCountry {\n\tCA\n\tUS\n}")
+	assert.Contains(t, g.SchemaDefinition(ctx), "shipTo(country: dynamicCountry!): dynamicCountry!")
+
+	result, err := g.ProcessRequest(ctx, `{ shipTo(country: US) }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"shipTo":"US"}}`, result)
+
+	_, err = g.ProcessRequest(ctx, `{ shipTo(country: MX) }`, "")
+	assert.ErrorContains(t, err, "invalid enum value MX")
+
+	// The values are picked up once the enum is refreshed.
+	countries.set("CA", "MX")
+	assert.NoError(t, g.RefreshDynamicEnum(ctx, "dynamicCountry"))
+
+	assert.Contains(t, g.SchemaDefinition(ctx), "enum dynamicCountry {\n\tCA\n\tMX\n}")
+	result, err = g.ProcessRequest(ctx, `{ __type(name: "dynamicCountry") { kind enumValues { name } } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"__type":{"enumValues":[{"name":"CA"},{"name":"MX"}],"kind":"ENUM"}}}`, result)
+
+	result, err = g.ProcessRequest(ctx, `{ shipTo(country: MX) }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"shipTo":"MX"}}`, result)
+	_, err = g.ProcessRequest(ctx, `{ shipTo(country: US) }`, "")
+	assert.ErrorContains(t, err, "invalid enum value US")
+}
+
+func TestGraphy_RegisterDynamicEnum_Variables(t *testing.T) {
+	ctx := context.Background()
+	countries := &dynamicCountries{values: []string{"CA", "US"}}
+	g := Graphy{}
+	g.RegisterQuery(ctx, "shipTo", func(country dynamicCountry) dynamicCountry {
+		return country
+	}, "country")
+	g.RegisterQuery(ctx, "addresses", func(addresses []dynamicAddress) int {
+		return len(addresses)
+	}, "addresses")
+	err := g.RegisterDynamicEnum(ctx, "dynamicCountry", countries.load, 0)
+	assert.NoError(t, err)
+
+	query := `query ship($country: dynamicCountry!) { shipTo(country: $country) }`
+	result, err := g.ProcessRequest(ctx, query, `{"country": "CA"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"shipTo":"CA"}}`, result)
+	_, err = g.ProcessRequest(ctx, query, `{"country": "MX"}`)
+	assert.ErrorContains(t, err, "invalid enum value MX")
+
+	// Values in lists and input objects are checked as well.
+	result, err = g.ProcessRequest(ctx, `{ addresses(addresses: [{Street: "a", Country: US}]) }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"addresses":1}}`, result)
+	query = `query count($addresses: [dynamicAddressInput!]!) { addresses(addresses: $addresses) }`
+	_, err = g.ProcessRequest(ctx, query, `{"addresses": [{"Street": "a", "Country": "US"}, {"Street": "b", "Country": "MX"}]}`)
+	assert.ErrorContains(t, err, "invalid enum value MX")
+}
+
+func TestGraphy_RegisterDynamicEnum_Errors(t *testing.T) {
+	ctx := context.Background()
+	countries := &dynamicCountries{values: []string{"CA"}}
+	g := Graphy{}
+	g.RegisterQuery(ctx, "shipTo", func(country dynamicCountry) dynamicCountry {
+		return country
+	}, "country")
+	err := g.RegisterDynamicEnum(ctx, "dynamicCountry", countries.load, 0)
+	assert.NoError(t, err)
+
+	// Failed refreshes keep the previous values.
+	countries.err = errors.New("datastore down")
+	err = g.RefreshDynamicEnum(ctx, "dynamicCountry")
+	assert.EqualError(t, err, "error loading the values of enum dynamicCountry: datastore down")
+	result, err := g.ProcessRequest(ctx, `{ shipTo(country: CA) }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"shipTo":"CA"}}`, result)
+
+	err = g.RegisterDynamicEnum(ctx, "Other", countries.load, 0)
+	assert.EqualError(t, err, "error loading the values of enum Other: datastore down")
+	err = g.RefreshDynamicEnum(ctx, "Other")
+	assert.EqualError(t, err, "unknown dynamic enum Other")
+}
+
+func TestGraphy_RegisterDynamicEnum_RefreshInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	countries := &dynamicCountries{values: []string{"CA"}}
+	g := &Graphy{}
+	g.RegisterQuery(ctx, "shipTo", func(country dynamicCountry) dynamicCountry {
+		return country
+	}, "country")
+	err := g.RegisterDynamicEnum(ctx, "dynamicCountry", countries.load, time.Millisecond)
+	assert.NoError(t, err)
+
+	generation := g.SchemaGeneration()
+	countries.set("US")
+	assert.Eventually(t, func() bool {
+		_, err := g.ProcessRequest(ctx, `{ shipTo(country: US) }`, "")
+		return err == nil
+	}, time.Second, time.Millisecond)
+	assert.Greater(t, g.SchemaGeneration(), generation)
+}
@@ -0,0 +1,50 @@
+package quickgraph
+
+import (
+	"context"
+	"fmt"
+	"github.com/alecthomas/participle/v2/lexer"
+	"strconv"
+)
+
+// isolateElementError records the error of the element of a list at the index, with the
+// path of the element, if Graphy.IsolateListElementErrors is set. It reports whether the
+// element is to be replaced with null instead of failing the list. Errors that are caused
+// by the request being cancelled still fail the list.
+func (f *graphFunction) isolateElementError(ctx context.Context, req *request, err error, message string, pos lexer.Position, index int) bool {
+	if req == nil || !f.g.IsolateListElementErrors || (ctx != nil && ctx.Err() != nil) {
+		return false
+	}
+	var path []string
+	for _, key := range currentResultPath(ctx) {
+		path = append(path, fmt.Sprint(key))
+	}
+	path = append(path, strconv.Itoa(index))
+	err = AugmentGraphError(err, message, pos, path...)
+
+	req.elementErrorsMu.Lock()
+	defer req.elementErrorsMu.Unlock()
+	req.elementErrors = append(req.elementErrors, err)
+	return true
+}
+
+// takeElementErrors returns the errors of the elements of lists that were replaced with
+// null since it was last called, for the payload that is being generated.
+func (r *request) takeElementErrors() []error {
+	r.elementErrorsMu.Lock()
+	defer r.elementErrorsMu.Unlock()
+	errs := r.elementErrors
+	r.elementErrors = nil
+	return errs
+}
+
+// addElementErrors adds the errors of the elements of lists that were replaced with null
+// to the entry of an incremental payload.
+func (r *request) addElementErrors(entry map[string]any) {
+	errs := r.takeElementErrors()
+	if len(errs) == 0 {
+		return
+	}
+	existing, _ := entry["errors"].([]error)
+	entry["errors"] = append(existing, errs...)
+}
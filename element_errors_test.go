@@ -0,0 +1,80 @@
+package quickgraph
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type feedPost struct {
+	Id int
+}
+
+func (p feedPost) Author() (string, error) {
+	if p.Id == 3 {
+		return "", errors.New("author service unavailable")
+	}
+	return "ann", nil
+}
+
+func TestGraphy_IsolateListElementErrors(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "posts", func() []feedPost {
+		return []feedPost{{Id: 1}, {Id: 2}, {Id: 3}, {Id: 4}}
+	})
+
+	// By default the error of an element fails the whole list.
+	result, err := g.ProcessRequest(ctx, `{ posts { Id Author } }`, "")
+	assert.Error(t, err)
+	assert.Equal(t, `{"data":{},"errors":[{"message":"function Author returned error: author service unavailable","locations":[{"line":1,"column":14}],"path":["posts","2","Author"]}]}`, result)
+
+	g.IsolateListElementErrors = true
+	result, err = g.ProcessRequest(ctx, `{ feed: posts { Id Author } }`, "")
+	assert.ErrorContains(t, err, "author service unavailable")
+	assert.Equal(t, `{"data":{"feed":[{"Author":"ann","Id":1},{"Author":"ann","Id":2},null,{"Author":"ann","Id":4}]},"errors":[{"message":"function Author returned error: author service unavailable","locations":[{"line":1,"column":20}],"path":["feed","2","Author"]}]}`, result)
+
+	// The elements that are generated concurrently are isolated as well.
+	g.QueryLimits = &QueryLimits{MaxConcurrentResolvers: 2}
+	result, _ = g.ProcessRequest(ctx, `{ posts { Author } }`, "")
+	assert.Equal(t, `{"data":{"posts":[{"Author":"ann"},{"Author":"ann"},null,{"Author":"ann"}]},"errors":[{"message":"function Author returned error: author service unavailable","locations":[{"line":1,"column":11}],"path":["posts","2","Author"]}]}`, result)
+}
+
+type cancelFeedItem struct {
+	Id int
+
+	cancel  context.CancelFunc
+	release chan struct{}
+}
+
+func (c cancelFeedItem) Value() int {
+	if c.Id < 2 {
+		<-c.release
+	} else if c.Id == 2 {
+		c.cancel()
+		close(c.release)
+	}
+	return c.Id * 10
+}
+
+func TestGraphy_IsolateListElementErrors_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g := Graphy{IsolateListElementErrors: true, QueryLimits: &QueryLimits{MaxConcurrentResolvers: 2}}
+	g.RegisterQuery(ctx, "items", func() []cancelFeedItem {
+		release := make(chan struct{})
+		var items []cancelFeedItem
+		for i := 0; i < 5; i++ {
+			items = append(items, cancelFeedItem{Id: i, cancel: cancel, release: release})
+		}
+		return items
+	})
+
+	// The first two elements hold the resolver slots until the third, which is resolved in
+	// the request's goroutine, cancels the request. The rest aren't started, so the list
+	// fails rather than having nulls for them.
+	result, err := g.ProcessRequest(ctx, `{ items { Id Value } }`, "")
+	assert.ErrorContains(t, err, "context canceled")
+	assert.NotContains(t, result, `"items":[`)
+}
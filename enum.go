@@ -1,8 +1,12 @@
 package quickgraph
 
-import "reflect"
+import (
+	"context"
+	"reflect"
+)
 
 var enumUnmarshalerType = reflect.TypeOf((*EnumUnmarshaler)(nil)).Elem()
+var enumUnmarshalerWithContextType = reflect.TypeOf((*EnumUnmarshalerWithContext)(nil)).Elem()
 var stringEnumValuesType = reflect.TypeOf((*StringEnumValues)(nil)).Elem()
 
 // EnumUnmarshaler provides an interface for types that can unmarshal
@@ -16,6 +20,14 @@ type EnumUnmarshaler interface {
 	UnmarshalString(input string) (interface{}, error)
 }
 
+// EnumUnmarshalerWithContext is like EnumUnmarshaler, but it also receives the context
+// of the request. This allows the mapping of the input to depend on things like the
+// locale or the tenant of the request. If a type implements both interfaces, this one is
+// used.
+type EnumUnmarshalerWithContext interface {
+	UnmarshalStringContext(ctx context.Context, input string) (interface{}, error)
+}
+
 // StringEnumValues provides an interface for types that can return
 // a list of valid string representations for their enumeration.
 // This can be useful in scenarios like validation or auto-generation
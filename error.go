@@ -17,13 +17,15 @@ import (
 // - Locations: A slice of ErrorLocation structs that detail where in the source the error occurred.
 // - Path: Represents the path in the graph where the error occurred.
 // - Extensions: A map containing additional error information not part of the standard fields.
+// - ValidationErrors: The messages for the fields of invalid input, serialized as the "validationErrors" extension.
 // - InnerError: An underlying error that might have caused this GraphError. It is not serialized to JSON.
 type GraphError struct {
-	Message    string            `json:"message"`
-	Locations  []ErrorLocation   `json:"locations,omitempty"`
-	Path       []string          `json:"path,omitempty"`
-	Extensions map[string]string `json:"extensions,omitempty"`
-	InnerError error             `json:"-"`
+	Message          string            `json:"message"`
+	Locations        []ErrorLocation   `json:"locations,omitempty"`
+	Path             []string          `json:"path,omitempty"`
+	Extensions       map[string]string `json:"extensions,omitempty"`
+	ValidationErrors map[string]string `json:"-"`
+	InnerError       error             `json:"-"`
 }
 
 // ErrorLocation provides details about where in the source a particular error occurred.
@@ -94,11 +96,14 @@ func lexerPositionError(pos lexer.Position) ErrorLocation {
 // a GraphError structure. This function serves to enrich errors with Graph-specific details
 // such as file position, path, and a custom message.
 //
-// The function primarily handles two cases:
+// The function primarily handles three cases:
 //  1. If the passed error is already a GraphError, it augments the existing GraphError with the
 //     provided context without losing any existing data.
 //  2. If the passed error is not a GraphError, it wraps it within a new GraphError, setting
 //     the provided context.
+//  3. If the passed error joins several errors, such as one from errors.Join, each of them is
+//     augmented separately and the results are joined again, so they remain separate errors
+//     in the response.
 //
 // Parameters:
 //   - err: The error to be wrapped or augmented. It can be a regular error or a GraphError.
@@ -110,8 +115,17 @@ func lexerPositionError(pos lexer.Position) ErrorLocation {
 //     These are prepended to any existing paths in a GraphError.
 //
 // Returns:
-// - A GraphError containing the augmented or wrapped error details.
+// - A GraphError containing the augmented or wrapped error details, or the joined GraphErrors.
 func AugmentGraphError(err error, message string, pos lexer.Position, paths ...string) error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs := joined.Unwrap()
+		augmented := make([]error, len(errs))
+		for i, inner := range errs {
+			augmented[i] = AugmentGraphError(inner, message, pos, append([]string(nil), paths...)...)
+		}
+		return errors.Join(augmented...)
+	}
+
 	var gErr GraphError
 
 	// We should never have a regular error wrapping a GraphError. If that ever happens
@@ -217,10 +231,10 @@ func (e GraphError) MarshalJSON() ([]byte, error) {
 	// We need to create a new type that has all of the fields of GraphError
 	// except for InnerError. We can then marshal that type.
 	type graphErrorNoInnerError struct {
-		Message    string            `json:"message"`
-		Locations  []ErrorLocation   `json:"locations,omitempty"`
-		Path       []string          `json:"path,omitempty"`
-		Extensions map[string]string `json:"extensions,omitempty"`
+		Message    string          `json:"message"`
+		Locations  []ErrorLocation `json:"locations,omitempty"`
+		Path       []string        `json:"path,omitempty"`
+		Extensions map[string]any  `json:"extensions,omitempty"`
 	}
 
 	// Create a new instance of the new type and copy the fields over.
@@ -228,7 +242,15 @@ func (e GraphError) MarshalJSON() ([]byte, error) {
 	gErr.Message = e.Message
 	gErr.Locations = e.Locations
 	gErr.Path = e.Path
-	gErr.Extensions = e.Extensions
+	if len(e.Extensions) > 0 || len(e.ValidationErrors) > 0 {
+		gErr.Extensions = map[string]any{}
+		for key, value := range e.Extensions {
+			gErr.Extensions[key] = value
+		}
+		if len(e.ValidationErrors) > 0 {
+			gErr.Extensions["validationErrors"] = e.ValidationErrors
+		}
+	}
 
 	// If there is an inner error, append that to the message.
 	if e.InnerError != nil {
@@ -257,15 +279,8 @@ func (e *GraphError) AddExtension(key string, value string) {
 
 func formatError(errs ...error) string {
 	var resultErrors []GraphError
-	for _, err := range errs {
-		var ge GraphError
-		if !errors.As(err, &ge) {
-			ge = GraphError{
-				Message:    err.Error(),
-				InnerError: err,
-			}
-		}
-		resultErrors = append(resultErrors, ge)
+	for _, err := range splitErrors(errs) {
+		resultErrors = append(resultErrors, toGraphError(err))
 	}
 	resultMap := map[string]any{
 		"errors": resultErrors,
@@ -274,6 +289,31 @@ func formatError(errs ...error) string {
 	return string(resultJson)
 }
 
+// splitErrors replaces the errors that were joined with the errors that they join, since
+// those are reported separately.
+func splitErrors(errs []error) []error {
+	var result []error
+	for _, err := range errs {
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			result = append(result, splitErrors(joined.Unwrap())...)
+			continue
+		}
+		result = append(result, err)
+	}
+	return result
+}
+
+func toGraphError(err error) GraphError {
+	var ge GraphError
+	if !errors.As(err, &ge) {
+		ge = GraphError{
+			Message:    err.Error(),
+			InnerError: err,
+		}
+	}
+	return ge
+}
+
 func (e UnknownCommandError) Unwrap() error {
 	return e.GraphError
 }
@@ -0,0 +1,149 @@
+package quickgraph
+
+import (
+	"context"
+)
+
+// ErrorCategory is the category of an error for an ErrorPolicy, which is the "code" in
+// its extensions, such as ErrorCodeUnauthorized. The errors without a code are in
+// ErrorCategoryDefault.
+type ErrorCategory string
+
+// ErrorCategoryDefault is the category of the errors that don't have a code, which
+// includes the errors that the functions return. Its redactor also applies to the
+// categories that don't have one of their own.
+const ErrorCategoryDefault ErrorCategory = ""
+
+// TrustLevel is how far the caller of a request is trusted with the details of errors.
+type TrustLevel int
+
+const (
+	// TrustPublic is for callers from outside, such as the clients of a public API.
+	TrustPublic TrustLevel = iota
+
+	// TrustInternal is for callers such as internal tooling and other services, which
+	// benefit from the details of errors.
+	TrustInternal
+)
+
+// ErrorRedactor changes an error before it is sent to a caller with the trust level. It
+// can remove details, such as the cause of the error, or add ones, such as information
+// from the claims of the caller, which are in the context.
+type ErrorRedactor func(ctx context.Context, trust TrustLevel, err *GraphError)
+
+// ErrorPolicy decides which details of errors are sent to the callers of requests,
+// depending on how far each caller is trusted. This goes beyond DevelopmentMode, which
+// is the same for all callers. Internal tooling can get the complete errors while
+// public traffic gets sanitized ones:
+//
+//	g.ErrorPolicy = &quickgraph.ErrorPolicy{
+//		TrustLevel: func(ctx context.Context) quickgraph.TrustLevel {
+//			claims, _ := auth.ClaimsFromContext(ctx)
+//			if claims.Issuer() == "https://internal.example.com" {
+//				return quickgraph.TrustInternal
+//			}
+//			return quickgraph.TrustPublic
+//		},
+//		Redactors: map[quickgraph.ErrorCategory]quickgraph.ErrorRedactor{
+//			quickgraph.ErrorCategoryDefault: quickgraph.RedactPublic("internal error"),
+//			quickgraph.ErrorCodeUnauthorized: quickgraph.RedactPublic(""),
+//		},
+//	}
+//
+// Only the errors in the responses are redacted. The errors that ProcessRequest returns,
+// which are usually logged, are complete.
+type ErrorPolicy struct {
+	// TrustLevel returns the trust level of the caller of a request from its context. If
+	// it is nil, all callers are TrustPublic.
+	TrustLevel func(ctx context.Context) TrustLevel
+
+	// Redactors are the redactors for the categories of errors. The errors in categories
+	// without one use the one for ErrorCategoryDefault, if there is one.
+	Redactors map[ErrorCategory]ErrorRedactor
+}
+
+// RedactPublic returns an ErrorRedactor that removes the details of errors for
+// TrustPublic callers. Their message is replaced with the message, unless it is "", and
+// the error that caused them, whose message is otherwise appended to theirs, is removed,
+// along with their extensions other than the code and the ones to keep. The
+// "validationErrors" extension can be kept as well. Errors for TrustInternal callers
+// are unchanged.
+func RedactPublic(message string, keep ...string) ErrorRedactor {
+	return func(ctx context.Context, trust TrustLevel, err *GraphError) {
+		if trust != TrustPublic {
+			return
+		}
+		if message != "" {
+			err.Message = message
+		}
+		err.InnerError = nil
+
+		kept := map[string]bool{"code": true}
+		for _, key := range keep {
+			kept[key] = true
+		}
+		var extensions map[string]string
+		for key, value := range err.Extensions {
+			if kept[key] {
+				if extensions == nil {
+					extensions = map[string]string{}
+				}
+				extensions[key] = value
+			}
+		}
+		err.Extensions = extensions
+		if !kept["validationErrors"] {
+			err.ValidationErrors = nil
+		}
+	}
+}
+
+// redact applies the policy to the errors of a request for its caller.
+func (p *ErrorPolicy) redact(ctx context.Context, errs []error) []error {
+	trust := TrustPublic
+	if p.TrustLevel != nil {
+		trust = p.TrustLevel(ctx)
+	}
+
+	var result []error
+	// Errors that were joined are reported separately.
+	for _, err := range splitErrors(errs) {
+		gErr := toGraphError(err)
+		redactor, ok := p.Redactors[ErrorCategory(gErr.Extensions["code"])]
+		if !ok {
+			redactor = p.Redactors[ErrorCategoryDefault]
+		}
+		if redactor != nil {
+			// The extensions may be shared with the error that is returned.
+			gErr.Extensions = copyExtensions(gErr.Extensions)
+			redactor(ctx, trust, &gErr)
+		}
+		result = append(result, gErr)
+	}
+	return result
+}
+
+// redactErrors applies the ErrorPolicy, if there is one, to the errors of a request.
+func (g *Graphy) redactErrors(ctx context.Context, errs []error) []error {
+	if g.ErrorPolicy == nil {
+		return errs
+	}
+	return g.ErrorPolicy.redact(ctx, errs)
+}
+
+// formatRequestError formats the error of a request that failed for the response, with
+// the ErrorPolicy applied to it.
+func (g *Graphy) formatRequestError(ctx context.Context, err error) string {
+	return formatError(g.redactErrors(ctx, []error{err})...)
+}
+
+func copyExtensions(extensions map[string]string) map[string]string {
+	if extensions == nil {
+		return nil
+	}
+	result := make(map[string]string, len(extensions))
+	for key, value := range extensions {
+		result[key] = value
+	}
+	return result
+}
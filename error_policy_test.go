@@ -0,0 +1,77 @@
+package quickgraph
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type errorPolicyCallerKey struct{}
+
+func TestGraphy_ErrorPolicy(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "balance", func() (int, error) {
+		return 0, errors.New("connection to db-7.internal refused")
+	})
+	g.RegisterQuery(ctx, "panics", func() int { panic("oops") })
+	g.OperationAccess = &OperationAccess{Public: []string{"balance", "panics"}}
+	g.ErrorPolicy = &ErrorPolicy{
+		TrustLevel: func(ctx context.Context) TrustLevel {
+			if ctx.Value(errorPolicyCallerKey{}) == "tooling" {
+				return TrustInternal
+			}
+			return TrustPublic
+		},
+		Redactors: map[ErrorCategory]ErrorRedactor{
+			ErrorCategoryDefault: RedactPublic("internal error"),
+			ErrorCodeUnauthorized: func(ctx context.Context, trust TrustLevel, err *GraphError) {
+				if trust == TrustInternal {
+					err.AddExtension("caller", ctx.Value(errorPolicyCallerKey{}).(string))
+				}
+			},
+		},
+	}
+	internal := context.WithValue(ctx, errorPolicyCallerKey{}, "tooling")
+
+	result, err := g.ProcessRequest(ctx, `{ balance }`, "")
+	assert.ErrorContains(t, err, "db-7.internal")
+	assert.Equal(t, `{"data":{},"errors":[{"message":"internal error","locations":[{"line":1,"column":3}],"path":["balance"]}]}`, result)
+	result, _ = g.ProcessRequest(internal, `{ balance }`, "")
+	assert.Equal(t, `{"data":{},"errors":[{"message":"function balance returned error: connection to db-7.internal refused","locations":[{"line":1,"column":3}],"path":["balance"]}]}`, result)
+
+	// The stack of a panic is only for internal callers.
+	result, _ = g.ProcessRequest(ctx, `{ panics }`, "")
+	assert.Equal(t, `{"data":{},"errors":[{"message":"internal error","locations":[{"line":1,"column":3}],"path":["panics"]}]}`, result)
+	result, _ = g.ProcessRequest(internal, `{ panics }`, "")
+	assert.Contains(t, result, `"stack":`)
+
+	// Errors with codes use the redactors for their categories, and the ones that fail
+	// the whole request are redacted as well.
+	result, _ = g.ProcessRequest(internal, `{ secret }`, "")
+	assert.Contains(t, result, "unknown command")
+	result, _ = g.ProcessRequest(ctx, `{ secret }`, "")
+	assert.Equal(t, `{"errors":[{"message":"internal error","locations":[{"line":1,"column":3}]}]}`, result)
+	g.OperationAccess.Public = nil
+	result, _ = g.ProcessRequest(internal, `{ balance }`, "")
+	assert.Equal(t, `{"errors":[{"message":"not authorized to call balance","locations":[{"line":1,"column":3}],"path":["balance"],"extensions":{"caller":"tooling","code":"UNAUTHORIZED"}}]}`, result)
+}
+
+func TestRedactPublic(t *testing.T) {
+	ctx := context.Background()
+	gErr := GraphError{
+		Message:          "invalid value for input",
+		Extensions:       map[string]string{"code": "BAD_USER_INPUT", "detail": "x", "hint": "y"},
+		ValidationErrors: map[string]string{"input.email": "is not valid"},
+		InnerError:       errors.New("details"),
+	}
+
+	redacted := gErr
+	RedactPublic("", "hint", "validationErrors")(ctx, TrustPublic, &redacted)
+	assert.Equal(t, `{"errors":[{"message":"invalid value for input","extensions":{"code":"BAD_USER_INPUT","hint":"y","validationErrors":{"input.email":"is not valid"}}}]}`, formatError(redacted))
+
+	redacted = gErr
+	RedactPublic("")(ctx, TrustInternal, &redacted)
+	assert.Equal(t, gErr, redacted)
+}
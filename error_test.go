@@ -158,3 +158,44 @@ func Test_formatError(t *testing.T) {
 	msg := formatError(err1, err2)
 	assert.Equal(t, `{"errors":[{"message":"random error: random error"},{"message":"graph error","locations":[{"line":1,"column":1}]}]}`, msg)
 }
+
+func TestGraphy_MultipleErrors(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterMutation(ctx, "importUsers", func(names []string) ([]string, []error) {
+		var imported []string
+		var errs []error
+		for i, name := range names {
+			if name == "" {
+				gErr := GraphError{Message: "name is empty", Path: []string{fmt.Sprint(i)}}
+				gErr.AddExtension("code", "BAD_USER_INPUT")
+				errs = append(errs, gErr)
+				continue
+			}
+			imported = append(imported, name)
+		}
+		return imported, errs
+	}, "names")
+	g.RegisterQuery(ctx, "check", func() (string, error) {
+		return "", errors.Join(errors.New("disk full"), GraphError{Message: "quota exceeded", Path: []string{"quota"}})
+	})
+
+	// The result is kept along with a separate error for each of the errors.
+	result, err := g.ProcessRequest(ctx, `mutation { importUsers(names: ["ann", "", "bo", ""]) }`, "")
+	assert.Error(t, err)
+	assert.Equal(t, `{"data":{"importUsers":["ann","bo"]},"errors":[`+
+		`{"message":"name is empty","locations":[{"line":1,"column":24}],"path":["importUsers","1"],"extensions":{"code":"BAD_USER_INPUT"}},`+
+		`{"message":"name is empty","locations":[{"line":1,"column":24}],"path":["importUsers","3"],"extensions":{"code":"BAD_USER_INPUT"}}]}`, result)
+	assert.Equal(t, "BAD_USER_INPUT", ErrorCode(err))
+
+	result, err = g.ProcessRequest(ctx, `mutation { importUsers(names: ["ann"]) }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"importUsers":["ann"]}}`, result)
+
+	// Errors that implement Unwrap() []error are reported separately as well.
+	result, err = g.ProcessRequest(ctx, `{ check }`, "")
+	assert.Error(t, err)
+	assert.Equal(t, `{"data":{},"errors":[`+
+		`{"message":"function check returned error: disk full","locations":[{"line":1,"column":3}],"path":["check"]},`+
+		`{"message":"quota exceeded","locations":[{"line":1,"column":3}],"path":["check","quota"]}]}`, result)
+}
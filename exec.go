@@ -0,0 +1,98 @@
+package quickgraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// DefaultMaxExecDepth is how deeply operations run with Exec may be nested if
+// Graphy.MaxExecDepth isn't set.
+const DefaultMaxExecDepth = 4
+
+// execFrame is an operation that is being run with Exec. The frames of the operations
+// that are nested in each other form a chain through the context.
+type execFrame struct {
+	parent    *execFrame
+	graphy    *Graphy
+	query     string
+	variables string
+	depth     int
+}
+
+// execDepth returns how many operations run with Exec the context is nested in.
+func execDepth(ctx context.Context) int {
+	frame, ok := ctx.Value(execContextKey).(*execFrame)
+	if !ok {
+		return 0
+	}
+	return frame.depth
+}
+
+// Exec runs a query or mutation on g from within a function that is handling a request,
+// so that composite functions, such as those that gather the results of several
+// operations into one, can reuse the operations that are already registered instead of
+// duplicating their logic. It returns the "data" of the result, decoded from JSON, and
+// the error of the operation, if any; both may be set if some of its fields failed.
+//
+// The operation runs with the context of the caller, so the same roles, feature flags,
+// and other values apply to it. Operations that run themselves again with the same
+// variables are rejected as loops, and nesting is limited to Graphy.MaxExecDepth levels.
+// Exec can also be used outside of a request, in which case it is the same as
+// ProcessRequest, except that TrustedDocumentsOnly doesn't apply.
+func Exec(ctx context.Context, g *Graphy, query string, variables map[string]any) (map[string]any, error) {
+	var variableJson string
+	if len(variables) > 0 {
+		payload, err := json.Marshal(variables)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding variables: %w", err)
+		}
+		variableJson = string(payload)
+	}
+
+	parent, _ := ctx.Value(execContextKey).(*execFrame)
+	frame := &execFrame{
+		parent:    parent,
+		graphy:    g,
+		query:     query,
+		variables: variableJson,
+		depth:     1,
+	}
+	if parent != nil {
+		frame.depth = parent.depth + 1
+	}
+	maxDepth := g.MaxExecDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxExecDepth
+	}
+	if frame.depth > maxDepth {
+		return nil, NewGraphError(fmt.Sprintf("nested operations exceed the maximum depth of %d", maxDepth), lexer.Position{})
+	}
+	for f := parent; f != nil; f = f.parent {
+		if f.graphy == g && f.query == query && f.variables == variableJson {
+			return nil, NewGraphError("nested operation runs itself again", lexer.Position{})
+		}
+	}
+	ctx = context.WithValue(ctx, execContextKey, frame)
+
+	var result string
+	var err error
+	if req, ok := ctx.Value(requestContextKey).(*request); ok && req.graphy == g && req.executing.Load() {
+		// The request that is calling holds the structure lock, which can't be taken again
+		// without risking a deadlock with a registration that is waiting for it. Types
+		// that are registered lazily can't be resolved while it's held, either.
+		result, err = g.processRequestLocked(ctx, query, variableJson)
+	} else {
+		result, err = g.processRequestWithTypes(ctx, query, variableJson)
+	}
+
+	var response struct {
+		Data map[string]any `json:"data"`
+	}
+	jsonErr := json.Unmarshal([]byte(result), &response)
+	if jsonErr != nil && err == nil {
+		err = jsonErr
+	}
+	return response.Data, err
+}
@@ -0,0 +1,76 @@
+package quickgraph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type execDashboard struct {
+	Greeting string
+	Count    float64
+}
+
+func TestExec(t *testing.T) {
+	ctx := context.Background()
+	g := &Graphy{}
+	g.RegisterQuery(ctx, "greeting", func(name string) string {
+		return "Hello, " + name
+	}, "name")
+	g.RegisterQuery(ctx, "count", func() int {
+		return 42
+	})
+	g.RegisterQuery(ctx, "dashboard", func(ctx context.Context, name string) (execDashboard, error) {
+		data, err := Exec(ctx, g, `query Dash($name: String!) { greeting(name: $name) count }`, map[string]any{"name": name})
+		if err != nil {
+			return execDashboard{}, err
+		}
+		return execDashboard{
+			Greeting: data["greeting"].(string),
+			Count:    data["count"].(float64),
+		}, nil
+	}, "name")
+
+	result, err := g.ProcessRequest(ctx, `{ dashboard(name: "Ada") { Greeting Count } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"dashboard":{"Count":42,"Greeting":"Hello, Ada"}}}`, result)
+
+	// Outside of a request, Exec processes the request on its own.
+	data, err := Exec(ctx, g, `{ greeting(name: "Bob") }`, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"greeting": "Hello, Bob"}, data)
+}
+
+func TestExec_Loop(t *testing.T) {
+	ctx := context.Background()
+	g := &Graphy{}
+	g.RegisterQuery(ctx, "again", func(ctx context.Context) (string, error) {
+		_, err := Exec(ctx, g, `{ again }`, nil)
+		return "", err
+	})
+
+	_, err := g.ProcessRequest(ctx, `{ again }`, "")
+	assert.ErrorContains(t, err, "nested operation runs itself again")
+}
+
+func TestExec_MaxDepth(t *testing.T) {
+	ctx := context.Background()
+	g := &Graphy{MaxExecDepth: 2}
+	g.RegisterQuery(ctx, "countdown", func(ctx context.Context, n int) (int, error) {
+		if n == 0 {
+			return 0, nil
+		}
+		data, err := Exec(ctx, g, `query Down($n: Int!) { countdown(n: $n) }`, map[string]any{"n": n - 1})
+		if err != nil {
+			return 0, err
+		}
+		return int(data["countdown"].(float64)) + 1, nil
+	}, "n")
+
+	result, err := g.ProcessRequest(ctx, `{ countdown(n: 2) }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"countdown":2}}`, result)
+
+	_, err = g.ProcessRequest(ctx, `{ countdown(n: 3) }`, "")
+	assert.ErrorContains(t, err, "nested operations exceed the maximum depth of 2")
+}
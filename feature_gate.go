@@ -0,0 +1,37 @@
+package quickgraph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrorCodeFeatureDisabled is the code in the extensions of the errors for operations
+// that FeatureGate turned off.
+const ErrorCodeFeatureDisabled = "FEATURE_DISABLED"
+
+// ErrFeatureDisabled can be returned by a FeatureGate for an operation that is turned off
+// when there is nothing more specific to say.
+var ErrFeatureDisabled = errors.New("feature disabled")
+
+// checkFeatureGate consults the FeatureGate, if there is one, before a query or mutation
+// is called. The error it returns is reported for the command with the
+// ErrorCodeFeatureDisabled code, and the other commands of the request are unaffected.
+func (g *Graphy) checkFeatureGate(ctx context.Context, cmd command) error {
+	if g.FeatureGate == nil || strings.HasPrefix(cmd.Name, "__") {
+		return nil
+	}
+	err := g.FeatureGate(ctx, cmd.Name)
+	if err == nil {
+		return nil
+	}
+	var gErr GraphError
+	if !errors.As(err, &gErr) {
+		gErr = GraphError{Message: fmt.Sprintf("%s is disabled", cmd.Name), InnerError: err}
+	}
+	if _, ok := gErr.Extensions["code"]; !ok {
+		gErr.AddExtension("code", ErrorCodeFeatureDisabled)
+	}
+	return AugmentGraphError(gErr, "", cmd.Pos, cmd.Name)
+}
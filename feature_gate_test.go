@@ -0,0 +1,66 @@
+package quickgraph
+
+import (
+	"context"
+	"errors"
+	"github.com/alecthomas/participle/v2/lexer"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type betaUserKey struct{}
+
+func TestFeatureGate(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "stable", func() string { return "stable" })
+	g.RegisterQuery(ctx, "beta", func() string { return "beta" })
+	g.RegisterQuery(ctx, "retired", func() string { return "retired" })
+	g.EnableIntrospection(ctx)
+	g.FeatureGate = func(ctx context.Context, operation string) error {
+		switch operation {
+		case "beta":
+			if ctx.Value(betaUserKey{}) == nil {
+				return ErrFeatureDisabled
+			}
+		case "retired":
+			gErr := NewGraphError("retired has been retired", lexer.Position{})
+			gErr.AddExtension("code", "GONE")
+			return gErr
+		}
+		return nil
+	}
+
+	result, err := g.ProcessRequest(ctx, `{ stable beta }`, "")
+	assert.Error(t, err)
+	assert.Equal(t, `{"data":{"stable":"stable"},"errors":[{"message":"beta is disabled: feature disabled","locations":[{"line":1,"column":10}],"path":["beta"],"extensions":{"code":"FEATURE_DISABLED"}}]}`, result)
+
+	betaCtx := context.WithValue(ctx, betaUserKey{}, true)
+	result, err = g.ProcessRequest(betaCtx, `{ stable beta }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"beta":"beta","stable":"stable"}}`, result)
+
+	// A code that the gate sets is kept.
+	result, err = g.ProcessRequest(ctx, `{ retired }`, "")
+	assert.Error(t, err)
+	assert.Equal(t, `{"data":{},"errors":[{"message":"retired has been retired","locations":[{"line":1,"column":3}],"path":["retired"],"extensions":{"code":"GONE"}}]}`, result)
+
+	// Introspection isn't gated.
+	_, err = g.ProcessRequest(ctx, `{ __schema { queryType { name } } }`, "")
+	assert.NoError(t, err)
+}
+
+func TestFeatureGate_DryRun(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	called := false
+	g.RegisterQuery(ctx, "beta", func() string { called = true; return "beta" })
+	g.FeatureGate = func(ctx context.Context, operation string) error {
+		return errors.New("beta is not available yet")
+	}
+
+	result, err := g.ProcessRequest(WithDryRun(ctx), `{ beta }`, "")
+	assert.Error(t, err)
+	assert.False(t, called)
+	assert.Equal(t, `{"data":{},"errors":[{"message":"beta is disabled: beta is not available yet","locations":[{"line":1,"column":3}],"path":["beta"],"extensions":{"code":"FEATURE_DISABLED"}}]}`, result)
+}
@@ -0,0 +1,172 @@
+package quickgraph
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fieldStatsBuckets are the upper bounds of the latency histogram that is kept for each
+// field.
+var fieldStatsBuckets = []time.Duration{
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+}
+
+// FieldStats are the statistics for resolving a single field of a type, as collected
+// when Graphy.EnableFieldStats is set. The functions that are called for queries and
+// mutations are reported as fields of the Query and Mutation types.
+type FieldStats struct {
+	TypeName  string
+	FieldName string
+
+	// Count is the number of times the field was resolved, and Errors is how many of
+	// those returned an error.
+	Count  int64
+	Errors int64
+
+	TotalDuration time.Duration
+	MaxDuration   time.Duration
+
+	// Buckets is a histogram of the time it took to resolve the field. Each bucket
+	// counts the resolutions that took at most its UpperBound, so the counts are
+	// cumulative. Resolutions that took longer than the last bucket are only part of
+	// Count.
+	Buckets []LatencyBucket
+}
+
+// LatencyBucket is a single bucket of the latency histogram in FieldStats.
+type LatencyBucket struct {
+	UpperBound time.Duration
+	Count      int64
+}
+
+// AverageDuration returns the average time it took to resolve the field.
+func (fs FieldStats) AverageDuration() time.Duration {
+	if fs.Count == 0 {
+		return 0
+	}
+	return fs.TotalDuration / time.Duration(fs.Count)
+}
+
+type fieldStatsKey struct {
+	typeName  string
+	fieldName string
+}
+
+// fieldStatsCollector accumulates the FieldStats for a Graphy.
+type fieldStatsCollector struct {
+	mu    sync.Mutex
+	stats map[fieldStatsKey]*FieldStats
+}
+
+// recordFieldStats records a single resolution of a field. The callers only call this
+// when EnableFieldStats is set so that the time isn't measured otherwise.
+func (g *Graphy) recordFieldStats(typeName, fieldName string, duration time.Duration, err error) {
+	g.fieldStats.mu.Lock()
+	defer g.fieldStats.mu.Unlock()
+
+	if g.fieldStats.stats == nil {
+		g.fieldStats.stats = map[fieldStatsKey]*FieldStats{}
+	}
+	key := fieldStatsKey{typeName: typeName, fieldName: fieldName}
+	fs, ok := g.fieldStats.stats[key]
+	if !ok {
+		fs = &FieldStats{
+			TypeName:  typeName,
+			FieldName: fieldName,
+			Buckets:   make([]LatencyBucket, len(fieldStatsBuckets)),
+		}
+		for i, bound := range fieldStatsBuckets {
+			fs.Buckets[i].UpperBound = bound
+		}
+		g.fieldStats.stats[key] = fs
+	}
+
+	fs.Count++
+	if err != nil {
+		fs.Errors++
+	}
+	fs.TotalDuration += duration
+	if duration > fs.MaxDuration {
+		fs.MaxDuration = duration
+	}
+	for i := range fs.Buckets {
+		if duration <= fs.Buckets[i].UpperBound {
+			fs.Buckets[i].Count++
+		}
+	}
+}
+
+// FieldStatsSnapshot returns a copy of the field statistics that have been collected so
+// far, sorted by type and field name. Statistics are only collected while
+// EnableFieldStats is set.
+func (g *Graphy) FieldStatsSnapshot() []FieldStats {
+	g.fieldStats.mu.Lock()
+	defer g.fieldStats.mu.Unlock()
+
+	result := make([]FieldStats, 0, len(g.fieldStats.stats))
+	for _, fs := range g.fieldStats.stats {
+		c := *fs
+		c.Buckets = append([]LatencyBucket(nil), fs.Buckets...)
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TypeName != result[j].TypeName {
+			return result[i].TypeName < result[j].TypeName
+		}
+		return result[i].FieldName < result[j].FieldName
+	})
+	return result
+}
+
+// LogSlowFields starts logging the topN fields with the highest average resolution time
+// every interval, until the context is done. This is a lightweight way to find out where
+// the time is going without needing an external APM system.
+func (g *Graphy) LogSlowFields(ctx context.Context, interval time.Duration, topN int) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if report := g.slowFieldReport(topN); report != "" {
+					log.Print(report)
+				}
+			}
+		}
+	}()
+}
+
+// slowFieldReport formats the topN fields with the highest average resolution time.
+func (g *Graphy) slowFieldReport(topN int) string {
+	stats := g.FieldStatsSnapshot()
+	if len(stats) == 0 {
+		return ""
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].AverageDuration() > stats[j].AverageDuration()
+	})
+	if len(stats) > topN {
+		stats = stats[:topN]
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString("Slowest fields:")
+	for i, fs := range stats {
+		sb.WriteString(fmt.Sprintf("\n%d. %s.%s avg=%v max=%v count=%d errors=%d",
+			i+1, fs.TypeName, fs.FieldName, fs.AverageDuration(), fs.MaxDuration, fs.Count, fs.Errors))
+	}
+	return sb.String()
+}
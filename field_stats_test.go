@@ -0,0 +1,79 @@
+package quickgraph
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fieldStatsTestItem struct {
+	Name string
+}
+
+func (i fieldStatsTestItem) Slow() string {
+	time.Sleep(2 * time.Millisecond)
+	return "slow"
+}
+
+func TestFieldStats(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{EnableFieldStats: true}
+	g.RegisterQuery(ctx, "items", func(ctx context.Context) []fieldStatsTestItem {
+		return []fieldStatsTestItem{{Name: "a"}, {Name: "b"}}
+	})
+	g.RegisterMutation(ctx, "fail", func(ctx context.Context) (string, error) {
+		return "", errors.New("failed")
+	})
+
+	_, err := g.ProcessRequest(ctx, `{ items { Name Slow } }`, "")
+	assert.NoError(t, err)
+	_, err = g.ProcessRequest(ctx, `mutation { fail }`, "")
+	assert.Error(t, err)
+
+	stats := g.FieldStatsSnapshot()
+	if !assert.Len(t, stats, 4) {
+		return
+	}
+	assert.Equal(t, "Mutation", stats[0].TypeName)
+	assert.Equal(t, "fail", stats[0].FieldName)
+	assert.Equal(t, int64(1), stats[0].Count)
+	assert.Equal(t, int64(1), stats[0].Errors)
+
+	assert.Equal(t, "Query", stats[1].TypeName)
+	assert.Equal(t, "items", stats[1].FieldName)
+
+	assert.Equal(t, "fieldStatsTestItem", stats[2].TypeName)
+	assert.Equal(t, "Name", stats[2].FieldName)
+	assert.Equal(t, int64(2), stats[2].Count)
+
+	slow := stats[3]
+	assert.Equal(t, "Slow", slow.FieldName)
+	assert.Equal(t, int64(2), slow.Count)
+	assert.Equal(t, int64(0), slow.Errors)
+	assert.GreaterOrEqual(t, slow.MaxDuration, 2*time.Millisecond)
+	assert.GreaterOrEqual(t, slow.AverageDuration(), 2*time.Millisecond)
+	// The buckets are cumulative; nothing took less than a millisecond.
+	assert.Equal(t, time.Millisecond, slow.Buckets[2].UpperBound)
+	assert.Equal(t, int64(0), slow.Buckets[2].Count)
+	assert.Equal(t, int64(2), slow.Buckets[len(slow.Buckets)-1].Count)
+
+	report := g.slowFieldReport(1)
+	assert.True(t, strings.HasPrefix(report, "Slowest fields:\n1. fieldStatsTestItem.Slow avg="), report)
+	assert.Equal(t, 1, strings.Count(report, "\n"))
+}
+
+func TestFieldStats_Disabled(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "items", func(ctx context.Context) []fieldStatsTestItem {
+		return []fieldStatsTestItem{{Name: "a"}}
+	})
+
+	_, err := g.ProcessRequest(ctx, `{ items { Name } }`, "")
+	assert.NoError(t, err)
+	assert.Empty(t, g.FieldStatsSnapshot())
+	assert.Equal(t, "", g.slowFieldReport(5))
+}
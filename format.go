@@ -0,0 +1,85 @@
+package quickgraph
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// TimeZoneHeader is the HTTP header that FormatContextFromHeaders reads the IANA time zone
+// of the client from, for instance "America/New_York".
+const TimeZoneHeader = "X-Time-Zone"
+
+// FormatContext describes how values should be formatted for the client of a request.
+// When a request has a FormatContext with a Location, the time.Time values in its
+// result are converted to that location before they are serialized. The Locale isn't
+// used by the library itself; it is there for functions and custom types that format
+// values for the client.
+type FormatContext struct {
+	// Locale is the BCP 47 language tag of the client, for instance "en-US".
+	Locale string
+
+	// Location is the time zone that times are rendered in. If it is nil, times are
+	// rendered as they were returned.
+	Location *time.Location
+}
+
+// WithFormatContext returns a context that causes a request processed with it to be
+// formatted according to fc.
+func WithFormatContext(ctx context.Context, fc FormatContext) context.Context {
+	return context.WithValue(ctx, formatContextKey, fc)
+}
+
+// GetFormatContext returns the FormatContext of the request that is being processed.
+// If there isn't one, the zero FormatContext is returned, which leaves the values as
+// they are.
+func GetFormatContext(ctx context.Context) FormatContext {
+	fc, _ := ctx.Value(formatContextKey).(FormatContext)
+	return fc
+}
+
+// FormatContextFromHeaders is a FormatContextExtractor that takes the locale from the
+// first language of the Accept-Language header and the time zone from the
+// TimeZoneHeader. Unknown time zones are ignored.
+func FormatContextFromHeaders(request *http.Request) FormatContext {
+	fc := FormatContext{}
+	if lang := request.Header.Get("Accept-Language"); lang != "" {
+		first := strings.Split(lang, ",")[0]
+		first = strings.TrimSpace(strings.Split(first, ";")[0])
+		if first != "*" {
+			fc.Locale = first
+		}
+	}
+	if tz := request.Header.Get(TimeZoneHeader); tz != "" {
+		if loc, err := time.LoadLocation(tz); err == nil {
+			fc.Location = loc
+		}
+	}
+	return fc
+}
+
+// dateTimeScalarName is the name of the scalar that time.Time values are represented
+// as. They are serialized as RFC 3339 strings.
+const dateTimeScalarName = "DateTime"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// formatScalar applies the FormatContext of the request to a value that is about to be
+// placed into the result.
+func formatScalar(ctx context.Context, value any) any {
+	switch v := value.(type) {
+	case time.Time:
+		if loc := GetFormatContext(ctx).Location; loc != nil {
+			return v.In(loc)
+		}
+	case *time.Time:
+		if v != nil {
+			if loc := GetFormatContext(ctx).Location; loc != nil {
+				return v.In(loc)
+			}
+		}
+	}
+	return value
+}
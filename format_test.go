@@ -0,0 +1,116 @@
+package quickgraph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type formatEvent struct {
+	Name     string
+	StartsAt time.Time
+	EndsAt   *time.Time
+}
+
+func TestFormatContextFromHeaders(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("Accept-Language", "de-CH, de;q=0.9, en;q=0.8")
+	req.Header.Set(TimeZoneHeader, "Europe/Zurich")
+
+	fc := FormatContextFromHeaders(req)
+	assert.Equal(t, "de-CH", fc.Locale)
+	assert.Equal(t, "Europe/Zurich", fc.Location.String())
+
+	req = httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("Accept-Language", "*")
+	req.Header.Set(TimeZoneHeader, "Not/AZone")
+	assert.Equal(t, FormatContext{}, FormatContextFromHeaders(req))
+}
+
+func TestFormatContext_Times(t *testing.T) {
+	ctx := context.Background()
+	starts := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	ends := starts.Add(2 * time.Hour)
+
+	g := Graphy{}
+	g.RegisterQuery(ctx, "event", func() formatEvent {
+		return formatEvent{Name: "Launch", StartsAt: starts, EndsAt: &ends}
+	})
+	g.RegisterQuery(ctx, "now", func() time.Time {
+		return starts
+	})
+
+	query := `{ event { Name StartsAt EndsAt } now }`
+
+	result, err := g.ProcessRequest(ctx, query, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"event":{"EndsAt":"2024-03-01T14:00:00Z","Name":"Launch","StartsAt":"2024-03-01T12:00:00Z"},"now":"2024-03-01T12:00:00Z"}}`, result)
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	assert.NoError(t, err)
+	result, err = g.ProcessRequest(WithFormatContext(ctx, FormatContext{Location: tokyo}), query, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"event":{"EndsAt":"2024-03-01T23:00:00+09:00","Name":"Launch","StartsAt":"2024-03-01T21:00:00+09:00"},"now":"2024-03-01T21:00:00+09:00"}}`, result)
+}
+
+func TestGraphHttpHandler_ServeHTTP_FormatContext(t *testing.T) {
+	g := Graphy{}
+	g.RegisterQuery(context.Background(), "greeting", func(ctx context.Context) string {
+		fc := GetFormatContext(ctx)
+		return fc.Locale + " " + time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC).In(fc.Location).Format(time.Kitchen)
+	})
+
+	h := NewGraphHttpHandler(&g)
+	h.FormatContextExtractor = FormatContextFromHeaders
+
+	body, _ := json.Marshal(graphqlRequest{Query: `{ greeting }`})
+	req := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+	req.Header.Set("Accept-Language", "en-US")
+	req.Header.Set(TimeZoneHeader, "America/New_York")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	res, err := io.ReadAll(rec.Result().Body)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"greeting":"en-US 7:00AM"}}`, string(res))
+}
+
+func TestDateTime_Schema(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "event", func(after time.Time) formatEvent {
+		return formatEvent{Name: "Launch", StartsAt: after.Add(time.Hour)}
+	}, "after")
+	g.EnableIntrospection(ctx)
+
+	expected := `type Query {
+	event(after: DateTime!): formatEvent!
+}
+
+type formatEvent {
+	EndsAt: DateTime
+	Name: String!
+	StartsAt: DateTime!
+}
+
+scalar DateTime
+
+`
+	assert.Equal(t, expected, g.SchemaDefinition(ctx))
+
+	result, err := g.ProcessRequest(ctx, `{ __type(name: "DateTime") { name kind } event(after: "2024-03-01T12:00:00Z") { StartsAt } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"__type":{"kind":"SCALAR","name":"DateTime"},"event":{"StartsAt":"2024-03-01T13:00:00Z"}}}`, result)
+
+	result, err = g.ProcessRequest(ctx, `query EventQuery($after: DateTime!) { event(after: $after) { StartsAt } }`, `{"after": "2024-03-01T12:00:00+01:00"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"event":{"StartsAt":"2024-03-01T13:00:00+01:00"}}}`, result)
+
+	_, err = g.ProcessRequest(ctx, `{ event(after: "yesterday") { StartsAt } }`, "")
+	assert.Error(t, err)
+}
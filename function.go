@@ -2,11 +2,13 @@ package quickgraph
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/alecthomas/participle/v2/lexer"
 	"reflect"
 	"runtime/debug"
 	"strings"
+	"time"
 )
 
 type GraphFunctionParamType int
@@ -59,6 +61,41 @@ type FunctionDefinition struct {
 	// DeprecatedReason is used to mark a function as deprecated. This will cause the function to
 	// be marked as deprecated in the schema.
 	DeprecatedReason *string
+
+	// SunsetAt is when a deprecated function is going to stop working. A function with a
+	// sunset is deprecated even if it has no DeprecatedReason. The date is added to its
+	// description in the schema, and requests that use it are told about it in the
+	// extensions of the response and, optionally, in the Sunset header; see
+	// GraphHttpHandler.DeprecationHeaders.
+	SunsetAt time.Time
+
+	// SupportsDryRun indicates that the function handles dry-run requests itself. Normally
+	// when a request is run in dry-run mode (see WithDryRun) the parameters for the function
+	// are parsed and validated, but the function is not called. If this is set, the function
+	// is called anyway and is responsible for checking IsDryRun and not making any changes.
+	SupportsDryRun bool
+
+	// Since and RemovedIn are the versions of the API that the function was added in and
+	// is scheduled to be removed in. They are added to the description of the function in
+	// the schema and are reported by SchemaChangelog.
+	Since     string
+	RemovedIn string
+
+	// ListSize is the number of elements that a list returned by the function is expected
+	// to have at most. It is used to estimate the size of a response before the request is
+	// run; see QueryLimits.MaxEstimatedResponse. If this is zero, QueryLimits.DefaultListSize
+	// is used instead.
+	ListSize int
+
+	// MaxResults, if set, is the number of elements that a list returned by the function
+	// may actually have. It protects against functions that return unbounded lists by
+	// accident. ResultLimitPolicy is what happens to longer lists.
+	MaxResults        int
+	ResultLimitPolicy ResultLimitPolicy
+
+	// Tags are labels for the function that SchemaFilters can select it by, such as
+	// "public" or "partner". They aren't part of the schema.
+	Tags []string
 }
 
 type graphFunction struct {
@@ -74,6 +111,19 @@ type graphFunction struct {
 	paramsByName  map[string]functionParamNameMapping
 	paramsByIndex []functionParamNameMapping
 
+	supportsDryRun bool
+	versions       versionInfo
+	listSize       int
+
+	// deprecatedReason and sunsetAt are set for deprecated functions. See
+	// FunctionDefinition.SunsetAt.
+	deprecatedReason *string
+	sunsetAt         time.Time
+
+	maxResults        int
+	resultLimitPolicy ResultLimitPolicy
+	tags              []string
+
 	// Output handling
 	baseReturnType *typeLookup
 	rawReturnType  reflect.Type
@@ -85,6 +135,17 @@ type functionParamNameMapping struct {
 	paramType         reflect.Type
 	required          bool
 	anonymousArgument bool
+
+	// unmarshaledType is the type of the parameter of the function if it has a parameter
+	// unmarshaler, in which case paramType is the input type of the unmarshaler.
+	unmarshaledType reflect.Type
+
+	// The description, deprecation, and default of the parameter, which come from the
+	// graphy tags of the fields of parameter structs. The default is a GraphQL literal.
+	description      *string
+	deprecatedReason *string
+	defaultValue     *string
+	defaultParsed    genericValue
 }
 
 func (g *Graphy) validateGraphFunction(graphFunc reflect.Value, name string, method bool) error {
@@ -225,12 +286,22 @@ func (g *Graphy) newAnonymousGraphFunction(def FunctionDefinition, graphFunc ref
 	// parameters as we don't have any names to use.
 
 	gf := graphFunction{
-		g:            g,
-		name:         def.Name,
-		mode:         def.Mode,
-		function:     graphFunc,
-		method:       method,
-		paramsByName: map[string]functionParamNameMapping{},
+		g:              g,
+		name:           def.Name,
+		mode:           def.Mode,
+		function:       graphFunc,
+		method:         method,
+		paramsByName:   map[string]functionParamNameMapping{},
+		supportsDryRun: def.SupportsDryRun,
+		versions:       versionInfo{since: def.Since, removedIn: def.RemovedIn},
+		listSize:       def.ListSize,
+
+		deprecatedReason: def.DeprecatedReason,
+		sunsetAt:         def.SunsetAt,
+
+		maxResults:        def.MaxResults,
+		resultLimitPolicy: def.ResultLimitPolicy,
+		tags:              def.Tags,
 	}
 
 	if len(def.ParameterNames) > 0 {
@@ -265,6 +336,7 @@ func (g *Graphy) newAnonymousGraphFunction(def FunctionDefinition, graphFunc ref
 	// Iterate over the parameters and create the anonymous arguments.
 	for i, mapping := range inputs {
 		mapping := mapping
+		g.applyParameterUnmarshaler(&mapping)
 
 		// If the field is a pointer, it is optional.
 		if mapping.paramType.Kind() == reflect.Ptr {
@@ -294,12 +366,22 @@ func (g *Graphy) newStructGraphFunction(def FunctionDefinition, graphFunc reflec
 	// the names of the struct fields as the parameter names.
 
 	gf := graphFunction{
-		g:         g,
-		name:      def.Name,
-		paramType: NamedParamsStruct,
-		mode:      def.Mode,
-		function:  graphFunc,
-		method:    method,
+		g:              g,
+		name:           def.Name,
+		paramType:      NamedParamsStruct,
+		mode:           def.Mode,
+		function:       graphFunc,
+		method:         method,
+		supportsDryRun: def.SupportsDryRun,
+		versions:       versionInfo{since: def.Since, removedIn: def.RemovedIn},
+		listSize:       def.ListSize,
+
+		deprecatedReason: def.DeprecatedReason,
+		sunsetAt:         def.SunsetAt,
+
+		maxResults:        def.MaxResults,
+		resultLimitPolicy: def.ResultLimitPolicy,
+		tags:              def.Tags,
 	}
 
 	mft := graphFunc.Type()
@@ -331,13 +413,9 @@ func (g *Graphy) newStructGraphFunction(def FunctionDefinition, graphFunc reflec
 			panic("anonymous fields are not supported")
 		}
 
-		name := field.Name
-		if jsonTag := field.Tag.Get("json"); jsonTag != "" {
-			if jsonTag == "-" {
-				continue
-			}
-			// Ignore anything after the first comma.
-			name = strings.Split(jsonTag, ",")[0]
+		name, include := graphFieldName(field)
+		if !include {
+			continue
 		}
 
 		mapping := functionParamNameMapping{
@@ -346,9 +424,11 @@ func (g *Graphy) newStructGraphFunction(def FunctionDefinition, graphFunc reflec
 			paramType:         field.Type,
 			anonymousArgument: false,
 		}
+		g.applyParameterUnmarshaler(&mapping)
+		g.applyParameterTags(field, &mapping)
 
-		// If the field is a pointer, it is optional.
-		if field.Type.Kind() == reflect.Ptr {
+		// If the field is a pointer, or has a default, it is optional.
+		if mapping.paramType.Kind() == reflect.Ptr || mapping.defaultValue != nil {
 			mapping.required = false
 		} else {
 			mapping.required = true
@@ -381,8 +461,8 @@ func (g *Graphy) createImplicitTypeLookupUnion(name string, types []any) *typeLo
 }
 
 // validateFunctionReturnTypes validates the return types of the function passed. It requires the function
-// to have at least one non-error return value and at most one error return value. The function should have
-// between one and two return values.
+// to have at least one non-error return value and at most one error return value, which is either an error
+// or a []error. The function should have between one and two return values.
 func (g *Graphy) validateFunctionReturnTypes(mft reflect.Type, definition FunctionDefinition) (*typeLookup, error) {
 	errorCount := 0
 
@@ -391,7 +471,7 @@ func (g *Graphy) validateFunctionReturnTypes(mft reflect.Type, definition Functi
 
 	for i := 0; i < mft.NumOut(); i++ {
 		out := mft.Out(i)
-		if out.ConvertibleTo(errorType) {
+		if out.ConvertibleTo(errorType) || out == errorSliceType {
 			errorCount++
 		} else {
 			returnTypes = append(returnTypes, out)
@@ -443,52 +523,79 @@ func (g *Graphy) validateFunctionReturnTypes(mft reflect.Type, definition Functi
 // Call executes the graph function with a given context, request and command. It first prepares the
 // parameters for the function call, then invokes the function and processes the results. If the function
 // returns an error, it returns a formatted error. If the function returns no results, it returns nil.
+// If the function returns a []error that isn't empty, its result is returned along with the errors
+// joined, for the callers that can report a partial result.
 func (f *graphFunction) Call(ctx context.Context, req *request, params *parameterList, methodTarget reflect.Value) (val reflect.Value, retErr error) {
+	// Functions without parameters have no parameter list to take the position from.
+	var pos lexer.Position
+	if params != nil {
+		pos = params.Pos
+	}
+
 	// Catch panics and return them as errors.
 	defer func() {
 		if r := recover(); r != nil {
 			stack := string(debug.Stack())
 			val = reflect.Value{}
-			var pos lexer.Position
-			if params != nil {
-				pos = params.Pos
-			}
 			gErr := NewGraphError(fmt.Sprintf("function %s panicked: %v", f.name, r), pos)
 			gErr.AddExtension("stack", stack)
 			retErr = gErr
 		}
 	}()
 
+	ctx, hintCall := startCacheHintCall(ctx)
+
 	paramValues, err := f.getCallParameters(ctx, req, params, methodTarget)
 	if err != nil {
-		var pos lexer.Position
-		if params != nil {
-			pos = params.Pos
-		}
 		return reflect.Value{}, AugmentGraphError(err, fmt.Sprintf("error getting call parameters for function %s", f.name), pos)
 	}
 
+	if f.g.StuckResolverTimeout > 0 {
+		done := make(chan struct{})
+		defer close(done)
+		go f.g.watchResolver(ctx, f.name, currentGoroutineID(), done)
+	}
+
 	gfv := f.function
 	callResults := gfv.Call(paramValues)
+	putParamValues(paramValues)
+	hintCall.end()
 	if len(callResults) == 0 {
 		// We should never get here because all functions must return at least one value and an optional error.
-		return reflect.Value{}, NewGraphError("function returned no values", params.Pos, f.name)
+		return reflect.Value{}, NewGraphError("function returned no values", pos, f.name)
 	}
 
 	var resultValues []reflect.Value
+	var partialErr error
 	for _, callResult := range callResults {
 		if callResult.CanConvert(errorType) {
 			if !callResult.IsNil() {
 				err := callResult.Convert(errorType).Interface().(error)
-				return reflect.Value{}, AugmentGraphError(err, fmt.Sprintf("function %s returned error", f.name), params.Pos)
+				return reflect.Value{}, AugmentGraphError(err, fmt.Sprintf("function %s returned error", f.name), pos)
+			}
+		} else if callResult.Type() == errorSliceType {
+			// The result is kept along with the errors, which are reported separately.
+			if errs := callResult.Interface().([]error); len(errs) > 0 {
+				partialErr = AugmentGraphError(errors.Join(errs...), fmt.Sprintf("function %s returned error", f.name), pos)
 			}
 		} else {
 			resultValues = append(resultValues, callResult)
 		}
 	}
+	if partialErr != nil {
+		val, err := f.resultValue(req, resultValues, pos)
+		if err != nil {
+			return reflect.Value{}, errors.Join(partialErr, err)
+		}
+		return val, partialErr
+	}
+	return f.resultValue(req, resultValues, pos)
+}
 
+// resultValue returns the value of the results of a call that isn't an error.
+func (f *graphFunction) resultValue(req *request, resultValues []reflect.Value, pos lexer.Position) (reflect.Value, error) {
 	if len(resultValues) == 1 {
-		return resultValues[0], nil
+		return f.limitResults(req, resultValues[0], pos)
 	}
 
 	// At this point, we are in the implicit union case. We need to return the single non-nil result
@@ -498,13 +605,13 @@ func (f *graphFunction) Call(ctx context.Context, req *request, params *paramete
 	for _, resultValue := range resultValues {
 		if !resultValue.IsNil() {
 			if nonNilResult.IsValid() {
-				return reflect.Value{}, NewGraphError(fmt.Sprintf("function %s returned multiple non-nil values", f.name), params.Pos)
+				return reflect.Value{}, NewGraphError(fmt.Sprintf("function %s returned multiple non-nil values", f.name), pos)
 			}
 			nonNilResult = resultValue
 		}
 	}
 	if !nonNilResult.IsValid() {
-		return reflect.Value{}, NewGraphError(fmt.Sprintf("function %s returned no non-nil values", f.name), params.Pos)
+		return reflect.Value{}, NewGraphError(fmt.Sprintf("function %s returned no non-nil values", f.name), pos)
 	}
 	return nonNilResult, nil
 }
@@ -548,3 +655,99 @@ func unionNameGenerator(def FunctionDefinition) string {
 		return def.Name + "ResultUnion"
 	}
 }
+
+// validateTypeUsage panics if the function, or any of the functions on the types that it
+// returns, takes an output-only type as a parameter or returns an input-only type. See
+// GraphTypeInfo.OutputOnly and GraphTypeInfo.InputOnly.
+func (g *Graphy) validateTypeUsage(gf *graphFunction) {
+	visited := map[typeUsage]bool{}
+	if err := g.checkFunctionTypeUsage(gf, visited); err != nil {
+		panic(fmt.Sprintf("function %s: %v", gf.name, err))
+	}
+}
+
+func (g *Graphy) checkFunctionTypeUsage(gf *graphFunction, visited map[typeUsage]bool) error {
+	if gf.paramType == NamedParamsStruct {
+		// The fields of the struct are the parameters, but the struct itself is also
+		// used as an input.
+		if err := g.checkTypeUsage(g.typeLookup(gf.structParamType()), TypeInput, visited); err != nil {
+			return fmt.Errorf("parameters: %w", err)
+		}
+	} else {
+		for _, param := range gf.paramsByIndex {
+			if err := g.checkTypeUsage(g.typeLookup(param.paramType), TypeInput, visited); err != nil {
+				return fmt.Errorf("parameter %s: %w", param.name, err)
+			}
+		}
+	}
+	if gf.baseReturnType != nil {
+		if err := g.checkTypeUsage(gf.baseReturnType, TypeOutput, visited); err != nil {
+			return fmt.Errorf("return type: %w", err)
+		}
+	}
+	return nil
+}
+
+// structParamType returns the type of the struct that holds the parameters of a
+// NamedParamsStruct function.
+func (f *graphFunction) structParamType() reflect.Type {
+	gft := f.function.Type()
+	startIndex := 0
+	if f.method {
+		startIndex = 1
+	}
+	for i := startIndex; i < gft.NumIn(); i++ {
+		if gft.In(i).Kind() == reflect.Struct {
+			return gft.In(i)
+		}
+	}
+	return nil
+}
+
+// typeUsage is a type being used in a direction, to avoid checking a type more than once.
+type typeUsage struct {
+	tl   *typeLookup
+	kind TypeKind
+}
+
+// checkTypeUsage checks that the type, and the types of its fields, may be used in the
+// given direction.
+func (g *Graphy) checkTypeUsage(tl *typeLookup, kind TypeKind, visited map[typeUsage]bool) error {
+	if tl == nil {
+		return nil
+	}
+	if visited[typeUsage{tl, kind}] {
+		return nil
+	}
+	visited[typeUsage{tl, kind}] = true
+
+	if kind == TypeInput && tl.outputOnly {
+		return fmt.Errorf("type %s is output-only and can't be used as an input", tl.name)
+	}
+	if kind == TypeOutput && tl.inputOnly {
+		return fmt.Errorf("type %s is input-only and can't be used as an output", tl.name)
+	}
+
+	for _, name := range sortedKeys(tl.fields) {
+		field := tl.fields[name]
+		var err error
+		switch field.fieldType {
+		case FieldTypeField:
+			err = g.checkTypeUsage(g.typeLookup(field.resultType), kind, visited)
+		case FieldTypeGraphFunction:
+			// Functions on types are only relevant for outputs.
+			if kind == TypeOutput {
+				err = g.checkFunctionTypeUsage(field.graphFunction, visited)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("field %s.%s: %w", tl.name, name, err)
+		}
+	}
+	for _, name := range sortedKeys(tl.union) {
+		if err := g.checkTypeUsage(tl.union[name], kind, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,9 +2,13 @@ package quickgraph
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // getCallParameters returns the parameters to use when calling the function represented by this graphFunction.
@@ -28,7 +32,7 @@ func (f *graphFunction) getCallParamsNamedInline(ctx context.Context, req *reque
 	gft := f.function.Type()
 
 	// Make something to hold the parameters
-	paramValues := make([]reflect.Value, gft.NumIn())
+	paramValues := getParamValues(gft.NumIn())
 
 	startIndex := 0
 	if f.method {
@@ -59,10 +63,19 @@ func (f *graphFunction) getCallParamsNamedInline(ctx context.Context, req *reque
 		for _, param := range parsedParams.Values {
 			if nameMapping, ok := f.paramsByName[param.Name]; ok {
 				val := reflect.New(nameMapping.paramType).Elem()
-				err := parseInputIntoValue(req, param.Value, val)
+				err := parseInputIntoValue(ctx, req, param.Value, val)
 				if err != nil {
+					nameInputTypeError(err, "argument "+param.Name)
 					return nil, err
 				}
+				err = validateInput(ctx, val, param.Name)
+				if err != nil {
+					return nil, err
+				}
+				val, err = f.g.unmarshalParameter(ctx, nameMapping, val)
+				if err != nil {
+					return nil, AugmentGraphError(err, "", param.Pos, param.Name)
+				}
 				paramValues[nameMapping.paramIndex] = val
 				delete(requiredParams, param.Name)
 			}
@@ -79,7 +92,7 @@ func (f *graphFunction) getCallParamsAnonymousInline(ctx context.Context, req *r
 	gft := f.function.Type()
 
 	// Make something to hold the parameters
-	paramValues := make([]reflect.Value, gft.NumIn())
+	paramValues := getParamValues(gft.NumIn())
 
 	startIndex := 0
 	if f.method {
@@ -97,9 +110,11 @@ func (f *graphFunction) getCallParamsAnonymousInline(ctx context.Context, req *r
 			paramValues[i] = reflect.ValueOf(ctx)
 			continue
 		} else {
-			// This is a normal parameter, fill it in from the command.
-			val := reflect.New(gft.In(i)).Elem()
-			paramValues[i] = val
+			// This is a normal parameter, fill it in from the command. It is parsed into
+			// the type of the argument in the schema.
+			mapping := f.paramsByIndex[normalParamCount]
+			val := reflect.New(mapping.paramType).Elem()
+			paramValues[i] = reflect.New(gft.In(i)).Elem()
 
 			if params == nil {
 				if val.Type().Kind() != reflect.Ptr {
@@ -109,10 +124,19 @@ func (f *graphFunction) getCallParamsAnonymousInline(ctx context.Context, req *r
 				if normalParamCount >= len(params.Values) {
 					return nil, fmt.Errorf("too many parameters provided %d", normalParamCount)
 				}
-				err := parseInputIntoValue(req, params.Values[normalParamCount].Value, val)
+				param := params.Values[normalParamCount]
+				err := parseInputIntoValue(ctx, req, param.Value, val)
+				if err != nil {
+					return nil, err
+				}
+				err = validateInput(ctx, val, param.Name)
 				if err != nil {
 					return nil, err
 				}
+				paramValues[i], err = f.g.unmarshalParameter(ctx, mapping, val)
+				if err != nil {
+					return nil, AugmentGraphError(err, "", param.Pos, param.Name)
+				}
 			}
 
 		}
@@ -133,7 +157,7 @@ func (f *graphFunction) getCallParamsNamedStruct(ctx context.Context, req *reque
 	gft := f.function.Type()
 
 	// Make something to hold the parameters
-	paramValues := make([]reflect.Value, gft.NumIn())
+	paramValues := getParamValues(gft.NumIn())
 
 	startIndex := 0
 	if f.method {
@@ -167,18 +191,51 @@ func (f *graphFunction) getCallParamsNamedStruct(ctx context.Context, req *reque
 		}
 	}
 
+	provided := map[string]bool{}
 	parsedParams := params
 	if parsedParams != nil {
 		for _, param := range parsedParams.Values {
 			if nameMapping, ok := f.paramsByName[param.Name]; ok {
-				err := parseInputIntoValue(req, param.Value, valueParam.Field(nameMapping.paramIndex))
+				val := reflect.New(nameMapping.paramType).Elem()
+				err := parseInputIntoValue(ctx, req, param.Value, val)
 				if err != nil {
+					nameInputTypeError(err, "argument "+param.Name)
 					return nil, err
 				}
+				if nameMapping.unmarshaledType != nil {
+					// The input value is validated by itself, since the struct only has the
+					// value that it is converted into.
+					err = validateInput(ctx, val, param.Name)
+					if err != nil {
+						return nil, err
+					}
+				}
+				val, err = f.g.unmarshalParameter(ctx, nameMapping, val)
+				if err != nil {
+					return nil, AugmentGraphError(err, "", param.Pos, param.Name)
+				}
+				valueParam.Field(nameMapping.paramIndex).Set(val)
 				delete(requiredParams, param.Name)
+				provided[param.Name] = true
 			}
 		}
 	}
+	// Parameters that weren't given take their defaults.
+	for _, nameMapping := range f.paramsByName {
+		if nameMapping.defaultValue == nil || provided[nameMapping.name] {
+			continue
+		}
+		val := reflect.New(nameMapping.paramType).Elem()
+		err := parseInputIntoValue(ctx, req, nameMapping.defaultParsed, val)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing default of argument %s: %w", nameMapping.name, err)
+		}
+		val, err = f.g.unmarshalParameter(ctx, nameMapping, val)
+		if err != nil {
+			return nil, err
+		}
+		valueParam.Field(nameMapping.paramIndex).Set(val)
+	}
 	if len(requiredParams) > 0 {
 		missingParams := []string{}
 		for paramName := range requiredParams {
@@ -186,14 +243,20 @@ func (f *graphFunction) getCallParamsNamedStruct(ctx context.Context, req *reque
 		}
 		return nil, fmt.Errorf("missing required parameters: %v", strings.Join(missingParams, ", "))
 	}
+	// The fields of the struct are the arguments, so their paths start with their names.
+	err := validateInput(ctx, valueParam, "")
+	if err != nil {
+		return nil, err
+	}
 	return paramValues, nil
 }
 
 // parseInputIntoValue interprets a genericValue according to the type of the targetValue and assigns the result to targetValue.
 // This method takes into account various types of input such as string, int, float, list, map, identifier, and GraphQL variable.
 // It returns an error if the input cannot be parsed into the target type.
-func parseInputIntoValue(req *request, inValue genericValue, targetValue reflect.Value) (err error) {
-	// Catch panics and return them as errors.
+func parseInputIntoValue(ctx context.Context, req *request, inValue genericValue, targetValue reflect.Value) (err error) {
+	// Values that don't match the target type are detected and reported without
+	// panicking. This only catches panics from user code, such as enum unmarshalers.
 	defer func() {
 		if r := recover(); r != nil {
 			e := fmt.Errorf("panic: %v", r)
@@ -201,8 +264,19 @@ func parseInputIntoValue(req *request, inValue genericValue, targetValue reflect
 		}
 	}()
 
+	return parseInput(ctx, req, inValue, targetValue)
+}
+
+// parseInput does the work of parseInputIntoValue. It calls itself for the elements of
+// lists and the fields of input objects.
+func parseInput(ctx context.Context, req *request, inValue genericValue, targetValue reflect.Value) (err error) {
 	typ := targetValue.Type()
 	isPtr := typ.Kind() == reflect.Ptr
+	if isPtr && isNullInputValue(inValue) {
+		// A null leaves a nullable target, including an element of a list, nil.
+		targetValue.Set(reflect.Zero(typ))
+		return nil
+	}
 	if isPtr {
 		// Make a new instance of the object, set the target to that new instance, then dereference.
 		typ = typ.Elem()
@@ -212,7 +286,15 @@ func parseInputIntoValue(req *request, inValue genericValue, targetValue reflect
 	}
 	isSlice := typ.Kind() == reflect.Slice
 	isStruct := typ.Kind() == reflect.Struct
-	if inValue.Variable != nil {
+	if isSlice && isSingleInputValue(inValue) {
+		// A single value where a list is expected is coerced to a list containing
+		// just that value.
+		inValue = genericValue{List: []genericValue{inValue}, Pos: inValue.Pos}
+	}
+	if (inValue.String != nil || inValue.Int != nil) && req != nil && req.graphy != nil && req.graphy.isIDScalar(typ) {
+		// IDs can be written as strings or as integers, whatever their Go type is.
+		err = parseIDIntoValue(inValue, targetValue)
+	} else if inValue.Variable != nil {
 		if req == nil {
 			return fmt.Errorf("variable %s provided but no request", *inValue.Variable)
 		}
@@ -222,26 +304,44 @@ func parseInputIntoValue(req *request, inValue genericValue, targetValue reflect
 	} else if inValue.String != nil {
 		// The string value has quotes around it, remove them.
 		literalValue := (*inValue.String)[1 : len(*inValue.String)-1]
-		parseStringIntoValue(literalValue, targetValue)
+		err = parseStringIntoValue(literalValue, targetValue)
 	} else if inValue.Identifier != nil {
 		// This is where we handle enums. We have to look up the value based on the field.
 		// This will only work with enums that are strings.
-		err = parseIdentifierIntoValue(*inValue.Identifier, targetValue)
+		err = parseIdentifierIntoValue(ctx, *inValue.Identifier, targetValue)
 	} else if inValue.Int != nil {
 		i := *inValue.Int
-		parseIntIntoValue(i, targetValue)
+		err = parseIntIntoValue(i, targetValue)
 	} else if inValue.Float != nil {
 		f := *inValue.Float
-		parseFloatIntoValue(f, targetValue)
-	} else if inValue.List != nil || isSlice {
-		err = parseListIntoValue(req, inValue, targetValue)
-	} else if inValue.Map != nil || isStruct {
-		err = parseMapIntoValue(req, inValue, targetValue)
+		err = parseFloatIntoValue(f, targetValue)
+	} else if isSlice {
+		err = parseListIntoValue(ctx, req, inValue, targetValue)
+	} else if inValue.List != nil {
+		err = newInputTypeError(targetValue.Type(), "List")
+	} else if isStruct {
+		err = parseMapIntoValue(ctx, req, inValue, targetValue)
+	} else if inValue.Map != nil {
+		err = newInputTypeError(targetValue.Type(), "Object")
 	} else {
 		// This should never occur as this should be a parse error
 		// that gets caught by the parser.
 		return fmt.Errorf("no input found to parse into value")
 	}
+	if err == nil && req != nil && req.graphy != nil && (inValue.Variable != nil || inValue.String != nil || inValue.Identifier != nil) {
+		// Lists and input objects are checked as their elements are parsed.
+		err = req.graphy.checkDynamicEnums(targetValue)
+	}
+	if ite, ok := err.(*inputTypeError); ok {
+		if req != nil && req.graphy != nil && !isSlice {
+			// The expected type is named as it is in the schema.
+			if scalar, ok := req.graphy.namedScalar(req.graphy.typeLookup(typ)); ok {
+				ite.expected = scalar
+			}
+		}
+		// Point at the value that has the wrong type.
+		return AugmentGraphError(ite, "", inValue.Pos)
+	}
 	if err != nil {
 		return err
 	}
@@ -249,6 +349,20 @@ func parseInputIntoValue(req *request, inValue genericValue, targetValue reflect
 	return nil
 }
 
+// isSingleInputValue reports whether the value is a single value, as opposed to a list,
+// a variable, or null. An empty list has no values set at all.
+func isSingleInputValue(inValue genericValue) bool {
+	if inValue.Identifier != nil {
+		return *inValue.Identifier != "null"
+	}
+	return inValue.String != nil || inValue.Int != nil || inValue.Float != nil || inValue.Map != nil
+}
+
+// isNullInputValue reports whether the value is the literal null.
+func isNullInputValue(inValue genericValue) bool {
+	return inValue.Identifier != nil && *inValue.Identifier == "null"
+}
+
 // parseVariableIntoValue extracts the value of a variable from the provided request and assigns it to targetValue.
 func parseVariableIntoValue(req *request, variableName string, targetValue reflect.Value) error {
 	value, ok := req.variables[variableName]
@@ -256,32 +370,87 @@ func parseVariableIntoValue(req *request, variableName string, targetValue refle
 		return fmt.Errorf("variable %v not found", variableName)
 	}
 	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			// A null leaves the target as it is.
+			return nil
+		}
 		value = value.Elem()
 	}
+	if !value.Type().AssignableTo(targetValue.Type()) {
+		return newInputTypeError(targetValue.Type(), "variable $"+variableName+" of type "+inputTypeName(value.Type()))
+	}
 	targetValue.Set(value)
 	return nil
 }
 
 // parseStringIntoValue interprets the provided string and assigns it to targetValue.
-func parseStringIntoValue(s string, targetValue reflect.Value) {
+func parseStringIntoValue(s string, targetValue reflect.Value) error {
+	if targetValue.Type() == timeType {
+		// DateTime values are RFC 3339 strings.
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return err
+		}
+		targetValue.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if targetValue.Kind() != reflect.String {
+		return newInputTypeError(targetValue.Type(), "String")
+	}
 	targetValue.SetString(s)
+	return nil
 }
 
-// parseIntIntoValue converts an int64 to the appropriate type and assigns it to targetValue.
-func parseIntIntoValue(i int64, targetValue reflect.Value) {
-	targetValue.SetInt(i)
+// parseIntIntoValue converts an int64 to the appropriate type and assigns it to targetValue. Ints
+// can also be used where floats are expected.
+// jsonNumberType is json.Number, which takes the text of numbers as they are written so that
+// large or precise numbers don't lose anything to float64.
+var jsonNumberType = reflect.TypeOf(json.Number(""))
+
+func parseIntIntoValue(i int64, targetValue reflect.Value) error {
+	if targetValue.Type() == jsonNumberType {
+		targetValue.SetString(strconv.FormatInt(i, 10))
+		return nil
+	}
+	switch targetValue.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if targetValue.OverflowInt(i) {
+			return fmt.Errorf("value %d is out of range for %v", i, targetValue.Type())
+		}
+		targetValue.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if i < 0 || targetValue.OverflowUint(uint64(i)) {
+			return fmt.Errorf("value %d is out of range for %v", i, targetValue.Type())
+		}
+		targetValue.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		targetValue.SetFloat(float64(i))
+	default:
+		return newInputTypeError(targetValue.Type(), "Int")
+	}
+	return nil
 }
 
 // parseFloatIntoValue converts a float64 to the appropriate type and assigns it to targetValue.
-func parseFloatIntoValue(f float64, targetValue reflect.Value) {
-	targetValue.SetFloat(f)
+func parseFloatIntoValue(f float64, targetValue reflect.Value) error {
+	if targetValue.Type() == jsonNumberType {
+		targetValue.SetString(strconv.FormatFloat(f, 'g', -1, 64))
+		return nil
+	}
+	switch targetValue.Kind() {
+	case reflect.Float32, reflect.Float64:
+		targetValue.SetFloat(f)
+	default:
+		return newInputTypeError(targetValue.Type(), "Float")
+	}
+	return nil
 }
 
 // parseIdentifierIntoValue attempts to interpret an identifier and assign its corresponding value to targetValue. It supports
 // EnumUnmarshaler interface and strings. Returns an error if it cannot unmarshal the identifier.
-func parseIdentifierIntoValue(identifier string, value reflect.Value) error {
+func parseIdentifierIntoValue(ctx context.Context, identifier string, value reflect.Value) error {
 
-	done, err := unmarshalWithEnumUnmarshaler(identifier, value)
+	done, err := unmarshalWithEnumUnmarshaler(ctx, identifier, value)
 	if done {
 		return err
 	}
@@ -312,24 +481,18 @@ func parseIdentifierIntoValue(identifier string, value reflect.Value) error {
 		return nil
 	}
 
-	if value.Type() == stringType {
-		// If the value is a string, set it. There is no
-		// possibility that this is a pointer since we're testing
-		// for the string type explicitly.
-		value.SetString(identifier)
-		return nil
-	} else {
-		// This assumes the value is a string or something that
-		// simply wraps a string. No extra checks are done as
-		// that is also handled at the reflection layer. If this
-		// is not a string, it will panic, that will get caught,
-		// and the error will be returned.
-		value.SetString(identifier)
-		return nil
+	if kind != reflect.String || ptr {
+		if identifier == "null" {
+			return newInputTypeError(value.Type(), "null")
+		}
+		return newInputTypeError(value.Type(), "enum value "+identifier)
 	}
+	// The value is a string or something that simply wraps a string.
+	value.SetString(identifier)
+	return nil
 }
 
-func unmarshalWithEnumUnmarshaler(identifier string, value reflect.Value) (bool, error) {
+func unmarshalWithEnumUnmarshaler(ctx context.Context, identifier string, value reflect.Value) (bool, error) {
 	// Make a pointer to the value type in case the receiver is a pointer.
 	interfaceVal := value
 	valueType := interfaceVal.Type()
@@ -345,8 +508,19 @@ func unmarshalWithEnumUnmarshaler(identifier string, value reflect.Value) (bool,
 		valueType = interfaceVal.Type().Elem()
 		interfaceVal = interfaceVal.Elem()
 	}
+	// The interfaces are checked on a pointer, since its method set includes the methods
+	// with both value and pointer receivers.
 	destinationVal := reflect.New(valueType)
-	if ok := value.CanConvert(enumUnmarshalerType); ok {
+	if ok := destinationVal.CanConvert(enumUnmarshalerWithContextType); ok {
+		// If it supports the EnumUnmarshalerWithContext interface, that takes precedence.
+		enumUnmarshaler := destinationVal.Convert(enumUnmarshalerWithContextType).Interface().(EnumUnmarshalerWithContext)
+		val, err := enumUnmarshaler.UnmarshalStringContext(ctx, identifier)
+		if err != nil {
+			return true, err
+		}
+		interfaceVal.Set(reflect.ValueOf(val))
+		return true, nil
+	} else if ok := destinationVal.CanConvert(enumUnmarshalerType); ok {
 		// If it supports the EnumUnmarshaler interface, use that.
 		enumUnmarshaler := destinationVal.Convert(enumUnmarshalerType).Interface().(EnumUnmarshaler)
 		val, err := enumUnmarshaler.UnmarshalString(identifier)
@@ -355,7 +529,7 @@ func unmarshalWithEnumUnmarshaler(identifier string, value reflect.Value) (bool,
 		}
 		interfaceVal.Set(reflect.ValueOf(val))
 		return true, nil
-	} else if ok := value.CanConvert(stringEnumValuesType); ok {
+	} else if ok := destinationVal.CanConvert(stringEnumValuesType); ok {
 		// If it supports the StringEnumValues interface, use that.
 		stringEnumValues := destinationVal.Convert(stringEnumValuesType).Interface().(StringEnumValues)
 		enumValues := stringEnumValues.EnumValues()
@@ -372,11 +546,11 @@ func unmarshalWithEnumUnmarshaler(identifier string, value reflect.Value) (bool,
 
 // parseListIntoValue assigns a list of GenericValues to targetValue. Each item in the list is parsed into a value and assigned
 // to the corresponding index in the slice represented by targetValue. If an item cannot be parsed, it returns an error.
-func parseListIntoValue(req *request, inVal genericValue, targetValue reflect.Value) error {
+func parseListIntoValue(ctx context.Context, req *request, inVal genericValue, targetValue reflect.Value) error {
 	targetType := targetValue.Type()
 	targetValue.Set(reflect.MakeSlice(targetType, len(inVal.List), len(inVal.List)))
 	for i, listItem := range inVal.List {
-		err := parseInputIntoValue(req, listItem, targetValue.Index(i))
+		err := parseInput(ctx, req, listItem, targetValue.Index(i))
 		if err != nil {
 			return err
 		}
@@ -384,16 +558,31 @@ func parseListIntoValue(req *request, inVal genericValue, targetValue reflect.Va
 	return nil
 }
 
+// inputStructField finds the field of a struct that a field of an input object refers to.
+// Fields are found by the name they have in the graph, but they may also be referred to by
+// their Go name. Fields that are excluded from the graph can't be found either way.
+func inputStructField(targetType reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < targetType.NumField(); i++ {
+		field := targetType.Field(i)
+		if graphName, include := graphFieldName(field); include && graphName == name {
+			return field, true
+		}
+	}
+	if field, ok := targetType.FieldByName(name); ok {
+		if _, include := graphFieldName(field); include {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
 // parseMapIntoValue assigns a map of GenericValues to the struct represented by targetValue. Each field in the input map is parsed
 // into a value and set on the struct field that has a matching "json" tag or field name. If a required field is missing from the
 // input map, it returns an error.
-func parseMapIntoValue(req *request, inValue genericValue, targetValue reflect.Value) error {
+func parseMapIntoValue(ctx context.Context, req *request, inValue genericValue, targetValue reflect.Value) error {
 	// A map is a little more complicated. We need to loop through the fields of the target type
 	// and set the values from the input map. This is how we initialize a struct from a map.
 	targetType := targetValue.Type()
-	// TODO: Cache this so we don't have to reconstruct it every time.
-	// Loop through the fields of the target type and make a map of the fields by "json" tag.
-	fieldMap := map[string]reflect.StructField{}
 	requiredFields := map[string]bool{}
 
 	if targetType.Kind() == reflect.Ptr {
@@ -407,42 +596,41 @@ func parseMapIntoValue(req *request, inValue genericValue, targetValue reflect.V
 
 	for i := 0; i < targetType.NumField(); i++ {
 		field := targetType.Field(i)
-		if tag, ok := field.Tag.Lookup("json"); ok {
-			// Only use the name from the tag if it's not "-" and ignore anything after a comma.
-			if tag == "-" {
-				continue
-			}
-			tag = strings.Split(tag, ",")[0]
-			fieldMap[tag] = field
+		if _, include := graphFieldName(field); !include {
+			continue
 		}
 		if field.Type.Kind() != reflect.Ptr {
 			requiredFields[field.Name] = true
 		}
 	}
 
+	limits := inputLimitsFor(targetType)
+
 	// Loop through the fields of the input map and set the values in the target value.
 	for _, namedValue := range inValue.Map {
 		var fieldValue reflect.Value
 		var fieldName string
 
-		if targetField, ok := fieldMap[namedValue.Name]; ok {
-			// If we find this in the fieldMap, the field has a defined JSON tag, so use that.
-			fieldValue = targetValue.FieldByName(targetField.Name)
+		if targetField, ok := inputStructField(targetType, namedValue.Name); ok {
+			fieldValue = targetValue.FieldByIndex(targetField.Index)
 			fieldName = targetField.Name
 		}
-		if fieldValue.Kind() == reflect.Invalid {
-			// If we didn't find it in the fieldMap, the field doesn't have a defined JSON tag, so
-			// try to find it by name in the structure.
-			fieldValue = targetValue.FieldByName(namedValue.Name)
-			fieldName = namedValue.Name
-		}
 
 		if fieldValue.Kind() != reflect.Invalid {
 			// We have found the field, so parse the value into it.
-			err := parseInputIntoValue(req, namedValue.Value, fieldValue)
+			err := parseInput(ctx, req, namedValue.Value, fieldValue)
 			if err != nil {
+				nameInputTypeError(err, "field "+namedValue.Name)
 				return AugmentGraphError(err, fmt.Sprintf("error setting field %s", fieldName), inValue.Pos, fieldName)
 			}
+			if fl := limits.field(fieldName); fl != nil {
+				// Nested literals have been checked as they were parsed, and variables when
+				// the request was created.
+				err = fl.check(fieldValue, false)
+				if err != nil {
+					return NewGraphError(err.Error(), namedValue.Pos, fieldName)
+				}
+			}
 			delete(requiredFields, fieldName)
 		} else {
 			return NewGraphError(fmt.Sprintf("field %s not found in input struct", namedValue.Name), namedValue.Pos, namedValue.Name)
@@ -455,3 +643,103 @@ func parseMapIntoValue(req *request, inValue genericValue, targetValue reflect.V
 	}
 	return nil
 }
+
+// inputTypeError is returned for values in requests that don't have the type that is
+// expected, such as a string where an Int is expected.
+type inputTypeError struct {
+	expected string
+	got      string
+
+	// name describes what the value is for, such as "argument id", if it is known.
+	name string
+}
+
+func newInputTypeError(expected reflect.Type, got string) *inputTypeError {
+	return &inputTypeError{expected: inputTypeName(expected), got: got}
+}
+
+func (e *inputTypeError) Error() string {
+	if e.name != "" {
+		return fmt.Sprintf("expected %s for %s, got %s", e.expected, e.name, e.got)
+	}
+	return fmt.Sprintf("expected %s, got %s", e.expected, e.got)
+}
+
+// nameInputTypeError records what the value of an inputTypeError is for, unless a more
+// specific name is already known.
+func nameInputTypeError(err error, name string) {
+	var ite *inputTypeError
+	if errors.As(err, &ite) && ite.name == "" {
+		ite.name = name
+	}
+}
+
+// toInputTypeError converts an error from unmarshalling the JSON value of a variable into
+// an inputTypeError if it is about a value of the wrong type, so that it mentions the types
+// of the schema rather than the Go types.
+func toInputTypeError(err error) error {
+	var ute *json.UnmarshalTypeError
+	if !errors.As(err, &ute) || ute.Type == nil {
+		return err
+	}
+	var got string
+	switch {
+	case ute.Value == "string":
+		got = "String"
+	case ute.Value == "bool":
+		got = "Boolean"
+	case ute.Value == "array":
+		got = "List"
+	case ute.Value == "object":
+		got = "Object"
+	case ute.Value == "number":
+		got = "number"
+	case strings.HasPrefix(ute.Value, "number "):
+		// The number is included if it doesn't fit the type.
+		if strings.ContainsAny(ute.Value[len("number "):], ".eE") {
+			got = "Float"
+		} else {
+			got = "Int"
+		}
+	default:
+		return err
+	}
+	ite := newInputTypeError(ute.Type, got)
+	if _, err := strconv.Atoi(ute.Field); err == nil {
+		ite.name = "element " + ute.Field
+	} else if ute.Field != "" {
+		ite.name = "field " + ute.Field
+	}
+	return ite
+}
+
+// inputTypeName names a Go type for the errors about the input values of requests, using
+// the names of the corresponding GraphQL types where there are any.
+func inputTypeName(typ reflect.Type) string {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == timeType {
+		return dateTimeScalarName
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		if typ != stringType && typ.Name() != "" {
+			return typ.Name()
+		}
+		return "String"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "Int"
+	case reflect.Float32, reflect.Float64:
+		return "Float"
+	case reflect.Bool:
+		return "Boolean"
+	case reflect.Slice, reflect.Array:
+		return "[" + inputTypeName(typ.Elem()) + "]"
+	}
+	if typ.Name() != "" {
+		return typ.Name()
+	}
+	return typ.String()
+}
@@ -1,9 +1,12 @@
 package quickgraph
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -37,7 +40,7 @@ func Test_parseNothing_Error(t *testing.T) {
 	v := reflect.ValueOf(&x).Elem()
 
 	req := &request{}
-	err := parseInputIntoValue(req, genericValue{}, v)
+	err := parseInputIntoValue(context.Background(), req, genericValue{}, v)
 
 	assert.EqualError(t, err, "no input found to parse into value")
 }
@@ -47,29 +50,90 @@ func Test_parseIdentifierIntoValue_Enum(t *testing.T) {
 	v := reflect.ValueOf(&x)
 
 	// Test a known identifier.
-	err := parseIdentifierIntoValue("EnumVal2", v)
+	err := parseIdentifierIntoValue(context.Background(), "EnumVal2", v)
 	assert.Equal(t, EnumVal2, x, "The enum value should have been set to EnumVal2")
 	assert.NoError(t, err)
 
 	// Test an unknown identifier.
-	err = parseIdentifierIntoValue("Unknown", v)
+	err = parseIdentifierIntoValue(context.Background(), "Unknown", v)
 	assert.Error(t, err)
 }
 
+type localeContextKey struct{}
+
+type LocalizedEnum string
+
+const (
+	LocalizedYes LocalizedEnum = "YES"
+	LocalizedNo  LocalizedEnum = "NO"
+)
+
+func (e *LocalizedEnum) UnmarshalString(input string) (interface{}, error) {
+	return nil, fmt.Errorf("the context-free unmarshaler should not be used")
+}
+
+func (e *LocalizedEnum) UnmarshalStringContext(ctx context.Context, input string) (interface{}, error) {
+	if ctx.Value(localeContextKey{}) == "de" {
+		switch input {
+		case "JA":
+			return LocalizedYes, nil
+		case "NEIN":
+			return LocalizedNo, nil
+		}
+	} else {
+		switch input {
+		case "YES":
+			return LocalizedYes, nil
+		case "NO":
+			return LocalizedNo, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid enum value %s", input)
+}
+
+func Test_parseIdentifierIntoValue_EnumWithContext(t *testing.T) {
+	var x LocalizedEnum
+	v := reflect.ValueOf(&x)
+
+	err := parseIdentifierIntoValue(context.Background(), "YES", v)
+	assert.NoError(t, err)
+	assert.Equal(t, LocalizedYes, x)
+
+	deCtx := context.WithValue(context.Background(), localeContextKey{}, "de")
+	err = parseIdentifierIntoValue(deCtx, "NEIN", v)
+	assert.NoError(t, err)
+	assert.Equal(t, LocalizedNo, x)
+
+	err = parseIdentifierIntoValue(deCtx, "YES", v)
+	assert.Error(t, err)
+}
+
+func TestEnumWithContext_Request(t *testing.T) {
+	g := Graphy{}
+	g.RegisterQuery(context.Background(), "answer", func(a LocalizedEnum) string {
+		return string(a)
+	}, "a")
+
+	ctx := context.WithValue(context.Background(), localeContextKey{}, "de")
+	result, err := g.ProcessRequest(ctx, `{ answer(a: JA) }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"answer":"YES"}}`, result)
+}
+
 func Test_parseIdentifierIntoValue_Bool(t *testing.T) {
 	var x bool
 	v := reflect.ValueOf(&x).Elem()
 
 	// Test a known identifier.
-	err := parseIdentifierIntoValue("true", v)
+	err := parseIdentifierIntoValue(context.Background(), "true", v)
 	assert.Equal(t, true, x)
 	assert.NoError(t, err)
 
-	err = parseIdentifierIntoValue("false", v)
+	err = parseIdentifierIntoValue(context.Background(), "false", v)
 	assert.Equal(t, false, x)
 	assert.NoError(t, err)
 
-	err = parseIdentifierIntoValue("random", v)
+	err = parseIdentifierIntoValue(context.Background(), "random", v)
 	assert.Error(t, err)
 }
 
@@ -78,11 +142,11 @@ func Test_parseIdentifierIntoValue_BoolPtr(t *testing.T) {
 	v := reflect.ValueOf(&x).Elem()
 
 	// Test a known identifier.
-	err := parseIdentifierIntoValue("true", v)
+	err := parseIdentifierIntoValue(context.Background(), "true", v)
 	assert.Equal(t, true, *x)
 	assert.NoError(t, err)
 
-	err = parseIdentifierIntoValue("false", v)
+	err = parseIdentifierIntoValue(context.Background(), "false", v)
 	assert.Equal(t, false, *x)
 	assert.NoError(t, err)
 }
@@ -98,7 +162,7 @@ func Test_parseFloatIntoValue_Base(t *testing.T) {
 	v := reflect.ValueOf(&outVal).Elem()
 
 	req := &request{}
-	err := parseInputIntoValue(req, inVal, v)
+	err := parseInputIntoValue(context.Background(), req, inVal, v)
 
 	assert.NoError(t, err)
 	assert.Equal(t, x, outVal)
@@ -115,7 +179,7 @@ func Test_parseFloatIntoValue_Ptr(t *testing.T) {
 	v := reflect.ValueOf(&outVal).Elem()
 
 	req := &request{}
-	err := parseInputIntoValue(req, inVal, v)
+	err := parseInputIntoValue(context.Background(), req, inVal, v)
 
 	assert.NoError(t, err)
 	assert.Equal(t, x, *outVal)
@@ -132,7 +196,7 @@ func Test_parseIntIntoValue_Base(t *testing.T) {
 	v := reflect.ValueOf(&outVal).Elem()
 
 	req := &request{}
-	err := parseInputIntoValue(req, inVal, v)
+	err := parseInputIntoValue(context.Background(), req, inVal, v)
 
 	assert.NoError(t, err)
 	assert.Equal(t, x, outVal)
@@ -149,7 +213,7 @@ func Test_parseIntIntoValue_Ptr(t *testing.T) {
 	v := reflect.ValueOf(&outVal).Elem()
 
 	req := &request{}
-	err := parseInputIntoValue(req, inVal, v)
+	err := parseInputIntoValue(context.Background(), req, inVal, v)
 
 	assert.NoError(t, err)
 	assert.Equal(t, x, *outVal)
@@ -166,7 +230,7 @@ func Test_parseStringIntoValue_Base(t *testing.T) {
 	v := reflect.ValueOf(&outVal).Elem()
 
 	req := &request{}
-	err := parseInputIntoValue(req, inVal, v)
+	err := parseInputIntoValue(context.Background(), req, inVal, v)
 
 	assert.NoError(t, err)
 	assert.Equal(t, "hello", outVal)
@@ -183,7 +247,7 @@ func Test_parseStringIntoValue_Ptr(t *testing.T) {
 	v := reflect.ValueOf(&outVal).Elem()
 
 	req := &request{}
-	err := parseInputIntoValue(req, inVal, v)
+	err := parseInputIntoValue(context.Background(), req, inVal, v)
 
 	assert.NoError(t, err)
 	assert.Equal(t, "hello", *outVal)
@@ -200,7 +264,7 @@ func Test_parseIdentifierIntoValue_Base(t *testing.T) {
 	v := reflect.ValueOf(&outVal).Elem()
 
 	req := &request{}
-	err := parseInputIntoValue(req, inVal, v)
+	err := parseInputIntoValue(context.Background(), req, inVal, v)
 
 	assert.NoError(t, err)
 	assert.Equal(t, "hello", outVal)
@@ -217,7 +281,7 @@ func Test_parseIdentifierIntoValue_Ptr(t *testing.T) {
 	v := reflect.ValueOf(&outVal).Elem()
 
 	req := &request{}
-	err := parseInputIntoValue(req, inVal, v)
+	err := parseInputIntoValue(context.Background(), req, inVal, v)
 
 	assert.NoError(t, err)
 	assert.Equal(t, "hello", *outVal)
@@ -235,7 +299,7 @@ func Test_parseIdentifierIntoValue_BaseType(t *testing.T) {
 	v := reflect.ValueOf(&outVal).Elem()
 
 	req := &request{}
-	err := parseInputIntoValue(req, inVal, v)
+	err := parseInputIntoValue(context.Background(), req, inVal, v)
 
 	assert.NoError(t, err)
 	assert.Equal(t, myType("hello"), outVal)
@@ -253,8 +317,368 @@ func Test_parseIdentifierIntoValue_PtrType(t *testing.T) {
 	v := reflect.ValueOf(&outVal).Elem()
 
 	req := &request{}
-	err := parseInputIntoValue(req, inVal, v)
+	err := parseInputIntoValue(context.Background(), req, inVal, v)
 
 	assert.NoError(t, err)
 	assert.Equal(t, myType("hello"), *outVal)
 }
+
+func Test_parseMapIntoValue_TagPrecedence(t *testing.T) {
+	type input struct {
+		Name     string
+		Internal string  `json:"-" graphy:"internal"`
+		Ignored  *string `json:"-"`
+	}
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "echo", func(in input) string {
+		return in.Name + "/" + in.Internal
+	}, "in")
+
+	result, err := g.ProcessRequest(ctx, `{ echo(in: {Name: "a", internal: "b"}) }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"echo":"a/b"}}`, result)
+
+	// Fields excluded from the graph can't be set, not even by their Go name.
+	_, err = g.ProcessRequest(ctx, `{ echo(in: {Name: "a", internal: "b", Ignored: "c"}) }`, "")
+	assert.ErrorContains(t, err, "field Ignored not found in input struct")
+}
+
+func Test_parseInputIntoValue_SingleValueToList(t *testing.T) {
+	var x int64 = 5
+	var outVal []int
+	err := parseInputIntoValue(context.Background(), &request{}, genericValue{Int: &x}, reflect.ValueOf(&outVal).Elem())
+	assert.NoError(t, err)
+	assert.Equal(t, []int{5}, outVal)
+
+	var nested [][]int
+	err = parseInputIntoValue(context.Background(), &request{}, genericValue{Int: &x}, reflect.ValueOf(&nested).Elem())
+	assert.NoError(t, err)
+	assert.Equal(t, [][]int{{5}}, nested)
+
+	s := `"a"`
+	var ptrs []*string
+	err = parseInputIntoValue(context.Background(), &request{}, genericValue{String: &s}, reflect.ValueOf(&ptrs).Elem())
+	assert.NoError(t, err)
+	if assert.Len(t, ptrs, 1) {
+		assert.Equal(t, "a", *ptrs[0])
+	}
+}
+
+func TestSingleValueToListCoercion(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "sum", func(ids []int) int {
+		total := 0
+		for _, id := range ids {
+			total += id
+		}
+		return total*100 + len(ids)
+	}, "ids")
+
+	tests := []struct {
+		query     string
+		variables string
+		expected  string
+	}{
+		{`{ sum(ids: 5) }`, "", `{"data":{"sum":501}}`},
+		{`{ sum(ids: [5, 6]) }`, "", `{"data":{"sum":1102}}`},
+		{`{ sum(ids: []) }`, "", `{"data":{"sum":0}}`},
+		{`query q($ids: [Int!]!) { sum(ids: $ids) }`, `{"ids": 5}`, `{"data":{"sum":501}}`},
+		{`query q($ids: [Int!]!) { sum(ids: $ids) }`, `{"ids": [5, 6]}`, `{"data":{"sum":1102}}`},
+	}
+	for _, tt := range tests {
+		result, err := g.ProcessRequest(ctx, tt.query, tt.variables)
+		assert.NoError(t, err, tt.query)
+		assert.Equal(t, tt.expected, result, tt.query)
+	}
+}
+
+func Test_coerceJsonList(t *testing.T) {
+	assert.Equal(t, `[5]`, string(coerceJsonList(json.RawMessage(`5`), reflect.TypeOf([]int{}))))
+	assert.Equal(t, `[[5]]`, string(coerceJsonList(json.RawMessage(`5`), reflect.TypeOf([][]int{}))))
+	assert.Equal(t, `[{"a":1}]`, string(coerceJsonList(json.RawMessage(`{"a":1}`), reflect.TypeOf(&[]map[string]int{}))))
+	assert.Equal(t, ` [5]`, string(coerceJsonList(json.RawMessage(` [5]`), reflect.TypeOf([]int{}))))
+	assert.Equal(t, `null`, string(coerceJsonList(json.RawMessage(`null`), reflect.TypeOf([]int{}))))
+	assert.Equal(t, `5`, string(coerceJsonList(json.RawMessage(`5`), reflect.TypeOf(0))))
+	assert.Equal(t, `"AQI="`, string(coerceJsonList(json.RawMessage(`"AQI="`), reflect.TypeOf([]byte{}))))
+}
+
+type nestedVarFilter struct {
+	Owner  string           `json:"owner"`
+	Status *episode         `json:"status"`
+	Tags   []string         `json:"tags"`
+	Sub    *nestedVarFilter `json:"sub"`
+}
+
+type nestedVarResult struct {
+	Filter nestedVarFilter
+}
+
+func (r nestedVarResult) Describe(f nestedVarFilter) string {
+	return describeNestedVarFilter(f)
+}
+
+func describeNestedVarFilter(f nestedVarFilter) string {
+	s := fmt.Sprintf("%s %v", f.Owner, f.Tags)
+	if f.Status != nil {
+		s += " " + string(*f.Status)
+	}
+	if f.Sub != nil {
+		s += " (" + describeNestedVarFilter(*f.Sub) + ")"
+	}
+	return s
+}
+
+func TestNestedVariablesInLiterals(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "find", func(filter nestedVarFilter) string {
+		return describeNestedVarFilter(filter)
+	}, "filter")
+	g.RegisterQuery(ctx, "result", func() nestedVarResult {
+		return nestedVarResult{}
+	})
+
+	vars := `{"user": "bob", "tag": "b", "status": "JEDI"}`
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{
+			`query q($user: String!, $status: episode) { find(filter: {owner: $user, status: $status, tags: []}) }`,
+			`{"data":{"find":"bob [] JEDI"}}`,
+		},
+		{
+			`query q($user: String!, $tag: String!) { find(filter: {owner: "x", tags: ["a", $tag], sub: {owner: $user, tags: [$tag]}}) }`,
+			`{"data":{"find":"x [a b] (bob [b])"}}`,
+		},
+		{
+			`query q($user: String!, $status: episode) { result { ...F } }
+			 fragment F on nestedVarResult { Describe(owner: "y", status: $status, tags: [], sub: {owner: $user, tags: []}) }`,
+			`{"data":{"result":{"Describe":"y [] JEDI (bob [])"}}}`,
+		},
+	}
+	for _, tt := range tests {
+		result, err := g.ProcessRequest(ctx, tt.query, vars)
+		assert.NoError(t, err, tt.query)
+		assert.Equal(t, tt.expected, result, tt.query)
+	}
+
+	// The nested variables still need to be used consistently.
+	_, err := g.ProcessRequest(ctx, `query q($tag: String!) { find(filter: {owner: $tag, tags: [$tag], sub: {owner: "x", tags: [], status: $tag}}) }`, vars)
+	assert.ErrorContains(t, err, "variable tag is used with different types")
+}
+
+func Test_parseInputIntoValue_NullListElements(t *testing.T) {
+	null := "null"
+	one := int64(1)
+	in := genericValue{List: []genericValue{
+		{List: []genericValue{{Int: &one}, {Identifier: &null}}},
+		{Identifier: &null},
+	}}
+
+	var ptrs []*[]*int
+	err := parseInputIntoValue(context.Background(), &request{}, in, reflect.ValueOf(&ptrs).Elem())
+	assert.NoError(t, err)
+	if assert.Len(t, ptrs, 2) {
+		assert.Len(t, *ptrs[0], 2)
+		assert.Equal(t, 1, *(*ptrs[0])[0])
+		assert.Nil(t, (*ptrs[0])[1])
+		assert.Nil(t, ptrs[1])
+	}
+}
+
+func TestNestedArrays_Request(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "cells", func() [][]*matrixCell {
+		return [][]*matrixCell{{{Value: 1}, nil}, nil}
+	})
+	g.RegisterQuery(ctx, "optionalCells", func() *[]*matrixCell {
+		return nil
+	})
+	g.RegisterQuery(ctx, "cube", func() [][][]string {
+		return [][][]string{{{"a", "b"}}}
+	})
+	g.RegisterQuery(ctx, "rows", func() []*[]*matrixCell {
+		row := []*matrixCell{{Value: 2}}
+		return []*[]*matrixCell{&row, nil}
+	})
+	g.RegisterQuery(ctx, "matrix", func() matrix {
+		one := []int{1}
+		return matrix{Rows: [][]*matrixCell{{{Value: 3}}}, Sparse: &[]*[]int{&one, nil}}
+	})
+	g.RegisterQuery(ctx, "sum", func(m [][]*int) int {
+		sum := 0
+		for _, row := range m {
+			for _, v := range row {
+				if v != nil {
+					sum += *v
+				}
+			}
+		}
+		return sum
+	}, "m")
+	g.RegisterQuery(ctx, "count", func(m *[]*[]int) int {
+		count := 0
+		for _, row := range *m {
+			if row != nil {
+				count += len(*row)
+			}
+		}
+		return count
+	}, "m")
+
+	result, err := g.ProcessRequest(ctx, `{ cells { Value } rows { Value } optionalCells { Value } cube }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"cells":[[{"Value":1},null],[]],"cube":[[["a","b"]]],"optionalCells":null,"rows":[[{"Value":2}],null]}}`, result)
+
+	result, err = g.ProcessRequest(ctx, `{ matrix { Rows { Value } Sparse } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"matrix":{"Rows":[[{"Value":3}]],"Sparse":[[1],null]}}}`, result)
+
+	result, err = g.ProcessRequest(ctx, `{ sum(m: [[1, null], [2]]) count(m: [[1, 2], null, [3]]) }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"count":3,"sum":3}}`, result)
+
+	result, err = g.ProcessRequest(ctx, `query Q($m: [[Int]!]!, $n: [[Int!]]) { sum(m: $m) count(m: $n) }`, `{"m": [[5, null], [6]], "n": [[1], null]}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"count":1,"sum":11}}`, result)
+}
+
+func Test_parseInputIntoValue_TypeMismatches(t *testing.T) {
+	str := `"foo"`
+	big := int64(300)
+	neg := int64(-1)
+	f := 1.5
+	ident := "RED"
+	null := "null"
+
+	tests := []struct {
+		name     string
+		in       genericValue
+		target   any
+		expected string
+	}{
+		{"string into int", genericValue{String: &str}, new(int64), "expected Int, got String"},
+		{"int into string", genericValue{Int: &big}, new(string), "expected String, got Int"},
+		{"float into int", genericValue{Float: &f}, new(int), "expected Int, got Float"},
+		{"int overflow", genericValue{Int: &big}, new(int8), "value 300 is out of range for int8"},
+		{"negative uint", genericValue{Int: &neg}, new(uint), "value -1 is out of range for uint"},
+		{"enum into int", genericValue{Identifier: &ident}, new(int), "expected Int, got enum value RED"},
+		{"null into int", genericValue{Identifier: &null}, new(int), "expected Int, got null"},
+		{"list into string", genericValue{List: []genericValue{{String: &str}}}, new(string), "expected String, got List"},
+		{"object into int", genericValue{Map: []namedValue{}}, new(int), "expected Int, got Object"},
+		{"list element", genericValue{List: []genericValue{{Int: &big}, {String: &str}}}, new([]int), "expected Int, got String"},
+		{"object field", genericValue{Map: []namedValue{{Name: "A", Value: genericValue{Int: &big}}}}, new(NonUnion), "expected String for field A, got Int"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parseInputIntoValue(context.Background(), &request{}, tt.in, reflect.ValueOf(tt.target).Elem())
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+func Test_parseInputIntoValue_IntIntoFloat(t *testing.T) {
+	i := int64(3)
+	var f float64
+	err := parseInputIntoValue(context.Background(), &request{}, genericValue{Int: &i}, reflect.ValueOf(&f).Elem())
+	assert.NoError(t, err)
+	assert.Equal(t, 3.0, f)
+
+	var u uint8
+	err = parseInputIntoValue(context.Background(), &request{}, genericValue{Int: &i}, reflect.ValueOf(&u).Elem())
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(3), u)
+}
+
+func TestArgumentTypeMismatch_Request(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "delay", DelayedFunc, "sleepTime")
+
+	response, err := g.ProcessRequest(ctx, `{ delay(sleepTime: "long") { Out } }`, "")
+	assert.Error(t, err)
+	assert.Equal(t, `{"data":{},"errors":[{"message":"error getting call parameters for function delay: expected Int for argument sleepTime, got String","locations":[{"line":1,"column":20}],"path":["delay"]}]}`, response)
+}
+
+type countedEnum string
+
+var countedEnumParses int
+
+func (c *countedEnum) UnmarshalString(input string) (interface{}, error) {
+	countedEnumParses++
+	return countedEnum(strings.ToLower(input)), nil
+}
+
+type contextEnum string
+
+var contextEnumParses int
+
+func (c *contextEnum) UnmarshalStringContext(ctx context.Context, input string) (interface{}, error) {
+	contextEnumParses++
+	return contextEnum(strings.ToLower(input)), nil
+}
+
+func TestVariableDefaults_ParsedOncePerStub(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{RequestCache: simpleCache{values: map[string]*simpleCacheEntry{}}}
+	g.RegisterQuery(ctx, "counted", func(c countedEnum, n int) string {
+		return fmt.Sprintf("%s %d", c, n)
+	}, "c", "n")
+	g.RegisterQuery(ctx, "contextual", func(c contextEnum) string {
+		return string(c)
+	}, "c")
+
+	countedEnumParses = 0
+	query := `query q($c: countedEnum = RED, $n: Int = 3) { counted(c: $c, n: $n) }`
+	for i := 0; i < 3; i++ {
+		result, err := g.ProcessRequest(ctx, query, "")
+		assert.NoError(t, err)
+		assert.Equal(t, `{"data":{"counted":"red 3"}}`, result)
+	}
+	assert.Equal(t, 1, countedEnumParses)
+
+	// Provided values still take precedence over the defaults.
+	result, err := g.ProcessRequest(ctx, query, `{"n": 4}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"counted":"red 4"}}`, result)
+
+	// Values that depend on the context of the request are parsed each time.
+	contextEnumParses = 0
+	query = `query q($c: contextEnum = BLUE) { contextual(c: $c) }`
+	for i := 0; i < 3; i++ {
+		result, err := g.ProcessRequest(ctx, query, "")
+		assert.NoError(t, err)
+		assert.Equal(t, `{"data":{"contextual":"blue"}}`, result)
+	}
+	assert.Equal(t, 3, contextEnumParses)
+}
+
+type preciseInput struct {
+	Amount json.Number
+	Extra  any
+}
+
+func TestPreciseNumbers(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "precise", func(in preciseInput) string {
+		return fmt.Sprintf("%s %v %T", in.Amount, in.Extra, in.Extra)
+	}, "in")
+	g.RegisterQuery(ctx, "amount", func(amount json.Number) string {
+		return amount.String()
+	}, "amount")
+
+	result, err := g.ProcessRequest(ctx, `query q($in: preciseInput!) { precise(in: $in) }`,
+		`{"in": {"Amount": 123456789012345678901234567890.123, "Extra": 9007199254740993}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"precise":"123456789012345678901234567890.123 9007199254740993 json.Number"}}`, result)
+
+	// Literal numbers are taken as they are as well.
+	result, err = g.ProcessRequest(ctx, `{ a: amount(amount: 9007199254740993) b: amount(amount: 0.1) }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"a":"9007199254740993","b":"0.1"}}`, result)
+}
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"github.com/alecthomas/participle/v2/lexer"
 	"reflect"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // processCallOutput takes a command and a slice of call results,
@@ -31,21 +34,58 @@ func (f *graphFunction) processCallOutput(ctx context.Context, req *request, fil
 		kind = callResult.Kind()
 	}
 
+	var pointer reflect.Value
 	if (kind == reflect.Pointer) && !callResult.IsNil() {
 		// If this is a pointer, dereference it.
+		pointer = callResult
 		callResult = callResult.Elem()
 		kind = callResult.Kind() // Update the kind
 	}
 
+	if kind == reflect.Func {
+		if _, ok := iteratorElemType(callResult.Type()); ok {
+			return f.processIteratorOutput(ctx, req, filter, callResult)
+		}
+	}
+
 	if kind == reflect.Slice {
+		var batches batchValues
+		if !callResult.IsNil() {
+			var err error
+			batches, err = f.prefetchBatchFields(ctx, req, filter, callResult)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if !callResult.IsNil() && req != nil && req.resolverSlots != nil &&
+			f.g.selectsGraphFunctions(f.g.typeLookup(callResult.Type().Elem()), filter, req.stub.fragments) {
+			return f.processSliceOutputConcurrently(ctx, req, filter, callResult, batches)
+		}
 		if !callResult.IsNil() {
 			retVal := []any{}
 			count := callResult.Len()
+			chunkSize := f.g.resultChunkSize()
 			for i := 0; i < count; i++ {
+				if i > 0 && i%chunkSize == 0 {
+					err := f.g.endResultChunk(ctx)
+					if err != nil {
+						return nil, AugmentGraphError(err, "context cancelled while generating results", pos)
+					}
+				}
 				a := callResult.Index(i)
-				sr, err := f.processCallOutput(ctx, req, filter, a)
+				sr, err := f.processCallOutput(req.withResultPath(batches.context(ctx, i), i), req, filter, a)
+				if include, ok := f.g.unauthorizedListItem(err); ok {
+					if include {
+						retVal = append(retVal, nil)
+					}
+					continue
+				}
 				if err != nil {
-					return nil, AugmentGraphError(err, fmt.Sprintf("error processing slice element %v", i), pos, strconv.Itoa(i))
+					message := fmt.Sprintf("error processing slice element %v", i)
+					if !f.isolateElementError(ctx, req, err, message, pos, i) {
+						return nil, AugmentGraphError(err, message, pos, strconv.Itoa(i))
+					}
+					sr = nil
 				}
 				retVal = append(retVal, sr)
 			}
@@ -55,15 +95,194 @@ func (f *graphFunction) processCallOutput(ctx context.Context, req *request, fil
 	} else if kind == reflect.Map {
 		// TODO: Handle maps?
 		return nil, NewGraphError(fmt.Sprintf("maps not supported"), pos)
+	} else if callResult.Type() == timeType {
+		return formatScalar(ctx, callResult.Interface()), nil
 	} else if kind == reflect.Struct {
-		sr, err := f.processOutputStruct(nil, req, filter, callResult.Interface())
+		err := checkCanView(ctx, callResult, pointer, pos)
+		if err != nil {
+			return nil, err
+		}
+		sr, err := f.processOutputStruct(ctx, req, filter, callResult.Interface())
 		if err != nil {
 			return nil, AugmentGraphError(err, fmt.Sprintf("error processing struct"), pos)
 		}
 		return sr, nil
 	} else {
-		return callResult.Interface(), nil
+		return formatScalar(ctx, callResult.Interface()), nil
+	}
+}
+
+// defaultResultChunkSize is the number of list elements between the checks for
+// cancellation if ResultChunkSize isn't set.
+const defaultResultChunkSize = 256
+
+func (g *Graphy) resultChunkSize() int {
+	if g.ResultChunkSize > 0 {
+		return g.ResultChunkSize
+	}
+	return defaultResultChunkSize
+}
+
+// endResultChunk is called after each chunk of the elements of a list is generated. It
+// returns the error of the context if the request was cancelled, so that the rest of the
+// list isn't generated for nothing, and yields to other goroutines if YieldBetweenChunks
+// is set.
+func (g *Graphy) endResultChunk(ctx context.Context) error {
+	if ctx != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if g.YieldBetweenChunks {
+		runtime.Gosched()
+	}
+	return nil
+}
+
+// processSliceOutputConcurrently processes the elements of a slice using additional goroutines
+// from the request's pool of resolver slots. If there is no free slot, the element is processed
+// in the current goroutine instead. This bounds the number of goroutines used by a request, even
+// with nested lists, and never blocks waiting for a slot. The order of the results matches the
+// order of the elements in the slice. The batches are the values of the batch fields of the
+// elements, if there are any.
+func (f *graphFunction) processSliceOutputConcurrently(ctx context.Context, req *request, filter *resultFilter, slice reflect.Value, batches batchValues) (any, error) {
+	var pos lexer.Position
+	if filter != nil {
+		pos = filter.Pos
+	}
+
+	count := slice.Len()
+	results := make([]any, count)
+	errs := make([]error, count)
+
+	var wg sync.WaitGroup
+	var cancelled error
+	for i := 0; i < count; i++ {
+		if cancelled = req.cancelledBeforeResolving(ctx); cancelled != nil {
+			// Don't start on the rest of the elements.
+			break
+		}
+		select {
+		case req.resolverSlots <- struct{}{}:
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				defer func() { <-req.resolverSlots }()
+				results[i], errs[i] = f.processCallOutput(req.withResultPath(batches.context(ctx, i), i), req, filter, slice.Index(i))
+			}(i)
+		default:
+			results[i], errs[i] = f.processCallOutput(req.withResultPath(batches.context(ctx, i), i), req, filter, slice.Index(i))
+		}
+	}
+	wg.Wait()
+	if cancelled != nil {
+		// The whole list fails, even if the errors of the elements are isolated, so that
+		// the elements that weren't generated aren't mistaken for nulls.
+		return nil, AugmentGraphError(cancelled, "context cancelled while generating results", pos)
+	}
+
+	visible := results[:0]
+	for i, err := range errs {
+		if include, ok := f.g.unauthorizedListItem(err); ok {
+			if include {
+				visible = append(visible, nil)
+			}
+			continue
+		}
+		if err != nil {
+			message := fmt.Sprintf("error processing slice element %v", i)
+			if !f.isolateElementError(ctx, req, err, message, pos, i) {
+				return nil, AugmentGraphError(err, message, pos, strconv.Itoa(i))
+			}
+			results[i] = nil
+		}
+		visible = append(visible, results[i])
 	}
+	return visible, nil
+}
+
+// selectsGraphFunctions reports whether applying the filter to the type selects any fields
+// that are implemented by functions, either directly or in any of the nested selections.
+// Unions and fragments are treated conservatively as the concrete types are only known at
+// runtime.
+func (g *Graphy) selectsGraphFunctions(tl *typeLookup, filter *resultFilter, fragments map[string]fragment) bool {
+	if filter == nil {
+		return false
+	}
+	if len(tl.union) > 0 || len(filter.Fragments) > 0 {
+		return true
+	}
+	for _, field := range filter.Fields {
+		fl, ok := tl.GetField(field.Name)
+		if !ok {
+			continue
+		}
+		if fl.fieldType == FieldTypeGraphFunction {
+			return true
+		}
+		if field.SubParts != nil && g.selectsGraphFunctions(g.typeLookup(fl.resultType), field.SubParts, fragments) {
+			return true
+		}
+	}
+	return false
+}
+
+// processIteratorOutput consumes an iterator function of the form func(yield func(T) bool)
+// and processes each of the elements as it is produced. This allows functions to return
+// very large lists without having to build a slice of all the elements first. The iteration
+// is stopped early if an element can't be processed or if the context is cancelled.
+func (f *graphFunction) processIteratorOutput(ctx context.Context, req *request, filter *resultFilter, iterator reflect.Value) (retVal any, retErr error) {
+	var pos lexer.Position
+	if filter != nil {
+		pos = filter.Pos
+	}
+
+	// The iterator is user code that runs outside of the function call, so it needs its own
+	// panic handling.
+	defer func() {
+		if r := recover(); r != nil {
+			retVal = nil
+			retErr = NewGraphError(fmt.Sprintf("iterator for function %s panicked: %v", f.name, r), pos)
+		}
+	}()
+
+	results := []any{}
+	if iterator.IsNil() {
+		return results, nil
+	}
+
+	var iterErr error
+	yield := reflect.MakeFunc(iterator.Type().In(0), func(args []reflect.Value) []reflect.Value {
+		index := len(results)
+		if ctx != nil && ctx.Err() != nil {
+			iterErr = AugmentGraphError(ctx.Err(), "context cancelled while iterating", pos)
+			return []reflect.Value{reflect.ValueOf(false)}
+		}
+		if f.g.YieldBetweenChunks && index > 0 && index%f.g.resultChunkSize() == 0 {
+			runtime.Gosched()
+		}
+		sr, err := f.processCallOutput(req.withResultPath(ctx, index), req, filter, args[0])
+		if include, ok := f.g.unauthorizedListItem(err); ok {
+			if include {
+				results = append(results, nil)
+			}
+			return []reflect.Value{reflect.ValueOf(true)}
+		}
+		if err != nil {
+			message := fmt.Sprintf("error processing iterator element %v", index)
+			if !f.isolateElementError(ctx, req, err, message, pos, index) {
+				iterErr = AugmentGraphError(err, message, pos, strconv.Itoa(index))
+				return []reflect.Value{reflect.ValueOf(false)}
+			}
+			sr = nil
+		}
+		results = append(results, sr)
+		return []reflect.Value{reflect.ValueOf(true)}
+	})
+	iterator.Call([]reflect.Value{yield})
+
+	if iterErr != nil {
+		return nil, iterErr
+	}
+	return results, nil
 }
 
 // processOutputStruct takes a result filter and a struct, processes the struct according to the filter,
@@ -80,67 +299,122 @@ func (f *graphFunction) processOutputStruct(ctx context.Context, req *request, f
 		return nil, AugmentGraphError(err, fmt.Sprintf("error dereferencing union type"), filter.Pos)
 	}
 
-	t := reflect.TypeOf(anyStruct)
-	typeName := t.Name()
-	fieldMap := f.g.typeLookup(t)
-
 	if filter == nil {
 		return nil, NewGraphError(fmt.Sprintf("output filter is not present"), lexer.Position{})
 	}
 
-	fieldsToProcess := []resultField{}
-	r := map[string]any{}
+	plan := req.stub.plan.selection(f.g, req.stub.fragments, filter, reflect.TypeOf(anyStruct))
+	r := f.g.newResultObject()
 
-	for _, field := range filter.Fields {
-		fieldsToProcess = append(fieldsToProcess, field)
-	}
-	for _, fragmentCall := range filter.Fragments {
-		var f *fragmentDef
-		if fragmentCall.Inline != nil {
-			f = fragmentCall.Inline
-		} else if fragmentCall.FragmentRef != nil {
-			f = req.stub.fragments[*fragmentCall.FragmentRef].Definition
-		}
-		if found, tl := fieldMap.ImplementsInterface(f.TypeName); found {
-			fieldMap = tl
-			for _, field := range f.Filter.Fields {
-				fieldsToProcess = append(fieldsToProcess, field)
-			}
-		}
+	// The values of batch fields that were fetched for the whole list that the struct is
+	// in. They are only for this struct, not for the ones in its fields.
+	var batched map[string]any
+	if ctx != nil {
+		batched, _ = ctx.Value(batchValuesContextKey).(map[string]any)
+	}
+	if batched != nil {
+		ctx = context.WithValue(ctx, batchValuesContextKey, map[string]any(nil))
 	}
 
 	// Go through the result fields and map them to the struct fields.
-	for _, field := range fieldsToProcess {
-		if field.Name == "__typename" {
-			r[field.Name] = typeName
-		} else {
-			fieldInfo, ok := fieldMap.GetField(field.Name)
-			if !ok {
-				// TODO: Is this an error?
-				continue
-			}
-			// Todo: Check for directives. Either here or in fetch.
-
-			fieldAny, err := fieldInfo.fetch(ctx, req, reflect.ValueOf(anyStruct), field.Params)
-			if err != nil {
-				return nil, AugmentGraphError(err, fmt.Sprintf("error fetching field %v", field.Name), field.Pos, field.Name)
-			}
-			if field.SubParts != nil {
-				fieldVal := reflect.ValueOf(fieldAny)
-				subPart, err := f.processCallOutput(ctx, req, field.SubParts, fieldVal)
-				if err != nil {
-					return nil, AugmentGraphError(err, fmt.Sprintf("error processing subpart %v", field.Name), field.Pos, field.Name)
-				}
-				r[field.Name] = subPart
-			} else {
-				r[field.Name] = fieldAny
+	var deferred []*deferredFields
+	for _, pf := range plan.fields {
+		if pf.lookup.fieldType == FieldTypeGraphFunction {
+			// The client is gone, so the rest of the fields would be resolved for nothing.
+			// Fields that are just values are still returned.
+			if err := req.cancelledBeforeResolving(ctx); err != nil {
+				return nil, AugmentGraphError(err, "context cancelled while generating results", filter.Pos)
 			}
 		}
+		var isDeferred bool
+		if deferred, isDeferred = req.deferField(ctx, deferred, pf); isDeferred {
+			continue
+		}
+		err := f.processPlannedField(ctx, req, plan, pf, anyStruct, batched, r)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, d := range deferred {
+		f.deferFields(ctx, req, plan, anyStruct, d)
+	}
+	if req.typenames && !plan.typeNameSelected {
+		r.Set("__typename", plan.typeName)
 	}
 
 	return r, nil
 }
 
+// cancelledBeforeResolving returns the error of the context if the request was cancelled
+// before a resolver is called. The results of mutations are always generated, since their
+// changes have been made already and the client needs to know that they were.
+func (req *request) cancelledBeforeResolving(ctx context.Context) error {
+	if ctx == nil || req.stub.mode == RequestMutation {
+		return nil
+	}
+	return ctx.Err()
+}
+
+// processPlannedField fetches the field of the struct and sets it in the result object.
+func (f *graphFunction) processPlannedField(ctx context.Context, req *request, plan *selectionPlan, pf plannedField, anyStruct any, batched map[string]any, r resultObject) error {
+	field := pf.field
+	key := field.resultKey()
+	include, err := f.g.includeField(ctx, req, pf)
+	if err != nil {
+		return AugmentGraphError(err, fmt.Sprintf("error applying directives to field %v", field.Name), field.Pos, key)
+	}
+	if !include {
+		return nil
+	}
+	if pf.typeName {
+		r.Set(key, plan.typeName)
+		return nil
+	}
+	if pf.unknown {
+		r.Set(key, nil)
+		return nil
+	}
+	fieldAny, ok := batched[pf.lookup.name]
+	if !ok || pf.lookup.fieldType != FieldTypeGraphFunction {
+		measure := f.g.measureResolvers() || req.tracing()
+		var start time.Time
+		if measure {
+			start = f.g.now()
+		}
+		fetchCtx, endSpan := req.startResolverSpan(ctx, plan.typeName, field.Name, key)
+		fieldAny, err = pf.lookup.fetch(fetchCtx, req, reflect.ValueOf(anyStruct), field.Params)
+		endSpan(err)
+		if measure {
+			duration := f.g.now().Sub(start)
+			f.g.recordResolver(plan.typeName, field.Name, duration, err)
+			req.traceResolver(plan.typeName, field.Name, field.Params, start, duration, err)
+		}
+	}
+	if err != nil {
+		return AugmentGraphError(err, fmt.Sprintf("error fetching field %v", field.Name), field.Pos, key)
+	}
+	if field.SubParts != nil || isIteratorType(reflect.TypeOf(fieldAny)) {
+		// Iterators need to be consumed even if there is no filter to apply to
+		// their elements.
+		fieldCtx := req.withResultPath(ctx, key)
+		fieldAny = f.streamField(fieldCtx, req, pf, fieldAny)
+		fieldVal := reflect.ValueOf(fieldAny)
+		subPart, err := f.processCallOutput(fieldCtx, req, field.SubParts, fieldVal)
+		if err != nil {
+			return AugmentGraphError(err, fmt.Sprintf("error processing subpart %v", field.Name), field.Pos, key)
+		}
+		r.Set(key, subPart)
+	} else {
+		fieldAny = f.streamField(req.withResultPath(ctx, key), req, pf, fieldAny)
+		value, err := f.g.applyOutputDirectives(ctx, req, field, formatScalar(ctx, fieldAny))
+		if err != nil {
+			return AugmentGraphError(err, fmt.Sprintf("error applying directives to field %v", field.Name), field.Pos, key)
+		}
+		r.Set(key, value)
+	}
+	return nil
+}
+
 // deferenceUnionType takes a struct and checks if the struct is a union type.
 // If it is, it finds the actual type of the struct and returns it.
 // If the struct is not a union type it's simply returned as-is. If there is an
@@ -2,6 +2,7 @@ package quickgraph
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/gburgyan/go-timing"
 	"github.com/stretchr/testify/assert"
@@ -498,11 +499,56 @@ mutation {
 	assert.Error(t, err)
 	assert.Equal(t, `{"data":{"a":{"Out":"DelayedFunc: 50"}},"errors":[{"message":"context timed out: context deadline exceeded"}]}`, response)
 
-	// The total time should be less than 200ms, since the queries are run in parallel.
+	// The second mutation isn't started, since the deadline passed while the first one ran.
 	duration := endTime.Sub(startTime)
 	assert.True(t, duration < 100*time.Millisecond)
 }
 
+func TestGraphFunction_FailFast(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{CommandErrorMode: FailFast}
+
+	cancelled := make(chan struct{})
+	g.RegisterQuery(ctx, "fail", func(ctx context.Context) (string, error) {
+		return "", errors.New("failed")
+	})
+	g.RegisterQuery(ctx, "wait", func(ctx context.Context) string {
+		<-ctx.Done()
+		close(cancelled)
+		return "done"
+	})
+
+	response, err := g.ProcessRequest(ctx, `{ wait fail }`, "")
+	assert.Error(t, err)
+	assert.Equal(t, `{"data":{},"errors":[{"message":"function fail returned error: failed","locations":[{"line":1,"column":8}],"path":["fail"]}]}`, response)
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("the running command was not cancelled")
+	}
+
+	// Mutations stop at the first error.
+	called := false
+	g.RegisterMutation(ctx, "failMutation", func(ctx context.Context) (string, error) {
+		return "", errors.New("failed")
+	})
+	g.RegisterMutation(ctx, "after", func(ctx context.Context) string {
+		called = true
+		return "after"
+	})
+	_, err = g.ProcessRequest(ctx, `mutation { failMutation after }`, "")
+	assert.Error(t, err)
+	assert.False(t, called)
+
+	// By default, all the commands are executed.
+	g.CommandErrorMode = CollectAllErrors
+	response, err = g.ProcessRequest(ctx, `mutation { failMutation after }`, "")
+	assert.Error(t, err)
+	assert.True(t, called)
+	assert.Contains(t, response, `"after":"after"`)
+}
+
 func TestGraphFunction_Invalid(t *testing.T) {
 	type in struct {
 		InString string
@@ -574,7 +620,7 @@ query f($time: int!) {
 	response, err := g.ProcessRequest(ctx, gql, `{"time": "foo"}`)
 
 	assert.Error(t, err)
-	assert.Equal(t, `{"errors":[{"message":"error parsing variable time into type int64: json: cannot unmarshal string into Go value of type int64","path":["time"]}]}`, response)
+	assert.Equal(t, `{"errors":[{"message":"error parsing variable time into type Int!: expected Int, got String","path":["time"]}]}`, response)
 }
 
 func TestGraphFunction_BadDefaultVariableType(t *testing.T) {
@@ -594,7 +640,35 @@ query f($time: int! = "foo") {
 	response, err := g.ProcessRequest(ctx, gql, ``)
 
 	assert.Error(t, err)
-	assert.Equal(t, `{"errors":[{"message":"error parsing default variable time into type int64: panic: reflect: call of reflect.Value.SetString on int64 Value","locations":[{"line":2,"column":23}],"path":["time"]}]}`, response)
+	assert.Equal(t, `{"errors":[{"message":"error parsing default variable time into type Int!: expected Int, got String","locations":[{"line":2,"column":23}],"path":["time"]}]}`, response)
+}
+
+type variableErrorInput struct {
+	Name  string
+	Count int
+}
+
+func TestGraphFunction_VariableTypeNames(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "tagged", func(tags []string, in *variableErrorInput) string {
+		return fmt.Sprint(tags)
+	}, "tags", "in")
+
+	gql := `query f($tags: [String!]!, $in: variableErrorInput) { tagged(tags: $tags, in: $in) }`
+	response, err := g.ProcessRequest(ctx, gql, `{"tags": ["a", true], "in": null}`)
+	assert.Error(t, err)
+	assert.Equal(t, `{"errors":[{"message":"error parsing variable tags into type [String!]!: expected String for element 1, got Boolean","path":["tags"]}]}`, response)
+
+	response, err = g.ProcessRequest(ctx, gql, `{"tags": [], "in": {"Count": 1.5}}`)
+	assert.Error(t, err)
+	assert.Equal(t, `{"errors":[{"message":"error parsing variable in into type variableErrorInput: expected Int for field Count, got Float","path":["in"]}]}`, response)
+
+	// In development mode the Go type is included as well.
+	g.DevelopmentMode = true
+	response, err = g.ProcessRequest(ctx, gql, `{"tags": ["a", true], "in": null}`)
+	assert.Error(t, err)
+	assert.Equal(t, `{"errors":[{"message":"error parsing variable tags into type [String!]!: expected String for element 1, got Boolean","path":["tags"],"extensions":{"goType":"[]string"}}]}`, response)
 }
 
 func TestGraphFunction_MissingVariable(t *testing.T) {
@@ -753,8 +827,8 @@ query mixed($in: string!) {
 }
 `
 	response, err := g.ProcessRequest(ctx, gql, ``)
-	assert.EqualError(t, err, "error adding variable $in (path: $in) [4:5]: variable in is used with different types: existing type: string, new type: int")
-	assert.Equal(t, `{"errors":[{"message":"error adding variable $in: variable in is used with different types: existing type: string, new type: int","locations":[{"line":4,"column":5}],"path":["$in"]}]}`, response)
+	assert.EqualError(t, err, "error adding variable $in (path: $in) [4:5]: variable in is used with different types: existing type: String!, new type: Int!")
+	assert.Equal(t, `{"errors":[{"message":"error adding variable $in: variable in is used with different types: existing type: String!, new type: Int!","locations":[{"line":4,"column":5}],"path":["$in"]}]}`, response)
 }
 
 type TestA struct {
@@ -1158,3 +1232,145 @@ query nestTest($in: [[String!]!]!) {
 `
 	assert.Equal(t, expected, schema)
 }
+
+type iteratorTestItem struct {
+	Name string
+}
+
+func TestGraphFunction_IteratorResult(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "items", func(count int) func(yield func(iteratorTestItem) bool) {
+		return func(yield func(iteratorTestItem) bool) {
+			for i := 0; i < count; i++ {
+				if !yield(iteratorTestItem{Name: fmt.Sprintf("item%d", i)}) {
+					return
+				}
+			}
+		}
+	})
+	g.RegisterQuery(ctx, "numbers", func() func(yield func(int) bool) {
+		return func(yield func(int) bool) {
+			for i := 1; i <= 3; i++ {
+				if !yield(i) {
+					return
+				}
+			}
+		}
+	})
+
+	result, err := g.ProcessRequest(ctx, `{ items(count: 3) { Name } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"items":[{"Name":"item0"},{"Name":"item1"},{"Name":"item2"}]}}`, result)
+
+	result, err = g.ProcessRequest(ctx, `{ numbers }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"numbers":[1,2,3]}}`, result)
+
+	g.EnableIntrospection(ctx)
+	schema := g.SchemaDefinition(ctx)
+	assert.Contains(t, schema, "items(arg0: Int!): [iteratorTestItem!]!")
+	assert.Contains(t, schema, "numbers: [Int!]!")
+}
+
+func TestGraphFunction_IteratorStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	produced := 0
+	g := Graphy{}
+	g.RegisterQuery(ctx, "items", func() func(yield func(iteratorTestItem) bool) {
+		return func(yield func(iteratorTestItem) bool) {
+			for i := 0; i < 100; i++ {
+				produced++
+				if i == 2 {
+					cancel()
+				}
+				if !yield(iteratorTestItem{Name: "x"}) {
+					return
+				}
+			}
+		}
+	})
+
+	_, err := g.ProcessRequest(ctx, `mutation { items { Name } }`, "")
+	assert.Error(t, err)
+	assert.Equal(t, 3, produced)
+}
+
+func TestGraphFunction_IteratorPanic(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "items", func() func(yield func(iteratorTestItem) bool) {
+		return func(yield func(iteratorTestItem) bool) {
+			panic("iterator failure")
+		}
+	})
+
+	result, err := g.ProcessRequest(ctx, `{ items { Name } }`, "")
+	assert.Error(t, err)
+	assert.Contains(t, result, "iterator for function items panicked: iterator failure")
+}
+
+type usageAccount struct {
+	Name  string
+	Stats usageStats
+}
+
+type usageStats struct {
+	Logins int
+}
+
+func (usageStats) GraphTypeExtension() GraphTypeInfo {
+	return GraphTypeInfo{Name: "usageStats", OutputOnly: true}
+}
+
+type usageFilter struct {
+	Prefix string
+}
+
+func (usageFilter) GraphTypeExtension() GraphTypeInfo {
+	return GraphTypeInfo{Name: "usageFilter", InputOnly: true}
+}
+
+type usageDirectory struct {
+	Accounts []usageAccount
+}
+
+func (d usageDirectory) Find(filter usageFilter) []usageAccount {
+	return d.Accounts
+}
+
+func (d usageDirectory) LastFilter() usageFilter {
+	return usageFilter{}
+}
+
+func TestGraphFunction_TypeUsage(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+
+	// Used the right way around, both are fine.
+	g.RegisterQuery(ctx, "account", func(filter usageFilter) usageAccount {
+		return usageAccount{Name: filter.Prefix}
+	})
+	result, err := g.ProcessRequest(ctx, `{ account(Prefix: "a") { Name Stats { Logins } } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"account":{"Name":"a","Stats":{"Logins":0}}}}`, result)
+
+	assert.PanicsWithValue(t, "function updateStats: parameters: type usageStats is output-only and can't be used as an input", func() {
+		g.RegisterMutation(ctx, "updateStats", func(stats usageStats) bool { return true })
+	})
+	assert.PanicsWithValue(t, "function updateAccount: parameter arg0: field usageAccount.Stats: type usageStats is output-only and can't be used as an input", func() {
+		g.RegisterMutation(ctx, "updateAccount", func(account usageAccount) bool { return true }, "arg0")
+	})
+	assert.PanicsWithValue(t, "function filter: return type: type usageFilter is input-only and can't be used as an output", func() {
+		g.RegisterQuery(ctx, "filter", func() *usageFilter { return nil })
+	})
+	assert.PanicsWithValue(t, "function directory: return type: field usageDirectory.LastFilter: return type: type usageFilter is input-only and can't be used as an output", func() {
+		g.RegisterFunction(ctx, FunctionDefinition{
+			Name:     "directory",
+			Function: func() usageDirectory { return usageDirectory{} },
+			Mode:     ModeQuery,
+		})
+	})
+}
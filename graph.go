@@ -2,10 +2,14 @@ package quickgraph
 
 import (
 	"context"
+	"errors"
+	"github.com/alecthomas/participle/v2/lexer"
 	"github.com/gburgyan/go-timing"
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Graphy is the main entry point for the go-quickgraph library. This holds all the
@@ -22,14 +26,212 @@ import (
 type Graphy struct {
 	RequestCache GraphRequestCache
 
+	// QueryLimits, if set, limits the complexity of the requests that are processed. See
+	// QueryLimits for more information.
+	QueryLimits *QueryLimits
+
 	EnableTiming bool
 
-	processors  map[string]graphFunction
-	typeLookups map[reflect.Type]*typeLookup
-	anyTypes    []*typeLookup
+	// CommandErrorMode determines whether the remaining commands of a request are still
+	// executed after one of them fails. The default is CollectAllErrors.
+	CommandErrorMode CommandErrorMode
+
+	// EnableFieldStats causes the time it takes to resolve each field of each type to be
+	// collected. See FieldStatsSnapshot and LogSlowFields.
+	EnableFieldStats bool
+
+	// Metrics, if set, receives measurements of the requests that are processed and the
+	// fields that are resolved. See MetricsCollector.
+	Metrics MetricsCollector
+
+	// Tracer, if set, starts spans for the requests that are processed and the fields
+	// that are resolved, for distributed tracing. See Tracer.
+	Tracer Tracer
+
+	// OrderedResults causes the fields of the results to be serialized in the order
+	// that they were selected in the request, as recommended by the GraphQL spec. By
+	// default they are serialized in alphabetical order.
+	OrderedResults bool
+
+	// IncludeTypenames adds the __typename of every object to the results, whether or
+	// not it was selected, which is what clients that normalize their caches need. It
+	// can be changed for a request with WithTypenames.
+	IncludeTypenames bool
+
+	// StrictTags causes the registration of types to panic if the tags on a field are
+	// ambiguous as to whether the field should be part of the graph. See the README for
+	// the rules that are used to interpret the tags.
+	StrictTags bool
+
+	// SchemaWarningHandler, if set, is called with each of the problems found when the
+	// schema is generated. See SchemaWarnings. It is called while the schema is being
+	// generated, so it must not register anything with the Graphy.
+	SchemaWarningHandler func(warning SchemaWarning)
+
+	// ConstraintDirectives causes the size limits of input fields, from the maxLength and
+	// maxItems options of their graphy tags, to be added to the schema as @constraint
+	// directives. Client-side form generators that follow the constraint directive
+	// convention can then validate the inputs before they are sent.
+	ConstraintDirectives bool
+
+	// NamedScalars causes named Go types with bool, number, or string underlying types,
+	// such as `type UserID int`, to be represented by custom scalars named after them,
+	// instead of by the built-in scalars of their underlying types. Enums aren't affected,
+	// and RegisterScalar takes precedence for the types it is used for.
+	NamedScalars bool
+
+	// ResultChunkSize is the number of elements of a list that are generated between checks
+	// of whether the request was cancelled. If it is zero, the checks are made every 256
+	// elements.
+	ResultChunkSize int
+
+	// YieldBetweenChunks causes the goroutine that generates a large list to yield to other
+	// goroutines after each chunk of ResultChunkSize elements, so that very large responses
+	// don't delay other requests as much when the processors are busy.
+	YieldBetweenChunks bool
+
+	// StuckResolverTimeout, if set, enables a watchdog for functions that ignore the
+	// cancellation of their context. If a function is still running this long after the
+	// context of its request was cancelled, StuckResolverHandler is called with its name
+	// and stack. Each call uses an additional goroutine while this is enabled.
+	StuckResolverTimeout time.Duration
+
+	// StuckResolverHandler is called for each function that is found by the watchdog. If
+	// it is nil, the functions are logged. See StuckResolverTimeout.
+	StuckResolverHandler func(stuck StuckResolver)
+
+	// OperationAccess, if set, restricts which queries and mutations each role may call.
+	// See OperationAccess for more information.
+	OperationAccess *OperationAccess
+
+	// UnauthorizedListItems is what happens to the objects in lists that the caller may
+	// not view. See Authorizable.
+	UnauthorizedListItems UnauthorizedListItemPolicy
+
+	// IsolateListElementErrors causes an element of a list that fails, such as when one
+	// of its fields returns an error, to be replaced with null instead of failing the
+	// whole list and the operation that returned it. The error is reported with the path
+	// of the element, such as ["posts", "3", "author"], which keeps feed-style queries
+	// useful when a few of their elements can't be resolved.
+	IsolateListElementErrors bool
+
+	// FeatureGate, if set, is called with the name of each query and mutation before it
+	// is called. If it returns an error, such as ErrFeatureDisabled, the operation isn't
+	// called and the error is reported for it with the FEATURE_DISABLED code. This allows
+	// operations to be put behind feature flags without registering them dynamically.
+	FeatureGate func(ctx context.Context, operation string) error
+
+	// OperationStore keeps the status of the mutations registered with
+	// RegisterAsyncMutation. If it is nil when the first of them is registered, a
+	// MemoryOperationStore is used.
+	OperationStore OperationStore
+
+	// DetachedContext, if set, saves and restores the values of the context that the
+	// operations detached with Detach need. See DetachedContext.
+	DetachedContext *DetachedContext
+
+	// TestingHooks, if set, make the values that change from run to run predictable. See
+	// TestingHooks for more information.
+	TestingHooks *TestingHooks
+
+	// ExecutionTraceSink, if set, receives the traces of the requests that are processed
+	// with WithExecutionTrace, instead of them being added to the extensions of the
+	// responses. It is called before the response is returned.
+	ExecutionTraceSink func(ctx context.Context, trace *ExecutionTrace)
+
+	// DevelopmentMode adds details to the errors that are useful to the developers of the
+	// service but not to its clients, such as the Go types of the variables that couldn't
+	// be parsed. It shouldn't be enabled in production.
+	DevelopmentMode bool
+
+	// ErrorPolicy, if set, decides which details of the errors in the responses are sent
+	// to the callers, depending on how far they are trusted. See ErrorPolicy.
+	ErrorPolicy *ErrorPolicy
+
+	// TrustedDocuments, if set, are the documents that can be processed by their IDs with
+	// ProcessTrustedDocument. See LoadTrustedDocuments.
+	TrustedDocuments TrustedDocumentStore
+
+	// TrustedDocumentsOnly causes ProcessRequest to reject all requests, so that only the
+	// TrustedDocuments can be processed. This is known as safelisting.
+	TrustedDocumentsOnly bool
+
+	// MaxExecDepth is how deeply operations run with Exec may be nested. If it is zero,
+	// DefaultMaxExecDepth is used.
+	MaxExecDepth int
+
+	// RequestValidators are custom checks that each request must pass, in addition to
+	// the built-in validation. See RequestValidator.
+	RequestValidators []RequestValidator
+
+	// UnusedVariables is how strictly the variables of requests are checked against the
+	// variables that they declare and use. See VariableCheck.
+	UnusedVariables VariableCheck
+
+	// BatchLoaderWait is how long Load waits for other keys to load in the same batch. If
+	// it is zero, a millisecond is used. See RegisterBatchLoader.
+	BatchLoaderWait time.Duration
+
+	// UnknownQueryFields and UnknownMutationFields are how fields that queries and
+	// mutations select on objects, but that the objects don't have, are handled. See
+	// UnknownFieldHandling.
+	UnknownQueryFields    UnknownFieldHandling
+	UnknownMutationFields UnknownFieldHandling
+
+	// DocumentRewriters change each request after it is parsed and before it is
+	// validated. See DocumentRewriter.
+	DocumentRewriters []DocumentRewriter
+
+	processors      map[string]graphFunction
+	typeLookups     map[reflect.Type]*typeLookup
+	anyTypes        []*typeLookup
+	registeredTypes []*typeLookup
+
+	// typeFields are the fields added to types with RegisterTypeField, keyed by the
+	// non-pointer type.
+	typeFields map[reflect.Type][]FunctionDefinition
+
+	// typeAdapters are the adapters registered with RegisterTypeAdapter, keyed by the
+	// non-pointer type.
+	typeAdapters map[reflect.Type]TypeAdapter
+
+	// scalars are the names of the scalars registered with RegisterScalar, keyed by the
+	// non-pointer type.
+	scalars map[reflect.Type]string
+
+	// parameterUnmarshalers are the unmarshalers registered with
+	// RegisterParameterUnmarshaler, keyed by the type that they return.
+	parameterUnmarshalers map[reflect.Type]*parameterUnmarshaler
+
+	// batchFields are the fields registered with RegisterBatchField, keyed by the
+	// non-pointer type and the name of the field.
+	batchFields map[reflect.Type]map[string]*batchField
+
+	// batchLoaders are the loaders registered with RegisterBatchLoader.
+	batchLoaders map[batchLoaderKey]*batchLoader
+
+	// dynamicEnums are the enums registered with RegisterDynamicEnum, keyed by name.
+	dynamicEnums map[string]*dynamicEnum
+
+	// outputDirectives are the directives registered with RegisterOutputDirective, keyed
+	// by name.
+	outputDirectives map[string]*outputDirective
+
+	// directives are the directives registered with RegisterDirective that decide whether
+	// fields are resolved, keyed by name.
+	directives map[string]*executableDirective
 
 	schemaEnabled bool
-	schemaBuffer  *schemaTypes
+
+	// schemaBuffer holds the schema types once they are built. They are never changed
+	// after that; changes to the structure build new ones for the next schemaGeneration.
+	schemaBuffer     atomic.Pointer[schemaTypes]
+	schemaGeneration atomic.Uint64
+
+	fieldStats fieldStatsCollector
+
+	// stats are the statistics of the __stats query, if EnableStats was called.
+	stats *statsCollector
 
 	// typeMutex is used to ensure that nothing strange happens when multiple threads
 	// are trying to add to the typeLookups map at the same time.
@@ -42,8 +244,30 @@ type Graphy struct {
 	// schemaLock ensures that there is only a single schema-generation request in
 	// progress at a time.
 	schemaLock sync.Mutex
+
+	// typeResolvers are the types that are registered lazily, keyed by type name.
+	// Entries are removed once their resolver has run. typeResolverLock guards the map.
+	typeResolvers    map[string]*lazyType
+	typeResolverLock sync.Mutex
 }
 
+// CommandErrorMode determines what happens to the other commands of a request when
+// one of them returns an error.
+type CommandErrorMode int
+
+const (
+	// CollectAllErrors executes all the commands of a request, even if some of them
+	// fail. The response has the results of the commands that succeeded as well as the
+	// errors of the ones that failed.
+	CollectAllErrors CommandErrorMode = iota
+
+	// FailFast stops the execution of a request as soon as one of its commands fails.
+	// Commands that are still running are abandoned and their context is cancelled, and
+	// commands that haven't started yet are not executed. The response has the results
+	// of the commands that had already completed along with the error.
+	FailFast
+)
+
 type GraphTypeExtension interface {
 	GraphTypeExtension() GraphTypeInfo
 }
@@ -58,16 +282,38 @@ type GraphTypeInfo struct {
 	// Deprecated is the deprecation status of the type.
 	Deprecated string
 
+	// OutputOnly marks a type that must never be used as an input, for instance because
+	// it has fields that are computed by the server. InputOnly is the reverse. Registering
+	// a function that uses the type the wrong way, directly or through the fields of
+	// another type, panics.
+	OutputOnly bool
+	InputOnly  bool
+
 	// Function overrides for the type.
 	FunctionDefinitions []FunctionDefinition
 }
 
+// graphTypeInfo calls GraphTypeExtension on a zero value of a type that implements
+// GraphTypeExtension. For pointer types the pointer refers to a zero value rather than
+// being nil, so that the method can have a value receiver.
+func graphTypeInfo(typ reflect.Type) GraphTypeInfo {
+	var gtev reflect.Value
+	if typ.Kind() == reflect.Ptr {
+		gtev = reflect.New(typ.Elem())
+	} else {
+		gtev = reflect.New(typ).Elem()
+	}
+	return gtev.Interface().(GraphTypeExtension).GraphTypeExtension()
+}
+
 var ignoredFunctions = map[string]bool{
 	"GraphTypeExtension": true,
+	"CanView":            true,
 }
 
 var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
 var errorType = reflect.TypeOf((*error)(nil)).Elem()
+var errorSliceType = reflect.TypeOf([]error(nil))
 var stringType = reflect.TypeOf((*string)(nil)).Elem()
 var anyType = reflect.TypeOf((*any)(nil)).Elem()
 var graphTypeExtensionType = reflect.TypeOf((*GraphTypeExtension)(nil)).Elem()
@@ -83,14 +329,12 @@ var graphTypeExtensionType = reflect.TypeOf((*GraphTypeExtension)(nil)).Elem()
 // parameters or as a single parameter that is a struct. If the function has a single parameter
 // that is a struct, then the names of the struct fields are used as the parameter names.
 func (g *Graphy) RegisterQuery(ctx context.Context, name string, f any, names ...string) {
-	g.ensureInitialized()
-	gf := g.newGraphFunction(FunctionDefinition{
+	g.RegisterFunction(ctx, FunctionDefinition{
 		Name:           name,
 		Function:       f,
 		ParameterNames: names,
 		Mode:           ModeQuery,
-	}, false)
-	g.processors[name] = gf
+	})
 }
 
 // RegisterMutation registers a function as a mutator.
@@ -104,14 +348,12 @@ func (g *Graphy) RegisterQuery(ctx context.Context, name string, f any, names ..
 // parameters or as a single parameter that is a struct. If the function has a single parameter
 // that is a struct, then the names of the struct fields are used as the parameter names.
 func (g *Graphy) RegisterMutation(ctx context.Context, name string, f any, names ...string) {
-	g.ensureInitialized()
-	gf := g.newGraphFunction(FunctionDefinition{
+	g.RegisterFunction(ctx, FunctionDefinition{
 		Name:           name,
 		Function:       f,
 		ParameterNames: names,
 		Mode:           ModeMutation,
-	}, false)
-	g.processors[name] = gf
+	})
 }
 
 // RegisterFunction is similar to both RegisterQuery and RegisterMutation, but it allows
@@ -123,9 +365,10 @@ func (g *Graphy) RegisterFunction(ctx context.Context, def FunctionDefinition) {
 
 	g.ensureInitialized()
 	gf := g.newGraphFunction(def, false)
+	g.validateTypeUsage(&gf)
 	g.processors[def.Name] = gf
 
-	g.schemaBuffer = nil
+	g.invalidateSchema()
 }
 
 // RegisterAnyType registers a type that is potentially used as a return type for a function
@@ -142,7 +385,7 @@ func (g *Graphy) RegisterAnyType(ctx context.Context, types ...any) {
 		g.anyTypes = append(g.anyTypes, tl)
 	}
 
-	g.schemaBuffer = nil
+	g.invalidateSchema()
 }
 
 // RegisterTypes is a method on the Graphy struct that registers types that implement interfaces.
@@ -162,10 +405,11 @@ func (g *Graphy) RegisterTypes(ctx context.Context, types ...any) {
 	defer g.structureLock.Unlock()
 
 	for _, t := range types {
-		g.typeLookup(reflect.TypeOf(t))
+		tl := g.typeLookup(reflect.TypeOf(t))
+		g.registeredTypes = append(g.registeredTypes, tl)
 	}
 
-	g.schemaBuffer = nil
+	g.invalidateSchema()
 }
 
 func (g *Graphy) ensureInitialized() {
@@ -175,8 +419,65 @@ func (g *Graphy) ensureInitialized() {
 }
 
 func (g *Graphy) ProcessRequest(ctx context.Context, request string, variableJson string) (string, error) {
+	if g.TrustedDocumentsOnly {
+		gErr := NewGraphError("only trusted documents are accepted", lexer.Position{})
+		gErr.AddExtension("code", ErrorCodeTrustedDocumentRequired)
+		return g.formatRequestError(ctx, gErr), gErr
+	}
+	return g.processRequestWithTypes(ctx, request, variableJson)
+}
+
+// processRequestWithTypes processes a request, resolving the lazily registered types that
+// it needs along the way.
+func (g *Graphy) processRequestWithTypes(ctx context.Context, request string, variableJson string) (string, error) {
+	for {
+		result, err := g.processRequest(ctx, request, variableJson)
+		var pending pendingTypesError
+		if !errors.As(err, &pending) {
+			return result, err
+		}
+		// The request refers to types that are registered lazily. Resolve them, which
+		// needs to happen outside the structure lock, and try again.
+		err = g.runTypeResolvers(ctx, pending.typeNames)
+		if err != nil {
+			return g.formatRequestError(ctx, err), err
+		}
+	}
+}
+
+func (g *Graphy) processRequest(ctx context.Context, request string, variableJson string) (string, error) {
 	g.structureLock.RLock()
 	defer g.structureLock.RUnlock()
+	return g.processRequestLocked(ctx, request, variableJson)
+}
+
+// processRequestLocked processes a request while the caller holds the structure lock.
+func (g *Graphy) processRequestLocked(ctx context.Context, request string, variableJson string) (result string, err error) {
+	var rs *RequestStub
+	completeStats := g.startRequestStats()
+	defer func() {
+		completeStats(err)
+	}()
+	ctx, endSpan := g.startOperationSpan(ctx, request)
+	defer func() {
+		endSpan(rs, err)
+	}()
+	if g.Metrics != nil {
+		start := g.now()
+		defer func() {
+			var pending pendingTypesError
+			if errors.As(err, &pending) {
+				// The request is processed again once the types are resolved.
+				return
+			}
+			metrics := RequestMetrics{Duration: g.now().Sub(start), Err: err}
+			if rs != nil {
+				metrics.Operation = rs.Name()
+				metrics.Mutation = rs.mode == RequestMutation
+			}
+			g.Metrics.RequestCompleted(ctx, metrics)
+		}()
+	}
 
 	var tCtx context.Context
 	var timingContext *timing.Context
@@ -189,18 +490,54 @@ func (g *Graphy) ProcessRequest(ctx context.Context, request string, variableJso
 		tCtx = ctx
 	}
 
-	rs, err := g.getRequestStub(tCtx, request)
+	rs, err = g.getRequestStub(tCtx, request)
 	if err != nil {
-		return formatError(err), err
+		return g.formatRequestError(ctx, err), err
 	}
 
 	if timingContext != nil {
 		timingContext.AddDetails("request", rs.Name())
 	}
 
+	// Access is checked for each request since the stub may be shared by callers with
+	// different roles.
+	err = g.checkOperationAccess(ctx, rs)
+	if err != nil {
+		return g.formatRequestError(ctx, err), err
+	}
+
+	// Nested operations run with Exec are part of the HTTP request of the outer one, which
+	// has already been accounted for.
+	if resp, ok := ctx.Value(httpResponseContextKey).(*httpResponse); ok && execDepth(ctx) == 0 {
+		resp.mu.Lock()
+		resp.operationName = rs.Name()
+		resp.mutation = rs.mode == RequestMutation
+		resp.deprecations = rs.deprecatedOperations()
+		resp.mu.Unlock()
+		if resp.admitRequest != nil {
+			err = resp.admitRequest(rs)
+			if err != nil {
+				return g.formatRequestError(ctx, err), err
+			}
+		}
+	}
+
 	newRequest, err := rs.newRequest(tCtx, variableJson)
 	if err != nil {
-		return formatError(err), err
+		return g.formatRequestError(ctx, err), err
+	}
+	newRequest.document = request
+	newRequest.typenames = g.includeTypenames(ctx)
+	if IsExecutionTraced(ctx) && execDepth(ctx) == 0 {
+		newRequest.trace = newRequest.newExecutionTrace()
+	}
+	if sender, ok := ctx.Value(incrementalContextKey).(*incrementalSender); ok && execDepth(ctx) == 0 {
+		newRequest.incremental = &incrementalDelivery{graphy: g, sender: sender}
+	}
+	if resp, ok := ctx.Value(httpResponseContextKey).(*httpResponse); ok && execDepth(ctx) == 0 {
+		resp.mu.Lock()
+		resp.executed = true
+		resp.mu.Unlock()
 	}
 
 	return newRequest.execute(tCtx)
@@ -237,15 +574,23 @@ func (g *Graphy) typeLookup(typ reflect.Type) *typeLookup {
 	if rootTyp.Kind() == reflect.Slice {
 		rootTyp = rootTyp.Elem()
 		rootTyp, result.array = g.dereferenceSlice(rootTyp)
+	} else if elemTyp, ok := iteratorElemType(rootTyp); ok {
+		// Iterators are represented the same way as slices of their elements.
+		rootTyp, result.array = g.dereferenceSlice(elemTyp)
 	}
 
 	result.rootType = rootTyp
 
-	if typ.Implements(graphTypeExtensionType) {
-		gtev := reflect.New(typ)
-		gtei := gtev.Elem().Interface().(GraphTypeExtension)
-		typeExtension := gtei.GraphTypeExtension()
+	extensionTyp := typ
+	if result.array != nil && !typ.Implements(graphTypeExtensionType) {
+		// Lists are named after their elements.
+		extensionTyp = reflect.PointerTo(rootTyp)
+	}
+	if extensionTyp.Implements(graphTypeExtensionType) {
+		typeExtension := graphTypeInfo(extensionTyp)
 		result.name = typeExtension.Name
+		result.outputOnly = typeExtension.OutputOnly
+		result.inputOnly = typeExtension.InputOnly
 		if typeExtension.Deprecated != "" {
 			result.isDeprecated = true
 			result.deprecatedReason = typeExtension.Deprecated
@@ -256,8 +601,11 @@ func (g *Graphy) typeLookup(typ reflect.Type) *typeLookup {
 	} else {
 		result.name = rootTyp.Name()
 	}
+	if adapter, ok := g.typeAdapters[rootTyp]; ok {
+		adapter.applyToType(result)
+	}
 
-	if rootTyp.Kind() == reflect.Struct {
+	if rootTyp.Kind() == reflect.Struct && rootTyp != timeType {
 		g.typeMutex.Unlock()
 		g.populateTypeLookup(rootTyp, nil, result)
 		g.typeMutex.Lock()
@@ -318,10 +666,14 @@ func (g *Graphy) getRequestStub(ctx context.Context, request string) (*RequestSt
 		if timingContext != nil {
 			timingContext.AddDetails("cache", "none")
 		}
-		return g.newRequestStub(request)
+		return g.newRequestStub(tCtx, request)
 	}
 
 	stub, err := g.RequestCache.GetRequestStub(tCtx, request)
+	if g.Metrics != nil {
+		g.Metrics.RequestCacheLookup(stub != nil || err != nil)
+	}
+	g.recordCacheLookupStats(stub != nil || err != nil)
 	if stub != nil || err != nil {
 		if timingContext != nil {
 			timingContext.AddDetails("cache", "hit")
@@ -333,7 +685,11 @@ func (g *Graphy) getRequestStub(ctx context.Context, request string) (*RequestSt
 		timingContext.AddDetails("cache", "miss")
 	}
 
-	stub, err = g.newRequestStub(request)
+	stub, err = g.newRequestStub(tCtx, request)
+	if _, pending := err.(pendingTypesError); pending {
+		// The request will be parsed again once the types are resolved.
+		return stub, err
+	}
 	g.RequestCache.SetRequestStub(tCtx, request, stub, err)
 	return stub, err
 }
@@ -1,28 +1,189 @@
 package quickgraph
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"github.com/gburgyan/go-timing"
 	"log"
 	"net/http"
+	"strings"
+	"sync"
 )
 
+// DryRunHeader is the HTTP header that, when set to "true", causes the request to be
+// processed in dry-run mode. See WithDryRun for details.
+const DryRunHeader = "X-GraphQL-Dry-Run"
+
+// GraphHttpHandler serves GraphQL requests over HTTP. Use NewGraphHttpHandler to create
+// one.
 type GraphHttpHandler struct {
 	graphy *Graphy
+
+	// EnableETags causes the handler to add a strong ETag to successful query responses
+	// and to the schema. If the request has an If-None-Match header that matches the
+	// ETag, a 304 Not Modified is returned without a body. Mutations never get an ETag.
+	EnableETags bool
+
+	// ETagFilter, if set, is called with the operation name of each successful query to
+	// decide whether it gets an ETag. This allows ETags to be limited to the operations
+	// that are actually polled. If it is nil, all queries get an ETag.
+	ETagFilter func(operationName string) bool
+
+	// FormatContextExtractor, if set, is called for each request to determine how the
+	// values in the response should be formatted for the client, such as the time zone
+	// that times are rendered in. FormatContextFromHeaders is a ready-made extractor.
+	FormatContextExtractor func(request *http.Request) FormatContext
+
+	// Throttle, if set, limits the rate of the requests from each client. See Throttle.
+	Throttle *Throttle
+
+	// Compression, if set, compresses responses for clients that accept it. See
+	// Compression.
+	Compression *Compression
+
+	// StatusMapper, if set, is called for the requests that fail with the error and the
+	// status of the response, which is 200 unless a function set another one with
+	// SetHTTPStatus. It returns the status to use instead, for instance to report requests
+	// that can't be parsed as 400 Bad Request. StatusForErrorCodes makes one from a map of
+	// error codes to statuses.
+	StatusMapper func(err error, status int) int
+
+	// DeprecationHeaders causes the handler to add a `Deprecation: true` header to the
+	// responses to requests that use deprecated queries or mutations, and a Sunset header
+	// (RFC 8594) with the earliest of their sunsets, if they have any. Headers that the
+	// functions set themselves are kept.
+	DeprecationHeaders bool
+
+	// CSRFPrevention, if set, blocks requests that browsers send from other sites without
+	// asking the server first. Endpoints that authenticate with cookies should set it. See
+	// CSRFPrevention.
+	CSRFPrevention *CSRFPrevention
 }
 
+// HttpHandler returns an http.Handler that serves GraphQL requests. Use
+// NewGraphHttpHandler to get a handler whose options can be set.
 func (g *Graphy) HttpHandler() http.Handler {
+	return NewGraphHttpHandler(g)
+}
+
+// NewGraphHttpHandler creates a GraphHttpHandler for the Graphy instance, whose options
+// can be set before it is used.
+func NewGraphHttpHandler(g *Graphy) *GraphHttpHandler {
 	return &GraphHttpHandler{
 		graphy: g,
 	}
 }
 
+// httpResponse collects the HTTP status and headers that functions set while a request
+// is processed by the HTTP handler. Functions may run concurrently, so access is guarded
+// by the mutex.
+type httpResponse struct {
+	mu     sync.Mutex
+	status int
+	header http.Header
+
+	// The operation that was processed, recorded by ProcessRequest.
+	operationName string
+	mutation      bool
+	deprecations  []deprecatedOperation
+
+	// executed is set by ProcessRequest once the request is executed. Responses to
+	// requests that weren't, because they couldn't be parsed or validated, have no data.
+	executed bool
+
+	// admitRequest, if set, is called by ProcessRequest once the request is parsed to
+	// decide whether it may be processed, such as by charging its cost to the client's
+	// rate limit budget.
+	admitRequest func(rs *RequestStub) error
+}
+
+// SetHTTPStatus sets the HTTP status code of the response to the request that is being
+// processed, for instance to return a 401 if authentication fails. If multiple functions
+// set the status, the last one wins.
+//
+// This only has an effect when the request is processed by the HTTP handler that is
+// returned from Graphy.HttpHandler; otherwise it does nothing.
+func SetHTTPStatus(ctx context.Context, status int) {
+	if resp, ok := ctx.Value(httpResponseContextKey).(*httpResponse); ok {
+		resp.mu.Lock()
+		defer resp.mu.Unlock()
+		resp.status = status
+	}
+}
+
+// SetHTTPHeader sets a header on the response to the request that is being processed,
+// replacing any values that were set for that header before. This can be used for
+// things like cache control headers.
+//
+// This only has an effect when the request is processed by the HTTP handler that is
+// returned from Graphy.HttpHandler; otherwise it does nothing.
+func SetHTTPHeader(ctx context.Context, key, value string) {
+	if resp, ok := ctx.Value(httpResponseContextKey).(*httpResponse); ok {
+		resp.mu.Lock()
+		defer resp.mu.Unlock()
+		resp.header.Set(key, value)
+	}
+}
+
+// AddHTTPHeader adds a value to a header on the response to the request that is being
+// processed. Unlike SetHTTPHeader, existing values are kept, which is needed for headers
+// like Set-Cookie that can appear multiple times.
+//
+// This only has an effect when the request is processed by the HTTP handler that is
+// returned from Graphy.HttpHandler; otherwise it does nothing.
+func AddHTTPHeader(ctx context.Context, key, value string) {
+	if resp, ok := ctx.Value(httpResponseContextKey).(*httpResponse); ok {
+		resp.mu.Lock()
+		defer resp.mu.Unlock()
+		resp.header.Add(key, value)
+	}
+}
+
 type graphqlRequest struct {
 	Query     string          `json:"query"`
 	Variables json.RawMessage `json:"variables"`
+
+	// OperationName, if set, must be the name of the operation in the query.
+	OperationName string `json:"operationName"`
+
+	// DocumentID is the ID of a trusted document to process instead of the query.
+	DocumentID string `json:"documentId"`
+
+	Extensions struct {
+		// PersistedQuery is how the clients of automatic persisted queries refer to a
+		// trusted document by its hash.
+		PersistedQuery *struct {
+			Sha256Hash string `json:"sha256Hash"`
+		} `json:"persistedQuery"`
+
+		// IncludeTypenames overrides Graphy.IncludeTypenames for the request.
+		IncludeTypenames *bool `json:"includeTypenames"`
+	} `json:"extensions"`
+}
+
+// documentID returns the ID of the trusted document that the request is for, if any.
+func (r graphqlRequest) documentID() string {
+	if r.DocumentID != "" {
+		return r.DocumentID
+	}
+	if pq := r.Extensions.PersistedQuery; pq != nil {
+		return pq.Sha256Hash
+	}
+	return ""
 }
 
 func (g GraphHttpHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "GET" && request.Method != "POST" {
+		writer.Header().Set("Allow", "GET, POST")
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	// A GET without GraphQL parameters is a request for the schema.
+	graphqlGET := request.Method == "GET" && hasGraphQLParams(request.URL.Query())
+
 	ctx := request.Context()
 	var timingContext *timing.Context
 	var complete timing.Complete
@@ -32,14 +193,31 @@ func (g GraphHttpHandler) ServeHTTP(writer http.ResponseWriter, request *http.Re
 		ctx = timingContext
 	}
 
-	if request.Method == "GET" {
+	var ip, apiKey string
+	if g.Throttle != nil {
+		ip, apiKey = g.Throttle.clientKeys(request)
+		if g.Throttle.Banned != nil && g.Throttle.Banned(ip, apiKey) {
+			g.graphy.recordThrottledStats()
+			writeThrottled(writer, http.StatusForbidden, 0)
+			return
+		}
+		// With QueryCost, GraphQL requests are charged the rest of their cost once they
+		// are parsed.
+		if ok, retryAfter := g.Throttle.take(ctx, ip, apiKey, 1); !ok {
+			g.graphy.recordThrottledStats()
+			writeThrottled(writer, http.StatusTooManyRequests, retryAfter)
+			return
+		}
+	}
+
+	if request.Method == "GET" && !graphqlGET {
 		if g.graphy.schemaEnabled {
-			schema := g.graphy.SchemaDefinition(ctx)
-			writer.WriteHeader(200)
-			_, err := writer.Write([]byte(schema))
-			if err != nil {
-				log.Printf("Error writing response: %v", err)
+			schema := []byte(g.graphy.SchemaDefinition(ctx))
+			encoding := g.responseEncoding(writer, request, len(schema))
+			if g.EnableETags && g.notModified(writer, request, schema, encoding) {
+				return
 			}
+			g.writeResponse(writer, 200, schema, encoding)
 		} else {
 			writer.WriteHeader(404)
 			_, err := writer.Write([]byte("Not Found"))
@@ -50,29 +228,177 @@ func (g GraphHttpHandler) ServeHTTP(writer http.ResponseWriter, request *http.Re
 		return
 	}
 
+	mediaType := responseMediaType(request)
+	if mediaType == "" {
+		writer.WriteHeader(http.StatusNotAcceptable)
+		return
+	}
+
+	preflighted := g.CSRFPrevention == nil || g.CSRFPrevention.preflighted(request)
+	if !preflighted && g.CSRFPrevention.AllOperations {
+		writer.Header().Set("Content-Type", mediaType)
+		g.writeResponse(writer, http.StatusBadRequest, []byte(formatError(errCSRFBlocked)), "")
+		return
+	}
+
 	var req graphqlRequest
-	err := json.NewDecoder(request.Body).Decode(&req)
+	var err error
+	if graphqlGET {
+		req, err = graphqlRequestFromParams(request.URL.Query())
+	} else {
+		err = json.NewDecoder(request.Body).Decode(&req)
+	}
 	if err != nil {
 		log.Printf("Error decoding request: %v", err)
+		if contentType := request.Header.Get("Content-Type"); !graphqlGET && contentType != "" && !isJSONMediaType(contentType) {
+			// The body isn't JSON because the client sent something else altogether.
+			writer.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
 		writer.WriteHeader(400)
 		return
 	}
+	if req.Query == "" && req.documentID() == "" {
+		writer.Header().Set("Content-Type", mediaType)
+		g.writeResponse(writer, http.StatusBadRequest, []byte(formatError(errMissingQuery)), "")
+		return
+	}
 
 	query := req.Query
 	variables := string(req.Variables)
 
+	if strings.EqualFold(request.Header.Get(DryRunHeader), "true") {
+		ctx = WithDryRun(ctx)
+	}
+	if g.graphy.DevelopmentMode && strings.EqualFold(request.Header.Get(ExecutionTraceHeader), "true") {
+		ctx = WithExecutionTrace(ctx)
+	}
+	if g.FormatContextExtractor != nil {
+		ctx = WithFormatContext(ctx, g.FormatContextExtractor(request))
+	}
+	if include := req.Extensions.IncludeTypenames; include != nil {
+		ctx = WithTypenames(ctx, *include)
+	}
+
+	resp := &httpResponse{
+		status: http.StatusOK, // Errors are in the response body, and there may be mixed errors and results.
+		header: http.Header{},
+	}
+	ctx = context.WithValue(ctx, httpResponseContextKey, resp)
+	ctx, cacheHint := WithCacheHints(ctx)
+	chargeCost := g.Throttle != nil && g.Throttle.QueryCost
+	if chargeCost || !preflighted || graphqlGET || req.OperationName != "" {
+		throttle := g.Throttle
+		resp.admitRequest = func(rs *RequestStub) error {
+			if graphqlGET && rs.mode == RequestMutation {
+				SetHTTPStatus(ctx, http.StatusMethodNotAllowed)
+				SetHTTPHeader(ctx, "Allow", "POST")
+				return errMutationOverGET
+			}
+			if !preflighted && rs.mode == RequestMutation {
+				SetHTTPStatus(ctx, http.StatusBadRequest)
+				return errCSRFBlocked
+			}
+			// An anonymous operation has no name to match, so any name is wrong for it.
+			if op := rs.parsedCall.OperationDef; req.OperationName != "" && (op == nil || op.Name != req.OperationName) {
+				pos := rs.parsedCall.Pos
+				if op != nil {
+					pos = op.Pos
+				}
+				return NewGraphError(fmt.Sprintf("the request has no operation named %s", req.OperationName), pos)
+			}
+			if !chargeCost {
+				return nil
+			}
+			budget, ok := throttle.maxCost(apiKey)
+			if !ok {
+				return nil
+			}
+			cost, over := g.graphy.estimateResponseSize(rs, budget+1)
+			if over != nil {
+				return responseSizeError(budget, *over)
+			}
+			// One unit was charged when the request was received.
+			if cost <= 1 {
+				return nil
+			}
+			if ok, retryAfter := throttle.take(ctx, ip, apiKey, cost-1); !ok {
+				g.graphy.recordThrottledStats()
+				SetHTTPStatus(ctx, http.StatusTooManyRequests)
+				SetHTTPHeader(ctx, "Retry-After", retryAfterHeader(retryAfter))
+				return errRateLimited
+			}
+			return nil
+		}
+	}
+
 	// Process the request.
-	res, err := g.graphy.ProcessRequest(ctx, query, variables)
+	process := func(ctx context.Context) (string, error) {
+		if id := req.documentID(); id != "" {
+			return g.graphy.ProcessTrustedDocument(ctx, id, variables)
+		}
+		return g.graphy.ProcessRequest(ctx, query, variables)
+	}
+	var res string
+	if acceptsMultipart(request) {
+		// The result is only sent as multipart/mixed if it has incremental payloads.
+		var parts *multipartWriter
+		err = g.graphy.processIncremental(ctx, func(payload string, hasNext bool) error {
+			if parts == nil {
+				if !hasNext {
+					res = payload
+					return nil
+				}
+				parts = startMultipart(writer, resp)
+			}
+			return parts.write(payload, hasNext)
+		}, process)
+		if parts != nil {
+			if err != nil {
+				log.Printf("Error processing request: %v", err)
+			}
+			if g.graphy.EnableTiming {
+				complete()
+				log.Printf("Timing: %v", timingContext.String())
+			}
+			return
+		}
+	} else {
+		res, err = process(ctx)
+	}
 	if err != nil {
 		log.Printf("Error processing request: %v (will still return response)", err)
 	}
 
-	// Return the response string.
-	writer.Header().Set("Content-Type", "application/json")
-	writer.WriteHeader(200) // Errors are in the response body, and there may be mixed errors and results.
-	_, err = writer.Write([]byte(res))
-	if err != nil {
-		log.Printf("Error writing response: %v", err)
+	// Return the response string along with anything the functions added.
+	resp.mu.Lock()
+	for key, values := range resp.header {
+		writer.Header()[key] = values
+	}
+	status := resp.status
+	if err != nil && mediaType == mediaTypeGraphQLResponse && status == http.StatusOK && !resp.executed {
+		// Only application/json responses report the requests that couldn't be executed
+		// with a 200, since older clients expect that.
+		status = http.StatusBadRequest
+	}
+	if err != nil && g.StatusMapper != nil {
+		status = g.StatusMapper(err, status)
+	}
+	cacheable := err == nil && !resp.mutation && status == http.StatusOK
+	operationName := resp.operationName
+	if g.DeprecationHeaders {
+		setDeprecationHeaders(writer.Header(), resp.deprecations)
+	}
+	resp.mu.Unlock()
+	writer.Header().Set("Content-Type", mediaType)
+	if hint, ok := cacheHint(); ok && cacheable && writer.Header().Get("Cache-Control") == "" {
+		writer.Header().Set("Cache-Control", hint.HeaderValue())
+	}
+	useETag := g.EnableETags && cacheable && (g.ETagFilter == nil || g.ETagFilter(operationName))
+	body := []byte(res)
+	encoding := g.responseEncoding(writer, request, len(body))
+	if !useETag || !g.notModified(writer, request, body, encoding) {
+		g.writeResponse(writer, status, body, encoding)
 	}
 
 	if g.graphy.EnableTiming {
@@ -80,3 +406,84 @@ func (g GraphHttpHandler) ServeHTTP(writer http.ResponseWriter, request *http.Re
 		log.Printf("Timing: %v", timingContext.String())
 	}
 }
+
+// acceptsMultipart reports whether the client accepts the incremental payloads of @defer
+// and @stream as a multipart/mixed response.
+func acceptsMultipart(request *http.Request) bool {
+	for _, accept := range request.Header.Values("Accept") {
+		if strings.Contains(accept, "multipart/mixed") {
+			return true
+		}
+	}
+	return false
+}
+
+// multipartWriter writes the payloads of a request that is delivered incrementally as the
+// parts of a multipart/mixed response, in the format that Apollo Client and graphql-helix
+// use.
+type multipartWriter struct {
+	writer  http.ResponseWriter
+	flusher http.Flusher
+}
+
+// startMultipart writes the status and headers of a multipart/mixed response, along with
+// the headers that the functions added.
+func startMultipart(writer http.ResponseWriter, resp *httpResponse) *multipartWriter {
+	resp.mu.Lock()
+	for key, values := range resp.header {
+		writer.Header()[key] = values
+	}
+	status := resp.status
+	resp.mu.Unlock()
+	writer.Header().Set("Content-Type", `multipart/mixed; boundary="-"; deferSpec=20220824`)
+	writer.Header().Del("Content-Length")
+	writer.WriteHeader(status)
+
+	flusher, _ := writer.(http.Flusher)
+	mw := &multipartWriter{writer: writer, flusher: flusher}
+	mw.writeString("\r\n---")
+	return mw
+}
+
+// write writes a payload as a part of the response, and closes the response after the
+// last one. Each part is flushed so that the client gets it right away.
+func (mw *multipartWriter) write(payload string, hasNext bool) error {
+	part := "\r\nContent-Type: application/json; charset=utf-8\r\n\r\n" + payload + "\r\n---"
+	if !hasNext {
+		part += "--\r\n"
+	}
+	return mw.writeString(part)
+}
+
+func (mw *multipartWriter) writeString(s string) error {
+	_, err := mw.writer.Write([]byte(s))
+	if err == nil && mw.flusher != nil {
+		mw.flusher.Flush()
+	}
+	return err
+}
+
+// notModified sets the ETag header for the body and reports whether the request's
+// If-None-Match header matches it. If it does, a 304 Not Modified has been written and
+// the body must not be sent. Compressed responses are a different representation of the
+// body, so the encoding is part of their ETag.
+func (g GraphHttpHandler) notModified(writer http.ResponseWriter, request *http.Request, body []byte, encoding string) bool {
+	sum := sha256.Sum256(body)
+	tag := hex.EncodeToString(sum[:])
+	if encoding != "" {
+		tag += "-" + encoding
+	}
+	etag := `"` + tag + `"`
+	writer.Header().Set("ETag", etag)
+
+	for _, candidate := range strings.Split(request.Header.Get("If-None-Match"), ",") {
+		candidate = strings.TrimSpace(candidate)
+		// If-None-Match uses the weak comparison, so a weak validator still matches.
+		candidate = strings.TrimPrefix(candidate, "W/")
+		if candidate == "*" || candidate == etag {
+			writer.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package quickgraph
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// The media types of the responses of the HTTP handler. The GraphQL over HTTP
+// specification introduces application/graphql-response+json so that clients can tell
+// GraphQL responses from the error pages of proxies. Responses of that type use the
+// status code to report requests that fail before they can be executed.
+const (
+	mediaTypeJSON            = "application/json"
+	mediaTypeGraphQLResponse = "application/graphql-response+json"
+)
+
+// errMissingQuery is reported for requests that have neither a query nor a trusted
+// document.
+var errMissingQuery = GraphError{Message: "the request has no query"}
+
+// errMutationOverGET is reported for mutations sent with GET, which must not change
+// anything.
+var errMutationOverGET = GraphError{Message: "mutations can only be sent with POST"}
+
+// hasGraphQLParams reports whether a GET request is a GraphQL request rather than a
+// request for the schema.
+func hasGraphQLParams(params url.Values) bool {
+	return params.Has("query") || params.Has("documentId") || params.Has("extensions")
+}
+
+// graphqlRequestFromParams reads a GraphQL request from the parameters of the URL of a
+// GET request. The variables and extensions are JSON encoded, as in a POST body.
+func graphqlRequestFromParams(params url.Values) (graphqlRequest, error) {
+	req := graphqlRequest{
+		Query:         params.Get("query"),
+		OperationName: params.Get("operationName"),
+		DocumentID:    params.Get("documentId"),
+	}
+	if variables := params.Get("variables"); variables != "" {
+		req.Variables = json.RawMessage(variables)
+	}
+	if extensions := params.Get("extensions"); extensions != "" {
+		err := json.Unmarshal([]byte(extensions), &req.Extensions)
+		if err != nil {
+			return req, fmt.Errorf("error parsing extensions: %w", err)
+		}
+	}
+	return req, nil
+}
+
+// isJSONMediaType reports whether the Content-Type of a request is JSON, including
+// types like application/graphql+json that are JSON with a suffix.
+func isJSONMediaType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	mediaType = strings.ToLower(mediaType)
+	return mediaType == mediaTypeJSON || strings.HasSuffix(mediaType, "+json")
+}
+
+// responseMediaType chooses the media type of the response from the Accept header of
+// the request, preferring application/graphql-response+json on a tie. Without an Accept
+// header, or with a wildcard, the response is application/json, as that is what older
+// clients expect. It returns "" if the client accepts neither.
+func responseMediaType(request *http.Request) string {
+	accepts := request.Header.Values("Accept")
+	if len(accepts) == 0 {
+		return mediaTypeJSON
+	}
+	best, bestQuality := "", 0.0
+	for _, accept := range accepts {
+		for _, candidate := range strings.Split(accept, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(candidate))
+			if err != nil {
+				continue
+			}
+			quality := 1.0
+			if q, ok := params["q"]; ok {
+				quality, err = strconv.ParseFloat(q, 64)
+				if err != nil {
+					continue
+				}
+			}
+			var offered string
+			switch strings.ToLower(mediaType) {
+			case mediaTypeGraphQLResponse:
+				offered = mediaTypeGraphQLResponse
+			case mediaTypeJSON, "application/*", "*/*", "multipart/mixed":
+				// Wildcards get what clients got before there was a choice, and incremental
+				// responses fall back to a single JSON response.
+				offered = mediaTypeJSON
+			default:
+				continue
+			}
+			if quality > bestQuality || (quality == bestQuality && offered == mediaTypeGraphQLResponse) {
+				best, bestQuality = offered, quality
+			}
+		}
+	}
+	return best
+}
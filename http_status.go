@@ -0,0 +1,62 @@
+package quickgraph
+
+import (
+	"errors"
+)
+
+// ErrorCodeParseFailed is what ErrorCode returns for requests that can't be parsed. Such
+// errors don't have a code in the response.
+const ErrorCodeParseFailed = "GRAPHQL_PARSE_FAILED"
+
+// requestParseError marks the errors from parsing requests for ErrorCode. It has the same
+// message as the error that it holds.
+type requestParseError struct {
+	error
+}
+
+func (e requestParseError) Unwrap() error {
+	return e.error
+}
+
+// ErrorCode returns the code of an error, which is the "code" in its extensions, or
+// ErrorCodeParseFailed if the request couldn't be parsed. It returns "" for errors
+// without a code. For errors that were joined, the code of the first one that has a code
+// is returned.
+func ErrorCode(err error) string {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, inner := range joined.Unwrap() {
+			if code := ErrorCode(inner); code != "" {
+				return code
+			}
+		}
+		return ""
+	}
+	var gErr GraphError
+	if errors.As(err, &gErr) {
+		if code := gErr.Extensions["code"]; code != "" {
+			return code
+		}
+	}
+	var parseErr requestParseError
+	if errors.As(err, &parseErr) {
+		return ErrorCodeParseFailed
+	}
+	return ""
+}
+
+// StatusForErrorCodes returns a StatusMapper for GraphHttpHandler that sets the status of
+// a response by the code of its error, as given by ErrorCode. Errors with codes that
+// aren't in the map keep the status that they have.
+//
+//	h.StatusMapper = quickgraph.StatusForErrorCodes(map[string]int{
+//		quickgraph.ErrorCodeParseFailed:  http.StatusBadRequest,
+//		quickgraph.ErrorCodeUnauthorized: http.StatusForbidden,
+//	})
+func StatusForErrorCodes(statuses map[string]int) func(err error, status int) int {
+	return func(err error, status int) int {
+		if mapped, ok := statuses[ErrorCode(err)]; ok {
+			return mapped
+		}
+		return status
+	}
+}
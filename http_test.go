@@ -9,7 +9,10 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 )
 
 type errorWriter struct {
@@ -225,3 +228,297 @@ func TestGraphHttpHandler_ServeHTTP_PostQuery_Error(t *testing.T) {
 
 	assert.Equal(t, `{"data":{},"errors":[{"message":"function greeting returned error: expected error","locations":[{"line":2,"column":11}],"path":["greeting"]}]}`, string(resBody))
 }
+
+func TestGraphHttpHandler_ServeHTTP_DryRunHeader(t *testing.T) {
+	called := false
+	g := Graphy{}
+	g.RegisterMutation(context.Background(), "greeting", func(ctx context.Context, name string) string {
+		called = true
+		return "Hello, " + name
+	}, "name")
+
+	h := g.HttpHandler()
+
+	body, _ := json.Marshal(graphqlRequest{
+		Query: `mutation { greeting(name: "World") }`,
+	})
+	req := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DryRunHeader, "true")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	resBody, _ := io.ReadAll(rec.Result().Body)
+	assert.Equal(t, `{"data":{"greeting":null}}`, string(resBody))
+	assert.False(t, called)
+}
+
+func TestGraphHttpHandler_ServeHTTP_ResponseStatusAndHeaders(t *testing.T) {
+	g := Graphy{}
+	g.RegisterQuery(context.Background(), "login", func(ctx context.Context, name string) (string, error) {
+		AddHTTPHeader(ctx, "Set-Cookie", "a=1")
+		AddHTTPHeader(ctx, "Set-Cookie", "b=2")
+		SetHTTPHeader(ctx, "Cache-Control", "no-store")
+		if name != "admin" {
+			SetHTTPStatus(ctx, http.StatusUnauthorized)
+			return "", errors.New("not allowed")
+		}
+		return "welcome", nil
+	}, "name")
+
+	h := g.HttpHandler()
+
+	body, _ := json.Marshal(graphqlRequest{Query: `{ login(name: "guest") }`})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/", bytes.NewReader(body)))
+
+	res := rec.Result()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	assert.Equal(t, []string{"a=1", "b=2"}, res.Header.Values("Set-Cookie"))
+	assert.Equal(t, "no-store", res.Header.Get("Cache-Control"))
+	assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+
+	body, _ = json.Marshal(graphqlRequest{Query: `{ login(name: "admin") }`})
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusOK, rec.Result().StatusCode)
+
+	// Outside of the HTTP handler the functions do nothing.
+	_, err := g.ProcessRequest(context.Background(), `{ login(name: "guest") }`, "")
+	assert.Error(t, err)
+}
+
+func TestGraphHttpHandler_ServeHTTP_StatusMapper(t *testing.T) {
+	g := Graphy{}
+	g.RegisterQuery(context.Background(), "greeting", func(name string) string {
+		return "Hello, " + name
+	}, "name")
+	g.RegisterQuery(context.Background(), "fail", func() (string, error) {
+		return "", errors.New("failed")
+	})
+	g.RegisterQuery(context.Background(), "secret", func() string {
+		return "s"
+	})
+	g.OperationAccess = &OperationAccess{Public: []string{"greeting", "fail"}}
+
+	h := NewGraphHttpHandler(&g)
+	h.StatusMapper = StatusForErrorCodes(map[string]int{
+		ErrorCodeParseFailed:  http.StatusBadRequest,
+		ErrorCodeUnauthorized: http.StatusForbidden,
+	})
+
+	tests := []struct {
+		query  string
+		status int
+	}{
+		{`{ greeting(name: "Ada") }`, http.StatusOK},
+		{`{ greeting(name: "Ada") `, http.StatusBadRequest},
+		{`{ secret }`, http.StatusForbidden},
+		{`{ fail }`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		body, _ := json.Marshal(graphqlRequest{Query: tt.query})
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("POST", "/", bytes.NewReader(body)))
+		assert.Equal(t, tt.status, rec.Result().StatusCode, tt.query)
+	}
+}
+
+func TestGraphHttpHandler_ServeHTTP_ETag(t *testing.T) {
+	calls := 0
+	g := Graphy{}
+	g.RegisterQuery(context.Background(), "greeting", func(ctx context.Context, name string) string {
+		calls++
+		return "Hello, " + name
+	}, "name")
+	g.RegisterMutation(context.Background(), "rename", func(ctx context.Context, name string) string {
+		return name
+	}, "name")
+
+	h := NewGraphHttpHandler(&g)
+	h.EnableETags = true
+
+	post := func(query, ifNoneMatch string) *http.Response {
+		body, _ := json.Marshal(graphqlRequest{Query: query})
+		req := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Result()
+	}
+
+	res := post(`query Greet { greeting(name: "World") }`, "")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	etag := res.Header.Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	res = post(`query Greet { greeting(name: "World") }`, `"other", W/`+etag)
+	assert.Equal(t, http.StatusNotModified, res.StatusCode)
+	resBody, _ := io.ReadAll(res.Body)
+	assert.Empty(t, resBody)
+	assert.Equal(t, 2, calls)
+
+	res = post(`query Greet { greeting(name: "Bob") }`, etag)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.NotEqual(t, etag, res.Header.Get("ETag"))
+
+	// Mutations never get an ETag.
+	res = post(`mutation { rename(name: "x") }`, "*")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Empty(t, res.Header.Get("ETag"))
+
+	// The filter limits which operations get an ETag.
+	h.ETagFilter = func(operationName string) bool {
+		return operationName == "Poll"
+	}
+	res = post(`query Greet { greeting(name: "World") }`, etag)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Empty(t, res.Header.Get("ETag"))
+	res = post(`query Poll { greeting(name: "World") }`, "")
+	assert.NotEmpty(t, res.Header.Get("ETag"))
+}
+
+func TestGraphHttpHandler_ServeHTTP_SchemaETag(t *testing.T) {
+	g := Graphy{}
+	g.RegisterQuery(context.Background(), "greeting", func(ctx context.Context, name string) string {
+		return "Hello, " + name
+	}, "name")
+	g.EnableIntrospection(context.Background())
+
+	h := NewGraphHttpHandler(&g)
+	h.EnableETags = true
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	etag := rec.Result().Header.Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Result().StatusCode)
+}
+
+func TestGraphHttpHandler_ServeHTTP_CacheHint(t *testing.T) {
+	g := Graphy{}
+	g.RegisterQuery(context.Background(), "greeting", func(ctx context.Context, name string) string {
+		SetCacheHint(ctx, time.Minute, 10*time.Second)
+		return "Hello, " + name
+	}, "name")
+	g.RegisterQuery(context.Background(), "private", func(ctx context.Context) string {
+		SetCacheHint(ctx, time.Minute, 0)
+		SetHTTPHeader(ctx, "Cache-Control", "no-store")
+		return "secret"
+	})
+	g.RegisterQuery(context.Background(), "unhinted", func() string {
+		return "unhinted"
+	})
+	g.RegisterMutation(context.Background(), "rename", func(ctx context.Context, name string) string {
+		SetCacheHint(ctx, time.Minute, 0)
+		return name
+	}, "name")
+
+	h := g.HttpHandler()
+	post := func(query string) *http.Response {
+		body, _ := json.Marshal(graphqlRequest{Query: query})
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("POST", "/", bytes.NewReader(body)))
+		return rec.Result()
+	}
+
+	assert.Equal(t, "max-age=60, stale-while-revalidate=10", post(`{ greeting(name: "World") }`).Header.Get("Cache-Control"))
+	// A header set explicitly by a function wins.
+	assert.Equal(t, "no-store", post(`{ private }`).Header.Get("Cache-Control"))
+	// Mutations are never cacheable.
+	assert.Empty(t, post(`mutation { rename(name: "x") }`).Header.Get("Cache-Control"))
+	// Neither are queries where a resolver doesn't set a hint.
+	assert.Empty(t, post(`{ greeting(name: "World") unhinted }`).Header.Get("Cache-Control"))
+}
+
+func TestGraphHttpHandler_ServeHTTP_GraphQLOverHTTP(t *testing.T) {
+	g := Graphy{}
+	g.RegisterQuery(context.Background(), "greeting", func(name string) string {
+		return "Hello, " + name
+	}, "name")
+	g.RegisterQuery(context.Background(), "fail", func() (string, error) {
+		return "", errors.New("failed")
+	})
+	g.RegisterMutation(context.Background(), "rename", func(name string) string {
+		return name
+	}, "name")
+	g.EnableIntrospection(context.Background())
+	h := g.HttpHandler()
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	get := func(params url.Values, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/graphql?"+params.Encode(), nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		return serve(req)
+	}
+
+	// Queries can be sent with GET.
+	rec := get(url.Values{
+		"query":         {`query Greet($name: String!) { greeting(name: $name) }`},
+		"variables":     {`{"name": "Ada"}`},
+		"operationName": {"Greet"},
+	}, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `{"data":{"greeting":"Hello, Ada"}}`, rec.Body.String())
+
+	// Mutations can't.
+	rec = get(url.Values{"query": {`mutation { rename(name: "x") }`}}, "")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "POST", rec.Header().Get("Allow"))
+
+	// Without GraphQL parameters, a GET is still for the schema.
+	rec = get(nil, "")
+	assert.Contains(t, rec.Body.String(), "type Query")
+
+	// The status of application/graphql-response+json responses tells request errors from
+	// field errors.
+	accept := "application/graphql-response+json, application/json;q=0.9"
+	rec = get(url.Values{"query": {`{ greeting(name: "Ada") `}}, accept)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "application/graphql-response+json", rec.Header().Get("Content-Type"))
+	rec = get(url.Values{"query": {`{ fail }`}}, accept)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = get(url.Values{"query": {`query Greet($name: String!) { greeting(name: $name) }`}, "variables": {`{"name": 5}`}}, accept)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.NotContains(t, rec.Body.String(), `"data"`)
+	rec = get(url.Values{"query": {`{ greeting(name: "Ada") `}}, "application/json")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = get(url.Values{"query": {`query Greet { greeting(name: "Ada") }`}, "operationName": {"Other"}}, accept)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "the request has no operation named Other")
+	rec = get(url.Values{"query": {`{ greeting(name: "Ada") }`}, "operationName": {"Other"}}, accept)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "the request has no operation named Other")
+	rec = get(url.Values{"query": {`query { greeting(name: "Ada") }`}, "operationName": {"Other"}}, accept)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "the request has no operation named Other")
+	rec = get(url.Values{"query": {`{ fail }`}}, "text/html")
+	assert.Equal(t, http.StatusNotAcceptable, rec.Code)
+
+	rec = serve(httptest.NewRequest("PUT", "/", strings.NewReader(`{"query": "{ fail }"}`)))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "GET, POST", rec.Header().Get("Allow"))
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`query=%7B+fail+%7D`))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	assert.Equal(t, http.StatusUnsupportedMediaType, serve(req).Code)
+
+	rec = serve(httptest.NewRequest("POST", "/", strings.NewReader(`{"variables": {}}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "the request has no query")
+}
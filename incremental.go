@@ -0,0 +1,367 @@
+package quickgraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+const (
+	deferDirectiveName  = "@defer"
+	streamDirectiveName = "@stream"
+)
+
+// deferArgs are the arguments of the @defer directive.
+type deferArgs struct {
+	If    *bool   `json:"if"`
+	Label *string `json:"label"`
+}
+
+// streamArgs are the arguments of the @stream directive.
+type streamArgs struct {
+	If           *bool   `json:"if"`
+	Label        *string `json:"label"`
+	InitialCount *int    `json:"initialCount"`
+}
+
+var (
+	deferArgsType  = reflect.TypeOf(deferArgs{})
+	streamArgsType = reflect.TypeOf(streamArgs{})
+)
+
+// IncrementalSender receives the payloads of a request processed with
+// ProcessRequestIncremental. hasNext is set for all the payloads except the last one. If
+// it returns an error, no more payloads are sent.
+type IncrementalSender func(payload string, hasNext bool) error
+
+// ProcessRequestIncremental processes a request like ProcessRequest, but delivers the
+// fragments marked with @defer and the elements of the lists marked with @stream after the
+// rest of the result:
+//
+//	{ hero { name ... on Human @defer(label: "details") { friends { name } } } }
+//
+// The first payload is the result without them, with "hasNext": true. The ones that
+// follow each have one of them in "incremental", along with its path in the result, as
+// graphql-js does:
+//
+//	{"incremental":[{"data":{"friends":[...]},"path":["hero"],"label":"details"}],"hasNext":false}
+//
+// @stream takes an initialCount of the elements to include in the first payload; the
+// others are sent one at a time. Both directives take an "if" argument to turn them off.
+// A request that doesn't use them, or that fails before it is executed, is sent as a
+// single payload with hasNext unset. ProcessRequest ignores the directives and returns
+// the complete result.
+//
+// The error that is returned is the first one of the first payload, the deferred ones,
+// or the sender.
+func (g *Graphy) ProcessRequestIncremental(ctx context.Context, request string, variableJson string, send IncrementalSender) error {
+	return g.processIncremental(ctx, send, func(ctx context.Context) (string, error) {
+		return g.ProcessRequest(ctx, request, variableJson)
+	})
+}
+
+// processIncremental runs process with a context that makes it deliver the result
+// incrementally with send.
+func (g *Graphy) processIncremental(ctx context.Context, send IncrementalSender, process func(ctx context.Context) (string, error)) error {
+	sender := &incrementalSender{send: send}
+	result, err := process(context.WithValue(ctx, incrementalContextKey, sender))
+	if !sender.started {
+		sendErr := send(result, false)
+		if err == nil {
+			err = sendErr
+		}
+		return err
+	}
+	if err == nil {
+		err = sender.err
+	}
+	return err
+}
+
+// incrementalSender is how a request that is processed with ProcessRequestIncremental
+// sends its payloads.
+type incrementalSender struct {
+	send IncrementalSender
+
+	// started is set once the first payload is sent, which only the request does if it
+	// has incremental payloads.
+	started bool
+
+	// err is the first error of the incremental payloads or of sending them.
+	err error
+}
+
+// incrementalDelivery is the work of a request that is delivered after the first payload.
+type incrementalDelivery struct {
+	graphy *Graphy
+	sender *incrementalSender
+
+	mu      sync.Mutex
+	pending []incrementalWork
+}
+
+// incrementalWork produces the entry of a payload, which is resolved with the context of
+// the request.
+type incrementalWork func(ctx context.Context) map[string]any
+
+func (d *incrementalDelivery) add(work incrementalWork) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pending = append(d.pending, work)
+}
+
+// next returns the next piece of work, or nil if there is none.
+func (d *incrementalDelivery) next() incrementalWork {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.pending) == 0 {
+		return nil
+	}
+	work := d.pending[0]
+	d.pending = d.pending[1:]
+	return work
+}
+
+func (d *incrementalDelivery) hasPending() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.pending) > 0
+}
+
+// deliver sends the first payload and then resolves and sends the others, in the order
+// that they were deferred. The work for one payload can defer more.
+func (d *incrementalDelivery) deliver(ctx context.Context, initial string) {
+	d.sender.started = true
+	if err := d.sender.send(initial, true); err != nil {
+		d.sender.err = err
+		return
+	}
+	for work := d.next(); work != nil; work = d.next() {
+		entry := work(ctx)
+		if errs, ok := entry["errors"].([]error); ok {
+			if d.sender.err == nil {
+				d.sender.err = errs[0]
+			}
+			entry["errors"] = d.graphy.redactErrors(ctx, errs)
+		}
+		hasNext := d.hasPending()
+		payload, err := json.Marshal(map[string]any{
+			"incremental": []any{entry},
+			"hasNext":     hasNext,
+		})
+		releaseResult(entry["data"])
+		releaseResult(entry["items"])
+		if err == nil {
+			err = d.sender.send(string(payload), hasNext)
+		}
+		if err != nil {
+			d.sender.err = err
+			return
+		}
+	}
+}
+
+// resultPath is the path of a value in the result, for the incremental payloads, the
+// spans of the Tracer, and the errors of list elements that are isolated. It is only kept
+// in the context for requests that need it.
+type resultPath struct {
+	parent *resultPath
+	key    any
+}
+
+// withResultPath returns a context for the value with the key, a field name or a list
+// index, in the value that the context is for.
+func (r *request) withResultPath(ctx context.Context, key any) context.Context {
+	if r == nil || (r.incremental == nil && r.graphy.Tracer == nil && !r.graphy.IsolateListElementErrors) {
+		return ctx
+	}
+	parent, _ := ctx.Value(resultPathContextKey).(*resultPath)
+	return context.WithValue(ctx, resultPathContextKey, &resultPath{parent: parent, key: key})
+}
+
+// currentResultPath returns the path of the value that the context is for.
+func currentResultPath(ctx context.Context) []any {
+	var path []any
+	for p, _ := ctx.Value(resultPathContextKey).(*resultPath); p != nil; p = p.parent {
+		path = append([]any{p.key}, path...)
+	}
+	return path
+}
+
+// contextWithResultPath returns a context for the value at the path.
+func (r *request) contextWithResultPath(ctx context.Context, path []any) context.Context {
+	for _, key := range path {
+		ctx = r.withResultPath(ctx, key)
+	}
+	return ctx
+}
+
+// incrementalEntry starts the entry of a payload for the value at the path.
+func incrementalEntry(path []any, label *string) map[string]any {
+	entry := map[string]any{"path": path}
+	if label != nil {
+		entry["label"] = *label
+	}
+	return entry
+}
+
+// directiveArgs parses the arguments of the directive into args, which points to a
+// deferArgs or streamArgs.
+func (r *request) directiveArgs(ctx context.Context, d *directive, args any) error {
+	var params []namedValue
+	if d.Parameters != nil {
+		params = d.Parameters.Values
+	}
+	return parseMapIntoValue(ctx, r, genericValue{Map: params, Pos: d.Pos}, reflect.ValueOf(args).Elem())
+}
+
+// deferredFields are the fields of an object that a deferred fragment selects.
+type deferredFields struct {
+	directive *directive
+	label     *string
+	fields    []plannedField
+}
+
+// deferField adds the field to the deferred fields of its fragment, if the request is
+// delivered incrementally and the fragment is deferred, and reports whether it did.
+func (r *request) deferField(ctx context.Context, deferred []*deferredFields, pf plannedField) ([]*deferredFields, bool) {
+	if pf.deferred == nil || r.incremental == nil {
+		return deferred, false
+	}
+	for _, d := range deferred {
+		if d.directive == pf.deferred {
+			d.fields = append(d.fields, pf)
+			return deferred, true
+		}
+	}
+	var args deferArgs
+	if err := r.directiveArgs(ctx, pf.deferred, &args); err != nil || (args.If != nil && !*args.If) {
+		return deferred, false
+	}
+	return append(deferred, &deferredFields{directive: pf.deferred, label: args.Label, fields: []plannedField{pf}}), true
+}
+
+// deferFields adds the work to resolve the deferred fields of the struct.
+func (f *graphFunction) deferFields(ctx context.Context, req *request, plan *selectionPlan, anyStruct any, d *deferredFields) {
+	path := currentResultPath(ctx)
+	req.incremental.add(func(ctx context.Context) map[string]any {
+		ctx = req.contextWithResultPath(ctx, path)
+		entry := incrementalEntry(path, d.label)
+		r := f.g.newResultObject()
+		for _, pf := range d.fields {
+			err := f.processPlannedField(ctx, req, plan, pf, anyStruct, nil, r)
+			if err != nil {
+				releaseResult(r)
+				entry["data"] = nil
+				entry["errors"] = []error{err}
+				req.addElementErrors(entry)
+				return entry
+			}
+		}
+		entry["data"] = r
+		req.addElementErrors(entry)
+		return entry
+	})
+}
+
+// streamField adds the work to resolve the elements of a list field that aren't part of
+// the first payload, if the field is streamed. It returns the elements that are.
+func (f *graphFunction) streamField(ctx context.Context, req *request, pf plannedField, fieldAny any) any {
+	if pf.stream == nil || req.incremental == nil {
+		return fieldAny
+	}
+	list := reflect.ValueOf(fieldAny)
+	if list.Kind() != reflect.Slice {
+		return fieldAny
+	}
+	var args streamArgs
+	if err := req.directiveArgs(ctx, pf.stream, &args); err != nil || (args.If != nil && !*args.If) {
+		return fieldAny
+	}
+	initial := 0
+	if args.InitialCount != nil && *args.InitialCount > 0 {
+		initial = *args.InitialCount
+	}
+	if initial >= list.Len() {
+		return fieldAny
+	}
+
+	field := pf.field
+	path := currentResultPath(ctx)
+	for i := initial; i < list.Len(); i++ {
+		itemPath := append(append([]any{}, path...), i)
+		item := list.Index(i)
+		req.incremental.add(func(ctx context.Context) map[string]any {
+			ctx = req.contextWithResultPath(ctx, itemPath)
+			entry := incrementalEntry(itemPath, args.Label)
+			var value any
+			if field.SubParts != nil {
+				var err error
+				value, err = f.processCallOutput(ctx, req, field.SubParts, item)
+				if err != nil {
+					entry["items"] = nil
+					entry["errors"] = []error{AugmentGraphError(err, fmt.Sprintf("error processing streamed element %d of %s", itemPath[len(itemPath)-1], field.Name), field.Pos)}
+					req.addElementErrors(entry)
+					return entry
+				}
+			} else {
+				value = formatScalar(ctx, item.Interface())
+			}
+			entry["items"] = []any{value}
+			req.addElementErrors(entry)
+			return entry
+		})
+	}
+	return list.Slice(0, initial).Interface()
+}
+
+// validateDeferDirectives checks the @defer directives of a fragment spread and adds the
+// variables that their arguments use.
+func (g *Graphy) validateDeferDirectives(fc fragmentCall, variableTypeMap map[string]*requestVariable) error {
+	for _, d := range fc.directives() {
+		if d.Name != deferDirectiveName || d.Parameters == nil {
+			continue
+		}
+		err := g.addNestedInputVariables(genericValue{Map: d.Parameters.Values}, deferArgsType, variableTypeMap)
+		if err != nil {
+			return AugmentGraphError(err, "error adding variables for @defer", d.Pos)
+		}
+	}
+	return nil
+}
+
+// validateStreamDirectives checks that the @stream directives of a field are on a list,
+// and adds the variables that their arguments use.
+func (g *Graphy) validateStreamDirectives(field resultField, resultType reflect.Type, variableTypeMap map[string]*requestVariable) error {
+	for resultType != nil && resultType.Kind() == reflect.Pointer {
+		resultType = resultType.Elem()
+	}
+	for _, d := range field.Directives {
+		if d.Name != streamDirectiveName {
+			continue
+		}
+		if resultType == nil || (resultType.Kind() != reflect.Slice && !isIteratorType(resultType)) {
+			return NewGraphError(fmt.Sprintf("directive %s can only be applied to list fields", d.Name), d.Pos, field.Name)
+		}
+		if d.Parameters == nil {
+			continue
+		}
+		err := g.addNestedInputVariables(genericValue{Map: d.Parameters.Values}, streamArgsType, variableTypeMap)
+		if err != nil {
+			return AugmentGraphError(err, "error adding variables for @stream", d.Pos, field.Name)
+		}
+	}
+	return nil
+}
+
+// findDirective returns the directive with the name, or nil if there is none.
+func findDirective(directives []directive, name string) *directive {
+	for i := range directives {
+		if directives[i].Name == name {
+			return &directives[i]
+		}
+	}
+	return nil
+}
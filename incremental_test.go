@@ -0,0 +1,129 @@
+package quickgraph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type incrementalReview struct {
+	Stars int
+}
+
+type incrementalBook struct {
+	Title  string
+	Tags   []string
+	Scores []incrementalReview
+}
+
+func (b incrementalBook) Reviews(ctx context.Context) []incrementalReview {
+	if req, ok := ctx.Value(requestContextKey).(*request); ok && req.incremental != nil && !req.incremental.sender.started {
+		panic("deferred field resolved before the first payload was sent")
+	}
+	return []incrementalReview{{Stars: 5}, {Stars: 3}}
+}
+
+type incrementalPayload struct {
+	payload string
+	hasNext bool
+}
+
+func processIncrementally(t *testing.T, g *Graphy, query, variables string) []incrementalPayload {
+	var payloads []incrementalPayload
+	err := g.ProcessRequestIncremental(context.Background(), query, variables, func(payload string, hasNext bool) error {
+		payloads = append(payloads, incrementalPayload{payload: payload, hasNext: hasNext})
+		return nil
+	})
+	assert.NoError(t, err)
+	return payloads
+}
+
+func TestGraphy_ProcessRequestIncrementalDefer(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "book", func() incrementalBook {
+		return incrementalBook{
+			Title:  "Dune",
+			Tags:   []string{"sf", "classic", "desert"},
+			Scores: []incrementalReview{{Stars: 1}, {Stars: 2}},
+		}
+	})
+	query := `{ book { Title ... on incrementalBook @defer(label: "reviews") { Reviews { Stars } } } }`
+
+	payloads := processIncrementally(t, &g, query, "")
+	assert.Equal(t, []incrementalPayload{
+		{`{"data":{"book":{"Title":"Dune"}},"hasNext":true}`, true},
+		{`{"hasNext":false,"incremental":[{"data":{"Reviews":[{"Stars":5},{"Stars":3}]},"label":"reviews","path":["book"]}]}`, false},
+	}, payloads)
+
+	// Without incremental delivery the directive is ignored.
+	result, err := g.ProcessRequest(ctx, query, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"book":{"Reviews":[{"Stars":5},{"Stars":3}],"Title":"Dune"}}}`, result)
+
+	// Neither is it if it's turned off, or if the fields are also selected eagerly.
+	payloads = processIncrementally(t, &g, `query Book($d: Boolean!) { book { ...Details @defer(if: $d) } }
+fragment Details on incrementalBook { Title }`, `{"d": false}`)
+	assert.Equal(t, []incrementalPayload{{`{"data":{"book":{"Title":"Dune"}}}`, false}}, payloads)
+	payloads = processIncrementally(t, &g, `{ book { Title ... on incrementalBook @defer { Title } } }`, "")
+	assert.Equal(t, []incrementalPayload{{`{"data":{"book":{"Title":"Dune"}}}`, false}}, payloads)
+}
+
+func TestGraphy_ProcessRequestIncrementalStream(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "book", func() incrementalBook {
+		return incrementalBook{
+			Title:  "Dune",
+			Tags:   []string{"sf", "classic", "desert"},
+			Scores: []incrementalReview{{Stars: 1}, {Stars: 2}},
+		}
+	})
+
+	payloads := processIncrementally(t, &g, `{ book { Tags @stream(initialCount: 1) Scores @stream { Stars } } }`, "")
+	assert.Equal(t, []incrementalPayload{
+		{`{"data":{"book":{"Scores":[],"Tags":["sf"]}},"hasNext":true}`, true},
+		{`{"hasNext":true,"incremental":[{"items":["classic"],"path":["book","Tags",1]}]}`, true},
+		{`{"hasNext":true,"incremental":[{"items":["desert"],"path":["book","Tags",2]}]}`, true},
+		{`{"hasNext":true,"incremental":[{"items":[{"Stars":1}],"path":["book","Scores",0]}]}`, true},
+		{`{"hasNext":false,"incremental":[{"items":[{"Stars":2}],"path":["book","Scores",1]}]}`, false},
+	}, payloads)
+
+	_, err := g.ProcessRequest(ctx, `{ book { Title @stream } }`, "")
+	assert.ErrorContains(t, err, "directive @stream can only be applied to list fields")
+}
+
+func TestGraphHttpHandler_Multipart(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "book", func() incrementalBook {
+		return incrementalBook{
+			Title:  "Dune",
+			Tags:   []string{"sf", "classic", "desert"},
+			Scores: []incrementalReview{{Stars: 1}, {Stars: 2}},
+		}
+	})
+	h := NewGraphHttpHandler(&g)
+
+	serve := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(`{"query": "`+query+`"}`))
+		req.Header.Set("Accept", "multipart/mixed; deferSpec=20220824, application/json")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(`{ book { Title Tags @stream(initialCount: 2) } }`)
+	assert.Equal(t, `multipart/mixed; boundary="-"; deferSpec=20220824`, rec.Header().Get("Content-Type"))
+	assert.Equal(t, "\r\n---"+
+		"\r\nContent-Type: application/json; charset=utf-8\r\n\r\n"+`{"data":{"book":{"Tags":["sf","classic"],"Title":"Dune"}},"hasNext":true}`+"\r\n---"+
+		"\r\nContent-Type: application/json; charset=utf-8\r\n\r\n"+`{"hasNext":false,"incremental":[{"items":["desert"],"path":["book","Tags",2]}]}`+"\r\n-----\r\n",
+		rec.Body.String())
+
+	// Results without incremental payloads are sent as usual.
+	rec = serve(`{ book { Title } }`)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `{"data":{"book":{"Title":"Dune"}}}`, rec.Body.String())
+}
@@ -0,0 +1,232 @@
+package quickgraph
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// inputLimitsPlan lists the fields of an input struct that have size limits given by the
+// maxLength and maxItems options of their graphy tags, directly or in the structs that they
+// contain. Plans are made once per type so that checking a value only looks at the fields
+// that have limits, and not at all for the types that have none.
+type inputLimitsPlan struct {
+	fields []*fieldInputLimits
+	byName map[string]*fieldInputLimits
+}
+
+type fieldInputLimits struct {
+	index     []int
+	name      string
+	maxLength int
+	maxItems  int
+
+	// elem is the plan for the struct that the field holds, directly or as the elements
+	// of a list, if that struct has limits.
+	elem *inputLimitsPlan
+}
+
+// inputLimitsPlans caches the plans by type. The tags of a type can't change, so the plans
+// are shared by all Graphy instances.
+var inputLimitsPlans sync.Map
+
+// inputLimitsFor returns the plan for the limits of a struct, or of the struct in a
+// pointer or list type, or nil if there are no limits to check.
+func inputLimitsFor(typ reflect.Type) *inputLimitsPlan {
+	typ = structElemType(typ)
+	if typ == nil {
+		return nil
+	}
+	if plan, ok := inputLimitsPlans.Load(typ); ok {
+		return plan.(*inputLimitsPlan)
+	}
+	plan := makeInputLimitsPlan(typ, map[reflect.Type]*inputLimitsPlan{})
+	inputLimitsPlans.Store(typ, plan)
+	return plan
+}
+
+// structElemType returns the struct type that the type is, or points to, or is a list of,
+// or nil if there isn't one. time.Time isn't an input struct.
+func structElemType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct || typ == timeType {
+		return nil
+	}
+	return typ
+}
+
+func makeInputLimitsPlan(typ reflect.Type, visiting map[reflect.Type]*inputLimitsPlan) *inputLimitsPlan {
+	if plan, ok := visiting[typ]; ok {
+		// A recursive type; the plan is filled in by the outer call.
+		return plan
+	}
+	plan := &inputLimitsPlan{byName: map[string]*fieldInputLimits{}}
+	visiting[typ] = plan
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, include := graphFieldName(field)
+		if !include {
+			continue
+		}
+		fl := &fieldInputLimits{index: field.Index, name: name}
+		fl.maxLength, fl.maxItems = parseInputLimitTags(field)
+		if elemType := structElemType(field.Type); elemType != nil {
+			fl.elem = makeInputLimitsPlan(elemType, visiting)
+		}
+		if fl.maxLength == 0 && fl.maxItems == 0 && fl.elem == nil {
+			continue
+		}
+		plan.fields = append(plan.fields, fl)
+		if !field.Anonymous {
+			plan.byName[field.Name] = fl
+		} else if fl.elem != nil {
+			// The fields of embedded structs are set as if they were fields of this one.
+			for name, efl := range fl.elem.byName {
+				if _, ok := plan.byName[name]; !ok {
+					plan.byName[name] = efl
+				}
+			}
+		}
+	}
+
+	delete(visiting, typ)
+	if len(plan.fields) == 0 {
+		return nil
+	}
+	return plan
+}
+
+// parseInputLimitTags returns the maxLength and maxItems options of the graphy tag of a
+// field, or zero for the ones that aren't set.
+func parseInputLimitTags(field reflect.StructField) (maxLength, maxItems int) {
+	graphyTag, ok := field.Tag.Lookup("graphy")
+	if !ok {
+		return 0, 0
+	}
+	for _, part := range strings.Split(graphyTag, ",") {
+		parts := strings.Split(part, "=")
+		if len(parts) != 2 || (parts[0] != "maxLength" && parts[0] != "maxItems") {
+			continue
+		}
+		limit, err := strconv.Atoi(parts[1])
+		if err != nil || limit <= 0 {
+			panic(fmt.Sprintf("invalid %s %s on field %s", parts[0], parts[1], field.Name))
+		}
+		if parts[0] == "maxLength" {
+			maxLength = limit
+		} else {
+			maxItems = limit
+		}
+	}
+	return maxLength, maxItems
+}
+
+// constraintDirectiveDefinition declares the @constraint directive that the schema uses
+// for the limits when Graphy.ConstraintDirectives is set. It has the arguments of the
+// constraint directive convention that the limits can be given with.
+const constraintDirectiveDefinition = "directive @constraint(maxLength: Int, maxItems: Int) on INPUT_FIELD_DEFINITION\n\n"
+
+// constraintDirective returns the @constraint directive for the limits of an input field
+// in the schema, or "" if it has none.
+func constraintDirective(field reflect.StructField) string {
+	maxLength, maxItems := parseInputLimitTags(field)
+	var args []string
+	if maxLength > 0 {
+		args = append(args, fmt.Sprintf("maxLength: %d", maxLength))
+	}
+	if maxItems > 0 {
+		args = append(args, fmt.Sprintf("maxItems: %d", maxItems))
+	}
+	if len(args) == 0 {
+		return ""
+	}
+	return " @constraint(" + strings.Join(args, ", ") + ")"
+}
+
+// field returns the limits of the field with the Go name, or nil if there are none.
+func (p *inputLimitsPlan) field(name string) *fieldInputLimits {
+	if p == nil {
+		return nil
+	}
+	return p.byName[name]
+}
+
+// check checks the limits of the fields of a struct. If deep is set, the structs that
+// it contains are checked as well.
+func (p *inputLimitsPlan) check(v reflect.Value, deep bool) error {
+	if p == nil {
+		return nil
+	}
+	for _, fl := range p.fields {
+		fv, err := v.FieldByIndexErr(fl.index)
+		if err != nil {
+			// A nil embedded pointer has nothing to check.
+			continue
+		}
+		err = fl.check(fv, deep)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check checks the limits of the value of a field.
+func (fl *fieldInputLimits) check(v reflect.Value, deep bool) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if fl.maxLength > 0 && v.Kind() == reflect.String {
+		s := v.String()
+		// The length in bytes is an upper bound for the length in characters.
+		if len(s) > fl.maxLength && utf8.RuneCountInString(s) > fl.maxLength {
+			return fmt.Errorf("field %s is longer than %d characters", fl.name, fl.maxLength)
+		}
+	}
+	if fl.maxItems > 0 && v.Kind() == reflect.Slice && v.Len() > fl.maxItems {
+		return fmt.Errorf("field %s has more than %d items", fl.name, fl.maxItems)
+	}
+	if deep && fl.elem != nil {
+		return fl.elem.checkValue(v)
+	}
+	return nil
+}
+
+// checkValue deeply checks a struct, or the structs in a list.
+func (p *inputLimitsPlan) checkValue(v reflect.Value) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		for i := 0; i < v.Len(); i++ {
+			err := p.checkValue(v.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return p.check(v, true)
+}
+
+// checkInputLimits deeply checks the limits of a value parsed from the variables of a
+// request.
+func checkInputLimits(v reflect.Value) error {
+	plan := inputLimitsFor(v.Type())
+	if plan == nil {
+		return nil
+	}
+	return plan.checkValue(v)
+}
@@ -0,0 +1,137 @@
+package quickgraph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"testing"
+)
+
+type limitedComment struct {
+	Body string   `graphy:"body,maxLength=10"`
+	Tags []string `graphy:"tags,maxItems=2"`
+}
+
+type LimitedAudit struct {
+	Note *string `graphy:"note,maxLength=4"`
+}
+
+type limitedPost struct {
+	LimitedAudit
+	Title    string           `graphy:"title,maxLength=5"`
+	Comments []limitedComment `graphy:"comments,maxItems=3"`
+	Replies  []*limitedPost   `graphy:"replies"`
+}
+
+type unlimitedPost struct {
+	Title    string
+	Comments []string
+}
+
+func TestInputLimits_Literals(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterMutation(ctx, "post", func(p limitedPost) string {
+		return p.Title
+	}, "p")
+
+	result, err := g.ProcessRequest(ctx, `mutation { post(p: {title: "héllo", comments: [{body: "ok", tags: ["a", "b"]}], replies: [], LimitedAudit: {}}) }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"post":"héllo"}}`, result)
+
+	tests := []struct {
+		name     string
+		request  string
+		expected string
+	}{
+		{"string", `mutation { post(p: {title: "toolong", comments: [], replies: [], LimitedAudit: {}}) }`, "field title is longer than 5 characters"},
+		{"list", `mutation { post(p: {title: "a", comments: [{body: "", tags: []}, {body: "", tags: []}, {body: "", tags: []}, {body: "", tags: []}], replies: [], LimitedAudit: {}}) }`, "field comments has more than 3 items"},
+		{"nested", `mutation { post(p: {title: "a", comments: [{body: "far too long", tags: []}], replies: [], LimitedAudit: {}}) }`, "field body is longer than 10 characters"},
+		{"embedded", `mutation { post(p: {title: "a", comments: [], replies: [], LimitedAudit: {note: "fives"}}) }`, "field note is longer than 4 characters"},
+		{"recursive", `mutation { post(p: {title: "a", comments: [], replies: [{title: "toolong", comments: [], replies: []}]}) }`, "field title is longer than 5 characters"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := g.ProcessRequest(ctx, tt.request, "")
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+func TestInputLimits_Variables(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterMutation(ctx, "post", func(p limitedPost) string {
+		return p.Title
+	}, "p")
+	request := `mutation m($p: limitedPost!) { post(p: $p) }`
+
+	result, err := g.ProcessRequest(ctx, request, `{"p": {"title": "ok", "comments": [{"body": "fine", "tags": ["x"]}], "replies": []}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"post":"ok"}}`, result)
+
+	result, err = g.ProcessRequest(ctx, request, `{"p": {"title": "ok", "comments": [{"body": "fine", "tags": ["x", "y", "z"]}], "replies": []}}`)
+	assert.Error(t, err)
+	assert.Equal(t, `{"errors":[{"message":"invalid variable p: field tags has more than 2 items","path":["p"]}]}`, result)
+
+	_, err = g.ProcessRequest(ctx, request, `{"p": {"title": "ok", "comments": [], "replies": [null, {"title": "toolong", "comments": [], "replies": []}]}}`)
+	assert.ErrorContains(t, err, "field title is longer than 5 characters")
+
+	// The fields of embedded structs are checked as well.
+	_, err = g.ProcessRequest(ctx, request, `{"p": {"title": "ok", "comments": [], "replies": [], "note": "fives"}}`)
+	assert.ErrorContains(t, err, "field note is longer than 4 characters")
+}
+
+func Test_inputLimitsFor(t *testing.T) {
+	assert.Nil(t, inputLimitsFor(reflect.TypeOf(unlimitedPost{})))
+	assert.Nil(t, inputLimitsFor(reflect.TypeOf("")))
+
+	plan := inputLimitsFor(reflect.TypeOf([]*limitedPost{}))
+	if assert.NotNil(t, plan) {
+		assert.Len(t, plan.fields, 4)
+		assert.Equal(t, 5, plan.field("Title").maxLength)
+		assert.Equal(t, 4, plan.field("Note").maxLength)
+		assert.Same(t, plan, plan.field("Replies").elem)
+	}
+
+	assert.Panics(t, func() {
+		type bad struct {
+			Name string `graphy:"maxLength=lots"`
+		}
+		inputLimitsFor(reflect.TypeOf(bad{}))
+	})
+}
+
+func TestInputLimits_ConstraintDirectives(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterMutation(ctx, "post", func(p limitedPost) string {
+		return p.Title
+	}, "p")
+	assert.NotContains(t, g.SchemaDefinition(ctx), "@constraint")
+
+	g.ConstraintDirectives = true
+	expected := `type Mutation {
+	post(p: limitedPost!): String!
+}
+
+input LimitedAudit {
+	note: String @constraint(maxLength: 4)
+}
+
+input limitedComment {
+	body: String! @constraint(maxLength: 10)
+	tags: [String!]! @constraint(maxItems: 2)
+}
+
+input limitedPost implements LimitedAudit {
+	comments: [limitedComment!]! @constraint(maxItems: 3)
+	replies: [limitedPost]!
+	title: String! @constraint(maxLength: 5)
+}
+
+directive @constraint(maxLength: Int, maxItems: Int) on INPUT_FIELD_DEFINITION
+
+`
+	assert.Equal(t, expected, g.SchemaDefinition(ctx))
+}
@@ -0,0 +1,197 @@
+package quickgraph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Validator is implemented by input types that check their own values. Validate is called
+// once the input is parsed, before the function that it is for is called, including in
+// dry-run mode. If it returns an error, the function isn't called and the error is
+// reported for the argument. The fields of the input are validated before the input
+// itself.
+type Validator interface {
+	Validate() error
+}
+
+// ValidatorWithContext is like Validator, for validation that needs the context of the
+// request, such as to look up the caller.
+type ValidatorWithContext interface {
+	ValidateWithContext(ctx context.Context) error
+}
+
+// FieldErrors is implemented by the errors of Validator and ValidatorWithContext that
+// are about specific fields of the input. The messages are reported by the path of the
+// fields in the arguments, like "input.email", in the "validationErrors" extension of
+// the error, so that clients can show them next to the fields of a form:
+//
+//	{"message": "...", "extensions": {"validationErrors": {"input.email": "is not valid"}}}
+//
+// The keys of FieldErrors are the paths of the fields in the input that is validated, by
+// their names in the schema. ValidationErrors is a ready-made FieldErrors.
+type FieldErrors interface {
+	error
+	FieldErrors() map[string]string
+}
+
+// ValidationErrors is an error with the messages for the fields of an input that aren't
+// valid, keyed by their names:
+//
+//	func (in UserInput) Validate() error {
+//		errs := quickgraph.ValidationErrors{}
+//		if !strings.Contains(in.Email, "@") {
+//			errs["email"] = "is not a valid email address"
+//		}
+//		return errs.OrNil()
+//	}
+type ValidationErrors map[string]string
+
+func (ve ValidationErrors) Error() string {
+	parts := make([]string, 0, len(ve))
+	for _, field := range sortedKeys(ve) {
+		parts = append(parts, field+" "+ve[field])
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (ve ValidationErrors) FieldErrors() map[string]string {
+	return ve
+}
+
+// OrNil returns the errors, or nil if there aren't any.
+func (ve ValidationErrors) OrNil() error {
+	if len(ve) == 0 {
+		return nil
+	}
+	return ve
+}
+
+var (
+	validatorType            = reflect.TypeOf((*Validator)(nil)).Elem()
+	validatorWithContextType = reflect.TypeOf((*ValidatorWithContext)(nil)).Elem()
+
+	// validatedTypes caches whether each input type has anything to validate.
+	validatedTypes sync.Map
+)
+
+// validateInput runs the validators of the value, which is at the path in the arguments,
+// and of the values in it.
+func validateInput(ctx context.Context, value reflect.Value, path string) error {
+	if !value.IsValid() || !hasValidators(value.Type()) {
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+		return validateInput(ctx, value.Elem(), path)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			err := validateInput(ctx, value.Index(i), joinInputPath(path, strconv.Itoa(i)))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			name, include := graphFieldName(value.Type().Field(i))
+			if !include {
+				continue
+			}
+			err := validateInput(ctx, value.Field(i), joinInputPath(path, name))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	var validated any
+	if value.CanAddr() {
+		validated = value.Addr().Interface()
+	} else {
+		validated = value.Interface()
+	}
+	var err error
+	switch v := validated.(type) {
+	case ValidatorWithContext:
+		err = v.ValidateWithContext(ctx)
+	case Validator:
+		err = v.Validate()
+	}
+	if err == nil {
+		return nil
+	}
+	return newInputValidationError(err, path)
+}
+
+// hasValidators reports whether the type, or any of the types that it contains,
+// implements Validator or ValidatorWithContext.
+func hasValidators(typ reflect.Type) bool {
+	if cached, ok := validatedTypes.Load(typ); ok {
+		return cached.(bool)
+	}
+	result := containsValidators(typ, map[reflect.Type]bool{})
+	validatedTypes.Store(typ, result)
+	return result
+}
+
+// containsValidators does the work of hasValidators. The seen types protect against
+// types that contain themselves.
+func containsValidators(typ reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[typ] {
+		return false
+	}
+	seen[typ] = true
+
+	ptr := reflect.PointerTo(typ)
+	if ptr.Implements(validatorType) || ptr.Implements(validatorWithContextType) {
+		return true
+	}
+	switch typ.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return containsValidators(typ.Elem(), seen)
+	case reflect.Interface:
+		return true
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			if _, include := graphFieldName(typ.Field(i)); include && containsValidators(typ.Field(i).Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newInputValidationError reports the error of a validator for the value at the path,
+// with the messages of its FieldErrors keyed by their paths in the arguments.
+func newInputValidationError(err error, path string) error {
+	message := "invalid input"
+	if path != "" {
+		message = fmt.Sprintf("invalid value for %s", path)
+	}
+	gErr := GraphError{Message: message, InnerError: err}
+
+	var fe FieldErrors
+	if errors.As(err, &fe) {
+		gErr.ValidationErrors = map[string]string{}
+		for field, fieldMessage := range fe.FieldErrors() {
+			gErr.ValidationErrors[joinInputPath(path, field)] = fieldMessage
+		}
+	}
+	return gErr
+}
+
+func joinInputPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
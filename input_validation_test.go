@@ -0,0 +1,87 @@
+package quickgraph
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+type validationAddress struct {
+	Zip string `json:"zip"`
+}
+
+func (a validationAddress) Validate() error {
+	if len(a.Zip) != 5 {
+		return ValidationErrors{"zip": "must have 5 digits"}
+	}
+	return nil
+}
+
+type validationUserInput struct {
+	Email   string              `json:"email"`
+	Name    string              `json:"name"`
+	Address []validationAddress `json:"address"`
+}
+
+func (in *validationUserInput) ValidateWithContext(ctx context.Context) error {
+	errs := ValidationErrors{}
+	if !strings.Contains(in.Email, "@") {
+		errs["email"] = "is not a valid email address"
+	}
+	if in.Name == "" {
+		errs["name"] = "is required"
+	}
+	return errs.OrNil()
+}
+
+type validationSignup struct {
+	Code string `json:"code"`
+}
+
+func (s validationSignup) Validate() error {
+	if s.Code != "open sesame" {
+		return errors.New("the code is wrong")
+	}
+	return nil
+}
+
+func TestInputValidation(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	calls := 0
+	g.RegisterMutation(ctx, "createUser", func(input validationUserInput) string {
+		calls++
+		return input.Name
+	}, "input")
+	g.RegisterMutation(ctx, "signup", func(s validationSignup) bool {
+		calls++
+		return true
+	})
+
+	result, err := g.ProcessRequest(ctx, `mutation { createUser(input: {email: "x", name: "", address: []}) }`, "")
+	assert.Error(t, err)
+	assert.Equal(t, `{"data":{},"errors":[{"message":"invalid value for input: email is not a valid email address; name is required","locations":[{"line":1,"column":23}],"path":["createUser"],"extensions":{"validationErrors":{"input.email":"is not a valid email address","input.name":"is required"}}}]}`, result)
+
+	// Nested inputs are validated first, including the ones from variables.
+	result, err = g.ProcessRequest(ctx, `mutation Create($address: [validationAddress!]!) { createUser(input: {email: "a@b", name: "A", address: $address}) }`, `{"address": [{"zip": "12345"}, {"zip": "1"}]}`)
+	assert.Error(t, err)
+	assert.Contains(t, result, `"extensions":{"validationErrors":{"input.address.1.zip":"must have 5 digits"}}`)
+
+	// Errors that aren't about fields don't have the extension, and struct parameters are
+	// validated as a whole.
+	result, err = g.ProcessRequest(ctx, `mutation { signup(code: "abc") }`, "")
+	assert.Error(t, err)
+	assert.Equal(t, `{"data":{},"errors":[{"message":"invalid input: the code is wrong","locations":[{"line":1,"column":19}],"path":["signup"]}]}`, result)
+	assert.Equal(t, 0, calls)
+
+	result, err = g.ProcessRequest(ctx, `mutation { createUser(input: {email: "a@b", name: "A", address: [{zip: "12345"}]}) signup(code: "open sesame") }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"createUser":"A","signup":true}}`, result)
+}
+
+func TestGraphError_ValidationErrorsJSON(t *testing.T) {
+	gErr := GraphError{Message: "bad", Extensions: map[string]string{"code": "BAD_USER_INPUT"}, ValidationErrors: map[string]string{"a": "b"}}
+	assert.Equal(t, `{"errors":[{"message":"bad","extensions":{"code":"BAD_USER_INPUT","validationErrors":{"a":"b"}}}]}`, formatError(gErr))
+}
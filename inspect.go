@@ -0,0 +1,205 @@
+package quickgraph
+
+import (
+	"sort"
+)
+
+// OperationInfo describes a query or mutation that is registered with a Graphy instance.
+type OperationInfo struct {
+	Name string
+	Mode GraphFunctionMode
+
+	// Type is the type of the result in schema notation, such as "[Character!]!". The
+	// names of the types are the ones that introspection reports.
+	Type string
+
+	Args              []ArgInfo
+	Description       string
+	IsDeprecated      bool
+	DeprecationReason string
+}
+
+// TypeInfo describes a named type of the schema.
+type TypeInfo struct {
+	Name string
+
+	// Kind is the kind of the type as reported by introspection, such as "OBJECT" or
+	// "ENUM".
+	Kind string
+
+	Description string
+
+	// Interfaces are the names of the interfaces that an object type implements.
+	Interfaces []string
+
+	// PossibleTypes are the names of the members of a union, or of the types that
+	// implement an interface.
+	PossibleTypes []string
+
+	// EnumValues are the values of an enum, including deprecated ones.
+	EnumValues []string
+}
+
+// FieldInfo describes a field of an object type or an input type.
+type FieldInfo struct {
+	Name string
+
+	// Type is the type of the field in schema notation.
+	Type string
+
+	Args              []ArgInfo
+	Description       string
+	IsDeprecated      bool
+	DeprecationReason string
+}
+
+// ArgInfo describes an argument of an operation or a field.
+type ArgInfo struct {
+	Name string
+
+	// Type is the type of the argument in schema notation.
+	Type string
+
+	Description       string
+	DefaultValue      *string
+	IsDeprecated      bool
+	DeprecationReason string
+}
+
+// Operations returns an iterator over the queries and mutations that are registered,
+// sorted by name, with the queries first. It has the same shape as iter.Seq so it can be
+// used with range-over-func:
+//
+//	for op := range g.Operations() {
+//		fmt.Println(op.Name, op.Type)
+//	}
+//
+// The operations are read when the iteration starts, so registering functions while
+// iterating doesn't affect it.
+func (g *Graphy) Operations() func(yield func(OperationInfo) bool) {
+	return func(yield func(OperationInfo) bool) {
+		for _, op := range g.Schema().operations {
+			if !yield(op) {
+				return
+			}
+		}
+	}
+}
+
+// Types returns an iterator over the named types of the schema, sorted by name. Like
+// Operations, it can be used with range-over-func.
+func (g *Graphy) Types() func(yield func(TypeInfo) bool) {
+	return func(yield func(TypeInfo) bool) {
+		for _, t := range g.Schema().types {
+			if !yield(t) {
+				return
+			}
+		}
+	}
+}
+
+// Fields returns an iterator over the fields of the named type, sorted by name. For
+// input types these are the input fields. The iterator is empty if there is no such
+// type or it has no fields.
+func (g *Graphy) Fields(typeName string) func(yield func(FieldInfo) bool) {
+	return func(yield func(FieldInfo) bool) {
+		for _, f := range g.Schema().fields[typeName] {
+			if !yield(f) {
+				return
+			}
+		}
+	}
+}
+
+func typeInfo(t *__Type) TypeInfo {
+	info := TypeInfo{
+		Name:        t.Name,
+		Kind:        string(t.Kind),
+		Description: derefString(t.Description),
+	}
+	for _, i := range t.Interfaces {
+		info.Interfaces = append(info.Interfaces, i.Name)
+	}
+	for _, p := range t.PossibleTypes {
+		info.PossibleTypes = append(info.PossibleTypes, p.Name)
+	}
+	for _, v := range sortedIntrospectionEnumValues(t) {
+		info.EnumValues = append(info.EnumValues, v.Name)
+	}
+	return info
+}
+
+func fieldInfos(t *__Type) []FieldInfo {
+	var result []FieldInfo
+	if t.Kind == IntrospectionKindInputObject {
+		for _, f := range t.InputFields {
+			result = append(result, FieldInfo{
+				Name:        f.Name,
+				Type:        typeRefString(f.Type),
+				Description: derefString(f.Description),
+			})
+		}
+		return result
+	}
+	for _, f := range sortedIntrospectionFields(t) {
+		result = append(result, FieldInfo{
+			Name:              f.Name,
+			Type:              typeRefString(f.Type),
+			Args:              argInfos(f.Args),
+			Description:       derefString(f.Description),
+			IsDeprecated:      f.IsDeprecated,
+			DeprecationReason: derefString(f.DeprecationReason),
+		})
+	}
+	return result
+}
+
+func argInfos(values []__InputValue) []ArgInfo {
+	var result []ArgInfo
+	for _, v := range values {
+		result = append(result, ArgInfo{
+			Name:              v.Name,
+			Type:              typeRefString(v.Type),
+			Description:       derefString(v.Description),
+			DefaultValue:      v.DefaultValue,
+			IsDeprecated:      v.IsDeprecated,
+			DeprecationReason: derefString(v.DeprecationReason),
+		})
+	}
+	return result
+}
+
+// typeRefString writes a type reference in schema notation, with the list and non-null
+// wrappers.
+func typeRefString(t *__Type) string {
+	if t == nil {
+		return ""
+	}
+	switch t.Kind {
+	case IntrospectionKindNonNull:
+		return typeRefString(t.OfType) + "!"
+	case IntrospectionKindList:
+		return "[" + typeRefString(t.OfType) + "]"
+	}
+	return t.Name
+}
+
+// sortedIntrospectionFields returns the fields of a type, including deprecated ones,
+// sorted by name. The fields are copied so that the shared model isn't modified.
+func sortedIntrospectionFields(t *__Type) []__Field {
+	fields := append([]__Field{}, t.fieldsRaw...)
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Name < fields[j].Name
+	})
+	return fields
+}
+
+// sortedIntrospectionEnumValues returns the values of an enum, including deprecated ones,
+// sorted by name.
+func sortedIntrospectionEnumValues(t *__Type) []__EnumValue {
+	values := append([]__EnumValue{}, t.enumValuesRaw...)
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].Name < values[j].Name
+	})
+	return values
+}
@@ -0,0 +1,113 @@
+package quickgraph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type inspectInput struct {
+	Name  string
+	Count *int
+}
+
+type inspectResult struct {
+	Name   string
+	Tags   []string
+	Legacy string `graphy:"deprecated=Use Name"`
+}
+
+func (r *inspectResult) Greeting(prefix string) string {
+	return prefix + r.Name
+}
+
+func TestGraphy_Operations(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name:           "find",
+		Function:       func(name string) []inspectResult { return nil },
+		ParameterNames: []string{"name"},
+		Since:          "1.2",
+	})
+	g.RegisterMutation(ctx, "create", func(input inspectInput) inspectResult { return inspectResult{} }, "input")
+
+	// The module targets an older Go version, so the iterators are called directly
+	// rather than with range-over-func.
+	var ops []OperationInfo
+	g.Operations()(func(op OperationInfo) bool {
+		ops = append(ops, op)
+		return true
+	})
+	assert.Equal(t, []OperationInfo{
+		{
+			Name:        "find",
+			Mode:        ModeQuery,
+			Type:        "[inspectResult!]!",
+			Args:        []ArgInfo{{Name: "name", Type: "string!"}},
+			Description: "Since 1.2.",
+		},
+		{
+			Name: "create",
+			Mode: ModeMutation,
+			Type: "inspectResult!",
+			Args: []ArgInfo{{Name: "input", Type: "inspectInput!"}},
+		},
+	}, ops)
+
+	// Stopping early ends the iteration.
+	count := 0
+	g.Operations()(func(op OperationInfo) bool {
+		count++
+		return false
+	})
+	assert.Equal(t, 1, count)
+}
+
+func TestGraphy_TypesAndFields(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name:           "find",
+		Function:       func(name string) []inspectResult { return nil },
+		ParameterNames: []string{"name"},
+		Since:          "1.2",
+	})
+	g.RegisterMutation(ctx, "create", func(input inspectInput) inspectResult { return inspectResult{} }, "input")
+
+	types := map[string]TypeInfo{}
+	g.Types()(func(ti TypeInfo) bool {
+		types[ti.Name] = ti
+		return true
+	})
+	assert.Equal(t, "OBJECT", types["inspectResult"].Kind)
+	assert.Equal(t, "INPUT_OBJECT", types["inspectInput"].Kind)
+	assert.NotContains(t, types, "__query")
+
+	var fields []FieldInfo
+	g.Fields("inspectResult")(func(fi FieldInfo) bool {
+		fields = append(fields, fi)
+		return true
+	})
+	assert.Equal(t, []FieldInfo{
+		{Name: "Greeting", Type: "string!", Args: []ArgInfo{{Name: "arg1", Type: "string!"}}},
+		{Name: "Legacy", Type: "string!", IsDeprecated: true, DeprecationReason: "Use Name"},
+		{Name: "Name", Type: "string!"},
+		{Name: "Tags", Type: "[string!]!"},
+	}, fields)
+
+	fields = nil
+	g.Fields("inspectInput")(func(fi FieldInfo) bool {
+		fields = append(fields, fi)
+		return true
+	})
+	assert.Equal(t, []FieldInfo{
+		{Name: "Count", Type: "Int"},
+		{Name: "Name", Type: "string!"},
+	}, fields)
+
+	g.Fields("Unknown")(func(fi FieldInfo) bool {
+		assert.Fail(t, "unexpected field")
+		return true
+	})
+}
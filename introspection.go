@@ -2,10 +2,12 @@ package quickgraph
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"sort"
 	"strings"
+	"time"
 )
 
 type __Directive struct {
@@ -26,6 +28,9 @@ type __Schema struct {
 	Directives []*__Directive `json:"directives"`
 
 	typeLookupByName map[string]*__Type
+
+	// st holds the names of the types while the schema is being built.
+	st *schemaTypes
 }
 
 type __Type struct {
@@ -55,6 +60,12 @@ type __Field struct {
 	Type              *__Type        `json:"type"`
 	IsDeprecated      bool           `json:"isDeprecated"`
 	DeprecationReason *string        `json:"deprecationReason"`
+
+	// SunsetAt is an extension to the introspection of the spec for the sunset of
+	// deprecated queries and mutations. See FunctionDefinition.SunsetAt.
+	SunsetAt *time.Time `json:"sunsetAt"`
+
+	versions versionInfo
 }
 
 type __TypeKind string
@@ -71,10 +82,14 @@ const (
 )
 
 type __InputValue struct {
-	Name         string  `json:"name"`
-	Description  *string `json:"description"`
-	Type         *__Type `json:"type"`
-	DefaultValue *string `json:"defaultValue"`
+	Name              string  `json:"name"`
+	Description       *string `json:"description"`
+	Type              *__Type `json:"type"`
+	DefaultValue      *string `json:"defaultValue"`
+	IsDeprecated      bool    `json:"isDeprecated"`
+	DeprecationReason *string `json:"deprecationReason"`
+
+	versions versionInfo
 }
 
 func (it *__Type) Fields(includeDeprecatedOpt *bool) []__Field {
@@ -134,6 +149,125 @@ func (g *Graphy) EnableIntrospection(ctx context.Context) {
 	g.RegisterQuery(ctx, "__type", typesFunc, "name")
 }
 
+// IntrospectionJSON returns the result of the standard introspection query for the
+// schema, in the same shape that running the IntrospectionQuery used by tools like
+// GraphiQL and graphql-codegen would return. This is intended for build pipelines that
+// need a schema.json file. The schema is serialized directly, so introspection doesn't
+// need to be enabled for this to work.
+func (g *Graphy) IntrospectionJSON(ctx context.Context) ([]byte, error) {
+	g.structureLock.RLock()
+	defer g.structureLock.RUnlock()
+
+	is := g.getSchemaTypes().introspectionSchema
+
+	types := []any{}
+	for _, t := range is.Types {
+		types = append(types, introspectionFullType(t))
+	}
+	directives := []any{}
+	for _, d := range is.Directives {
+		directives = append(directives, map[string]any{
+			"name":        d.Name,
+			"description": d.Description,
+			"locations":   d.Locations,
+			"args":        introspectionInputValues(d.Args),
+		})
+	}
+
+	schema := map[string]any{
+		"queryType":        introspectionNamedType(is.Queries),
+		"mutationType":     introspectionNamedType(is.Mutations),
+		"subscriptionType": introspectionNamedType(is.Subscription),
+		"types":            types,
+		"directives":       directives,
+	}
+	return json.Marshal(map[string]any{
+		"data": map[string]any{
+			"__schema": schema,
+		},
+	})
+}
+
+// introspectionNamedType corresponds to `{ name }` in the introspection query.
+func introspectionNamedType(t *__Type) any {
+	if t == nil {
+		return nil
+	}
+	return map[string]any{"name": t.Name}
+}
+
+// introspectionFullType corresponds to the FullType fragment of the introspection query.
+func introspectionFullType(t *__Type) map[string]any {
+	includeDeprecated := true
+
+	fields := []any{}
+	for _, f := range t.Fields(&includeDeprecated) {
+		fields = append(fields, map[string]any{
+			"name":              f.Name,
+			"description":       f.Description,
+			"args":              introspectionInputValues(f.Args),
+			"type":              introspectionTypeRef(f.Type),
+			"isDeprecated":      f.IsDeprecated,
+			"deprecationReason": f.DeprecationReason,
+		})
+	}
+	enumValues := []any{}
+	for _, v := range t.EnumValues(&includeDeprecated) {
+		enumValues = append(enumValues, map[string]any{
+			"name":              v.Name,
+			"description":       v.Description,
+			"isDeprecated":      v.IsDeprecated,
+			"deprecationReason": v.DeprecationReason,
+		})
+	}
+
+	return map[string]any{
+		"kind":          t.Kind,
+		"name":          t.Name,
+		"description":   t.Description,
+		"fields":        fields,
+		"inputFields":   introspectionInputValues(t.InputFields),
+		"interfaces":    introspectionTypeRefs(t.Interfaces),
+		"enumValues":    enumValues,
+		"possibleTypes": introspectionTypeRefs(t.PossibleTypes),
+	}
+}
+
+// introspectionInputValues corresponds to the InputValue fragment of the introspection
+// query.
+func introspectionInputValues(values []__InputValue) []any {
+	result := []any{}
+	for _, v := range values {
+		result = append(result, map[string]any{
+			"name":         v.Name,
+			"description":  v.Description,
+			"type":         introspectionTypeRef(v.Type),
+			"defaultValue": v.DefaultValue,
+		})
+	}
+	return result
+}
+
+func introspectionTypeRefs(types []*__Type) []any {
+	result := []any{}
+	for _, t := range types {
+		result = append(result, introspectionTypeRef(t))
+	}
+	return result
+}
+
+// introspectionTypeRef corresponds to the TypeRef fragment of the introspection query.
+func introspectionTypeRef(t *__Type) any {
+	if t == nil {
+		return nil
+	}
+	return map[string]any{
+		"kind":   t.Kind,
+		"name":   t.Name,
+		"ofType": introspectionTypeRef(t.OfType),
+	}
+}
+
 func (g *Graphy) populateIntrospection(st *schemaTypes) {
 	queries := &__Type{Kind: IntrospectionKindObject, Name: "__query"}
 	mutations := &__Type{Kind: IntrospectionKindObject, Name: "__mutation"}
@@ -143,6 +277,7 @@ func (g *Graphy) populateIntrospection(st *schemaTypes) {
 		Mutations:        mutations,
 		Types:            []*__Type{},
 		typeLookupByName: make(map[string]*__Type),
+		st:               st,
 	}
 
 	processorNames := keys(g.processors)
@@ -154,7 +289,16 @@ func (g *Graphy) populateIntrospection(st *schemaTypes) {
 			continue
 		}
 		t, args := g.introspectionCall(is, &f)
-		qf := __Field{Name: f.name, Type: t, Args: args}
+		qf := __Field{Name: f.name, Type: t, Args: args, Description: f.schemaDescription(), versions: f.versions}
+		if f.isDeprecated() {
+			reason := f.deprecationReason()
+			qf.IsDeprecated = true
+			qf.DeprecationReason = &reason
+		}
+		if !f.sunsetAt.IsZero() {
+			sunsetAt := f.sunsetAt
+			qf.SunsetAt = &sunsetAt
+		}
 
 		switch f.mode {
 		case ModeQuery:
@@ -172,24 +316,33 @@ func (g *Graphy) populateIntrospection(st *schemaTypes) {
 	}
 
 	is.Types = append(is.Types, queries, mutations)
-	g.schemaBuffer.introspectionSchema = is
+	is.Directives = append(g.introspectionOutputDirectives(is), g.introspectionDirectives(is)...)
+	sort.Slice(is.Directives, func(i, j int) bool {
+		return is.Directives[i].Name < is.Directives[j].Name
+	})
+	is.st = nil
+	st.introspectionSchema = is
 }
 
 func (g *Graphy) getIntrospectionBaseType(is *__Schema, tl *typeLookup, io TypeKind) *__Type {
 	var name string
 
-	if tl.rootType != nil && tl.rootType.ConvertibleTo(stringEnumValuesType) {
-		name = g.schemaBuffer.enumTypeNameLookup[tl]
+	if g.isEnum(tl) {
+		name = is.st.enumTypeNameLookup[tl]
+	} else if tl.rootType == timeType {
+		name = dateTimeScalarName
+	} else if scalar, ok := g.namedScalar(tl); ok {
+		name = scalar
 	} else if tl.fundamental {
-		if otlName, ok := g.schemaBuffer.outputTypeNameLookup[tl]; ok {
+		if otlName, ok := is.st.outputTypeNameLookup[tl]; ok {
 			name = otlName
 		} else {
 			name = introspectionScalarName(tl)
 		}
 	} else if io == TypeOutput || tl.fundamental {
-		name = g.schemaBuffer.outputTypeNameLookup[tl]
+		name = is.st.outputTypeNameLookup[tl]
 	} else if io == TypeInput {
-		name = g.schemaBuffer.inputTypeNameLookup[tl]
+		name = is.st.inputTypeNameLookup[tl]
 	} else {
 		panic("unknown IO type")
 	}
@@ -198,7 +351,7 @@ func (g *Graphy) getIntrospectionBaseType(is *__Schema, tl *typeLookup, io TypeK
 		return existing
 	}
 
-	result := &__Type{Name: name}
+	result := &__Type{Name: name, Description: tl.description}
 	is.typeLookupByName[name] = result
 
 	switch {
@@ -219,12 +372,10 @@ func (g *Graphy) getIntrospectionBaseType(is *__Schema, tl *typeLookup, io TypeK
 			implType := g.getIntrospectionBaseType(is, impl, io)
 			result.PossibleTypes = append(result.PossibleTypes, implType)
 		}
-	case tl.rootType.ConvertibleTo(stringEnumValuesType):
+		g.addIntrospectionInterfaces(is, tl, io, result)
+	case g.isEnum(tl):
 		result.Kind = IntrospectionKindEnum
-		enumValue := reflect.New(tl.rootType)
-		sev := enumValue.Convert(stringEnumValuesType)
-		se := sev.Interface().(StringEnumValues)
-		for _, s := range se.EnumValues() {
+		for _, s := range g.enumValues(tl) {
 			s := s
 			value := __EnumValue{
 				Name: s.Name,
@@ -250,14 +401,20 @@ func (g *Graphy) getIntrospectionBaseType(is *__Schema, tl *typeLookup, io TypeK
 	default:
 		result.Kind = IntrospectionKindObject
 		g.addIntrospectionSchemaFields(is, tl, io, result)
-		for _, impls := range sortedKeys(tl.implements) {
-			result.Interfaces = append(result.Interfaces, g.getIntrospectionModifiedType(is, tl.implements[impls], io))
-		}
+		g.addIntrospectionInterfaces(is, tl, io, result)
 	}
 
 	return result
 }
 
+// addIntrospectionInterfaces lists the interfaces that the type implements. This includes
+// interface types that embed other interfaces.
+func (g *Graphy) addIntrospectionInterfaces(is *__Schema, tl *typeLookup, io TypeKind, result *__Type) {
+	for _, name := range sortedKeys(tl.implements) {
+		result.Interfaces = append(result.Interfaces, g.getIntrospectionBaseType(is, tl.implements[name], io))
+	}
+}
+
 func introspectionScalarName(tl *typeLookup) string {
 	kind := tl.rootType.Kind()
 	switch kind {
@@ -269,6 +426,11 @@ func introspectionScalarName(tl *typeLookup) string {
 		return "Float"
 	case reflect.String:
 		return "String"
+	case reflect.Struct:
+		if tl.rootType == timeType {
+			return dateTimeScalarName
+		}
+		panic("unknown scalar type")
 	default:
 		panic("unknown scalar type")
 	}
@@ -279,10 +441,19 @@ func (g *Graphy) addIntrospectionSchemaFields(is *__Schema, tl *typeLookup, io T
 		ft := tl.fields[fieldName]
 		if ft.fieldType == FieldTypeField {
 			if io == TypeOutput {
+				tl := g.typeLookup(ft.resultType)
+				typ := g.getIntrospectionModifiedType(is, tl, io)
+				if nullable := ft.schemaNullable(tl.isPointer); nullable && typ.Kind == IntrospectionKindNonNull {
+					typ = typ.OfType
+				} else if !nullable && typ.Kind != IntrospectionKindNonNull {
+					typ = g.wrapType(typ, "required", IntrospectionKindNonNull)
+				}
 				field := __Field{
 					Name:         fieldName,
-					Type:         g.getIntrospectionModifiedType(is, g.typeLookup(ft.resultType), io),
+					Description:  ft.schemaDescription(),
+					Type:         typ,
 					IsDeprecated: ft.isDeprecated,
+					versions:     ft.versions,
 				}
 				if ft.isDeprecated {
 					field.DeprecationReason = &ft.deprecatedReason
@@ -290,14 +461,22 @@ func (g *Graphy) addIntrospectionSchemaFields(is *__Schema, tl *typeLookup, io T
 				result.fieldsRaw = append(result.fieldsRaw, field)
 			} else {
 				input := __InputValue{
-					Name: fieldName,
-					Type: g.getIntrospectionModifiedType(is, g.typeLookup(ft.resultType), io),
+					Name:        fieldName,
+					Description: ft.schemaDescription(),
+					Type:        g.getIntrospectionModifiedType(is, g.typeLookup(ft.resultType), io),
+					versions:    ft.versions,
 				}
 				result.InputFields = append(result.InputFields, input)
 			}
 		} else if ft.fieldType == FieldTypeGraphFunction {
 			call, args := g.introspectionCall(is, ft.graphFunction)
-			result.fieldsRaw = append(result.fieldsRaw, __Field{Name: fieldName, Type: call, Args: args})
+			result.fieldsRaw = append(result.fieldsRaw, __Field{
+				Name:        fieldName,
+				Description: ft.schemaDescription(),
+				Type:        call,
+				Args:        args,
+				versions:    ft.versions,
+			})
 		}
 	}
 }
@@ -306,10 +485,14 @@ func (g *Graphy) introspectionCall(is *__Schema, f *graphFunction) (*__Type, []_
 	result := g.getIntrospectionModifiedType(is, f.baseReturnType, TypeOutput)
 
 	var args []__InputValue
-	for _, param := range f.paramsByIndex {
+	for _, param := range f.orderedParams() {
 		args = append(args, __InputValue{
-			Name: param.name,
-			Type: g.getIntrospectionModifiedType(is, g.typeLookup(param.paramType), TypeInput),
+			Name:              param.name,
+			Description:       param.description,
+			Type:              g.getIntrospectionModifiedType(is, g.typeLookup(param.paramType), TypeInput),
+			DefaultValue:      param.defaultValue,
+			IsDeprecated:      param.deprecatedReason != nil,
+			DeprecationReason: param.deprecatedReason,
 		})
 	}
 	return result, args
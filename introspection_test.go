@@ -132,7 +132,58 @@ func TestGraphy_Introspection_Schema(t *testing.T) {
 	expected := `{
   "data": {
     "__schema": {
-      "directives": [],
+      "directives": [
+        {
+          "args": [
+            {
+              "defaultValue": null,
+              "description": null,
+              "name": "if",
+              "type": {
+                "kind": "NON_NULL",
+                "name": "required",
+                "ofType": {
+                  "kind": "SCALAR",
+                  "name": "Boolean",
+                  "ofType": null
+                }
+              }
+            }
+          ],
+          "description": "Directs the executor to include this field or fragment only when the \"if\" argument is true.",
+          "locations": [
+            "FIELD",
+            "FRAGMENT_SPREAD",
+            "INLINE_FRAGMENT"
+          ],
+          "name": "include"
+        },
+        {
+          "args": [
+            {
+              "defaultValue": null,
+              "description": null,
+              "name": "if",
+              "type": {
+                "kind": "NON_NULL",
+                "name": "required",
+                "ofType": {
+                  "kind": "SCALAR",
+                  "name": "Boolean",
+                  "ofType": null
+                }
+              }
+            }
+          ],
+          "description": "Directs the executor to skip this field or fragment when the \"if\" argument is true.",
+          "locations": [
+            "FIELD",
+            "FRAGMENT_SPREAD",
+            "INLINE_FRAGMENT"
+          ],
+          "name": "skip"
+        }
+      ],
       "mutationType": {
         "name": "__mutation"
       },
@@ -405,13 +456,9 @@ func TestGraphy_Introspection_Schema(t *testing.T) {
           "inputFields": [],
           "interfaces": [
             {
-              "kind": "NON_NULL",
-              "name": "required",
-              "ofType": {
-                "kind": "OBJECT",
-                "name": "Character",
-                "ofType": null
-              }
+              "kind": "OBJECT",
+              "name": "Character",
+              "ofType": null
             }
           ],
           "kind": "OBJECT",
@@ -619,13 +666,9 @@ func TestGraphy_Introspection_Schema(t *testing.T) {
           "inputFields": [],
           "interfaces": [
             {
-              "kind": "NON_NULL",
-              "name": "required",
-              "ofType": {
-                "kind": "OBJECT",
-                "name": "Character",
-                "ofType": null
-              }
+              "kind": "OBJECT",
+              "name": "Character",
+              "ofType": null
             }
           ],
           "kind": "OBJECT",
@@ -961,7 +1004,58 @@ func TestGraphy_Introspection_Deprecation(t *testing.T) {
 	expected := `{
   "data": {
     "__schema": {
-      "directives": [],
+      "directives": [
+        {
+          "args": [
+            {
+              "defaultValue": null,
+              "description": null,
+              "name": "if",
+              "type": {
+                "kind": "NON_NULL",
+                "name": "required",
+                "ofType": {
+                  "kind": "SCALAR",
+                  "name": "Boolean",
+                  "ofType": null
+                }
+              }
+            }
+          ],
+          "description": "Directs the executor to include this field or fragment only when the \"if\" argument is true.",
+          "locations": [
+            "FIELD",
+            "FRAGMENT_SPREAD",
+            "INLINE_FRAGMENT"
+          ],
+          "name": "include"
+        },
+        {
+          "args": [
+            {
+              "defaultValue": null,
+              "description": null,
+              "name": "if",
+              "type": {
+                "kind": "NON_NULL",
+                "name": "required",
+                "ofType": {
+                  "kind": "SCALAR",
+                  "name": "Boolean",
+                  "ofType": null
+                }
+              }
+            }
+          ],
+          "description": "Directs the executor to skip this field or fragment when the \"if\" argument is true.",
+          "locations": [
+            "FIELD",
+            "FRAGMENT_SPREAD",
+            "INLINE_FRAGMENT"
+          ],
+          "name": "skip"
+        }
+      ],
       "mutationType": {
         "name": "__mutation"
       },
@@ -1154,7 +1248,58 @@ func TestGraphy_Introspection_Interface(t *testing.T) {
 	expected := `{
   "data": {
     "__schema": {
-      "directives": [],
+      "directives": [
+        {
+          "args": [
+            {
+              "defaultValue": null,
+              "description": null,
+              "name": "if",
+              "type": {
+                "kind": "NON_NULL",
+                "name": "required",
+                "ofType": {
+                  "kind": "SCALAR",
+                  "name": "Boolean",
+                  "ofType": null
+                }
+              }
+            }
+          ],
+          "description": "Directs the executor to include this field or fragment only when the \"if\" argument is true.",
+          "locations": [
+            "FIELD",
+            "FRAGMENT_SPREAD",
+            "INLINE_FRAGMENT"
+          ],
+          "name": "include"
+        },
+        {
+          "args": [
+            {
+              "defaultValue": null,
+              "description": null,
+              "name": "if",
+              "type": {
+                "kind": "NON_NULL",
+                "name": "required",
+                "ofType": {
+                  "kind": "SCALAR",
+                  "name": "Boolean",
+                  "ofType": null
+                }
+              }
+            }
+          ],
+          "description": "Directs the executor to skip this field or fragment when the \"if\" argument is true.",
+          "locations": [
+            "FIELD",
+            "FRAGMENT_SPREAD",
+            "INLINE_FRAGMENT"
+          ],
+          "name": "skip"
+        }
+      ],
       "mutationType": {
         "name": "__mutation"
       },
@@ -1433,13 +1578,9 @@ func TestGraphy_Introspection_Interface(t *testing.T) {
           "inputFields": [],
           "interfaces": [
             {
-              "kind": "NON_NULL",
-              "name": "required",
-              "ofType": {
-                "kind": "INTERFACE",
-                "name": "Character",
-                "ofType": null
-              }
+              "kind": "INTERFACE",
+              "name": "Character",
+              "ofType": null
             }
           ],
           "kind": "OBJECT",
@@ -1628,6 +1769,43 @@ func TestGraphy_Introspection_Interface(t *testing.T) {
 	assert.Equal(t, expected, formatted)
 }
 
+func TestGraphy_IntrospectionJSON(t *testing.T) {
+	g := Graphy{}
+	ctx := context.Background()
+
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name: "search",
+		Function: func(search string) []SearchResultUnion {
+			return nil
+		},
+		Mode:           ModeQuery,
+		ParameterNames: []string{"search"},
+	})
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name: "sample",
+		Function: func(input string) any {
+			return Droid{}
+		},
+		Mode:              ModeQuery,
+		ParameterNames:    []string{"input"},
+		ReturnAnyOverride: []any{Character{}},
+	})
+	g.RegisterMutation(ctx, "episode", func(ctx context.Context, e episode) episode {
+		return e
+	}, "episode")
+	g.RegisterTypes(ctx, Droid{}, Character{})
+
+	// This works without introspection being enabled.
+	exported, err := g.IntrospectionJSON(ctx)
+	assert.NoError(t, err)
+
+	g.EnableIntrospection(ctx)
+	queried, err := g.ProcessRequest(ctx, fullIntrospectionQuery, "")
+	assert.NoError(t, err)
+
+	assert.JSONEq(t, queried, string(exported))
+}
+
 func TestIntrospectionScalarName_WithBoolType(t *testing.T) {
 	tl := &typeLookup{rootType: reflect.TypeOf(true)}
 	result := introspectionScalarName(tl)
@@ -1658,3 +1836,87 @@ func TestIntrospectionScalarName_WithUnknownType(t *testing.T) {
 		introspectionScalarName(tl)
 	})
 }
+
+func TestGraphy_Introspection_NestedArrays(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "cells", func() [][]*matrixCell {
+		return [][]*matrixCell{{{Value: 1}, nil}, nil}
+	})
+	g.RegisterQuery(ctx, "optionalCells", func() *[]*matrixCell {
+		return nil
+	})
+	g.RegisterQuery(ctx, "cube", func() [][][]string {
+		return [][][]string{{{"a", "b"}}}
+	})
+	g.RegisterQuery(ctx, "rows", func() []*[]*matrixCell {
+		row := []*matrixCell{{Value: 2}}
+		return []*[]*matrixCell{&row, nil}
+	})
+	g.RegisterQuery(ctx, "matrix", func() matrix {
+		one := []int{1}
+		return matrix{Rows: [][]*matrixCell{{{Value: 3}}}, Sparse: &[]*[]int{&one, nil}}
+	})
+	g.RegisterQuery(ctx, "sum", func(m [][]*int) int {
+		sum := 0
+		for _, row := range m {
+			for _, v := range row {
+				if v != nil {
+					sum += *v
+				}
+			}
+		}
+		return sum
+	}, "m")
+	g.RegisterQuery(ctx, "count", func(m *[]*[]int) int {
+		count := 0
+		for _, row := range *m {
+			if row != nil {
+				count += len(*row)
+			}
+		}
+		return count
+	}, "m")
+	g.EnableIntrospection(ctx)
+
+	// The names of the scalars are the ones introspection reports for Go types.
+	types := map[string]string{}
+	for _, op := range g.Schema().Operations() {
+		types[op.Name] = op.Type
+		for _, arg := range op.Args {
+			types[op.Name+"."+arg.Name] = arg.Type
+		}
+	}
+	for _, f := range g.Schema().Fields("matrix") {
+		types["matrix."+f.Name] = f.Type
+	}
+	assert.Equal(t, map[string]string{
+		"cells":         "[[matrixCell]!]!",
+		"count":         "int!",
+		"count.m":       "[[int!]]",
+		"cube":          "[[[string!]!]!]!",
+		"matrix":        "matrix!",
+		"matrix.Rows":   "[[matrixCell]!]!",
+		"matrix.Sparse": "[[int!]]",
+		"optionalCells": "[matrixCell]",
+		"rows":          "[[matrixCell]]!",
+		"sum":           "int!",
+		"sum.m":         "[[Int]!]!",
+	}, types)
+
+	query := `{
+  __type(name: "matrix") {
+    fields {
+      name
+      type { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } } }
+    }
+  }
+}`
+	result, err := g.ProcessRequest(ctx, query, "")
+	assert.NoError(t, err)
+	expected := `{"data":{"__type":{"fields":[` +
+		`{"name":"Rows","type":{"kind":"NON_NULL","name":"required","ofType":{"kind":"LIST","name":"list","ofType":{"kind":"NON_NULL","name":"required","ofType":{"kind":"LIST","name":"list","ofType":{"kind":"OBJECT","name":"matrixCell"}}}}}},` +
+		`{"name":"Sparse","type":{"kind":"LIST","name":"list","ofType":{"kind":"LIST","name":"list","ofType":{"kind":"NON_NULL","name":"required","ofType":{"kind":"SCALAR","name":"int","ofType":null}}}}}` +
+		`]}}}`
+	assert.Equal(t, expected, result)
+}
@@ -0,0 +1,331 @@
+package quickgraph
+
+import (
+	"fmt"
+)
+
+// QueryLimits holds limits that are enforced on the requests that are processed. These
+// are a defense against overly expensive requests, especially when the service is
+// exposed to untrusted clients. A zero value for any of the limits means that the limit
+// is not enforced.
+//
+// The limits are checked when the request is parsed. If a RequestCache is in use, the
+// result of the check is cached along with the rest of the request.
+type QueryLimits struct {
+	// MaxDepth is the maximum nesting depth of the selections of a request. A selection
+	// directly on a query or mutation, such as `hero { name }`, has a depth of 1.
+	MaxDepth int
+
+	// IntrospectionMaxDepth is the maximum depth that applies to introspection commands
+	// (`__schema` and `__type`) instead of MaxDepth. The standard introspection query nests
+	// type references many levels deep, so enforcing a strict MaxDepth on it breaks
+	// tools that rely on introspection. If this is zero, DefaultIntrospectionMaxDepth is
+	// used, which allows the standard query but not arbitrarily deep ones.
+	IntrospectionMaxDepth int
+
+	// MaxConcurrentResolvers is the maximum number of additional goroutines that a single
+	// request may use to generate the results for the elements of a list. This is used when
+	// the elements of the list have fields that are implemented by functions, which may be
+	// expensive to call. The order of the elements is preserved. If this is zero or one, the
+	// elements are processed sequentially.
+	MaxConcurrentResolvers int
+
+	// MaxConcurrentCommands is the maximum number of commands of a query that are
+	// executed at the same time. Each command is executed in its own goroutine, so this
+	// bounds the number of goroutines that a request with many commands can use. If this
+	// is zero, all the commands of a query are executed at the same time. Mutations are
+	// always executed one at a time.
+	MaxConcurrentCommands int
+
+	// MaxEstimatedResponse is the maximum estimated number of values in the response to a
+	// request. The estimate is made before the request is run, from the selected fields and
+	// the number of elements that lists are expected to have. This rejects requests that
+	// would produce very large responses before any work is done for them. Introspection
+	// commands are not included in the estimate.
+	MaxEstimatedResponse int
+
+	// DefaultListSize is the number of elements that a list is assumed to have when
+	// estimating the size of a response if the list doesn't declare its own size. Struct
+	// fields declare the size with a `graphy:"listSize=N"` tag, and functions with
+	// FunctionDefinition.ListSize. If this is zero, lists are assumed to have 10 elements.
+	DefaultListSize int
+}
+
+// defaultEstimatedListSize is the number of elements that lists are assumed to have when
+// neither the list nor the QueryLimits specify one.
+const defaultEstimatedListSize = 10
+
+// queryLimitError is the error for a request that exceeds the QueryLimits, so that the
+// rejections can be counted.
+type queryLimitError struct {
+	GraphError
+}
+
+func (e queryLimitError) Unwrap() error {
+	return e.GraphError
+}
+
+// responseSizeError is the error for a request whose estimated response size goes over
+// the maximum with the command.
+func responseSizeError(maximum int, command command) error {
+	return queryLimitError{NewGraphError(fmt.Sprintf("estimated response size exceeds the maximum of %d values", maximum), command.Pos, command.Name)}
+}
+
+// DefaultIntrospectionMaxDepth is the depth limit of introspection commands if
+// QueryLimits.IntrospectionMaxDepth isn't set. The standard introspection query has a
+// depth of 12.
+const DefaultIntrospectionMaxDepth = 15
+
+// isIntrospectionCommand reports whether the command is one of the introspection
+// commands, as opposed to an operation that is registered with a name that starts with
+// "__".
+func isIntrospectionCommand(name string) bool {
+	return name == "__schema" || name == "__type"
+}
+
+// validateRequestLimits checks a parsed request against the QueryLimits of the Graphy
+// instance, if any, and returns an error if a limit is exceeded.
+func (g *Graphy) validateRequestLimits(parsedCall *wrapper, fragments map[string]fragment) error {
+	limits := g.QueryLimits
+	if limits == nil {
+		return nil
+	}
+
+	estimatedSize := 0
+	for _, command := range parsedCall.Commands {
+		if limits.MaxEstimatedResponse > 0 && !isIntrospectionCommand(command.Name) {
+			estimator := g.newSizeEstimator(fragments, limits.MaxEstimatedResponse+1)
+			estimatedSize = estimator.add(estimatedSize, estimator.command(command))
+			if estimatedSize > limits.MaxEstimatedResponse {
+				return responseSizeError(limits.MaxEstimatedResponse, command)
+			}
+		}
+
+		maxDepth := limits.MaxDepth
+		if isIntrospectionCommand(command.Name) {
+			maxDepth = limits.IntrospectionMaxDepth
+			if maxDepth <= 0 {
+				maxDepth = DefaultIntrospectionMaxDepth
+			}
+		}
+		if maxDepth <= 0 {
+			continue
+		}
+		depth := selectionDepth(command.ResultFilter, fragments, map[string]bool{}, map[string]int{})
+		if depth > maxDepth {
+			return queryLimitError{NewGraphError(fmt.Sprintf("query depth %d exceeds the maximum depth of %d", depth, maxDepth), command.Pos, command.Name)}
+		}
+	}
+	return nil
+}
+
+// selectionDepth returns the maximum nesting depth of a result filter. Fragments do not
+// add to the depth, their fields are counted as if they were directly in the filter. The
+// visiting map protects against fragments that refer to themselves, and the depths map
+// remembers the depth of each fragment so that fragments that are spread many times
+// are only walked once.
+func selectionDepth(filter *resultFilter, fragments map[string]fragment, visiting map[string]bool, depths map[string]int) int {
+	if filter == nil {
+		return 0
+	}
+
+	depth := 0
+	for _, field := range filter.Fields {
+		fieldDepth := 1 + selectionDepth(field.SubParts, fragments, visiting, depths)
+		if fieldDepth > depth {
+			depth = fieldDepth
+		}
+	}
+
+	for _, fragmentCall := range filter.Fragments {
+		var fragmentDepth int
+		if fragmentCall.Inline != nil {
+			fragmentDepth = selectionDepth(fragmentCall.Inline.Filter, fragments, visiting, depths)
+		} else if fragmentCall.FragmentRef != nil {
+			name := *fragmentCall.FragmentRef
+			frag, ok := fragments[name]
+			if !ok || visiting[name] {
+				continue
+			}
+			if known, ok := depths[name]; ok {
+				fragmentDepth = known
+			} else {
+				visiting[name] = true
+				fragmentDepth = selectionDepth(frag.Definition.Filter, fragments, visiting, depths)
+				delete(visiting, name)
+				depths[name] = fragmentDepth
+			}
+		}
+		if fragmentDepth > depth {
+			depth = fragmentDepth
+		}
+	}
+
+	return depth
+}
+
+// sizeEstimator estimates the number of values in the response to a request, assuming
+// that every list has as many elements as it is expected to have at most. The counts
+// saturate at the limit so that deeply nested lists don't overflow. The estimates of the
+// fragments are remembered by type, so that fragments that are spread many times are
+// only walked once.
+type sizeEstimator struct {
+	g         *Graphy
+	fragments map[string]fragment
+	visiting  map[string]bool
+	estimates map[fragmentEstimateKey]int
+	listSize  int
+	limit     int
+}
+
+// fragmentEstimateKey identifies the estimate of a named fragment applied to a type.
+type fragmentEstimateKey struct {
+	name string
+	typ  *typeLookup
+}
+
+// newSizeEstimator creates a sizeEstimator that uses the DefaultListSize of the QueryLimits,
+// if any, and saturates at the limit.
+func (g *Graphy) newSizeEstimator(fragments map[string]fragment, limit int) *sizeEstimator {
+	e := &sizeEstimator{
+		g:         g,
+		fragments: fragments,
+		visiting:  map[string]bool{},
+		estimates: map[fragmentEstimateKey]int{},
+		listSize:  defaultEstimatedListSize,
+		limit:     limit,
+	}
+	if g.QueryLimits != nil && g.QueryLimits.DefaultListSize > 0 {
+		e.listSize = g.QueryLimits.DefaultListSize
+	}
+	return e
+}
+
+// estimateResponseSize returns the estimated number of values in the response to the
+// request, not counting introspection commands. The estimate saturates at the limit, and
+// if it reaches it, the command with which it did is returned too.
+func (g *Graphy) estimateResponseSize(rs *RequestStub, limit int) (int, *command) {
+	e := g.newSizeEstimator(rs.fragments, limit)
+	size := 0
+	for i, command := range rs.commands {
+		if !isIntrospectionCommand(command.Name) {
+			size = e.add(size, e.command(command))
+			if size >= limit {
+				return size, &rs.commands[i]
+			}
+		}
+	}
+	return size, nil
+}
+
+// command returns the estimated number of values in the result of a command.
+func (e *sizeEstimator) command(command command) int {
+	gf := e.g.processors[command.Name]
+	return e.value(gf.baseReturnType, gf.listSize, command.ResultFilter)
+}
+
+// value returns the estimated number of values for a value of the given type with the
+// selection applied to it. Each level of list nesting multiplies the count by the list
+// size, which is the declared size if there is one.
+func (e *sizeEstimator) value(typ *typeLookup, listSize int, filter *resultFilter) int {
+	count := e.add(1, e.selection(typ, filter))
+	if typ == nil {
+		return count
+	}
+	if listSize <= 0 {
+		listSize = e.listSize
+	}
+	for array := typ.array; array != nil; array = array.array {
+		count = e.mul(count, listSize)
+	}
+	return count
+}
+
+// selection returns the estimated number of values for the fields and fragments of a
+// selection. Fields that don't exist are counted as a single value; they are rejected
+// when the request is validated. All the fragments that can apply to the type are
+// counted, as any of them may be used for a given value.
+func (e *sizeEstimator) selection(typ *typeLookup, filter *resultFilter) int {
+	if typ == nil || filter == nil {
+		return 0
+	}
+
+	count := 0
+	for _, field := range filter.Fields {
+		pf, ok := e.field(typ, field.Name)
+		if !ok {
+			count = e.add(count, 1)
+			continue
+		}
+		var childType *typeLookup
+		if pf.fieldType == FieldTypeGraphFunction {
+			childType = pf.graphFunction.baseReturnType
+		} else {
+			childType = e.g.typeLookup(pf.resultType)
+		}
+		count = e.add(count, e.value(childType, pf.listSize, field.SubParts))
+	}
+
+	for _, fragmentCall := range filter.Fragments {
+		if fragmentCall.Inline != nil {
+			count = e.add(count, e.fragment(typ, fragmentCall.Inline))
+		} else if fragmentCall.FragmentRef != nil {
+			name := *fragmentCall.FragmentRef
+			frag, ok := e.fragments[name]
+			if !ok || e.visiting[name] {
+				continue
+			}
+			key := fragmentEstimateKey{name: name, typ: typ}
+			estimate, known := e.estimates[key]
+			if !known {
+				e.visiting[name] = true
+				estimate = e.fragment(typ, frag.Definition)
+				delete(e.visiting, name)
+				e.estimates[key] = estimate
+			}
+			count = e.add(count, estimate)
+		}
+	}
+
+	return count
+}
+
+// fragment returns the estimated number of values for a fragment, if it can apply to the
+// type.
+func (e *sizeEstimator) fragment(typ *typeLookup, def *fragmentDef) int {
+	if found, subTyp := typ.ImplementsInterface(def.TypeName); found {
+		return e.selection(subTyp, def.Filter)
+	}
+	return 0
+}
+
+// field finds a field of a type. For unions, which don't have fields of their own, the
+// field is looked up in the members.
+func (e *sizeEstimator) field(typ *typeLookup, name string) (fieldLookup, bool) {
+	if pf, ok := typ.GetField(name); ok {
+		return pf, true
+	}
+	for _, memberName := range sortedKeys(typ.union) {
+		if pf, ok := typ.union[memberName].GetField(name); ok {
+			return pf, true
+		}
+	}
+	return fieldLookup{}, false
+}
+
+func (e *sizeEstimator) add(a, b int) int {
+	if a+b > e.limit {
+		return e.limit
+	}
+	return a + b
+}
+
+func (e *sizeEstimator) mul(a, b int) int {
+	if b != 0 && a > e.limit/b {
+		return e.limit
+	}
+	if a*b > e.limit {
+		return e.limit
+	}
+	return a * b
+}
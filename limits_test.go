@@ -0,0 +1,287 @@
+package quickgraph
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type depthTestNode struct {
+	Name  string
+	Child *depthTestNode
+}
+
+func TestQueryLimits_MaxDepth(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{QueryLimits: &QueryLimits{MaxDepth: 2}}
+	g.RegisterQuery(ctx, "node", func() depthTestNode {
+		return depthTestNode{Name: "a", Child: &depthTestNode{Name: "b", Child: &depthTestNode{Name: "c"}}}
+	})
+
+	result, err := g.ProcessRequest(ctx, `{ node { Name Child { Name } } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"node":{"Child":{"Name":"b"},"Name":"a"}}}`, result)
+
+	result, err = g.ProcessRequest(ctx, `{ node { Name Child { Name Child { Name } } } }`, "")
+	assert.Error(t, err)
+	assert.Equal(t, `{"errors":[{"message":"query depth 3 exceeds the maximum depth of 2","locations":[{"line":1,"column":3}],"path":["node"]}]}`, result)
+
+	// Fragments are counted at the depth they are used.
+	query := `{ node { ...NodeFields } }
+fragment NodeFields on depthTestNode { Child { Child { Name } } }`
+	_, err = g.ProcessRequest(ctx, query, "")
+	assert.Error(t, err)
+}
+
+func TestQueryLimits_IntrospectionExempt(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{QueryLimits: &QueryLimits{MaxDepth: 1}}
+	g.RegisterQuery(ctx, "node", func() depthTestNode {
+		return depthTestNode{Name: "a"}
+	})
+	g.EnableIntrospection(ctx)
+
+	_, err := g.ProcessRequest(ctx, fullIntrospectionQuery, "")
+	assert.NoError(t, err)
+
+	g.QueryLimits.IntrospectionMaxDepth = 3
+	_, err = g.ProcessRequest(ctx, fullIntrospectionQuery, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds the maximum depth of 3")
+}
+
+func TestQueryLimits_IntrospectionDefaultDepth(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{QueryLimits: &QueryLimits{MaxDepth: 2}}
+	g.RegisterQuery(ctx, "__node", func() depthTestNode {
+		return depthTestNode{Name: "a", Child: &depthTestNode{Name: "b", Child: &depthTestNode{Name: "c"}}}
+	})
+	g.EnableIntrospection(ctx)
+
+	// Operations that are registered with names that start with __ aren't introspection.
+	_, err := g.ProcessRequest(ctx, `{ __node { Child { Child { Name } } } }`, "")
+	assert.ErrorContains(t, err, "query depth 3 exceeds the maximum depth of 2")
+
+	// Introspection is limited to DefaultIntrospectionMaxDepth, which is enough for the
+	// standard query, but not for ones that nest deeper.
+	_, err = g.ProcessRequest(ctx, fullIntrospectionQuery, "")
+	assert.NoError(t, err)
+	query := "{ __schema { types { fields { type" + strings.Repeat(" { ofType", 12) + " { name }" + strings.Repeat(" }", 12) + " } } } }"
+	_, err = g.ProcessRequest(ctx, query, "")
+	assert.ErrorContains(t, err, "query depth 16 exceeds the maximum depth of 15")
+}
+
+func TestSelectionDepth_RecursiveFragment(t *testing.T) {
+	query := `{ node { ...A } }
+fragment A on depthTestNode { Child { ...A } }`
+	parsed, err := parseRequest(query)
+	assert.NoError(t, err)
+	fragments := map[string]fragment{}
+	for _, f := range parsed.Fragments {
+		fragments[f.Name] = f
+	}
+	assert.Equal(t, 1, selectionDepth(parsed.Commands[0].ResultFilter, fragments, map[string]bool{}, map[string]int{}))
+}
+
+func TestQueryLimits_FragmentFanOut(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{QueryLimits: &QueryLimits{MaxDepth: 5, MaxEstimatedResponse: 1000}}
+	g.RegisterQuery(ctx, "node", func() depthTestNode {
+		return depthTestNode{Name: "a"}
+	})
+
+	// Each fragment spreads the next one four times, so expanding the fragments every time
+	// they are used would take 4^30 steps.
+	sb := strings.Builder{}
+	sb.WriteString("{ node { ...F0 } }\n")
+	for i := 0; i < 30; i++ {
+		sb.WriteString(fmt.Sprintf("fragment F%d on depthTestNode { Name", i))
+		if i < 29 {
+			sb.WriteString(strings.Repeat(fmt.Sprintf(" ...F%d", i+1), 4))
+		}
+		sb.WriteString(" }\n")
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := g.ProcessRequest(ctx, sb.String(), "")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		assert.ErrorContains(t, err, "estimated response size exceeds the maximum of 1000 values")
+	case <-time.After(5 * time.Second):
+		t.Fatal("the limits weren't checked in time")
+	}
+
+	parsed, err := parseRequest(sb.String())
+	assert.NoError(t, err)
+	fragments := map[string]fragment{}
+	for _, f := range parsed.Fragments {
+		fragments[f.Name] = f
+	}
+	assert.Equal(t, 1, selectionDepth(parsed.Commands[0].ResultFilter, fragments, map[string]bool{}, map[string]int{}))
+}
+
+type concurrentTestItem struct {
+	ID int
+
+	active    *int32
+	maxActive *int32
+}
+
+func (c concurrentTestItem) Expensive() int {
+	active := atomic.AddInt32(c.active, 1)
+	for {
+		prevMax := atomic.LoadInt32(c.maxActive)
+		if active <= prevMax || atomic.CompareAndSwapInt32(c.maxActive, prevMax, active) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	atomic.AddInt32(c.active, -1)
+	return c.ID * 10
+}
+
+func TestQueryLimits_MaxConcurrentResolvers(t *testing.T) {
+	ctx := context.Background()
+
+	var active, maxActive int32
+	g := Graphy{QueryLimits: &QueryLimits{MaxConcurrentResolvers: 4}}
+	g.RegisterQuery(ctx, "items", func() []concurrentTestItem {
+		var items []concurrentTestItem
+		for i := 0; i < 20; i++ {
+			items = append(items, concurrentTestItem{ID: i, active: &active, maxActive: &maxActive})
+		}
+		return items
+	})
+	result, err := g.ProcessRequest(ctx, `{ items { ID Expensive } }`, "")
+	assert.NoError(t, err)
+
+	var expected []string
+	for i := 0; i < 20; i++ {
+		expected = append(expected, fmt.Sprintf(`{"Expensive":%d,"ID":%d}`, i*10, i))
+	}
+	assert.Equal(t, `{"data":{"items":[`+strings.Join(expected, ",")+`]}}`, result)
+	assert.Greater(t, maxActive, int32(1))
+	// The slots bound the additional goroutines, the current goroutine may also be working.
+	assert.LessOrEqual(t, maxActive, int32(5))
+
+	// Without the limit, the elements are processed one at a time.
+	maxActive = 0
+	g.QueryLimits = nil
+	_, err = g.ProcessRequest(ctx, `{ items { ID Expensive } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), maxActive)
+}
+
+func TestSelectsGraphFunctions(t *testing.T) {
+	g := &Graphy{}
+	tl := g.typeLookup(reflect.TypeOf(concurrentTestItem{}))
+
+	parsed, err := parseRequest(`{ items { ID } }`)
+	assert.NoError(t, err)
+	assert.False(t, g.selectsGraphFunctions(tl, parsed.Commands[0].ResultFilter, nil))
+
+	parsed, err = parseRequest(`{ items { ID Expensive } }`)
+	assert.NoError(t, err)
+	assert.True(t, g.selectsGraphFunctions(tl, parsed.Commands[0].ResultFilter, nil))
+}
+
+func TestQueryLimits_MaxConcurrentCommands(t *testing.T) {
+	ctx := context.Background()
+
+	var active, maxActive int32
+	g := Graphy{QueryLimits: &QueryLimits{MaxConcurrentCommands: 2}}
+	g.RegisterQuery(ctx, "work", func(id int) int {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			m := atomic.LoadInt32(&maxActive)
+			if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&active, -1)
+		return id
+	}, "id")
+
+	var query strings.Builder
+	var expected []string
+	query.WriteString("{")
+	for i := 0; i < 6; i++ {
+		query.WriteString(fmt.Sprintf(" w%d: work(id: %d)", i, i))
+		expected = append(expected, fmt.Sprintf(`"w%d":%d`, i, i))
+	}
+	query.WriteString(" }")
+
+	result, err := g.ProcessRequest(ctx, query.String(), "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{`+strings.Join(expected, ",")+`}}`, result)
+	assert.Equal(t, int32(2), maxActive)
+}
+
+type sizeTestItem struct {
+	Name string
+	Tags []string `graphy:"listSize=3"`
+}
+
+func TestQueryLimits_MaxEstimatedResponse(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{QueryLimits: &QueryLimits{MaxEstimatedResponse: 20}}
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name: "items",
+		Function: func() []sizeTestItem {
+			return []sizeTestItem{{Name: "a", Tags: []string{"x"}}}
+		},
+		ListSize: 5,
+	})
+	g.RegisterQuery(ctx, "all", func() []sizeTestItem {
+		return []sizeTestItem{{Name: "a"}}
+	})
+	g.EnableIntrospection(ctx)
+
+	// 5 items, each with a name: 10 values.
+	result, err := g.ProcessRequest(ctx, `{ items { Name } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"items":[{"Name":"a"}]}}`, result)
+
+	// Without a declared size, lists are assumed to have 10 elements: 20 values.
+	_, err = g.ProcessRequest(ctx, `{ all { Name } }`, "")
+	assert.NoError(t, err)
+
+	// 5 items, each with a name and 3 tags: 25 values.
+	result, err = g.ProcessRequest(ctx, `{ items { Name Tags } }`, "")
+	assert.Error(t, err)
+	assert.Equal(t, `{"errors":[{"message":"estimated response size exceeds the maximum of 20 values","locations":[{"line":1,"column":3}],"path":["items"]}]}`, result)
+
+	// The estimate covers all the commands of the request.
+	_, err = g.ProcessRequest(ctx, `{ items { Name } all { Name } }`, "")
+	assert.Error(t, err)
+
+	// Fragments are counted where they are used.
+	_, err = g.ProcessRequest(ctx, `{ items { ...ItemFields } }
+fragment ItemFields on sizeTestItem { Name Tags }`, "")
+	assert.Error(t, err)
+
+	// Introspection isn't part of the estimate.
+	_, err = g.ProcessRequest(ctx, fullIntrospectionQuery, "")
+	assert.NoError(t, err)
+
+	// With smaller lists by default: 2 items, each with a name and 3 tags.
+	g.QueryLimits.DefaultListSize = 2
+	_, err = g.ProcessRequest(ctx, `{ all { Name Tags } }`, "")
+	assert.NoError(t, err)
+}
+
+func TestSizeEstimator_Saturates(t *testing.T) {
+	e := sizeEstimator{limit: 100}
+	assert.Equal(t, 100, e.mul(1<<40, 1<<40))
+	assert.Equal(t, 100, e.add(60, 60))
+	assert.Equal(t, 12, e.mul(3, 4))
+}
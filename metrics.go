@@ -0,0 +1,57 @@
+package quickgraph
+
+import (
+	"context"
+	"time"
+)
+
+// MetricsCollector receives measurements of the work that a Graphy does, so they can be
+// exported to a monitoring system. The prometheus subpackage has an implementation. The
+// methods are called concurrently from the requests that are being processed, so they
+// must be safe for concurrent use and should return quickly.
+type MetricsCollector interface {
+	// RequestCompleted is called once for each request that is processed.
+	RequestCompleted(ctx context.Context, metrics RequestMetrics)
+
+	// ResolverCompleted is called each time a query, mutation, or field of a type is
+	// resolved. Queries and mutations are reported as fields of the "Query" and
+	// "Mutation" types.
+	ResolverCompleted(typeName, fieldName string, duration time.Duration, err error)
+
+	// RequestCacheLookup is called each time the RequestCache is consulted, with whether
+	// the request was found in it.
+	RequestCacheLookup(hit bool)
+}
+
+// RequestMetrics describes a request that was processed.
+type RequestMetrics struct {
+	// Operation is the name of the request as given by RequestStub.Name, or "" if the
+	// request couldn't be parsed.
+	Operation string
+
+	// Mutation is true if the request is a mutation.
+	Mutation bool
+
+	// Duration is how long it took to process the request, including parsing it.
+	Duration time.Duration
+
+	// Err is the error of the request, if it failed or some of its parts failed.
+	Err error
+}
+
+// measureResolvers reports whether the time it takes to resolve each field needs to be
+// measured.
+func (g *Graphy) measureResolvers() bool {
+	return g.EnableFieldStats || g.Metrics != nil
+}
+
+// recordResolver records the time it took to resolve a field for the field statistics
+// and the MetricsCollector, whichever are enabled.
+func (g *Graphy) recordResolver(typeName, fieldName string, duration time.Duration, err error) {
+	if g.EnableFieldStats {
+		g.recordFieldStats(typeName, fieldName, duration, err)
+	}
+	if g.Metrics != nil {
+		g.Metrics.ResolverCompleted(typeName, fieldName, duration, err)
+	}
+}
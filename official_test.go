@@ -659,3 +659,43 @@ query  {
 	assert.Error(t, err)
 	assert.Equal(t, `{"data":{},"errors":[{"message":"error getting call parameters for function hero: invalid enum value INVALID","locations":[{"line":3,"column":8}],"path":["hero"]}]}`, resultAny)
 }
+
+func TestUnionCommonFields(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "search", func(search string) []SearchResultUnion {
+		return []SearchResultUnion{
+			{Human: &Human{Character: Character{Name: "Luke"}, HeightMeters: 1.72}},
+			{Droid: &Droid{Character: Character{Name: "R2-D2"}, PrimaryFunction: "Astromech"}},
+			{Starship: &Starship{Name: "Falcon"}},
+		}
+	}, "search")
+
+	// Fields shared by all the members can be selected without a fragment.
+	result, err := g.ProcessRequest(ctx, `{
+  search(search: "x") {
+    __typename
+    name
+    ... on Droid { primaryFunction }
+  }
+}`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"search":[{"__typename":"Human","name":"Luke"},{"__typename":"Droid","name":"R2-D2","primaryFunction":"Astromech"},{"__typename":"Starship","name":"Falcon"}]}}`, result)
+
+	// Fields that some members don't have need a fragment.
+	result, err = g.ProcessRequest(ctx, `{
+  search(search: "x") {
+    HeightMeters
+  }
+}`, "")
+	assert.Error(t, err)
+	assert.Equal(t, `{"errors":[{"message":"field HeightMeters can't be selected directly on union SearchResult because it is not defined by Droid, Starship; select it in fragments such as `+"`... on Human`"+` instead","locations":[{"line":3,"column":5}],"path":["search"]}]}`, result)
+
+	result, err = g.ProcessRequest(ctx, `{
+  search(search: "x") {
+    bogus
+  }
+}`, "")
+	assert.Error(t, err)
+	assert.Equal(t, `{"errors":[{"message":"unknown field bogus on union SearchResult; it is not defined by any of the members Droid, Human, Starship","locations":[{"line":3,"column":5}],"path":["search"]}]}`, result)
+}
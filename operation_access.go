@@ -0,0 +1,84 @@
+package quickgraph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrorCodeUnauthorized is the code in the extensions of the errors for operations that
+// the caller isn't allowed to call.
+const ErrorCodeUnauthorized = "UNAUTHORIZED"
+
+// OperationAccess restricts which queries and mutations each role may call. It is a
+// coarse-grained alternative to checking permissions in each function: a request that
+// calls an operation that none of the roles of the caller allow is rejected as a whole,
+// before any function is called.
+//
+// Introspection isn't restricted; use EnableIntrospection to control whether it is
+// available.
+type OperationAccess struct {
+	// Roles returns the roles of the caller of a request, typically from values that
+	// authentication middleware put in the context. A nil Roles treats every caller as
+	// having no roles.
+	Roles func(ctx context.Context) []string
+
+	// Allowed maps each role to the names of the queries and mutations that it may call.
+	// The name "*" allows all of them.
+	Allowed map[string][]string
+
+	// Public are the names of the queries and mutations that anybody may call, including
+	// callers without any roles.
+	Public []string
+
+	// Allow, if set, decides whether a caller with the roles may call the operation. It is
+	// consulted for the operations that neither Public nor Allowed allow.
+	Allow func(ctx context.Context, roles []string, operation string) bool
+}
+
+// checkOperationAccess returns an error for each of the operations in the request that
+// the caller may not call.
+func (g *Graphy) checkOperationAccess(ctx context.Context, rs *RequestStub) error {
+	oa := g.OperationAccess
+	if oa == nil {
+		return nil
+	}
+
+	var roles []string
+	if oa.Roles != nil {
+		roles = oa.Roles(ctx)
+	}
+
+	var errs []error
+	for _, command := range rs.commands {
+		if strings.HasPrefix(command.Name, "__") || oa.allowed(ctx, roles, command.Name) {
+			continue
+		}
+		gErr := NewGraphError(fmt.Sprintf("not authorized to call %s", command.Name), command.Pos, command.Name)
+		gErr.AddExtension("code", ErrorCodeUnauthorized)
+		errs = append(errs, gErr)
+	}
+	return errors.Join(errs...)
+}
+
+func (oa *OperationAccess) allowed(ctx context.Context, roles []string, operation string) bool {
+	if containsOperation(oa.Public, operation) {
+		return true
+	}
+	for _, role := range roles {
+		if containsOperation(oa.Allowed[role], operation) {
+			return true
+		}
+	}
+	return oa.Allow != nil && oa.Allow(ctx, roles, operation)
+}
+
+func containsOperation(operations []string, operation string) bool {
+	for _, op := range operations {
+		if op == operation || op == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package quickgraph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type accessTestRolesKey struct{}
+
+func accessTestContext(roles ...string) context.Context {
+	return context.WithValue(context.Background(), accessTestRolesKey{}, roles)
+}
+
+func TestOperationAccess(t *testing.T) {
+	ctx := context.Background()
+	called := false
+	g := Graphy{OperationAccess: &OperationAccess{
+		Roles: func(ctx context.Context) []string {
+			roles, _ := ctx.Value(accessTestRolesKey{}).([]string)
+			return roles
+		},
+		Public: []string{"status"},
+		Allowed: map[string][]string{
+			"analyst": {"report"},
+			"admin":   {"*"},
+		},
+	}}
+	g.RegisterQuery(ctx, "status", func() string { return "ok" })
+	g.RegisterQuery(ctx, "report", func() string { return "numbers" })
+	g.RegisterMutation(ctx, "reset", func() string { return "done" })
+	g.RegisterMutation(ctx, "audit", func() string {
+		called = true
+		return "audited"
+	})
+	g.EnableIntrospection(ctx)
+
+	result, err := g.ProcessRequest(accessTestContext(), `{ status }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"status":"ok"}}`, result)
+
+	result, err = g.ProcessRequest(accessTestContext("analyst"), `{ status report }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"report":"numbers","status":"ok"}}`, result)
+
+	// Nothing is called if any of the operations isn't allowed.
+	result, err = g.ProcessRequest(accessTestContext("analyst"), `mutation { audit reset }`, "")
+	assert.Error(t, err)
+	assert.False(t, called)
+	assert.Equal(t, `{"errors":[`+
+		`{"message":"not authorized to call audit","locations":[{"line":1,"column":12}],"path":["audit"],"extensions":{"code":"UNAUTHORIZED"}},`+
+		`{"message":"not authorized to call reset","locations":[{"line":1,"column":18}],"path":["reset"],"extensions":{"code":"UNAUTHORIZED"}}]}`, result)
+
+	result, err = g.ProcessRequest(accessTestContext("reader", "admin"), `mutation { audit }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"audit":"audited"}}`, result)
+	assert.True(t, called)
+
+	// Introspection isn't restricted.
+	_, err = g.ProcessRequest(accessTestContext(), `{ __schema { queryType { name } } }`, "")
+	assert.NoError(t, err)
+}
+
+func TestOperationAccess_Allow(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{OperationAccess: &OperationAccess{
+		Allow: func(ctx context.Context, roles []string, operation string) bool {
+			return operation != "reset"
+		},
+	}}
+	g.RegisterQuery(ctx, "status", func() string { return "ok" })
+	g.RegisterQuery(ctx, "report", func() string { return "numbers" })
+	g.RegisterMutation(ctx, "reset", func() string { return "done" })
+
+	_, err := g.ProcessRequest(ctx, `{ status report }`, "")
+	assert.NoError(t, err)
+
+	_, err = g.ProcessRequest(ctx, `mutation { reset }`, "")
+	assert.ErrorContains(t, err, "not authorized to call reset")
+}
@@ -0,0 +1,73 @@
+package quickgraph
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// orderedMap is a map that serializes its keys to JSON in the order that they were
+// first set. It is used to build the results of a request when
+// Graphy.OrderedResults is set so that the fields appear in the order that they were
+// selected, as the GraphQL spec recommends.
+type orderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+func newOrderedMap() *orderedMap {
+	return &orderedMap{values: map[string]any{}}
+}
+
+// Set sets the value of the key. If the key is already present it keeps its original
+// position.
+func (m *orderedMap) Set(key string, value any) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	buf := bytes.Buffer{}
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// resultObject is what the fields of an object in the result are written to. It is
+// either a plain map, whose keys are serialized in sorted order, or an orderedMap.
+type resultObject interface {
+	Set(key string, value any)
+}
+
+// plainMap adapts a map to resultObject.
+type plainMap map[string]any
+
+func (m plainMap) Set(key string, value any) {
+	m[key] = value
+}
+
+// newResultObject returns an empty object for the results of a request, depending on
+// whether OrderedResults is set.
+func (g *Graphy) newResultObject() resultObject {
+	if g.OrderedResults {
+		return newOrderedMap()
+	}
+	return getResultMap()
+}
@@ -0,0 +1,79 @@
+package quickgraph
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestOrderedMap_MarshalJSON(t *testing.T) {
+	m := newOrderedMap()
+	m.Set("zebra", 1)
+	m.Set("apple", "a")
+	m.Set("mango", nil)
+	m.Set("zebra", 2)
+
+	b, err := json.Marshal(m)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"zebra":2,"apple":"a","mango":null}`, string(b))
+
+	b, err = json.Marshal(newOrderedMap())
+	assert.NoError(t, err)
+	assert.Equal(t, `{}`, string(b))
+}
+
+func TestGraphy_OrderedResults(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{OrderedResults: true}
+	g.RegisterQuery(ctx, "search", func(search string) []SearchResultUnion {
+		return []SearchResultUnion{
+			{Human: &Human{Character: Character{Name: "Luke"}, HeightMeters: 1.72}},
+			{Droid: &Droid{Character: Character{Name: "R2-D2"}, PrimaryFunction: "Astromech"}},
+		}
+	}, "search")
+	g.RegisterQuery(ctx, "hero", func() Character {
+		return Character{Name: "Han"}
+	})
+
+	query := `{
+  search(search: "x") {
+    __typename
+    ... on Droid { primaryFunction }
+    ... on Character { name }
+  }
+  hero { name }
+}`
+	result, err := g.ProcessRequest(ctx, query, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"search":[{"__typename":"Human","name":"Luke"},{"__typename":"Droid","primaryFunction":"Astromech","name":"R2-D2"}],"hero":{"name":"Han"}}}`, result)
+}
+
+func TestGraphy_OrderedResultsAliases(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{OrderedResults: true}
+	g.RegisterQuery(ctx, "hero", func() *Character {
+		return &Character{Name: "Luke", Friends: []*Character{{Name: "Han"}, {Name: "Leia"}}}
+	})
+
+	query := `{
+  second: hero { name }
+  hero {
+    kind: __typename
+    top: FriendsConnection(arg1: 1) { totalCount edges { node { friend: name } } }
+    name
+    all: FriendsConnection(arg1: 2) { edges { node { name } } }
+    ... on Character { name alias: name }
+  }
+}`
+	result, err := g.ProcessRequest(ctx, query, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"second":{"name":"Luke"},"hero":{"kind":"Character","top":{"totalCount":2,"edges":[{"node":{"friend":"Han"}}]},"name":"Luke","all":{"edges":[{"node":{"name":"Han"}},{"node":{"name":"Leia"}}]},"alias":"Luke"}}}`, result)
+
+	// Aliased fields are in the sorted results under their aliases as well.
+	g.OrderedResults = false
+	g.IncludeTypenames = true
+	result, err = g.ProcessRequest(ctx, `{ hero { kind: __typename alias: name } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"hero":{"__typename":"Character","alias":"Luke","kind":"Character"}}}`, result)
+}
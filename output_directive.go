@@ -0,0 +1,303 @@
+package quickgraph
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OutputDirective is a directive that clients can put on scalar fields to transform
+// their values, for instance:
+//
+//	{ article { publishedAt @formatDate(format: "2006-01-02") } }
+//
+// This gives clients some control over the formatting of values without having to add
+// fields for each format. See RegisterOutputDirective.
+type OutputDirective struct {
+	// Name is the name of the directive, without the "@".
+	Name string
+
+	// Description is the description of the directive in the schema.
+	Description string
+
+	// Handler transforms a value. It is a function of the form:
+	//
+	//	func([ctx context.Context,] value V[, args A]) (R[, error])
+	//
+	// V is the type of the values that the directive can be applied to. Pointers to V are
+	// dereferenced, and the elements of slices of V are transformed individually. A nil
+	// value is left alone. A, if present, is a struct whose fields are the arguments of
+	// the directive; like the fields of an input type, they are required unless they are
+	// pointers. The result R replaces the value in the response.
+	Handler any
+}
+
+type outputDirective struct {
+	name        string
+	description *string
+	handler     reflect.Value
+	hasContext  bool
+	valueType   reflect.Type
+	argsType    reflect.Type
+	hasError    bool
+}
+
+// RegisterOutputDirective registers a directive that clients can use to transform the
+// values of scalar fields. Directives are applied in the order they appear on a field,
+// each to the result of the previous one, after the FormatContext of the request has
+// been applied. They can't be applied to the queries and mutations themselves.
+//
+// Registering a directive with a handler that doesn't have the form described in
+// OutputDirective panics.
+func (g *Graphy) RegisterOutputDirective(ctx context.Context, directive OutputDirective) {
+	g.structureLock.Lock()
+	defer g.structureLock.Unlock()
+
+	od := newOutputDirective(directive)
+	if g.outputDirectives == nil {
+		g.outputDirectives = map[string]*outputDirective{}
+	}
+	g.outputDirectives[od.name] = od
+	g.invalidateSchema()
+}
+
+func newOutputDirective(directive OutputDirective) *outputDirective {
+	name := strings.TrimPrefix(directive.Name, "@")
+	if name == "" {
+		panic("output directive: name is required")
+	}
+
+	handler := reflect.ValueOf(directive.Handler)
+	if handler.Kind() != reflect.Func {
+		panic(fmt.Sprintf("output directive @%s: handler must be a function", name))
+	}
+	ht := handler.Type()
+
+	od := &outputDirective{
+		name:    name,
+		handler: handler,
+	}
+	if directive.Description != "" {
+		description := directive.Description
+		od.description = &description
+	}
+
+	in := 0
+	if in < ht.NumIn() && ht.In(in) == contextType {
+		od.hasContext = true
+		in++
+	}
+	if in >= ht.NumIn() {
+		panic(fmt.Sprintf("output directive @%s: handler must take the value to transform", name))
+	}
+	od.valueType = ht.In(in)
+	in++
+	if in < ht.NumIn() {
+		if ht.In(in).Kind() != reflect.Struct {
+			panic(fmt.Sprintf("output directive @%s: arguments must be a struct, not %v", name, ht.In(in)))
+		}
+		od.argsType = ht.In(in)
+		for _, field := range od.args() {
+			ft := field.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && ft != timeType {
+				panic(fmt.Sprintf("output directive @%s: argument %s must be a scalar", name, field.Name))
+			}
+		}
+		in++
+	}
+	if in < ht.NumIn() {
+		panic(fmt.Sprintf("output directive @%s: handler has too many parameters", name))
+	}
+
+	switch {
+	case ht.NumOut() == 1 && ht.Out(0) != errorType:
+	case ht.NumOut() == 2 && ht.Out(1) == errorType:
+		od.hasError = true
+	default:
+		panic(fmt.Sprintf("output directive @%s: handler must return a value and optionally an error", name))
+	}
+
+	return od
+}
+
+// applyOutputDirectives applies the output directives on a field to its value. Directives
+// that aren't registered are ignored.
+func (g *Graphy) applyOutputDirectives(ctx context.Context, req *request, field *resultField, value any) (any, error) {
+	for i := range field.Directives {
+		d := &field.Directives[i]
+		od, ok := g.outputDirectives[strings.TrimPrefix(d.Name, "@")]
+		if !ok {
+			continue
+		}
+		args, err := od.parseArgs(ctx, req, d)
+		if err != nil {
+			return nil, AugmentGraphError(err, fmt.Sprintf("error parsing arguments of %s", d.Name), d.Pos)
+		}
+		value, err = od.apply(ctx, args, reflect.ValueOf(value))
+		if err != nil {
+			return nil, AugmentGraphError(err, fmt.Sprintf("error applying %s", d.Name), d.Pos)
+		}
+	}
+	return value, nil
+}
+
+func (od *outputDirective) parseArgs(ctx context.Context, req *request, d *directive) (reflect.Value, error) {
+	if od.argsType == nil {
+		if d.Parameters != nil && len(d.Parameters.Values) > 0 {
+			return reflect.Value{}, fmt.Errorf("directive @%s has no arguments", od.name)
+		}
+		return reflect.Value{}, nil
+	}
+	return parseDirectiveArgs(ctx, req, d, od.argsType)
+}
+
+// apply transforms a single value, or each element of a slice of values.
+func (od *outputDirective) apply(ctx context.Context, args reflect.Value, value reflect.Value) (any, error) {
+	for {
+		if !value.IsValid() {
+			return nil, nil
+		}
+		if value.Type().AssignableTo(od.valueType) {
+			return od.call(ctx, args, value)
+		}
+		switch value.Kind() {
+		case reflect.Ptr, reflect.Interface:
+			if value.IsNil() {
+				return nil, nil
+			}
+			value = value.Elem()
+			continue
+		case reflect.Slice, reflect.Array:
+			if value.Kind() == reflect.Slice && value.IsNil() {
+				return nil, nil
+			}
+			results := make([]any, value.Len())
+			for i := range results {
+				r, err := od.apply(ctx, args, value.Index(i))
+				if err != nil {
+					return nil, err
+				}
+				results[i] = r
+			}
+			return results, nil
+		}
+		return nil, fmt.Errorf("directive @%s can't be applied to a value of type %v", od.name, value.Type())
+	}
+}
+
+func (od *outputDirective) call(ctx context.Context, args reflect.Value, value reflect.Value) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = nil
+			err = fmt.Errorf("directive @%s panicked: %v", od.name, r)
+		}
+	}()
+
+	in := make([]reflect.Value, 0, 3)
+	if od.hasContext {
+		in = append(in, reflect.ValueOf(ctx))
+	}
+	in = append(in, value)
+	if od.argsType != nil {
+		in = append(in, args)
+	}
+	out := od.handler.Call(in)
+	if od.hasError && !out[1].IsNil() {
+		return nil, out[1].Interface().(error)
+	}
+	return out[0].Interface(), nil
+}
+
+// validateOutputDirectives checks that the output directives on a field are used on a
+// scalar, and adds the variables that their arguments use.
+func (g *Graphy) validateOutputDirectives(field resultField, variableTypeMap map[string]*requestVariable) error {
+	for _, d := range field.Directives {
+		od, ok := g.outputDirectives[strings.TrimPrefix(d.Name, "@")]
+		if !ok {
+			continue
+		}
+		if field.SubParts != nil {
+			return NewGraphError(fmt.Sprintf("directive %s can only be applied to scalar fields", d.Name), d.Pos, field.Name)
+		}
+		if od.argsType != nil && d.Parameters != nil {
+			err := g.addNestedInputVariables(genericValue{Map: d.Parameters.Values}, od.argsType, variableTypeMap)
+			if err != nil {
+				return AugmentGraphError(err, fmt.Sprintf("error adding variables for %s", d.Name), d.Pos, field.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// args returns the arguments of a directive in the order of the fields of
+// its argument struct.
+func (od *outputDirective) args() []reflect.StructField {
+	return directiveArgs(od.argsType)
+}
+
+// sortedOutputDirectives returns the registered output directives sorted by name.
+func (g *Graphy) sortedOutputDirectives() []*outputDirective {
+	var result []*outputDirective
+	for _, od := range g.outputDirectives {
+		result = append(result, od)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].name < result[j].name
+	})
+	return result
+}
+
+// schemaForOutputDirectives writes the definitions of the output directives.
+func (g *Graphy) schemaForOutputDirectives() string {
+	sb := strings.Builder{}
+	for _, od := range g.sortedOutputDirectives() {
+		if od.description != nil {
+			sb.WriteString(strconv.Quote(*od.description))
+			sb.WriteString("\n")
+		}
+		sb.WriteString("directive @")
+		sb.WriteString(od.name)
+		if args := od.args(); len(args) > 0 {
+			sb.WriteString("(")
+			for i, field := range args {
+				if i > 0 {
+					sb.WriteString(", ")
+				}
+				name, _ := graphFieldName(field)
+				sb.WriteString(name)
+				sb.WriteString(": ")
+				sb.WriteString(g.schemaRefForType(g.typeLookup(field.Type), nil))
+			}
+			sb.WriteString(")")
+		}
+		sb.WriteString(" on FIELD\n\n")
+	}
+	return sb.String()
+}
+
+// introspectionOutputDirectives describes the output directives for introspection.
+func (g *Graphy) introspectionOutputDirectives(is *__Schema) []*__Directive {
+	var result []*__Directive
+	for _, od := range g.sortedOutputDirectives() {
+		d := &__Directive{
+			Name:        od.name,
+			Description: od.description,
+			Locations:   []string{"FIELD"},
+		}
+		for _, field := range od.args() {
+			name, _ := graphFieldName(field)
+			d.Args = append(d.Args, __InputValue{
+				Name: name,
+				Type: g.getIntrospectionModifiedType(is, g.typeLookup(field.Type), TypeInput),
+			})
+		}
+		result = append(result, d)
+	}
+	return result
+}
@@ -0,0 +1,215 @@
+package quickgraph
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+type directiveTestArticle struct {
+	Title       string
+	Tags        []string
+	PublishedAt time.Time
+	UpdatedAt   *time.Time
+	Author      directiveTestAuthor
+}
+
+type directiveTestAuthor struct {
+	Name string
+}
+
+type formatDateArgs struct {
+	Format string `graphy:"format"`
+}
+
+type truncateArgs struct {
+	Length int     `graphy:"length"`
+	Suffix *string `graphy:"suffix"`
+}
+
+func TestOutputDirectives(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "article", func() directiveTestArticle {
+		return directiveTestArticle{
+			Title:       "Matrices for Fun",
+			Tags:        []string{"math", "go"},
+			PublishedAt: time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC),
+			Author:      directiveTestAuthor{Name: "Ada"},
+		}
+	})
+	g.RegisterOutputDirective(ctx, OutputDirective{
+		Name:        "uppercase",
+		Description: "Converts a string to upper case.",
+		Handler:     strings.ToUpper,
+	})
+	g.RegisterOutputDirective(ctx, OutputDirective{
+		Name: "formatDate",
+		Handler: func(t time.Time, args formatDateArgs) string {
+			return t.Format(args.Format)
+		},
+	})
+	g.RegisterOutputDirective(ctx, OutputDirective{
+		Name: "truncate",
+		Handler: func(ctx context.Context, s string, args truncateArgs) (string, error) {
+			if args.Length == 0 {
+				return "", errors.New("length must be positive")
+			}
+			if len(s) <= args.Length {
+				return s, nil
+			}
+			suffix := "..."
+			if args.Suffix != nil {
+				suffix = *args.Suffix
+			}
+			return s[:args.Length] + suffix, nil
+		},
+	})
+
+	query := `{
+  article {
+    Title @uppercase
+    Tags @uppercase
+    PublishedAt @formatDate(format: "2006-01-02")
+    UpdatedAt @formatDate(format: "2006-01-02")
+    Author { Name @uppercase }
+  }
+}`
+	result, err := g.ProcessRequest(ctx, query, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"article":{"Author":{"Name":"ADA"},"PublishedAt":"2023-04-05","Tags":["MATH","GO"],"Title":"MATRICES FOR FUN","UpdatedAt":null}}}`, result)
+
+	// Directives are applied in order, and arguments can come from variables.
+	query = `query Get($length: Int!) { article { Title @truncate(length: $length) @uppercase } }`
+	result, err = g.ProcessRequest(ctx, query, `{"length": 8}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"article":{"Title":"MATRICES..."}}}`, result)
+
+	result, err = g.ProcessRequest(ctx, `{ article { Title @truncate(length: 3, suffix: "") } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"article":{"Title":"Mat"}}}`, result)
+
+	// Unknown directives are ignored.
+	result, err = g.ProcessRequest(ctx, `{ article { Title @unknown } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"article":{"Title":"Matrices for Fun"}}}`, result)
+}
+
+func TestOutputDirectives_Errors(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "article", func() directiveTestArticle {
+		return directiveTestArticle{
+			Title:       "Matrices for Fun",
+			Tags:        []string{"math", "go"},
+			PublishedAt: time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC),
+			Author:      directiveTestAuthor{Name: "Ada"},
+		}
+	})
+	g.RegisterOutputDirective(ctx, OutputDirective{
+		Name:        "uppercase",
+		Description: "Converts a string to upper case.",
+		Handler:     strings.ToUpper,
+	})
+	g.RegisterOutputDirective(ctx, OutputDirective{
+		Name: "truncate",
+		Handler: func(ctx context.Context, s string, args truncateArgs) (string, error) {
+			if args.Length == 0 {
+				return "", errors.New("length must be positive")
+			}
+			if len(s) <= args.Length {
+				return s, nil
+			}
+			suffix := "..."
+			if args.Suffix != nil {
+				suffix = *args.Suffix
+			}
+			return s[:args.Length] + suffix, nil
+		},
+	})
+
+	_, err := g.ProcessRequest(ctx, `{ article { Author @uppercase { Name } } }`, "")
+	assert.ErrorContains(t, err, "directive @uppercase can only be applied to scalar fields")
+
+	_, err = g.ProcessRequest(ctx, `{ article { PublishedAt @uppercase } }`, "")
+	assert.ErrorContains(t, err, "directive @uppercase can't be applied to a value of type time.Time")
+
+	_, err = g.ProcessRequest(ctx, `{ article { Title @truncate } }`, "")
+	assert.ErrorContains(t, err, "missing required fields: Length")
+
+	_, err = g.ProcessRequest(ctx, `{ article { Title @truncate(length: 0) } }`, "")
+	assert.ErrorContains(t, err, "length must be positive")
+
+	_, err = g.ProcessRequest(ctx, `{ article { Title @uppercase(loud: true) } }`, "")
+	assert.ErrorContains(t, err, "directive @uppercase has no arguments")
+
+	assert.Panics(t, func() {
+		g.RegisterOutputDirective(ctx, OutputDirective{Name: "bad", Handler: func() string { return "" }})
+	})
+	assert.Panics(t, func() {
+		g.RegisterOutputDirective(ctx, OutputDirective{Name: "bad", Handler: func(s string, format string) string { return s }})
+	})
+	assert.Panics(t, func() {
+		g.RegisterOutputDirective(ctx, OutputDirective{Name: "bad", Handler: func(s string) {}})
+	})
+}
+
+func TestOutputDirectives_Schema(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "article", func() directiveTestArticle {
+		return directiveTestArticle{
+			Title:       "Matrices for Fun",
+			Tags:        []string{"math", "go"},
+			PublishedAt: time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC),
+			Author:      directiveTestAuthor{Name: "Ada"},
+		}
+	})
+	g.RegisterOutputDirective(ctx, OutputDirective{
+		Name:        "uppercase",
+		Description: "Converts a string to upper case.",
+		Handler:     strings.ToUpper,
+	})
+	g.RegisterOutputDirective(ctx, OutputDirective{
+		Name: "formatDate",
+		Handler: func(t time.Time, args formatDateArgs) string {
+			return t.Format(args.Format)
+		},
+	})
+	g.RegisterOutputDirective(ctx, OutputDirective{
+		Name: "truncate",
+		Handler: func(ctx context.Context, s string, args truncateArgs) (string, error) {
+			if args.Length == 0 {
+				return "", errors.New("length must be positive")
+			}
+			if len(s) <= args.Length {
+				return s, nil
+			}
+			suffix := "..."
+			if args.Suffix != nil {
+				suffix = *args.Suffix
+			}
+			return s[:args.Length] + suffix, nil
+		},
+	})
+
+	schema := g.SchemaDefinition(ctx)
+	assert.Contains(t, schema, `directive @formatDate(format: String!) on FIELD
+
+directive @truncate(length: Int!, suffix: String) on FIELD
+
+"Converts a string to upper case."
+directive @uppercase on FIELD
+`)
+
+	directives := g.Schema().Directives()
+	if assert.Len(t, directives, 5) {
+		assert.Equal(t, "formatDate", directives[0].Name)
+		assert.Equal(t, []string{"FIELD"}, directives[0].Locations)
+		assert.Equal(t, []ArgInfo{{Name: "format", Type: "string!"}}, directives[0].Args)
+		assert.Equal(t, "Converts a string to upper case.", directives[4].Description)
+	}
+}
@@ -0,0 +1,280 @@
+package quickgraph
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// DefaultPageSize is the number of items in a page of a connection when the request
+// gives neither first nor last.
+const DefaultPageSize = 20
+
+// ConnectionArgs are the arguments of a field that returns a Relay connection. Functions
+// that return connections take them as their parameter:
+//
+//	func users(ctx context.Context, args quickgraph.ConnectionArgs) (quickgraph.Connection[User], error) {
+//		return quickgraph.Paginate[User](ctx, args, userPaginator)
+//	}
+type ConnectionArgs struct {
+	First  *int    `json:"first"`
+	After  *string `json:"after"`
+	Last   *int    `json:"last"`
+	Before *string `json:"before"`
+}
+
+// Connection is a page of a list in the form of a Relay connection. In the schema it is
+// named after the type of its nodes, so a Connection[User] is a UserConnection.
+type Connection[T any] struct {
+	Edges    []Edge[T] `json:"edges"`
+	PageInfo PageInfo  `json:"pageInfo"`
+}
+
+func (Connection[T]) GraphTypeExtension() GraphTypeInfo {
+	return GraphTypeInfo{
+		Name:       connectionNodeName[T]() + "Connection",
+		OutputOnly: true,
+	}
+}
+
+// Edge is an item of a Connection with its cursor. In the schema it is named after the
+// type of its node, so an Edge[User] is a UserEdge.
+type Edge[T any] struct {
+	Node   T      `json:"node"`
+	Cursor string `json:"cursor"`
+}
+
+func (Edge[T]) GraphTypeExtension() GraphTypeInfo {
+	return GraphTypeInfo{
+		Name:       connectionNodeName[T]() + "Edge",
+		OutputOnly: true,
+	}
+}
+
+// PageInfo tells clients whether there are more items before or after a page of a
+// Connection, and the cursors to get them with.
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor"`
+	EndCursor       *string `json:"endCursor"`
+}
+
+// connectionNodeName returns the name of the nodes of a connection in the schema.
+func connectionNodeName[T any]() string {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Implements(graphTypeExtensionType) {
+		return graphTypeInfo(typ).Name
+	}
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Name()
+}
+
+// PageRequest is the page of items that Paginate asks a Paginator for.
+type PageRequest struct {
+	// Limit is the most items to fetch. It is one more than the size of the page, so
+	// that Paginate can tell from the extra item whether there are more.
+	Limit int
+
+	// Backward is set for requests with last. The items are then the ones just before
+	// the position of the page rather than the ones just after it, and are still
+	// returned in their usual order.
+	Backward bool
+
+	// HasCursor is set when the request gave the after or before cursor, and
+	// ApplyCursor was called with it. Without a cursor, pages start at the first item,
+	// or end at the last one when paging backward.
+	HasCursor bool
+
+	// Offset is the position of the first item to fetch for the OffsetPaginator.
+	Offset int
+
+	// Key is the key of the item that the page starts after, or ends before when paging
+	// backward, for the KeysetPaginator. It is empty without a cursor.
+	Key string
+}
+
+// Paginator fetches the pages of a list for Paginate, which takes care of the arguments
+// of the connection, the encoding of the cursors, and the PageInfo. OffsetPaginator and
+// KeysetPaginator cover the common ways of fetching pages from a database; other ways can
+// be supported by implementing this.
+type Paginator[T any] interface {
+	// ApplyCursor sets the position of the page from a cursor that Cursor returned.
+	ApplyCursor(page *PageRequest, cursor string) error
+
+	// FetchPage fetches up to page.Limit items at the position of the page. It may
+	// change the position, such as when it can only be worked out once the list is
+	// known, and the changed page is what Cursor is given.
+	FetchPage(ctx context.Context, page *PageRequest) ([]T, error)
+
+	// Cursor returns the cursor of an item that FetchPage returned for the page. The
+	// index is the index of the item in those that FetchPage returned.
+	Cursor(page PageRequest, index int, item T) string
+}
+
+// Paginate returns the page of a list that the arguments of a connection ask for,
+// fetched with the paginator. Requests may not give both first and last, nor after with
+// last or before with first.
+func Paginate[T any](ctx context.Context, args ConnectionArgs, paginator Paginator[T]) (Connection[T], error) {
+	if args.First != nil && args.Last != nil {
+		return Connection[T]{}, errors.New("first and last can't be used together")
+	}
+
+	size := DefaultPageSize
+	page := PageRequest{}
+	cursor := args.After
+	if args.Last != nil {
+		size = *args.Last
+		page.Backward = true
+		cursor = args.Before
+		if args.After != nil {
+			return Connection[T]{}, errors.New("after can't be used with last")
+		}
+	} else {
+		if args.First != nil {
+			size = *args.First
+		}
+		if args.Before != nil {
+			return Connection[T]{}, errors.New("before can only be used with last")
+		}
+	}
+	if size < 0 {
+		return Connection[T]{}, fmt.Errorf("the page size %d is negative", size)
+	}
+	page.Limit = size + 1
+
+	if cursor != nil {
+		decoded, err := base64.RawURLEncoding.DecodeString(*cursor)
+		if err != nil {
+			return Connection[T]{}, fmt.Errorf("invalid cursor %s", *cursor)
+		}
+		page.HasCursor = true
+		err = paginator.ApplyCursor(&page, string(decoded))
+		if err != nil {
+			return Connection[T]{}, err
+		}
+	}
+
+	items, err := paginator.FetchPage(ctx, &page)
+	if err != nil {
+		return Connection[T]{}, err
+	}
+
+	// The extra item, if there is one, is the one furthest from the position of the page.
+	first, more := 0, len(items) > size
+	if more {
+		if page.Backward {
+			first = len(items) - size
+		} else {
+			items = items[:size]
+		}
+	}
+
+	result := Connection[T]{Edges: []Edge[T]{}}
+	for i := first; i < len(items); i++ {
+		result.Edges = append(result.Edges, Edge[T]{
+			Node:   items[i],
+			Cursor: base64.RawURLEncoding.EncodeToString([]byte(paginator.Cursor(page, i, items[i]))),
+		})
+	}
+	if len(result.Edges) > 0 {
+		result.PageInfo.StartCursor = &result.Edges[0].Cursor
+		result.PageInfo.EndCursor = &result.Edges[len(result.Edges)-1].Cursor
+	}
+
+	// There is at least the item of the cursor on the other side of the page.
+	if page.Backward {
+		result.PageInfo.HasPreviousPage = more
+		result.PageInfo.HasNextPage = page.HasCursor
+	} else {
+		result.PageInfo.HasNextPage = more
+		result.PageInfo.HasPreviousPage = page.HasCursor
+	}
+	return result, nil
+}
+
+// OffsetPaginator fetches pages by their offset in the list, like a SQL query with
+// LIMIT and OFFSET. Its cursors are the positions of the items.
+type OffsetPaginator[T any] struct {
+	// Fetch fetches up to limit items starting with the one at the offset.
+	Fetch func(ctx context.Context, offset, limit int) ([]T, error)
+
+	// Count returns the number of items in the list. It is only needed for requests
+	// with last and without before.
+	Count func(ctx context.Context) (int, error)
+}
+
+func (p OffsetPaginator[T]) ApplyCursor(page *PageRequest, cursor string) error {
+	position, err := strconv.Atoi(cursor)
+	if err != nil || position < 0 {
+		return fmt.Errorf("invalid cursor position %s", cursor)
+	}
+	if page.Backward {
+		page.setEnd(position)
+	} else {
+		page.Offset = position + 1
+	}
+	return nil
+}
+
+func (p OffsetPaginator[T]) FetchPage(ctx context.Context, page *PageRequest) ([]T, error) {
+	if page.Backward && !page.HasCursor {
+		if p.Count == nil {
+			return nil, errors.New("last can only be used with before")
+		}
+		count, err := p.Count(ctx)
+		if err != nil {
+			return nil, err
+		}
+		page.setEnd(count)
+	}
+	if page.Limit == 0 {
+		return nil, nil
+	}
+	return p.Fetch(ctx, page.Offset, page.Limit)
+}
+
+func (p OffsetPaginator[T]) Cursor(page PageRequest, index int, item T) string {
+	return strconv.Itoa(page.Offset + index)
+}
+
+// setEnd sets the offset of a backward page to end just before the item at the
+// position. Pages that would start before the first item are shortened.
+func (page *PageRequest) setEnd(position int) {
+	page.Offset = position - page.Limit
+	if page.Offset < 0 {
+		page.Limit += page.Offset
+		page.Offset = 0
+	}
+}
+
+// KeysetPaginator fetches pages by the key of the item that they start after, like a SQL
+// query with `WHERE id > ? ORDER BY id LIMIT ?`. Its cursors are the keys of the items.
+// Unlike offsets, keys stay valid when items are added or removed before them.
+type KeysetPaginator[T any] struct {
+	// Fetch fetches up to page.Limit items with keys after page.Key, or, when
+	// page.Backward is set, the last page.Limit items with keys before it. An empty key
+	// means the start of the list, or the end of the list when paging backward.
+	Fetch func(ctx context.Context, page PageRequest) ([]T, error)
+
+	// Key returns the key of an item.
+	Key func(item T) string
+}
+
+func (p KeysetPaginator[T]) ApplyCursor(page *PageRequest, cursor string) error {
+	page.Key = cursor
+	return nil
+}
+
+func (p KeysetPaginator[T]) FetchPage(ctx context.Context, page *PageRequest) ([]T, error) {
+	return p.Fetch(ctx, *page)
+}
+
+func (p KeysetPaginator[T]) Cursor(page PageRequest, index int, item T) string {
+	return p.Key(item)
+}
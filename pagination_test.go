@@ -0,0 +1,197 @@
+package quickgraph
+
+import (
+	"context"
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+type paginationPlanet struct {
+	Id   int
+	Name string
+}
+
+var paginationPlanets = []paginationPlanet{
+	{1, "Mercury"}, {2, "Venus"}, {3, "Earth"}, {4, "Mars"}, {5, "Jupiter"},
+}
+
+func paginationOffsetPaginator() OffsetPaginator[paginationPlanet] {
+	return OffsetPaginator[paginationPlanet]{
+		Fetch: func(ctx context.Context, offset, limit int) ([]paginationPlanet, error) {
+			if offset >= len(paginationPlanets) {
+				return nil, nil
+			}
+			end := offset + limit
+			if end > len(paginationPlanets) {
+				end = len(paginationPlanets)
+			}
+			return paginationPlanets[offset:end], nil
+		},
+		Count: func(ctx context.Context) (int, error) {
+			return len(paginationPlanets), nil
+		},
+	}
+}
+
+func paginationKeysetPaginator() KeysetPaginator[paginationPlanet] {
+	return KeysetPaginator[paginationPlanet]{
+		Fetch: func(ctx context.Context, page PageRequest) ([]paginationPlanet, error) {
+			key := 0
+			if page.Key != "" {
+				key, _ = strconv.Atoi(page.Key)
+			}
+			var result []paginationPlanet
+			if page.Backward {
+				for i := len(paginationPlanets) - 1; i >= 0 && len(result) < page.Limit; i-- {
+					if key == 0 || paginationPlanets[i].Id < key {
+						result = append([]paginationPlanet{paginationPlanets[i]}, result...)
+					}
+				}
+				return result, nil
+			}
+			for _, p := range paginationPlanets {
+				if p.Id > key && len(result) < page.Limit {
+					result = append(result, p)
+				}
+			}
+			return result, nil
+		},
+		Key: func(item paginationPlanet) string {
+			return strconv.Itoa(item.Id)
+		},
+	}
+}
+
+func paginationNames(c Connection[paginationPlanet]) []string {
+	var names []string
+	for _, e := range c.Edges {
+		names = append(names, e.Node.Name)
+	}
+	return names
+}
+
+func paginationCursor(s string) *string {
+	c := base64.RawURLEncoding.EncodeToString([]byte(s))
+	return &c
+}
+
+func TestPaginate(t *testing.T) {
+	ctx := context.Background()
+	two := 2
+	tests := []struct {
+		name        string
+		args        ConnectionArgs
+		names       []string
+		hasNext     bool
+		hasPrevious bool
+	}{
+		{"default size", ConnectionArgs{}, []string{"Mercury", "Venus", "Earth", "Mars", "Jupiter"}, false, false},
+		{"first", ConnectionArgs{First: &two}, []string{"Mercury", "Venus"}, true, false},
+		{"first after", ConnectionArgs{First: &two, After: paginationCursor("2")}, []string{"Mars", "Jupiter"}, false, true},
+		{"last", ConnectionArgs{Last: &two}, []string{"Mars", "Jupiter"}, false, true},
+		{"last before", ConnectionArgs{Last: &two, Before: paginationCursor("3")}, []string{"Venus", "Earth"}, true, true},
+		{"last before start", ConnectionArgs{Last: &two, Before: paginationCursor("1")}, []string{"Mercury"}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Paginate[paginationPlanet](ctx, tt.args, paginationOffsetPaginator())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.names, paginationNames(c))
+			assert.Equal(t, tt.hasNext, c.PageInfo.HasNextPage)
+			assert.Equal(t, tt.hasPrevious, c.PageInfo.HasPreviousPage)
+		})
+	}
+}
+
+func TestPaginate_FollowCursors(t *testing.T) {
+	ctx := context.Background()
+	two := 2
+	paginators := map[string]Paginator[paginationPlanet]{
+		"offset": paginationOffsetPaginator(),
+		"keyset": paginationKeysetPaginator(),
+	}
+	for name, paginator := range paginators {
+		t.Run(name, func(t *testing.T) {
+			var names []string
+			args := ConnectionArgs{First: &two}
+			for {
+				c, err := Paginate(ctx, args, paginator)
+				assert.NoError(t, err)
+				names = append(names, paginationNames(c)...)
+				if !c.PageInfo.HasNextPage {
+					break
+				}
+				args.After = c.PageInfo.EndCursor
+			}
+			assert.Equal(t, []string{"Mercury", "Venus", "Earth", "Mars", "Jupiter"}, names)
+
+			names = nil
+			args = ConnectionArgs{Last: &two}
+			for {
+				c, err := Paginate(ctx, args, paginator)
+				assert.NoError(t, err)
+				names = append(paginationNames(c), names...)
+				if !c.PageInfo.HasPreviousPage {
+					break
+				}
+				args.Before = c.PageInfo.StartCursor
+			}
+			assert.Equal(t, []string{"Mercury", "Venus", "Earth", "Mars", "Jupiter"}, names)
+		})
+	}
+}
+
+func TestPaginate_Errors(t *testing.T) {
+	ctx := context.Background()
+	two := 2
+	negative := -1
+	invalid := "!"
+	tests := []struct {
+		name string
+		args ConnectionArgs
+		err  string
+	}{
+		{"first and last", ConnectionArgs{First: &two, Last: &two}, "first and last can't be used together"},
+		{"after with last", ConnectionArgs{Last: &two, After: paginationCursor("1")}, "after can't be used with last"},
+		{"before with first", ConnectionArgs{First: &two, Before: paginationCursor("1")}, "before can only be used with last"},
+		{"negative", ConnectionArgs{First: &negative}, "the page size -1 is negative"},
+		{"invalid cursor", ConnectionArgs{After: &invalid}, "invalid cursor !"},
+		{"invalid position", ConnectionArgs{After: paginationCursor("x")}, "invalid cursor position x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Paginate[paginationPlanet](ctx, tt.args, paginationOffsetPaginator())
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+
+	// Without Count, the end of the list isn't known.
+	p := paginationOffsetPaginator()
+	p.Count = nil
+	_, err := Paginate[paginationPlanet](ctx, ConnectionArgs{Last: &two}, p)
+	assert.EqualError(t, err, "last can only be used with before")
+}
+
+func TestPaginate_Graph(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "planets", func(ctx context.Context, args ConnectionArgs) (Connection[paginationPlanet], error) {
+		return Paginate[paginationPlanet](ctx, args, paginationOffsetPaginator())
+	})
+
+	schema := g.SchemaDefinition(ctx)
+	assert.Contains(t, schema, "planets(first: Int, after: String, last: Int, before: String): paginationPlanetConnection!")
+	assert.Contains(t, schema, "type paginationPlanetConnection {\n\tedges: [paginationPlanetEdge!]!\n\tpageInfo: PageInfo!\n}")
+	assert.Contains(t, schema, "type paginationPlanetEdge {\n\tcursor: String!\n\tnode: paginationPlanet!\n}")
+
+	result, err := g.ProcessRequest(ctx, `{ planets(first: 1, after: "MA") { edges { cursor node { Name } } pageInfo { hasNextPage endCursor } } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"planets":{"edges":[{"cursor":"MQ","node":{"Name":"Venus"}}],"pageInfo":{"endCursor":"MQ","hasNextPage":true}}}}`, result)
+
+	// The connection types are named as they are in the schema.
+	result, err = g.ProcessRequest(ctx, `{ planets(first: 1) { __typename edges { __typename node { __typename } } } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"planets":{"__typename":"paginationPlanetConnection","edges":[{"__typename":"paginationPlanetEdge","node":{"__typename":"paginationPlanet"}}]}}}`, result)
+}
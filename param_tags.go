@@ -0,0 +1,90 @@
+package quickgraph
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// applyParameterTags sets the description, deprecation, and default of a parameter from
+// the graphy tag of the field of the parameter struct that it comes from:
+//
+//	type SearchParams struct {
+//		Query string `graphy:"description=The text to search for"`
+//		Limit int    `graphy:"default=10"`
+//		Page  *int   `graphy:"deprecated=Use cursor"`
+//	}
+//
+// Like the other parts of the tag, the values can't contain commas. The default is a
+// GraphQL literal, such as 10, "text", ACTIVE, or [1, 2], and makes the parameter
+// optional. A default that can't be parsed into the parameter panics.
+func (g *Graphy) applyParameterTags(field reflect.StructField, mapping *functionParamNameMapping) {
+	graphyTag, ok := field.Tag.Lookup("graphy")
+	if !ok {
+		return
+	}
+	for _, part := range strings.Split(graphyTag, ",") {
+		key, value, found := strings.Cut(part, "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "description":
+			mapping.description = &value
+		case "deprecated":
+			mapping.deprecatedReason = &value
+		case "default":
+			parsed, err := valueParser.ParseString("", value)
+			if err != nil {
+				panic(fmt.Sprintf("invalid default %s for parameter %s: %v", value, mapping.name, err))
+			}
+			err = parseInputIntoValue(context.Background(), nil, *parsed, reflect.New(mapping.paramType).Elem())
+			if err != nil {
+				panic(fmt.Sprintf("invalid default %s for parameter %s: %v", value, mapping.name, err))
+			}
+			mapping.defaultValue = &value
+			mapping.defaultParsed = *parsed
+		}
+	}
+}
+
+// orderedParams returns the parameters of the function in the order they are declared.
+func (f *graphFunction) orderedParams() []functionParamNameMapping {
+	if f.paramType != NamedParamsStruct {
+		return f.paramsByIndex
+	}
+	var result []functionParamNameMapping
+	for _, param := range f.paramsByName {
+		result = append(result, param)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].paramIndex < result[j].paramIndex
+	})
+	return result
+}
+
+// schemaForParameter writes the description, default, and deprecation of a parameter
+// around its type in the schema.
+func schemaForParameter(param functionParamNameMapping, typeRef string) string {
+	sb := strings.Builder{}
+	if param.description != nil {
+		sb.WriteString(strconv.Quote(*param.description))
+		sb.WriteString(" ")
+	}
+	sb.WriteString(param.name)
+	sb.WriteString(": ")
+	sb.WriteString(typeRef)
+	if param.defaultValue != nil {
+		sb.WriteString(" = ")
+		sb.WriteString(*param.defaultValue)
+	}
+	if param.deprecatedReason != nil {
+		sb.WriteString(" @deprecated(reason: ")
+		sb.WriteString(strconv.Quote(*param.deprecatedReason))
+		sb.WriteString(")")
+	}
+	return sb.String()
+}
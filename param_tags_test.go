@@ -0,0 +1,74 @@
+package quickgraph
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type taggedSearchParams struct {
+	Query string  `graphy:"query,description=The text to search for"`
+	Limit int     `graphy:"limit,default=10"`
+	Page  *int    `graphy:"page,deprecated=Use cursor"`
+	Sort  episode `graphy:"sort,default=EMPIRE"`
+}
+
+func TestGraphy_ParameterTags(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "search", func(params taggedSearchParams) string {
+		return fmt.Sprintf("%s %d %v %s", params.Query, params.Limit, params.Page != nil, params.Sort)
+	})
+
+	// The defaults are used for the arguments that aren't given, and make them optional.
+	result, err := g.ProcessRequest(ctx, `{ search(query: "droids") }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"search":"droids 10 false EMPIRE"}}`, result)
+
+	result, err = g.ProcessRequest(ctx, `{ search(query: "droids", limit: 5, page: 2, sort: JEDI) }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"search":"droids 5 true JEDI"}}`, result)
+
+	_, err = g.ProcessRequest(ctx, `{ search(limit: 5) }`, "")
+	assert.ErrorContains(t, err, "query")
+
+	schema := g.SchemaDefinition(ctx)
+	assert.Contains(t, schema, `search("The text to search for" query: String!, limit: Int! = 10, page: Int @deprecated(reason: "Use cursor"), sort: episode! = EMPIRE): String!`)
+
+	var args []ArgInfo
+	g.Operations()(func(op OperationInfo) bool {
+		args = op.Args
+		return true
+	})
+	if assert.Len(t, args, 4) {
+		assert.Equal(t, "query", args[0].Name)
+		assert.Equal(t, "The text to search for", args[0].Description)
+		assert.Equal(t, "limit", args[1].Name)
+		if assert.NotNil(t, args[1].DefaultValue) {
+			assert.Equal(t, "10", *args[1].DefaultValue)
+		}
+		assert.Equal(t, "page", args[2].Name)
+		assert.True(t, args[2].IsDeprecated)
+		assert.Equal(t, "Use cursor", args[2].DeprecationReason)
+		assert.False(t, args[3].IsDeprecated)
+	}
+
+	g.EnableIntrospection(ctx)
+	result, err = g.ProcessRequest(ctx, `{ __schema { queryType { fields { args { name description defaultValue isDeprecated deprecationReason } } } } }`, "")
+	assert.NoError(t, err)
+	assert.Contains(t, result, `{"defaultValue":null,"deprecationReason":null,"description":"The text to search for","isDeprecated":false,"name":"query"}`)
+	assert.Contains(t, result, `{"defaultValue":null,"deprecationReason":"Use cursor","description":null,"isDeprecated":true,"name":"page"}`)
+}
+
+func TestGraphy_ParameterTags_InvalidDefault(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	assert.Panics(t, func() {
+		g.RegisterQuery(ctx, "bad", func(params struct {
+			Limit int `graphy:"limit,default=\"ten\""`
+		}) int {
+			return params.Limit
+		})
+	})
+}
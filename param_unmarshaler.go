@@ -0,0 +1,195 @@
+package quickgraph
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// parameterUnmarshaler converts the values of arguments into a type that functions take
+// instead of the input type of the schema. See RegisterParameterUnmarshaler.
+type parameterUnmarshaler struct {
+	fn         reflect.Value
+	hasContext bool
+	hasError   bool
+	inType     reflect.Type
+	outType    reflect.Type
+}
+
+// RegisterParameterUnmarshaler registers a function that converts the values of arguments
+// into values of a type that isn't an input type, such as domain objects that are looked
+// up by their IDs. Functions can then take the hydrated values directly:
+//
+//	g.RegisterParameterUnmarshaler(ctx, func(ctx context.Context, id string) (*User, error) {
+//		return LookupUserByID(ctx, id)
+//	})
+//	g.RegisterMutation(ctx, "promote", func(ctx context.Context, user *User) *User { ... }, "user")
+//
+// The unmarshaler has the form func([ctx context.Context,] in I) (T[, error]). In the
+// schema, the arguments of type T are of type I, so the argument above is a String!.
+// Arguments that are pointers to T, or lists of T, are nullable or lists of I. The
+// unmarshaler is called for each argument value after the value is parsed and validated,
+// including values that come from variables. It isn't used for the fields of input
+// objects.
+//
+// The unmarshaler must be registered before the functions that take T. Registering one
+// that has another form, or for a type that functions already take, panics.
+func (g *Graphy) RegisterParameterUnmarshaler(ctx context.Context, fn any) {
+	g.structureLock.Lock()
+	defer g.structureLock.Unlock()
+
+	fv := reflect.ValueOf(fn)
+	if fv.Kind() != reflect.Func {
+		panic("parameter unmarshaler: not a function")
+	}
+	ft := fv.Type()
+	pu := &parameterUnmarshaler{fn: fv}
+	in := 0
+	if ft.NumIn() > 0 && ft.In(0) == contextType {
+		pu.hasContext = true
+		in++
+	}
+	if ft.NumIn() != in+1 {
+		panic(fmt.Sprintf("parameter unmarshaler: %v must take a single value", ft))
+	}
+	pu.inType = ft.In(in)
+	switch {
+	case ft.NumOut() == 1:
+	case ft.NumOut() == 2 && ft.Out(1) == errorType:
+		pu.hasError = true
+	default:
+		panic(fmt.Sprintf("parameter unmarshaler: %v must return a value and, optionally, an error", ft))
+	}
+	pu.outType = ft.Out(0)
+	if pu.outType == pu.inType || pu.outType == errorType {
+		panic(fmt.Sprintf("parameter unmarshaler: %v doesn't convert to another type", ft))
+	}
+
+	for _, gf := range g.processors {
+		for _, param := range gf.paramsByName {
+			if refersToType(param.goType(), pu.outType) {
+				panic(fmt.Sprintf("parameter unmarshaler: %v is already taken by %s", pu.outType, gf.name))
+			}
+		}
+	}
+
+	if g.parameterUnmarshalers == nil {
+		g.parameterUnmarshalers = map[reflect.Type]*parameterUnmarshaler{}
+	}
+	g.parameterUnmarshalers[pu.outType] = pu
+	g.invalidateSchema()
+}
+
+// refersToType reports whether the type is the target, or a pointer to or list of it.
+func refersToType(typ, target reflect.Type) bool {
+	for {
+		if typ == target {
+			return true
+		}
+		if typ.Kind() != reflect.Ptr && typ.Kind() != reflect.Slice {
+			return false
+		}
+		typ = typ.Elem()
+	}
+}
+
+// parameterInputType returns the type that the values of an argument of the type are
+// parsed into, which differs from the type if there is an unmarshaler for it or for the
+// elements of lists of it.
+func (g *Graphy) parameterInputType(typ reflect.Type) (reflect.Type, bool) {
+	if pu, ok := g.parameterUnmarshalers[typ]; ok {
+		return pu.inType, true
+	}
+	switch typ.Kind() {
+	case reflect.Ptr:
+		if in, ok := g.parameterInputType(typ.Elem()); ok {
+			return reflect.PointerTo(in), true
+		}
+	case reflect.Slice:
+		if in, ok := g.parameterInputType(typ.Elem()); ok {
+			return reflect.SliceOf(in), true
+		}
+	}
+	return typ, false
+}
+
+// applyParameterUnmarshaler makes the parameter take the input type of the unmarshaler of
+// its type, if there is one.
+func (g *Graphy) applyParameterUnmarshaler(mapping *functionParamNameMapping) {
+	in, ok := g.parameterInputType(mapping.paramType)
+	if !ok {
+		return
+	}
+	mapping.unmarshaledType = mapping.paramType
+	mapping.paramType = in
+}
+
+// goType returns the type of the parameter of the function.
+func (m functionParamNameMapping) goType() reflect.Type {
+	if m.unmarshaledType != nil {
+		return m.unmarshaledType
+	}
+	return m.paramType
+}
+
+// unmarshalParameter converts the parsed value of the parameter into the type that the
+// function takes. Parameters without an unmarshaler are returned as they are.
+func (g *Graphy) unmarshalParameter(ctx context.Context, mapping functionParamNameMapping, value reflect.Value) (reflect.Value, error) {
+	if mapping.unmarshaledType == nil {
+		return value, nil
+	}
+	result := reflect.New(mapping.unmarshaledType).Elem()
+	err := g.unmarshalValue(ctx, value, result)
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("error unmarshaling argument %s: %w", mapping.name, err)
+	}
+	return result, nil
+}
+
+// unmarshalValue converts the value into the target by calling the unmarshaler of the
+// type of the target, or of its elements.
+func (g *Graphy) unmarshalValue(ctx context.Context, value, target reflect.Value) (err error) {
+	if pu, ok := g.parameterUnmarshalers[target.Type()]; ok {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("unmarshaler panicked: %v", r)
+			}
+		}()
+		in := make([]reflect.Value, 0, 2)
+		if pu.hasContext {
+			in = append(in, reflect.ValueOf(ctx))
+		}
+		out := pu.fn.Call(append(in, value))
+		if pu.hasError && !out[1].IsNil() {
+			return out[1].Interface().(error)
+		}
+		target.Set(out[0])
+		return nil
+	}
+	switch target.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return nil
+		}
+		elem := reflect.New(target.Type().Elem())
+		err := g.unmarshalValue(ctx, value.Elem(), elem.Elem())
+		if err != nil {
+			return err
+		}
+		target.Set(elem)
+	case reflect.Slice:
+		if value.IsNil() {
+			return nil
+		}
+		target.Set(reflect.MakeSlice(target.Type(), value.Len(), value.Len()))
+		for i := 0; i < value.Len(); i++ {
+			err := g.unmarshalValue(ctx, value.Index(i), target.Index(i))
+			if err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+	default:
+		target.Set(value)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package quickgraph
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type unmarshaledUser struct {
+	ID   string
+	Name string
+}
+
+type promoteParams struct {
+	User  *unmarshaledUser `json:"user"`
+	Level int              `json:"level"`
+}
+
+func TestGraphy_RegisterParameterUnmarshaler(t *testing.T) {
+	ctx := context.Background()
+	users := map[string]*unmarshaledUser{
+		"1": {ID: "1", Name: "Ada"},
+		"2": {ID: "2", Name: "Grace"},
+	}
+	g := Graphy{}
+	g.RegisterParameterUnmarshaler(ctx, func(ctx context.Context, id string) (*unmarshaledUser, error) {
+		user, ok := users[id]
+		if !ok {
+			return nil, errors.New("no user " + id)
+		}
+		return user, nil
+	})
+	g.RegisterQuery(ctx, "user", func(user *unmarshaledUser) *unmarshaledUser {
+		return user
+	}, "id")
+	g.RegisterQuery(ctx, "names", func(users []*unmarshaledUser) []string {
+		var names []string
+		for _, user := range users {
+			names = append(names, user.Name)
+		}
+		return names
+	}, "ids")
+	g.RegisterMutation(ctx, "promote", func(params promoteParams) string {
+		return params.User.Name
+	})
+
+	result, err := g.ProcessRequest(ctx, `{ user(id: "1") { Name } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"user":{"Name":"Ada"}}}`, result)
+
+	result, err = g.ProcessRequest(ctx, `query names($second: String!) { names(ids: ["1", $second]) }`, `{"second": "2"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"names":["Ada","Grace"]}}`, result)
+
+	result, err = g.ProcessRequest(ctx, `mutation promote($user: String!) { promote(user: $user, level: 2) }`, `{"user": "2"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"promote":"Grace"}}`, result)
+
+	_, err = g.ProcessRequest(ctx, `{ user(id: "3") { Name } }`, "")
+	assert.ErrorContains(t, err, "error unmarshaling argument id: no user 3")
+
+	// The arguments have the input type of the unmarshaler in the schema.
+	schema := g.SchemaDefinition(ctx)
+	assert.Contains(t, schema, "user(id: String!): unmarshaledUser\n")
+	assert.Contains(t, schema, "names(ids: [String!]!): [String!]!")
+	assert.Contains(t, schema, "promote(user: String!, level: Int!): String!")
+
+	// The functions have been registered with the unmarshaler that they take already.
+	assert.Panics(t, func() {
+		g.RegisterParameterUnmarshaler(ctx, func(id int) *unmarshaledUser { return nil })
+	})
+	assert.Panics(t, func() {
+		g.RegisterParameterUnmarshaler(ctx, func(a, b string) *unmarshaledUser { return nil })
+	})
+}
@@ -85,6 +85,7 @@ type resultFilter struct {
 
 // resultField is a field in the result to be returned.
 type resultField struct {
+	Alias      *string        `parser:"(@Ident ':')?"`
 	Name       string         `parser:"@Ident"`
 	Params     *parameterList `parser:"('(' @@ ')')?"`
 	Directives []directive    `parser:"@@*"`
@@ -92,9 +93,29 @@ type resultField struct {
 	Pos        lexer.Position
 }
 
+// resultKey returns the key of the field in the result, which is its alias if it has one.
+func (f *resultField) resultKey() string {
+	if f.Alias != nil {
+		return *f.Alias
+	}
+	return f.Name
+}
+
 type fragmentCall struct {
 	Inline      *fragmentDef `parser:"@@"`
 	FragmentRef *string      `parser:"| @Ident "`
+
+	// Directives are the directives of a fragment spread. The directives of an inline
+	// fragment are part of its definition.
+	Directives []directive `parser:"@@*"`
+}
+
+// directives returns the directives of the fragment spread or inline fragment.
+func (fc *fragmentCall) directives() []directive {
+	if fc.Inline != nil {
+		return fc.Inline.Directives
+	}
+	return fc.Directives
 }
 
 type fragment struct {
@@ -104,8 +125,9 @@ type fragment struct {
 }
 
 type fragmentDef struct {
-	TypeName string        `parser:"'on' @Ident"`
-	Filter   *resultFilter `parser:"'{' @@ '}'"`
+	TypeName   string        `parser:"'on' @Ident"`
+	Directives []directive   `parser:"@@*"`
+	Filter     *resultFilter `parser:"'{' @@ '}'"`
 }
 
 type directive struct {
@@ -134,6 +156,13 @@ var (
 		participle.Elide("Whitespace", "Comment"),
 		participle.UseLookahead(2),
 	)
+
+	// valueParser parses single values, such as the defaults of parameters.
+	valueParser = participle.MustBuild[genericValue](
+		participle.Lexer(graphQLLexer),
+		participle.Elide("Whitespace", "Comment"),
+		participle.UseLookahead(2),
+	)
 )
 
 func parseRequest(input string) (*wrapper, error) {
@@ -144,7 +173,7 @@ func parseRequest(input string) (*wrapper, error) {
 		if errors.As(err, &pErr) {
 			position = pErr.Position()
 		}
-		return nil, AugmentGraphError(err, "error parsing request", position)
+		return nil, AugmentGraphError(requestParseError{err}, "error parsing request", position)
 	}
 	return r, nil
 }
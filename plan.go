@@ -0,0 +1,162 @@
+package quickgraph
+
+import (
+	"reflect"
+	"sync"
+)
+
+// executionPlan holds the work of mapping the selections of a request onto the Go types
+// that they are applied to. It is kept with the RequestStub, so when a stub is cached and
+// executed again the field lookups, fragment expansion, and type lookups don't need to be
+// repeated.
+//
+// Since the concrete type of a result isn't known until a function returns it (think
+// interfaces and unions), the plan is built up lazily as types are encountered.
+type executionPlan struct {
+	mu         sync.RWMutex
+	selections map[selectionKey]*selectionPlan
+
+	// unknown are the selected fields that aren't known and are resolved as null. See
+	// UnknownFieldHandling.
+	unknown map[*resultField]bool
+}
+
+// selectionKey identifies a selection being applied to a specific Go type. The filters
+// belong to the parsed request of the stub, so their addresses are stable.
+type selectionKey struct {
+	filter *resultFilter
+	typ    reflect.Type
+}
+
+// selectionPlan is the result of applying a selection to a Go type.
+type selectionPlan struct {
+	typeName string
+	fields   []plannedField
+
+	// typeNameSelected is set if the selection includes __typename without an alias.
+	typeNameSelected bool
+}
+
+// plannedField is a field of a selection along with how to fetch it. Fields that are
+// not present on the type are not part of the plan.
+type plannedField struct {
+	field    *resultField
+	typeName bool
+	unknown  bool
+	lookup   fieldLookup
+
+	// deferred is the @defer directive of the fragment that selects the field, and
+	// stream the @stream directive of the field. They only matter for requests that are
+	// delivered incrementally.
+	deferred *directive
+	stream   *directive
+
+	// fragment is the fragment that selects the field, if it isn't selected directly, for
+	// the directives that are on it.
+	fragment *fragmentCall
+}
+
+func newExecutionPlan() *executionPlan {
+	return &executionPlan{
+		selections: map[selectionKey]*selectionPlan{},
+	}
+}
+
+// selection returns the plan for applying the filter to the type, building it if it
+// hasn't been seen before. A nil plan builds the selection without caching it.
+func (p *executionPlan) selection(g *Graphy, fragments map[string]fragment, filter *resultFilter, typ reflect.Type) *selectionPlan {
+	if p == nil {
+		return g.planSelection(fragments, filter, typ, nil)
+	}
+
+	key := selectionKey{filter: filter, typ: typ}
+	p.mu.RLock()
+	sp, ok := p.selections[key]
+	p.mu.RUnlock()
+	if ok {
+		return sp
+	}
+
+	// Building the same selection twice is harmless, so the lock isn't held for it.
+	sp = g.planSelection(fragments, filter, typ, p.unknown)
+	p.mu.Lock()
+	p.selections[key] = sp
+	p.mu.Unlock()
+	return sp
+}
+
+// planSelection expands the fragments of the filter that apply to the type and looks
+// up each of the selected fields. The unknown fields are planned to be null.
+func (g *Graphy) planSelection(fragments map[string]fragment, filter *resultFilter, typ reflect.Type, unknown map[*resultField]bool) *selectionPlan {
+	fieldMap := g.typeLookup(typ)
+
+	fieldsToProcess := []*resultField{}
+	for i := range filter.Fields {
+		fieldsToProcess = append(fieldsToProcess, &filter.Fields[i])
+	}
+	// The fields that are selected by deferred fragments, unless they are also selected
+	// without being deferred.
+	deferred := map[*resultField]*directive{}
+	eager := map[string]bool{}
+	for _, field := range fieldsToProcess {
+		eager[field.resultKey()] = true
+	}
+	selectedBy := map[*resultField]*fragmentCall{}
+	for i := range filter.Fragments {
+		fragmentCall := &filter.Fragments[i]
+		var f *fragmentDef
+		if fragmentCall.Inline != nil {
+			f = fragmentCall.Inline
+		} else if fragmentCall.FragmentRef != nil {
+			f = fragments[*fragmentCall.FragmentRef].Definition
+		}
+		if found, tl := fieldMap.ImplementsInterface(f.TypeName); found {
+			fieldMap = tl
+			deferDirective := findDirective(fragmentCall.directives(), deferDirectiveName)
+			for i := range f.Filter.Fields {
+				field := &f.Filter.Fields[i]
+				fieldsToProcess = append(fieldsToProcess, field)
+				selectedBy[field] = fragmentCall
+				if deferDirective != nil {
+					deferred[field] = deferDirective
+				} else {
+					eager[field.resultKey()] = true
+				}
+			}
+		}
+	}
+
+	// The name is the one in the schema, which differs from the Go name of types that
+	// are renamed, like the generic connection types.
+	result := &selectionPlan{
+		typeName: g.typeLookup(typ).name,
+	}
+	for _, field := range fieldsToProcess {
+		if field.Name == "__typename" {
+			result.fields = append(result.fields, plannedField{field: field, typeName: true, fragment: selectedBy[field]})
+			if field.Alias == nil {
+				result.typeNameSelected = true
+			}
+			continue
+		}
+		fieldInfo, ok := fieldMap.GetField(field.Name)
+		if !ok {
+			if unknown[field] {
+				result.fields = append(result.fields, plannedField{field: field, unknown: true, fragment: selectedBy[field]})
+			}
+			// TODO: Is this an error?
+			continue
+		}
+		pf := plannedField{
+			field:    field,
+			lookup:   fieldInfo,
+			stream:   findDirective(field.Directives, streamDirectiveName),
+			fragment: selectedBy[field],
+		}
+		if !eager[field.resultKey()] {
+			pf.deferred = deferred[field]
+		}
+		result.fields = append(result.fields, pf)
+	}
+	return result
+}
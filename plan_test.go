@@ -0,0 +1,71 @@
+package quickgraph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"testing"
+)
+
+func TestExecutionPlan_ReusedAcrossExecutions(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{
+		RequestCache: simpleCache{
+			values: map[string]*simpleCacheEntry{},
+		},
+	}
+	g.RegisterQuery(ctx, "search", func(search string) []SearchResultUnion {
+		return []SearchResultUnion{
+			{Human: &Human{Character: Character{Name: "Luke"}, HeightMeters: 1.72}},
+			{Droid: &Droid{Character: Character{Name: "R2-D2"}, PrimaryFunction: "Astromech"}},
+		}
+	}, "search")
+
+	query := `{
+  search(search: "x") {
+    __typename
+    ... on Character { name }
+    ... on Human { HeightMeters }
+    ... on Droid { primaryFunction }
+  }
+}`
+	expected := `{"data":{"search":[{"HeightMeters":1.72,"__typename":"Human","name":"Luke"},{"__typename":"Droid","name":"R2-D2","primaryFunction":"Astromech"}]}}`
+
+	result, err := g.ProcessRequest(ctx, query, "")
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+
+	stub, err := g.RequestCache.GetRequestStub(ctx, query)
+	assert.NoError(t, err)
+	assert.Len(t, stub.plan.selections, 2)
+	humanPlan := stub.plan.selections[selectionKey{
+		filter: stub.commands[0].ResultFilter,
+		typ:    reflect.TypeOf(Human{}),
+	}]
+	assert.NotNil(t, humanPlan)
+
+	// The second execution uses the plan that was built the first time around.
+	result, err = g.ProcessRequest(ctx, query, "")
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+	assert.Len(t, stub.plan.selections, 2)
+	assert.Same(t, humanPlan, stub.plan.selections[selectionKey{
+		filter: stub.commands[0].ResultFilter,
+		typ:    reflect.TypeOf(Human{}),
+	}])
+}
+
+func TestExecutionPlan_Nil(t *testing.T) {
+	g := Graphy{}
+	filter := &resultFilter{
+		Fields: []resultField{{Name: "__typename"}, {Name: "name"}, {Name: "missing"}},
+	}
+
+	var plan *executionPlan
+	sp := plan.selection(&g, nil, filter, reflect.TypeOf(Character{}))
+	assert.Equal(t, "Character", sp.typeName)
+	if assert.Len(t, sp.fields, 2) {
+		assert.True(t, sp.fields[0].typeName)
+		assert.Equal(t, "name", sp.fields[1].field.Name)
+	}
+}
@@ -0,0 +1,70 @@
+package quickgraph
+
+import (
+	"reflect"
+	"sync"
+)
+
+// The pools below reuse the short-lived allocations that are made for every function
+// call and every object in a result. Anything that is put back in a pool is cleared
+// first so that no user data is kept alive between requests.
+
+var paramValuesPool = sync.Pool{
+	New: func() any {
+		s := make([]reflect.Value, 0, 8)
+		return &s
+	},
+}
+
+// getParamValues returns a slice of n zero values to hold the parameters of a function
+// call. It should be returned with putParamValues once the call is done.
+func getParamValues(n int) []reflect.Value {
+	sp := paramValuesPool.Get().(*[]reflect.Value)
+	s := *sp
+	if cap(s) < n {
+		s = make([]reflect.Value, n)
+	}
+	return s[:n]
+}
+
+// putParamValues returns a slice that was obtained from getParamValues to the pool.
+func putParamValues(s []reflect.Value) {
+	for i := range s {
+		s[i] = reflect.Value{}
+	}
+	s = s[:0]
+	paramValuesPool.Put(&s)
+}
+
+var resultMapPool = sync.Pool{
+	New: func() any {
+		return plainMap{}
+	},
+}
+
+// getResultMap returns an empty plainMap for an object in the result of a request.
+func getResultMap() plainMap {
+	return resultMapPool.Get().(plainMap)
+}
+
+// releaseResult returns the plainMaps in a result that has been serialized to the pool.
+// The result must not be used afterwards. Only the maps that were created for the result
+// are released; values that come from the functions are left alone.
+func releaseResult(result any) {
+	switch r := result.(type) {
+	case plainMap:
+		for key, value := range r {
+			releaseResult(value)
+			delete(r, key)
+		}
+		resultMapPool.Put(r)
+	case *orderedMap:
+		for _, value := range r.values {
+			releaseResult(value)
+		}
+	case []any:
+		for _, value := range r {
+			releaseResult(value)
+		}
+	}
+}
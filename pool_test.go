@@ -0,0 +1,48 @@
+package quickgraph
+
+import (
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"testing"
+)
+
+func TestParamValuesPool(t *testing.T) {
+	s := getParamValues(3)
+	assert.Len(t, s, 3)
+	s[0] = reflect.ValueOf("user data")
+	putParamValues(s)
+
+	// Reused slices never carry values over.
+	for i := 0; i < 10; i++ {
+		s = getParamValues(2)
+		for _, v := range s {
+			assert.False(t, v.IsValid())
+		}
+		putParamValues(s)
+	}
+
+	s = getParamValues(20)
+	assert.Len(t, s, 20)
+	putParamValues(s)
+}
+
+func TestReleaseResult(t *testing.T) {
+	userMap := map[string]any{"kept": "value"}
+	child := getResultMap()
+	child["name"] = "child"
+	ordered := newOrderedMap()
+	orderedChild := getResultMap()
+	orderedChild["a"] = 1
+	ordered.Set("child", orderedChild)
+	root := getResultMap()
+	root["child"] = child
+	root["list"] = []any{ordered, userMap}
+
+	releaseResult(root)
+
+	assert.Empty(t, root)
+	assert.Empty(t, child)
+	assert.Empty(t, orderedChild)
+	// Values that weren't created for the result are left alone.
+	assert.Equal(t, map[string]any{"kept": "value"}, userMap)
+}
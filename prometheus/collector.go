@@ -0,0 +1,194 @@
+// Package prometheus exports the metrics of a Graphy instance in the Prometheus text
+// format, without depending on the Prometheus client library.
+//
+// A Collector is a quickgraph.MetricsCollector that keeps counters and histograms of the
+// requests, the resolvers, and the request cache. It is also an http.Handler that serves
+// them for Prometheus to scrape:
+//
+//	collector := prometheus.NewCollector()
+//	g.Metrics = collector
+//	http.Handle("/metrics", collector)
+package prometheus
+
+import (
+	"context"
+	"github.com/gburgyan/go-quickgraph"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the buckets of the duration
+// histograms. They are the same as the default buckets of the Prometheus client library.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefaultMaxOperations is the default for Collector.MaxOperations.
+const DefaultMaxOperations = 500
+
+// OtherOperation is the operation label of the requests that are reported after
+// Collector.MaxOperations operations have been seen.
+const OtherOperation = "other"
+
+// Collector collects the metrics of a Graphy instance. Use NewCollector to create one.
+//
+// The metrics are:
+//
+//   - quickgraph_requests_total: the requests by operation and status ("ok" or "error")
+//   - quickgraph_request_duration_seconds: a histogram of the time to process requests,
+//     by operation and status
+//   - quickgraph_resolver_duration_seconds: a histogram of the time to resolve fields,
+//     by type and field
+//   - quickgraph_resolver_errors_total: the resolutions that failed, by type and field
+//   - quickgraph_request_cache_lookups_total: the lookups in the request cache, by result
+//     ("hit" or "miss")
+//
+// The hit ratio of the request cache can be computed from the lookups in PromQL.
+type Collector struct {
+	// Namespace is the prefix of the names of the metrics. It defaults to "quickgraph".
+	Namespace string
+
+	// Buckets are the upper bounds of the buckets of the duration histograms, in seconds
+	// and in increasing order. They default to DefaultBuckets.
+	Buckets []float64
+
+	// MaxOperations limits the number of distinct operation labels, since the names of
+	// operations come from the clients. Once the limit is reached, new operations are
+	// reported as OtherOperation. It defaults to DefaultMaxOperations.
+	MaxOperations int
+
+	mu             sync.Mutex
+	requests       map[requestKey]*histogram
+	resolvers      map[resolverKey]*histogram
+	resolverErrors map[resolverKey]uint64
+	operations     map[string]bool
+	cacheHits      uint64
+	cacheMisses    uint64
+}
+
+type requestKey struct {
+	operation string
+	status    string
+}
+
+type resolverKey struct {
+	typeName  string
+	fieldName string
+}
+
+// NewCollector returns a Collector with the default settings.
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+var _ quickgraph.MetricsCollector = (*Collector)(nil)
+
+// RequestCompleted implements quickgraph.MetricsCollector.
+func (c *Collector) RequestCompleted(ctx context.Context, metrics quickgraph.RequestMetrics) {
+	status := "ok"
+	if metrics.Err != nil {
+		status = "error"
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.requests == nil {
+		c.requests = map[requestKey]*histogram{}
+		c.operations = map[string]bool{}
+	}
+	operation := metrics.Operation
+	if !c.operations[operation] {
+		if len(c.operations) >= c.maxOperations() {
+			operation = OtherOperation
+		} else {
+			c.operations[operation] = true
+		}
+	}
+
+	key := requestKey{operation: operation, status: status}
+	h, ok := c.requests[key]
+	if !ok {
+		h = newHistogram(c.buckets())
+		c.requests[key] = h
+	}
+	h.observe(metrics.Duration)
+}
+
+// ResolverCompleted implements quickgraph.MetricsCollector.
+func (c *Collector) ResolverCompleted(typeName, fieldName string, duration time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.resolvers == nil {
+		c.resolvers = map[resolverKey]*histogram{}
+		c.resolverErrors = map[resolverKey]uint64{}
+	}
+	key := resolverKey{typeName: typeName, fieldName: fieldName}
+	h, ok := c.resolvers[key]
+	if !ok {
+		h = newHistogram(c.buckets())
+		c.resolvers[key] = h
+	}
+	h.observe(duration)
+	if err != nil {
+		c.resolverErrors[key]++
+	}
+}
+
+// RequestCacheLookup implements quickgraph.MetricsCollector.
+func (c *Collector) RequestCacheLookup(hit bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if hit {
+		c.cacheHits++
+	} else {
+		c.cacheMisses++
+	}
+}
+
+func (c *Collector) namespace() string {
+	if c.Namespace == "" {
+		return "quickgraph"
+	}
+	return c.Namespace
+}
+
+func (c *Collector) buckets() []float64 {
+	if len(c.Buckets) == 0 {
+		return DefaultBuckets
+	}
+	return c.Buckets
+}
+
+func (c *Collector) maxOperations() int {
+	if c.MaxOperations <= 0 {
+		return DefaultMaxOperations
+	}
+	return c.MaxOperations
+}
+
+// histogram is a cumulative histogram of durations, in the form that Prometheus expects.
+type histogram struct {
+	bounds []float64
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{
+		bounds: bounds,
+		counts: make([]uint64, len(bounds)),
+	}
+}
+
+func (h *histogram) observe(d time.Duration) {
+	seconds := d.Seconds()
+	for i, bound := range h.bounds {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
@@ -0,0 +1,84 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"github.com/gburgyan/go-quickgraph"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollector_Graphy(t *testing.T) {
+	ctx := context.Background()
+	collector := &Collector{Buckets: []float64{0.1, 1}}
+	g := &quickgraph.Graphy{Metrics: collector}
+	g.RegisterQuery(ctx, "greeting", func() string { return "hello" })
+	g.RegisterQuery(ctx, "broken", func() (string, error) { return "", errors.New("broken") })
+
+	_, err := g.ProcessRequest(ctx, `query Greet { greeting }`, "")
+	assert.NoError(t, err)
+	_, err = g.ProcessRequest(ctx, `{ broken }`, "")
+	assert.Error(t, err)
+	_, err = g.ProcessRequest(ctx, `{ nope`, "")
+	assert.Error(t, err)
+
+	rec := httptest.NewRecorder()
+	collector.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+	body := rec.Body.String()
+
+	assert.Contains(t, body, "# TYPE quickgraph_requests_total counter\n")
+	assert.Contains(t, body, `quickgraph_requests_total{operation="",status="error"} 1`+"\n")
+	assert.Contains(t, body, `quickgraph_requests_total{operation="Greet",status="ok"} 1`+"\n")
+	assert.Contains(t, body, `quickgraph_requests_total{operation="broken",status="error"} 1`+"\n")
+	assert.Contains(t, body, `quickgraph_request_duration_seconds_bucket{operation="Greet",status="ok",le="+Inf"} 1`+"\n")
+	assert.Contains(t, body, `quickgraph_resolver_duration_seconds_count{type="Query",field="greeting"} 1`+"\n")
+	assert.Contains(t, body, `quickgraph_resolver_errors_total{type="Query",field="broken"} 1`+"\n")
+	assert.Contains(t, body, `quickgraph_resolver_errors_total{type="Query",field="greeting"} 0`+"\n")
+	assert.Contains(t, body, `quickgraph_request_cache_lookups_total{result="hit"} 0`+"\n")
+}
+
+func TestCollector_Histogram(t *testing.T) {
+	c := &Collector{Namespace: "api", Buckets: []float64{0.1, 1}}
+	c.ResolverCompleted("Query", "slow", 50*time.Millisecond, nil)
+	c.ResolverCompleted("Query", "slow", 500*time.Millisecond, nil)
+	c.ResolverCompleted("Query", "slow", 5*time.Second, nil)
+	c.RequestCacheLookup(true)
+	c.RequestCacheLookup(false)
+	c.RequestCacheLookup(true)
+
+	sb := strings.Builder{}
+	_, err := c.WriteTo(&sb)
+	assert.NoError(t, err)
+	assert.Contains(t, sb.String(), `api_resolver_duration_seconds_bucket{type="Query",field="slow",le="0.1"} 1
+api_resolver_duration_seconds_bucket{type="Query",field="slow",le="1"} 2
+api_resolver_duration_seconds_bucket{type="Query",field="slow",le="+Inf"} 3
+api_resolver_duration_seconds_sum{type="Query",field="slow"} 5.55
+api_resolver_duration_seconds_count{type="Query",field="slow"} 3
+`)
+	assert.Contains(t, sb.String(), `api_request_cache_lookups_total{result="hit"} 2
+api_request_cache_lookups_total{result="miss"} 1
+`)
+}
+
+func TestCollector_MaxOperations(t *testing.T) {
+	c := &Collector{MaxOperations: 2}
+	for _, op := range []string{"a", "b", "c", "a", "d"} {
+		c.RequestCompleted(context.Background(), quickgraph.RequestMetrics{Operation: op})
+	}
+
+	sb := strings.Builder{}
+	_, err := c.WriteTo(&sb)
+	assert.NoError(t, err)
+	assert.Contains(t, sb.String(), `quickgraph_requests_total{operation="a",status="ok"} 2`)
+	assert.Contains(t, sb.String(), `quickgraph_requests_total{operation="other",status="ok"} 2`)
+	assert.NotContains(t, sb.String(), `operation="c"`)
+}
+
+func TestLabels_Escaping(t *testing.T) {
+	assert.Equal(t, `{operation="a\"b\\c\nd"}`, labels("operation", "a\"b\\c\nd"))
+}
@@ -0,0 +1,152 @@
+package prometheus
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the content type of the Prometheus text format that the Collector
+// serves.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// ServeHTTP serves the metrics in the Prometheus text format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	_, err := c.WriteTo(w)
+	if err != nil {
+		log.Printf("Error writing metrics: %v", err)
+	}
+}
+
+// WriteTo writes the metrics in the Prometheus text format. The metrics and their series
+// are sorted so that the output is stable.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	ns := c.namespace()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var requestKeys []requestKey
+	for key := range c.requests {
+		requestKeys = append(requestKeys, key)
+	}
+	sort.Slice(requestKeys, func(i, j int) bool {
+		if requestKeys[i].operation != requestKeys[j].operation {
+			return requestKeys[i].operation < requestKeys[j].operation
+		}
+		return requestKeys[i].status < requestKeys[j].status
+	})
+
+	var resolverKeys []resolverKey
+	for key := range c.resolvers {
+		resolverKeys = append(resolverKeys, key)
+	}
+	sort.Slice(resolverKeys, func(i, j int) bool {
+		if resolverKeys[i].typeName != resolverKeys[j].typeName {
+			return resolverKeys[i].typeName < resolverKeys[j].typeName
+		}
+		return resolverKeys[i].fieldName < resolverKeys[j].fieldName
+	})
+
+	name := ns + "_requests_total"
+	cw.header(name, "counter", "GraphQL requests processed, by operation and status.")
+	for _, key := range requestKeys {
+		cw.sample(name, labels("operation", key.operation, "status", key.status), formatUint(c.requests[key].count))
+	}
+
+	name = ns + "_request_duration_seconds"
+	cw.header(name, "histogram", "Time to process GraphQL requests, by operation and status.")
+	for _, key := range requestKeys {
+		cw.histogram(name, []string{"operation", key.operation, "status", key.status}, c.requests[key])
+	}
+
+	name = ns + "_resolver_duration_seconds"
+	cw.header(name, "histogram", "Time to resolve fields, by type and field.")
+	for _, key := range resolverKeys {
+		cw.histogram(name, []string{"type", key.typeName, "field", key.fieldName}, c.resolvers[key])
+	}
+
+	name = ns + "_resolver_errors_total"
+	cw.header(name, "counter", "Field resolutions that failed, by type and field.")
+	for _, key := range resolverKeys {
+		cw.sample(name, labels("type", key.typeName, "field", key.fieldName), formatUint(c.resolverErrors[key]))
+	}
+
+	name = ns + "_request_cache_lookups_total"
+	cw.header(name, "counter", "Lookups in the request cache, by result.")
+	cw.sample(name, labels("result", "hit"), formatUint(c.cacheHits))
+	cw.sample(name, labels("result", "miss"), formatUint(c.cacheMisses))
+
+	if cw.err != nil {
+		return cw.n, cw.err
+	}
+	return cw.n, cw.w.Flush()
+}
+
+func (cw *countingWriter) header(name, typ, help string) {
+	cw.writeString("# HELP " + name + " " + help + "\n")
+	cw.writeString("# TYPE " + name + " " + typ + "\n")
+}
+
+func (cw *countingWriter) sample(name, labels, value string) {
+	cw.writeString(name + labels + " " + value + "\n")
+}
+
+func (cw *countingWriter) histogram(name string, labelPairs []string, h *histogram) {
+	for i, bound := range h.bounds {
+		cw.sample(name+"_bucket", labels(append(labelPairs, "le", formatFloat(bound))...), formatUint(h.counts[i]))
+	}
+	cw.sample(name+"_bucket", labels(append(labelPairs, "le", "+Inf")...), formatUint(h.count))
+	cw.sample(name+"_sum", labels(labelPairs...), formatFloat(h.sum))
+	cw.sample(name+"_count", labels(labelPairs...), formatUint(h.count))
+}
+
+// labels formats pairs of label names and values.
+func labels(pairs ...string) string {
+	sb := strings.Builder{}
+	sb.WriteString("{")
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(pairs[i])
+		sb.WriteString(`="`)
+		sb.WriteString(labelValueEscaper.Replace(pairs[i+1]))
+		sb.WriteString(`"`)
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatUint(n uint64) string {
+	return strconv.FormatUint(n, 10)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// countingWriter keeps track of the bytes written and the first error, so that the
+// metrics can be written without checking each write.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) writeString(s string) {
+	if cw.err != nil {
+		return
+	}
+	n, err := cw.w.WriteString(s)
+	cw.n += int64(n)
+	cw.err = err
+}
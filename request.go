@@ -1,13 +1,19 @@
 package quickgraph
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/alecthomas/participle/v2/lexer"
 	"github.com/gburgyan/go-timing"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // RequestType is an enumeration of the types of requests. It can be a Query or a Mutation.
@@ -28,6 +34,18 @@ type RequestStub struct {
 	fragments  map[string]fragment
 	name       string
 	parsedCall *wrapper
+
+	// knownVariables are the names of the variables that the request declares, or that it
+	// uses if it declares none.
+	knownVariables map[string]bool
+
+	// plan caches how the selections of the request map onto the result types, so
+	// that repeated executions of the stub can skip that work.
+	plan *executionPlan
+
+	// unknownFieldWarnings are the warnings for the selected fields that aren't known, for
+	// the extensions of the response.
+	unknownFieldWarnings []GraphError
 }
 
 // requestVariable represents a variable in a GraphQL-like request. It contains the variable name and its type.
@@ -35,6 +53,44 @@ type requestVariable struct {
 	Name    string
 	Type    reflect.Type
 	Default *genericValue
+
+	// defaultValue is the parsed Default if it can be shared between requests, so that
+	// it doesn't need to be parsed for each of them. See precomputeDefault.
+	defaultValue reflect.Value
+}
+
+// precomputeDefault parses the default value of the variable ahead of time if the value
+// can be shared by all the requests that use the stub. That is the case for scalars, which
+// are copied when they are used, unless their parsing depends on the context of the
+// request. If the default can't be parsed, it is left for each request to report.
+func (v *requestVariable) precomputeDefault() {
+	if v.Default == nil || !isSharableScalar(v.Type) {
+		return
+	}
+	value := reflect.New(v.Type).Elem()
+	err := parseInputIntoValue(context.Background(), nil, *v.Default, value)
+	if err != nil {
+		return
+	}
+	v.defaultValue = value
+}
+
+// isSharableScalar reports whether values of the type can be shared between requests.
+func isSharableScalar(typ reflect.Type) bool {
+	if typ == timeType {
+		return true
+	}
+	if reflect.PointerTo(typ).Implements(enumUnmarshalerWithContextType) {
+		return false
+	}
+	switch typ.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
 }
 
 // GraphRequestCache represents an interface for caching request stubs
@@ -60,13 +116,69 @@ type request struct {
 	graphy    *Graphy
 	stub      RequestStub
 	variables map[string]reflect.Value
+
+	// document and variableJson are the request as it was received, for Detach.
+	document     string
+	variableJson string
+
+	// executing is set while the request is executed, and with it the structure lock is
+	// held, so that Exec knows whether it is being called from one of its functions.
+	executing atomic.Bool
+
+	// resolverSlots limits the number of goroutines used to generate list results
+	// concurrently. It is nil if list results are generated sequentially.
+	resolverSlots chan struct{}
+
+	// truncated are the lists that were truncated to the MaxResults of their functions,
+	// for the extensions of the response. extensionsMu guards it.
+	truncated    []truncatedResult
+	extensionsMu sync.Mutex
+
+	// elementErrors are the errors of the elements of lists that were replaced with null
+	// because of IsolateListElementErrors. elementErrorsMu guards it.
+	elementErrors   []error
+	elementErrorsMu sync.Mutex
+
+	// trace is the trace of the execution of the request, or nil if it isn't traced.
+	trace *ExecutionTrace
+
+	// typenames adds the __typename to all the objects in the result.
+	typenames bool
+
+	// loads are the values loaded with Load for each batch loader. loadsMu guards it.
+	loads   map[*batchLoader]*batchLoads
+	loadsMu sync.Mutex
+
+	// incremental is the work that is delivered after the first payload, if the request
+	// is processed with ProcessRequestIncremental.
+	incremental *incrementalDelivery
 }
 
 // newRequestStub creates a new request stub from a string representation of a GraphQL request.
 // It parses the request, gathers and validates the variables used in the request, and determines
 // the request type (Query or Mutation).
-func (g *Graphy) newRequestStub(request string) (*RequestStub, error) {
+func (g *Graphy) newRequestStub(ctx context.Context, request string) (*RequestStub, error) {
+	_, endParse := g.startSpan(ctx, "graphql.parse")
 	parsedCall, err := parseRequest(request)
+	endParse(err)
+	if err != nil {
+		return nil, err
+	}
+
+	_, endValidate := g.startSpan(ctx, "graphql.validate")
+	stub, err := g.validateRequest(parsedCall)
+	if _, pending := err.(pendingTypesError); pending {
+		// The request is validated again once the types are resolved.
+		endValidate(nil)
+	} else {
+		endValidate(err)
+	}
+	return stub, err
+}
+
+// validateRequest validates a parsed request against the schema and creates its stub.
+func (g *Graphy) validateRequest(parsedCall *wrapper) (*RequestStub, error) {
+	parsedCall, err := g.rewriteDocument(parsedCall)
 	if err != nil {
 		return nil, err
 	}
@@ -82,6 +194,10 @@ func (g *Graphy) newRequestStub(request string) (*RequestStub, error) {
 		return nil, NewGraphError(fmt.Sprintf("unknown/unsupported call mode %s", parsedCall.Mode), parsedCall.Pos)
 	}
 
+	if pending := g.pendingTypeResolvers(parsedCall); len(pending) > 0 {
+		return nil, pendingTypesError{typeNames: pending}
+	}
+
 	// Validate that we have processors for all the commands.
 	var missingCommands []command
 	for _, command := range parsedCall.Commands {
@@ -115,19 +231,44 @@ func (g *Graphy) newRequestStub(request string) (*RequestStub, error) {
 		fragments[fragment.Name] = fragment
 	}
 
+	err = g.validateRequestLimits(parsedCall, fragments)
+	if err != nil {
+		return nil, err
+	}
+
 	// TODO: Use the fragments in the variable gathering.
-	variableTypeMap, err := g.gatherRequestVariables(parsedCall, fragments)
+	var unknown *unknownFields
+	if g.unknownFieldHandling(mode) == UnknownFieldsNull {
+		unknown = &unknownFields{fields: map[*resultField]bool{}}
+	}
+	variableTypeMap, err := g.gatherRequestVariables(parsedCall, fragments, unknown)
+	if err != nil {
+		return nil, err
+	}
+
+	knownVariables, err := g.checkDeclaredVariables(parsedCall)
+	if err != nil {
+		return nil, err
+	}
+
+	err = g.runRequestValidators(parsedCall, fragments)
 	if err != nil {
 		return nil, err
 	}
 
 	rs := RequestStub{
-		parsedCall: parsedCall,
-		graphy:     g,
-		commands:   parsedCall.Commands,
-		variables:  variableTypeMap,
-		fragments:  fragments,
-		mode:       mode,
+		parsedCall:     parsedCall,
+		knownVariables: knownVariables,
+		graphy:         g,
+		commands:       parsedCall.Commands,
+		variables:      variableTypeMap,
+		fragments:      fragments,
+		mode:           mode,
+		plan:           newExecutionPlan(),
+	}
+	if unknown != nil && len(unknown.fields) > 0 {
+		rs.plan.unknown = unknown.fields
+		rs.unknownFieldWarnings = unknown.warnings
 	}
 
 	return &rs, nil
@@ -161,7 +302,7 @@ func (r *RequestStub) Name() string {
 
 // gatherRequestVariables gathers and validates the variables used in a GraphQL request.
 // It ensures that the variables used across different commands are of the same type.
-func (g *Graphy) gatherRequestVariables(parsedCall *wrapper, fragments map[string]fragment) (map[string]*requestVariable, error) {
+func (g *Graphy) gatherRequestVariables(parsedCall *wrapper, fragments map[string]fragment, unknown *unknownFields) (map[string]*requestVariable, error) {
 	// TODO: Look at the parsed arguments, find their types, then later verify that
 	//  they are correct.
 
@@ -182,16 +323,19 @@ func (g *Graphy) gatherRequestVariables(parsedCall *wrapper, fragments map[strin
 		}
 		if command.Parameters != nil {
 			for _, parameter := range command.Parameters.Values {
-				if parameter.Value.Variable != nil {
-					varName := *parameter.Value.Variable
-					var paramTarget functionParamNameMapping
-					if anonArgs {
+				var paramTarget functionParamNameMapping
+				if anonArgs {
+					if argIndex < len(graphFunc.paramsByIndex) {
 						paramTarget = graphFunc.paramsByIndex[argIndex]
-						argIndex++
-					} else {
-						paramTarget = graphFunc.paramsByName[parameter.Name]
 					}
-					targetType := paramTarget.paramType
+					argIndex++
+				} else {
+					paramTarget = graphFunc.paramsByName[parameter.Name]
+				}
+				targetType := paramTarget.paramType
+
+				if parameter.Value.Variable != nil {
+					varName := *parameter.Value.Variable
 					if targetType == nil {
 						panic(fmt.Sprintf("unknown parameter %s", parameter.Name))
 					}
@@ -200,6 +344,11 @@ func (g *Graphy) gatherRequestVariables(parsedCall *wrapper, fragments map[strin
 					if err != nil {
 						return nil, AugmentGraphError(err, fmt.Sprintf("error adding variable %s", varName), parameter.Pos, varName)
 					}
+				} else if targetType != nil {
+					err := g.addNestedInputVariables(parameter.Value, targetType, variableTypeMap)
+					if err != nil {
+						return nil, AugmentGraphError(err, fmt.Sprintf("error adding variables for parameter %s", parameter.Name), parameter.Pos, parameter.Name)
+					}
 				}
 			}
 		}
@@ -207,7 +356,7 @@ func (g *Graphy) gatherRequestVariables(parsedCall *wrapper, fragments map[strin
 		// Depth-first search into the result filter.
 		typeLookup := graphFunc.baseReturnType
 
-		err := g.addAndValidateResultVariables(typeLookup, command.ResultFilter, variableTypeMap, fragments)
+		err := g.addAndValidateResultVariables(typeLookup, command.ResultFilter, variableTypeMap, fragments, unknown)
 		if err != nil {
 			return nil, AugmentGraphError(err, fmt.Sprintf("error validating result filter for %s", command.Name), command.ResultFilter.Pos, command.Name)
 		}
@@ -223,11 +372,11 @@ func (g *Graphy) gatherRequestVariables(parsedCall *wrapper, fragments map[strin
 			opVars[name] = variable
 		}
 
-		for key, variable := range variableTypeMap {
+		for _, variable := range variableTypeMap {
 			if reqVar, found := opVars[variable.Name]; found {
 				// TODO: Validate the variable type.
-				variableTypeMap[key].Default = reqVar.Value
 				variable.Default = reqVar.Value
+				variable.precomputeDefault()
 			} else {
 				return nil, fmt.Errorf("variable %s is not defined in the operation", variable.Name)
 			}
@@ -242,7 +391,7 @@ func (g *Graphy) addTypedInputVariable(varName string, variableTypeMap map[strin
 	varName = varName[1:]
 	if existingVariable, found := variableTypeMap[varName]; found {
 		if existingVariable.Type != targetType {
-			return fmt.Errorf("variable %s is used with different types: existing type: %v, new type: %v", varName, existingVariable.Type, targetType)
+			return fmt.Errorf("variable %s is used with different types: existing type: %s, new type: %s", varName, g.schemaTypeName(existingVariable.Type), g.schemaTypeName(targetType))
 		}
 	} else {
 		variableTypeMap[varName] = &requestVariable{
@@ -253,34 +402,48 @@ func (g *Graphy) addTypedInputVariable(varName string, variableTypeMap map[strin
 	return nil
 }
 
-func (g *Graphy) addAndValidateResultVariables(typ *typeLookup, filter *resultFilter, variableTypeMap map[string]*requestVariable, fragments map[string]fragment) error {
+func (g *Graphy) addAndValidateResultVariables(typ *typeLookup, filter *resultFilter, variableTypeMap map[string]*requestVariable, fragments map[string]fragment, unknown *unknownFields) error {
 
 	if filter == nil {
 		return nil
 	}
 
-	for _, field := range filter.Fields {
-		if len(typ.fields) == 0 {
+	// Fields selected directly on a union are looked up in the fields that all of its
+	// members have in common. The union itself is still used for the fragments.
+	fieldTyp := typ
+	if len(typ.union) > 0 && len(typ.fields) == 0 {
+		unionFields, err := commonUnionFields(typ, filter)
+		if err != nil {
+			return err
+		}
+		fieldTyp = unionFields
+	}
+
+	for i, field := range filter.Fields {
+		if len(fieldTyp.fields) == 0 {
 			// This is a bit silly, but not an error.
 			return nil
 		}
+		err := g.validateDirectives(field.Directives, DirectiveLocationField, variableTypeMap)
+		if err != nil {
+			return err
+		}
 		if field.Name == "__typename" {
 			// This is a virtual field that is always present.
 			continue
 		}
-		if pf, ok := typ.GetField(field.Name); ok {
-			var commandField *resultField
-			for _, resultField := range filter.Fields {
-				if resultField.Name == field.Name {
-					commandField = &resultField
-					break
-				}
+		if pf, ok := fieldTyp.GetField(field.Name); ok {
+			err := g.validateOutputDirectives(field, variableTypeMap)
+			if err != nil {
+				return err
 			}
-			if commandField == nil {
-				// Todo: Warning?
-				continue
+			err = g.validateStreamDirectives(field, pf.resultType, variableTypeMap)
+			if err != nil {
+				return err
 			}
 
+			commandField := &filter.Fields[i]
+
 			var childType *typeLookup
 			if pf.fieldType == FieldTypeField {
 				childType = g.typeLookup(pf.resultType)
@@ -297,11 +460,13 @@ func (g *Graphy) addAndValidateResultVariables(typ *typeLookup, filter *resultFi
 
 			if childType != nil {
 				// Recurse
-				err := g.addAndValidateResultVariables(childType, field.SubParts, variableTypeMap, fragments)
+				err := g.addAndValidateResultVariables(childType, field.SubParts, variableTypeMap, fragments, unknown)
 				if err != nil {
 					return AugmentGraphError(err, fmt.Sprintf("error validating field for %s", field.Name), field.SubParts.Pos, field.Name)
 				}
 			}
+		} else if unknown != nil {
+			unknown.add(fieldTyp, &filter.Fields[i])
 		} else {
 			return NewGraphError(fmt.Sprintf("unknown field %s", field.Name), field.Pos)
 		}
@@ -317,8 +482,16 @@ func (g *Graphy) addAndValidateResultVariables(typ *typeLookup, filter *resultFi
 		} else {
 			return fmt.Errorf("unknown fragment type")
 		}
+		err := g.validateDeferDirectives(fragment, variableTypeMap)
+		if err != nil {
+			return err
+		}
+		err = g.validateDirectives(fragment.directives(), fragmentLocation(&fragment), variableTypeMap)
+		if err != nil {
+			return err
+		}
 		if found, subTyp := typ.ImplementsInterface(fragmentDef.TypeName); found {
-			err := g.addAndValidateResultVariables(subTyp, fragmentDef.Filter, variableTypeMap, fragments)
+			err := g.addAndValidateResultVariables(subTyp, fragmentDef.Filter, variableTypeMap, fragments, unknown)
 			if err != nil {
 				return AugmentGraphError(err, fmt.Sprintf("error validating fragment %s", fragmentDef.TypeName), fragmentDef.Filter.Pos, fragmentDef.TypeName)
 			}
@@ -328,6 +501,63 @@ func (g *Graphy) addAndValidateResultVariables(typ *typeLookup, filter *resultFi
 	return nil
 }
 
+// commonUnionFields checks that the fields that are selected directly on a union, rather
+// than through fragments, are defined identically by all the members of the union. It
+// returns a lookup that holds those fields so they can be validated further.
+func commonUnionFields(union *typeLookup, filter *resultFilter) (*typeLookup, error) {
+	result := &typeLookup{
+		name:            union.name,
+		fields:          map[string]fieldLookup{},
+		fieldsLowercase: map[string]fieldLookup{},
+	}
+	memberNames := sortedKeys(union.union)
+	for _, field := range filter.Fields {
+		if field.Name == "__typename" {
+			continue
+		}
+		var common fieldLookup
+		var commonType reflect.Type
+		var missing []string
+		for _, memberName := range memberNames {
+			fl, ok := union.union[memberName].GetField(field.Name)
+			if !ok {
+				missing = append(missing, memberName)
+				continue
+			}
+			flType := fl.resultType
+			if commonType == nil {
+				common = fl
+				commonType = flType
+			} else if flType != commonType {
+				return nil, NewGraphError(fmt.Sprintf("field %s can't be selected directly on union %s because its type differs between the members %s; select it in fragments such as `... on %s` instead",
+					field.Name, union.name, strings.Join(memberNames, ", "), memberNames[0]), field.Pos)
+			}
+		}
+		if commonType == nil {
+			return nil, NewGraphError(fmt.Sprintf("unknown field %s on union %s; it is not defined by any of the members %s",
+				field.Name, union.name, strings.Join(memberNames, ", ")), field.Pos)
+		}
+		if len(missing) > 0 {
+			return nil, NewGraphError(fmt.Sprintf("field %s can't be selected directly on union %s because it is not defined by %s; select it in fragments such as `... on %s` instead",
+				field.Name, union.name, strings.Join(missing, ", "), typeNameWithField(union, memberNames, field.Name)), field.Pos)
+		}
+		result.fields[field.Name] = common
+		result.fieldsLowercase[strings.ToLower(field.Name)] = common
+	}
+	return result, nil
+}
+
+// typeNameWithField returns the name of the first member of the union that defines the
+// field, for use in error messages.
+func typeNameWithField(union *typeLookup, memberNames []string, fieldName string) string {
+	for _, memberName := range memberNames {
+		if _, ok := union.union[memberName].GetField(fieldName); ok {
+			return memberName
+		}
+	}
+	return memberNames[0]
+}
+
 func (g *Graphy) validateGraphFunctionParameters(commandField *resultField, gf *graphFunction, variableTypeMap map[string]*requestVariable) error {
 	// Validate the parameters.
 	switch gf.paramType {
@@ -346,34 +576,21 @@ func (g *Graphy) validateAnonymousFunctionParams(commandField *resultField, gf *
 	// Ensure that the number of parameters is correct.
 	// TODO: If the parameters are all pointers, then they are optional.
 
-	if commandField.Params == nil && gf.function.Type().NumIn() != 1 {
+	// The parameters by index don't include the receiver or a context.Context.
+	if commandField.Params == nil {
 		// If all of the parameters are pointers, then they are optional and we're OK.
-		allOptional := true
-		for i := 1; i < gf.function.Type().NumIn(); i++ {
-			if gf.function.Type().In(i).Kind() != reflect.Ptr {
-				allOptional = false
-				break
+		for _, param := range gf.paramsByIndex {
+			if param.paramType.Kind() != reflect.Ptr {
+				return NewGraphError("missing parameters", commandField.Pos)
 			}
 		}
-		if !allOptional {
-			return NewGraphError("missing parameters", commandField.Pos)
-		}
 		return nil
 	}
-	paramCount := 0
-	if commandField.Params != nil {
-		paramCount = len(commandField.Params.Values)
-	}
-	if paramCount != gf.function.Type().NumIn()-1 {
+	if len(commandField.Params.Values) != len(gf.paramsByIndex) {
 		return fmt.Errorf("wrong number of parameters")
 	}
-	paramIndex := 1 // Skip the first parameter which is the receiver.
-	if commandField.Params == nil {
-		return nil
-	}
-	for _, cfp := range commandField.Params.Values {
-		targetType := gf.function.Type().In(paramIndex)
-		paramIndex++
+	for i, cfp := range commandField.Params.Values {
+		targetType := gf.paramsByIndex[i].paramType
 
 		// Ensure that the parameter is the correct type.
 		if cfp.Value.Variable != nil {
@@ -385,6 +602,11 @@ func (g *Graphy) validateAnonymousFunctionParams(commandField *resultField, gf *
 			if err != nil {
 				return err
 			}
+		} else {
+			err := g.addNestedInputVariables(cfp.Value, targetType, variableTypeMap)
+			if err != nil {
+				return err
+			}
 		}
 		// Todo: Consider parsing, validating, and caching the value for value types. The
 		//  special consideration that is needed is that pointers to objects are
@@ -397,7 +619,7 @@ func (g *Graphy) validateAnonymousFunctionParams(commandField *resultField, gf *
 func (g *Graphy) validateNamedFunctionParams(commandField *resultField, gf *graphFunction, variableTypeMap map[string]*requestVariable) error {
 	neededField := map[string]bool{}
 	for _, param := range gf.paramsByName {
-		neededField[param.name] = true
+		neededField[param.name] = param.required
 	}
 
 	if commandField.Params != nil {
@@ -416,6 +638,11 @@ func (g *Graphy) validateNamedFunctionParams(commandField *resultField, gf *grap
 				if err != nil {
 					return AugmentGraphError(err, fmt.Sprintf("error validating variable %s", varName), cfp.Pos, varName)
 				}
+			} else if targetType != nil {
+				err := g.addNestedInputVariables(cfp.Value, targetType, variableTypeMap)
+				if err != nil {
+					return AugmentGraphError(err, fmt.Sprintf("error validating parameter %s", cfp.Name), cfp.Pos, cfp.Name)
+				}
 			}
 			// Todo: Consider parsing, validating, and caching the value for value types. The
 			//  special consideration that is needed is that pointers to objects are
@@ -437,7 +664,7 @@ func (g *Graphy) validateNamedFunctionParams(commandField *resultField, gf *grap
 func (g *Graphy) validateFunctionVarParam(variableTypeMap map[string]*requestVariable, varName string, targetType reflect.Type) error {
 	if existingVariable, found := variableTypeMap[varName]; found {
 		if existingVariable.Type != targetType {
-			return fmt.Errorf("variable %s is used with different types: existing type: %v, new type: %v", varName, existingVariable.Type, targetType)
+			return fmt.Errorf("variable %s is used with different types: existing type: %s, new type: %s", varName, g.schemaTypeName(existingVariable.Type), g.schemaTypeName(targetType))
 		}
 	} else {
 		variableTypeMap[varName] = &requestVariable{
@@ -448,6 +675,53 @@ func (g *Graphy) validateFunctionVarParam(variableTypeMap map[string]*requestVar
 	return nil
 }
 
+// addNestedInputVariables finds the variables that are used inside of the list and object
+// literals of an input value, at any depth, and adds them with the type of the list
+// element or struct field that they are parsed into.
+func (g *Graphy) addNestedInputVariables(value genericValue, targetType reflect.Type, variableTypeMap map[string]*requestVariable) error {
+	for targetType.Kind() == reflect.Ptr {
+		targetType = targetType.Elem()
+	}
+
+	switch {
+	case targetType.Kind() == reflect.Slice:
+		items := value.List
+		if isSingleInputValue(value) {
+			// A single value is coerced to a list.
+			items = []genericValue{value}
+		}
+		for _, item := range items {
+			err := g.addInputVariable(item, targetType.Elem(), variableTypeMap)
+			if err != nil {
+				return err
+			}
+		}
+	case targetType.Kind() == reflect.Struct && value.Map != nil:
+		for _, nv := range value.Map {
+			field, ok := inputStructField(targetType, nv.Name)
+			if !ok {
+				// This will be reported when the value is parsed.
+				continue
+			}
+			err := g.addInputVariable(nv.Value, field.Type, variableTypeMap)
+			if err != nil {
+				return AugmentGraphError(err, "", nv.Pos, nv.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// addInputVariable adds the variable if the value is one, otherwise it looks for
+// variables nested inside the value.
+func (g *Graphy) addInputVariable(value genericValue, targetType reflect.Type, variableTypeMap map[string]*requestVariable) error {
+	if value.Variable != nil {
+		// Strip the leading $ from the variable name.
+		return g.validateFunctionVarParam(variableTypeMap, (*value.Variable)[1:], targetType)
+	}
+	return g.addNestedInputVariables(value, targetType, variableTypeMap)
+}
+
 // newRequest creates a new request from a request stub and a JSON string representing the variables used in the request.
 // It unmarshals the variables and assigns them to the corresponding variables in the request.
 func (rs *RequestStub) newRequest(ctx context.Context, variableJson string) (*request, error) {
@@ -463,6 +737,12 @@ func (rs *RequestStub) newRequest(ctx context.Context, variableJson string) (*re
 			return nil, transformJsonError(variableJson, err)
 		}
 	}
+	if rs.graphy.UnusedVariables != VariablesIgnore {
+		err := rs.checkProvidedVariables(sortedKeys(rawVariables))
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	// Now use the variable type map to convert the variables to the correct type.
 	variables := map[string]reflect.Value{}
@@ -471,15 +751,23 @@ func (rs *RequestStub) newRequest(ctx context.Context, variableJson string) (*re
 		// Then unmarshal the variable from JSON.
 		variableValue := reflect.New(variable.Type)
 		if variableJson, found := rawVariables[varName]; found {
-			err := json.Unmarshal(variableJson, variableValue.Interface())
+			variableJson = coerceJsonList(variableJson, variable.Type)
+			variableJson = rs.graphy.coerceJsonIDs(variableJson, variable.Type)
+			err := decodeVariable(variableJson, variableValue.Interface())
+			if err != nil {
+				return nil, rs.graphy.variableError(toInputTypeError(err), "error parsing variable", varName, variable.Type)
+			}
+			err = checkInputLimits(variableValue.Elem())
 			if err != nil {
-				return nil, AugmentGraphError(err, fmt.Sprintf("error parsing variable %s into type %s", varName, variable.Type.Name()), lexer.Position{}, varName)
+				return nil, AugmentGraphError(err, fmt.Sprintf("invalid variable %s", varName), lexer.Position{}, varName)
 			}
 			variables[varName] = variableValue.Elem()
+		} else if variable.defaultValue.IsValid() {
+			variables[varName] = variable.defaultValue
 		} else if variable.Default != nil {
-			err := parseInputIntoValue(nil, *variable.Default, variableValue.Elem())
+			err := parseInputIntoValue(ctx, nil, *variable.Default, variableValue.Elem())
 			if err != nil {
-				return nil, AugmentGraphError(err, fmt.Sprintf("error parsing default variable %s into type %s", varName, variable.Type.Name()), lexer.Position{}, varName)
+				return nil, rs.graphy.variableError(err, "error parsing default variable", varName, variable.Type)
 			}
 			variables[varName] = variableValue.Elem()
 		} else {
@@ -487,11 +775,78 @@ func (rs *RequestStub) newRequest(ctx context.Context, variableJson string) (*re
 		}
 	}
 
-	return &request{
-		graphy:    rs.graphy,
-		stub:      *rs,
-		variables: variables,
-	}, nil
+	req := &request{
+		graphy:       rs.graphy,
+		stub:         *rs,
+		variables:    variables,
+		variableJson: variableJson,
+	}
+	if limits := rs.graphy.QueryLimits; limits != nil && limits.MaxConcurrentResolvers > 1 {
+		req.resolverSlots = make(chan struct{}, limits.MaxConcurrentResolvers)
+	}
+
+	return req, nil
+}
+
+// variableError reports an error parsing the value of a variable. The type is given by its
+// name in the schema, since that is what the clients know; in DevelopmentMode the Go type
+// is added to the extensions of the error as well.
+func (g *Graphy) variableError(err error, message, varName string, typ reflect.Type) error {
+	message = fmt.Sprintf("%s %s into type %s", message, varName, g.schemaTypeName(typ))
+	gErr := AugmentGraphError(err, message, lexer.Position{}, varName).(GraphError)
+	if g.DevelopmentMode {
+		gErr.AddExtension("goType", typ.String())
+	}
+	return gErr
+}
+
+// schemaTypeName returns how a type is written in the schema when it is used as an input,
+// such as "[String!]!".
+func (g *Graphy) schemaTypeName(typ reflect.Type) string {
+	tl := g.typeLookup(typ)
+	mapping := g.getSchemaTypes().inputTypeNameLookup
+	if _, ok := mapping[tl]; !ok && tl.rootType != typ {
+		// The names of the types are only known for the types themselves, not for the
+		// pointers to them or the slices of them.
+		if name, ok := mapping[g.typeLookup(tl.rootType)]; ok {
+			mapping = typeNameMapping{tl: name}
+		}
+	}
+	return g.schemaRefForType(tl, mapping)
+}
+
+// decodeVariable decodes the JSON of a variable into the target. Numbers that are decoded
+// into interface values, such as the values of a map[string]any, become json.Numbers rather
+// than float64s, so that they keep their precision.
+func decodeVariable(raw json.RawMessage, target any) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	return decoder.Decode(target)
+}
+
+// coerceJsonList wraps a JSON value that isn't a list in a list if the type it is going
+// to be unmarshalled into is a slice. This implements the GraphQL input coercion that
+// allows a single value to be provided where a list is expected. For nested lists, the
+// value is wrapped once for each level of nesting.
+func coerceJsonList(raw json.RawMessage, typ reflect.Type) json.RawMessage {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || trimmed[0] == '[' || bytes.Equal(trimmed, []byte("null")) {
+		return raw
+	}
+	for {
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		// Byte slices are left alone since JSON represents them as base64 strings.
+		if typ.Kind() != reflect.Slice || typ.Elem().Kind() == reflect.Uint8 {
+			return raw
+		}
+		wrapped := make(json.RawMessage, 0, len(raw)+2)
+		wrapped = append(wrapped, '[')
+		wrapped = append(wrapped, raw...)
+		raw = append(wrapped, ']')
+		typ = typ.Elem()
+	}
 }
 
 type commandResult struct {
@@ -503,6 +858,11 @@ type commandResult struct {
 // execute executes a GraphQL request. It looks up the appropriate processor for each command and invokes it.
 // It returns the result of the request as a JSON string.
 func (r *request) execute(ctx context.Context) (string, error) {
+	// Make the request available to the functions that are called.
+	ctx = context.WithValue(ctx, requestContextKey, r)
+	r.executing.Store(true)
+	defer r.executing.Store(false)
+
 	var parallel bool
 	if r.stub.mode == RequestMutation {
 		parallel = false
@@ -523,45 +883,16 @@ func (r *request) execute(ctx context.Context) (string, error) {
 	}
 
 	result := map[string]any{}
-	data := map[string]any{}
+	data := r.graphy.newResultObject()
 	var errColl []error
 	result["data"] = data
 	var retErr error
 
 	var cmdResults []commandResult
-
 	if parallel {
-		resultChan := make(chan commandResult)
-		// execute the commands in parallel.
-		for _, cmd := range r.stub.commands {
-			go func(cmd command) {
-				resultChan <- r.executeCommand(tCtx, cmd)
-			}(cmd)
-		}
-		// Gather the results from the channel and put them in the cmdResults
-		// slice.
-		for len(cmdResults) < len(r.stub.commands) {
-			select {
-			case <-tCtx.Done():
-				cmdResults = append(cmdResults, commandResult{
-					err: AugmentGraphError(tCtx.Err(), "context timed out", lexer.Position{}),
-				})
-				break
-			case cmdResult := <-resultChan:
-				cmdResults = append(cmdResults, cmdResult)
-			}
-		}
+		cmdResults = r.executeParallel(tCtx)
 	} else {
-		for _, command := range r.stub.commands {
-			ctxErr := tCtx.Err()
-			if ctxErr != nil {
-				cmdResults = append(cmdResults, commandResult{
-					err: AugmentGraphError(tCtx.Err(), "context timed out", lexer.Position{}),
-				})
-				break
-			}
-			cmdResults = append(cmdResults, r.executeCommand(tCtx, command))
-		}
+		cmdResults = r.executeSerial(tCtx)
 	}
 
 	for _, cmdResult := range cmdResults {
@@ -571,12 +902,38 @@ func (r *request) execute(ctx context.Context) (string, error) {
 		}
 
 		if cmdResult.name != "" {
-			data[cmdResult.name] = cmdResult.obj
+			data.Set(cmdResult.name, cmdResult.obj)
+		}
+	}
+	if elementErrs := r.takeElementErrors(); len(elementErrs) > 0 {
+		errColl = append(errColl, elementErrs...)
+		if retErr == nil {
+			retErr = elementErrs[0]
 		}
 	}
 
 	if len(errColl) > 0 {
-		result["errors"] = errColl
+		result["errors"] = r.graphy.redactErrors(ctx, splitErrors(errColl))
+	}
+	extensions := map[string]any{}
+	if len(r.truncated) > 0 {
+		extensions["truncated"] = r.truncated
+	}
+	if deprecations := r.stub.deprecatedOperations(); len(deprecations) > 0 {
+		extensions["deprecations"] = deprecations
+	}
+	if len(r.stub.unknownFieldWarnings) > 0 {
+		extensions["warnings"] = r.stub.unknownFieldWarnings
+	}
+	if r.trace != nil {
+		r.finishExecutionTrace(ctx, extensions)
+	}
+	if len(extensions) > 0 {
+		result["extensions"] = extensions
+	}
+	incremental := r.incremental != nil && r.incremental.hasPending()
+	if incremental {
+		result["hasNext"] = true
 	}
 
 	// Serialize the result to JSON.
@@ -585,9 +942,107 @@ func (r *request) execute(ctx context.Context) (string, error) {
 		// There should be no way for this to happen since we're using basic objects.
 		return "", err
 	}
+	releaseResult(data)
+	if incremental {
+		r.incremental.deliver(ctx, string(marshal))
+	}
 	return string(marshal), retErr
 }
 
+// executeParallel executes the commands of the request concurrently, using at most
+// QueryLimits.MaxConcurrentCommands goroutines at a time. The results are returned in
+// the order of the commands. If the context is done before all the commands are
+// complete, the commands that are still running are abandoned with a cancelled context
+// and a single timeout error is reported for them.
+func (r *request) executeParallel(ctx context.Context) []commandResult {
+	commands := r.stub.commands
+	failFast := r.graphy.CommandErrorMode == FailFast
+
+	// Cancelling this context when returning signals any abandoned commands to stop.
+	cmdCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	limit := len(commands)
+	if limits := r.graphy.QueryLimits; limits != nil && limits.MaxConcurrentCommands > 0 && limits.MaxConcurrentCommands < limit {
+		limit = limits.MaxConcurrentCommands
+	}
+
+	type indexedResult struct {
+		index  int
+		result commandResult
+	}
+	// The channel is buffered so that abandoned commands don't block when they finish.
+	resultChan := make(chan indexedResult, len(commands))
+	next := 0
+	running := 0
+	startNext := func() {
+		index, cmd := next, commands[next]
+		next++
+		running++
+		go func() {
+			resultChan <- indexedResult{index: index, result: r.executeCommand(cmdCtx, cmd)}
+		}()
+	}
+	for next < limit {
+		startNext()
+	}
+
+	var indexed []indexedResult
+	var timeout *commandResult
+	for running > 0 && timeout == nil {
+		select {
+		case <-ctx.Done():
+			timeout = &commandResult{
+				err: AugmentGraphError(ctx.Err(), "context timed out", lexer.Position{}),
+			}
+		case res := <-resultChan:
+			running--
+			indexed = append(indexed, res)
+			if res.result.err != nil && failFast {
+				// Don't start any more commands, and abandon the ones that are running.
+				running = 0
+				continue
+			}
+			if next < len(commands) {
+				startNext()
+			}
+		}
+	}
+
+	sort.Slice(indexed, func(i, j int) bool {
+		return indexed[i].index < indexed[j].index
+	})
+	cmdResults := make([]commandResult, 0, len(indexed)+1)
+	for _, res := range indexed {
+		cmdResults = append(cmdResults, res.result)
+	}
+	if timeout != nil {
+		cmdResults = append(cmdResults, *timeout)
+	}
+	return cmdResults
+}
+
+// executeSerial executes the commands of the request one after the other, as is
+// required for mutations.
+func (r *request) executeSerial(ctx context.Context) []commandResult {
+	var cmdResults []commandResult
+	for _, command := range r.stub.commands {
+		ctxErr := ctx.Err()
+		if ctxErr != nil {
+			cmdResults = append(cmdResults, commandResult{
+				err: AugmentGraphError(ctxErr, "context timed out", lexer.Position{}),
+			})
+			break
+		}
+		cmdResult := r.executeCommand(ctx, command)
+		cmdResults = append(cmdResults, cmdResult)
+		if cmdResult.err != nil && r.graphy.CommandErrorMode == FailFast {
+			break
+		}
+	}
+	return cmdResults
+}
+
 func (r *request) executeCommand(ctx context.Context, command command) commandResult {
 	var name string
 	if command.Alias != nil {
@@ -613,14 +1068,54 @@ func (r *request) executeCommand(ctx context.Context, command command) commandRe
 		}
 	}
 
-	obj, err := processor.Call(tCtx, r, command.Parameters, reflect.Value{})
+	err := r.graphy.checkFeatureGate(tCtx, command)
 	if err != nil {
+		return commandResult{err: err}
+	}
+
+	if IsDryRun(tCtx) && !processor.supportsDryRun {
+		// In dry-run mode the parameters are still parsed so that any input errors are
+		// reported, but the function itself is never called.
+		paramValues, err := processor.getCallParameters(tCtx, r, command.Parameters, reflect.Value{})
+		if err != nil {
+			return commandResult{
+				err: AugmentGraphError(err, fmt.Sprintf("error getting call parameters for function %s", command.Name), command.Pos, command.Name),
+			}
+		}
+		putParamValues(paramValues)
 		return commandResult{
-			err: AugmentGraphError(err, fmt.Sprintf("error calling %s", command.Name), command.Pos, command.Name),
+			name: name,
+		}
+	}
+
+	typeName := "Query"
+	if processor.mode == ModeMutation {
+		typeName = "Mutation"
+	}
+	measure := r.graphy.measureResolvers() || r.tracing()
+	var start time.Time
+	if measure {
+		start = r.graphy.now()
+	}
+	callCtx, endSpan := r.startResolverSpan(tCtx, typeName, command.Name, name)
+	obj, err := processor.Call(callCtx, r, command.Parameters, reflect.Value{})
+	endSpan(err)
+	if measure {
+		duration := r.graphy.now().Sub(start)
+		r.graphy.recordResolver(typeName, command.Name, duration, err)
+		r.traceResolver(typeName, command.Name, command.Parameters, start, duration, err)
+	}
+	var partialErr error
+	if err != nil {
+		err = AugmentGraphError(err, fmt.Sprintf("error calling %s", command.Name), command.Pos, command.Name)
+		if !obj.IsValid() {
+			return commandResult{err: err}
 		}
+		// Functions that return a []error keep their result along with the errors.
+		partialErr = err
 	}
 
-	res, err := processor.GenerateResult(tCtx, r, obj, command.ResultFilter)
+	res, err := processor.GenerateResult(r.withResultPath(tCtx, name), r, obj, command.ResultFilter)
 	if err != nil {
 		var pos lexer.Position
 		if command.ResultFilter != nil {
@@ -628,13 +1123,16 @@ func (r *request) executeCommand(ctx context.Context, command command) commandRe
 		} else {
 			pos = command.Pos
 		}
-		return commandResult{
-			err: AugmentGraphError(err, fmt.Sprintf("error generating result for %s", command.Name), pos, command.Name),
+		err = AugmentGraphError(err, fmt.Sprintf("error generating result for %s", command.Name), pos, command.Name)
+		if partialErr != nil {
+			err = errors.Join(partialErr, err)
 		}
+		return commandResult{err: err}
 	}
 
 	return commandResult{
 		name: name,
 		obj:  res,
+		err:  partialErr,
 	}
 }
@@ -0,0 +1,76 @@
+package quickgraph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+)
+
+type chunkTestItem struct {
+	Id     int
+	cancel context.CancelFunc
+}
+
+func (c chunkTestItem) Label() string {
+	if c.Id == 2 {
+		c.cancel()
+	}
+	return "item"
+}
+
+func TestResultChunks_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	generated := 0
+	g := &Graphy{ResultChunkSize: 2, YieldBetweenChunks: true}
+	g.RegisterMutation(ctx, "items", func() []chunkTestItem {
+		var items []chunkTestItem
+		for i := 0; i < 10; i++ {
+			items = append(items, chunkTestItem{Id: i, cancel: cancel})
+		}
+		return items
+	})
+	g.RegisterTypeField(ctx, chunkTestItem{}, "Count", func(c chunkTestItem) int {
+		generated++
+		return generated
+	})
+
+	_, err := g.ProcessRequest(ctx, `mutation { items { Label Count } }`, "")
+	assert.ErrorContains(t, err, "context cancelled while generating results")
+	// The items of the chunk that was being generated are completed, but no more.
+	assert.Equal(t, 4, generated)
+}
+
+func TestResultChunks_CancelledConcurrently(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var generated atomic.Int32
+	g := &Graphy{QueryLimits: &QueryLimits{MaxConcurrentResolvers: 4}}
+	g.RegisterQuery(ctx, "items", func() []chunkTestItem {
+		// The client goes away while the result is being generated.
+		cancel()
+		return make([]chunkTestItem, 10)
+	})
+	g.RegisterTypeField(ctx, chunkTestItem{}, "Count", func(c chunkTestItem) int {
+		return int(generated.Add(1))
+	})
+
+	_, err := g.ProcessRequest(ctx, `{ items { Count } }`, "")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(0), generated.Load())
+}
+
+func TestResultChunks_Complete(t *testing.T) {
+	ctx := context.Background()
+	g := &Graphy{ResultChunkSize: 2, YieldBetweenChunks: true}
+	g.RegisterQuery(ctx, "numbers", func() []int {
+		return []int{1, 2, 3, 4, 5}
+	})
+
+	result, err := g.ProcessRequest(ctx, `{ numbers }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"numbers":[1,2,3,4,5]}}`, result)
+}
@@ -0,0 +1,62 @@
+package quickgraph
+
+import (
+	"fmt"
+	"github.com/alecthomas/participle/v2/lexer"
+	"reflect"
+)
+
+// ResultLimitPolicy is what happens when a function returns a longer list than its
+// FunctionDefinition.MaxResults allows.
+type ResultLimitPolicy int
+
+const (
+	// ResultLimitError fails the field with an error. This is the default.
+	ResultLimitError ResultLimitPolicy = iota
+
+	// ResultLimitTruncate returns the first MaxResults elements of the list, and reports
+	// the truncation in the "truncated" entry of the extensions of the response.
+	ResultLimitTruncate
+)
+
+// truncatedResult is the entry in the extensions of the response for a list that was
+// truncated.
+type truncatedResult struct {
+	Field    string `json:"field"`
+	Returned int    `json:"returned"`
+	Total    int    `json:"total"`
+}
+
+// limitResults enforces the MaxResults of the function on the value that it returned.
+// Only slices are limited; the elements of iterators aren't known until they are produced.
+func (f *graphFunction) limitResults(req *request, val reflect.Value, pos lexer.Position) (reflect.Value, error) {
+	if f.maxResults <= 0 {
+		return val, nil
+	}
+	list := val
+	for list.Kind() == reflect.Interface || list.Kind() == reflect.Ptr {
+		if list.IsNil() {
+			return val, nil
+		}
+		list = list.Elem()
+	}
+	if list.Kind() != reflect.Slice || list.Len() <= f.maxResults {
+		return val, nil
+	}
+
+	total := list.Len()
+	if f.resultLimitPolicy != ResultLimitTruncate {
+		return reflect.Value{}, NewGraphError(fmt.Sprintf("function %s returned %d results, more than the maximum of %d", f.name, total, f.maxResults), pos)
+	}
+	if req != nil {
+		req.addTruncatedResult(truncatedResult{Field: f.name, Returned: f.maxResults, Total: total})
+	}
+	return list.Slice(0, f.maxResults), nil
+}
+
+// addTruncatedResult records a list that was truncated for the extensions of the response.
+func (r *request) addTruncatedResult(t truncatedResult) {
+	r.extensionsMu.Lock()
+	defer r.extensionsMu.Unlock()
+	r.truncated = append(r.truncated, t)
+}
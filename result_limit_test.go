@@ -0,0 +1,63 @@
+package quickgraph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type resultLimitItem struct {
+	Id int
+}
+
+func TestResultLimit_Error(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name: "items",
+		Function: func(count int) []resultLimitItem {
+			var items []resultLimitItem
+			for i := 0; i < count; i++ {
+				items = append(items, resultLimitItem{Id: i})
+			}
+			return items
+		},
+		ParameterNames:    []string{"count"},
+		MaxResults:        2,
+		ResultLimitPolicy: ResultLimitError,
+	})
+
+	result, err := g.ProcessRequest(ctx, `{ items(count: 2) { Id } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"items":[{"Id":0},{"Id":1}]}}`, result)
+
+	result, err = g.ProcessRequest(ctx, `{ items(count: 3) { Id } }`, "")
+	assert.Error(t, err)
+	assert.Equal(t, `{"data":{},"errors":[{"message":"function items returned 3 results, more than the maximum of 2","locations":[{"line":1,"column":9}],"path":["items"]}]}`, result)
+}
+
+func TestResultLimit_Truncate(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name: "items",
+		Function: func(count int) []resultLimitItem {
+			var items []resultLimitItem
+			for i := 0; i < count; i++ {
+				items = append(items, resultLimitItem{Id: i})
+			}
+			return items
+		},
+		ParameterNames:    []string{"count"},
+		MaxResults:        2,
+		ResultLimitPolicy: ResultLimitTruncate,
+	})
+
+	result, err := g.ProcessRequest(ctx, `{ items(count: 5) { Id } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"items":[{"Id":0},{"Id":1}]},"extensions":{"truncated":[{"field":"items","returned":2,"total":5}]}}`, result)
+
+	result, err = g.ProcessRequest(ctx, `{ items(count: 1) { Id } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"items":[{"Id":0}]}}`, result)
+}
@@ -0,0 +1,185 @@
+package quickgraph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// idScalarName is the name of the built-in ID scalar.
+const idScalarName = "ID"
+
+// RegisterScalar sets the scalar that represents a named Go type with a bool, number, or
+// string underlying type, such as `type UserID int`. The name can be that of a built-in
+// scalar, such as "ID", or of a custom scalar, which is added to the schema. This takes
+// precedence over NamedScalars, so it can also keep a type as the built-in scalar when
+// NamedScalars is set:
+//
+//	g.RegisterScalar(ctx, UserID(0), "ID")
+//	g.RegisterScalar(ctx, Title(""), "String")
+//
+// Like the built-in ID scalar, the values of types that are represented by ID can be given
+// as strings or as integers in requests, whether the Go type is a string or an integer.
+// This applies to arguments and to variables that are IDs or lists of IDs.
+//
+// Registering a type that isn't a scalar, or an enum, panics, as does representing
+// anything but strings and integers as ID.
+func (g *Graphy) RegisterScalar(ctx context.Context, typ any, name string) {
+	g.structureLock.Lock()
+	defer g.structureLock.Unlock()
+
+	baseType := reflect.TypeOf(typ)
+	if baseType.Kind() == reflect.Ptr {
+		baseType = baseType.Elem()
+	}
+	if !isScalarKind(baseType.Kind()) {
+		panic(fmt.Sprintf("scalar: %v is not a bool, number, or string", baseType))
+	}
+	if baseType.ConvertibleTo(stringEnumValuesType) {
+		panic(fmt.Sprintf("scalar: %v is an enum", baseType))
+	}
+	if name == idScalarName && baseType.Kind() != reflect.String && !isIntegerKind(baseType.Kind()) {
+		panic(fmt.Sprintf("scalar: %v can't be represented by ID", baseType))
+	}
+
+	if g.scalars == nil {
+		g.scalars = map[reflect.Type]string{}
+	}
+	g.scalars[baseType] = name
+	g.invalidateSchema()
+}
+
+// namedScalar returns the name of the scalar that represents the type if it is set with
+// RegisterScalar or, with NamedScalars, named after the Go type. Lists are represented
+// by the scalar of their elements.
+func (g *Graphy) namedScalar(tl *typeLookup) (string, bool) {
+	if !tl.fundamental || tl.rootType == nil || tl.rootType == timeType || g.isEnum(tl) {
+		return "", false
+	}
+	if name, ok := g.scalars[tl.rootType]; ok {
+		return name, true
+	}
+	if g.NamedScalars && tl.rootType.PkgPath() != "" && isScalarKind(tl.rootType.Kind()) {
+		return tl.name, true
+	}
+	return "", false
+}
+
+// customScalarNames returns the names of the scalars that represent the types, other than
+// the built-in ones and DateTime, sorted by name.
+func (g *Graphy) customScalarNames(types ...[]*typeLookup) []string {
+	names := map[string]bool{}
+	for _, list := range types {
+		for _, tl := range list {
+			name, ok := g.namedScalar(tl)
+			if ok && !builtInScalars[name] && name != dateTimeScalarName {
+				names[name] = true
+			}
+		}
+	}
+	result := keys(names)
+	sort.Strings(result)
+	return result
+}
+
+// isIDScalar reports whether values of the type are represented by the ID scalar. This
+// doesn't include lists of them.
+func (g *Graphy) isIDScalar(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Slice {
+		return false
+	}
+	name, ok := g.namedScalar(g.typeLookup(typ))
+	return ok && name == idScalarName
+}
+
+// parseIDIntoValue parses a string or integer literal into a value of a type that is
+// represented by the ID scalar.
+func parseIDIntoValue(inValue genericValue, targetValue reflect.Value) error {
+	if inValue.Int != nil {
+		if targetValue.Kind() == reflect.String {
+			targetValue.SetString(strconv.FormatInt(*inValue.Int, 10))
+			return nil
+		}
+		return parseIntIntoValue(*inValue.Int, targetValue)
+	}
+
+	// The string value has quotes around it, remove them.
+	s := (*inValue.String)[1 : len(*inValue.String)-1]
+	if targetValue.Kind() == reflect.String {
+		targetValue.SetString(s)
+		return nil
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("ID %q is not valid for %v", s, targetValue.Type())
+	}
+	return parseIntIntoValue(i, targetValue)
+}
+
+// coerceJsonIDs converts the JSON of a variable whose type is represented by the ID
+// scalar, or of a list of them, to the JSON of the Go type: IDs can be given as strings or
+// as integers, whatever the Go type is.
+func (g *Graphy) coerceJsonIDs(raw json.RawMessage, typ reflect.Type) json.RawMessage {
+	elemType := typ
+	for elemType.Kind() == reflect.Ptr || elemType.Kind() == reflect.Slice {
+		elemType = elemType.Elem()
+	}
+	if !g.isIDScalar(elemType) {
+		return raw
+	}
+	return coerceJsonID(raw, typ)
+}
+
+func coerceJsonID(raw json.RawMessage, typ reflect.Type) json.RawMessage {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 {
+		return raw
+	}
+
+	if typ.Kind() == reflect.Slice {
+		var items []json.RawMessage
+		if trimmed[0] != '[' || json.Unmarshal(trimmed, &items) != nil {
+			return raw
+		}
+		for i, item := range items {
+			items[i] = coerceJsonID(item, typ.Elem())
+		}
+		result, _ := json.Marshal(items)
+		return result
+	}
+
+	isNumber := trimmed[0] == '-' || (trimmed[0] >= '0' && trimmed[0] <= '9')
+	switch {
+	case trimmed[0] == '"' && typ.Kind() != reflect.String:
+		var s string
+		if json.Unmarshal(trimmed, &s) == nil {
+			if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return json.RawMessage(s)
+			}
+		}
+	case isNumber && typ.Kind() == reflect.String:
+		quoted, _ := json.Marshal(string(trimmed))
+		return quoted
+	}
+	return raw
+}
+
+func isScalarKind(kind reflect.Kind) bool {
+	return kind == reflect.Bool || kind == reflect.String || kind == reflect.Float32 || kind == reflect.Float64 || isIntegerKind(kind)
+}
+
+func isIntegerKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
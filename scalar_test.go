@@ -0,0 +1,105 @@
+package quickgraph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type scalarUserID int
+
+type scalarEmail string
+
+type scalarTitle string
+
+type scalarUser struct {
+	ID      scalarUserID   `json:"id"`
+	Email   *scalarEmail   `json:"email"`
+	Title   scalarTitle    `json:"title"`
+	Friends []scalarUserID `json:"friends"`
+}
+
+func TestGraphy_NamedScalarsSchema(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{NamedScalars: true}
+	g.RegisterScalar(ctx, scalarUserID(0), "ID")
+	g.RegisterScalar(ctx, scalarTitle(""), "String")
+	g.RegisterQuery(ctx, "user", func(id scalarUserID, email scalarEmail) scalarUser {
+		return scalarUser{ID: id, Email: &email, Title: "Captain", Friends: []scalarUserID{id + 1}}
+	}, "id", "email")
+	g.RegisterQuery(ctx, "users", func(ids []scalarUserID) []scalarUserID { return ids }, "ids")
+
+	assert.Equal(t, `type Query {
+	user(id: ID!, email: scalarEmail!): scalarUser!
+	users(ids: [ID!]!): [ID!]!
+}
+
+type scalarUser {
+	email: scalarEmail
+	friends: [ID!]!
+	id: ID!
+	title: String!
+}
+
+scalar scalarEmail
+
+`, g.SchemaDefinition(ctx))
+
+	email, ok := g.Schema().Type("scalarEmail")
+	assert.True(t, ok)
+	assert.Equal(t, "SCALAR", email.Kind)
+	_, ok = g.Schema().Type("scalarTitle")
+	assert.False(t, ok)
+	user, _ := g.Schema().Operation("user")
+	assert.Equal(t, []ArgInfo{{Name: "id", Type: "ID!"}, {Name: "email", Type: "scalarEmail!"}}, user.Args)
+
+	// Without NamedScalars, only the registered types are represented by other scalars.
+	g.NamedScalars = false
+	g.invalidateSchema()
+	assert.Contains(t, g.SchemaDefinition(ctx), "user(id: ID!, email: String!): scalarUser!")
+	assert.NotContains(t, g.SchemaDefinition(ctx), "scalar ")
+}
+
+func TestGraphy_NamedScalarsInput(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{NamedScalars: true}
+	g.RegisterScalar(ctx, scalarUserID(0), "ID")
+	g.RegisterScalar(ctx, scalarTitle(""), "String")
+	g.RegisterQuery(ctx, "user", func(id scalarUserID, email scalarEmail) scalarUser {
+		return scalarUser{ID: id, Email: &email, Title: "Captain", Friends: []scalarUserID{id + 1}}
+	}, "id", "email")
+	g.RegisterQuery(ctx, "users", func(ids []scalarUserID) []scalarUserID { return ids }, "ids")
+
+	// IDs can be given as strings or integers.
+	result, err := g.ProcessRequest(ctx, `{ user(id: "42", email: "a@b") { id email friends } users(ids: [1, "2"]) }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"user":{"email":"a@b","friends":[43],"id":42},"users":[1,2]}}`, result)
+
+	result, err = g.ProcessRequest(ctx, `query Users($id: ID!, $ids: [ID!]!) { user(id: $id, email: "a@b") { id } users(ids: $ids) }`, `{"id": "7", "ids": ["8", 9]}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"user":{"id":7},"users":[8,9]}}`, result)
+
+	result, err = g.ProcessRequest(ctx, `{ user(id: "abc", email: "a@b") { id } }`, "")
+	assert.Error(t, err)
+	assert.Contains(t, result, `ID \"abc\" is not valid for quickgraph.scalarUserID`)
+
+	// Errors name the scalars that the values should have.
+	_, err = g.ProcessRequest(ctx, `{ user(id: 1, email: 5) { id } }`, "")
+	assert.ErrorContains(t, err, "expected scalarEmail for argument email, got Int")
+	_, err = g.ProcessRequest(ctx, `query User($email: scalarEmail!) { user(id: 1, email: $email) { id } }`, `{"email": 5}`)
+	assert.ErrorContains(t, err, "error parsing variable email into type scalarEmail!")
+}
+
+func TestGraphy_RegisterScalarPanics(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	assert.PanicsWithValue(t, "scalar: quickgraph.scalarUser is not a bool, number, or string", func() {
+		g.RegisterScalar(ctx, scalarUser{}, "User")
+	})
+	assert.PanicsWithValue(t, "scalar: quickgraph.episode is an enum", func() {
+		g.RegisterScalar(ctx, episode(""), "Episode")
+	})
+	assert.PanicsWithValue(t, "scalar: float64 can't be represented by ID", func() {
+		g.RegisterScalar(ctx, 1.5, "ID")
+	})
+}
@@ -2,6 +2,8 @@ package quickgraph
 
 import (
 	"context"
+	"fmt"
+	"reflect"
 	"sort"
 	"strings"
 )
@@ -27,21 +29,31 @@ type schemaTypes struct {
 	enumTypesByName   typeNameLookup
 
 	introspectionSchema *__Schema
+	model               *SchemaModel
+
+	warnings []SchemaWarning
+
+	// generation is the schema generation that the types were built for.
+	generation uint64
 }
 
 func (g *Graphy) SchemaDefinition(ctx context.Context) string {
 	g.structureLock.RLock()
 	defer g.structureLock.RUnlock()
 
-	st := g.getSchemaTypes()
+	return g.schemaDefinition(g.getSchemaTypes(), nil)
+}
 
+// schemaDefinition writes the schema of the functions that include accepts, or of all of
+// them if it is nil, using the types in st.
+func (g *Graphy) schemaDefinition(st *schemaTypes, include func(*graphFunction) bool) string {
 	sb := strings.Builder{}
 
 	procByMode := map[GraphFunctionMode][]*graphFunction{}
 
 	for _, function := range g.processors {
 		function := function
-		if strings.HasPrefix(function.name, "__") {
+		if strings.HasPrefix(function.name, "__") || (include != nil && !include(&function)) {
 			continue
 		}
 		byMode, ok := procByMode[function.mode]
@@ -70,6 +82,7 @@ func (g *Graphy) SchemaDefinition(ctx context.Context) string {
 		})
 
 		for _, function := range functions {
+			writeSchemaDescription(&sb, function.schemaDescription())
 			sb.WriteString("\t")
 			sb.WriteString(function.name)
 			if len(function.paramsByName) > 0 {
@@ -83,6 +96,11 @@ func (g *Graphy) SchemaDefinition(ctx context.Context) string {
 			schemaRef := g.schemaRefForType(function.baseReturnType, st.outputTypeNameLookup)
 
 			sb.WriteString(schemaRef)
+			if function.isDeprecated() {
+				sb.WriteString(" @deprecated(reason: \"")
+				sb.WriteString(function.deprecationReason())
+				sb.WriteString("\")")
+			}
 			sb.WriteString("\n")
 		}
 		sb.WriteString("}\n\n")
@@ -97,25 +115,81 @@ func (g *Graphy) SchemaDefinition(ctx context.Context) string {
 	enumSchema := g.schemaForEnumTypes(st.enumTypes...)
 	sb.WriteString(enumSchema)
 
+	sb.WriteString(g.schemaForOutputDirectives())
+	sb.WriteString(g.schemaForDirectives())
+	if g.ConstraintDirectives {
+		sb.WriteString(constraintDirectiveDefinition)
+	}
+
+	if usesDateTime(st.inputTypes) || usesDateTime(st.outputTypes) {
+		sb.WriteString("scalar ")
+		sb.WriteString(dateTimeScalarName)
+		sb.WriteString("\n\n")
+	}
+	for _, name := range g.customScalarNames(st.inputTypes, st.outputTypes) {
+		sb.WriteString("scalar ")
+		sb.WriteString(name)
+		sb.WriteString("\n\n")
+	}
+
 	return sb.String()
 }
 
+// SchemaGeneration returns a number that changes whenever the schema may have changed,
+// such as when a function or type is registered. Servers that cache anything derived from
+// the schema, such as the SDL or the introspection result, can compare it to the number
+// that the cached copy was made with instead of rebuilding the copy for each request.
+func (g *Graphy) SchemaGeneration() uint64 {
+	return g.schemaGeneration.Load()
+}
+
+// invalidateSchema starts a new schema generation so that the schema types are built
+// again the next time they are needed.
+func (g *Graphy) invalidateSchema() {
+	g.schemaGeneration.Add(1)
+}
+
+// getSchemaTypes returns the schema types of the current generation, building them if
+// they haven't been built yet. The result is never modified, so callers can keep using it
+// even if the structure changes while they do.
 func (g *Graphy) getSchemaTypes() *schemaTypes {
-	// We're already in a structure lock, so we are good making this check without
-	// a lock.
-	if g.schemaBuffer != nil {
-		return g.schemaBuffer
+	generation := g.schemaGeneration.Load()
+	if st := g.schemaBuffer.Load(); st != nil && st.generation == generation {
+		return st
 	}
 
 	g.schemaLock.Lock()
 	defer g.schemaLock.Unlock()
 
-	// Check it again in case to cover a race condition.
-	if g.schemaBuffer != nil {
-		return g.schemaBuffer
+	// Check it again in case another request built them while we were waiting.
+	generation = g.schemaGeneration.Load()
+	if st := g.schemaBuffer.Load(); st != nil && st.generation == generation {
+		return st
 	}
 
-	outputTypes, inputTypes, enumTypes := g.processFunctionsForSchema()
+	st := g.buildSchemaTypes(nil)
+	st.generation = generation
+
+	g.populateIntrospection(st)
+	st.model = newSchemaModel(st.introspectionSchema)
+
+	st.warnings = g.unreachableTypeWarnings(st.inputTypes, st.outputTypes, st.enumTypes)
+	if g.SchemaWarningHandler != nil {
+		for _, warning := range st.warnings {
+			g.SchemaWarningHandler(warning)
+		}
+	}
+
+	// The types are only published once they are complete, so that concurrent readers
+	// never see them half built.
+	g.schemaBuffer.Store(st)
+	return st
+}
+
+// buildSchemaTypes collects the types that the functions that include accepts, or all the
+// functions if it is nil, use and names them.
+func (g *Graphy) buildSchemaTypes(include func(*graphFunction) bool) *schemaTypes {
+	outputTypes, inputTypes, enumTypes := g.processFunctionsForSchema(include)
 
 	inputTypes = g.expandTypeLookups(inputTypes)
 	outputTypes = g.expandTypeLookups(outputTypes)
@@ -123,7 +197,7 @@ func (g *Graphy) getSchemaTypes() *schemaTypes {
 	inputMapping, outputMapping := solveInputOutputNameMapping(inputTypes, outputTypes)
 	enumMapping := createEnumMapping(enumTypes)
 
-	g.schemaBuffer = &schemaTypes{
+	return &schemaTypes{
 		inputTypes:  inputTypes,
 		outputTypes: outputTypes,
 		enumTypes:   enumTypes,
@@ -136,22 +210,18 @@ func (g *Graphy) getSchemaTypes() *schemaTypes {
 		outputTypesByName: makeTypeNameLookup(outputMapping),
 		enumTypesByName:   makeTypeNameLookup(enumMapping),
 	}
-
-	g.populateIntrospection(g.schemaBuffer)
-
-	return g.schemaBuffer
 }
 
-func (g *Graphy) processFunctionsForSchema() ([]*typeLookup, []*typeLookup, []*typeLookup) {
+func (g *Graphy) processFunctionsForSchema(include func(*graphFunction) bool) ([]*typeLookup, []*typeLookup, []*typeLookup) {
 	var outputTypes []*typeLookup
 	var inputTypes []*typeLookup
 	var enumTypes []*typeLookup
 
 	for _, proc := range g.processors {
-		if strings.HasPrefix(proc.name, "__") {
+		function := &proc
+		if strings.HasPrefix(proc.name, "__") || (include != nil && !include(function)) {
 			continue
 		}
-		function := &proc
 		inputMap := make(usageMap)
 		outputMap := make(usageMap)
 
@@ -160,16 +230,16 @@ func (g *Graphy) processFunctionsForSchema() ([]*typeLookup, []*typeLookup, []*t
 		fInput := keys(inputMap)
 		fOutput := keys(outputMap)
 
-		outputTypes, enumTypes = appendTypesForSchema(outputTypes, enumTypes, fOutput)
-		inputTypes, enumTypes = appendTypesForSchema(inputTypes, enumTypes, fInput)
+		outputTypes, enumTypes = g.appendTypesForSchema(outputTypes, enumTypes, fOutput)
+		inputTypes, enumTypes = g.appendTypesForSchema(inputTypes, enumTypes, fInput)
 	}
 
 	return outputTypes, inputTypes, enumTypes
 }
 
-func appendTypesForSchema(types []*typeLookup, enumTypes []*typeLookup, newTypes []*typeLookup) ([]*typeLookup, []*typeLookup) {
+func (g *Graphy) appendTypesForSchema(types []*typeLookup, enumTypes []*typeLookup, newTypes []*typeLookup) ([]*typeLookup, []*typeLookup) {
 	for _, typeLookup := range newTypes {
-		if typeLookup.rootType != nil && typeLookup.rootType.AssignableTo(stringEnumValuesType) {
+		if g.isEnum(typeLookup) {
 			enumTypes = append(enumTypes, typeLookup)
 		} else {
 			types = append(types, typeLookup)
@@ -261,24 +331,13 @@ func (g *Graphy) recursiveAddTypeLookup(tl *typeLookup, typeMap map[*typeLookup]
 func (g *Graphy) schemaForFunctionParameters(f *graphFunction, mapping typeNameMapping) string {
 	sb := strings.Builder{}
 
-	mappings := []functionParamNameMapping{}
-	for _, param := range f.paramsByName {
-		mappings = append(mappings, param)
-	}
-	// Sort by index
-	sort.Slice(mappings, func(i, j int) bool {
-		return mappings[i].paramIndex < mappings[j].paramIndex
-	})
-
-	for i, param := range mappings {
+	for i, param := range f.orderedParams() {
 		if i > 0 {
 			sb.WriteString(", ")
 		}
-		sb.WriteString(param.name)
-		sb.WriteString(": ")
 		paramTl := g.typeLookup(param.paramType)
 		schemaRef := g.schemaRefForType(paramTl, mapping)
-		sb.WriteString(schemaRef)
+		sb.WriteString(schemaForParameter(param, schemaRef))
 	}
 
 	return sb.String()
@@ -324,3 +383,63 @@ func (g *Graphy) gatherTypeInputsOutputs(tl *typeLookup, io TypeKind, inputTypes
 		g.gatherTypeInputsOutputs(tl, io, inputTypes, outputTypes)
 	}
 }
+
+// usesDateTime reports whether any of the types are represented by the DateTime scalar.
+func usesDateTime(types []*typeLookup) bool {
+	for _, t := range types {
+		if t.rootType == timeType {
+			return true
+		}
+	}
+	return false
+}
+
+// SchemaWarning describes a likely configuration mistake that was found when the schema
+// was generated.
+type SchemaWarning struct {
+	// TypeName is the name of the type that the warning is about.
+	TypeName string
+	Message  string
+}
+
+func (w SchemaWarning) String() string {
+	return w.Message
+}
+
+// SchemaWarnings returns the problems found when generating the schema, sorted by type
+// name. Presently these are the types registered with RegisterTypes or RegisterAnyType
+// that can't be reached from any query or mutation, and so are not part of the schema.
+func (g *Graphy) SchemaWarnings() []SchemaWarning {
+	g.structureLock.RLock()
+	defer g.structureLock.RUnlock()
+
+	return g.getSchemaTypes().warnings
+}
+
+// unreachableTypeWarnings finds the explicitly registered types that are not part of the
+// schema. A type counts as reachable if it is used through a pointer or a slice as well.
+func (g *Graphy) unreachableTypeWarnings(typeLists ...[]*typeLookup) []SchemaWarning {
+	reachable := map[reflect.Type]bool{}
+	for _, types := range typeLists {
+		for _, tl := range types {
+			reachable[tl.rootType] = true
+		}
+	}
+
+	reported := map[reflect.Type]bool{}
+	var warnings []SchemaWarning
+	for _, tl := range append(append([]*typeLookup{}, g.registeredTypes...), g.anyTypes...) {
+		if reachable[tl.rootType] || reported[tl.rootType] {
+			continue
+		}
+		reported[tl.rootType] = true
+		warnings = append(warnings, SchemaWarning{
+			TypeName: tl.name,
+			Message:  fmt.Sprintf("type %s is registered but not reachable from any query or mutation", tl.name),
+		})
+	}
+	sort.Slice(warnings, func(i, j int) bool {
+		return warnings[i].TypeName < warnings[j].TypeName
+	})
+	return warnings
+}
@@ -0,0 +1,87 @@
+package quickgraph
+
+import (
+	"context"
+	"strings"
+)
+
+// SchemaOperation describes a query or mutation to a SchemaFilter.
+type SchemaOperation struct {
+	Name     string
+	Mutation bool
+
+	// Tags are the Tags from the FunctionDefinition of the operation.
+	Tags []string
+}
+
+// HasTag reports whether the operation has the tag.
+func (op SchemaOperation) HasTag(tag string) bool {
+	for _, t := range op.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// SchemaFilter decides which operations a schema from SchemaDefinitionFiltered includes.
+type SchemaFilter func(ctx context.Context, op SchemaOperation) bool
+
+// SchemaFilterTags returns a SchemaFilter that includes the operations that have any of
+// the tags.
+func SchemaFilterTags(tags ...string) SchemaFilter {
+	return func(ctx context.Context, op SchemaOperation) bool {
+		for _, tag := range tags {
+			if op.HasTag(tag) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// SchemaFilterPrefix returns a SchemaFilter that includes the operations whose names
+// start with the prefix, for services that put the operations of each area in a
+// namespace of their own, like "billing_invoices".
+func SchemaFilterPrefix(prefix string) SchemaFilter {
+	return func(ctx context.Context, op SchemaOperation) bool {
+		return strings.HasPrefix(op.Name, prefix)
+	}
+}
+
+// SchemaFilter returns a SchemaFilter that includes the operations that a caller with the
+// roles may call.
+func (oa *OperationAccess) SchemaFilter(roles ...string) SchemaFilter {
+	return func(ctx context.Context, op SchemaOperation) bool {
+		return oa.allowed(ctx, roles, op.Name)
+	}
+}
+
+// SchemaDefinitionFiltered returns the schema like SchemaDefinition, but with only the
+// queries and mutations that the filter accepts, and only the types that they use. This
+// makes it possible to publish a subset of the schema, such as the operations for
+// external partners, without revealing the internal ones:
+//
+//	sdl := g.SchemaDefinitionFiltered(ctx, quickgraph.SchemaFilterTags("public"))
+//
+// Only the document is filtered; the operations that are left out can still be called.
+// Use OperationAccess to restrict that. Unlike the full schema, the filtered one is built
+// each time it is asked for.
+func (g *Graphy) SchemaDefinitionFiltered(ctx context.Context, filter SchemaFilter) string {
+	g.structureLock.RLock()
+	defer g.structureLock.RUnlock()
+
+	include := func(f *graphFunction) bool {
+		return filter(ctx, f.schemaOperation())
+	}
+	return g.schemaDefinition(g.buildSchemaTypes(include), include)
+}
+
+// schemaOperation describes the function to a SchemaFilter.
+func (f *graphFunction) schemaOperation() SchemaOperation {
+	return SchemaOperation{
+		Name:     f.name,
+		Mutation: f.mode == ModeMutation,
+		Tags:     f.tags,
+	}
+}
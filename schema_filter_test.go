@@ -0,0 +1,97 @@
+package quickgraph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type filterProduct struct {
+	Name  string
+	Price int
+}
+
+type filterAudit struct {
+	Actor string
+}
+
+type filterAuditQuery struct {
+	Since string
+}
+
+func TestGraphy_SchemaDefinitionFiltered(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name:     "products",
+		Function: func() []filterProduct { return nil },
+		Tags:     []string{"public"},
+	})
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name:     "internal_audit",
+		Function: func(q filterAuditQuery) []filterAudit { return nil },
+	})
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name:           "internal_reprice",
+		Function:       func(name string) filterProduct { return filterProduct{} },
+		ParameterNames: []string{"name"},
+		Mode:           ModeMutation,
+		Tags:           []string{"admin"},
+	})
+
+	expected := `type Query {
+	products: [filterProduct!]!
+}
+
+type filterProduct {
+	Name: String!
+	Price: Int!
+}
+
+`
+	assert.Equal(t, expected, g.SchemaDefinitionFiltered(ctx, SchemaFilterTags("public")))
+
+	schema := g.SchemaDefinitionFiltered(ctx, SchemaFilterPrefix("internal_"))
+	assert.NotContains(t, schema, "products")
+	assert.Contains(t, schema, "internal_audit(Since: String!): [filterAudit!]!")
+	assert.Contains(t, schema, "type Mutation {\n\tinternal_reprice(name: String!): filterProduct!\n}")
+
+	// The full schema isn't affected, and neither are the requests.
+	assert.Contains(t, g.SchemaDefinition(ctx), "internal_audit")
+	_, err := g.ProcessRequest(ctx, `{ internal_audit(Since: "x") { Actor } }`, "")
+	assert.NoError(t, err)
+}
+
+func TestOperationAccess_SchemaFilter(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name:     "products",
+		Function: func() []filterProduct { return nil },
+		Tags:     []string{"public"},
+	})
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name:     "internal_audit",
+		Function: func(q filterAuditQuery) []filterAudit { return nil },
+	})
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name:           "internal_reprice",
+		Function:       func(name string) filterProduct { return filterProduct{} },
+		ParameterNames: []string{"name"},
+		Mode:           ModeMutation,
+		Tags:           []string{"admin"},
+	})
+	oa := &OperationAccess{
+		Public:  []string{"products"},
+		Allowed: map[string][]string{"auditor": {"internal_audit"}},
+	}
+
+	schema := g.SchemaDefinitionFiltered(ctx, oa.SchemaFilter())
+	assert.Contains(t, schema, "products")
+	assert.NotContains(t, schema, "internal_")
+
+	schema = g.SchemaDefinitionFiltered(ctx, oa.SchemaFilter("auditor"))
+	assert.Contains(t, schema, "products")
+	assert.Contains(t, schema, "internal_audit")
+	assert.NotContains(t, schema, "internal_reprice")
+}
@@ -0,0 +1,158 @@
+package quickgraph
+
+import (
+	"encoding/json"
+)
+
+// SchemaJSONVersion is the version of the JSON format of the SchemaModel. It is increased
+// when the format changes in a way that isn't backward compatible; fields may be added
+// without changing it.
+const SchemaJSONVersion = 1
+
+// schemaJSON is the JSON format of the SchemaModel. Unlike the introspection result, it
+// is flat and holds the fields with their types, so that it can be read without a GraphQL
+// client.
+type schemaJSON struct {
+	Version    int                   `json:"version"`
+	Queries    []schemaFieldJSON     `json:"queries"`
+	Mutations  []schemaFieldJSON     `json:"mutations"`
+	Types      []schemaTypeJSON      `json:"types"`
+	Directives []schemaDirectiveJSON `json:"directives"`
+}
+
+type schemaTypeJSON struct {
+	Name          string            `json:"name"`
+	Kind          string            `json:"kind"`
+	Description   string            `json:"description,omitempty"`
+	Interfaces    []string          `json:"interfaces,omitempty"`
+	PossibleTypes []string          `json:"possibleTypes,omitempty"`
+	EnumValues    []string          `json:"enumValues,omitempty"`
+	Fields        []schemaFieldJSON `json:"fields,omitempty"`
+}
+
+type schemaFieldJSON struct {
+	Name        string                       `json:"name"`
+	Type        string                       `json:"type"`
+	Description string                       `json:"description,omitempty"`
+	Args        []schemaArgJSON              `json:"args,omitempty"`
+	Directives  []schemaAppliedDirectiveJSON `json:"directives,omitempty"`
+}
+
+type schemaArgJSON struct {
+	Name         string                       `json:"name"`
+	Type         string                       `json:"type"`
+	Description  string                       `json:"description,omitempty"`
+	DefaultValue *string                      `json:"defaultValue,omitempty"`
+	Directives   []schemaAppliedDirectiveJSON `json:"directives,omitempty"`
+}
+
+// schemaAppliedDirectiveJSON is a directive that is applied to a field, such as
+// @deprecated, with the values of its arguments by name.
+type schemaAppliedDirectiveJSON struct {
+	Name string         `json:"name"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+type schemaDirectiveJSON struct {
+	Name         string          `json:"name"`
+	Description  string          `json:"description,omitempty"`
+	Locations    []string        `json:"locations"`
+	Args         []schemaArgJSON `json:"args,omitempty"`
+	IsRepeatable bool            `json:"isRepeatable,omitempty"`
+}
+
+// MarshalJSON returns the model in a stable JSON format for tools that catalog schemas,
+// such as developer portals:
+//
+//	{
+//	  "version": 1,
+//	  "queries": [{"name": "user", "type": "User", "args": [{"name": "id", "type": "String!"}]}],
+//	  "mutations": [...],
+//	  "types": [{"name": "User", "kind": "OBJECT", "fields": [...]}],
+//	  "directives": [{"name": "include", "locations": ["FIELD"], "args": [...]}]
+//	}
+//
+// Deprecations are given as applied @deprecated directives of the fields. See
+// SchemaJSONVersion for the versioning of the format.
+func (m *SchemaModel) MarshalJSON() ([]byte, error) {
+	s := schemaJSON{
+		Version:    SchemaJSONVersion,
+		Queries:    []schemaFieldJSON{},
+		Mutations:  []schemaFieldJSON{},
+		Types:      []schemaTypeJSON{},
+		Directives: []schemaDirectiveJSON{},
+	}
+
+	for _, op := range m.operations {
+		f := schemaFieldJSON{
+			Name:        op.Name,
+			Type:        op.Type,
+			Description: op.Description,
+			Args:        schemaArgsJSON(op.Args),
+			Directives:  deprecatedDirectiveJSON(op.IsDeprecated, op.DeprecationReason),
+		}
+		if op.Mode == ModeMutation {
+			s.Mutations = append(s.Mutations, f)
+		} else {
+			s.Queries = append(s.Queries, f)
+		}
+	}
+
+	for _, t := range m.types {
+		tj := schemaTypeJSON{
+			Name:          t.Name,
+			Kind:          t.Kind,
+			Description:   t.Description,
+			Interfaces:    t.Interfaces,
+			PossibleTypes: t.PossibleTypes,
+			EnumValues:    t.EnumValues,
+		}
+		for _, f := range m.fields[t.Name] {
+			tj.Fields = append(tj.Fields, schemaFieldJSON{
+				Name:        f.Name,
+				Type:        f.Type,
+				Description: f.Description,
+				Args:        schemaArgsJSON(f.Args),
+				Directives:  deprecatedDirectiveJSON(f.IsDeprecated, f.DeprecationReason),
+			})
+		}
+		s.Types = append(s.Types, tj)
+	}
+
+	for _, d := range m.directives {
+		s.Directives = append(s.Directives, schemaDirectiveJSON{
+			Name:         d.Name,
+			Description:  d.Description,
+			Locations:    d.Locations,
+			Args:         schemaArgsJSON(d.Args),
+			IsRepeatable: d.IsRepeatable,
+		})
+	}
+
+	return json.Marshal(s)
+}
+
+func schemaArgsJSON(args []ArgInfo) []schemaArgJSON {
+	var result []schemaArgJSON
+	for _, a := range args {
+		result = append(result, schemaArgJSON{
+			Name:         a.Name,
+			Type:         a.Type,
+			Description:  a.Description,
+			DefaultValue: a.DefaultValue,
+			Directives:   deprecatedDirectiveJSON(a.IsDeprecated, a.DeprecationReason),
+		})
+	}
+	return result
+}
+
+func deprecatedDirectiveJSON(deprecated bool, reason string) []schemaAppliedDirectiveJSON {
+	if !deprecated {
+		return nil
+	}
+	d := schemaAppliedDirectiveJSON{Name: "deprecated"}
+	if reason != "" {
+		d.Args = map[string]any{"reason": reason}
+	}
+	return []schemaAppliedDirectiveJSON{d}
+}
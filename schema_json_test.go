@@ -0,0 +1,74 @@
+package quickgraph
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestSchemaModel_MarshalJSON(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name:           "find",
+		Function:       func(name string) []inspectResult { return nil },
+		ParameterNames: []string{"name"},
+		Since:          "1.2",
+	})
+	g.RegisterMutation(ctx, "create", func(input inspectInput) inspectResult { return inspectResult{} }, "input")
+	g.RegisterOutputDirective(ctx, OutputDirective{
+		Name:        "uppercase",
+		Description: "Converts a string to upper case.",
+		Handler:     strings.ToUpper,
+	})
+
+	payload, err := json.Marshal(g.Schema())
+	assert.NoError(t, err)
+
+	var s schemaJSON
+	err = json.Unmarshal(payload, &s)
+	assert.NoError(t, err)
+
+	assert.Equal(t, SchemaJSONVersion, s.Version)
+	assert.Equal(t, []schemaFieldJSON{{
+		Name:        "find",
+		Type:        "[inspectResult!]!",
+		Description: "Since 1.2.",
+		Args:        []schemaArgJSON{{Name: "name", Type: "string!"}},
+	}}, s.Queries)
+	assert.Equal(t, []schemaFieldJSON{{
+		Name: "create",
+		Type: "inspectResult!",
+		Args: []schemaArgJSON{{Name: "input", Type: "inspectInput!"}},
+	}}, s.Mutations)
+
+	types := map[string]schemaTypeJSON{}
+	for _, typ := range s.Types {
+		types[typ.Name] = typ
+	}
+	assert.Equal(t, schemaTypeJSON{
+		Name: "inspectResult",
+		Kind: "OBJECT",
+		Fields: []schemaFieldJSON{
+			{Name: "Greeting", Type: "string!", Args: []schemaArgJSON{{Name: "arg1", Type: "string!"}}},
+			{Name: "Legacy", Type: "string!", Directives: []schemaAppliedDirectiveJSON{
+				{Name: "deprecated", Args: map[string]any{"reason": "Use Name"}},
+			}},
+			{Name: "Name", Type: "string!"},
+			{Name: "Tags", Type: "[string!]!"},
+		},
+	}, types["inspectResult"])
+	assert.Equal(t, "INPUT_OBJECT", types["inspectInput"].Kind)
+
+	// The @include and @skip directives are always there.
+	if assert.Len(t, s.Directives, 3) {
+		assert.Equal(t, []string{"include", "skip"}, []string{s.Directives[0].Name, s.Directives[1].Name})
+		assert.Equal(t, schemaDirectiveJSON{
+			Name:        "uppercase",
+			Description: "Converts a string to upper case.",
+			Locations:   []string{"FIELD"},
+		}, s.Directives[2])
+	}
+}
@@ -0,0 +1,130 @@
+package quickgraph
+
+// SchemaModel is a read-only description of the schema of a Graphy instance. It contains
+// the same information as the schema definition and introspection, in a form that Go code
+// can use directly, for instance to generate documentation or check permissions. Use
+// Graphy.Schema to get one.
+//
+// The model is a snapshot: it doesn't change when functions or types are registered
+// afterwards. The slices that its methods return are copies, but the values in them are
+// shared and should not be modified.
+type SchemaModel struct {
+	operations  []OperationInfo
+	types       []TypeInfo
+	typesByName map[string]TypeInfo
+	fields      map[string][]FieldInfo
+	directives  []DirectiveInfo
+}
+
+// DirectiveInfo describes a directive of the schema.
+type DirectiveInfo struct {
+	Name         string
+	Description  string
+	Locations    []string
+	Args         []ArgInfo
+	IsRepeatable bool
+}
+
+// Schema returns a model of the current schema. The model is built along with the schema
+// definition and is reused until the schema changes.
+func (g *Graphy) Schema() *SchemaModel {
+	g.structureLock.RLock()
+	defer g.structureLock.RUnlock()
+	return g.getSchemaTypes().model
+}
+
+// Operations returns the queries and mutations, sorted by name, with the queries first.
+func (m *SchemaModel) Operations() []OperationInfo {
+	return append([]OperationInfo{}, m.operations...)
+}
+
+// Operation returns the query or mutation with the given name.
+func (m *SchemaModel) Operation(name string) (OperationInfo, bool) {
+	for _, op := range m.operations {
+		if op.Name == name {
+			return op, true
+		}
+	}
+	return OperationInfo{}, false
+}
+
+// Types returns the named types of the schema, sorted by name.
+func (m *SchemaModel) Types() []TypeInfo {
+	return append([]TypeInfo{}, m.types...)
+}
+
+// Type returns the named type.
+func (m *SchemaModel) Type(name string) (TypeInfo, bool) {
+	t, ok := m.typesByName[name]
+	return t, ok
+}
+
+// Fields returns the fields of the named type, sorted by name. For input types these are
+// the input fields.
+func (m *SchemaModel) Fields(typeName string) []FieldInfo {
+	return append([]FieldInfo{}, m.fields[typeName]...)
+}
+
+// Args returns the arguments of a field of the named type.
+func (m *SchemaModel) Args(typeName, fieldName string) []ArgInfo {
+	for _, f := range m.fields[typeName] {
+		if f.Name == fieldName {
+			return append([]ArgInfo{}, f.Args...)
+		}
+	}
+	return nil
+}
+
+// Directives returns the directives of the schema.
+func (m *SchemaModel) Directives() []DirectiveInfo {
+	return append([]DirectiveInfo{}, m.directives...)
+}
+
+// newSchemaModel builds the model from the introspection model of the schema.
+func newSchemaModel(is *__Schema) *SchemaModel {
+	m := &SchemaModel{
+		typesByName: map[string]TypeInfo{},
+		fields:      map[string][]FieldInfo{},
+	}
+
+	for _, op := range []struct {
+		t    *__Type
+		mode GraphFunctionMode
+	}{{is.Queries, ModeQuery}, {is.Mutations, ModeMutation}} {
+		for _, f := range sortedIntrospectionFields(op.t) {
+			m.operations = append(m.operations, OperationInfo{
+				Name:              f.Name,
+				Mode:              op.mode,
+				Type:              typeRefString(f.Type),
+				Args:              argInfos(f.Args),
+				Description:       derefString(f.Description),
+				IsDeprecated:      f.IsDeprecated,
+				DeprecationReason: derefString(f.DeprecationReason),
+			})
+		}
+	}
+
+	for _, t := range is.Types {
+		if t == is.Queries || t == is.Mutations {
+			continue
+		}
+		info := typeInfo(t)
+		m.types = append(m.types, info)
+		m.typesByName[info.Name] = info
+		if fields := fieldInfos(t); len(fields) > 0 {
+			m.fields[info.Name] = fields
+		}
+	}
+
+	for _, d := range is.Directives {
+		m.directives = append(m.directives, DirectiveInfo{
+			Name:         d.Name,
+			Description:  derefString(d.Description),
+			Locations:    d.Locations,
+			Args:         argInfos(d.Args),
+			IsRepeatable: d.IsRepeatable,
+		})
+	}
+
+	return m
+}
@@ -0,0 +1,52 @@
+package quickgraph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSchemaModel(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name:           "find",
+		Function:       func(name string) []inspectResult { return nil },
+		ParameterNames: []string{"name"},
+		Since:          "1.2",
+	})
+	g.RegisterMutation(ctx, "create", func(input inspectInput) inspectResult { return inspectResult{} }, "input")
+	m := g.Schema()
+
+	op, ok := m.Operation("create")
+	assert.True(t, ok)
+	assert.Equal(t, ModeMutation, op.Mode)
+	assert.Equal(t, "inspectResult!", op.Type)
+	_, ok = m.Operation("missing")
+	assert.False(t, ok)
+	assert.Len(t, m.Operations(), 2)
+
+	result, ok := m.Type("inspectResult")
+	assert.True(t, ok)
+	assert.Equal(t, "OBJECT", result.Kind)
+	_, ok = m.Type("__query")
+	assert.False(t, ok)
+
+	fields := m.Fields("inspectResult")
+	assert.Len(t, fields, 4)
+	assert.Equal(t, []ArgInfo{{Name: "arg1", Type: "string!"}}, m.Args("inspectResult", "Greeting"))
+	assert.Empty(t, m.Args("inspectResult", "Name"))
+	if assert.Len(t, m.Directives(), 2) {
+		assert.Equal(t, "include", m.Directives()[0].Name)
+		assert.Equal(t, []ArgInfo{{Name: "if", Type: "Boolean!"}}, m.Directives()[1].Args)
+	}
+
+	// The returned slices are copies.
+	fields[0].Name = "changed"
+	assert.Equal(t, "Greeting", m.Fields("inspectResult")[0].Name)
+
+	// The model is a snapshot of the schema at the time it was taken.
+	g.RegisterFunction(ctx, FunctionDefinition{Name: "count", Function: func() int { return 0 }})
+	assert.Len(t, m.Operations(), 2)
+	assert.Len(t, g.Schema().Operations(), 3)
+}
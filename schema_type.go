@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -67,15 +68,11 @@ func (g *Graphy) schemaForEnum(et *typeLookup) string {
 
 	sb := strings.Builder{}
 
-	enumValue := reflect.New(et.rootType)
-	sev := enumValue.Convert(stringEnumValuesType)
-	se := sev.Interface().(StringEnumValues)
-
 	sb.WriteString("enum ")
 	sb.WriteString(et.name)
 	sb.WriteString(" {\n")
 
-	for _, s := range se.EnumValues() {
+	for _, s := range g.enumValues(et) {
 		sb.WriteString("\t")
 		sb.WriteString(s.Name) // TODO: Add deprecated support.
 		sb.WriteString("\n")
@@ -114,19 +111,15 @@ func (g *Graphy) getSchemaImplementedInterfaces(t *typeLookup, mapping typeNameM
 		return ""
 	}
 
-	sb := &strings.Builder{}
-	sb.WriteString(" implements")
-	interfaceCount := 0
+	// Embedding is transitive, so an interface that embeds another one lists it here,
+	// as does every type that implements the interface: interface B implements A.
+	var names []string
 	for _, implementedType := range t.implements {
-		if interfaceCount > 0 {
-			sb.WriteString("& ")
-		}
-		interfaceCount++
-		sb.WriteString(" ")
-		sb.WriteString(mapping[implementedType])
+		names = append(names, mapping[implementedType])
 	}
+	sort.Strings(names)
 
-	return sb.String()
+	return " implements " + strings.Join(names, " & ")
 }
 
 func (g *Graphy) getSchemaFields(t *typeLookup, kind TypeKind, mapping typeNameMapping) string {
@@ -142,6 +135,7 @@ func (g *Graphy) getSchemaFields(t *typeLookup, kind TypeKind, mapping typeNameM
 			continue
 		}
 
+		writeSchemaDescription(sb, field.schemaDescription())
 		sb.WriteString("\t")
 		sb.WriteString(field.name)
 		sb.WriteString(fieldTypeString)
@@ -151,6 +145,9 @@ func (g *Graphy) getSchemaFields(t *typeLookup, kind TypeKind, mapping typeNameM
 			sb.WriteString(field.deprecatedReason)
 			sb.WriteString("\")")
 		}
+		if kind == TypeInput && g.ConstraintDirectives {
+			sb.WriteString(constraintDirective(t.rootType.FieldByIndex(field.fieldIndexes)))
+		}
 
 		sb.WriteString("\n")
 	}
@@ -161,7 +158,16 @@ func (g *Graphy) getSchemaFields(t *typeLookup, kind TypeKind, mapping typeNameM
 func (g *Graphy) getSchemaFieldType(field *fieldLookup, kind TypeKind, mapping typeNameMapping) string {
 	switch field.fieldType {
 	case FieldTypeField:
-		return ": " + g.schemaRefForType(g.typeLookup(field.resultType), mapping)
+		tl := g.typeLookup(field.resultType)
+		ref := g.schemaRefForType(tl, mapping)
+		if kind == TypeOutput && field.schemaNullable(tl.isPointer) != tl.isPointer {
+			if tl.isPointer {
+				ref += "!"
+			} else {
+				ref = strings.TrimSuffix(ref, "!")
+			}
+		}
+		return ": " + ref
 	case FieldTypeGraphFunction:
 		if kind == TypeOutput {
 			return g.getSchemaGraphFunctionType(field, mapping)
@@ -213,10 +219,12 @@ func (g *Graphy) schemaRefForType(t *typeLookup, mapping typeNameMapping) string
 	var baseType string
 	if t.rootType == nil {
 		baseType = t.name
+	} else if scalar, ok := g.namedScalar(t); ok {
+		baseType = scalar
 	} else {
 		switch t.rootType.Kind() {
 		case reflect.String:
-			if t.rootType.AssignableTo(stringEnumValuesType) {
+			if g.isEnum(t) {
 				baseType = t.name
 			} else {
 				baseType = "String"
@@ -233,7 +241,9 @@ func (g *Graphy) schemaRefForType(t *typeLookup, mapping typeNameMapping) string
 			baseType = "Boolean"
 
 		case reflect.Struct:
-			if t != nil {
+			if t.rootType == timeType {
+				baseType = dateTimeScalarName
+			} else if t != nil {
 				baseType = mapping[t]
 			}
 
@@ -264,3 +274,14 @@ func (g *Graphy) wrapSchemaArray(work string, array *typeArrayModifier) string {
 	}
 	return "[" + work + "]"
 }
+
+// writeSchemaDescription writes the description of a field, if there is one, on the line
+// before the field.
+func writeSchemaDescription(sb *strings.Builder, description *string) {
+	if description == nil {
+		return
+	}
+	sb.WriteString("\t")
+	sb.WriteString(strconv.Quote(*description))
+	sb.WriteString("\n")
+}
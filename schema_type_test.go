@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/stretchr/testify/assert"
 	"reflect"
+	"sync"
 	"testing"
 )
 
@@ -407,3 +408,199 @@ enum episode {
 	assert.NoError(t, err)
 	assert.Equal(t, `{"data":{"extended":{"newCharacter":{"name":"test"}}}}`, result)
 }
+
+type unreachableWidget struct {
+	Name string
+}
+
+func TestSchemaWarnings_UnreachableTypes(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "hero", func() Character { return Character{} })
+	g.RegisterTypes(ctx, Human{}, &unreachableWidget{}, unreachableWidget{})
+	g.RegisterAnyType(ctx, Droid{}, changelogPilot{})
+
+	var handled []string
+	g.SchemaWarningHandler = func(warning SchemaWarning) {
+		handled = append(handled, warning.String())
+	}
+
+	warnings := g.SchemaWarnings()
+	assert.Equal(t, []SchemaWarning{
+		{TypeName: "changelogPilot", Message: "type changelogPilot is registered but not reachable from any query or mutation"},
+		{TypeName: "unreachableWidget", Message: "type unreachableWidget is registered but not reachable from any query or mutation"},
+	}, warnings)
+	assert.Equal(t, []string{
+		"type changelogPilot is registered but not reachable from any query or mutation",
+		"type unreachableWidget is registered but not reachable from any query or mutation",
+	}, handled)
+
+	// Once something refers to the type it is no longer reported.
+	g.RegisterQuery(ctx, "widget", func() *unreachableWidget { return nil })
+	g.RegisterQuery(ctx, "anything", func() any { return nil })
+	assert.Empty(t, g.SchemaWarnings())
+}
+
+func TestGraphy_SchemaGeneration(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "widget", func() *unreachableWidget { return nil })
+
+	schema := g.SchemaDefinition(ctx)
+	generation := g.SchemaGeneration()
+	assert.Equal(t, schema, g.SchemaDefinition(ctx))
+	assert.Equal(t, generation, g.SchemaGeneration())
+
+	// Registering a query changes the generation and the schema is built again.
+	g.RegisterQuery(ctx, "count", func() int { return 1 })
+	assert.Greater(t, g.SchemaGeneration(), generation)
+	assert.Contains(t, g.SchemaDefinition(ctx), "count: Int!")
+}
+
+func TestGraphy_SchemaConcurrentRegistration(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "widget", func() *unreachableWidget { return nil })
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				g.RegisterTypes(ctx, changelogPilot{})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				assert.Contains(t, g.SchemaDefinition(ctx), "widget: unreachableWidget")
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, []SchemaWarning{
+		{TypeName: "changelogPilot", Message: "type changelogPilot is registered but not reachable from any query or mutation"},
+	}, g.SchemaWarnings())
+}
+
+type matrixCell struct {
+	Value int
+}
+
+type matrix struct {
+	Rows   [][]*matrixCell
+	Sparse *[]*[]int
+}
+
+func TestGraphy_NestedArraySchema(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "cells", func() [][]*matrixCell {
+		return [][]*matrixCell{{{Value: 1}, nil}, nil}
+	})
+	g.RegisterQuery(ctx, "optionalCells", func() *[]*matrixCell {
+		return nil
+	})
+	g.RegisterQuery(ctx, "cube", func() [][][]string {
+		return [][][]string{{{"a", "b"}}}
+	})
+	g.RegisterQuery(ctx, "rows", func() []*[]*matrixCell {
+		row := []*matrixCell{{Value: 2}}
+		return []*[]*matrixCell{&row, nil}
+	})
+	g.RegisterQuery(ctx, "matrix", func() matrix {
+		one := []int{1}
+		return matrix{Rows: [][]*matrixCell{{{Value: 3}}}, Sparse: &[]*[]int{&one, nil}}
+	})
+	g.RegisterQuery(ctx, "sum", func(m [][]*int) int {
+		sum := 0
+		for _, row := range m {
+			for _, v := range row {
+				if v != nil {
+					sum += *v
+				}
+			}
+		}
+		return sum
+	}, "m")
+	g.RegisterQuery(ctx, "count", func(m *[]*[]int) int {
+		count := 0
+		for _, row := range *m {
+			if row != nil {
+				count += len(*row)
+			}
+		}
+		return count
+	}, "m")
+
+	expected := `type Query {
+	cells: [[matrixCell]!]!
+	count(m: [[Int!]]): Int!
+	cube: [[[String!]!]!]!
+	matrix: matrix!
+	optionalCells: [matrixCell]
+	rows: [[matrixCell]]!
+	sum(m: [[Int]!]!): Int!
+}
+
+type matrix {
+	Rows: [[matrixCell]!]!
+	Sparse: [[Int!]]
+}
+
+type matrixCell {
+	Value: Int!
+}
+
+`
+	assert.Equal(t, expected, g.SchemaDefinition(ctx))
+}
+
+type chainNode struct {
+	ID string
+}
+
+type chainResource struct {
+	chainNode
+	URL string
+}
+
+type chainImage struct {
+	chainResource
+	Width int
+}
+
+func TestGraphy_interfaceImplementsInterface(t *testing.T) {
+	g := Graphy{}
+	ctx := context.Background()
+
+	g.RegisterQuery(ctx, "images", func() []chainImage { return nil })
+	g.EnableIntrospection(ctx)
+
+	expected := `type Query {
+	images: [chainImage!]!
+}
+
+type chainImage implements chainNode & chainResource {
+	Width: Int!
+}
+
+type chainNode {
+	ID: String!
+}
+
+type chainResource implements chainNode {
+	URL: String!
+}
+
+`
+	assert.Equal(t, expected, g.SchemaDefinition(ctx))
+
+	result, err := g.ProcessRequest(ctx, `{
+		resource: __type(name: "chainResource") { kind interfaces { name } }
+		image: __type(name: "chainImage") { kind interfaces { name } }
+	}`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"image":{"interfaces":[{"name":"chainNode"},{"name":"chainResource"}],"kind":"OBJECT"},"resource":{"interfaces":[{"name":"chainNode"}],"kind":"INTERFACE"}}}`, result)
+}
@@ -0,0 +1,410 @@
+package quickgraph
+
+import (
+	"errors"
+	"fmt"
+	"github.com/alecthomas/participle/v2"
+	"github.com/alecthomas/participle/v2/lexer"
+	"sort"
+	"strings"
+)
+
+// SDLDifferenceKind is the kind of difference that an SDLDifference describes.
+type SDLDifferenceKind string
+
+const (
+	SDLTypeMissing                 SDLDifferenceKind = "TYPE_MISSING"
+	SDLTypeUnexpected              SDLDifferenceKind = "TYPE_UNEXPECTED"
+	SDLTypeKindMismatch            SDLDifferenceKind = "TYPE_KIND_MISMATCH"
+	SDLFieldMissing                SDLDifferenceKind = "FIELD_MISSING"
+	SDLFieldUnexpected             SDLDifferenceKind = "FIELD_UNEXPECTED"
+	SDLFieldTypeMismatch           SDLDifferenceKind = "FIELD_TYPE_MISMATCH"
+	SDLFieldNullabilityMismatch    SDLDifferenceKind = "FIELD_NULLABILITY_MISMATCH"
+	SDLArgumentMissing             SDLDifferenceKind = "ARGUMENT_MISSING"
+	SDLArgumentUnexpected          SDLDifferenceKind = "ARGUMENT_UNEXPECTED"
+	SDLArgumentTypeMismatch        SDLDifferenceKind = "ARGUMENT_TYPE_MISMATCH"
+	SDLArgumentNullabilityMismatch SDLDifferenceKind = "ARGUMENT_NULLABILITY_MISMATCH"
+)
+
+// SDLDifference is a single difference between an SDL document and the schema of a
+// Graphy instance, as found by ValidateAgainstSDL. "Missing" is something that the SDL
+// has and the schema doesn't; "unexpected" is the other way around. Queries and
+// mutations are fields of the Query and Mutation types, and the values of enums and the
+// members of unions are treated as fields without a type. FieldName is empty for
+// differences of whole types, and ArgumentName is empty unless the difference is in an
+// argument.
+type SDLDifference struct {
+	Kind         SDLDifferenceKind
+	TypeName     string
+	FieldName    string
+	ArgumentName string
+	Description  string
+}
+
+// ValidateAgainstSDL compares the schema with an SDL document, for teams that treat the
+// SDL as the contract of the API and the Go code as its implementation. It reports the
+// types, fields, and arguments that are missing from either side, and the ones whose
+// types or nullability differ. Descriptions, directives, and default values aren't
+// compared, and neither are the built-in scalars.
+//
+// The differences are sorted by type, field, and argument name. An error is only
+// returned if the SDL can't be parsed. This makes for a simple test that keeps the code
+// in line with the contract:
+//
+//	diffs, err := g.ValidateAgainstSDL(contract)
+//	require.NoError(t, err)
+//	assert.Empty(t, diffs, quickgraph.FormatSDLDifferences(diffs))
+func (g *Graphy) ValidateAgainstSDL(sdl string) ([]SDLDifference, error) {
+	expected, err := parseSDLTypes(sdl)
+	if err != nil {
+		return nil, err
+	}
+
+	g.structureLock.RLock()
+	definition := g.schemaDefinition(g.getSchemaTypes(), nil)
+	g.structureLock.RUnlock()
+
+	actual, err := parseSDLTypes(definition)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the schema definition: %w", err)
+	}
+	return compareSDLTypes(expected, actual), nil
+}
+
+// FormatSDLDifferences formats the differences one per line, starting with their kind,
+// for the output of CI jobs.
+func FormatSDLDifferences(diffs []SDLDifference) string {
+	sb := strings.Builder{}
+	for _, diff := range diffs {
+		sb.WriteString(string(diff.Kind))
+		sb.WriteString(": ")
+		sb.WriteString(diff.Description)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// sdlDocument is a GraphQL SDL document. The grammar covers the type system definitions
+// and extensions, which is all that ValidateAgainstSDL needs.
+type sdlDocument struct {
+	Definitions []sdlDefinition `parser:"@@*"`
+}
+
+type sdlDefinition struct {
+	Description string           `parser:"(@String | @BlockString)?"`
+	Extend      bool             `parser:"@'extend'?"`
+	Schema      *sdlSchemaDef    `parser:"( 'schema' @@"`
+	Directive   *sdlDirectiveDef `parser:"| 'directive' @@"`
+	Type        *sdlTypeDef      `parser:"| @@ )"`
+}
+
+type sdlSchemaDef struct {
+	Directives []sdlDirective     `parser:"@@*"`
+	Operations []sdlOperationType `parser:"('{' @@* '}')?"`
+}
+
+type sdlOperationType struct {
+	Operation string `parser:"@Ident ':'"`
+	Type      string `parser:"@Ident"`
+}
+
+type sdlDirectiveDef struct {
+	Name       string          `parser:"@Directive"`
+	Args       []sdlInputValue `parser:"('(' @@* ')')?"`
+	Repeatable bool            `parser:"@'repeatable'?"`
+	Locations  []string        `parser:"'on' '|'? @Ident ('|' @Ident)*"`
+}
+
+type sdlTypeDef struct {
+	Kind       string         `parser:"@('type' | 'interface' | 'input' | 'enum' | 'union' | 'scalar')"`
+	Name       string         `parser:"@Ident"`
+	Interfaces []string       `parser:"('implements' '&'? @Ident ('&' @Ident)*)?"`
+	Directives []sdlDirective `parser:"@@*"`
+	Members    []string       `parser:"('=' '|'? @Ident ('|' @Ident)*)?"`
+	Fields     []sdlField     `parser:"('{' @@* '}')?"`
+}
+
+// sdlField is a field of an object, interface, or input type, or a value of an enum,
+// which has neither arguments nor a type.
+type sdlField struct {
+	Description string          `parser:"(@String | @BlockString)?"`
+	Name        string          `parser:"@Ident"`
+	Args        []sdlInputValue `parser:"('(' @@* ')')?"`
+	Type        *sdlTypeRef     `parser:"(':' @@)?"`
+	Default     *sdlValue       `parser:"('=' @@)?"`
+	Directives  []sdlDirective  `parser:"@@*"`
+}
+
+type sdlInputValue struct {
+	Description string         `parser:"(@String | @BlockString)?"`
+	Name        string         `parser:"@Ident ':'"`
+	Type        sdlTypeRef     `parser:"@@"`
+	Default     *sdlValue      `parser:"('=' @@)?"`
+	Directives  []sdlDirective `parser:"@@*"`
+}
+
+type sdlTypeRef struct {
+	List    *sdlTypeRef `parser:"( '[' @@ ']'"`
+	Name    string      `parser:"| @Ident )"`
+	NonNull bool        `parser:"@'!'?"`
+}
+
+type sdlDirective struct {
+	Name string        `parser:"@Directive"`
+	Args []sdlArgument `parser:"('(' @@* ')')?"`
+}
+
+type sdlArgument struct {
+	Name  string   `parser:"@Ident ':'"`
+	Value sdlValue `parser:"@@"`
+}
+
+type sdlValue struct {
+	Scalar string        `parser:"@(String | BlockString | Int | Float | Ident)"`
+	List   []sdlValue    `parser:"| '[' @@* ']'"`
+	Object []sdlArgument `parser:"| '{' @@* '}'"`
+}
+
+// String returns the type in schema notation, such as "[Character!]!".
+func (t sdlTypeRef) String() string {
+	result := t.Name
+	if t.List != nil {
+		result = "[" + t.List.String() + "]"
+	}
+	if t.NonNull {
+		result += "!"
+	}
+	return result
+}
+
+var (
+	sdlLexer = lexer.MustSimple([]lexer.SimpleRule{
+		{Name: "BlockString", Pattern: `"""(?s:.*?)"""`},
+		{Name: "String", Pattern: `"(\\.|[^"\\])*"`},
+		{Name: "Directive", Pattern: `@[a-zA-Z_]\w*`},
+		{Name: "Ident", Pattern: `[a-zA-Z_]\w*`},
+		{Name: "Float", Pattern: `-?\d+(\.\d+)?[eE][-+]?\d+|-?\d+\.\d+`},
+		{Name: "Int", Pattern: `-?\d+`},
+		{Name: "Comment", Pattern: `#[^\n]*`},
+		{Name: "Comma", Pattern: `,`},
+		{Name: "Punct", Pattern: `[!():=\[\]{}|&]`},
+		{Name: "Whitespace", Pattern: `[ \t\n\r]+`},
+	})
+	sdlParser = participle.MustBuild[sdlDocument](
+		participle.Lexer(sdlLexer),
+		participle.Elide("Whitespace", "Comment", "Comma"),
+		participle.UseLookahead(2),
+	)
+)
+
+// sdlType is what is compared for each type of an SDL document.
+type sdlType struct {
+	kind   string
+	fields map[string]sdlFieldType
+}
+
+// sdlFieldType is the type of a field and the types of its arguments. Both are empty
+// for the values of enums and the members of unions.
+type sdlFieldType struct {
+	typ  string
+	args map[string]string
+}
+
+// builtInScalars are the scalars that every schema has, whether it declares them or not.
+var builtInScalars = map[string]bool{
+	"Boolean": true,
+	"Float":   true,
+	"ID":      true,
+	"Int":     true,
+	"String":  true,
+}
+
+// parseSDLTypes parses an SDL document into its types, keyed by name. Extensions of types
+// are merged into the types, and the root types are named Query, Mutation, and
+// Subscription whatever the schema definition calls them.
+func parseSDLTypes(sdl string) (map[string]*sdlType, error) {
+	doc, err := sdlParser.ParseString("", sdl)
+	if err != nil {
+		var pErr participle.Error
+		var position lexer.Position
+		if errors.As(err, &pErr) {
+			position = pErr.Position()
+		}
+		return nil, AugmentGraphError(err, "error parsing SDL", position)
+	}
+
+	roots := map[string]string{}
+	types := map[string]*sdlType{}
+	for _, def := range doc.Definitions {
+		if def.Schema != nil {
+			for _, op := range def.Schema.Operations {
+				roots[op.Type] = strings.ToUpper(op.Operation[:1]) + op.Operation[1:]
+			}
+		}
+		if def.Type == nil {
+			continue
+		}
+		t, ok := types[def.Type.Name]
+		if !ok {
+			t = &sdlType{kind: def.Type.Kind, fields: map[string]sdlFieldType{}}
+			types[def.Type.Name] = t
+		}
+		for _, member := range def.Type.Members {
+			t.fields[member] = sdlFieldType{}
+		}
+		for _, field := range def.Type.Fields {
+			ft := sdlFieldType{args: map[string]string{}}
+			if field.Type != nil {
+				ft.typ = field.Type.String()
+			}
+			for _, arg := range field.Args {
+				ft.args[arg.Name] = arg.Type.String()
+			}
+			t.fields[field.Name] = ft
+		}
+	}
+
+	for name, root := range roots {
+		if t, ok := types[name]; ok && name != root {
+			delete(types, name)
+			types[root] = t
+		}
+	}
+	return types, nil
+}
+
+// compareSDLTypes finds the differences between the types of the expected SDL and the
+// actual schema.
+func compareSDLTypes(expected, actual map[string]*sdlType) []SDLDifference {
+	var diffs []SDLDifference
+	for _, typeName := range sortedKeys(expected) {
+		if builtInScalars[typeName] {
+			continue
+		}
+		expectedType := expected[typeName]
+		actualType, ok := actual[typeName]
+		if !ok {
+			diffs = append(diffs, SDLDifference{
+				Kind:        SDLTypeMissing,
+				TypeName:    typeName,
+				Description: fmt.Sprintf("Type %s is in the SDL but not in the schema.", typeName),
+			})
+			continue
+		}
+		if expectedType.kind != actualType.kind {
+			diffs = append(diffs, SDLDifference{
+				Kind:        SDLTypeKindMismatch,
+				TypeName:    typeName,
+				Description: fmt.Sprintf("Type %s is declared with %s in the SDL but with %s in the schema.", typeName, expectedType.kind, actualType.kind),
+			})
+			continue
+		}
+		diffs = append(diffs, compareSDLFields(typeName, expectedType.fields, actualType.fields)...)
+	}
+	for _, typeName := range sortedKeys(actual) {
+		if _, ok := expected[typeName]; !ok && !builtInScalars[typeName] {
+			diffs = append(diffs, SDLDifference{
+				Kind:        SDLTypeUnexpected,
+				TypeName:    typeName,
+				Description: fmt.Sprintf("Type %s is in the schema but not in the SDL.", typeName),
+			})
+		}
+	}
+
+	sort.SliceStable(diffs, func(i, j int) bool {
+		if diffs[i].TypeName != diffs[j].TypeName {
+			return diffs[i].TypeName < diffs[j].TypeName
+		}
+		if diffs[i].FieldName != diffs[j].FieldName {
+			return diffs[i].FieldName < diffs[j].FieldName
+		}
+		return diffs[i].ArgumentName < diffs[j].ArgumentName
+	})
+	return diffs
+}
+
+func compareSDLFields(typeName string, expected, actual map[string]sdlFieldType) []SDLDifference {
+	var diffs []SDLDifference
+	for _, fieldName := range sortedKeys(expected) {
+		qualified := typeName + "." + fieldName
+		expectedField := expected[fieldName]
+		actualField, ok := actual[fieldName]
+		if !ok {
+			diffs = append(diffs, SDLDifference{
+				Kind:        SDLFieldMissing,
+				TypeName:    typeName,
+				FieldName:   fieldName,
+				Description: fmt.Sprintf("Field %s is in the SDL but not in the schema.", qualified),
+			})
+			continue
+		}
+		if kind, ok := sdlTypeDifference(expectedField.typ, actualField.typ, SDLFieldTypeMismatch, SDLFieldNullabilityMismatch); ok {
+			diffs = append(diffs, SDLDifference{
+				Kind:        kind,
+				TypeName:    typeName,
+				FieldName:   fieldName,
+				Description: fmt.Sprintf("Field %s is %s in the SDL but %s in the schema.", qualified, expectedField.typ, actualField.typ),
+			})
+		}
+
+		for _, argName := range sortedKeys(expectedField.args) {
+			qualifiedArg := fmt.Sprintf("%s(%s)", qualified, argName)
+			expectedArg := expectedField.args[argName]
+			actualArg, ok := actualField.args[argName]
+			if !ok {
+				diffs = append(diffs, SDLDifference{
+					Kind:         SDLArgumentMissing,
+					TypeName:     typeName,
+					FieldName:    fieldName,
+					ArgumentName: argName,
+					Description:  fmt.Sprintf("Argument %s is in the SDL but not in the schema.", qualifiedArg),
+				})
+				continue
+			}
+			if kind, ok := sdlTypeDifference(expectedArg, actualArg, SDLArgumentTypeMismatch, SDLArgumentNullabilityMismatch); ok {
+				diffs = append(diffs, SDLDifference{
+					Kind:         kind,
+					TypeName:     typeName,
+					FieldName:    fieldName,
+					ArgumentName: argName,
+					Description:  fmt.Sprintf("Argument %s is %s in the SDL but %s in the schema.", qualifiedArg, expectedArg, actualArg),
+				})
+			}
+		}
+		for _, argName := range sortedKeys(actualField.args) {
+			if _, ok := expectedField.args[argName]; !ok {
+				diffs = append(diffs, SDLDifference{
+					Kind:         SDLArgumentUnexpected,
+					TypeName:     typeName,
+					FieldName:    fieldName,
+					ArgumentName: argName,
+					Description:  fmt.Sprintf("Argument %s(%s) is in the schema but not in the SDL.", qualified, argName),
+				})
+			}
+		}
+	}
+	for _, fieldName := range sortedKeys(actual) {
+		if _, ok := expected[fieldName]; !ok {
+			diffs = append(diffs, SDLDifference{
+				Kind:        SDLFieldUnexpected,
+				TypeName:    typeName,
+				FieldName:   fieldName,
+				Description: fmt.Sprintf("Field %s.%s is in the schema but not in the SDL.", typeName, fieldName),
+			})
+		}
+	}
+	return diffs
+}
+
+// sdlTypeDifference returns the kind of difference between two types in schema notation,
+// if they differ. Types that only differ in their non-null markers are a nullability
+// mismatch.
+func sdlTypeDifference(expected, actual string, typeMismatch, nullabilityMismatch SDLDifferenceKind) (SDLDifferenceKind, bool) {
+	if expected == actual {
+		return "", false
+	}
+	if strings.ReplaceAll(expected, "!", "") == strings.ReplaceAll(actual, "!", "") {
+		return nullabilityMismatch, true
+	}
+	return typeMismatch, true
+}
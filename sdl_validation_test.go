@@ -0,0 +1,107 @@
+package quickgraph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type sdlShip struct {
+	Name   string   `json:"name"`
+	Length *float64 `json:"length"`
+	Crew   []string `json:"crew"`
+}
+
+type sdlShipInput struct {
+	Name string `json:"name"`
+}
+
+func TestGraphy_ValidateAgainstSDL(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "ship", func(id string, limit *int) *sdlShip { return nil }, "id", "limit")
+	g.RegisterMutation(ctx, "createShip", func(input sdlShipInput) sdlShip { return sdlShip{} }, "input")
+	g.RegisterQuery(ctx, "humans", func() []Human { return nil })
+
+	// The schema matches its own definition.
+	diffs, err := g.ValidateAgainstSDL(g.SchemaDefinition(ctx))
+	assert.NoError(t, err)
+	assert.Empty(t, diffs)
+
+	contract := `
+# The contract of the API.
+schema {
+	query: RootQuery
+	mutation: Mutation
+}
+
+"""
+The entry points.
+"""
+type RootQuery {
+	ship(id: ID!, limit: Int, "Not implemented yet." fleet: String): sdlShip
+	humans: [Human!]!
+}
+
+extend type RootQuery {
+	fleets: [String!]!
+}
+
+type Mutation {
+	createShip(input: sdlShipInput!): sdlShip
+}
+
+input sdlShipInput {
+	name: String! = "Falcon" @deprecated(reason: "Use the registry.")
+}
+
+type sdlShip {
+	name: String!
+	length: Float!
+	crew: [String]!
+}
+
+type Human implements Character {
+	FriendsConnection(arg1: Int!): FriendsConnection
+	Height(arg1: String): Float!
+	HeightMeters: Float!
+}
+
+type Character {
+	appearsIn: [episode!]!
+	friends: [Character]!
+	FriendsConnection(arg1: Int!): FriendsConnection
+	id: String!
+	name: String!
+}
+
+enum episode { NEWHOPE, EMPIRE, JEDI, ROGUE_ONE }
+
+union ConnectionEdge = Human | Character
+
+scalar DateTime
+`
+	diffs, err = g.ValidateAgainstSDL(contract)
+	assert.NoError(t, err)
+	assert.Equal(t, `TYPE_KIND_MISMATCH: Type ConnectionEdge is declared with union in the SDL but with type in the schema.
+TYPE_MISSING: Type DateTime is in the SDL but not in the schema.
+TYPE_UNEXPECTED: Type FriendsConnection is in the schema but not in the SDL.
+FIELD_NULLABILITY_MISMATCH: Field Mutation.createShip is sdlShip in the SDL but sdlShip! in the schema.
+FIELD_MISSING: Field Query.fleets is in the SDL but not in the schema.
+ARGUMENT_MISSING: Argument Query.ship(fleet) is in the SDL but not in the schema.
+ARGUMENT_TYPE_MISMATCH: Argument Query.ship(id) is ID! in the SDL but String! in the schema.
+FIELD_MISSING: Field episode.ROGUE_ONE is in the SDL but not in the schema.
+FIELD_NULLABILITY_MISMATCH: Field sdlShip.crew is [String]! in the SDL but [String!]! in the schema.
+FIELD_NULLABILITY_MISMATCH: Field sdlShip.length is Float! in the SDL but Float in the schema.
+`, FormatSDLDifferences(diffs))
+	assert.Equal(t, SDLDifference{
+		Kind:         SDLArgumentTypeMismatch,
+		TypeName:     "Query",
+		FieldName:    "ship",
+		ArgumentName: "id",
+		Description:  "Argument Query.ship(id) is ID! in the SDL but String! in the schema.",
+	}, diffs[6])
+
+	_, err = g.ValidateAgainstSDL("type Query {\n\tship(: String\n}")
+	assert.ErrorContains(t, err, "error parsing SDL [2:7]")
+}
@@ -0,0 +1,139 @@
+package quickgraph
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// GraphStats are the runtime statistics of a Graphy instance, as returned by the __stats
+// query. The counts are since EnableStats was called.
+type GraphStats struct {
+	StartedAt     time.Time `json:"startedAt"`
+	UptimeSeconds float64   `json:"uptimeSeconds"`
+
+	// Requests is the number of requests that were processed, and FailedRequests is the
+	// number of them that had errors. ActiveRequests is the number of requests that are
+	// being processed at the moment, including the one for the statistics.
+	Requests       int64 `json:"requests"`
+	FailedRequests int64 `json:"failedRequests"`
+	ActiveRequests int64 `json:"activeRequests"`
+
+	// RequestCacheHits and RequestCacheMisses count the lookups in the RequestCache, and
+	// RequestCacheHitRatio is the share of them that were hits, or 0 if there weren't
+	// any.
+	RequestCacheHits     int64   `json:"requestCacheHits"`
+	RequestCacheMisses   int64   `json:"requestCacheMisses"`
+	RequestCacheHitRatio float64 `json:"requestCacheHitRatio"`
+
+	// LimitRejections is the number of requests that were rejected for exceeding the
+	// QueryLimits, and ThrottledRequests is the number that the Throttle of the HTTP
+	// handler rejected.
+	LimitRejections   int64 `json:"limitRejections"`
+	ThrottledRequests int64 `json:"throttledRequests"`
+}
+
+// statsCollector accumulates the GraphStats for a Graphy.
+type statsCollector struct {
+	startedAt         time.Time
+	requests          atomic.Int64
+	failedRequests    atomic.Int64
+	activeRequests    atomic.Int64
+	cacheHits         atomic.Int64
+	cacheMisses       atomic.Int64
+	limitRejections   atomic.Int64
+	throttledRequests atomic.Int64
+}
+
+// EnableStats adds the __stats query, which returns the GraphStats of the Graphy, for
+// operators who want a lightweight dashboard without a separate metrics stack:
+//
+//	g.EnableStats(ctx, func(ctx context.Context) bool {
+//		return hasRole(ctx, "operator")
+//	})
+//
+// Only the callers that allow accepts can call the query; the others get an error with
+// the UNAUTHORIZED code. Like the introspection queries, __stats isn't part of the schema
+// and OperationAccess doesn't apply to it, so allow must not be nil. The statistics are
+// counted from the time that this is called.
+func (g *Graphy) EnableStats(ctx context.Context, allow func(ctx context.Context) bool) {
+	if allow == nil {
+		panic("stats: allow must not be nil")
+	}
+	stats := &statsCollector{startedAt: g.now()}
+	g.stats = stats
+	g.RegisterQuery(ctx, "__stats", func(ctx context.Context) (GraphStats, error) {
+		if !allow(ctx) {
+			gErr := GraphError{Message: "not authorized to call __stats"}
+			gErr.AddExtension("code", ErrorCodeUnauthorized)
+			return GraphStats{}, gErr
+		}
+		return stats.snapshot(g.now()), nil
+	})
+}
+
+func (s *statsCollector) snapshot(now time.Time) GraphStats {
+	result := GraphStats{
+		StartedAt:          s.startedAt,
+		UptimeSeconds:      now.Sub(s.startedAt).Seconds(),
+		Requests:           s.requests.Load(),
+		FailedRequests:     s.failedRequests.Load(),
+		ActiveRequests:     s.activeRequests.Load(),
+		RequestCacheHits:   s.cacheHits.Load(),
+		RequestCacheMisses: s.cacheMisses.Load(),
+		LimitRejections:    s.limitRejections.Load(),
+		ThrottledRequests:  s.throttledRequests.Load(),
+	}
+	if lookups := result.RequestCacheHits + result.RequestCacheMisses; lookups > 0 {
+		result.RequestCacheHitRatio = float64(result.RequestCacheHits) / float64(lookups)
+	}
+	return result
+}
+
+// startRequestStats counts a request that is starting, if the statistics are enabled. The
+// returned function counts it as completed with the error.
+func (g *Graphy) startRequestStats() func(err error) {
+	stats := g.stats
+	if stats == nil {
+		return func(error) {}
+	}
+	stats.activeRequests.Add(1)
+	return func(err error) {
+		stats.activeRequests.Add(-1)
+		var pending pendingTypesError
+		if errors.As(err, &pending) {
+			// The request is processed again once the types are resolved.
+			return
+		}
+		stats.requests.Add(1)
+		if err != nil {
+			stats.failedRequests.Add(1)
+		}
+		var qle queryLimitError
+		if errors.As(err, &qle) {
+			stats.limitRejections.Add(1)
+		}
+	}
+}
+
+// recordCacheLookupStats counts a lookup in the RequestCache, if the statistics are
+// enabled.
+func (g *Graphy) recordCacheLookupStats(hit bool) {
+	if g.stats == nil {
+		return
+	}
+	if hit {
+		g.stats.cacheHits.Add(1)
+	} else {
+		g.stats.cacheMisses.Add(1)
+	}
+}
+
+// recordThrottledStats counts a request that the Throttle rejected, if the statistics are
+// enabled.
+func (g *Graphy) recordThrottledStats() {
+	if g.stats != nil {
+		g.stats.throttledRequests.Add(1)
+	}
+}
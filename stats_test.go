@@ -0,0 +1,74 @@
+package quickgraph
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type statsOperatorKey struct{}
+
+type statsGreeting struct {
+	Text string `json:"text"`
+}
+
+func TestGraphy_Stats(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	g := Graphy{
+		RequestCache: simpleCache{values: map[string]*simpleCacheEntry{}},
+		QueryLimits:  &QueryLimits{MaxEstimatedResponse: 1},
+		TestingHooks: &TestingHooks{Now: func() time.Time { return now }},
+	}
+	g.RegisterQuery(ctx, "hello", func() string { return "world" })
+	g.RegisterQuery(ctx, "fail", func() (string, error) { return "", errors.New("boom") })
+	g.RegisterQuery(ctx, "greeting", func() statsGreeting { return statsGreeting{Text: "hi"} })
+	g.EnableStats(ctx, func(ctx context.Context) bool {
+		return ctx.Value(statsOperatorKey{}) != nil
+	})
+	now = now.Add(90 * time.Second)
+
+	_, err := g.ProcessRequest(ctx, `{ hello }`, "")
+	assert.NoError(t, err)
+	_, err = g.ProcessRequest(ctx, `{ hello }`, "")
+	assert.NoError(t, err)
+	_, err = g.ProcessRequest(ctx, `{ fail }`, "")
+	assert.Error(t, err)
+	_, err = g.ProcessRequest(ctx, `{ greeting { text } }`, "")
+	assert.ErrorContains(t, err, "estimated response size exceeds the maximum of 1 values")
+
+	// The stats query is subject to the limits like any other query.
+	g.QueryLimits.MaxEstimatedResponse = 100
+
+	result, err := g.ProcessRequest(ctx, `{ __stats { requests } }`, "")
+	assert.Error(t, err)
+	assert.Equal(t, `{"data":{},"errors":[{"message":"not authorized to call __stats","locations":[{"line":1,"column":3}],"path":["__stats"],"extensions":{"code":"UNAUTHORIZED"}}]}`, result)
+
+	operator := context.WithValue(ctx, statsOperatorKey{}, true)
+	result, err = g.ProcessRequest(operator, `{ __stats { startedAt uptimeSeconds requests failedRequests activeRequests requestCacheHits requestCacheMisses requestCacheHitRatio limitRejections throttledRequests } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"__stats":{"activeRequests":1,"failedRequests":3,"limitRejections":1,"requestCacheHitRatio":0.16666666666666666,"requestCacheHits":1,"requestCacheMisses":5,"requests":5,"startedAt":"2026-10-16T12:00:00Z","throttledRequests":0,"uptimeSeconds":90}}}`, result)
+
+	// The stats query isn't part of the schema.
+	assert.NotContains(t, g.SchemaDefinition(ctx), "__stats")
+}
+
+func TestGraphy_StatsThrottled(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "hello", func() string { return "world" })
+	g.EnableStats(ctx, func(ctx context.Context) bool { return true })
+	h := NewGraphHttpHandler(&g)
+	h.Throttle = &Throttle{PerIP: &RateLimit{Budget: 1, Window: time.Minute}}
+
+	for i := 0; i < 2; i++ {
+		request := httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ hello }"}`))
+		h.ServeHTTP(httptest.NewRecorder(), request)
+	}
+	stats, _ := g.ProcessRequest(ctx, `{ __stats { requests throttledRequests } }`, "")
+	assert.Equal(t, `{"data":{"__stats":{"requests":1,"throttledRequests":1}}}`, stats)
+}
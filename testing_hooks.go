@@ -0,0 +1,62 @@
+package quickgraph
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// TestingHooks replace the sources of values that differ from run to run, so that tests
+// of a graph, such as golden-file tests of its responses, produce the same output every
+// time. They affect the library itself and the functions that use Now and NewID.
+type TestingHooks struct {
+	// Now, if set, is used instead of time.Now. This includes the durations measured for
+	// field statistics.
+	Now func() time.Time
+
+	// NewID, if set, is used to generate the IDs that NewID returns.
+	NewID func() string
+}
+
+// Now returns the current time for the request that is being processed. Functions should
+// use it instead of time.Now for values that end up in their results, such as timestamps
+// of records they create, so that the time can be fixed in tests with TestingHooks.
+func Now(ctx context.Context) time.Time {
+	if req, ok := ctx.Value(requestContextKey).(*request); ok {
+		return req.graphy.now()
+	}
+	return time.Now()
+}
+
+// NewID returns a new random ID for the request that is being processed, as 32
+// hexadecimal digits. Like Now, it can be made predictable with TestingHooks.
+func NewID(ctx context.Context) string {
+	if req, ok := ctx.Value(requestContextKey).(*request); ok {
+		return req.graphy.newID()
+	}
+	return randomID()
+}
+
+func (g *Graphy) now() time.Time {
+	if g.TestingHooks != nil && g.TestingHooks.Now != nil {
+		return g.TestingHooks.Now()
+	}
+	return time.Now()
+}
+
+func (g *Graphy) newID() string {
+	if g.TestingHooks != nil && g.TestingHooks.NewID != nil {
+		return g.TestingHooks.NewID()
+	}
+	return randomID()
+}
+
+func randomID() string {
+	var b [16]byte
+	_, err := rand.Read(b[:])
+	if err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
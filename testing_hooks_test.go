@@ -0,0 +1,59 @@
+package quickgraph
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type hooksTestRecord struct {
+	Id        string
+	CreatedAt time.Time
+}
+
+func TestTestingHooks(t *testing.T) {
+	ctx := context.Background()
+	clock := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	ids := 0
+	g := &Graphy{
+		EnableFieldStats: true,
+		TestingHooks: &TestingHooks{
+			Now: func() time.Time { return clock },
+			NewID: func() string {
+				ids++
+				return fmt.Sprintf("id-%d", ids)
+			},
+		},
+	}
+	g.RegisterMutation(ctx, "create", func(ctx context.Context) hooksTestRecord {
+		return hooksTestRecord{Id: NewID(ctx), CreatedAt: Now(ctx)}
+	})
+
+	for i := 1; i <= 2; i++ {
+		result, err := g.ProcessRequest(ctx, `mutation { create { Id CreatedAt } }`, "")
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf(`{"data":{"create":{"CreatedAt":"2024-01-02T03:04:05Z","Id":"id-%d"}}}`, i), result)
+	}
+
+	// With a fixed clock, the durations are all zero.
+	assert.NotEmpty(t, g.FieldStatsSnapshot())
+	for _, fs := range g.FieldStatsSnapshot() {
+		assert.Equal(t, time.Duration(0), fs.MaxDuration)
+	}
+}
+
+func TestTestingHooks_Defaults(t *testing.T) {
+	ctx := context.Background()
+	before := time.Now()
+	assert.False(t, Now(ctx).Before(before))
+
+	id := NewID(ctx)
+	assert.Len(t, id, 32)
+	assert.NotEqual(t, id, NewID(ctx))
+
+	g := &Graphy{}
+	assert.Len(t, g.newID(), 32)
+	assert.False(t, g.now().Before(before))
+}
@@ -0,0 +1,279 @@
+package quickgraph
+
+import (
+	"context"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIKeyHeader is the default HTTP header that identifies the API key of a client for
+// throttling. See Throttle.
+const APIKeyHeader = "X-API-Key"
+
+// RateLimit is a budget that a client may use up within a window of time.
+type RateLimit struct {
+	// Budget is the amount that may be used within the window. Normally each request
+	// uses one unit; see Throttle.QueryCost for charging by the size of the request.
+	Budget int
+
+	// Window is the length of the windows that the budget applies to.
+	Window time.Duration
+}
+
+// RateLimitStore keeps track of how much of their budgets the clients have used. The
+// default is an in-memory store; an implementation backed by a shared cache allows the
+// limits to apply across multiple instances of a service.
+type RateLimitStore interface {
+	// Take uses cost units of the budget of the key in the current window. If the budget
+	// doesn't have enough left, nothing is used, false is returned, and retryAfter is the
+	// time until the next window starts.
+	Take(ctx context.Context, key string, cost int, limit RateLimit) (ok bool, retryAfter time.Duration, err error)
+
+	// Check reports whether Take would succeed, without using any of the budget. It is
+	// used so that a request that is over one of its limits isn't charged to the others.
+	Check(ctx context.Context, key string, cost int, limit RateLimit) (ok bool, retryAfter time.Duration, err error)
+}
+
+// Throttle limits the rate of the requests that the HTTP handler processes for each
+// client. Clients are identified by their IP address and, if they send one, their API
+// key. Requests over the limit get a 429 Too Many Requests response with a Retry-After
+// header. Set it on GraphHttpHandler.Throttle to enable it.
+type Throttle struct {
+	// PerIP, if set, is the limit for each client IP address.
+	PerIP *RateLimit
+
+	// PerAPIKey, if set, is the limit for each API key. Requests without an API key are
+	// only subject to the PerIP limit.
+	PerAPIKey *RateLimit
+
+	// APIKeyHeader is the header that holds the API key. If it is empty, APIKeyHeader
+	// (X-API-Key) is used.
+	APIKeyHeader string
+
+	// TrustForwardedFor takes the IP address of the client from the X-Forwarded-For
+	// header. Proxies append the address that they got the request from to the header,
+	// so the address is the one that the nearest of the TrustedProxies added; the ones
+	// before it are whatever the client sent. Only set this when the service is behind
+	// proxies that set the header, otherwise clients can pick their own address.
+	TrustForwardedFor bool
+
+	// TrustedProxies is the number of proxies in front of the service that append to the
+	// X-Forwarded-For header. If it is zero, there is taken to be one, so the last address
+	// of the header is used.
+	TrustedProxies int
+
+	// Banned, if set, is called for each request. Requests from clients for which it
+	// returns true get a 403 Forbidden response.
+	Banned func(ip, apiKey string) bool
+
+	// QueryCost charges each GraphQL request its estimated response size, as used by
+	// QueryLimits.MaxEstimatedResponse, instead of one unit. This lets clients make many
+	// cheap requests, or a few expensive ones. Every request is still charged one unit
+	// when it is received, so that requests that can't be parsed aren't free; the rest of
+	// the estimate is charged once it is parsed. Introspection commands add nothing to it.
+	// Requests that are estimated to cost more than the budget of the client are rejected
+	// like requests over the QueryLimits, since they would never fit.
+	QueryCost bool
+
+	// Store keeps track of the budgets. If it is nil, an in-memory store is used.
+	Store RateLimitStore
+
+	storeOnce sync.Once
+}
+
+// errRateLimited is reported to clients that have used up their budget.
+var errRateLimited = GraphError{Message: "rate limit exceeded"}
+
+func (t *Throttle) store() RateLimitStore {
+	t.storeOnce.Do(func() {
+		if t.Store == nil {
+			t.Store = NewMemoryRateLimitStore()
+		}
+	})
+	return t.Store
+}
+
+// clientKeys returns the IP address and API key of the client that made the request.
+func (t *Throttle) clientKeys(request *http.Request) (string, string) {
+	ip := request.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	if t.TrustForwardedFor {
+		if forwarded := request.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			addresses := strings.Split(strings.Join(forwarded, ","), ",")
+			hops := t.TrustedProxies
+			if hops < 1 {
+				hops = 1
+			}
+			// With fewer addresses than proxies, the first one was added by a proxy too.
+			index := len(addresses) - hops
+			if index < 0 {
+				index = 0
+			}
+			ip = strings.TrimSpace(addresses[index])
+		}
+	}
+	header := t.APIKeyHeader
+	if header == "" {
+		header = APIKeyHeader
+	}
+	return ip, request.Header.Get(header)
+}
+
+// take charges the cost to the budgets of the client. All the budgets are checked before
+// any of them is charged, so a request that is over one limit doesn't use up the others.
+// If the store fails, the request is allowed so that an outage of the store doesn't take
+// the service down with it.
+func (t *Throttle) take(ctx context.Context, ip, apiKey string, cost int) (bool, time.Duration) {
+	type bucket struct {
+		key   string
+		limit RateLimit
+	}
+	var buckets []bucket
+	if t.PerIP != nil {
+		buckets = append(buckets, bucket{"ip:" + ip, *t.PerIP})
+	}
+	if apiKey != "" && t.PerAPIKey != nil {
+		buckets = append(buckets, bucket{"key:" + apiKey, *t.PerAPIKey})
+	}
+
+	store := t.store()
+	for _, b := range buckets {
+		ok, retryAfter, err := store.Check(ctx, b.key, cost, b.limit)
+		if err != nil {
+			log.Printf("Error checking rate limit: %v", err)
+			continue
+		}
+		if !ok {
+			return false, retryAfter
+		}
+	}
+	for _, b := range buckets {
+		// Another request may have used the budget since it was checked; it is charged
+		// anyway, as it was allowed.
+		_, _, err := store.Take(ctx, b.key, cost, b.limit)
+		if err != nil {
+			log.Printf("Error checking rate limit: %v", err)
+		}
+	}
+	return true, 0
+}
+
+// maxCost returns the smallest budget of the limits that apply to a client with the API
+// key, which is the most that a single request of the client can be charged. It returns
+// false if no limits apply.
+func (t *Throttle) maxCost(apiKey string) (int, bool) {
+	budget, ok := 0, false
+	if t.PerIP != nil {
+		budget, ok = t.PerIP.Budget, true
+	}
+	if apiKey != "" && t.PerAPIKey != nil && (!ok || t.PerAPIKey.Budget < budget) {
+		budget, ok = t.PerAPIKey.Budget, true
+	}
+	return budget, ok
+}
+
+// retryAfterHeader formats a duration as the number of seconds for a Retry-After header,
+// rounding up so that clients don't retry too early.
+func retryAfterHeader(retryAfter time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))
+}
+
+// MemoryRateLimitStore is a RateLimitStore that keeps the budgets in memory, using fixed
+// windows. Use NewMemoryRateLimitStore to create one.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	windows   map[string]*rateWindow
+	lastPrune time.Time
+
+	// now returns the current time; it is replaced in tests.
+	now func() time.Time
+}
+
+type rateWindow struct {
+	end  time.Time
+	used int
+}
+
+// NewMemoryRateLimitStore creates an empty MemoryRateLimitStore.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		windows: map[string]*rateWindow{},
+		now:     time.Now,
+	}
+}
+
+func (m *MemoryRateLimitStore) Take(ctx context.Context, key string, cost int, limit RateLimit) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, retryAfter := m.window(key, cost, limit)
+	if retryAfter > 0 {
+		return false, retryAfter, nil
+	}
+	w.used += cost
+	return true, 0, nil
+}
+
+func (m *MemoryRateLimitStore) Check(ctx context.Context, key string, cost int, limit RateLimit) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, retryAfter := m.window(key, cost, limit)
+	return retryAfter == 0, retryAfter, nil
+}
+
+// window returns the current window of the key, and the time until the next one if the
+// cost doesn't fit in it. m.mu must be held.
+func (m *MemoryRateLimitStore) window(key string, cost int, limit RateLimit) (*rateWindow, time.Duration) {
+	now := m.now()
+	m.prune(now, limit.Window)
+
+	w, ok := m.windows[key]
+	if !ok || !now.Before(w.end) {
+		w = &rateWindow{end: now.Add(limit.Window)}
+		m.windows[key] = w
+	}
+	if w.used+cost > limit.Budget {
+		return w, w.end.Sub(now)
+	}
+	return w, 0
+}
+
+// prune removes the windows that have ended, at most once per window length, so that
+// clients that have gone away don't use memory forever.
+func (m *MemoryRateLimitStore) prune(now time.Time, window time.Duration) {
+	if now.Sub(m.lastPrune) < window {
+		return
+	}
+	m.lastPrune = now
+	for key, w := range m.windows {
+		if !now.Before(w.end) {
+			delete(m.windows, key)
+		}
+	}
+}
+
+// writeThrottled writes the response for a request that is rejected by the Throttle.
+func writeThrottled(writer http.ResponseWriter, status int, retryAfter time.Duration) {
+	writer.Header().Set("Content-Type", "application/json")
+	if status == http.StatusTooManyRequests {
+		writer.Header().Set("Retry-After", retryAfterHeader(retryAfter))
+	}
+	writer.WriteHeader(status)
+	message := errRateLimited
+	if status == http.StatusForbidden {
+		message = GraphError{Message: "forbidden"}
+	}
+	_, err := writer.Write([]byte(formatError(message)))
+	if err != nil {
+		log.Printf("Error writing response: %v", err)
+	}
+}
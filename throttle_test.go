@@ -0,0 +1,244 @@
+package quickgraph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serveThrottled(h *GraphHttpHandler, query string, header map[string]string) (*http.Response, string) {
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"query":"`+query+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	res := rec.Result()
+	body, _ := io.ReadAll(res.Body)
+	return res, string(body)
+}
+
+func TestThrottle_PerIP(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "hello", func() string { return "world" })
+	h := NewGraphHttpHandler(&g)
+	h.Throttle = &Throttle{
+		PerIP: &RateLimit{Budget: 2, Window: time.Minute},
+	}
+
+	for i := 0; i < 2; i++ {
+		res, _ := serveThrottled(h, "{ hello }", nil)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	}
+	res, body := serveThrottled(h, "{ hello }", nil)
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	assert.Equal(t, "60", res.Header.Get("Retry-After"))
+	assert.Equal(t, `{"errors":[{"message":"rate limit exceeded"}]}`, body)
+
+	// X-Forwarded-For is only used to identify the client when it is trusted.
+	res, _ = serveThrottled(h, "{ hello }", map[string]string{"X-Forwarded-For": "198.51.100.7"})
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	h.Throttle.TrustForwardedFor = true
+	res, _ = serveThrottled(h, "{ hello }", map[string]string{"X-Forwarded-For": "198.51.100.7"})
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	res, _ = serveThrottled(h, "{ hello }", map[string]string{"X-Forwarded-For": "198.51.100.7"})
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	// The addresses before the one that the proxy added come from the client, so
+	// changing them doesn't get around the limit.
+	res, _ = serveThrottled(h, "{ hello }", map[string]string{"X-Forwarded-For": "203.0.113.1, 198.51.100.7"})
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+
+	// With two proxies, the address that the outer one added is the client.
+	h.Throttle.TrustedProxies = 2
+	res, _ = serveThrottled(h, "{ hello }", map[string]string{"X-Forwarded-For": "203.0.113.1, 198.51.100.7, 10.0.0.1"})
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	res, _ = serveThrottled(h, "{ hello }", map[string]string{"X-Forwarded-For": "198.51.100.8, 10.0.0.1"})
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestThrottle_ChecksBeforeCharging(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "hello", func() string { return "world" })
+	h := NewGraphHttpHandler(&g)
+	h.Throttle = &Throttle{
+		PerIP:     &RateLimit{Budget: 2, Window: time.Minute},
+		PerAPIKey: &RateLimit{Budget: 1, Window: time.Minute},
+	}
+
+	res, _ := serveThrottled(h, "{ hello }", map[string]string{APIKeyHeader: "a"})
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	// The request is over the limit of the key, so the IP isn't charged for it either.
+	res, _ = serveThrottled(h, "{ hello }", map[string]string{APIKeyHeader: "a"})
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	res, _ = serveThrottled(h, "{ hello }", map[string]string{APIKeyHeader: "b"})
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestThrottle_PerAPIKey(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "hello", func() string { return "world" })
+	h := NewGraphHttpHandler(&g)
+	h.Throttle = &Throttle{
+		PerAPIKey:    &RateLimit{Budget: 1, Window: time.Minute},
+		APIKeyHeader: "X-Key",
+	}
+
+	res, _ := serveThrottled(h, "{ hello }", map[string]string{"X-Key": "a"})
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	res, _ = serveThrottled(h, "{ hello }", map[string]string{"X-Key": "a"})
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	res, _ = serveThrottled(h, "{ hello }", map[string]string{"X-Key": "b"})
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	// Requests without a key only count against the IP limit, of which there is none.
+	res, _ = serveThrottled(h, "{ hello }", nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestThrottle_Banned(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "hello", func() string { return "world" })
+	h := NewGraphHttpHandler(&g)
+	h.Throttle = &Throttle{
+		Banned: func(ip, apiKey string) bool {
+			return apiKey == "revoked"
+		},
+	}
+
+	res, body := serveThrottled(h, "{ hello }", map[string]string{APIKeyHeader: "revoked"})
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	assert.Equal(t, `{"errors":[{"message":"forbidden"}]}`, body)
+
+	res, _ = serveThrottled(h, "{ hello }", map[string]string{APIKeyHeader: "fine"})
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestThrottle_QueryCost(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name: "items",
+		Function: func() []sizeTestItem {
+			return []sizeTestItem{{Name: "a"}}
+		},
+		ListSize: 5,
+	})
+	h := NewGraphHttpHandler(&g)
+	h.Throttle = &Throttle{
+		PerIP:     &RateLimit{Budget: 25, Window: time.Minute},
+		QueryCost: true,
+	}
+
+	// Each of these requests is estimated at 10 values.
+	for i := 0; i < 2; i++ {
+		res, _ := serveThrottled(h, "{ items { Name } }", nil)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	}
+	res, body := serveThrottled(h, "{ items { Name } }", nil)
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	assert.Equal(t, "60", res.Header.Get("Retry-After"))
+	assert.Equal(t, `{"errors":[{"message":"rate limit exceeded"}]}`, body)
+
+	// A cheaper request still fits in the remaining budget.
+	res, _ = serveThrottled(h, "{ __typename }", nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestThrottle_QueryCost_InvalidQueries(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "hello", func() string { return "world" })
+	h := NewGraphHttpHandler(&g)
+	h.Throttle = &Throttle{
+		PerIP:     &RateLimit{Budget: 3, Window: time.Minute},
+		QueryCost: true,
+	}
+
+	// Requests that can't be parsed are charged a unit too.
+	for i := 0; i < 3; i++ {
+		res, _ := serveThrottled(h, "{ hello", nil)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	}
+	res, body := serveThrottled(h, "{ hello }", nil)
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	assert.Equal(t, `{"errors":[{"message":"rate limit exceeded"}]}`, body)
+}
+
+func TestThrottle_QueryCost_OverBudget(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterFunction(ctx, FunctionDefinition{
+		Name: "items",
+		Function: func() []sizeTestItem {
+			return []sizeTestItem{{Name: "a"}}
+		},
+		ListSize: 50,
+	})
+	g.RegisterQuery(ctx, "hello", func() string { return "world" })
+	h := NewGraphHttpHandler(&g)
+	h.Throttle = &Throttle{
+		PerIP:     &RateLimit{Budget: 20, Window: time.Minute},
+		PerAPIKey: &RateLimit{Budget: 1000, Window: time.Minute},
+		QueryCost: true,
+	}
+
+	// The request can never fit in the budget, so it isn't worth retrying.
+	res, body := serveThrottled(h, "{ items { Name } }", nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Empty(t, res.Header.Get("Retry-After"))
+	assert.Equal(t, `{"errors":[{"message":"estimated response size exceeds the maximum of 20 values","locations":[{"line":1,"column":3}],"path":["items"]}]}`, body)
+
+	// Only the unit for receiving it was charged.
+	res, _ = serveThrottled(h, "{ hello }", nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	// The smallest of the budgets that apply to the client counts.
+	res, body = serveThrottled(h, "{ items { Name } }", map[string]string{APIKeyHeader: "k"})
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, body, "estimated response size exceeds the maximum of 20 values")
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+	store := NewMemoryRateLimitStore()
+	store.now = func() time.Time { return now }
+	limit := RateLimit{Budget: 3, Window: 10 * time.Second}
+
+	ok, _, _ := store.Take(ctx, "a", 2, limit)
+	assert.True(t, ok)
+	now = now.Add(4 * time.Second)
+	ok, retryAfter, _ := store.Check(ctx, "a", 2, limit)
+	assert.False(t, ok)
+	assert.Equal(t, 6*time.Second, retryAfter)
+	ok, retryAfter, _ = store.Take(ctx, "a", 2, limit)
+	assert.False(t, ok)
+	assert.Equal(t, 6*time.Second, retryAfter)
+	// Checking doesn't use the budget.
+	ok, _, _ = store.Check(ctx, "a", 1, limit)
+	assert.True(t, ok)
+	ok, _, _ = store.Take(ctx, "a", 1, limit)
+	assert.True(t, ok)
+
+	// A cost larger than the budget never fits.
+	ok, _, _ = store.Take(ctx, "b", 4, limit)
+	assert.False(t, ok)
+
+	// The budget is renewed in the next window, and old windows are pruned.
+	now = now.Add(20 * time.Second)
+	ok, _, _ = store.Take(ctx, "a", 3, limit)
+	assert.True(t, ok)
+	assert.Len(t, store.windows, 1)
+}
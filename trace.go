@@ -0,0 +1,225 @@
+package quickgraph
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExecutionTraceHeader is the HTTP header that, when set to "true", causes the handler to
+// trace the execution of the request. It is only honored in DevelopmentMode, since traces
+// include the arguments of the calls. See WithExecutionTrace for details.
+const ExecutionTraceHeader = "X-GraphQL-Trace"
+
+// ExecutionTrace is the trace of the execution of a single request, for debugging
+// requests that misbehave.
+type ExecutionTrace struct {
+	// Query is the request as it was received.
+	Query string `json:"query"`
+
+	// Variables are the values of the variables after they were parsed.
+	Variables map[string]any `json:"variables,omitempty"`
+
+	// Document is the request as it was parsed, with the fragments merged into the fields
+	// that use them.
+	Document *RequestDocument `json:"document"`
+
+	// Plan is how the fields of the request map onto the queries or mutations that are
+	// called, in the order that they appear in the request.
+	Plan []TracePlanStep `json:"plan"`
+
+	// Resolvers are the calls that were made to resolve the fields, in the order that
+	// they completed.
+	Resolvers []ResolverTrace `json:"resolvers"`
+
+	// Duration is how long the request took to execute, in nanoseconds.
+	Duration time.Duration `json:"durationNs"`
+
+	start time.Time
+	mu    sync.Mutex
+}
+
+// TracePlanStep is a query or mutation that a request calls.
+type TracePlanStep struct {
+	// Field is the name of the field in the response, which is the alias if it has one.
+	Field string `json:"field"`
+
+	// Operation is the query or mutation that is called for the field, such as
+	// "Query.orders".
+	Operation string `json:"operation"`
+
+	// ReturnType is the name of the type that the operation returns.
+	ReturnType string `json:"returnType"`
+}
+
+// ResolverTrace is a call that was made to resolve a field.
+type ResolverTrace struct {
+	// Type is the type that the field belongs to, "Query" or "Mutation" for the
+	// operations themselves.
+	Type  string `json:"type"`
+	Field string `json:"field"`
+
+	// Arguments are the arguments of the call, with the values of the variables that it
+	// uses filled in.
+	Arguments map[string]any `json:"arguments,omitempty"`
+
+	// Start is when the call started, in nanoseconds since the start of the request, and
+	// Duration is how long it took.
+	Start    time.Duration `json:"startNs"`
+	Duration time.Duration `json:"durationNs"`
+
+	// Error is the error that the call returned, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// WithExecutionTrace returns a context that causes the request that is processed with it
+// to be traced. The trace is added to the extensions of the response as
+// "executionTrace", or passed to Graphy.ExecutionTraceSink if that is set. Tracing is
+// expensive and the traces contain the arguments of all the calls, so this is meant for
+// debugging, not for production traffic.
+func WithExecutionTrace(ctx context.Context) context.Context {
+	return context.WithValue(ctx, executionTraceContextKey, true)
+}
+
+// IsExecutionTraced reports whether the context belongs to a request that is traced.
+func IsExecutionTraced(ctx context.Context) bool {
+	traced, _ := ctx.Value(executionTraceContextKey).(bool)
+	return traced
+}
+
+// newExecutionTrace starts the trace of a request.
+func (r *request) newExecutionTrace() *ExecutionTrace {
+	rs := &r.stub
+	trace := &ExecutionTrace{
+		Query:    r.document,
+		Document: newRequestDocument(rs.parsedCall, rs.fragments),
+		start:    r.graphy.now(),
+	}
+	if len(r.variables) > 0 {
+		trace.Variables = map[string]any{}
+		for name, value := range r.variables {
+			trace.Variables[name] = value.Interface()
+		}
+	}
+
+	operationType := "Query"
+	if rs.mode == RequestMutation {
+		operationType = "Mutation"
+	}
+	for _, command := range rs.commands {
+		step := TracePlanStep{
+			Field:     command.Name,
+			Operation: operationType + "." + command.Name,
+		}
+		if command.Alias != nil {
+			step.Field = *command.Alias
+		}
+		if processor, ok := r.graphy.processors[command.Name]; ok && processor.baseReturnType != nil {
+			step.ReturnType = processor.baseReturnType.name
+		}
+		trace.Plan = append(trace.Plan, step)
+	}
+	return trace
+}
+
+// tracing reports whether the execution of the request is traced.
+func (r *request) tracing() bool {
+	return r != nil && r.trace != nil
+}
+
+// traceResolver adds a call that resolved a field to the trace of the request, if it is
+// traced.
+func (r *request) traceResolver(typeName, fieldName string, params *parameterList, start time.Time, duration time.Duration, err error) {
+	if !r.tracing() {
+		return
+	}
+	rt := ResolverTrace{
+		Type:      typeName,
+		Field:     fieldName,
+		Arguments: r.traceArguments(params),
+		Start:     start.Sub(r.trace.start),
+		Duration:  duration,
+	}
+	if err != nil {
+		rt.Error = err.Error()
+	}
+
+	r.trace.mu.Lock()
+	defer r.trace.mu.Unlock()
+	r.trace.Resolvers = append(r.trace.Resolvers, rt)
+}
+
+// traceArguments returns the arguments of a call as plain values for the trace.
+func (r *request) traceArguments(params *parameterList) map[string]any {
+	if params == nil || len(params.Values) == 0 {
+		return nil
+	}
+	return r.traceObject(params.Values)
+}
+
+func (r *request) traceObject(values []namedValue) map[string]any {
+	result := map[string]any{}
+	for _, v := range values {
+		result[v.Name] = r.traceValue(v.Value)
+	}
+	return result
+}
+
+func (r *request) traceValue(v genericValue) any {
+	switch {
+	case v.Variable != nil:
+		if value, ok := r.variables[(*v.Variable)[1:]]; ok && value.IsValid() {
+			return value.Interface()
+		}
+		return nil
+	case v.Identifier != nil:
+		switch strings.ToLower(*v.Identifier) {
+		case "null":
+			return nil
+		case "true":
+			return true
+		case "false":
+			return false
+		}
+		return *v.Identifier
+	case v.String != nil:
+		// The string value has quotes around it, remove them.
+		return (*v.String)[1 : len(*v.String)-1]
+	case v.Int != nil:
+		return *v.Int
+	case v.Float != nil:
+		return *v.Float
+	case v.Map != nil:
+		return r.traceObject(v.Map)
+	}
+	list := []any{}
+	for _, item := range v.List {
+		list = append(list, r.traceValue(item))
+	}
+	return list
+}
+
+// finishExecutionTrace completes the trace of the request and either passes it to the
+// ExecutionTraceSink or adds it to the extensions of the response.
+func (r *request) finishExecutionTrace(ctx context.Context, extensions map[string]any) {
+	// Commands that were abandoned may still be adding to the trace, so a copy of it is
+	// handed out.
+	r.trace.mu.Lock()
+	trace := &ExecutionTrace{
+		Query:     r.trace.Query,
+		Variables: r.trace.Variables,
+		Document:  r.trace.Document,
+		Plan:      r.trace.Plan,
+		Resolvers: append([]ResolverTrace(nil), r.trace.Resolvers...),
+		Duration:  r.graphy.now().Sub(r.trace.start),
+		start:     r.trace.start,
+	}
+	r.trace.mu.Unlock()
+
+	if r.graphy.ExecutionTraceSink != nil {
+		r.graphy.ExecutionTraceSink(ctx, trace)
+		return
+	}
+	extensions["executionTrace"] = trace
+}
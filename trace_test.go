@@ -0,0 +1,120 @@
+package quickgraph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type traceOrder struct {
+	Id    int
+	Total float64
+}
+
+func (o traceOrder) Customer(ctx context.Context, verbose bool) (string, error) {
+	if verbose {
+		return "", errors.New("no details")
+	}
+	return "Ann", nil
+}
+
+func TestExecutionTrace(t *testing.T) {
+	ctx := context.Background()
+	// The clock advances by a millisecond each time it is read.
+	var mu sync.Mutex
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	g := Graphy{TestingHooks: &TestingHooks{Now: func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(time.Millisecond)
+		return now
+	}}}
+	g.RegisterMutation(ctx, "placeOrder", func(id int) traceOrder {
+		return traceOrder{Id: id, Total: 9.5}
+	}, "id")
+
+	// Requests aren't traced unless they ask for it.
+	query := `mutation place($id: Int!) { order: placeOrder(id: $id) { Id Customer(verbose: false) } }`
+	result, err := g.ProcessRequest(ctx, query, `{"id": 7}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"order":{"Customer":"Ann","Id":7}}}`, result)
+
+	result, err = g.ProcessRequest(WithExecutionTrace(ctx), query, `{"id": 7}`)
+	assert.NoError(t, err)
+	var response struct {
+		Extensions struct {
+			ExecutionTrace json.RawMessage `json:"executionTrace"`
+		} `json:"extensions"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(result), &response))
+	assert.JSONEq(t, `{
+		"query": "mutation place($id: Int!) { order: placeOrder(id: $id) { Id Customer(verbose: false) } }",
+		"variables": {"id": 7},
+		"document": {
+			"operation": "mutation",
+			"name": "place",
+			"variables": ["id"],
+			"fields": [{"name": "placeOrder", "alias": "order", "arguments": ["id"], "line": 1, "column": 29, "fields": [
+				{"name": "Id", "line": 1, "column": 58},
+				{"name": "Customer", "arguments": ["verbose"], "line": 1, "column": 61}
+			]}]
+		},
+		"plan": [{"field": "order", "operation": "Mutation.placeOrder", "returnType": "traceOrder"}],
+		"resolvers": [
+			{"type": "Mutation", "field": "placeOrder", "arguments": {"id": 7}, "startNs": 1000000, "durationNs": 1000000},
+			{"type": "traceOrder", "field": "Id", "startNs": 3000000, "durationNs": 1000000},
+			{"type": "traceOrder", "field": "Customer", "arguments": {"verbose": false}, "startNs": 5000000, "durationNs": 1000000}
+		],
+		"durationNs": 7000000
+	}`, string(response.Extensions.ExecutionTrace))
+}
+
+func TestExecutionTrace_Sink(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterMutation(ctx, "placeOrder", func(id int) traceOrder {
+		return traceOrder{Id: id, Total: 9.5}
+	}, "id")
+	var traces []*ExecutionTrace
+	g.ExecutionTraceSink = func(ctx context.Context, trace *ExecutionTrace) {
+		traces = append(traces, trace)
+	}
+
+	// The trace goes to the sink instead of the response, and includes the errors.
+	result, err := g.ProcessRequest(WithExecutionTrace(ctx), `mutation { placeOrder(id: 1) { Customer(verbose: true) } }`, "")
+	assert.Error(t, err)
+	assert.NotContains(t, result, "executionTrace")
+	if assert.Len(t, traces, 1) {
+		assert.Len(t, traces[0].Resolvers, 2)
+		assert.Equal(t, "Customer", traces[0].Resolvers[1].Field)
+		assert.Equal(t, "function Customer returned error [1:41]: no details", traces[0].Resolvers[1].Error)
+	}
+}
+
+func TestExecutionTrace_HTTPHeader(t *testing.T) {
+	g := Graphy{}
+	g.RegisterMutation(context.Background(), "placeOrder", func(id int) traceOrder {
+		return traceOrder{Id: id, Total: 9.5}
+	}, "id")
+	h := g.HttpHandler()
+
+	serve := func() string {
+		body, _ := json.Marshal(graphqlRequest{Query: `mutation { placeOrder(id: 1) { Id } }`})
+		req := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+		req.Header.Set(ExecutionTraceHeader, "true")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Body.String()
+	}
+
+	// The header is only honored in development mode.
+	assert.NotContains(t, serve(), "executionTrace")
+	g.DevelopmentMode = true
+	assert.Contains(t, serve(), `"executionTrace":{`)
+}
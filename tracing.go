@@ -0,0 +1,131 @@
+package quickgraph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Tracer starts the spans that trace the processing of requests in a distributed tracing
+// system such as OpenTelemetry. Like the MetricsCollector, it keeps the library free of
+// the dependencies of the tracing system; the README shows an adapter for an OpenTelemetry
+// TracerProvider. The spans and their attributes follow the OpenTelemetry semantic
+// conventions for GraphQL servers:
+//
+//   - Each request has a span named after its operation type and name, such as
+//     "query GetUser", with the graphql.operation.type, graphql.operation.name, and
+//     graphql.document attributes.
+//   - Parsing and validating a request that isn't in the RequestCache have the
+//     "graphql.parse" and "graphql.validate" spans.
+//   - Each call of a query, mutation, or field function has a span named after the
+//     field, such as "Query.user" or "User.orders", with the graphql.field.name,
+//     graphql.field.parent_type, and graphql.field.path attributes. The path is the keys
+//     of the field in the result joined with dots, such as "user.orders.0.total".
+//
+// The context of the span of a call is passed to the function, so the spans that it starts
+// are children of it. Tracer is called concurrently, so it must be safe for concurrent use.
+type Tracer interface {
+	// Start starts a span as a child of the span of the context, if it has one, and
+	// returns a context that carries the new span.
+	Start(ctx context.Context, name string, attributes ...SpanAttribute) (context.Context, Span)
+}
+
+// Span is a span that a Tracer started.
+type Span interface {
+	// SetName changes the name of the span.
+	SetName(name string)
+
+	// SetAttributes adds attributes to the span.
+	SetAttributes(attributes ...SpanAttribute)
+
+	// End ends the span, marking it as failed with the error if it isn't nil.
+	End(err error)
+}
+
+// SpanAttribute is an attribute of a Span.
+type SpanAttribute struct {
+	Key   string
+	Value string
+}
+
+// The attributes of the spans, as named by the semantic conventions.
+const (
+	SpanAttributeOperationName   = "graphql.operation.name"
+	SpanAttributeOperationType   = "graphql.operation.type"
+	SpanAttributeDocument        = "graphql.document"
+	SpanAttributeFieldName       = "graphql.field.name"
+	SpanAttributeFieldParentType = "graphql.field.parent_type"
+	SpanAttributeFieldPath       = "graphql.field.path"
+)
+
+// operationSpanName is the name of the span of a request until it is known which operation
+// the request is.
+const operationSpanName = "GraphQL Operation"
+
+// endNoSpan is returned by the functions that start spans when there is no Tracer.
+func endNoSpan(error) {}
+
+// startSpan starts a span, if there is a Tracer, and returns a function that ends it.
+func (g *Graphy) startSpan(ctx context.Context, name string, attributes ...SpanAttribute) (context.Context, func(err error)) {
+	if g.Tracer == nil {
+		return ctx, endNoSpan
+	}
+	ctx, span := g.Tracer.Start(ctx, name, attributes...)
+	return ctx, span.End
+}
+
+// startOperationSpan starts the span of a request, if there is a Tracer. The returned
+// function names the span after the operation of the stub, if the request got that far,
+// and ends it with the error of the request.
+func (g *Graphy) startOperationSpan(ctx context.Context, document string) (context.Context, func(rs *RequestStub, err error)) {
+	if g.Tracer == nil {
+		return ctx, func(*RequestStub, error) {}
+	}
+	ctx, span := g.Tracer.Start(ctx, operationSpanName, SpanAttribute{Key: SpanAttributeDocument, Value: document})
+	return ctx, func(rs *RequestStub, err error) {
+		if rs != nil {
+			operationType := "query"
+			if rs.mode == RequestMutation {
+				operationType = "mutation"
+			}
+			span.SetAttributes(SpanAttribute{Key: SpanAttributeOperationType, Value: operationType})
+			if rs.parsedCall.OperationDef != nil && rs.parsedCall.OperationDef.Name != "" {
+				name := rs.parsedCall.OperationDef.Name
+				span.SetName(operationType + " " + name)
+				span.SetAttributes(SpanAttribute{Key: SpanAttributeOperationName, Value: name})
+			} else {
+				span.SetName(operationType)
+			}
+		}
+		var pending pendingTypesError
+		if errors.As(err, &pending) {
+			// The request is processed again once the types are resolved.
+			err = nil
+		}
+		span.End(err)
+	}
+}
+
+// startResolverSpan starts the span of a call that resolves the field of the type, if
+// there is a Tracer. The key is the key of the field in the result, which completes the
+// path of the context.
+func (r *request) startResolverSpan(ctx context.Context, typeName, fieldName string, key any) (context.Context, func(err error)) {
+	if r == nil || r.graphy.Tracer == nil {
+		return ctx, endNoSpan
+	}
+	return r.graphy.startSpan(ctx, typeName+"."+fieldName,
+		SpanAttribute{Key: SpanAttributeFieldName, Value: fieldName},
+		SpanAttribute{Key: SpanAttributeFieldParentType, Value: typeName},
+		SpanAttribute{Key: SpanAttributeFieldPath, Value: formatResultPath(append(currentResultPath(ctx), key))},
+	)
+}
+
+// formatResultPath formats a path in the result with dots between its keys.
+func formatResultPath(path []any) string {
+	parts := make([]string, len(path))
+	for i, key := range path {
+		parts[i] = fmt.Sprint(key)
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,108 @@
+package quickgraph
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type testSpanContextKey struct{}
+
+// testTracer records the spans that it starts in the order that they end.
+type testTracer struct {
+	mu    sync.Mutex
+	spans []string
+}
+
+type testSpan struct {
+	tracer     *testTracer
+	name       string
+	parent     string
+	attributes []string
+}
+
+func (t *testTracer) Start(ctx context.Context, name string, attributes ...SpanAttribute) (context.Context, Span) {
+	span := &testSpan{tracer: t, name: name}
+	if parent, ok := ctx.Value(testSpanContextKey{}).(*testSpan); ok {
+		span.parent = parent.name
+	}
+	span.SetAttributes(attributes...)
+	return context.WithValue(ctx, testSpanContextKey{}, span), span
+}
+
+func (s *testSpan) SetName(name string) {
+	s.name = name
+}
+
+func (s *testSpan) SetAttributes(attributes ...SpanAttribute) {
+	for _, a := range attributes {
+		s.attributes = append(s.attributes, a.Key+"="+a.Value)
+	}
+}
+
+func (s *testSpan) End(err error) {
+	sort.Strings(s.attributes)
+	line := fmt.Sprintf("%s <- %s %s", s.name, s.parent, strings.Join(s.attributes, " "))
+	if err != nil {
+		line += " error"
+	}
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.tracer.spans = append(s.tracer.spans, line)
+}
+
+func TestGraphy_Tracer(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterMutation(ctx, "placeOrder", func(id int) traceOrder {
+		return traceOrder{Id: id, Total: 9.5}
+	}, "id")
+	g.RegisterQuery(ctx, "orders", func(ctx context.Context) []traceOrder {
+		// The spans of the functions are children of the spans of their calls.
+		_, span := g.Tracer.Start(ctx, "db")
+		span.End(nil)
+		return []traceOrder{{Id: 1}, {Id: 2}}
+	})
+	tracer := &testTracer{}
+	g.Tracer = tracer
+
+	result, err := g.ProcessRequest(ctx, `query recent { orders { Id Customer(verbose: false) } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"orders":[{"Customer":"Ann","Id":1},{"Customer":"Ann","Id":2}]}}`, result)
+	assert.Equal(t, []string{
+		"graphql.parse <- GraphQL Operation ",
+		"graphql.validate <- GraphQL Operation ",
+		"db <- Query.orders ",
+		"Query.orders <- GraphQL Operation graphql.field.name=orders graphql.field.parent_type=Query graphql.field.path=orders",
+		"traceOrder.Id <- GraphQL Operation graphql.field.name=Id graphql.field.parent_type=traceOrder graphql.field.path=orders.0.Id",
+		"traceOrder.Customer <- GraphQL Operation graphql.field.name=Customer graphql.field.parent_type=traceOrder graphql.field.path=orders.0.Customer",
+		"traceOrder.Id <- GraphQL Operation graphql.field.name=Id graphql.field.parent_type=traceOrder graphql.field.path=orders.1.Id",
+		"traceOrder.Customer <- GraphQL Operation graphql.field.name=Customer graphql.field.parent_type=traceOrder graphql.field.path=orders.1.Customer",
+		"query recent <-  graphql.document=query recent { orders { Id Customer(verbose: false) } } graphql.operation.name=recent graphql.operation.type=query",
+	}, tracer.spans)
+
+	// Failed calls fail their spans and those of their requests.
+	tracer.spans = nil
+	_, err = g.ProcessRequest(ctx, `mutation { order: placeOrder(id: 3) { Customer(verbose: true) } }`, "")
+	assert.Error(t, err)
+	assert.Equal(t, []string{
+		"graphql.parse <- GraphQL Operation ",
+		"graphql.validate <- GraphQL Operation ",
+		"Mutation.placeOrder <- GraphQL Operation graphql.field.name=placeOrder graphql.field.parent_type=Mutation graphql.field.path=order",
+		"traceOrder.Customer <- GraphQL Operation graphql.field.name=Customer graphql.field.parent_type=traceOrder graphql.field.path=order.Customer error",
+		"mutation <-  graphql.document=mutation { order: placeOrder(id: 3) { Customer(verbose: true) } } graphql.operation.type=mutation error",
+	}, tracer.spans)
+
+	// The spans of requests that can't be parsed keep their generic name.
+	tracer.spans = nil
+	_, err = g.ProcessRequest(ctx, `{ orders `, "")
+	assert.Error(t, err)
+	assert.Equal(t, []string{
+		"graphql.parse <- GraphQL Operation  error",
+		"GraphQL Operation <-  graphql.document={ orders  error",
+	}, tracer.spans)
+}
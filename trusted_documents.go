@@ -0,0 +1,183 @@
+package quickgraph
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/alecthomas/participle/v2/lexer"
+	"io/fs"
+	"log"
+	"path"
+	"strings"
+	"sync"
+)
+
+// ErrorCodeTrustedDocumentNotFound is the code in the extensions of the error for a
+// request for a trusted document that doesn't exist.
+const ErrorCodeTrustedDocumentNotFound = "TRUSTED_DOCUMENT_NOT_FOUND"
+
+// ErrorCodeTrustedDocumentRequired is the code in the extensions of the error for a
+// request that isn't a trusted document when TrustedDocumentsOnly is set.
+const ErrorCodeTrustedDocumentRequired = "TRUSTED_DOCUMENT_REQUIRED"
+
+// TrustedDocumentStore looks up trusted documents, which are the requests that are known
+// ahead of time, by their IDs. Clients send the ID of a document instead of its text,
+// and with TrustedDocumentsOnly, those are the only requests that are accepted.
+type TrustedDocumentStore interface {
+	// TrustedDocument returns the text of the document with the ID, and whether there is
+	// one.
+	TrustedDocument(id string) (string, bool)
+}
+
+// FSTrustedDocuments is a TrustedDocumentStore with the documents from the files of an
+// fs.FS, such as a directory with os.DirFS or an embed.FS. Use NewFSTrustedDocuments to
+// create one. The files are:
+//
+//   - .graphql and .gql files, each with one document. Its IDs are the path of the file
+//     without the extension, such as "users/getUser", and the SHA-256 of its text in
+//     hexadecimal, which is what clients of automatic persisted queries send.
+//   - .json files with an object that maps IDs to documents, such as the manifests that
+//     tools like Relay generate.
+//
+// Other files are ignored.
+type FSTrustedDocuments struct {
+	// Reload causes the files to be read again each time a document is looked up, so
+	// that changes to them are picked up without a restart. This is meant for development
+	// with os.DirFS.
+	Reload bool
+
+	fsys      fs.FS
+	mu        sync.RWMutex
+	documents map[string]string
+}
+
+// NewFSTrustedDocuments reads the trusted documents from the files of fsys.
+func NewFSTrustedDocuments(fsys fs.FS) (*FSTrustedDocuments, error) {
+	documents, err := readTrustedDocuments(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &FSTrustedDocuments{
+		fsys:      fsys,
+		documents: documents,
+	}, nil
+}
+
+// TrustedDocument implements TrustedDocumentStore.
+func (f *FSTrustedDocuments) TrustedDocument(id string) (string, bool) {
+	if f.Reload {
+		documents, err := readTrustedDocuments(f.fsys)
+		if err != nil {
+			// Keep using the documents that were read last.
+			log.Printf("Error reloading trusted documents: %v", err)
+		} else {
+			f.mu.Lock()
+			f.documents = documents
+			f.mu.Unlock()
+		}
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	document, ok := f.documents[id]
+	return document, ok
+}
+
+// Len returns the number of trusted documents.
+func (f *FSTrustedDocuments) Len() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return len(f.documents)
+}
+
+func readTrustedDocuments(fsys fs.FS) (map[string]string, error) {
+	documents := map[string]string{}
+	add := func(id, document, file string) error {
+		if existing, ok := documents[id]; ok && existing != document {
+			return fmt.Errorf("trusted document %s in %s is already defined differently", id, file)
+		}
+		documents[id] = document
+		return nil
+	}
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		ext := path.Ext(name)
+		switch ext {
+		case ".graphql", ".gql":
+			content, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return err
+			}
+			document := string(content)
+			err = add(strings.TrimSuffix(name, ext), document, name)
+			if err != nil {
+				return err
+			}
+			return add(documentHash(document), document, name)
+		case ".json":
+			content, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return err
+			}
+			manifest := map[string]string{}
+			err = json.Unmarshal(content, &manifest)
+			if err != nil {
+				return fmt.Errorf("error reading trusted documents from %s: %w", name, err)
+			}
+			for id, document := range manifest {
+				err = add(id, document, name)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+// documentHash returns the SHA-256 of a document in hexadecimal.
+func documentHash(document string) string {
+	sum := sha256.Sum256([]byte(document))
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadTrustedDocuments reads the trusted documents from the files of fsys and uses them as
+// the TrustedDocuments. See FSTrustedDocuments for the files that are read.
+//
+//	//go:embed operations
+//	var operations embed.FS
+//
+//	err := g.LoadTrustedDocuments(operations)
+func (g *Graphy) LoadTrustedDocuments(fsys fs.FS) error {
+	store, err := NewFSTrustedDocuments(fsys)
+	if err != nil {
+		return err
+	}
+	g.TrustedDocuments = store
+	return nil
+}
+
+// ProcessTrustedDocument processes the trusted document with the ID the same way that
+// ProcessRequest processes the text of a request.
+func (g *Graphy) ProcessTrustedDocument(ctx context.Context, id string, variableJson string) (string, error) {
+	var document string
+	ok := false
+	if g.TrustedDocuments != nil {
+		document, ok = g.TrustedDocuments.TrustedDocument(id)
+	}
+	if !ok {
+		gErr := NewGraphError(fmt.Sprintf("unknown trusted document %s", id), lexer.Position{})
+		gErr.AddExtension("code", ErrorCodeTrustedDocumentNotFound)
+		return g.formatRequestError(ctx, gErr), gErr
+	}
+	return g.processRequestWithTypes(ctx, document, variableJson)
+}
@@ -0,0 +1,110 @@
+package quickgraph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const greetingDocument = `query Greet($name: String!) { greeting(name: $name) }`
+
+func TestTrustedDocuments(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "greeting", func(name string) string {
+		return "Hello, " + name
+	}, "name")
+	err := g.LoadTrustedDocuments(fstest.MapFS{
+		"greetings/greet.graphql": {Data: []byte(greetingDocument)},
+		"manifest.json":           {Data: []byte(`{"abc123": "{ greeting(name: \"Bob\") }"}`)},
+		"README.md":               {Data: []byte("not a document")},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, g.TrustedDocuments.(*FSTrustedDocuments).Len())
+
+	result, err := g.ProcessTrustedDocument(ctx, "greetings/greet", `{"name": "Ada"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"greeting":"Hello, Ada"}}`, result)
+
+	result, err = g.ProcessTrustedDocument(ctx, documentHash(greetingDocument), `{"name": "Hash"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"greeting":"Hello, Hash"}}`, result)
+
+	result, err = g.ProcessTrustedDocument(ctx, "abc123", "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"greeting":"Hello, Bob"}}`, result)
+
+	result, err = g.ProcessTrustedDocument(ctx, "missing", "")
+	assert.Error(t, err)
+	assert.Equal(t, `{"errors":[{"message":"unknown trusted document missing","extensions":{"code":"TRUSTED_DOCUMENT_NOT_FOUND"}}]}`, result)
+
+	// With TrustedDocumentsOnly, other requests are rejected.
+	g.TrustedDocumentsOnly = true
+	result, err = g.ProcessRequest(ctx, greetingDocument, `{"name": "Eve"}`)
+	assert.Error(t, err)
+	assert.Equal(t, `{"errors":[{"message":"only trusted documents are accepted","extensions":{"code":"TRUSTED_DOCUMENT_REQUIRED"}}]}`, result)
+
+	result, err = g.ProcessTrustedDocument(ctx, "greetings/greet", `{"name": "Ada"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"greeting":"Hello, Ada"}}`, result)
+}
+
+func TestTrustedDocuments_Errors(t *testing.T) {
+	_, err := NewFSTrustedDocuments(fstest.MapFS{
+		"bad.json": {Data: []byte(`["not", "a", "map"]`)},
+	})
+	assert.ErrorContains(t, err, "error reading trusted documents from bad.json")
+
+	_, err = NewFSTrustedDocuments(fstest.MapFS{
+		"a.json": {Data: []byte(`{"greet": "{ a }"}`)},
+		"b.json": {Data: []byte(`{"greet": "{ b }"}`)},
+	})
+	assert.EqualError(t, err, "trusted document greet in b.json is already defined differently")
+}
+
+func TestTrustedDocuments_Reload(t *testing.T) {
+	fsys := fstest.MapFS{
+		"greet.graphql": {Data: []byte(`{ greeting(name: "One") }`)},
+	}
+	store, err := NewFSTrustedDocuments(fsys)
+	assert.NoError(t, err)
+
+	fsys["greet.graphql"] = &fstest.MapFile{Data: []byte(`{ greeting(name: "Two") }`)}
+	document, ok := store.TrustedDocument("greet")
+	assert.True(t, ok)
+	assert.Equal(t, `{ greeting(name: "One") }`, document)
+
+	store.Reload = true
+	document, ok = store.TrustedDocument("greet")
+	assert.True(t, ok)
+	assert.Equal(t, `{ greeting(name: "Two") }`, document)
+}
+
+func TestTrustedDocuments_HTTP(t *testing.T) {
+	g := Graphy{TrustedDocumentsOnly: true}
+	g.RegisterQuery(context.Background(), "greeting", func(name string) string {
+		return "Hello, " + name
+	}, "name")
+	err := g.LoadTrustedDocuments(fstest.MapFS{
+		"greet.graphql": {Data: []byte(greetingDocument)},
+	})
+	assert.NoError(t, err)
+	h := g.HttpHandler()
+
+	bodies := []string{
+		`{"documentId": "greet", "variables": {"name": "Ada"}}`,
+		`{"extensions": {"persistedQuery": {"version": 1, "sha256Hash": "` + documentHash(greetingDocument) + `"}}, "variables": {"name": "Ada"}}`,
+	}
+	for _, body := range bodies {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		assert.Equal(t, `{"data":{"greeting":"Hello, Ada"}}`, rec.Body.String())
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader(`{"query": "{ greeting(name: \"Eve\") }"}`)))
+	assert.Equal(t, `{"errors":[{"message":"only trusted documents are accepted","extensions":{"code":"TRUSTED_DOCUMENT_REQUIRED"}}]}`, rec.Body.String())
+}
@@ -0,0 +1,153 @@
+package quickgraph
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// TypeAdapter configures how a type is represented in the graph without changing the
+// type itself. It serves the same purpose as the graphy tags and GraphTypeExtension, but
+// for types that can't be edited, such as those from other packages. See
+// RegisterTypeAdapter.
+type TypeAdapter struct {
+	// Name, if set, is the name of the type in the graph.
+	Name string
+
+	// Description is the description of the type.
+	Description string
+
+	// Deprecated, if set, marks the type as deprecated with this as the reason.
+	Deprecated string
+
+	// Fields configures the fields of the type, keyed by the name of the Go field.
+	Fields map[string]FieldAdapter
+}
+
+// FieldAdapter configures a single field of a type. See TypeAdapter.
+type FieldAdapter struct {
+	// Name, if set, is the name of the field in the graph. Like a name in the graphy
+	// tag, this includes the field even if its json tag excludes it.
+	Name string
+
+	// Exclude leaves the field out of the graph.
+	Exclude bool
+
+	// Description is the description of the field.
+	Description string
+
+	// Deprecated, if set, marks the field as deprecated with this as the reason.
+	Deprecated string
+
+	// Since and RemovedIn are the versions of the API that the field was added in and is
+	// scheduled to be removed in, like the since and removedIn parts of the graphy tag.
+	Since     string
+	RemovedIn string
+}
+
+// RegisterTypeAdapter registers an adapter that configures how a type is represented in
+// the graph. The settings of the adapter take precedence over the tags of the type.
+//
+// The adapter must be registered before the type is first used, including by registering
+// a function that takes or returns it. Registering an adapter for a type that is already
+// in use, or one that refers to fields that the type doesn't have, panics.
+func (g *Graphy) RegisterTypeAdapter(ctx context.Context, typ any, adapter TypeAdapter) {
+	g.structureLock.Lock()
+	defer g.structureLock.Unlock()
+
+	baseType := reflect.TypeOf(typ)
+	if baseType.Kind() == reflect.Ptr {
+		baseType = baseType.Elem()
+	}
+	if baseType.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("type adapter: %v is not a struct", baseType))
+	}
+	for name := range adapter.Fields {
+		if _, ok := baseType.FieldByName(name); !ok {
+			panic(fmt.Sprintf("type adapter: %v has no field %s", baseType, name))
+		}
+	}
+
+	g.typeMutex.Lock()
+	defer g.typeMutex.Unlock()
+	for _, tl := range g.typeLookups {
+		if tl.rootType == baseType {
+			panic(fmt.Sprintf("type adapter: %v is already in use", baseType))
+		}
+	}
+
+	if g.typeAdapters == nil {
+		g.typeAdapters = map[reflect.Type]TypeAdapter{}
+	}
+	g.typeAdapters[baseType] = adapter
+	g.invalidateSchema()
+}
+
+func (a TypeAdapter) applyToType(tl *typeLookup) {
+	if a.Name != "" {
+		tl.name = a.Name
+	}
+	if a.Description != "" {
+		description := a.Description
+		tl.description = &description
+	}
+	if a.Deprecated != "" {
+		tl.isDeprecated = true
+		tl.deprecatedReason = a.Deprecated
+	}
+}
+
+// applyToField applies the adapter to the field lookup that was created from the tags of
+// the field. An empty name in the result means the field is not part of the graph.
+func (a TypeAdapter) applyToField(field reflect.StructField, index []int, tfl fieldLookup) fieldLookup {
+	fa, ok := a.Fields[field.Name]
+	if !ok || !field.IsExported() {
+		return tfl
+	}
+	if fa.Exclude {
+		return fieldLookup{}
+	}
+	if fa.Name != "" {
+		if tfl.name == "" {
+			tfl = fieldLookup{
+				resultType:   field.Type,
+				fieldIndexes: index,
+				fieldType:    FieldTypeField,
+			}
+		}
+		tfl.name = fa.Name
+	}
+	if tfl.name == "" {
+		return tfl
+	}
+	if fa.Description != "" {
+		description := fa.Description
+		tfl.description = &description
+	}
+	if fa.Deprecated != "" {
+		tfl.isDeprecated = true
+		tfl.deprecatedReason = fa.Deprecated
+	}
+	if fa.Since != "" {
+		tfl.versions.since = fa.Since
+	}
+	if fa.RemovedIn != "" {
+		tfl.versions.removedIn = fa.RemovedIn
+	}
+	return tfl
+}
+
+// schemaDescription is the description of the field in the schema: the description of
+// the field followed by the versions it was added and removed in.
+func (t *fieldLookup) schemaDescription() *string {
+	versions := t.versions.description()
+	if t.description == nil {
+		return versions
+	}
+	if versions == nil {
+		return t.description
+	}
+	result := strings.Join([]string{*t.description, *versions}, " ")
+	return &result
+}
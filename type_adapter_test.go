@@ -0,0 +1,77 @@
+package quickgraph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// adaptedInvoice stands in for a type from a package that can't be edited.
+type adaptedInvoice struct {
+	ID       string `json:"id"`
+	Total    int    `json:"total"`
+	Internal string `json:"-"`
+	Secret   string
+	OldTotal int `json:"oldTotal"`
+}
+
+func TestGraphy_RegisterTypeAdapter(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterTypeAdapter(ctx, adaptedInvoice{}, TypeAdapter{
+		Name:        "Invoice",
+		Description: "An invoice from billing",
+		Fields: map[string]FieldAdapter{
+			"Total":    {Name: "amount", Description: "Total in cents", Since: "1.2"},
+			"Internal": {Name: "internalRef"},
+			"Secret":   {Exclude: true},
+			"OldTotal": {Deprecated: "Use amount"},
+		},
+	})
+	g.RegisterQuery(ctx, "invoice", func() adaptedInvoice {
+		return adaptedInvoice{ID: "inv-1", Total: 1250, Internal: "ref", Secret: "s", OldTotal: 12}
+	})
+	g.EnableIntrospection(ctx)
+
+	result, err := g.ProcessRequest(ctx, `{ invoice { id amount internalRef oldTotal } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"invoice":{"amount":1250,"id":"inv-1","internalRef":"ref","oldTotal":12}}}`, result)
+
+	_, err = g.ProcessRequest(ctx, `{ invoice { Secret } }`, "")
+	assert.Error(t, err)
+
+	expected := `type Query {
+	invoice: Invoice!
+}
+
+type Invoice {
+	"Total in cents Since 1.2."
+	amount: Int!
+	id: String!
+	internalRef: String!
+	oldTotal: Int! @deprecated(reason: "Use amount")
+}
+
+`
+	assert.Equal(t, expected, g.SchemaDefinition(ctx))
+
+	result, err = g.ProcessRequest(ctx, `{ __type(name: "Invoice") { description fields { name description } } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"__type":{"description":"An invoice from billing","fields":[{"description":"Total in cents Since 1.2.","name":"amount"},{"description":null,"name":"id"},{"description":null,"name":"internalRef"}]}}}`, result)
+}
+
+func TestGraphy_RegisterTypeAdapter_Invalid(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	assert.PanicsWithValue(t, "type adapter: quickgraph.adaptedInvoice has no field Totl", func() {
+		g.RegisterTypeAdapter(ctx, adaptedInvoice{}, TypeAdapter{Fields: map[string]FieldAdapter{"Totl": {Exclude: true}}})
+	})
+	assert.PanicsWithValue(t, "type adapter: string is not a struct", func() {
+		g.RegisterTypeAdapter(ctx, "", TypeAdapter{})
+	})
+
+	g.RegisterQuery(ctx, "invoice", func() *adaptedInvoice { return nil })
+	assert.PanicsWithValue(t, "type adapter: quickgraph.adaptedInvoice is already in use", func() {
+		g.RegisterTypeAdapter(ctx, &adaptedInvoice{}, TypeAdapter{Name: "Invoice"})
+	})
+}
@@ -0,0 +1,111 @@
+package quickgraph
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// RegisterTypeField adds a virtual field to an existing type without modifying the type
+// itself. This is useful for types from other packages that can't be given methods or
+// tags. The field behaves just like a method on the type would.
+//
+// The function takes the value that the field is resolved on as its first parameter,
+// optionally preceded by a context.Context. Its remaining parameters are the arguments of
+// the field and are handled the same way as for RegisterQuery, including the optional
+// names:
+//
+//	g.RegisterTypeField(ctx, User{}, "avatarUrl", func(ctx context.Context, u User, size int) string {
+//		return avatarUrl(u.Email, size)
+//	}, "size")
+//
+// The value may be taken as either the type or a pointer to it. Fields are not inherited
+// by types that embed the type. Invalid functions cause a panic.
+func (g *Graphy) RegisterTypeField(ctx context.Context, typ any, name string, f any, names ...string) {
+	g.structureLock.Lock()
+	defer g.structureLock.Unlock()
+
+	baseType := reflect.TypeOf(typ)
+	if baseType.Kind() == reflect.Ptr {
+		baseType = baseType.Elem()
+	}
+	if baseType.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("type field %s: %v is not a struct", name, baseType))
+	}
+
+	def := FunctionDefinition{
+		Name:           name,
+		Function:       typeFieldMethod(name, baseType, reflect.ValueOf(f)),
+		ParameterNames: names,
+	}
+	if err := g.validateGraphFunction(def.Function.(reflect.Value), name, true); err != nil {
+		panic("not valid graph function: " + err.Error())
+	}
+
+	if g.typeFields == nil {
+		g.typeFields = map[reflect.Type][]FunctionDefinition{}
+	}
+	g.typeFields[baseType] = append(g.typeFields[baseType], def)
+
+	// Add the field to the lookups of the type that already exist. Any lookups created
+	// later pick it up from typeFields.
+	g.typeMutex.Lock()
+	var existing []*typeLookup
+	for _, tl := range g.typeLookups {
+		if tl.rootType == baseType {
+			existing = append(existing, tl)
+		}
+	}
+	g.typeMutex.Unlock()
+	for _, tl := range existing {
+		g.addGraphMethod(def, nil, tl)
+	}
+
+	g.invalidateSchema()
+}
+
+// typeFieldMethod adapts the function of a type field so that it looks like a method
+// expression, with the receiver as the first parameter, so that it can be handled the
+// same way as the methods of the type.
+func typeFieldMethod(name string, baseType reflect.Type, fn reflect.Value) reflect.Value {
+	ft := fn.Type()
+	if ft.Kind() != reflect.Func {
+		panic(fmt.Sprintf("type field %s: %v is not a func", name, ft))
+	}
+
+	// Find the receiver: the first parameter that isn't a context.
+	receiverIndex := -1
+	for i := 0; i < ft.NumIn(); i++ {
+		if ft.In(i).ConvertibleTo(contextType) {
+			continue
+		}
+		receiverIndex = i
+		break
+	}
+	if receiverIndex < 0 || (ft.In(receiverIndex) != baseType && ft.In(receiverIndex) != reflect.PtrTo(baseType)) {
+		panic(fmt.Sprintf("type field %s: the first non-context parameter must be a %v or *%v", name, baseType, baseType))
+	}
+	if receiverIndex == 0 {
+		return fn
+	}
+
+	in := []reflect.Type{ft.In(receiverIndex)}
+	for i := 0; i < ft.NumIn(); i++ {
+		if i != receiverIndex {
+			in = append(in, ft.In(i))
+		}
+	}
+	out := make([]reflect.Type, ft.NumOut())
+	for i := range out {
+		out[i] = ft.Out(i)
+	}
+
+	methodType := reflect.FuncOf(in, out, false)
+	return reflect.MakeFunc(methodType, func(args []reflect.Value) []reflect.Value {
+		callArgs := make([]reflect.Value, 0, len(args))
+		callArgs = append(callArgs, args[1:receiverIndex+1]...)
+		callArgs = append(callArgs, args[0])
+		callArgs = append(callArgs, args[receiverIndex+1:]...)
+		return fn.Call(callArgs)
+	})
+}
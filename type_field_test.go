@@ -0,0 +1,78 @@
+package quickgraph
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type typeFieldUser struct {
+	Name  string
+	Email string
+}
+
+type typeFieldTeam struct {
+	Lead    typeFieldUser
+	Members []*typeFieldUser
+}
+
+func TestGraphy_RegisterTypeField(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "team", func() typeFieldTeam {
+		return typeFieldTeam{
+			Lead:    typeFieldUser{Name: "Ann", Email: "ann@example.com"},
+			Members: []*typeFieldUser{{Name: "Bob", Email: "bob@example.com"}},
+		}
+	})
+
+	// The lookups for the type already exist at this point.
+	g.RegisterTypeField(ctx, typeFieldUser{}, "avatarUrl", func(ctx context.Context, u typeFieldUser, size int) string {
+		return fmt.Sprintf("https://avatars.example.com/%s?s=%d", u.Email, size)
+	}, "size")
+	g.RegisterTypeField(ctx, &typeFieldUser{}, "initial", func(u *typeFieldUser) string {
+		return u.Name[:1]
+	})
+
+	result, err := g.ProcessRequest(ctx, `{ team { Lead { Name initial avatarUrl(size: 32) } Members { initial avatarUrl(size: 16) } } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"team":{"Lead":{"Name":"Ann","avatarUrl":"https://avatars.example.com/ann@example.com?s=32","initial":"A"},"Members":[{"avatarUrl":"https://avatars.example.com/bob@example.com?s=16","initial":"B"}]}}}`, result)
+
+	g.EnableIntrospection(ctx)
+	assert.Contains(t, g.SchemaDefinition(ctx), `type typeFieldUser {
+	avatarUrl(size: Int!): String!
+	Email: String!
+	initial: String!
+	Name: String!
+}`)
+}
+
+func TestGraphy_RegisterTypeField_BeforeType(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterTypeField(ctx, typeFieldUser{}, "greeting", func(u typeFieldUser) string {
+		return "Hello, " + u.Name
+	})
+	g.RegisterQuery(ctx, "user", func() *typeFieldUser {
+		return &typeFieldUser{Name: "Ann"}
+	})
+
+	result, err := g.ProcessRequest(ctx, `{ user { greeting } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"user":{"greeting":"Hello, Ann"}}}`, result)
+}
+
+func TestGraphy_RegisterTypeField_Invalid(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	assert.PanicsWithValue(t, "type field x: the first non-context parameter must be a quickgraph.typeFieldUser or *quickgraph.typeFieldUser", func() {
+		g.RegisterTypeField(ctx, typeFieldUser{}, "x", func(ctx context.Context, s string) string { return s })
+	})
+	assert.PanicsWithValue(t, "type field x: quickgraph.typeFieldUser is not a func", func() {
+		g.RegisterTypeField(ctx, typeFieldUser{}, "x", typeFieldUser{})
+	})
+	assert.PanicsWithValue(t, "type field x: string is not a struct", func() {
+		g.RegisterTypeField(ctx, "", "x", func(s string) string { return s })
+	})
+}
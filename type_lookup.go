@@ -3,7 +3,9 @@ package quickgraph
 import (
 	"context"
 	"fmt"
+	"github.com/alecthomas/participle/v2/lexer"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
@@ -23,6 +25,14 @@ type fieldLookup struct {
 
 	isDeprecated     bool
 	deprecatedReason string
+	versions         versionInfo
+	description      *string
+	listSize         int
+
+	// nullable and nonNull override whether the field is nullable in the schema of
+	// output types, which otherwise follows whether its Go type is a pointer.
+	nullable bool
+	nonNull  bool
 }
 
 type typeLookup struct {
@@ -43,6 +53,9 @@ type typeLookup struct {
 	description      *string
 	isDeprecated     bool
 	deprecatedReason string
+
+	outputOnly bool
+	inputOnly  bool
 }
 
 type typeArrayModifier struct {
@@ -131,6 +144,9 @@ func (g *Graphy) processBaseTypeFieldLookup(typ reflect.Type, prevIndex []int, t
 		} else {
 
 			tfl := g.baseFieldLookup(field, index)
+			if adapter, ok := g.typeAdapters[typ]; ok {
+				tfl = adapter.applyToField(field, index, tfl)
+			}
 
 			if tfl.name == "" {
 				continue
@@ -183,56 +199,129 @@ func (g *Graphy) processBaseTypeFieldLookup(typ reflect.Type, prevIndex []int, t
 }
 
 func (g *Graphy) baseFieldLookup(field reflect.StructField, index []int) fieldLookup {
-	// If there's a json tag on the field, use that for the name of the field.
-	// Otherwise, use the name of the field.
-	// If there's a json tag with a "-" value, ignore the field.
+	name, include := g.fieldNameFromTags(field)
+	if !include {
+		return fieldLookup{}
+	}
+
 	tfl := fieldLookup{
-		name:         field.Name,
+		name:         name,
 		resultType:   field.Type,
 		fieldIndexes: index,
 		fieldType:    FieldTypeField,
 	}
 
-	if jsonTag := field.Tag.Get("json"); jsonTag != "" {
-		jsonParts := strings.Split(jsonTag, ",")
-		if jsonParts[0] == "-" {
-			return fieldLookup{}
-		}
-		if jsonParts[0] != "" {
-			tfl.name = jsonParts[0]
-		}
-	}
-
 	if graphyTag := field.Tag.Get("graphy"); graphyTag != "" {
 		graphyParts := strings.Split(graphyTag, ",")
 
-		// First part, if it has no special meaning, is the name of the field.
-		// All the parts are name=value pairs (except the first part, which can be special).
-		// If there are quotes around the value, they are stripped.
+		// All the parts are name=value pairs (except the first part, which can be the
+		// name of the field -- that is handled by fieldNameFromTags).
 		// The special parts are:
-		//  - name: the name of the field
 		//  - deprecated: if exists, the field is deprecated with the value as the reason
+		//  - since: the version of the API that the field was added in
+		//  - removedIn: the version of the API that the field is scheduled to be removed in
+		//  - listSize: the number of elements that a list field is expected to have at most
+		//  - nullable: the field is nullable in the schema even if its type isn't a pointer
+		//  - nonnull: the field is non-null in the schema even if its type is a pointer
 
 		for _, part := range graphyParts {
+			switch part {
+			case nullableTag:
+				tfl.nullable = true
+			case nonNullTag:
+				tfl.nonNull = true
+			}
 			parts := strings.Split(part, "=")
-			if len(parts) == 1 {
-				tfl.name = parts[0]
-			} else {
-				// If the value is quoted, strip the quotes.
+			if len(parts) == 2 {
 				switch parts[0] {
-				case "name":
-					tfl.name = parts[1]
 				case "deprecated":
 					tfl.isDeprecated = true
 					tfl.deprecatedReason = parts[1]
+				case "since":
+					tfl.versions.since = parts[1]
+				case "removedIn":
+					tfl.versions.removedIn = parts[1]
+				case "listSize":
+					size, err := strconv.Atoi(parts[1])
+					if err != nil || size < 0 {
+						panic(fmt.Sprintf("invalid listSize %s on field %s", parts[1], field.Name))
+					}
+					tfl.listSize = size
 				}
 			}
 		}
+		if tfl.nullable && tfl.nonNull {
+			panic(fmt.Sprintf("field %s can't be both nullable and nonnull", field.Name))
+		}
 	}
 
 	return tfl
 }
 
+// fieldNameFromTags determines the name that a struct field has in the graph, and whether it
+// should be part of the graph at all. See graphFieldName for the rules that are used.
+//
+// If StrictTags is set on the Graphy instance, combinations of tags that are ambiguous cause
+// a panic: a graphy tag on an unexported field, or a `json:"-"` tag together with a graphy
+// tag that does not give the field a name.
+func (g *Graphy) fieldNameFromTags(field reflect.StructField) (string, bool) {
+	name, include := graphFieldName(field)
+	if g.StrictTags && !include {
+		graphyTag, hasGraphyTag := field.Tag.Lookup("graphy")
+		if hasGraphyTag && !field.IsExported() {
+			panic(fmt.Sprintf("graphy tag on unexported field %s", field.Name))
+		}
+		if hasGraphyTag && graphyTag != "-" && !strings.HasPrefix(graphyTag, "-,") {
+			panic(fmt.Sprintf("field %s has a json:\"-\" tag and a graphy tag without a name", field.Name))
+		}
+	}
+	return name, include
+}
+
+// graphFieldName determines the name that a struct field has in the graph, and whether it
+// should be part of the graph at all. The rules, in order of precedence, are:
+//
+//  1. Unexported fields are never included since they can't be accessed.
+//  2. A `graphy:"-"` tag excludes the field, regardless of the json tag.
+//  3. A name in the graphy tag, either as the first part or as `name=`, is used. This
+//     includes the field even if it has a `json:"-"` tag, which allows exposing fields
+//     in the graph that are not part of the regular JSON representation.
+//  4. A `json:"-"` tag excludes the field.
+//  5. A name in the json tag is used.
+//  6. Otherwise, the name of the field itself is used.
+func graphFieldName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+
+	graphyName := ""
+	if graphyTag, ok := field.Tag.Lookup("graphy"); ok {
+		for i, part := range strings.Split(graphyTag, ",") {
+			parts := strings.Split(part, "=")
+			if len(parts) == 1 && i == 0 && part != nullableTag && part != nonNullTag {
+				graphyName = parts[0]
+			} else if len(parts) == 2 && parts[0] == "name" {
+				graphyName = parts[1]
+			}
+		}
+	}
+	if graphyName == "-" {
+		return "", false
+	}
+	if graphyName != "" {
+		return graphyName, true
+	}
+
+	jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+	if jsonName == "-" {
+		return "", false
+	}
+	if jsonName != "" {
+		return jsonName, true
+	}
+	return field.Name, true
+}
+
 func (g *Graphy) addGraphMethodsForType(typ reflect.Type, index []int, tl *typeLookup) {
 	functionDefs := map[string]FunctionDefinition{}
 	for i := 0; i < typ.NumMethod(); i++ {
@@ -249,59 +338,63 @@ func (g *Graphy) addGraphMethodsForType(typ reflect.Type, index []int, tl *typeL
 		functionDefs[m.Name] = fd
 	}
 	if typ.Implements(graphTypeExtensionType) {
-		gtev := reflect.New(typ)
-		gtei := gtev.Elem().Interface().(GraphTypeExtension)
-		typeExtension := gtei.GraphTypeExtension()
+		typeExtension := graphTypeInfo(typ)
 		for _, override := range typeExtension.FunctionDefinitions {
 			functionDefs[override.Name] = override
 		}
 	}
 
-	for _, funcDef := range functionDefs {
-		// Gather the inputs and outputs of the function.
-		var method reflect.Type
-		var function reflect.Value
-		if f, ok := funcDef.Function.(reflect.Value); ok {
-			method = f.Type()
-			function = f
-		} else {
-			method = reflect.TypeOf(funcDef.Function)
-			function = reflect.ValueOf(funcDef.Function)
-		}
-
-		var inTypes []reflect.Type
-		var outTypes []reflect.Type
-		for j := 0; j < method.NumIn(); j++ {
-			inTypes = append(inTypes, method.In(j))
+	if len(index) == 0 {
+		// Fields that were added with RegisterTypeField. These aren't inherited by types
+		// that embed this one.
+		baseType := typ
+		if baseType.Kind() == reflect.Ptr {
+			baseType = baseType.Elem()
 		}
-		for j := 0; j < method.NumOut(); j++ {
-			outTypes = append(outTypes, method.Out(j))
+		for _, fieldDef := range g.typeFields[baseType] {
+			functionDefs[fieldDef.Name] = fieldDef
 		}
+	}
 
-		err := g.validateGraphFunction(function, funcDef.Name, true)
-		if err == nil {
-			// Todo: Make this take a reflect.Type instead of an any.
-			gf := g.newGraphFunction(funcDef, true)
-			// TODO: There seems to be a reflection issue where functions from
-			//  an anonymous struct are not properly recognized as being from
-			//  that struct. We need to figure out what's going on so when emitting
-			//  the schema we can properly identify the type and not output the
-			//  function multiple times. Basically, if an anonymous struct member
-			//  has a function, that will presently be output as a function of
-			//  both the struct as well as the type that includes it as anonymous.
-			tfl := fieldLookup{
-				name:          funcDef.Name,
-				resultType:    gf.rawReturnType,
-				fieldIndexes:  index,
-				fieldType:     FieldTypeGraphFunction,
-				graphFunction: &gf,
-			}
-			tl.fields[funcDef.Name] = tfl
-			// If the lowercase version of the field name is not already in the map,
-			// add it.
-			if _, ok := tl.fieldsLowercase[strings.ToLower(funcDef.Name)]; !ok {
-				tl.fieldsLowercase[strings.ToLower(funcDef.Name)] = tfl
-			}
+	for _, funcDef := range functionDefs {
+		g.addGraphMethod(funcDef, index, tl)
+	}
+}
+
+// addGraphMethod adds a function to the type as a field, if it is a valid graph function.
+func (g *Graphy) addGraphMethod(funcDef FunctionDefinition, index []int, tl *typeLookup) {
+	var function reflect.Value
+	if f, ok := funcDef.Function.(reflect.Value); ok {
+		function = f
+	} else {
+		function = reflect.ValueOf(funcDef.Function)
+	}
+
+	err := g.validateGraphFunction(function, funcDef.Name, true)
+	if err == nil {
+		// Todo: Make this take a reflect.Type instead of an any.
+		gf := g.newGraphFunction(funcDef, true)
+		// TODO: There seems to be a reflection issue where functions from
+		//  an anonymous struct are not properly recognized as being from
+		//  that struct. We need to figure out what's going on so when emitting
+		//  the schema we can properly identify the type and not output the
+		//  function multiple times. Basically, if an anonymous struct member
+		//  has a function, that will presently be output as a function of
+		//  both the struct as well as the type that includes it as anonymous.
+		tfl := fieldLookup{
+			name:          funcDef.Name,
+			resultType:    gf.rawReturnType,
+			fieldIndexes:  index,
+			fieldType:     FieldTypeGraphFunction,
+			graphFunction: &gf,
+			versions:      gf.versions,
+			listSize:      gf.listSize,
+		}
+		tl.fields[funcDef.Name] = tfl
+		// If the lowercase version of the field name is not already in the map,
+		// add it.
+		if _, ok := tl.fieldsLowercase[strings.ToLower(funcDef.Name)]; !ok {
+			tl.fieldsLowercase[strings.ToLower(funcDef.Name)] = tfl
 		}
 	}
 }
@@ -323,13 +416,38 @@ func (t *fieldLookup) fetchField(v reflect.Value) (any, error) {
 	for _, i := range t.fieldIndexes {
 		v = v.Field(i)
 	}
+	if t.nonNull && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return nil, fmt.Errorf("field %s is non-null but is nil", t.name)
+	}
 	return v.Interface(), nil
 }
 
+// The options of the graphy tag that override the nullability of a field.
+const (
+	nullableTag = "nullable"
+	nonNullTag  = "nonnull"
+)
+
+// schemaNullable reports whether the field is nullable in the schema of an output type,
+// given whether its type is.
+func (t *fieldLookup) schemaNullable(typeNullable bool) bool {
+	if t.nullable {
+		return true
+	}
+	if t.nonNull {
+		return false
+	}
+	return typeNullable
+}
+
 func (t *fieldLookup) fetchGraphFunction(ctx context.Context, req *request, v reflect.Value, params *parameterList) (any, error) {
 	obj, err := t.graphFunction.Call(ctx, req, params, v)
 	if err != nil {
-		return nil, AugmentGraphError(err, "error calling graph function", params.Pos)
+		var pos lexer.Position
+		if params != nil {
+			pos = params.Pos
+		}
+		return nil, AugmentGraphError(err, "error calling graph function", pos)
 	}
 	return obj.Interface(), nil
 }
@@ -337,3 +455,25 @@ func (t *fieldLookup) fetchGraphFunction(ctx context.Context, req *request, v re
 func (t *typeLookup) String() string {
 	return fmt.Sprintf("typeLookup: %v", t.typ)
 }
+
+// iteratorElemType checks whether the type is an iterator function of the form
+// func(yield func(T) bool), which is the form used by range-over-func in Go 1.23 and
+// later (iter.Seq). If it is, the type of the elements, T, is returned.
+func iteratorElemType(typ reflect.Type) (reflect.Type, bool) {
+	if !isIteratorType(typ) {
+		return nil, false
+	}
+	return typ.In(0).In(0), true
+}
+
+// isIteratorType returns whether the type is an iterator function. See iteratorElemType.
+func isIteratorType(typ reflect.Type) bool {
+	if typ == nil || typ.Kind() != reflect.Func || typ.NumIn() != 1 || typ.NumOut() != 0 {
+		return false
+	}
+	yield := typ.In(0)
+	return yield.Kind() == reflect.Func &&
+		yield.NumIn() == 1 &&
+		yield.NumOut() == 1 &&
+		yield.Out(0).Kind() == reflect.Bool
+}
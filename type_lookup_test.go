@@ -1,6 +1,7 @@
 package quickgraph
 
 import (
+	"context"
 	"reflect"
 	"testing"
 
@@ -59,3 +60,159 @@ func TestBaseFieldLookup_GraphyTagDeprecated(t *testing.T) {
 	assert.True(t, result.isDeprecated)
 	assert.Equal(t, "Deprecated for testing", result.deprecatedReason)
 }
+
+func TestBaseFieldLookup_GraphyNameOverridesJsonIgnore(t *testing.T) {
+	field := reflect.StructField{
+		Name: "TestField",
+		Tag:  reflect.StructTag(`json:"-" graphy:"name=exposed"`),
+		Type: reflect.TypeOf(""),
+	}
+	g := Graphy{}
+	result := g.baseFieldLookup(field, []int{0})
+
+	assert.Equal(t, "exposed", result.name)
+}
+
+func TestBaseFieldLookup_GraphyIgnore(t *testing.T) {
+	field := reflect.StructField{
+		Name: "TestField",
+		Tag:  reflect.StructTag(`json:"test_json" graphy:"-"`),
+		Type: reflect.TypeOf(""),
+	}
+	g := Graphy{}
+	result := g.baseFieldLookup(field, []int{0})
+
+	assert.Equal(t, "", result.name)
+}
+
+func TestBaseFieldLookup_Unexported(t *testing.T) {
+	field := reflect.StructField{
+		Name:    "testField",
+		PkgPath: "github.com/gburgyan/go-quickgraph",
+		Type:    reflect.TypeOf(""),
+	}
+	g := Graphy{}
+	result := g.baseFieldLookup(field, []int{0})
+
+	assert.Equal(t, "", result.name)
+}
+
+func TestBaseFieldLookup_StrictTags(t *testing.T) {
+	g := Graphy{StrictTags: true}
+
+	assert.PanicsWithValue(t, `field TestField has a json:"-" tag and a graphy tag without a name`, func() {
+		g.baseFieldLookup(reflect.StructField{
+			Name: "TestField",
+			Tag:  reflect.StructTag(`json:"-" graphy:"deprecated=old"`),
+			Type: reflect.TypeOf(""),
+		}, []int{0})
+	})
+
+	assert.PanicsWithValue(t, "graphy tag on unexported field testField", func() {
+		g.baseFieldLookup(reflect.StructField{
+			Name:    "testField",
+			PkgPath: "github.com/gburgyan/go-quickgraph",
+			Tag:     reflect.StructTag(`graphy:"name=test"`),
+			Type:    reflect.TypeOf(""),
+		}, []int{0})
+	})
+
+	// Unambiguous tags are fine.
+	assert.NotPanics(t, func() {
+		g.baseFieldLookup(reflect.StructField{
+			Name: "TestField",
+			Tag:  reflect.StructTag(`json:"-" graphy:"-"`),
+			Type: reflect.TypeOf(""),
+		}, []int{0})
+	})
+}
+
+type tagPrecedenceResult struct {
+	Public   string
+	Internal string `json:"-" graphy:"internal"`
+	Hidden   string `json:"hidden" graphy:"-"`
+	secret   string
+}
+
+func TestTagPrecedence_Output(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "get", func() tagPrecedenceResult {
+		return tagPrecedenceResult{Public: "a", Internal: "b", Hidden: "c", secret: "d"}
+	})
+	g.EnableIntrospection(ctx)
+
+	result, err := g.ProcessRequest(ctx, `{ get { Public internal } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"get":{"Public":"a","internal":"b"}}}`, result)
+
+	_, err = g.ProcessRequest(ctx, `{ get { Hidden } }`, "")
+	assert.Error(t, err)
+	_, err = g.ProcessRequest(ctx, `{ get { secret } }`, "")
+	assert.Error(t, err)
+
+	assert.Contains(t, g.SchemaDefinition(ctx), `type tagPrecedenceResult {
+	internal: String!
+	Public: String!
+}`)
+}
+
+type nullabilityResult struct {
+	Id       string
+	Nickname string  `graphy:"nullable"`
+	Email    *string `graphy:"email,nonnull"`
+	Phone    *string
+}
+
+type nullabilityInput struct {
+	Nickname string  `graphy:"nullable"`
+	Email    *string `graphy:"nonnull"`
+}
+
+func TestBaseFieldLookup_Nullability(t *testing.T) {
+	ctx := context.Background()
+	email := "a@example.com"
+	g := Graphy{}
+	g.RegisterQuery(ctx, "get", func() nullabilityResult {
+		return nullabilityResult{Id: "1", Email: &email}
+	})
+	g.RegisterQuery(ctx, "missing", func() nullabilityResult {
+		return nullabilityResult{Id: "2"}
+	})
+	g.RegisterQuery(ctx, "save", func(in nullabilityInput) string { return in.Nickname }, "in")
+	g.EnableIntrospection(ctx)
+
+	// The overrides only apply to outputs; inputs follow the Go types.
+	schema := g.SchemaDefinition(ctx)
+	assert.Contains(t, schema, `type nullabilityResult {
+	email: String!
+	Id: String!
+	Nickname: String
+	Phone: String
+}`)
+	assert.Contains(t, schema, `input nullabilityInput {
+	Email: String
+	Nickname: String!
+}`)
+
+	result, err := g.ProcessRequest(ctx, `{ __type(name: "nullabilityResult") { fields { name type { kind ofType { kind } } } } }`, "")
+	assert.NoError(t, err)
+	assert.Contains(t, result, `{"name":"email","type":{"kind":"NON_NULL","ofType":{"kind":"SCALAR"}}}`)
+	assert.Contains(t, result, `{"name":"Nickname","type":{"kind":"SCALAR","ofType":null}}`)
+
+	result, err = g.ProcessRequest(ctx, `{ get { Nickname email } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"get":{"Nickname":"","email":"a@example.com"}}}`, result)
+
+	// A nonnull field that is nil is an error rather than a null.
+	_, err = g.ProcessRequest(ctx, `{ missing { email } }`, "")
+	assert.ErrorContains(t, err, "field email is non-null but is nil")
+
+	assert.PanicsWithValue(t, "field Both can't be both nullable and nonnull", func() {
+		g.baseFieldLookup(reflect.StructField{
+			Name: "Both",
+			Tag:  reflect.StructTag(`graphy:"nullable,nonnull"`),
+			Type: reflect.TypeOf(""),
+		}, []int{0})
+	})
+}
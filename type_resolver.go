@@ -0,0 +1,139 @@
+package quickgraph
+
+import (
+	"context"
+	"fmt"
+	"github.com/alecthomas/participle/v2/lexer"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TypeResolver is called the first time that a request refers to a type by name, before
+// the request is processed. It is expected to register the types, and any functions,
+// that the type needs with the Graphy that is passed in. This allows modules that are
+// expensive to load to be deferred until a request needs them.
+//
+// If the resolver returns an error, the request fails with that error and the resolver
+// is called again for the next request that refers to the type.
+type TypeResolver func(ctx context.Context, g *Graphy) error
+
+// lazyType is a type that is registered with a TypeResolver. The mutex ensures that the
+// resolver is only run once, even if multiple requests refer to the type at the same
+// time.
+type lazyType struct {
+	mu       sync.Mutex
+	resolver TypeResolver
+	resolved bool
+}
+
+// pendingTypesError is returned while creating a request stub if the request refers to
+// types that have resolvers that haven't run yet.
+type pendingTypesError struct {
+	typeNames []string
+}
+
+func (e pendingTypesError) Error() string {
+	return fmt.Sprintf("types need to be resolved: %s", strings.Join(e.typeNames, ", "))
+}
+
+// RegisterTypeResolver registers a resolver that is called the first time that a request
+// refers to the named type. Requests refer to types in the type conditions of fragments,
+// like `... on Droid`, and in the `__type` introspection query.
+//
+// Until the resolver has run, the types that it registers are not part of the schema.
+func (g *Graphy) RegisterTypeResolver(typeName string, resolver TypeResolver) {
+	g.typeResolverLock.Lock()
+	defer g.typeResolverLock.Unlock()
+
+	if g.typeResolvers == nil {
+		g.typeResolvers = map[string]*lazyType{}
+	}
+	g.typeResolvers[typeName] = &lazyType{resolver: resolver}
+}
+
+// pendingTypeResolvers returns the names of the types that the request refers to that
+// have resolvers that haven't run yet.
+func (g *Graphy) pendingTypeResolvers(parsedCall *wrapper) []string {
+	g.typeResolverLock.Lock()
+	defer g.typeResolverLock.Unlock()
+
+	if len(g.typeResolvers) == 0 {
+		return nil
+	}
+
+	var pending []string
+	for name := range referencedTypeNames(parsedCall) {
+		if _, ok := g.typeResolvers[name]; ok {
+			pending = append(pending, name)
+		}
+	}
+	sort.Strings(pending)
+	return pending
+}
+
+// runTypeResolvers runs the resolvers for the named types. This must not be called while
+// the structure lock is held since the resolvers register things, and each of the
+// Register functions takes the lock itself while requests may be processed.
+func (g *Graphy) runTypeResolvers(ctx context.Context, typeNames []string) error {
+	for _, name := range typeNames {
+		g.typeResolverLock.Lock()
+		lt, ok := g.typeResolvers[name]
+		g.typeResolverLock.Unlock()
+		if !ok {
+			// Another request resolved this in the meantime.
+			continue
+		}
+
+		lt.mu.Lock()
+		var err error
+		if !lt.resolved {
+			err = lt.resolver(ctx, g)
+			lt.resolved = err == nil
+		}
+		lt.mu.Unlock()
+		if err != nil {
+			return AugmentGraphError(err, fmt.Sprintf("error resolving type %s", name), lexer.Position{})
+		}
+
+		g.typeResolverLock.Lock()
+		delete(g.typeResolvers, name)
+		g.typeResolverLock.Unlock()
+	}
+	return nil
+}
+
+// referencedTypeNames finds the names of the types that a request refers to.
+func referencedTypeNames(parsedCall *wrapper) map[string]bool {
+	names := map[string]bool{}
+	for _, f := range parsedCall.Fragments {
+		names[f.Definition.TypeName] = true
+		addFilterTypeNames(f.Definition.Filter, names)
+	}
+	for _, c := range parsedCall.Commands {
+		addFilterTypeNames(c.ResultFilter, names)
+		if c.Name == "__type" && c.Parameters != nil {
+			for _, p := range c.Parameters.Values {
+				if p.Name == "name" && p.Value.String != nil {
+					names[strings.Trim(*p.Value.String, `"`)] = true
+				}
+			}
+		}
+	}
+	return names
+}
+
+func addFilterTypeNames(filter *resultFilter, names map[string]bool) {
+	if filter == nil {
+		return
+	}
+	for _, field := range filter.Fields {
+		addFilterTypeNames(field.SubParts, names)
+	}
+	for _, fc := range filter.Fragments {
+		if fc.Inline != nil {
+			names[fc.Inline.TypeName] = true
+			addFilterTypeNames(fc.Inline.Filter, names)
+		}
+	}
+}
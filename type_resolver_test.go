@@ -0,0 +1,150 @@
+package quickgraph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestTypeResolver_Introspection(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{
+		RequestCache: simpleCache{values: map[string]*simpleCacheEntry{}},
+	}
+	g.RegisterQuery(ctx, "character", func() Character {
+		return Character{Name: "Luke"}
+	})
+	g.EnableIntrospection(ctx)
+
+	calls := 0
+	g.RegisterTypeResolver("Droid", func(ctx context.Context, g *Graphy) error {
+		calls++
+		g.RegisterTypes(ctx, Droid{})
+		return nil
+	})
+
+	assert.NotContains(t, g.SchemaDefinition(ctx), "type Droid")
+
+	// A request that doesn't refer to the type leaves it alone.
+	_, err := g.ProcessRequest(ctx, `{ character { name } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, calls)
+
+	result, err := g.ProcessRequest(ctx, `{ __type(name: "Droid") { name kind } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"__type":{"kind":"OBJECT","name":"Droid"}}}`, result)
+	assert.Equal(t, 1, calls)
+	assert.Contains(t, g.SchemaDefinition(ctx), "type Droid")
+
+	_, err = g.ProcessRequest(ctx, `{ character { ... on Droid { primaryFunction } } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestTypeResolver_Fragments(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "character", func() Character {
+		return Character{Name: "Luke"}
+	})
+
+	var resolved []string
+	var mu sync.Mutex
+	for _, name := range []string{"Droid", "Human", "Starship"} {
+		name := name
+		g.RegisterTypeResolver(name, func(ctx context.Context, g *Graphy) error {
+			mu.Lock()
+			defer mu.Unlock()
+			resolved = append(resolved, name)
+			return nil
+		})
+	}
+
+	query := `{ character { name ... on Droid { primaryFunction } ...H } }
+fragment H on Human { HeightMeters }`
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := g.ProcessRequest(ctx, query, "")
+			assert.NoError(t, err)
+			assert.Equal(t, `{"data":{"character":{"name":"Luke"}}}`, result)
+		}()
+	}
+	wg.Wait()
+
+	assert.ElementsMatch(t, []string{"Droid", "Human"}, resolved)
+}
+
+func TestTypeResolver_Error(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "character", func() Character {
+		return Character{Name: "Luke"}
+	})
+
+	fail := true
+	calls := 0
+	g.RegisterTypeResolver("Droid", func(ctx context.Context, g *Graphy) error {
+		calls++
+		if fail {
+			return errors.New("module unavailable")
+		}
+		return nil
+	})
+
+	query := `{ character { ... on Droid { primaryFunction } } }`
+	result, err := g.ProcessRequest(ctx, query, "")
+	assert.Error(t, err)
+	assert.Equal(t, `{"errors":[{"message":"error resolving type Droid: module unavailable"}]}`, result)
+
+	// The resolver is tried again on the next request.
+	fail = false
+	_, err = g.ProcessRequest(ctx, query, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestTypeResolver_RegistersFunctionsConcurrently(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "character", func() Character {
+		return Character{Name: "Luke"}
+	})
+	g.RegisterTypeResolver("Droid", func(ctx context.Context, g *Graphy) error {
+		g.RegisterTypes(ctx, Droid{})
+		for i := 0; i < 50; i++ {
+			g.RegisterQuery(ctx, fmt.Sprintf("droid%d", i), func() Droid { return Droid{} })
+			g.RegisterMutation(ctx, fmt.Sprintf("repair%d", i), func() Droid { return Droid{} })
+		}
+		return nil
+	})
+
+	// The resolver registers functions while other requests are processed, which the race
+	// detector checks.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			query := `{ character { name } }`
+			if i == 5 {
+				query = `{ character { ... on Droid { primaryFunction } } }`
+			}
+			for j := 0; j < 20; j++ {
+				_, err := g.ProcessRequest(ctx, query, "")
+				assert.NoError(t, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	result, err := g.ProcessRequest(ctx, `{ droid49 { name } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"droid49":{"name":""}}}`, result)
+}
@@ -0,0 +1,22 @@
+package quickgraph
+
+import "context"
+
+// WithTypenames returns a context that adds the __typename of every object to the result
+// of a request processed with it if include is set, or only the ones that are selected if
+// it isn't. This overrides Graphy.IncludeTypenames for the request. The HTTP handler does
+// this for requests with "includeTypenames" in their extensions:
+//
+//	{"query": "{ hero { name } }", "extensions": {"includeTypenames": true}}
+func WithTypenames(ctx context.Context, include bool) context.Context {
+	return context.WithValue(ctx, typenamesContextKey, include)
+}
+
+// includeTypenames reports whether the results of the request that the context belongs to
+// include the __typename of every object.
+func (g *Graphy) includeTypenames(ctx context.Context) bool {
+	if include, ok := ctx.Value(typenamesContextKey).(bool); ok {
+		return include
+	}
+	return g.IncludeTypenames
+}
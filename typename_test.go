@@ -0,0 +1,77 @@
+package quickgraph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type typenameAuthor struct {
+	Name string
+}
+
+type typenameBook struct {
+	Title   string
+	Authors []typenameAuthor
+}
+
+func TestGraphy_IncludeTypenames(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "book", func() typenameBook {
+		return typenameBook{Title: "Dune", Authors: []typenameAuthor{{Name: "Frank Herbert"}}}
+	})
+
+	query := `{ book { Title Authors { Name } } }`
+	result, err := g.ProcessRequest(ctx, query, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"book":{"Authors":[{"Name":"Frank Herbert"}],"Title":"Dune"}}}`, result)
+
+	result, err = g.ProcessRequest(WithTypenames(ctx, true), query, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"book":{"Authors":[{"Name":"Frank Herbert","__typename":"typenameAuthor"}],"Title":"Dune","__typename":"typenameBook"}}}`, result)
+
+	// A selected __typename isn't added again.
+	g.IncludeTypenames = true
+	g.OrderedResults = true
+	result, err = g.ProcessRequest(ctx, `{ book { __typename Title } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"book":{"__typename":"typenameBook","Title":"Dune"}}}`, result)
+
+	result, err = g.ProcessRequest(WithTypenames(ctx, false), `{ book { Title } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"book":{"Title":"Dune"}}}`, result)
+}
+
+type typenameEdition struct {
+	Year int
+}
+
+func (typenameEdition) GraphTypeExtension() GraphTypeInfo {
+	return GraphTypeInfo{Name: "Edition"}
+}
+
+func TestGraphy_IncludeTypenames_RenamedType(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{IncludeTypenames: true}
+	g.RegisterQuery(ctx, "edition", func() typenameEdition { return typenameEdition{Year: 1965} })
+
+	// The name that is added is the one in the schema, not the Go name.
+	result, err := g.ProcessRequest(ctx, `{ edition { Year } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"edition":{"Year":1965,"__typename":"Edition"}}}`, result)
+}
+
+func TestGraphHttpHandler_IncludeTypenames(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "book", func() typenameBook { return typenameBook{Title: "Dune"} })
+	h := NewGraphHttpHandler(&g)
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"query": "{ book { Title } }", "extensions": {"includeTypenames": true}}`))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, `{"data":{"book":{"Title":"Dune","__typename":"typenameBook"}}}`, rec.Body.String())
+}
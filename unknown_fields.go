@@ -0,0 +1,48 @@
+package quickgraph
+
+import "fmt"
+
+// UnknownFieldHandling is what happens to requests that select fields on objects that the
+// objects don't have. Clients that are deployed ahead of the server may select fields that
+// only a newer version of it has; resolving those fields as null keeps the clients working
+// while the server catches up.
+type UnknownFieldHandling int
+
+const (
+	// UnknownFieldsRejected fails the validation of the request, as the GraphQL spec
+	// requires. This is the default.
+	UnknownFieldsRejected UnknownFieldHandling = iota
+
+	// UnknownFieldsNull resolves the fields as null and adds a warning for each of them to
+	// the "warnings" in the extensions of the response. The selections of the fields
+	// aren't validated. Unknown queries and mutations are still rejected.
+	UnknownFieldsNull
+)
+
+// unknownFields collects the fields that a request selects but that aren't known, when
+// they are resolved as null rather than rejected.
+type unknownFields struct {
+	fields   map[*resultField]bool
+	warnings []GraphError
+}
+
+// add records a field that isn't known on the type. A field that is in a fragment that is
+// used more than once is only recorded once.
+func (u *unknownFields) add(typ *typeLookup, field *resultField) {
+	if u.fields[field] {
+		return
+	}
+	u.fields[field] = true
+	u.warnings = append(u.warnings, GraphError{
+		Message:   fmt.Sprintf("unknown field %s on %s is resolved as null", field.Name, typ.name),
+		Locations: []ErrorLocation{lexerPositionError(field.Pos)},
+	})
+}
+
+// unknownFieldHandling returns how the unknown fields of a request of the mode are handled.
+func (g *Graphy) unknownFieldHandling(mode RequestType) UnknownFieldHandling {
+	if mode == RequestMutation {
+		return g.UnknownMutationFields
+	}
+	return g.UnknownQueryFields
+}
@@ -0,0 +1,50 @@
+package quickgraph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type unknownFieldsPlanet struct {
+	Name string
+	Moon *unknownFieldsPlanet
+}
+
+func TestGraphy_UnknownFieldsNull(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{}
+	g.RegisterQuery(ctx, "planet", func() unknownFieldsPlanet {
+		return unknownFieldsPlanet{Name: "Earth", Moon: &unknownFieldsPlanet{Name: "Luna"}}
+	})
+	g.RegisterMutation(ctx, "rename", func(name string) unknownFieldsPlanet {
+		return unknownFieldsPlanet{Name: name}
+	}, "name")
+
+	query := `{
+	planet {
+		Name
+		Mass
+		Moon { Name Rings { Count } }
+	}
+}`
+	_, err := g.ProcessRequest(ctx, query, "")
+	assert.EqualError(t, err, "unknown field Mass (path: planet) [4:3]")
+
+	g.UnknownQueryFields = UnknownFieldsNull
+	result, err := g.ProcessRequest(ctx, query, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"planet":{"Mass":null,"Moon":{"Name":"Luna","Rings":null},"Name":"Earth"}},"extensions":{"warnings":[{"message":"unknown field Mass on unknownFieldsPlanet is resolved as null","locations":[{"line":4,"column":3}]},{"message":"unknown field Rings on unknownFieldsPlanet is resolved as null","locations":[{"line":5,"column":15}]}]}}`, result)
+
+	// Mutations are handled separately.
+	_, err = g.ProcessRequest(ctx, `mutation { rename(name: "Terra") { Name Mass } }`, "")
+	assert.Error(t, err)
+	g.UnknownMutationFields = UnknownFieldsNull
+	result, err = g.ProcessRequest(ctx, `mutation { rename(name: "Terra") { Name Mass } }`, "")
+	assert.NoError(t, err)
+	assert.Contains(t, result, `"rename":{"Mass":null,"Name":"Terra"}`)
+
+	// Unknown queries are still errors.
+	_, err = g.ProcessRequest(ctx, `{ star { Name } }`, "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,173 @@
+package quickgraph
+
+import (
+	"errors"
+)
+
+// RequestValidator is a custom check that runs on each request after the built-in
+// validation, for rules that are specific to an application, like requiring every query
+// to have an operation name. The validators run when a request is parsed, so if a
+// RequestCache is in use their results are cached along with the rest of the request.
+type RequestValidator interface {
+	// ValidateRequest checks the request against the schema. It returns the problems
+	// that it finds, if any; each of them is reported to the client. RequestField.Error
+	// creates errors that point at a field of the request.
+	ValidateRequest(doc *RequestDocument, schema *SchemaModel) []error
+}
+
+// RequestValidatorFunc adapts a function to a RequestValidator.
+type RequestValidatorFunc func(doc *RequestDocument, schema *SchemaModel) []error
+
+func (f RequestValidatorFunc) ValidateRequest(doc *RequestDocument, schema *SchemaModel) []error {
+	return f(doc, schema)
+}
+
+// RequestDocument is a read-only view of a parsed request for RequestValidators.
+type RequestDocument struct {
+	// Operation is "query" or "mutation".
+	Operation string `json:"operation"`
+
+	// Name is the name of the operation, or "" if it has none.
+	Name string `json:"name,omitempty"`
+
+	// Variables are the names of the variables that the operation declares, without the
+	// leading "$".
+	Variables []string `json:"variables,omitempty"`
+
+	// Fields are the queries or mutations that are called.
+	Fields []RequestField `json:"fields"`
+}
+
+// RequestField is a field that is selected in a request. The fields of fragments are
+// included where the fragments are used.
+type RequestField struct {
+	Name string `json:"name"`
+
+	// Alias is the alias of the field, or "" if it has none.
+	Alias string `json:"alias,omitempty"`
+
+	// Arguments are the names of the arguments that are given to the field.
+	Arguments []string `json:"arguments,omitempty"`
+
+	// OnType is the type condition of the fragment that the field was selected in, or ""
+	// if it was selected directly.
+	OnType string `json:"onType,omitempty"`
+
+	// Fields are the fields selected below this one.
+	Fields []RequestField `json:"fields,omitempty"`
+
+	Line   int `json:"line"`
+	Column int `json:"column"`
+
+	path []string
+}
+
+// Error returns an error with the message that refers to the location and path of the
+// field.
+func (f RequestField) Error(message string) error {
+	return GraphError{
+		Message:   message,
+		Locations: []ErrorLocation{{Line: f.Line, Column: f.Column}},
+		Path:      f.path,
+	}
+}
+
+// runRequestValidators runs the RequestValidators on a parsed request. All the problems
+// that they find are returned together.
+func (g *Graphy) runRequestValidators(parsedCall *wrapper, fragments map[string]fragment) error {
+	if len(g.RequestValidators) == 0 {
+		return nil
+	}
+
+	doc := newRequestDocument(parsedCall, fragments)
+	schema := g.getSchemaTypes().model
+	var errs []error
+	for _, validator := range g.RequestValidators {
+		errs = append(errs, validator.ValidateRequest(doc, schema)...)
+	}
+	return errors.Join(errs...)
+}
+
+func newRequestDocument(parsedCall *wrapper, fragments map[string]fragment) *RequestDocument {
+	doc := &RequestDocument{Operation: parsedCall.Mode}
+	if doc.Operation == "" {
+		doc.Operation = "query"
+	}
+	if parsedCall.OperationDef != nil {
+		doc.Name = parsedCall.OperationDef.Name
+		for _, v := range parsedCall.OperationDef.Variables {
+			doc.Variables = append(doc.Variables, v.Name[1:])
+		}
+	}
+
+	for _, command := range parsedCall.Commands {
+		field := RequestField{
+			Name:      command.Name,
+			Arguments: parameterNames(command.Parameters),
+			Line:      command.Pos.Line,
+			Column:    command.Pos.Column,
+		}
+		if command.Alias != nil {
+			field.Alias = *command.Alias
+			field.path = []string{*command.Alias}
+		} else {
+			field.path = []string{command.Name}
+		}
+		field.Fields = requestFields(command.ResultFilter, "", field.path, fragments, map[string]bool{})
+		doc.Fields = append(doc.Fields, field)
+	}
+	return doc
+}
+
+// requestFields converts the fields of a selection, including the ones from fragments.
+// The visiting map protects against fragments that refer to themselves.
+func requestFields(filter *resultFilter, onType string, path []string, fragments map[string]fragment, visiting map[string]bool) []RequestField {
+	if filter == nil {
+		return nil
+	}
+
+	var result []RequestField
+	for _, f := range filter.Fields {
+		fieldPath := append(append([]string{}, path...), f.resultKey())
+		field := RequestField{
+			Name:      f.Name,
+			Arguments: parameterNames(f.Params),
+			OnType:    onType,
+			Fields:    requestFields(f.SubParts, "", fieldPath, fragments, visiting),
+			Line:      f.Pos.Line,
+			Column:    f.Pos.Column,
+			path:      fieldPath,
+		}
+		if f.Alias != nil {
+			field.Alias = *f.Alias
+		}
+		result = append(result, field)
+	}
+
+	for _, fragmentCall := range filter.Fragments {
+		if fragmentCall.Inline != nil {
+			result = append(result, requestFields(fragmentCall.Inline.Filter, fragmentCall.Inline.TypeName, path, fragments, visiting)...)
+		} else if fragmentCall.FragmentRef != nil {
+			name := *fragmentCall.FragmentRef
+			frag, ok := fragments[name]
+			if !ok || visiting[name] {
+				continue
+			}
+			visiting[name] = true
+			result = append(result, requestFields(frag.Definition.Filter, frag.Definition.TypeName, path, fragments, visiting)...)
+			delete(visiting, name)
+		}
+	}
+	return result
+}
+
+func parameterNames(params *parameterList) []string {
+	if params == nil {
+		return nil
+	}
+	var names []string
+	for _, p := range params.Values {
+		names = append(names, p.Name)
+	}
+	return names
+}
@@ -0,0 +1,130 @@
+package quickgraph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type validatorTestWidget struct {
+	Id   string
+	Name string
+}
+
+// requireOperationName rejects requests that don't name their operation.
+var requireOperationName = RequestValidatorFunc(func(doc *RequestDocument, schema *SchemaModel) []error {
+	if doc.Name == "" {
+		return []error{GraphError{Message: "operations must be named"}}
+	}
+	return nil
+})
+
+// requireMutationId checks that each mutation selects the Id of its result.
+var requireMutationId = RequestValidatorFunc(func(doc *RequestDocument, schema *SchemaModel) []error {
+	if doc.Operation != "mutation" {
+		return nil
+	}
+	var errs []error
+	for _, field := range doc.Fields {
+		if _, ok := schema.Operation(field.Name); !ok {
+			continue
+		}
+		selected := false
+		for _, sub := range field.Fields {
+			if sub.Name == "Id" {
+				selected = true
+			}
+		}
+		if !selected {
+			errs = append(errs, field.Error("mutations must select Id"))
+		}
+	}
+	return errs
+})
+
+func TestRequestValidators(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{RequestValidators: []RequestValidator{requireOperationName, requireMutationId}}
+	g.RegisterQuery(ctx, "widget", func() validatorTestWidget {
+		return validatorTestWidget{Id: "1", Name: "gear"}
+	})
+	g.RegisterMutation(ctx, "renameWidget", func(name string) validatorTestWidget {
+		return validatorTestWidget{Id: "1", Name: name}
+	}, "name")
+
+	result, err := g.ProcessRequest(ctx, `query GetWidget { widget { Name } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"widget":{"Name":"gear"}}}`, result)
+
+	result, err = g.ProcessRequest(ctx, `{ widget { Name } }`, "")
+	assert.Error(t, err)
+	assert.Equal(t, `{"errors":[{"message":"operations must be named"}]}`, result)
+
+	result, err = g.ProcessRequest(ctx, `mutation Rename { renameWidget(name: "cog") { Id Name } }`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"renameWidget":{"Id":"1","Name":"cog"}}}`, result)
+
+	// All the problems are reported.
+	result, err = g.ProcessRequest(ctx, `mutation { renameWidget(name: "cog") { Name } }`, "")
+	assert.Error(t, err)
+	assert.Equal(t, `{"errors":[{"message":"operations must be named"},{"message":"mutations must select Id","locations":[{"line":1,"column":12}],"path":["renameWidget"]}]}`, result)
+}
+
+func TestRequestValidators_Document(t *testing.T) {
+	ctx := context.Background()
+	var doc *RequestDocument
+	g := Graphy{RequestValidators: []RequestValidator{RequestValidatorFunc(func(d *RequestDocument, schema *SchemaModel) []error {
+		doc = d
+		return nil
+	})}}
+	g.RegisterQuery(ctx, "widget", func() validatorTestWidget {
+		return validatorTestWidget{Id: "1", Name: "gear"}
+	})
+	g.RegisterMutation(ctx, "renameWidget", func(name string) validatorTestWidget {
+		return validatorTestWidget{Id: "1", Name: name}
+	}, "name")
+
+	query := `query Get($x: String) {
+  w: widget { Id ...Named }
+}
+fragment Named on validatorTestWidget { Name }`
+	_, err := g.ProcessRequest(ctx, query, `{"x":"a"}`)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "query", doc.Operation)
+	assert.Equal(t, "Get", doc.Name)
+	assert.Equal(t, []string{"x"}, doc.Variables)
+	assert.Len(t, doc.Fields, 1)
+	w := doc.Fields[0]
+	assert.Equal(t, "widget", w.Name)
+	assert.Equal(t, "w", w.Alias)
+	assert.Len(t, w.Fields, 2)
+	assert.Equal(t, "Id", w.Fields[0].Name)
+	assert.Equal(t, "", w.Fields[0].OnType)
+	assert.Equal(t, "Name", w.Fields[1].Name)
+	assert.Equal(t, "validatorTestWidget", w.Fields[1].OnType)
+	assert.Equal(t, []string{"w", "Name"}, w.Fields[1].path)
+	assert.Equal(t, 2, w.Fields[0].Line)
+}
+
+func TestRequestValidators_Cached(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+	g := Graphy{RequestValidators: []RequestValidator{RequestValidatorFunc(func(doc *RequestDocument, schema *SchemaModel) []error {
+		calls++
+		return nil
+	})}}
+	g.RegisterQuery(ctx, "widget", func() validatorTestWidget {
+		return validatorTestWidget{Id: "1", Name: "gear"}
+	})
+	g.RegisterMutation(ctx, "renameWidget", func(name string) validatorTestWidget {
+		return validatorTestWidget{Id: "1", Name: name}
+	}, "name")
+	g.RequestCache = simpleCache{values: map[string]*simpleCacheEntry{}}
+
+	for i := 0; i < 3; i++ {
+		_, err := g.ProcessRequest(ctx, `query Get { widget { Name } }`, "")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, calls)
+}
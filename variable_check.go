@@ -0,0 +1,137 @@
+package quickgraph
+
+import (
+	"errors"
+	"fmt"
+	"github.com/alecthomas/participle/v2/lexer"
+	"log"
+)
+
+// VariableCheck is how strictly the variables of requests are checked against the
+// variables that they declare and use.
+type VariableCheck int
+
+const (
+	// VariablesIgnore ignores variables that are declared but not used, and variables
+	// that are provided but not known to the request. This is the default.
+	VariablesIgnore VariableCheck = iota
+
+	// VariablesWarn logs the variables that VariablesReject would reject.
+	VariablesWarn
+
+	// VariablesReject rejects requests that declare variables that they don't use, as
+	// the GraphQL spec requires, and requests with variables that aren't declared or, in
+	// requests without declarations, not used.
+	VariablesReject
+)
+
+// usedVariableNames returns the names of the variables, without the leading "$", that are
+// used anywhere in a request, including in fragments and directives.
+func usedVariableNames(parsedCall *wrapper) map[string]bool {
+	names := map[string]bool{}
+	for _, c := range parsedCall.Commands {
+		addParameterVariableNames(c.Parameters, names)
+		addFilterVariableNames(c.ResultFilter, names)
+	}
+	for _, f := range parsedCall.Fragments {
+		addFilterVariableNames(f.Definition.Filter, names)
+	}
+	return names
+}
+
+func addFilterVariableNames(filter *resultFilter, names map[string]bool) {
+	if filter == nil {
+		return
+	}
+	for _, field := range filter.Fields {
+		addParameterVariableNames(field.Params, names)
+		for _, d := range field.Directives {
+			addParameterVariableNames(d.Parameters, names)
+		}
+		addFilterVariableNames(field.SubParts, names)
+	}
+	for _, fc := range filter.Fragments {
+		for _, d := range fc.directives() {
+			addParameterVariableNames(d.Parameters, names)
+		}
+		if fc.Inline != nil {
+			addFilterVariableNames(fc.Inline.Filter, names)
+		}
+	}
+}
+
+func addParameterVariableNames(params *parameterList, names map[string]bool) {
+	if params == nil {
+		return
+	}
+	for _, p := range params.Values {
+		addValueVariableNames(p.Value, names)
+	}
+}
+
+func addValueVariableNames(v genericValue, names map[string]bool) {
+	if v.Variable != nil {
+		names[(*v.Variable)[1:]] = true
+	}
+	for _, nv := range v.Map {
+		addValueVariableNames(nv.Value, names)
+	}
+	for _, item := range v.List {
+		addValueVariableNames(item, names)
+	}
+}
+
+// checkDeclaredVariables reports the variables that a request declares but doesn't use,
+// according to UnusedVariables. It returns the names of the variables that the request
+// knows, which are the ones that it declares, or the ones that it uses if it has no
+// declarations.
+func (g *Graphy) checkDeclaredVariables(parsedCall *wrapper) (map[string]bool, error) {
+	used := usedVariableNames(parsedCall)
+	if parsedCall.OperationDef == nil {
+		return used, nil
+	}
+
+	known := map[string]bool{}
+	var errs []error
+	for _, v := range parsedCall.OperationDef.Variables {
+		name := v.Name[1:]
+		known[name] = true
+		if !used[name] {
+			errs = append(errs, NewGraphError(fmt.Sprintf("variable %s is declared but not used", name), v.Pos))
+		}
+	}
+	return known, g.reportVariableErrors(errs)
+}
+
+// checkProvidedVariables reports the variables that are provided for a request but that
+// it doesn't know, according to UnusedVariables.
+func (rs *RequestStub) checkProvidedVariables(provided []string) error {
+	var errs []error
+	for _, name := range provided {
+		if !rs.knownVariables[name] {
+			if rs.parsedCall.OperationDef != nil {
+				errs = append(errs, NewGraphError(fmt.Sprintf("variable %s is not declared", name), lexer.Position{}))
+			} else {
+				errs = append(errs, NewGraphError(fmt.Sprintf("variable %s is not used", name), lexer.Position{}))
+			}
+		}
+	}
+	return rs.graphy.reportVariableErrors(errs)
+}
+
+// reportVariableErrors returns the errors if UnusedVariables is VariablesReject, or logs
+// them if it is VariablesWarn.
+func (g *Graphy) reportVariableErrors(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	switch g.UnusedVariables {
+	case VariablesWarn:
+		for _, err := range errs {
+			log.Printf("Warning: %v", err)
+		}
+	case VariablesReject:
+		return errors.Join(errs...)
+	}
+	return nil
+}
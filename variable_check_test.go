@@ -0,0 +1,65 @@
+package quickgraph
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"os"
+	"testing"
+)
+
+func TestUnusedVariables_Ignore(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{UnusedVariables: VariablesIgnore}
+	g.RegisterQuery(ctx, "greeting", func(name string) string {
+		return "Hello, " + name
+	}, "name")
+
+	result, err := g.ProcessRequest(ctx, `query Greet($name: String!, $extra: Int) { greeting(name: $name) }`, `{"name": "Ada", "other": 1}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"greeting":"Hello, Ada"}}`, result)
+}
+
+func TestUnusedVariables_Reject(t *testing.T) {
+	ctx := context.Background()
+	g := Graphy{UnusedVariables: VariablesReject}
+	g.RegisterQuery(ctx, "greeting", func(name string) string {
+		return "Hello, " + name
+	}, "name")
+
+	result, err := g.ProcessRequest(ctx, `query Greet($name: String!) { greeting(name: $name) }`, `{"name": "Ada"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"greeting":"Hello, Ada"}}`, result)
+
+	result, err = g.ProcessRequest(ctx, `query Greet($name: String!, $extra: Int) { greeting(name: $name) }`, `{"name": "Ada"}`)
+	assert.Error(t, err)
+	assert.Equal(t, `{"errors":[{"message":"variable extra is declared but not used","locations":[{"line":1,"column":29}]}]}`, result)
+
+	result, err = g.ProcessRequest(ctx, `query Greet($name: String!) { greeting(name: $name) }`, `{"name": "Ada", "b": 1, "a": 2}`)
+	assert.Error(t, err)
+	assert.Equal(t, `{"errors":[{"message":"variable a is not declared"},{"message":"variable b is not declared"}]}`, result)
+
+	// Without declarations, the variables that are used are the known ones.
+	result, err = g.ProcessRequest(ctx, `{ greeting(name: $name) }`, `{"name": "Ada", "other": 1}`)
+	assert.Error(t, err)
+	assert.Equal(t, `{"errors":[{"message":"variable other is not used"}]}`, result)
+
+}
+
+func TestUnusedVariables_Warn(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	ctx := context.Background()
+	g := Graphy{UnusedVariables: VariablesWarn}
+	g.RegisterQuery(ctx, "greeting", func(name string) string {
+		return "Hello, " + name
+	}, "name")
+
+	result, err := g.ProcessRequest(ctx, `query Greet($name: String!) { greeting(name: $name) }`, `{"name": "Ada", "other": 1}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"greeting":"Hello, Ada"}}`, result)
+	assert.Contains(t, logged.String(), "Warning: variable other is not declared")
+}
@@ -0,0 +1,96 @@
+package quickgraph
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// StuckResolver describes a function that kept running after the context of its request
+// was cancelled. See Graphy.StuckResolverTimeout.
+type StuckResolver struct {
+	// Function is the name of the function.
+	Function string
+
+	// SinceCancel is how long the function had been running after the context was
+	// cancelled when it was reported.
+	SinceCancel time.Duration
+
+	// Stack is the stack of the goroutine that is running the function, at the time it
+	// was reported. It shows where the function is stuck.
+	Stack string
+}
+
+func (s StuckResolver) String() string {
+	return "function " + s.Function + " still running " + s.SinceCancel.String() + " after its context was cancelled\n" + s.Stack
+}
+
+// watchResolver waits for the function call to complete. If the context is cancelled and
+// the function is still running after StuckResolverTimeout, it is reported. Each call is
+// reported at most once.
+func (g *Graphy) watchResolver(ctx context.Context, name string, goroutine uint64, done <-chan struct{}) {
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+
+	cancelledAt := time.Now()
+	timer := time.NewTimer(g.StuckResolverTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return
+	case <-timer.C:
+	}
+
+	stuck := StuckResolver{
+		Function:    name,
+		SinceCancel: time.Since(cancelledAt),
+		Stack:       goroutineStack(goroutine),
+	}
+	if g.StuckResolverHandler != nil {
+		g.StuckResolverHandler(stuck)
+	} else {
+		log.Printf("Stuck resolver: %v", stuck)
+	}
+}
+
+// currentGoroutineID returns the ID of the calling goroutine, as it appears in stack
+// traces.
+func currentGoroutineID() uint64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	// The trace starts with "goroutine N [running]:".
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	if i := bytes.IndexByte(buf, ' '); i >= 0 {
+		buf = buf[:i]
+	}
+	id, _ := strconv.ParseUint(string(buf), 10, 64)
+	return id
+}
+
+// goroutineStack returns the stack trace of the goroutine with the given ID, or "" if it
+// isn't running anymore.
+func goroutineStack(id uint64) string {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	header := []byte("goroutine " + strconv.FormatUint(id, 10) + " ")
+	for _, trace := range bytes.Split(buf, []byte("\n\n")) {
+		if bytes.HasPrefix(trace, header) {
+			return string(trace)
+		}
+	}
+	return ""
+}
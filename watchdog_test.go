@@ -0,0 +1,63 @@
+package quickgraph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func stuckTestResolver(release chan struct{}) string {
+	<-release
+	return "done"
+}
+
+func TestStuckResolverWatchdog(t *testing.T) {
+	release := make(chan struct{})
+	reported := make(chan StuckResolver, 2)
+
+	g := Graphy{
+		StuckResolverTimeout: 10 * time.Millisecond,
+		StuckResolverHandler: func(stuck StuckResolver) {
+			reported <- stuck
+		},
+	}
+	g.RegisterQuery(context.Background(), "stuck", func(ctx context.Context) string {
+		// Ignores the context on purpose.
+		return stuckTestResolver(release)
+	})
+	g.RegisterQuery(context.Background(), "polite", func(ctx context.Context) string {
+		<-ctx.Done()
+		return "cancelled"
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_, _ = g.ProcessRequest(ctx, `{ stuck polite }`, "")
+		close(done)
+	}()
+	time.Sleep(5 * time.Millisecond)
+	cancel()
+
+	select {
+	case stuck := <-reported:
+		assert.Equal(t, "stuck", stuck.Function)
+		assert.GreaterOrEqual(t, stuck.SinceCancel, 10*time.Millisecond)
+		assert.Contains(t, stuck.Stack, "stuckTestResolver")
+	case <-time.After(time.Second):
+		assert.Fail(t, "the stuck resolver wasn't reported")
+	}
+
+	close(release)
+	<-done
+	// The function that honored the cancellation isn't reported.
+	assert.Len(t, reported, 0)
+}
+
+func TestGoroutineStack(t *testing.T) {
+	id := currentGoroutineID()
+	assert.NotZero(t, id)
+	assert.Contains(t, goroutineStack(id), "TestGoroutineStack")
+	assert.Equal(t, "", goroutineStack(0))
+}